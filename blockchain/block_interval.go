@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// blockIntervalSampleBlocks is a number of the latest blocks which are
+	// used to estimate average block interval
+	blockIntervalSampleBlocks = 100
+	// blockIntervalRefreshInterval is a time after which block interval is
+	// estimated again
+	blockIntervalRefreshInterval = time.Hour
+)
+
+// blockIntervalCache keeps the latest estimation of the average block
+// interval, it changes slowly so it is not requested on each payment.
+type blockIntervalCache struct {
+	lock        sync.Mutex
+	interval    time.Duration
+	estimatedAt time.Time
+}
+
+type blockHeader struct {
+	Number    string `json:"number"`
+	Timestamp string `json:"timestamp"`
+}
+
+// BlockInterval returns average interval between the latest blocks. It is
+// used to convert durations into the number of blocks.
+func (processor *Processor) BlockInterval() (interval time.Duration, err error) {
+	cache := processor.blockIntervals
+	if cache == nil {
+		return processor.estimateBlockInterval()
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.interval > 0 && time.Since(cache.estimatedAt) < blockIntervalRefreshInterval {
+		return cache.interval, nil
+	}
+	if interval, err = processor.estimateBlockInterval(); err != nil {
+		return 0, err
+	}
+	cache.interval, cache.estimatedAt = interval, time.Now()
+	return interval, nil
+}
+
+func (processor *Processor) estimateBlockInterval() (interval time.Duration, err error) {
+	latest, err := processor.blockHeader("latest")
+	if err != nil {
+		return 0, err
+	}
+	latestNumber := new(big.Int).SetBytes(common.FromHex(latest.Number))
+	if latestNumber.Sign() == 0 {
+		return 0, fmt.Errorf("error estimating block interval: chain has no blocks")
+	}
+
+	sample := big.NewInt(blockIntervalSampleBlocks)
+	if latestNumber.Cmp(sample) < 0 {
+		sample.Set(latestNumber)
+	}
+	earlier, err := processor.blockHeader(hexutil.EncodeBig(new(big.Int).Sub(latestNumber, sample)))
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := new(big.Int).Sub(
+		new(big.Int).SetBytes(common.FromHex(latest.Timestamp)),
+		new(big.Int).SetBytes(common.FromHex(earlier.Timestamp)))
+	interval = time.Duration(elapsed.Int64()) * time.Second / time.Duration(sample.Int64())
+	if interval <= 0 {
+		return 0, fmt.Errorf("error estimating block interval: block timestamps are not increasing")
+	}
+	return interval, nil
+}
+
+func (processor *Processor) blockHeader(number string) (header *blockHeader, err error) {
+	header = &blockHeader{}
+	if err = processor.rawClient.CallContext(context.Background(), header, "eth_getBlockByNumber", number, false); err != nil {
+		return nil, fmt.Errorf("error getting block %v: %v", number, err)
+	}
+	return header, nil
+}
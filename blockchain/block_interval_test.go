@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBlocksProcessor returns processor connected to the fake Ethereum node
+// which returns block timestamps by block number on eth_getBlockByNumber
+// call.
+func newBlocksProcessor(t *testing.T, latest string, timestamps map[string]string) (processor *Processor, calls *int, stop func()) {
+	calls = new(int)
+	processor, stop = newFakeNodeProcessor(t, map[string]interface{}{
+		"eth_getBlockByNumber": fakeNodeResult(func(params []interface{}) interface{} {
+			*calls++
+			number := params[0].(string)
+			if number == "latest" {
+				number = latest
+			}
+			return map[string]string{"number": number, "timestamp": timestamps[number]}
+		}),
+	})
+	processor.blockIntervals = &blockIntervalCache{}
+	return processor, calls, stop
+}
+
+func TestBlockInterval(t *testing.T) {
+	processor, calls, stop := newBlocksProcessor(t, "0x3e8", map[string]string{
+		"0x384": "0x5bc47000",
+		"0x3e8": "0x5bc474e2",
+	})
+	defer stop()
+
+	interval, err := processor.BlockInterval()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 12500*time.Millisecond, interval)
+
+	interval, err = processor.BlockInterval()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 12500*time.Millisecond, interval)
+	assert.Equal(t, 2, *calls, "block interval is cached")
+}
+
+func TestBlockIntervalShortChain(t *testing.T) {
+	processor, _, stop := newBlocksProcessor(t, "0xa", map[string]string{
+		"0x0": "0x5bc47000",
+		"0xa": "0x5bc47064",
+	})
+	defer stop()
+
+	interval, err := processor.BlockInterval()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, interval)
+}
+
+func TestBlockIntervalNoBlocks(t *testing.T) {
+	processor, _, stop := newBlocksProcessor(t, "0x0", map[string]string{
+		"0x0": "0x5bc47000",
+	})
+	defer stop()
+
+	_, err := processor.BlockInterval()
+
+	assert.Equal(t, "error estimating block interval: chain has no blocks", err.Error())
+}
@@ -12,8 +12,12 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/pkg/errors"
 	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/tracing"
 	log "github.com/sirupsen/logrus"
+	"math"
 	"math/big"
+	"sync"
+	"time"
 )
 
 var (
@@ -38,6 +42,18 @@ type Processor struct {
 	escrowContractAddress   common.Address
 	registryContractAddress common.Address
 	multiPartyEscrow        *MultiPartyEscrow
+	gasBumpPolicy           GasBumpPolicy
+	// gasPrices is nil if gas price is requested on each transaction
+	gasPrices *gasPriceCache
+	// txType is a type of the sent transactions: legacy or dynamic
+	txType      string
+	dynamicFees dynamicFeeSettings
+	// claims limits number of concurrent claims and assigns their nonces
+	claims *claimScheduler
+	// blockIntervals caches estimation of the average block interval
+	blockIntervals *blockIntervalCache
+	// chainIDs caches id of the chain, it is requested once
+	chainIDs *chainIDCache
 }
 
 // NewProcessor creates a new blockchain processor
@@ -47,6 +63,8 @@ func NewProcessor(metadata *ServiceMetadata) (Processor, error) {
 	p := Processor{
 		jobCompletionQueue: make(chan *jobInfo, 1000),
 		enabled:            config.GetBool(config.BlockchainEnabledKey),
+		blockIntervals:     &blockIntervalCache{},
+		chainIDs:           &chainIDCache{},
 	}
 
 	if !p.enabled {
@@ -62,6 +80,14 @@ func NewProcessor(metadata *ServiceMetadata) (Processor, error) {
 		p.ethClient = ethclients.EthClient
 	}
 
+	if expected, err := config.GetUint64(config.ExpectedChainIDKey); err != nil {
+		return p, err
+	} else if err = checkChainID(p.chainID, expected); err != nil {
+		return p, err
+	} else if expected != 0 {
+		p.chainIDs.id = new(big.Int).SetUint64(expected)
+	}
+
 	// TODO: if address is not in config, try to load it using network
 
 	//TODO: Read this from github
@@ -74,29 +100,152 @@ func NewProcessor(metadata *ServiceMetadata) (Processor, error) {
 		p.multiPartyEscrow = mpe
 	}
 
+	if policy, err := GetGasBumpPolicy(); err != nil {
+		return p, errors.Wrap(err, "error reading gas bump policy")
+	} else {
+		p.gasBumpPolicy = policy
+	}
+
+	p.txType = config.GetString(config.TxTypeKey)
+	if settings, err := getDynamicFeeSettings(); err != nil {
+		return p, errors.Wrap(err, "error reading transaction fees")
+	} else {
+		p.dynamicFees = settings
+	}
+
+	ethClient := p.ethClient
+	if cache, err := newGasPriceCache(func() (*big.Int, error) {
+		return ethClient.SuggestGasPrice(context.Background())
+	}); err != nil {
+		return p, errors.Wrap(err, "error reading gas price settings")
+	} else if cache != nil {
+		cache.start()
+		p.gasPrices = cache
+	}
+
 	// set local signature hash creator
 	p.sigHasher = func(i []byte) []byte {
 		return crypto.Keccak256(HashPrefix32Bytes, crypto.Keccak256(i))
 	}
 
 	// Setup identity
+	if privKey, err := daemonPrivateKey(); err != nil {
+		return p, err
+	} else if privKey != nil {
+		if err = checkDaemonAddress(privKey, config.GetString(config.ExpectedDaemonAddressKey)); err != nil {
+			return p, err
+		}
+		p.privateKey = privKey
+		p.address = crypto.PubkeyToAddress(p.privateKey.PublicKey).Hex()
+		log.WithField("address", p.address).Info("Daemon Ethereum address")
+
+		from := crypto.PubkeyToAddress(p.privateKey.PublicKey)
+		p.claims = newClaimScheduler(config.GetInt(config.MaxConcurrentClaimsKey), func() (uint64, error) {
+			return ethClient.PendingNonceAt(context.Background(), from)
+		})
+	}
+
+	return p, nil
+}
+
+// daemonPrivateKey returns private key set by private_key or derived from
+// hdwallet_mnemonic and hdwallet_index. It returns nil if neither of them
+// is configured.
+func daemonPrivateKey() (privateKey *ecdsa.PrivateKey, err error) {
 	if privateKeyString := config.GetString(config.PrivateKeyKey); privateKeyString != "" {
-		if privKey, err := crypto.HexToECDSA(privateKeyString); err != nil {
-			return p, errors.Wrap(err, "error getting private key")
-		} else {
-			p.privateKey = privKey
-			p.address = crypto.PubkeyToAddress(p.privateKey.PublicKey).Hex()
+		if privateKey, err = crypto.HexToECDSA(privateKeyString); err != nil {
+			return nil, errors.Wrap(err, "error getting private key")
+		}
+		return privateKey, nil
+	}
+
+	if hdwalletMnemonic := config.GetString(config.HdwalletMnemonicKey); hdwalletMnemonic != "" {
+		hdwalletIndex, err := config.GetUint64(config.HdwalletIndexKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting hdwallet index")
+		}
+		if hdwalletIndex > math.MaxUint32 {
+			return nil, errors.Errorf("hdwallet index %v is out of range", hdwalletIndex)
 		}
-	} else if hdwalletMnemonic := config.GetString(config.HdwalletMnemonicKey); hdwalletMnemonic != "" {
-		if privKey, err := derivePrivateKey(hdwalletMnemonic, 44, 60, 0, 0, uint32(config.GetInt(config.HdwalletIndexKey))); err != nil {
-			log.WithError(err).Panic("error deriving private key")
-		} else {
-			p.privateKey = privKey
-			p.address = crypto.PubkeyToAddress(p.privateKey.PublicKey).Hex()
+		if privateKey, err = derivePrivateKey(hdwalletMnemonic, 44, 60, 0, 0, uint32(hdwalletIndex)); err != nil {
+			return nil, errors.Wrap(err, "error deriving private key")
 		}
+		return privateKey, nil
 	}
 
-	return p, nil
+	return nil, nil
+}
+
+// DaemonAddress returns Ethereum address of the key which daemon uses to
+// sign transactions. Key is set by private_key or derived from
+// hdwallet_mnemonic and hdwallet_index. Error is returned if key is not
+// configured or incorrect.
+func DaemonAddress() (address common.Address, err error) {
+	privateKey, err := daemonPrivateKey()
+	if err != nil {
+		return
+	}
+	if privateKey == nil {
+		return address, errors.New("neither private_key nor hdwallet_mnemonic is set")
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey), nil
+}
+
+// checkDaemonAddress returns error if expected_daemon_address is set and it
+// is not equal to the address of the daemon key, which means that wrong key
+// is configured.
+func checkDaemonAddress(privateKey *ecdsa.PrivateKey, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	if address != common.HexToAddress(expected) {
+		return errors.Errorf("address of the daemon key %v doesn't match %v %v", address.Hex(), config.ExpectedDaemonAddressKey, expected)
+	}
+	return nil
+}
+
+// checkChainID returns error if expected_chain_id is set and it is not equal
+// to the chain id reported by Ethereum node, which means that daemon
+// configured for one network is connected to the node of another one.
+func checkChainID(chainID func() (*big.Int, error), expected uint64) error {
+	if expected == 0 {
+		return nil
+	}
+	actual, err := chainID()
+	if err != nil {
+		return errors.Wrap(err, "error checking chain id of Ethereum node")
+	}
+	if !actual.IsUint64() || actual.Uint64() != expected {
+		return errors.Errorf("chain id %v reported by Ethereum node doesn't match %v %v", actual, config.ExpectedChainIDKey, expected)
+	}
+	return nil
+}
+
+// chainIDCache keeps id of the chain after it is requested from Ethereum
+// node.
+type chainIDCache struct {
+	lock sync.Mutex
+	id   *big.Int
+}
+
+// ChainID returns id of the chain daemon is connected to. It is
+// expected_chain_id when it is set as it is checked against Ethereum node on
+// start, otherwise it is requested from the node on the first call.
+func (processor *Processor) ChainID() (chainID *big.Int, err error) {
+	cache := processor.chainIDs
+	if cache == nil {
+		return processor.chainID()
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.id == nil {
+		if cache.id, err = processor.chainID(); err != nil {
+			return nil, err
+		}
+	}
+	return new(big.Int).Set(cache.id), nil
 }
 
 func (processor *Processor) Enabled() (enabled bool) {
@@ -111,11 +260,19 @@ func (processor *Processor) MultiPartyEscrow() *MultiPartyEscrow {
 	return processor.multiPartyEscrow
 }
 
-func (processor *Processor) CurrentBlock() (currentBlock *big.Int, err error) {
+// CurrentBlock returns number of the latest block known to the Ethereum
+// node, span of the call is reported as a child of the span passed in ctx.
+func (processor *Processor) CurrentBlock(ctx context.Context) (currentBlock *big.Int, err error) {
+	ctx, span := tracing.StartSpan(ctx, "blockchain.current_block")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	// We have to do a raw call because the standard method of ethClient.HeaderByNumber(ctx, nil) errors on
 	// unmarshaling the response currently. See https://github.com/ethereum/go-ethereum/issues/3230
 	var currentBlockHex string
-	if err = processor.rawClient.CallContext(context.Background(), &currentBlockHex, "eth_blockNumber"); err != nil {
+	if err = processor.rawClient.CallContext(ctx, &currentBlockHex, "eth_blockNumber"); err != nil {
 		log.WithError(err).Error("error determining current block")
 		return nil, fmt.Errorf("error determining current block: %v", err)
 	}
@@ -126,11 +283,38 @@ func (processor *Processor) CurrentBlock() (currentBlock *big.Int, err error) {
 	return
 }
 
+// CurrentBlockTime returns timestamp of the latest block known to the
+// Ethereum node.
+func (processor *Processor) CurrentBlockTime() (blockTime time.Time, err error) {
+	var header struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err = processor.rawClient.CallContext(context.Background(), &header, "eth_getBlockByNumber", "latest", false); err != nil {
+		log.WithError(err).Error("error determining current block time")
+		return time.Time{}, fmt.Errorf("error determining current block time: %v", err)
+	}
+
+	timestamp := new(big.Int).SetBytes(common.FromHex(header.Timestamp))
+	return time.Unix(timestamp.Int64(), 0), nil
+}
+
 func (processor *Processor) HasIdentity() bool {
 	return processor.address != ""
 }
 
+// gasPrice returns gas price of the new transaction, it is taken from cache
+// if gas_price_refresh_interval is set.
+func (processor *Processor) gasPrice() (*big.Int, error) {
+	if processor.gasPrices != nil {
+		return processor.gasPrices.GasPrice()
+	}
+	return processor.ethClient.SuggestGasPrice(context.Background())
+}
+
 func (processor *Processor) Close() {
+	if processor.gasPrices != nil {
+		processor.gasPrices.close()
+	}
 	processor.ethClient.Close()
 	processor.rawClient.Close()
 }
@@ -0,0 +1,164 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+const (
+	testMnemonic   = "candy maple cake sugar pudding cream honey rich smooth crumble sweet treat"
+	testPrivateKey = "c87509a1c067bbde78beb793e6fa76530b6382a4c0241e5e4a9ec0a0f44dc0d3"
+)
+
+func setIdentityConfig(privateKey, mnemonic string, index int) (restore func()) {
+	config.Vip().Set(config.PrivateKeyKey, privateKey)
+	config.Vip().Set(config.HdwalletMnemonicKey, mnemonic)
+	config.Vip().Set(config.HdwalletIndexKey, index)
+	return func() {
+		config.Vip().Set(config.PrivateKeyKey, "")
+		config.Vip().Set(config.HdwalletMnemonicKey, "")
+		config.Vip().Set(config.HdwalletIndexKey, 0)
+	}
+}
+
+func TestDaemonAddressFromPrivateKey(t *testing.T) {
+	defer setIdentityConfig(testPrivateKey, "", 0)()
+
+	address, err := DaemonAddress()
+
+	assert.Nil(t, err)
+	assert.Equal(t, common.HexToAddress("0x627306090abaB3A6e1400e9345bC60c78a8BEf57"), address)
+}
+
+func TestDaemonAddressFromMnemonic(t *testing.T) {
+	defer setIdentityConfig("", testMnemonic, 0)()
+
+	address, err := DaemonAddress()
+
+	assert.Nil(t, err)
+	assert.Equal(t, common.HexToAddress("0x627306090abaB3A6e1400e9345bC60c78a8BEf57"), address)
+}
+
+func TestDaemonAddressFromMnemonicWithIndex(t *testing.T) {
+	defer setIdentityConfig("", testMnemonic, 1)()
+
+	address, err := DaemonAddress()
+
+	assert.Nil(t, err)
+	assert.Equal(t, common.HexToAddress("0xf17f52151EbEF6C7334FAD080c5704D77216b732"), address)
+}
+
+func TestDaemonAddressPrivateKeyTakesPrecedence(t *testing.T) {
+	defer setIdentityConfig(testPrivateKey, testMnemonic, 1)()
+
+	address, err := DaemonAddress()
+
+	assert.Nil(t, err)
+	assert.Equal(t, common.HexToAddress("0x627306090abaB3A6e1400e9345bC60c78a8BEf57"), address)
+}
+
+func TestDaemonAddressNoKey(t *testing.T) {
+	defer setIdentityConfig("", "", 0)()
+
+	_, err := DaemonAddress()
+
+	assert.Equal(t, "neither private_key nor hdwallet_mnemonic is set", err.Error())
+}
+
+func TestDaemonAddressIncorrectPrivateKey(t *testing.T) {
+	defer setIdentityConfig("0xzz", "", 0)()
+
+	_, err := DaemonAddress()
+
+	assert.NotNil(t, err)
+}
+
+func TestCheckDaemonAddressMatches(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA(testPrivateKey)
+
+	assert.Nil(t, checkDaemonAddress(privateKey, "0x627306090abaB3A6e1400e9345bC60c78a8BEf57"))
+	assert.Nil(t, checkDaemonAddress(privateKey, "0x627306090abab3a6e1400e9345bc60c78a8bef57"), "case is ignored")
+}
+
+func TestCheckDaemonAddressNotSet(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA(testPrivateKey)
+
+	assert.Nil(t, checkDaemonAddress(privateKey, ""))
+}
+
+func TestCheckDaemonAddressMismatch(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA(testPrivateKey)
+
+	err := checkDaemonAddress(privateKey, "0xf17f52151EbEF6C7334FAD080c5704D77216b732")
+
+	assert.Equal(t, "address of the daemon key 0x627306090abaB3A6e1400e9345bC60c78a8BEf57 doesn't match expected_daemon_address 0xf17f52151EbEF6C7334FAD080c5704D77216b732", err.Error())
+}
+
+// newChainIDProcessor returns processor connected to the fake Ethereum node
+// which reports chainID on eth_chainId call.
+func newChainIDProcessor(t *testing.T, chainID string) (processor *Processor, stop func()) {
+	return newFakeNodeProcessor(t, map[string]interface{}{"eth_chainId": chainID})
+}
+
+func TestCheckChainIDMatches(t *testing.T) {
+	processor, stop := newChainIDProcessor(t, "0x1")
+	defer stop()
+
+	assert.Nil(t, checkChainID(processor.chainID, 1))
+}
+
+func TestCheckChainIDMismatch(t *testing.T) {
+	processor, stop := newChainIDProcessor(t, "0xaa36a7")
+	defer stop()
+
+	err := checkChainID(processor.chainID, 1)
+
+	assert.EqualError(t, err, "chain id 11155111 reported by Ethereum node doesn't match expected_chain_id 1")
+}
+
+func TestCheckChainIDNotSet(t *testing.T) {
+	processor, stop := newChainIDProcessor(t, "0xaa36a7")
+	defer stop()
+
+	assert.Nil(t, checkChainID(processor.chainID, 0))
+}
+
+func TestCheckChainIDNodeError(t *testing.T) {
+	processor, stop := newChainIDProcessor(t, "not a number")
+	defer stop()
+
+	err := checkChainID(processor.chainID, 1)
+
+	assert.Contains(t, err.Error(), "error checking chain id of Ethereum node: cannot get chain id")
+}
+
+func TestChainIDIsCached(t *testing.T) {
+	processor, stop := newChainIDProcessor(t, "0xaa36a7")
+	processor.chainIDs = &chainIDCache{}
+
+	chainID, err := processor.ChainID()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(11155111), chainID)
+
+	stop()
+	chainID, err = processor.ChainID()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(11155111), chainID, "chain id is not requested again")
+}
+
+func TestChainIDIsNotCachedOnError(t *testing.T) {
+	processor, stop := newChainIDProcessor(t, "not a number")
+	defer stop()
+	processor.chainIDs = &chainIDCache{}
+
+	_, err := processor.ChainID()
+
+	assert.NotNil(t, err)
+	assert.Nil(t, processor.chainIDs.id)
+}
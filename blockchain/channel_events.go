@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ChannelOpenEvent is a ChannelOpen event of the MultiPartyEscrow contract.
+// BlockNumber and LogIndex identify the event, the same event is delivered
+// again when the blocks are scanned again or after chain reorganization.
+type ChannelOpenEvent struct {
+	ChannelID   *big.Int
+	Nonce       *big.Int
+	Sender      common.Address
+	Signer      common.Address
+	Recipient   common.Address
+	GroupID     [32]byte
+	Amount      *big.Int
+	Expiration  *big.Int
+	BlockNumber uint64
+	LogIndex    uint
+}
+
+// ChannelOpenEvents returns ChannelOpen events of the channels opened to the
+// recipient in the group from fromBlock to toBlock inclusive.
+func (processor *Processor) ChannelOpenEvents(ctx context.Context, fromBlock, toBlock uint64, recipient common.Address, groupID [32]byte) (events []*ChannelOpenEvent, err error) {
+	opts := &bind.FilterOpts{Start: fromBlock, End: &toBlock, Context: ctx}
+	iterator, err := processor.multiPartyEscrow.FilterChannelOpen(opts, nil, []common.Address{recipient}, [][32]byte{groupID})
+	if err != nil {
+		return nil, fmt.Errorf("error filtering ChannelOpen events: %v", err)
+	}
+	defer iterator.Close()
+
+	for iterator.Next() {
+		event := iterator.Event
+		events = append(events, &ChannelOpenEvent{
+			ChannelID:   event.ChannelId,
+			Nonce:       event.Nonce,
+			Sender:      event.Sender,
+			Signer:      event.Signer,
+			Recipient:   event.Recipient,
+			GroupID:     event.GroupId,
+			Amount:      event.Amount,
+			Expiration:  event.Expiration,
+			BlockNumber: event.Raw.BlockNumber,
+			LogIndex:    event.Raw.Index,
+		})
+	}
+	if err = iterator.Error(); err != nil {
+		return nil, fmt.Errorf("error reading ChannelOpen events: %v", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,62 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// ClaimRevertedError is returned by ClaimFundsFromChannel when claim
+// transaction is mined but reverted by the MultiPartyEscrow contract.
+type ClaimRevertedError struct {
+	TxHash common.Hash
+}
+
+func (err *ClaimRevertedError) Error() string {
+	return fmt.Sprintf("claim transaction %v is reverted", err.TxHash.Hex())
+}
+
+// isTransactionReverted returns true if receipt of the mined transaction has
+// failed status. Receipts without status, which are returned for the blocks
+// before Byzantium fork, are considered successful.
+func (processor *Processor) isTransactionReverted(hash common.Hash) (bool, error) {
+	var receipt *struct {
+		Status *hexutil.Uint64 `json:"status"`
+	}
+	if err := processor.rawClient.CallContext(context.Background(), &receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return false, err
+	}
+	if receipt == nil {
+		return false, errors.New("transaction receipt not found")
+	}
+	return receipt.Status != nil && uint64(*receipt.Status) == 0, nil
+}
+
+// ClaimRevertReason returns reason of the claim transaction revert which is
+// derived from the current channel state: one of config.ClaimRevertXxx
+// constants. nonce and amount are the channel nonce and the amount of the
+// payment which was claimed.
+func (processor *Processor) ClaimRevertReason(channelID, nonce, amount *big.Int) (reason string, err error) {
+	channel, ok, err := processor.MultiPartyEscrowChannel(context.Background(), channelID)
+	if err != nil {
+		return "", err
+	}
+	return claimRevertReason(channel, ok, nonce, amount), nil
+}
+
+func claimRevertReason(channel *MultiPartyEscrowChannel, ok bool, nonce, amount *big.Int) string {
+	// channel is removed from contract when it is claimed and closed
+	if !ok || channel.Nonce.Cmp(nonce) > 0 {
+		return config.ClaimRevertAlreadyClaimed
+	}
+	if channel.Value.Cmp(amount) < 0 {
+		return config.ClaimRevertInsufficientFunds
+	}
+	return config.ClaimRevertUnknown
+}
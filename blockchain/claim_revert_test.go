@@ -0,0 +1,55 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+func TestClaimRevertReason(t *testing.T) {
+	channel := &MultiPartyEscrowChannel{Nonce: big.NewInt(3), Value: big.NewInt(100)}
+
+	assert.Equal(t, config.ClaimRevertAlreadyClaimed, claimRevertReason(channel, true, big.NewInt(2), big.NewInt(100)), "channel nonce is incremented")
+	assert.Equal(t, config.ClaimRevertAlreadyClaimed, claimRevertReason(nil, false, big.NewInt(3), big.NewInt(100)), "channel is removed")
+	assert.Equal(t, config.ClaimRevertInsufficientFunds, claimRevertReason(channel, true, big.NewInt(3), big.NewInt(101)))
+	assert.Equal(t, config.ClaimRevertUnknown, claimRevertReason(channel, true, big.NewInt(3), big.NewInt(100)))
+}
+
+// newReceiptProcessor returns processor connected to the fake Ethereum node
+// which returns receipt on eth_getTransactionReceipt call.
+func newReceiptProcessor(t *testing.T, receipt interface{}) (processor *Processor, stop func()) {
+	return newFakeNodeProcessor(t, map[string]interface{}{"eth_getTransactionReceipt": receipt})
+}
+
+func TestIsTransactionReverted(t *testing.T) {
+	processor, stop := newReceiptProcessor(t, map[string]interface{}{"status": "0x0"})
+	defer stop()
+
+	reverted, err := processor.isTransactionReverted(common.HexToHash("0x01"))
+
+	assert.Nil(t, err)
+	assert.True(t, reverted)
+}
+
+func TestIsTransactionRevertedSucceeded(t *testing.T) {
+	processor, stop := newReceiptProcessor(t, map[string]interface{}{"status": "0x1"})
+	defer stop()
+
+	reverted, err := processor.isTransactionReverted(common.HexToHash("0x01"))
+
+	assert.Nil(t, err)
+	assert.False(t, reverted)
+}
+
+func TestIsTransactionRevertedReceiptNotFound(t *testing.T) {
+	processor, stop := newReceiptProcessor(t, nil)
+	defer stop()
+
+	_, err := processor.isTransactionReverted(common.HexToHash("0x01"))
+
+	assert.EqualError(t, err, "transaction receipt not found")
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"sort"
+	"sync"
+)
+
+// claimScheduler limits number of channel claim transactions which are sent
+// concurrently and assigns nonces to them. When no claims are in progress
+// nonce is the pending nonce of the daemon account reported by Ethereum node,
+// so transactions sent by other processes using the same account are taken
+// into account. While claims are in progress nonces are assigned locally, so
+// concurrent claims never get the same nonce even when previous transactions
+// are not seen by Ethereum node yet; nonces of the claims which didn't send
+// the transaction are given to the next claims first, otherwise transactions
+// with greater nonces would wait for the gap forever.
+type claimScheduler struct {
+	slots        chan struct{}
+	pendingNonce func() (uint64, error)
+
+	lock sync.Mutex
+	// next is the nonce following the greatest assigned one
+	next uint64
+	// inProgress contains nonces of the claims which are not released yet
+	inProgress map[uint64]bool
+	// free contains nonces less than next which were released without
+	// sending the transaction, sorted in ascending order
+	free []uint64
+}
+
+func newClaimScheduler(maxConcurrentClaims int, pendingNonce func() (uint64, error)) *claimScheduler {
+	if maxConcurrentClaims < 1 {
+		maxConcurrentClaims = 1
+	}
+	return &claimScheduler{
+		slots:        make(chan struct{}, maxConcurrentClaims),
+		pendingNonce: pendingNonce,
+		inProgress:   make(map[uint64]bool),
+	}
+}
+
+// acquire waits until number of claims in progress is less than
+// max_concurrent_claims and returns nonce of the claim transaction. release
+// should be called when claim is finished if err is nil.
+func (scheduler *claimScheduler) acquire() (nonce uint64, err error) {
+	scheduler.slots <- struct{}{}
+
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+
+	pending, err := scheduler.pendingNonce()
+	if err != nil {
+		<-scheduler.slots
+		return 0, err
+	}
+
+	if len(scheduler.inProgress) == 0 {
+		// node knows about all transactions sent by finished claims
+		scheduler.next, scheduler.free = pending, nil
+	}
+	// nonces below pending one are used by other processes
+	for len(scheduler.free) > 0 && scheduler.free[0] < pending {
+		scheduler.free = scheduler.free[1:]
+	}
+
+	if len(scheduler.free) > 0 {
+		nonce, scheduler.free = scheduler.free[0], scheduler.free[1:]
+	} else {
+		nonce = scheduler.next
+		if pending > nonce {
+			nonce = pending
+		}
+		scheduler.next = nonce + 1
+	}
+	scheduler.inProgress[nonce] = true
+	return nonce, nil
+}
+
+// release finishes the claim which got the nonce. submitted is false when no
+// transaction with the nonce was sent, then the nonce is given to the next
+// claim.
+func (scheduler *claimScheduler) release(nonce uint64, submitted bool) {
+	scheduler.lock.Lock()
+	delete(scheduler.inProgress, nonce)
+	if !submitted {
+		scheduler.free = append(scheduler.free, nonce)
+		sort.Slice(scheduler.free, func(i, j int) bool { return scheduler.free[i] < scheduler.free[j] })
+		// free nonces at the end are not gaps
+		for len(scheduler.free) > 0 && scheduler.free[len(scheduler.free)-1]+1 == scheduler.next {
+			scheduler.next--
+			scheduler.free = scheduler.free[:len(scheduler.free)-1]
+		}
+	}
+	scheduler.lock.Unlock()
+
+	<-scheduler.slots
+}
@@ -0,0 +1,166 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimSchedulerAssignsNoncesInOrder(t *testing.T) {
+	scheduler := newClaimScheduler(3, func() (uint64, error) { return 5, nil })
+
+	first, err := scheduler.acquire()
+	assert.Nil(t, err)
+	second, _ := scheduler.acquire()
+	third, _ := scheduler.acquire()
+
+	assert.Equal(t, []uint64{5, 6, 7}, []uint64{first, second, third}, "nonces of pending transactions are not reused")
+}
+
+func TestClaimSchedulerUsesNodePendingNonce(t *testing.T) {
+	pendingNonce := uint64(5)
+	scheduler := newClaimScheduler(1, func() (uint64, error) { return pendingNonce, nil })
+
+	nonce, _ := scheduler.acquire()
+	scheduler.release(nonce, true)
+	pendingNonce = 10
+	next, _ := scheduler.acquire()
+
+	assert.Equal(t, uint64(5), nonce)
+	assert.Equal(t, uint64(10), next, "transactions sent by other processes are taken into account")
+}
+
+func TestClaimSchedulerReusesNonceOfNotSentTransaction(t *testing.T) {
+	scheduler := newClaimScheduler(2, func() (uint64, error) { return 5, nil })
+
+	first, _ := scheduler.acquire()
+	second, _ := scheduler.acquire()
+	scheduler.release(second, false)
+	third, _ := scheduler.acquire()
+	scheduler.release(first, false)
+	fourth, _ := scheduler.acquire()
+
+	assert.Equal(t, uint64(6), third)
+	assert.Equal(t, uint64(5), fourth, "released nonce is reused before the next one")
+}
+
+func TestClaimSchedulerReusesReleasedNoncesInOrder(t *testing.T) {
+	scheduler := newClaimScheduler(4, func() (uint64, error) { return 5, nil })
+
+	scheduler.acquire()
+	second, _ := scheduler.acquire()
+	third, _ := scheduler.acquire()
+	scheduler.acquire()
+	scheduler.release(third, false)
+	scheduler.release(second, false)
+	fifth, _ := scheduler.acquire()
+	sixth, _ := scheduler.acquire()
+	scheduler.release(sixth, false)
+	scheduler.release(fifth, true)
+	seventh, _ := scheduler.acquire()
+	eighth, _ := scheduler.acquire()
+
+	assert.Equal(t, []uint64{6, 7}, []uint64{fifth, sixth})
+	assert.Equal(t, []uint64{7, 9}, []uint64{seventh, eighth})
+}
+
+func TestClaimSchedulerSkipsReleasedNoncesUsedByOthers(t *testing.T) {
+	pendingNonce := uint64(5)
+	scheduler := newClaimScheduler(3, func() (uint64, error) { return pendingNonce, nil })
+
+	first, _ := scheduler.acquire()
+	second, _ := scheduler.acquire()
+	third, _ := scheduler.acquire()
+	scheduler.release(first, false)
+	pendingNonce = 6
+	fourth, _ := scheduler.acquire()
+
+	assert.Equal(t, []uint64{5, 6, 7}, []uint64{first, second, third})
+	assert.Equal(t, uint64(8), fourth, "nonce 5 is used by another process")
+}
+
+func TestClaimSchedulerResyncsWhenNoClaimsInProgress(t *testing.T) {
+	pendingNonce := uint64(5)
+	scheduler := newClaimScheduler(2, func() (uint64, error) { return pendingNonce, nil })
+
+	first, _ := scheduler.acquire()
+	second, _ := scheduler.acquire()
+	scheduler.release(first, true)
+	scheduler.release(second, true)
+	// transaction with nonce 6 is replaced and dropped by node
+	pendingNonce = 6
+	third, _ := scheduler.acquire()
+
+	assert.Equal(t, uint64(6), third, "pending nonce of node is used when no claims are in progress")
+}
+
+func TestClaimSchedulerPendingNonceError(t *testing.T) {
+	scheduler := newClaimScheduler(1, func() (uint64, error) { return 0, errors.New("node is down") })
+
+	_, err := scheduler.acquire()
+	assert.EqualError(t, err, "node is down")
+	_, err = scheduler.acquire()
+	assert.EqualError(t, err, "node is down", "slot is released on error")
+}
+
+func TestClaimSchedulerBoundsConcurrentClaims(t *testing.T) {
+	var lock sync.Mutex
+	// node pending nonce follows the sent transactions
+	sent := uint64(0)
+	scheduler := newClaimScheduler(3, func() (uint64, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return sent, nil
+	})
+	inProgress, maxInProgress := 0, 0
+	var nonces []uint64
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := scheduler.acquire()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			lock.Lock()
+			inProgress++
+			if inProgress > maxInProgress {
+				maxInProgress = inProgress
+			}
+			nonces = append(nonces, nonce)
+			lock.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			lock.Lock()
+			inProgress--
+			sent++
+			lock.Unlock()
+			scheduler.release(nonce, true)
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, maxInProgress <= 3, "max claims in progress: %v", maxInProgress)
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	for i, nonce := range nonces {
+		assert.Equal(t, uint64(i), nonce)
+	}
+	assert.Equal(t, 20, len(nonces))
+}
+
+func TestClaimFundsWithoutIdentity(t *testing.T) {
+	processor := &Processor{}
+
+	_, err := processor.ClaimFundsFromChannel(time.Second, big.NewInt(42), big.NewInt(100), make([]byte, 65), false)
+
+	assert.EqualError(t, err, "cannot claim funds as neither private key nor HD wallet is specified")
+}
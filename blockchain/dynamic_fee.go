@@ -0,0 +1,219 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// dynamicFeeTxType is a type of the EIP-1559 transaction envelope.
+const dynamicFeeTxType = 0x02
+
+// dynamicFeeSettings contains fees of the EIP-1559 transactions set by
+// tx_max_fee_per_gas and tx_max_priority_fee_per_gas, zero fee is derived
+// from the chain.
+type dynamicFeeSettings struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+func getDynamicFeeSettings() (settings dynamicFeeSettings, err error) {
+	if settings.MaxFeePerGas, err = config.GetCheckedBigInt(config.TxMaxFeePerGasKey); err != nil {
+		return
+	}
+	settings.MaxPriorityFeePerGas, err = config.GetCheckedBigInt(config.TxMaxPriorityFeePerGasKey)
+	return
+}
+
+// fees returns max fee and max priority fee per gas of the new transaction.
+// Priority fee which is not configured is suggested by Ethereum node. Max fee
+// which is not configured is twice the base fee of the latest block plus
+// priority fee, so transaction stays valid when base fee grows during next
+// blocks.
+func (settings dynamicFeeSettings) fees(baseFee, suggestPriorityFee func() (*big.Int, error)) (feeCap, tipCap *big.Int, err error) {
+	tipCap = settings.MaxPriorityFeePerGas
+	if tipCap == nil || tipCap.Sign() == 0 {
+		if tipCap, err = suggestPriorityFee(); err != nil {
+			return nil, nil, fmt.Errorf("cannot get suggested priority fee: %v", err)
+		}
+	}
+
+	feeCap = settings.MaxFeePerGas
+	if feeCap == nil || feeCap.Sign() == 0 {
+		base, err := baseFee()
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot get base fee: %v", err)
+		}
+		feeCap = new(big.Int).Mul(base, big.NewInt(2))
+		feeCap.Add(feeCap, tipCap)
+	}
+
+	if tipCap.Cmp(feeCap) > 0 {
+		tipCap = feeCap
+	}
+	return feeCap, tipCap, nil
+}
+
+// bumpedTipCap returns priority fee which is increased in the same
+// proportion as max fee, node doesn't replace pending transaction unless both
+// fees are increased.
+func bumpedTipCap(tipCap, initialFeeCap, feeCap *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(tipCap, feeCap)
+	bumped.Div(bumped, initialFeeCap)
+	if bumped.Cmp(feeCap) > 0 {
+		bumped.Set(feeCap)
+	}
+	return bumped
+}
+
+// accessTuple is an item of the EIP-2930 access list.
+type accessTuple struct {
+	Address     common.Address
+	StorageKeys []common.Hash
+}
+
+// dynamicFeeTx is an EIP-1559 transaction. Typed transactions are not
+// supported by the go-ethereum version used, so transaction is encoded and
+// signed here and sent as raw transaction.
+type dynamicFeeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         common.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList []accessTuple
+}
+
+// signedDynamicFeeTx is a payload of the signed EIP-1559 transaction.
+type signedDynamicFeeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         common.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList []accessTuple
+	V, R, S    *big.Int
+}
+
+// sigHash returns hash of the transaction which is signed by sender.
+func (tx *dynamicFeeTx) sigHash() (hash common.Hash, err error) {
+	payload, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return hash, err
+	}
+	return crypto.Keccak256Hash([]byte{dynamicFeeTxType}, payload), nil
+}
+
+// sign returns raw transaction which can be sent by eth_sendRawTransaction
+// and hash of the transaction.
+func (tx *dynamicFeeTx) sign(privateKey *ecdsa.PrivateKey) (raw []byte, hash common.Hash, err error) {
+	sigHash, err := tx.sigHash()
+	if err != nil {
+		return nil, hash, err
+	}
+	signature, err := crypto.Sign(sigHash[:], privateKey)
+	if err != nil {
+		return nil, hash, err
+	}
+
+	payload, err := rlp.EncodeToBytes(&signedDynamicFeeTx{
+		ChainID:    tx.ChainID,
+		Nonce:      tx.Nonce,
+		GasTipCap:  tx.GasTipCap,
+		GasFeeCap:  tx.GasFeeCap,
+		Gas:        tx.Gas,
+		To:         tx.To,
+		Value:      tx.Value,
+		Data:       tx.Data,
+		AccessList: tx.AccessList,
+		V:          new(big.Int).SetUint64(uint64(signature[64])),
+		R:          new(big.Int).SetBytes(signature[0:32]),
+		S:          new(big.Int).SetBytes(signature[32:64]),
+	})
+	if err != nil {
+		return nil, hash, err
+	}
+	raw = append([]byte{dynamicFeeTxType}, payload...)
+	return raw, crypto.Keccak256Hash(raw), nil
+}
+
+// packChannelClaim returns input data of the channelClaim call of the
+// MultiPartyEscrow contract.
+func packChannelClaim(channelId, amount *big.Int, v uint8, r, s [32]byte, sendBack bool) (data []byte, err error) {
+	parsed, err := abi.JSON(strings.NewReader(MultiPartyEscrowABI))
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Pack("channelClaim", channelId, amount, v, r, s, sendBack)
+}
+
+// chainID returns id of the chain which is used to sign EIP-1559
+// transactions.
+func (processor *Processor) chainID() (*big.Int, error) {
+	var chainID hexutil.Big
+	if err := processor.rawClient.CallContext(context.Background(), &chainID, "eth_chainId"); err != nil {
+		return nil, fmt.Errorf("cannot get chain id: %v", err)
+	}
+	return (*big.Int)(&chainID), nil
+}
+
+// baseFee returns base fee per gas of the latest block.
+func (processor *Processor) baseFee() (*big.Int, error) {
+	var header struct {
+		BaseFeePerGas *hexutil.Big `json:"baseFeePerGas"`
+	}
+	if err := processor.rawClient.CallContext(context.Background(), &header, "eth_getBlockByNumber", "latest", false); err != nil {
+		return nil, err
+	}
+	if header.BaseFeePerGas == nil {
+		return nil, errors.New("latest block has no base fee, network doesn't support EIP-1559")
+	}
+	return (*big.Int)(header.BaseFeePerGas), nil
+}
+
+// suggestPriorityFee returns priority fee per gas suggested by Ethereum node.
+func (processor *Processor) suggestPriorityFee() (*big.Int, error) {
+	var fee hexutil.Big
+	if err := processor.rawClient.CallContext(context.Background(), &fee, "eth_maxPriorityFeePerGas"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&fee), nil
+}
+
+// sendRawTransaction sends signed transaction to Ethereum node.
+func (processor *Processor) sendRawTransaction(raw []byte) (hash common.Hash, err error) {
+	err = processor.rawClient.CallContext(context.Background(), &hash, "eth_sendRawTransaction", hexutil.Encode(raw))
+	return
+}
+
+// isTransactionPending returns true if transaction is not mined yet. It is
+// used instead of ethclient which cannot decode EIP-1559 transactions.
+func (processor *Processor) isTransactionPending(hash common.Hash) (bool, error) {
+	var tx *struct {
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if err := processor.rawClient.CallContext(context.Background(), &tx, "eth_getTransactionByHash", hash); err != nil {
+		return false, err
+	}
+	if tx == nil {
+		return false, errors.New("transaction not found")
+	}
+	return tx.BlockNumber == nil, nil
+}
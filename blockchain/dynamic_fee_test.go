@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+func chainFeesNotUsed() (*big.Int, error) {
+	return nil, errors.New("fee is read from chain")
+}
+
+func chainFee(fee int64) func() (*big.Int, error) {
+	return func() (*big.Int, error) { return big.NewInt(fee), nil }
+}
+
+func TestDynamicFeesConfigured(t *testing.T) {
+	settings := dynamicFeeSettings{MaxFeePerGas: big.NewInt(100), MaxPriorityFeePerGas: big.NewInt(3)}
+
+	feeCap, tipCap, err := settings.fees(chainFeesNotUsed, chainFeesNotUsed)
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(100), feeCap)
+	assert.Equal(t, big.NewInt(3), tipCap)
+}
+
+func TestDynamicFeesFromChain(t *testing.T) {
+	settings := dynamicFeeSettings{MaxFeePerGas: big.NewInt(0), MaxPriorityFeePerGas: big.NewInt(0)}
+
+	feeCap, tipCap, err := settings.fees(chainFee(10), chainFee(2))
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(22), feeCap, "twice the base fee plus priority fee")
+	assert.Equal(t, big.NewInt(2), tipCap)
+}
+
+func TestDynamicFeesPriorityFeeLimitedByMaxFee(t *testing.T) {
+	settings := dynamicFeeSettings{MaxFeePerGas: big.NewInt(5), MaxPriorityFeePerGas: big.NewInt(0)}
+
+	feeCap, tipCap, err := settings.fees(chainFeesNotUsed, chainFee(7))
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(5), feeCap)
+	assert.Equal(t, big.NewInt(5), tipCap)
+}
+
+func TestDynamicFeesNoBaseFee(t *testing.T) {
+	settings := dynamicFeeSettings{MaxFeePerGas: big.NewInt(0), MaxPriorityFeePerGas: big.NewInt(2)}
+
+	_, _, err := settings.fees(chainFeesNotUsed, chainFeesNotUsed)
+
+	assert.Equal(t, errors.New("cannot get base fee: fee is read from chain"), err)
+}
+
+func TestBumpedTipCap(t *testing.T) {
+	assert.Equal(t, big.NewInt(12), bumpedTipCap(big.NewInt(10), big.NewInt(100), big.NewInt(120)))
+	assert.Equal(t, big.NewInt(10), bumpedTipCap(big.NewInt(10), big.NewInt(100), big.NewInt(100)))
+	assert.Equal(t, big.NewInt(10), bumpedTipCap(big.NewInt(10), big.NewInt(10), big.NewInt(10)), "tip doesn't exceed max fee")
+}
+
+func TestDynamicFeeTxSign(t *testing.T) {
+	privateKey, _ := crypto.GenerateKey()
+	tx := &dynamicFeeTx{
+		ChainID:   big.NewInt(3),
+		Nonce:     7,
+		GasTipCap: big.NewInt(2000000000),
+		GasFeeCap: big.NewInt(100000000000),
+		Gas:       claimGasLimit,
+		To:        common.HexToAddress("0xf25186b5081ff5ce73482ad761db0eb0d25abfbf"),
+		Value:     big.NewInt(0),
+		Data:      []byte{1, 2, 3},
+	}
+
+	raw, hash, err := tx.sign(privateKey)
+
+	assert.Nil(t, err)
+	assert.Equal(t, byte(0x02), raw[0], "EIP-1559 transaction type")
+	assert.Equal(t, crypto.Keccak256Hash(raw), hash)
+	var signed signedDynamicFeeTx
+	assert.Nil(t, rlp.DecodeBytes(raw[1:], &signed))
+	assert.Equal(t, big.NewInt(3), signed.ChainID)
+	assert.Equal(t, uint64(7), signed.Nonce)
+	assert.Equal(t, big.NewInt(2000000000), signed.GasTipCap)
+	assert.Equal(t, big.NewInt(100000000000), signed.GasFeeCap)
+	assert.Equal(t, uint64(claimGasLimit), signed.Gas)
+	assert.Equal(t, tx.To, signed.To)
+	assert.Equal(t, 0, signed.Value.Sign())
+	assert.Equal(t, []byte{1, 2, 3}, signed.Data)
+	assert.Empty(t, signed.AccessList)
+
+	sigHash, _ := tx.sigHash()
+	signature := append(append(common.LeftPadBytes(signed.R.Bytes(), 32), common.LeftPadBytes(signed.S.Bytes(), 32)...), byte(signed.V.Uint64()))
+	publicKey, err := crypto.SigToPub(sigHash[:], signature)
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), crypto.PubkeyToAddress(*publicKey))
+}
+
+func TestLegacyTransactOpts(t *testing.T) {
+	from := common.HexToAddress("0x592E3C0f3B038A0D673F19a18a773F993d4b2610")
+	privateKey, _ := crypto.GenerateKey()
+
+	opts := legacyTransactOpts(from, bind.NewKeyedTransactor(privateKey).Signer, big.NewInt(7), big.NewInt(20000000000))
+
+	assert.Equal(t, from, opts.From)
+	assert.Equal(t, big.NewInt(7), opts.Nonce)
+	assert.Equal(t, big.NewInt(20000000000), opts.GasPrice)
+	assert.Equal(t, uint64(claimGasLimit), opts.GasLimit)
+	assert.NotNil(t, opts.Signer)
+}
+
+func TestPackChannelClaim(t *testing.T) {
+	data, err := packChannelClaim(big.NewInt(42), big.NewInt(12345), 27, [32]byte{1}, [32]byte{2}, true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, crypto.Keccak256([]byte("channelClaim(uint256,uint256,uint8,bytes32,bytes32,bool)"))[:4], data[:4])
+	assert.Equal(t, 4+6*32, len(data))
+	assert.True(t, bytes.Equal(common.LeftPadBytes([]byte{42}, 32), data[4:36]))
+}
@@ -8,10 +8,22 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/tracing"
 	log "github.com/sirupsen/logrus"
 )
 
-func (processor *Processor) ClaimFundsFromChannel(timeout time.Duration, channelId, amount *big.Int, signature []byte, sendBack bool) (err error) {
+// claimGasLimit is a gas limit of the channel claim transaction.
+const claimGasLimit = 1000000
+
+func (processor *Processor) ClaimFundsFromChannel(timeout time.Duration, channelId, amount *big.Int, signature []byte, sendBack bool) (txHash common.Hash, err error) {
+	_, span := tracing.StartSpan(context.Background(), "blockchain.claim_funds")
+	span.SetAttribute("channel_id", channelId.String())
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	log := log.WithFields(log.Fields{
 		"timeout":    timeout,
 		"channelId":  channelId,
@@ -20,55 +32,171 @@ func (processor *Processor) ClaimFundsFromChannel(timeout time.Duration, channel
 		"isSendBack": sendBack,
 	})
 
+	if processor.claims == nil {
+		return txHash, fmt.Errorf("cannot claim funds as neither private key nor HD wallet is specified")
+	}
+
 	v, r, s, err := ParseSignature(signature)
 	if err != nil {
 		log.WithError(err).Error("Error in Parsing the Signature.")
-		return fmt.Errorf("Error in Parsing the Signature: %v", err)
+		return txHash, fmt.Errorf("Error in Parsing the Signature: %v", err)
 	}
 
-	auth := bind.NewKeyedTransactor(processor.privateKey)
+	from := common.HexToAddress(processor.address)
 
-	log.Info("Submitting transaction to claim funds from channel")
-	txn, err := processor.multiPartyEscrow.ChannelClaim(
-		&bind.TransactOpts{
-			From:     common.HexToAddress(processor.address),
-			Signer:   auth.Signer,
-			GasLimit: 1000000,
+	nonce, err := processor.claims.acquire()
+	if err != nil {
+		log.WithError(err).Error("Error getting nonce of the daemon account")
+		return txHash, fmt.Errorf("Error getting nonce of the daemon account: %v", err)
+	}
+	// nonce is reused by the next claim if transaction is not sent
+	submitted := false
+	defer func() { processor.claims.release(nonce, submitted) }()
+	log = log.WithField("nonce", nonce)
+
+	var submit func(gasPrice *big.Int) (common.Hash, error)
+	var isPending func(hash common.Hash) (bool, error)
+	var gasPrice *big.Int
+	if processor.txType == config.TxTypeDynamic {
+		submit, gasPrice, err = processor.dynamicFeeClaimSubmitter(log, nonce, channelId, amount, v, r, s, sendBack)
+		isPending = processor.isTransactionPending
+	} else {
+		submit, gasPrice, err = processor.legacyClaimSubmitter(log, from, nonce, channelId, amount, v, r, s, sendBack)
+		isPending = func(hash common.Hash) (bool, error) {
+			_, isPending, err := processor.ethClient.TransactionByHash(context.Background(), hash)
+			return isPending, err
+		}
+	}
+	if err != nil {
+		return txHash, err
+	}
+
+	sender := &transactionSender{
+		policy: processor.gasBumpPolicy,
+		submit: func(gasPrice *big.Int) (common.Hash, error) {
+			hash, err := submit(gasPrice)
+			if err == nil {
+				submitted = true
+			}
+			return hash, err
 		},
-		channelId,
-		amount,
-		v,
-		r,
-		s,
-		sendBack,
-	)
+		isPending:    isPending,
+		pollInterval: time.Second,
+		now:          time.Now,
+		sleep:        time.Sleep,
+	}
+	if txHash, err = sender.send(gasPrice, timeout); err != nil {
+		log.WithError(err).Error("Error claiming funds from channel")
+		return
+	}
+
+	reverted, err := processor.isTransactionReverted(txHash)
 	if err != nil {
-		log.WithError(err).Error("Error submitting transaction to claim funds from channel")
-		return fmt.Errorf("Error submitting transaction to claim funds from channel: %v", err)
+		log.WithError(err).WithField("txHash", txHash.Hex()).Error("Error getting transaction receipt")
+		return txHash, fmt.Errorf("Error getting receipt of transaction %v: %v", txHash.Hex(), err)
 	}
+	if reverted {
+		err = &ClaimRevertedError{TxHash: txHash}
+		log.WithError(err).Error("Error claiming funds from channel")
+		return txHash, err
+	}
+
+	log.WithField("txHash", txHash.Hex()).Info("Transaction finished successfully")
+	return txHash, nil
+}
+
+// legacyClaimSubmitter returns function which submits channel claim
+// transaction paying fixed gas price and initial gas price.
+func (processor *Processor) legacyClaimSubmitter(log *log.Entry, from common.Address, nonce uint64, channelId, amount *big.Int, v uint8, r, s [32]byte, sendBack bool) (submit func(*big.Int) (common.Hash, error), gasPrice *big.Int, err error) {
+	auth := bind.NewKeyedTransactor(processor.privateKey)
 
-	log.WithField("timeout", timeout).Info("Transaction sent, waiting for timeout till transaction is committed")
-	endTime := time.Now().Add(timeout)
-	isPending := true
-	for {
-		_, isPending, err = processor.ethClient.TransactionByHash(context.Background(), txn.Hash())
+	// gas price is set explicitly when transaction can be resubmitted to
+	// replace pending transaction by a new one, when it is cached or when it
+	// is limited by tx_max_gas_price
+	policy := processor.gasBumpPolicy
+	if policy.Enabled() || processor.gasPrices != nil || (policy.MaxGasPrice != nil && policy.MaxGasPrice.Sign() > 0) {
+		if gasPrice, err = processor.gasPrice(); err != nil {
+			log.WithError(err).Error("Error getting suggested gas price")
+			return nil, nil, fmt.Errorf("Error getting suggested gas price: %v", err)
+		}
+		gasPrice = policy.limit(gasPrice)
+	}
+
+	submit = func(gasPrice *big.Int) (common.Hash, error) {
+		log.WithField("gasPrice", gasPrice).Info("Submitting transaction to claim funds from channel")
+		txn, err := processor.multiPartyEscrow.ChannelClaim(
+			legacyTransactOpts(from, auth.Signer, new(big.Int).SetUint64(nonce), gasPrice),
+			channelId,
+			amount,
+			v,
+			r,
+			s,
+			sendBack,
+		)
 		if err != nil {
-			log.WithError(err).Error("Transaction error")
-			// TODO: fix properly
-			//return fmt.Errorf("Error while committing blockchain transaction: %v", err)
+			return common.Hash{}, err
+		}
+		return txn.Hash(), nil
+	}
+	return submit, gasPrice, nil
+}
+
+// legacyTransactOpts returns options of the transaction which pays fixed gas
+// price, nil gas price is estimated on submission.
+func legacyTransactOpts(from common.Address, signer bind.SignerFn, nonce, gasPrice *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From:     from,
+		Signer:   signer,
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		GasLimit: claimGasLimit,
+	}
+}
+
+// dynamicFeeClaimSubmitter returns function which submits channel claim
+// transaction as EIP-1559 transaction and initial max fee per gas. Max fee
+// passed to the function is increased when transaction is resubmitted,
+// priority fee is increased in the same proportion.
+func (processor *Processor) dynamicFeeClaimSubmitter(log *log.Entry, nonce uint64, channelId, amount *big.Int, v uint8, r, s [32]byte, sendBack bool) (submit func(*big.Int) (common.Hash, error), feeCap *big.Int, err error) {
+	data, err := packChannelClaim(channelId, amount, v, r, s, sendBack)
+	if err != nil {
+		log.WithError(err).Error("Error packing channel claim call")
+		return nil, nil, fmt.Errorf("Error packing channel claim call: %v", err)
+	}
+	chainID, err := processor.chainID()
+	if err != nil {
+		log.WithError(err).Error("Error getting chain id")
+		return nil, nil, err
+	}
+	feeCap, tipCap, err := processor.dynamicFees.fees(processor.baseFee, processor.suggestPriorityFee)
+	if err != nil {
+		log.WithError(err).Error("Error getting transaction fees")
+		return nil, nil, fmt.Errorf("Error getting transaction fees: %v", err)
+	}
+
+	initialFeeCap := feeCap
+	submit = func(feeCap *big.Int) (common.Hash, error) {
+		tx := &dynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: bumpedTipCap(tipCap, initialFeeCap, feeCap),
+			GasFeeCap: feeCap,
+			Gas:       claimGasLimit,
+			To:        processor.escrowContractAddress,
+			Value:     big.NewInt(0),
+			Data:      data,
 		}
-		if !isPending {
-			break
+		log.WithField("maxFeePerGas", tx.GasFeeCap).WithField("maxPriorityFeePerGas", tx.GasTipCap).Info("Submitting EIP-1559 transaction to claim funds from channel")
+		raw, hash, err := tx.sign(processor.privateKey)
+		if err != nil {
+			return hash, err
 		}
-		if time.Now().After(endTime) {
-			log.Error("Transaction timeout")
-			return fmt.Errorf("Timeout while waiting for blockchain transaction commit")
+		if _, err = processor.sendRawTransaction(raw); err != nil {
+			return hash, err
 		}
-		time.Sleep(time.Second * 1)
+		return hash, nil
 	}
-
-	log.Info("Transaction finished successfully")
-	return nil
+	return submit, feeCap, nil
 }
 
 type MultiPartyEscrowChannel struct {
@@ -83,10 +211,19 @@ type MultiPartyEscrowChannel struct {
 
 var zeroAddress = common.Address{}
 
-func (processor *Processor) MultiPartyEscrowChannel(channelID *big.Int) (channel *MultiPartyEscrowChannel, ok bool, err error) {
+// MultiPartyEscrowChannel reads channel state from the contract, span of the
+// call is reported as a child of the span passed in ctx.
+func (processor *Processor) MultiPartyEscrowChannel(ctx context.Context, channelID *big.Int) (channel *MultiPartyEscrowChannel, ok bool, err error) {
+	ctx, span := tracing.StartSpan(ctx, "blockchain.get_channel")
+	span.SetAttribute("channel_id", channelID.String())
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	log := log.WithField("channelID", channelID)
 
-	ch, err := processor.multiPartyEscrow.Channels(nil, channelID)
+	ch, err := processor.multiPartyEscrow.Channels(&bind.CallOpts{Context: ctx}, channelID)
 	if err != nil {
 		log.WithError(err).Warn("Error while looking up for channel id in blockchain")
 		return nil, false, err
@@ -0,0 +1,47 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeNodeResult returns result of the fake Ethereum node call which depends
+// on the call parameters.
+type fakeNodeResult func(params []interface{}) interface{}
+
+// newFakeNodeProcessor returns processor connected to the fake Ethereum node
+// which returns results by JSON-RPC method name. Result can be
+// fakeNodeResult to compute it from the call parameters. Calls of other
+// methods fail with "method not found" error.
+func newFakeNodeProcessor(t *testing.T, results map[string]interface{}) (processor *Processor, stop func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []interface{}   `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		result, ok := results[request.Method]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "error": map[string]interface{}{"code": -32601, "message": "method not found"}})
+			return
+		}
+		if compute, ok := result.(fakeNodeResult); ok {
+			result = compute(request.Params)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	client, err := rpc.DialHTTP(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Processor{rawClient: client}, func() {
+		client.Close()
+		server.Close()
+	}
+}
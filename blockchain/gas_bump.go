@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// GasBumpPolicy describes how gas price of the pending transaction is
+// increased when transaction is not mined in time.
+type GasBumpPolicy struct {
+	// BumpPercent is a percent gas price is increased by on each
+	// resubmission, zero disables resubmission.
+	BumpPercent int64
+	// ResubmitInterval is a time transaction is waited for before it is
+	// resubmitted with higher gas price.
+	ResubmitInterval time.Duration
+	// MaxGasPrice is a gas price in wei which is never exceeded.
+	MaxGasPrice *big.Int
+}
+
+// GetGasBumpPolicy returns gas bump policy set by tx_gas_bump_percent,
+// tx_resubmit_interval and tx_max_gas_price.
+func GetGasBumpPolicy() (policy GasBumpPolicy, err error) {
+	maxGasPrice, err := config.GetCheckedBigInt(config.TxMaxGasPriceKey)
+	if err != nil {
+		return
+	}
+	return GasBumpPolicy{
+		BumpPercent:      int64(config.GetInt(config.TxGasBumpPercentKey)),
+		ResubmitInterval: config.GetDuration(config.TxResubmitIntervalKey),
+		MaxGasPrice:      maxGasPrice,
+	}, nil
+}
+
+// Enabled returns true if pending transactions should be resubmitted.
+func (policy GasBumpPolicy) Enabled() bool {
+	return policy.BumpPercent > 0
+}
+
+// limit returns price limited by MaxGasPrice, zero or nil MaxGasPrice means
+// that price is not limited.
+func (policy GasBumpPolicy) limit(price *big.Int) *big.Int {
+	if policy.MaxGasPrice == nil || policy.MaxGasPrice.Sign() == 0 || price.Cmp(policy.MaxGasPrice) <= 0 {
+		return price
+	}
+	return new(big.Int).Set(policy.MaxGasPrice)
+}
+
+// next returns gas price increased by BumpPercent and limited by
+// MaxGasPrice. ok is false if price cannot be increased anymore.
+func (policy GasBumpPolicy) next(price *big.Int) (next *big.Int, ok bool) {
+	if !policy.Enabled() || price.Cmp(policy.MaxGasPrice) >= 0 {
+		return nil, false
+	}
+	next = new(big.Int).Mul(price, big.NewInt(100+policy.BumpPercent))
+	next.Div(next, big.NewInt(100))
+	if next.Cmp(price) <= 0 {
+		next.Add(price, big.NewInt(1))
+	}
+	if next.Cmp(policy.MaxGasPrice) > 0 {
+		next.Set(policy.MaxGasPrice)
+	}
+	return next, true
+}
+
+// transactionSender submits transaction and waits until it is mined
+// resubmitting it with higher gas price according to the policy.
+type transactionSender struct {
+	policy GasBumpPolicy
+	// submit sends transaction with given gas price and returns its hash,
+	// all transactions should have the same nonce to replace each other.
+	// Gas price is max fee per gas for EIP-1559 transactions.
+	submit func(gasPrice *big.Int) (txHash common.Hash, err error)
+	// isPending returns true if transaction is not mined yet.
+	isPending    func(hash common.Hash) (bool, error)
+	pollInterval time.Duration
+	now          func() time.Time
+	sleep        func(time.Duration)
+}
+
+// send submits transaction with initial gas price and waits until one of the
+// submitted transactions is mined or timeout is expired. Initial gas price
+// can be nil if policy is disabled, then it is estimated on submission.
+func (sender *transactionSender) send(gasPrice *big.Int, timeout time.Duration) (txHash common.Hash, err error) {
+	hash, err := sender.submit(gasPrice)
+	if err != nil {
+		return txHash, fmt.Errorf("Error submitting transaction to claim funds from channel: %v", err)
+	}
+	submitted := []common.Hash{hash}
+
+	log.WithField("timeout", timeout).Info("Transaction sent, waiting for timeout till transaction is committed")
+	endTime := sender.now().Add(timeout)
+	submittedAt := sender.now()
+	for {
+		for _, hash := range submitted {
+			isPending, err := sender.isPending(hash)
+			if err != nil {
+				log.WithError(err).WithField("txHash", hash.Hex()).Debug("Transaction error")
+				continue
+			}
+			if !isPending {
+				return hash, nil
+			}
+		}
+		if sender.now().After(endTime) {
+			log.Error("Transaction timeout")
+			return txHash, fmt.Errorf("Timeout while waiting for blockchain transaction commit")
+		}
+		if sender.now().Sub(submittedAt) >= sender.policy.ResubmitInterval {
+			if next, ok := sender.policy.next(gasPrice); ok {
+				submittedAt = sender.now()
+				log := log.WithField("gasPrice", next)
+				if hash, err := sender.submit(next); err != nil {
+					log.WithError(err).Warn("Error resubmitting transaction with higher gas price")
+				} else {
+					log.WithField("txHash", hash.Hex()).Info("Transaction resubmitted with higher gas price")
+					gasPrice = next
+					submitted = append(submitted, hash)
+				}
+			}
+		}
+		sender.sleep(sender.pollInterval)
+	}
+}
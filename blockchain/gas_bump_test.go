@@ -0,0 +1,141 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// stuckTransactionNetwork simulates network which doesn't mine transactions
+// with gas price lower than minGasPrice.
+type stuckTransactionNetwork struct {
+	minGasPrice *big.Int
+	submitted   []*big.Int
+	gasPrices   map[common.Hash]*big.Int
+	time        time.Time
+}
+
+func newStuckTransactionNetwork(minGasPrice int64) *stuckTransactionNetwork {
+	return &stuckTransactionNetwork{
+		minGasPrice: big.NewInt(minGasPrice),
+		gasPrices:   make(map[common.Hash]*big.Int),
+		time:        time.Unix(1500000000, 0),
+	}
+}
+
+func (network *stuckTransactionNetwork) sender(policy GasBumpPolicy) *transactionSender {
+	return &transactionSender{
+		policy: policy,
+		submit: func(gasPrice *big.Int) (common.Hash, error) {
+			network.submitted = append(network.submitted, gasPrice)
+			txn := types.NewTransaction(1, common.Address{}, big.NewInt(0), 1000000, gasPrice, nil)
+			network.gasPrices[txn.Hash()] = gasPrice
+			return txn.Hash(), nil
+		},
+		isPending: func(hash common.Hash) (bool, error) {
+			gasPrice, ok := network.gasPrices[hash]
+			if !ok {
+				return false, errors.New("not found")
+			}
+			return gasPrice.Cmp(network.minGasPrice) < 0, nil
+		},
+		pollInterval: time.Second,
+		now:          func() time.Time { return network.time },
+		sleep:        func(d time.Duration) { network.time = network.time.Add(d) },
+	}
+}
+
+func testGasBumpPolicy() GasBumpPolicy {
+	return GasBumpPolicy{
+		BumpPercent:      20,
+		ResubmitInterval: time.Minute,
+		MaxGasPrice:      big.NewInt(150),
+	}
+}
+
+func TestGasBumpPolicyNext(t *testing.T) {
+	policy := testGasBumpPolicy()
+
+	next, ok := policy.next(big.NewInt(100))
+
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(120), next)
+}
+
+func TestGasBumpPolicyNextLimitedByMaxGasPrice(t *testing.T) {
+	policy := testGasBumpPolicy()
+
+	next, ok := policy.next(big.NewInt(140))
+
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(150), next)
+}
+
+func TestGasBumpPolicyNextMaxGasPriceReached(t *testing.T) {
+	policy := testGasBumpPolicy()
+
+	_, ok := policy.next(big.NewInt(150))
+
+	assert.False(t, ok)
+}
+
+func TestGasBumpPolicyLimit(t *testing.T) {
+	policy := testGasBumpPolicy()
+
+	assert.Equal(t, big.NewInt(100), policy.limit(big.NewInt(100)))
+	assert.Equal(t, big.NewInt(150), policy.limit(big.NewInt(200)))
+	assert.Equal(t, big.NewInt(200), GasBumpPolicy{MaxGasPrice: big.NewInt(0)}.limit(big.NewInt(200)))
+}
+
+func TestGasBumpPolicyNextDisabled(t *testing.T) {
+	_, ok := GasBumpPolicy{}.next(big.NewInt(100))
+
+	assert.False(t, ok)
+}
+
+func TestSendResubmitsStuckTransaction(t *testing.T) {
+	network := newStuckTransactionNetwork(130)
+	sender := network.sender(testGasBumpPolicy())
+
+	txHash, err := sender.send(big.NewInt(100), time.Hour)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(100), big.NewInt(120), big.NewInt(144)}, network.submitted)
+	assert.Equal(t, big.NewInt(144), network.gasPrices[txHash])
+}
+
+func TestSendDoesNotExceedMaxGasPrice(t *testing.T) {
+	network := newStuckTransactionNetwork(200)
+	sender := network.sender(testGasBumpPolicy())
+
+	_, err := sender.send(big.NewInt(100), time.Hour)
+
+	assert.EqualError(t, err, "Timeout while waiting for blockchain transaction commit")
+	assert.Equal(t, []*big.Int{big.NewInt(100), big.NewInt(120), big.NewInt(144), big.NewInt(150)}, network.submitted)
+}
+
+func TestSendWithoutGasBump(t *testing.T) {
+	network := newStuckTransactionNetwork(130)
+	sender := network.sender(GasBumpPolicy{})
+
+	_, err := sender.send(big.NewInt(100), time.Hour)
+
+	assert.EqualError(t, err, "Timeout while waiting for blockchain transaction commit")
+	assert.Equal(t, []*big.Int{big.NewInt(100)}, network.submitted)
+}
+
+func TestSendReturnsMinedTransaction(t *testing.T) {
+	network := newStuckTransactionNetwork(100)
+	sender := network.sender(testGasBumpPolicy())
+
+	txHash, err := sender.send(big.NewInt(100), time.Hour)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(100)}, network.submitted)
+	assert.Equal(t, big.NewInt(100), network.gasPrices[txHash])
+}
@@ -0,0 +1,113 @@
+package blockchain
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// gasPriceCache keeps the latest gas price suggested by Ethereum node. Price
+// is refreshed in background so transactions are submitted without waiting
+// for the node.
+type gasPriceCache struct {
+	// suggest returns gas price suggested by Ethereum node
+	suggest         func() (*big.Int, error)
+	refreshInterval time.Duration
+	// multiplierPercent is a percent of the suggested price which is used
+	multiplierPercent int64
+	// maxGasPrice is a gas price which is never exceeded, zero means
+	// unlimited
+	maxGasPrice *big.Int
+	now         func() time.Time
+	after       func(time.Duration) <-chan time.Time
+
+	lock      sync.Mutex
+	price     *big.Int
+	fetchedAt time.Time
+	stop      chan struct{}
+}
+
+// newGasPriceCache returns gas price cache configured by
+// gas_price_refresh_interval, gas_price_multiplier_percent and
+// tx_max_gas_price or nil if gas_price_refresh_interval is not set.
+func newGasPriceCache(suggest func() (*big.Int, error)) (cache *gasPriceCache, err error) {
+	refreshInterval := config.GetDuration(config.GasPriceRefreshIntervalKey)
+	if refreshInterval <= 0 {
+		return nil, nil
+	}
+	maxGasPrice, err := config.GetCheckedBigInt(config.TxMaxGasPriceKey)
+	if err != nil {
+		return nil, err
+	}
+	return &gasPriceCache{
+		suggest:           suggest,
+		refreshInterval:   refreshInterval,
+		multiplierPercent: int64(config.GetInt(config.GasPriceMultiplierPercentKey)),
+		maxGasPrice:       maxGasPrice,
+		now:               time.Now,
+		after:             time.After,
+	}, nil
+}
+
+// GasPrice returns cached gas price. Price is fetched from Ethereum node if
+// it is older than refresh interval, for example when background refresh
+// fails.
+func (cache *gasPriceCache) GasPrice() (price *big.Int, err error) {
+	cache.lock.Lock()
+	price, fetchedAt := cache.price, cache.fetchedAt
+	cache.lock.Unlock()
+
+	if price != nil && cache.now().Sub(fetchedAt) < cache.refreshInterval {
+		return new(big.Int).Set(price), nil
+	}
+	return cache.refresh()
+}
+
+// refresh fetches gas price from Ethereum node and applies multiplier and
+// maximum gas price to it.
+func (cache *gasPriceCache) refresh() (price *big.Int, err error) {
+	suggested, err := cache.suggest()
+	if err != nil {
+		return nil, err
+	}
+
+	price = new(big.Int).Mul(suggested, big.NewInt(cache.multiplierPercent))
+	price.Div(price, big.NewInt(100))
+	if cache.maxGasPrice.Sign() > 0 && price.Cmp(cache.maxGasPrice) > 0 {
+		price.Set(cache.maxGasPrice)
+	}
+
+	cache.lock.Lock()
+	cache.price, cache.fetchedAt = price, cache.now()
+	cache.lock.Unlock()
+
+	log.WithField("suggestedGasPrice", suggested).WithField("gasPrice", price).Debug("Gas price refreshed")
+	return new(big.Int).Set(price), nil
+}
+
+// start refreshes gas price in background until close is called.
+func (cache *gasPriceCache) start() {
+	cache.stop = make(chan struct{})
+	go func(stop <-chan struct{}) {
+		for {
+			if _, err := cache.refresh(); err != nil {
+				log.WithError(err).Warn("Cannot refresh gas price")
+			}
+			select {
+			case <-stop:
+				return
+			case <-cache.after(cache.refreshInterval):
+			}
+		}
+	}(cache.stop)
+}
+
+func (cache *gasPriceCache) close() {
+	if cache.stop != nil {
+		close(cache.stop)
+	}
+}
@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testGasPriceNode simulates Ethereum node which suggests gas price.
+type testGasPriceNode struct {
+	suggested int64
+	err       error
+	calls     int
+	time      time.Time
+}
+
+func (node *testGasPriceNode) cache() *gasPriceCache {
+	return &gasPriceCache{
+		suggest: func() (*big.Int, error) {
+			node.calls++
+			return big.NewInt(node.suggested), node.err
+		},
+		refreshInterval:   time.Minute,
+		multiplierPercent: 100,
+		maxGasPrice:       big.NewInt(0),
+		now:               func() time.Time { return node.time },
+	}
+}
+
+func TestGasPriceCacheUsesCachedPrice(t *testing.T) {
+	node := &testGasPriceNode{suggested: 100, time: time.Unix(1500000000, 0)}
+	cache := node.cache()
+
+	price, err := cache.GasPrice()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(100), price)
+
+	node.suggested = 200
+	node.time = node.time.Add(time.Minute - time.Second)
+	price, err = cache.GasPrice()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(100), price)
+	assert.Equal(t, 1, node.calls)
+}
+
+func TestGasPriceCacheRefreshedOnInterval(t *testing.T) {
+	node := &testGasPriceNode{suggested: 100, time: time.Unix(1500000000, 0)}
+	cache := node.cache()
+	cache.GasPrice()
+
+	node.suggested = 200
+	node.time = node.time.Add(time.Minute)
+	price, err := cache.GasPrice()
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(200), price)
+	assert.Equal(t, 2, node.calls)
+}
+
+func TestGasPriceCacheMultiplierAndMaxGasPrice(t *testing.T) {
+	node := &testGasPriceNode{suggested: 60, time: time.Unix(1500000000, 0)}
+	cache := node.cache()
+	cache.multiplierPercent = 150
+	cache.maxGasPrice = big.NewInt(120)
+
+	price, _ := cache.refresh()
+	assert.Equal(t, big.NewInt(90), price)
+
+	node.suggested = 100
+	price, _ = cache.refresh()
+	assert.Equal(t, big.NewInt(120), price)
+}
+
+func TestGasPriceCacheRefreshError(t *testing.T) {
+	node := &testGasPriceNode{suggested: 100, time: time.Unix(1500000000, 0)}
+	cache := node.cache()
+	cache.GasPrice()
+
+	node.err = errors.New("node is unavailable")
+	node.time = node.time.Add(time.Minute)
+	_, err := cache.GasPrice()
+
+	assert.Equal(t, errors.New("node is unavailable"), err)
+}
+
+func TestGasPriceCacheBackgroundRefresh(t *testing.T) {
+	node := &testGasPriceNode{suggested: 100, time: time.Unix(1500000000, 0)}
+	cache := node.cache()
+	refreshed := make(chan int64)
+	cache.suggest = func() (*big.Int, error) {
+		suggested := node.suggested
+		refreshed <- suggested
+		return big.NewInt(suggested), nil
+	}
+	tick := make(chan time.Time)
+	cache.after = func(d time.Duration) <-chan time.Time {
+		assert.Equal(t, time.Minute, d)
+		return tick
+	}
+
+	cache.start()
+	defer cache.close()
+
+	assert.Equal(t, int64(100), <-refreshed, "price is fetched on start")
+	node.suggested = 200
+	tick <- node.time.Add(time.Minute)
+	assert.Equal(t, int64(200), <-refreshed, "price is fetched on interval")
+}
@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/ipfsutils"
+	log "github.com/sirupsen/logrus"
+)
+
+// metadataRefreshInterval is an interval between attempts to read service
+// metadata from IPFS when daemon is started using cached metadata.
+const metadataRefreshInterval = 30 * time.Second
+
+// metadataLoader returns JSON of the service metadata.
+type metadataLoader func() (jsonData string, err error)
+
+func readServiceMetaDataJsonFromIPFS() (jsonData string, err error) {
+	uri := []byte(pinnedMetadataCID())
+	if len(uri) == 0 {
+		uri, err = readMetaDataUriFromRegistry(config.GetString(config.OrganizationId), config.GetString(config.ServiceId))
+		if err != nil {
+			return
+		}
+	}
+	return ipfsutils.ReadIpfsFile(FormatHash(string(uri)))
+}
+
+// serviceMetaDataWithOfflineStart loads service metadata and saves it into
+// cacheFile. If metadata cannot be loaded then last known metadata is read
+// from cacheFile and loading is repeated in background each refreshInterval
+// until it succeeds.
+func serviceMetaDataWithOfflineStart(load metadataLoader, cacheFile string, refreshInterval time.Duration) (metadata *ServiceMetadata, err error) {
+	jsonData, loadErr := load()
+	if loadErr == nil {
+		metadata, err = InitServiceMetaDataFromJson(jsonData)
+		if err != nil {
+			return
+		}
+		saveMetaDataCache(cacheFile, jsonData)
+		return
+	}
+
+	log.WithError(loadErr).WithField("cacheFile", cacheFile).Warn("Cannot load service metadata, starting using cached metadata")
+	cached, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load service metadata: %v, cannot read cached metadata: %v", loadErr, err)
+	}
+	metadata, err = InitServiceMetaDataFromJson(string(cached))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load service metadata: %v, cannot parse cached metadata: %v", loadErr, err)
+	}
+
+	go metadata.refresh(load, cacheFile, refreshInterval)
+	return
+}
+
+func (metaData *ServiceMetadata) refresh(load metadataLoader, cacheFile string, refreshInterval time.Duration) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		jsonData, err := load()
+		if err != nil {
+			log.WithError(err).Debug("Cannot load service metadata, will retry")
+			continue
+		}
+		fresh, err := InitServiceMetaDataFromJson(jsonData)
+		if err != nil {
+			log.WithError(err).Error("Cannot parse loaded service metadata, will retry")
+			continue
+		}
+
+		metaData.update(fresh)
+		saveMetaDataCache(cacheFile, jsonData)
+		log.Info("Service metadata is loaded, cached metadata is replaced")
+		return
+	}
+}
+
+func (metaData *ServiceMetadata) update(fresh *ServiceMetadata) {
+	metaData.lock.Lock()
+	defer metaData.lock.Unlock()
+
+	metaData.Version = fresh.Version
+	metaData.DisplayName = fresh.DisplayName
+	metaData.Encoding = fresh.Encoding
+	metaData.ServiceType = fresh.ServiceType
+	metaData.PaymentExpirationThreshold = fresh.PaymentExpirationThreshold
+	metaData.ModelIpfsHash = fresh.ModelIpfsHash
+	metaData.MpeAddress = fresh.MpeAddress
+	metaData.Pricing = fresh.Pricing
+	metaData.Groups = fresh.Groups
+	metaData.Endpoints = fresh.Endpoints
+	metaData.daemonReplicaGroupID = fresh.daemonReplicaGroupID
+	metaData.daemonGroupName = fresh.daemonGroupName
+	metaData.daemonEndPoint = fresh.daemonEndPoint
+	metaData.recipientPaymentAddress = fresh.recipientPaymentAddress
+	metaData.multiPartyEscrowAddress = fresh.multiPartyEscrowAddress
+}
+
+func saveMetaDataCache(cacheFile string, jsonData string) {
+	err := ioutil.WriteFile(cacheFile, []byte(jsonData), 0600)
+	if err != nil {
+		log.WithError(err).WithField("cacheFile", cacheFile).Warn("Cannot save service metadata cache")
+	}
+}
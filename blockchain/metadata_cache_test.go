@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errIpfsIsUnreachable = errors.New("ipfs is unreachable")
+
+type testMetadataLoader struct {
+	mutex    sync.Mutex
+	jsonData string
+	err      error
+}
+
+func (loader *testMetadataLoader) set(jsonData string, err error) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	loader.jsonData, loader.err = jsonData, err
+}
+
+func (loader *testMetadataLoader) load() (string, error) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	return loader.jsonData, loader.err
+}
+
+func tempMetadataCacheFile(t *testing.T) (cacheFile string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "metadata-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "service_metadata.cache.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestServiceMetaDataWithOfflineStartSavesCache(t *testing.T) {
+	cacheFile, cleanup := tempMetadataCacheFile(t)
+	defer cleanup()
+	loader := &testMetadataLoader{jsonData: testJsonData}
+
+	metadata, err := serviceMetaDataWithOfflineStart(loader.load, cacheFile, time.Hour)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Example1", metadata.GetDisplayName())
+	cached, err := ioutil.ReadFile(cacheFile)
+	assert.Nil(t, err)
+	assert.Equal(t, testJsonData, string(cached))
+}
+
+func TestServiceMetaDataWithOfflineStartUsesCache(t *testing.T) {
+	cacheFile, cleanup := tempMetadataCacheFile(t)
+	defer cleanup()
+	assert.Nil(t, ioutil.WriteFile(cacheFile, []byte(testJsonData), 0600))
+	loader := &testMetadataLoader{err: errIpfsIsUnreachable}
+
+	metadata, err := serviceMetaDataWithOfflineStart(loader.load, cacheFile, time.Millisecond)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Example1", metadata.GetDisplayName())
+	assert.Equal(t, "default_group", metadata.GetDaemonGroupName())
+
+	freshJsonData := strings.Replace(testJsonData, "Example1", "Example2", 1)
+	loader.set(freshJsonData, nil)
+	assert.True(t, waitFor(func() bool { return metadata.GetDisplayName() == "Example2" }), "metadata is not refreshed")
+	assert.True(t, waitFor(func() bool {
+		cached, _ := ioutil.ReadFile(cacheFile)
+		return string(cached) == freshJsonData
+	}), "cache is not updated")
+}
+
+func TestServiceMetaDataWithOfflineStartNoCache(t *testing.T) {
+	cacheFile, cleanup := tempMetadataCacheFile(t)
+	defer cleanup()
+	loader := &testMetadataLoader{err: errIpfsIsUnreachable}
+
+	_, err := serviceMetaDataWithOfflineStart(loader.load, cacheFile, time.Hour)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cannot load service metadata: ipfs is unreachable, cannot read cached metadata")
+}
+
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
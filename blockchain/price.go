@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/singnet/snet-daemon/ipfsutils"
+)
+
+// MetadataPriceProvider reads price of the service call from the service
+// metadata which is published in Registry or pinned by CID.
+type MetadataPriceProvider struct {
+	organizationID string
+	serviceID      string
+	metadataCID    string
+	readRegistry   func(organizationID, serviceID string) (uri []byte, err error)
+	readIpfsFile   func(hash string) (content string, err error)
+}
+
+// NewMetadataPriceProvider returns price provider for the given service. If
+// metadataCID is not empty then metadata is read by this CID and Registry is
+// not used.
+func NewMetadataPriceProvider(organizationID, serviceID, metadataCID string) *MetadataPriceProvider {
+	return &MetadataPriceProvider{
+		organizationID: organizationID,
+		serviceID:      serviceID,
+		metadataCID:    metadataCID,
+		readRegistry:   readMetaDataUriFromRegistry,
+		readIpfsFile:   ipfsutils.ReadIpfsFile,
+	}
+}
+
+func (provider *MetadataPriceProvider) String() string {
+	if provider.metadataCID != "" {
+		return fmt.Sprintf("metadata(%v/%v@%v)", provider.organizationID, provider.serviceID, provider.metadataCID)
+	}
+	return fmt.Sprintf("metadata(%v/%v)", provider.organizationID, provider.serviceID)
+}
+
+// GetPriceInCogs returns current price of the method call. Service metadata
+// supports fixed price model only so the same price is returned for each
+// method.
+func (provider *MetadataPriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	uri := []byte(provider.metadataCID)
+	if len(uri) == 0 {
+		uri, err = provider.readRegistry(provider.organizationID, provider.serviceID)
+		if err != nil {
+			return
+		}
+	}
+
+	jsondata, err := provider.readIpfsFile(FormatHash(string(uri)))
+	if err != nil {
+		return
+	}
+
+	metadata := &ServiceMetadata{}
+	err = json.Unmarshal([]byte(jsondata), metadata)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse metadata of service %v/%v: %v", provider.organizationID, provider.serviceID, err)
+	}
+	if metadata.Pricing.PriceInCogs == nil {
+		return nil, fmt.Errorf("price is not set in metadata of service %v/%v", provider.organizationID, provider.serviceID)
+	}
+
+	return metadata.Pricing.PriceInCogs, nil
+}
+
+// registryClient is a connection to the Ethereum node which is shared by
+// metadata price providers, so price lookups don't dial the node each time.
+var registryClient = &sharedEthereumClient{dial: GetEthereumClient}
+
+// sharedEthereumClient dials Ethereum node on the first use and keeps the
+// client until it fails.
+type sharedEthereumClient struct {
+	lock   sync.Mutex
+	dial   func() (*EthereumClient, error)
+	client *EthereumClient
+}
+
+func (shared *sharedEthereumClient) get() (client *EthereumClient, err error) {
+	shared.lock.Lock()
+	defer shared.lock.Unlock()
+
+	if shared.client == nil {
+		if shared.client, err = shared.dial(); err != nil {
+			return nil, err
+		}
+	}
+	return shared.client, nil
+}
+
+// reset closes client after request failure, next get dials the node again.
+func (shared *sharedEthereumClient) reset(client *EthereumClient) {
+	shared.lock.Lock()
+	defer shared.lock.Unlock()
+
+	if shared.client == client {
+		shared.client = nil
+		client.Close()
+	}
+}
+
+func readMetaDataUriFromRegistry(organizationID, serviceID string) (uri []byte, err error) {
+	ethClient, err := registryClient.get()
+	if err != nil {
+		return
+	}
+
+	reg, err := NewRegistryCaller(getRegistryAddressKey(), ethClient.EthClient)
+	if err != nil {
+		return nil, fmt.Errorf("error instantiating Registry contract: %v", err)
+	}
+
+	serviceRegistration, err := reg.GetServiceRegistrationById(nil, StringToBytes32(organizationID), StringToBytes32(serviceID))
+	if err != nil {
+		registryClient.reset(ethClient)
+		return nil, fmt.Errorf("error retrieving registration of service %v/%v: %v", organizationID, serviceID, err)
+	}
+
+	return serviceRegistration.MetadataURI[:], nil
+}
@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPinnedMetadataCID   = "QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ"
+	testRegistryMetadataCID = "QmaGnQ3iVZPuPwdam2rEeQcCSoCYRpxjnZhQ6Z2oeeRSrp"
+)
+
+// newTestMetadataPriceProvider returns provider which reads metadata from
+// the fake IPFS and remembers hashes which were requested.
+func newTestMetadataPriceProvider(metadataCID string, requested *[]string) *MetadataPriceProvider {
+	provider := NewMetadataPriceProvider("org", "service", metadataCID)
+	provider.readRegistry = func(organizationID, serviceID string) ([]byte, error) {
+		*requested = append(*requested, "registry")
+		return []byte(IpfsPrefix + testRegistryMetadataCID), nil
+	}
+	provider.readIpfsFile = func(hash string) (string, error) {
+		*requested = append(*requested, hash)
+		return testJsonData, nil
+	}
+	return provider
+}
+
+func TestMetadataPriceProviderPinnedCID(t *testing.T) {
+	var requested []string
+	provider := newTestMetadataPriceProvider(testPinnedMetadataCID, &requested)
+
+	price, err := provider.GetPriceInCogs("/Service/Method")
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(12000000), price)
+	assert.Equal(t, []string{testPinnedMetadataCID}, requested, "Registry is not used")
+	assert.Equal(t, "metadata(org/service@"+testPinnedMetadataCID+")", provider.String())
+}
+
+func TestMetadataPriceProviderRegistry(t *testing.T) {
+	var requested []string
+	provider := newTestMetadataPriceProvider("", &requested)
+
+	price, err := provider.GetPriceInCogs("/Service/Method")
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(12000000), price)
+	assert.Equal(t, []string{"registry", testRegistryMetadataCID}, requested)
+	assert.Equal(t, "metadata(org/service)", provider.String())
+}
+
+func TestMetadataPriceProviderRegistryError(t *testing.T) {
+	var requested []string
+	provider := newTestMetadataPriceProvider("", &requested)
+	provider.readRegistry = func(organizationID, serviceID string) ([]byte, error) {
+		return nil, errors.New("registry is unavailable")
+	}
+
+	_, err := provider.GetPriceInCogs("/Service/Method")
+
+	assert.Equal(t, errors.New("registry is unavailable"), err)
+	assert.Empty(t, requested)
+}
+
+func TestSharedEthereumClientDialsOnce(t *testing.T) {
+	dials := 0
+	shared := &sharedEthereumClient{dial: func() (*EthereumClient, error) {
+		dials++
+		client := rpc.DialInProc(rpc.NewServer())
+		return &EthereumClient{RawClient: client, EthClient: ethclient.NewClient(client)}, nil
+	}}
+
+	first, err := shared.get()
+	assert.Nil(t, err)
+	second, _ := shared.get()
+	assert.True(t, first == second, "client is reused")
+	assert.Equal(t, 1, dials)
+
+	shared.reset(first)
+	third, _ := shared.get()
+	assert.False(t, first == third, "failed client is replaced")
+	assert.Equal(t, 2, dials)
+}
+
+func TestSharedEthereumClientDialError(t *testing.T) {
+	shared := &sharedEthereumClient{dial: func() (*EthereumClient, error) {
+		return nil, errors.New("connection refused")
+	}}
+
+	_, err := shared.get()
+
+	assert.EqualError(t, err, "connection refused")
+}
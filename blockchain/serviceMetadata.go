@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"strings"
+	"sync"
 )
 
 const IpfsPrefix = "ipfs://"
@@ -41,19 +42,29 @@ type ServiceMetadata struct {
 	daemonEndPoint          string
 	recipientPaymentAddress common.Address
 	multiPartyEscrowAddress common.Address
+	// lock guards metadata fields when metadata is refreshed in background,
+	// see metadata_offline_start
+	lock sync.RWMutex
 }
 
 func getRegistryAddressKey() common.Address {
-	address := config.GetString(config.RegistryAddressKey)
-	return common.HexToAddress(address)
+	return config.GetRegistryAddress()
 }
 
 func ServiceMetaData() *ServiceMetadata {
 	var metadata *ServiceMetadata
 	var err error
 	if config.GetBool(config.BlockchainEnabledKey) {
-		ipfsHash := string(getMetaDataUrifromRegistry())
-		metadata, err = GetServiceMetaDataFromIPFS(FormatHash(ipfsHash))
+		if config.GetBool(config.MetadataOfflineStartKey) {
+			metadata, err = serviceMetaDataWithOfflineStart(readServiceMetaDataJsonFromIPFS,
+				config.GetString(config.MetadataCacheFileKey), metadataRefreshInterval)
+		} else {
+			ipfsHash := pinnedMetadataCID()
+			if ipfsHash == "" {
+				ipfsHash = string(getMetaDataUrifromRegistry())
+			}
+			metadata, err = GetServiceMetaDataFromIPFS(FormatHash(ipfsHash))
+		}
 	} else {
 		//TO DO, have a snetd command to create a default metadata json file, for now just read from a local file
 		// when block chain reading is disabled
@@ -66,6 +77,37 @@ func ServiceMetaData() *ServiceMetadata {
 	return metadata
 }
 
+// ServiceMetaDataOf returns metadata of the given service. If metadataCID is
+// not empty then metadata is read by this CID and Registry is not used. When
+// blockchain is disabled metadata is read from the local
+// service_metadata.json file which is shared by all services.
+func ServiceMetaDataOf(organizationID, serviceID, metadataCID string) (*ServiceMetadata, error) {
+	if !config.GetBool(config.BlockchainEnabledKey) {
+		return readServiceMetaDataFromLocalFile("service_metadata.json")
+	}
+	return readServiceMetaData(organizationID, serviceID, metadataCID, readMetaDataUriFromRegistry, ipfsutils.ReadIpfsFile)
+}
+
+func readServiceMetaData(organizationID, serviceID, metadataCID string,
+	readRegistry func(organizationID, serviceID string) ([]byte, error),
+	readIpfsFile func(hash string) (string, error)) (metadata *ServiceMetadata, err error) {
+
+	uri := []byte(metadataCID)
+	if len(uri) == 0 {
+		if uri, err = readRegistry(organizationID, serviceID); err != nil {
+			return
+		}
+	}
+	jsondata, err := readIpfsFile(FormatHash(string(uri)))
+	if err != nil {
+		return
+	}
+	if metadata, err = InitServiceMetaDataFromJson(jsondata); err != nil {
+		return nil, fmt.Errorf("cannot parse metadata of service %v/%v: %v", organizationID, serviceID, err)
+	}
+	return metadata, nil
+}
+
 func readServiceMetaDataFromLocalFile(filename string) (*ServiceMetadata, error) {
 	file, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -101,6 +143,23 @@ func getMetaDataUrifromRegistry() []byte {
 	return serviceRegistration.MetadataURI[:]
 }
 
+// pinnedMetadataCID returns service_metadata_cid of the service set by
+// organization_id and service_id, empty string means that metadata URI should
+// be read from Registry.
+func pinnedMetadataCID() string {
+	services, err := config.GetServices()
+	if err != nil {
+		return ""
+	}
+	for _, service := range services {
+		if service.OrganizationID == config.GetString(config.OrganizationId) &&
+			service.ServiceID == config.GetString(config.ServiceId) {
+			return service.MetadataCID
+		}
+	}
+	return ""
+}
+
 func GetServiceMetaDataFromIPFS(hash string) (*ServiceMetadata, error) {
 	jsondata := ipfsutils.GetIpfsFile(hash)
 	return InitServiceMetaDataFromJson(jsondata)
@@ -180,44 +239,66 @@ func setDaemonGroupIDAndPaymentAddress(metaData *ServiceMetadata) error {
 }
 
 func (metaData *ServiceMetadata) GetDaemonEndPoint() string {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.daemonEndPoint
 }
 
 func (metaData *ServiceMetadata) GetMpeAddress() common.Address {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.multiPartyEscrowAddress
 }
 
 func (metaData *ServiceMetadata) GetPaymentExpirationThreshold() *big.Int {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.PaymentExpirationThreshold
 }
 
 func (metaData *ServiceMetadata) GetPriceInCogs() *big.Int {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.Pricing.PriceInCogs
 }
 
 func (metaData *ServiceMetadata) GetDaemonGroupName() string {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.daemonGroupName
 }
 func (metaData *ServiceMetadata) GetWireEncoding() string {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.Encoding
 }
 
 func (metaData *ServiceMetadata) GetVersion() int {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.Version
 }
 
 func (metaData *ServiceMetadata) GetServiceType() string {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.ServiceType
 }
 
 func (metaData *ServiceMetadata) GetDisplayName() string {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.DisplayName
 }
 
 func (metaData *ServiceMetadata) GetDaemonGroupID() [32]byte {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.daemonReplicaGroupID
 }
 
 func (metaData *ServiceMetadata) GetPaymentAddress() common.Address {
+	metaData.lock.RLock()
+	defer metaData.lock.RUnlock()
 	return metaData.recipientPaymentAddress
 }
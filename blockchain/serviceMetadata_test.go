@@ -58,3 +58,37 @@ func TestReadServiceMetaDataFromLocalFile(t *testing.T) {
 	assert.Equal(t, metadata.Version, 1)
 
 }
+
+func TestReadServiceMetaDataPinnedCID(t *testing.T) {
+	var requested []string
+	metadata, err := readServiceMetaData("org", "service", testPinnedMetadataCID,
+		func(organizationID, serviceID string) ([]byte, error) {
+			requested = append(requested, "registry")
+			return []byte(IpfsPrefix + testRegistryMetadataCID), nil
+		},
+		func(hash string) (string, error) {
+			requested = append(requested, hash)
+			return testJsonData, nil
+		})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, requested, []string{testPinnedMetadataCID})
+	assert.Equal(t, metadata.GetPaymentAddress(), common.HexToAddress("0xD6C6344f1D122dC6f4C1782A4622B683b9008081"))
+}
+
+func TestReadServiceMetaDataRegistry(t *testing.T) {
+	var requested []string
+	metadata, err := readServiceMetaData("org", "service", "",
+		func(organizationID, serviceID string) ([]byte, error) {
+			requested = append(requested, "registry")
+			return []byte(IpfsPrefix + testRegistryMetadataCID), nil
+		},
+		func(hash string) (string, error) {
+			requested = append(requested, hash)
+			return strings.Replace(testJsonData, "0xD6C6344f1D122dC6f4C1782A4622B683b9008081", "0x52653A9091b5d5021bed06c5118D24b23620c529", -1), nil
+		})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, requested, []string{"registry", testRegistryMetadataCID})
+	assert.Equal(t, metadata.GetPaymentAddress(), common.HexToAddress("0x52653A9091b5d5021bed06c5118D24b23620c529"))
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/viper"
+)
+
+// CanonicalizeAddress checks that string is a valid Ethereum address and
+// returns it in EIP-55 checksum form. Lower and upper case addresses are
+// accepted as is, mixed case address should have correct checksum.
+func CanonicalizeAddress(address string) (canonical string, err error) {
+	if !common.IsHexAddress(address) {
+		return "", fmt.Errorf("'%v' is not a valid Ethereum address", address)
+	}
+
+	canonical = common.HexToAddress(address).Hex()
+
+	hex := strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) && "0x"+hex != canonical {
+		return "", fmt.Errorf("address '%v' has incorrect EIP-55 checksum", address)
+	}
+
+	return canonical, nil
+}
+
+// canonicalizeAddressFromVip canonicalizes address from the config. If
+// strict_address_checksum is true only addresses in EIP-55 checksum form are
+// accepted.
+func canonicalizeAddressFromVip(config *viper.Viper, address string) (canonical string, err error) {
+	canonical, err = CanonicalizeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if config.GetBool(StrictAddressChecksumKey) && address != canonical {
+		return "", fmt.Errorf("address '%v' is not in EIP-55 checksum form, expected '%v'", address, canonical)
+	}
+	return canonical, nil
+}
+
+// GetRegistryAddress returns address of the Registry contract.
+func GetRegistryAddress() common.Address {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	address, _ := GetRegistryAddressFromVip(vip)
+	return address
+}
+
+// GetRegistryAddressFromVip returns address of the Registry contract using
+// viper config. Error is returned if address is incorrect.
+func GetRegistryAddressFromVip(config *viper.Viper) (address common.Address, err error) {
+	canonical, err := canonicalizeAddressFromVip(config, config.GetString(RegistryAddressKey))
+	if err != nil {
+		return address, fmt.Errorf("incorrect %v: %v", RegistryAddressKey, err)
+	}
+	return common.HexToAddress(canonical), nil
+}
+
+// GetBlockedSenders returns addresses of the senders which calls are
+// rejected.
+func GetBlockedSenders() (senders []common.Address, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return GetBlockedSendersFromVip(vip)
+}
+
+// GetBlockedSendersFromVip returns addresses of the blocked_senders list.
+// Error is returned if any address is incorrect.
+func GetBlockedSendersFromVip(config *viper.Viper) (senders []common.Address, err error) {
+	for _, address := range config.GetStringSlice(BlockedSendersKey) {
+		canonical, err := canonicalizeAddressFromVip(config, address)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect address in %v: %v", BlockedSendersKey, err)
+		}
+		senders = append(senders, common.HexToAddress(canonical))
+	}
+	return senders, nil
+}
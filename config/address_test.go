@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const checksumAddress = "0x4E74FefA82E83E0964f0D9f53c68e03f7298a8b2"
+
+func TestCanonicalizeAddress(t *testing.T) {
+	for _, address := range []string{
+		"0x4e74fefa82e83e0964f0d9f53c68e03f7298a8b2",
+		"0x4E74FEFA82E83E0964F0D9F53C68E03F7298A8B2",
+		checksumAddress,
+	} {
+		canonical, err := CanonicalizeAddress(address)
+
+		assert.Nil(t, err)
+		assert.Equal(t, checksumAddress, canonical)
+	}
+}
+
+func TestCanonicalizeAddressIncorrectChecksum(t *testing.T) {
+	_, err := CanonicalizeAddress("0x4e74FefA82e83e0964f0d9F53C68e03f7298a8b2")
+
+	assert.Equal(t, "address '0x4e74FefA82e83e0964f0d9F53C68e03f7298a8b2' has incorrect EIP-55 checksum", err.Error())
+}
+
+func TestCanonicalizeAddressIncorrectAddress(t *testing.T) {
+	_, err := CanonicalizeAddress("0x4e74fefa82e83e")
+
+	assert.Equal(t, "'0x4e74fefa82e83e' is not a valid Ethereum address", err.Error())
+}
+
+func TestGetRegistryAddress(t *testing.T) {
+	assert.Equal(t, common.HexToAddress(checksumAddress), GetRegistryAddress())
+	assert.Equal(t, checksumAddress, GetRegistryAddress().Hex())
+}
+
+func TestGetRegistryAddressFromVipIncorrectAddress(t *testing.T) {
+	config := viper.New()
+	config.Set(RegistryAddressKey, "0x123")
+
+	_, err := GetRegistryAddressFromVip(config)
+
+	assert.Equal(t, "incorrect registry_address_key: '0x123' is not a valid Ethereum address", err.Error())
+}
+
+func strictAddressConfig(strict bool, address string) *viper.Viper {
+	config := viper.New()
+	config.Set(StrictAddressChecksumKey, strict)
+	config.Set(RegistryAddressKey, address)
+	return config
+}
+
+func TestGetRegistryAddressFromVipStrictChecksumAddress(t *testing.T) {
+	address, err := GetRegistryAddressFromVip(strictAddressConfig(true, checksumAddress))
+
+	assert.Nil(t, err)
+	assert.Equal(t, checksumAddress, address.Hex())
+}
+
+func TestGetRegistryAddressFromVipStrictLowerCaseAddress(t *testing.T) {
+	_, err := GetRegistryAddressFromVip(strictAddressConfig(true, "0x4e74fefa82e83e0964f0d9f53c68e03f7298a8b2"))
+
+	assert.Equal(t, "incorrect registry_address_key: address '0x4e74fefa82e83e0964f0d9f53c68e03f7298a8b2' is not in EIP-55 checksum form, expected '0x4E74FefA82E83E0964f0D9f53c68e03f7298a8b2'", err.Error())
+}
+
+func TestGetRegistryAddressFromVipNotStrictLowerCaseAddress(t *testing.T) {
+	address, err := GetRegistryAddressFromVip(strictAddressConfig(false, "0x4e74fefa82e83e0964f0d9f53c68e03f7298a8b2"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, checksumAddress, address.Hex())
+}
+
+func TestGetRegistryAddressFromVipIncorrectChecksum(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		_, err := GetRegistryAddressFromVip(strictAddressConfig(strict, "0x4e74FefA82e83e0964f0d9F53C68e03f7298a8b2"))
+
+		assert.Equal(t, "incorrect registry_address_key: address '0x4e74FefA82e83e0964f0d9F53C68e03f7298a8b2' has incorrect EIP-55 checksum", err.Error())
+	}
+}
+
+func TestGetBlockedSendersFromVip(t *testing.T) {
+	config := viper.New()
+	config.Set(BlockedSendersKey, []string{checksumAddress, "0xf17f52151ebef6c7334fad080c5704d77216b732"})
+
+	senders, err := GetBlockedSendersFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{common.HexToAddress(checksumAddress), common.HexToAddress("0xf17f52151EbEF6C7334FAD080c5704D77216b732")}, senders)
+}
+
+func TestGetBlockedSendersFromVipNotSet(t *testing.T) {
+	senders, err := GetBlockedSendersFromVip(viper.New())
+
+	assert.Nil(t, err)
+	assert.Empty(t, senders)
+}
+
+func TestGetBlockedSendersFromVipIncorrectAddress(t *testing.T) {
+	config := viper.New()
+	config.Set(BlockedSendersKey, []string{checksumAddress, "0x123"})
+
+	_, err := GetBlockedSendersFromVip(config)
+
+	assert.Equal(t, "incorrect address in blocked_senders: '0x123' is not a valid Ethereum address", err.Error())
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// CertificateWatcher keeps X509 key pair loaded from the certificate and
+// key files and reloads it when files are changed. It allows replacing
+// certificate without restarting the daemon, for instance after renewal.
+type CertificateWatcher struct {
+	certPath    string
+	keyPath     string
+	certificate atomic.Value
+	watcher     *fsnotify.Watcher
+}
+
+// NewCertificateWatcher loads key pair and starts watching certificate and
+// key files. Key pair is reloaded on each file change; if new files cannot
+// be loaded, for example when only one of them is updated yet, then
+// previous key pair is kept.
+func NewCertificateWatcher(certPath, keyPath string) (certWatcher *CertificateWatcher, err error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return
+	}
+
+	// directories are watched to not lose the files when they are replaced
+	// by renaming new versions
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+	for _, dir := range []string{filepath.Dir(certPath), filepath.Dir(keyPath)} {
+		if err = watcher.Add(dir); err != nil {
+			watcher.Close()
+			return
+		}
+	}
+
+	certWatcher = &CertificateWatcher{
+		certPath: certPath,
+		keyPath:  keyPath,
+		watcher:  watcher,
+	}
+	certWatcher.certificate.Store(&cert)
+	go certWatcher.watch()
+
+	return certWatcher, nil
+}
+
+// GetCertificate returns the latest loaded key pair, it is intended to be
+// used as tls.Config.GetCertificate.
+func (certWatcher *CertificateWatcher) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return certWatcher.certificate.Load().(*tls.Certificate), nil
+}
+
+// Close stops watching certificate files.
+func (certWatcher *CertificateWatcher) Close() {
+	certWatcher.watcher.Close()
+}
+
+func (certWatcher *CertificateWatcher) watch() {
+	for {
+		select {
+		case event, ok := <-certWatcher.watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Clean(event.Name)
+			if (name != filepath.Clean(certWatcher.certPath) && name != filepath.Clean(certWatcher.keyPath)) ||
+				event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			certWatcher.reload()
+		case err, ok := <-certWatcher.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).Error("Error watching SSL certificate files")
+		}
+	}
+}
+
+func (certWatcher *CertificateWatcher) reload() {
+	cert, err := tls.LoadX509KeyPair(certWatcher.certPath, certWatcher.keyPath)
+	if err != nil {
+		log.WithError(err).WithField("certPath", certWatcher.certPath).Warn("Cannot reload SSL certificate, previous certificate is kept")
+		return
+	}
+
+	certWatcher.certificate.Store(&cert)
+	log.WithField("certPath", certWatcher.certPath).Info("SSL certificate reloaded")
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificate writes self-signed certificate with given serial
+// number and its key to the files.
+func writeTestCertificate(t *testing.T, certPath, keyPath string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// key is written first to not have new certificate with old key
+	writeFileAtomically(t, keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	writeFileAtomically(t, certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}))
+}
+
+func writeFileAtomically(t *testing.T, path string, data []byte) {
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshakeSerial returns serial number of the certificate presented by
+// server.
+func handshakeSerial(t *testing.T, address string) int64 {
+	conn, err := tls.Dial("tcp", address, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func startTLSServer(t *testing.T, certWatcher *CertificateWatcher) net.Listener {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: certWatcher.GetCertificate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return listener
+}
+
+func waitForSerial(t *testing.T, address string, expected int64) (serial int64) {
+	for i := 0; i < 100; i++ {
+		serial = handshakeSerial(t, address)
+		if serial == expected {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return
+}
+
+func newTestCertificateFiles(t *testing.T) (dir, certPath, keyPath string) {
+	dir, err := ioutil.TempDir("", "snet-daemon-certificate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeTestCertificate(t, certPath, keyPath, 1)
+	return
+}
+
+func TestCertificateWatcherReloadsCertificate(t *testing.T) {
+	dir, certPath, keyPath := newTestCertificateFiles(t)
+	defer os.RemoveAll(dir)
+	certWatcher, err := NewCertificateWatcher(certPath, keyPath)
+	assert.Nil(t, err)
+	defer certWatcher.Close()
+	listener := startTLSServer(t, certWatcher)
+	defer listener.Close()
+
+	assert.Equal(t, int64(1), handshakeSerial(t, listener.Addr().String()))
+	writeTestCertificate(t, certPath, keyPath, 2)
+
+	assert.Equal(t, int64(2), waitForSerial(t, listener.Addr().String(), 2))
+}
+
+func TestCertificateWatcherKeepsCertificateOnError(t *testing.T) {
+	dir, certPath, keyPath := newTestCertificateFiles(t)
+	defer os.RemoveAll(dir)
+	certWatcher, err := NewCertificateWatcher(certPath, keyPath)
+	assert.Nil(t, err)
+	defer certWatcher.Close()
+	listener := startTLSServer(t, certWatcher)
+	defer listener.Close()
+
+	writeFileAtomically(t, certPath, []byte("not a certificate"))
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, int64(1), handshakeSerial(t, listener.Addr().String()))
+}
+
+func TestNewCertificateWatcherIncorrectFiles(t *testing.T) {
+	_, err := NewCertificateWatcher("/non-existent/server.crt", "/non-existent/server.key")
+
+	assert.NotNil(t, err)
+}
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"net/url"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -18,47 +22,209 @@ const (
 	AutoSSLDomainKey     = "auto_ssl_domain"
 	AutoSSLCacheDirKey   = "auto_ssl_cache_dir"
 	BlockchainEnabledKey = "blockchain_enabled"
+	BlockedSendersKey    = "blocked_senders"
 	BurstSize            = "burst_size"
+	ClaimWebhookURLsKey  = "claim_webhook_urls"
 	ConfigPathKey        = "config_path"
 
+	AcceptedCurrencyKey            = "accepted_currency"
+	AllowZeroBalanceChannelsKey    = "allow_zero_balance_channels"
+	AutoClaimMaxChannelAgeKey      = "auto_claim_max_channel_age"
+	AutoClaimMinAmountKey          = "auto_claim_min_amount"
+	CacheableMethodsKey            = "cacheable_methods"
+	ChannelEventDedupBlocksKey     = "channel_event_dedup_blocks"
+	ChannelEventsPollIntervalKey   = "channel_events_poll_interval"
+	ClaimRevertPolicyKey           = "claim_revert_policy"
+	ConnectionIdleTimeoutKey       = "connection_idle_timeout"
+	CurrencyRatesKey               = "currency_rates"
 	DaemonTypeKey                  = "daemon_type"
 	DaemonEndPoint                 = "daemon_end_point"
+	DebugLogBodiesKey              = "debug_log_bodies"
+	DebugLogBodyLimitKey           = "debug_log_body_limit"
+	DiagnosticsEnabledKey          = "diagnostics_enabled"
+	DiagnosticsTokenKey            = "diagnostics_token"
+	DisabledMethodsKey             = "disabled_methods"
+	EnabledMethodsKey              = "enabled_methods"
+	EndpointSelectionKey           = "endpoint_selection"
 	EthereumJsonRpcEndpointKey     = "ethereum_json_rpc_endpoint"
+	EtcdStartupTimeoutKey          = "etcd_startup_timeout"
 	ExecutablePathKey              = "executable_path"
+	ExpectedChainIDKey             = "expected_chain_id"
+	ExpectedDaemonAddressKey       = "expected_daemon_address"
+	FeaturesKey                    = "features"
+	GasPriceMultiplierPercentKey   = "gas_price_multiplier_percent"
+	GasPriceRefreshIntervalKey     = "gas_price_refresh_interval"
+	GrpcMaxConcurrentStreamsKey    = "grpc_max_concurrent_streams"
+	HTTPMaxRequestBytesKey         = "http_max_request_bytes"
+	HTTPRequestSchemasKey          = "http_request_schemas"
+	HandlerQueueSizeKey            = "handler_queue_size"
+	HandlerWorkerCountKey          = "handler_worker_count"
 	HdwalletIndexKey               = "hdwallet_index"
 	HdwalletMnemonicKey            = "hdwallet_mnemonic"
+	IdleBurstSizeKey               = "idle_burst_size"
+	IdleThresholdKey               = "idle_threshold"
+	IncomeValidationModeKey        = "income_validation_mode"
+	IncomeValidationOrderKey       = "income_validation_order"
+	IncomeValidationTimeoutKey     = "income_validation_timeout"
+	InvoiceIDHeaderKey             = "invoice_id_header"
 	IpfsEndPoint                   = "ipfs_end_point"
+	JobValueHeaderKey              = "job_value_header"
 	LogKey                         = "log"
+	MaintenanceWindowsKey          = "maintenance_windows"
+	MaxBlockLagKey                 = "max_block_lag"
+	MaxChannelExpiryBlocksKey      = "max_channel_expiry_blocks"
+	MaxClockSkewKey                = "max_clock_skew"
+	MaxConcurrentClaimsKey         = "max_concurrent_claims"
+	MaxConnectionsKey              = "max_connections"
+	MaxMetadataBytesKey            = "max_metadata_bytes"
+	MetadataCacheFileKey           = "metadata_cache_file"
+	MetadataCacheRefreshJitterKey  = "metadata_cache_refresh_jitter"
+	MetadataOfflineStartKey        = "metadata_offline_start"
+	MinChannelDepositKey           = "min_channel_deposit"
+	MinIncomeKey                   = "min_income"
+	OfflineAuthModeKey             = "offline_auth_mode"
+	OfflineAuthTokensKey           = "offline_auth_tokens"
 	OrganizationId                 = "organization_id"
+	OrganizationIDHeaderKey        = "organization_id_header"
 	ServiceId                      = "service_id"
+	ServiceIDHeaderKey             = "service_id_header"
+	ServiceMetadataCIDKey          = "service_metadata_cid"
+	ShutdownOnConfigRemovalKey     = "shutdown_on_config_removal"
+	StreamingIncomeValidationKey   = "streaming_income_validation"
 	PassthroughEnabledKey          = "passthrough_enabled"
 	PassthroughEndpointKey         = "passthrough_endpoint"
+	PassthroughFlushOnTimeoutKey   = "passthrough_flush_on_timeout"
+	PassthroughMaxResponseBytesKey = "passthrough_max_response_bytes"
+	PassthroughMethodTimeoutsKey   = "passthrough_method_timeouts"
+	PassthroughStreamingMethodsKey = "passthrough_streaming_methods"
+	PassthroughTimeoutKey          = "passthrough_timeout"
+	PerSenderSpendCapKey           = "per_sender_spend_cap"
+	PerSenderSpendCapPeriodKey     = "per_sender_spend_cap_period"
+	PersistRateLimitsKey           = "persist_rate_limits"
+	PriceCacheTtlKey               = "price_cache_ttl"
+	PriceCurrencyKey               = "price_currency"
+	PricingFileKey                 = "pricing_file"
 	PrivateKeyKey                  = "private_key"
 	RateLimitPerMinute             = "rate_limit_per_minute"
+	ReconcileConcurrencyKey        = "reconcile_concurrency"
+	ReconcileIntervalKey           = "reconcile_interval"
+	RejectedCallLogPerMinuteKey    = "rejected_call_log_per_minute"
+	ReplicaModeKey                 = "replica_mode"
+	ResponseCacheMaxEntriesKey     = "response_cache_max_entries"
+	ResponseCacheTtlKey            = "response_cache_ttl"
 	SSLCertPathKey                 = "ssl_cert"
 	SSLKeyPathKey                  = "ssl_key"
+	StartupTimeoutKey              = "startup_timeout"
+	StatsdEndpointKey              = "statsd_endpoint"
+	StrictAddressChecksumKey       = "strict_address_checksum"
+	SubscriptionAdminTokenKey      = "subscription_admin_token"
+	SubscriptionPeriodKey          = "subscription_period"
+	SubscriptionPriceKey           = "subscription_price"
+	TracingEnabledKey              = "tracing_enabled"
+	TracingOTLPEndpointKey         = "tracing_otlp_endpoint"
+	TxGasBumpPercentKey            = "tx_gas_bump_percent"
+	TxMaxFeePerGasKey              = "tx_max_fee_per_gas"
+	TxMaxGasPriceKey               = "tx_max_gas_price"
+	TxMaxPriorityFeePerGasKey      = "tx_max_priority_fee_per_gas"
+	TxResubmitIntervalKey          = "tx_resubmit_interval"
+	TxTypeKey                      = "tx_type"
+	UnknownMetadataPolicyKey       = "unknown_metadata_policy"
+	ValidationErrorMessagesKey     = "validation_error_messages"
+	ValidationRecordFileKey        = "income_validation_record_file"
+	ValidationRecordSampleRateKey  = "income_validation_record_sample_rate"
+	WebhookMaxAttemptsKey          = "webhook_max_attempts"
+	WebhookMaxRetryBackoffKey      = "webhook_max_retry_backoff"
+	WebhookRetryBackoffKey         = "webhook_retry_backoff"
+	PaymentChannelIDHeadersKey     = "payment_channel_id_headers"
+	PaymentSignatureSchemeKey      = "payment_signature_scheme"
 	PaymentChannelStorageTypeKey   = "payment_channel_storage_type"
 	PaymentChannelStorageClientKey = "payment_channel_storage_client"
 	PaymentChannelStorageServerKey = "payment_channel_storage_server"
 
 	defaultConfigJson string = `
 {
+	"accepted_currency": "AGI",
+	"allow_zero_balance_channels": true,
 	"auto_ssl_domain": "",
 	"auto_ssl_cache_dir": ".certs",
+	"auto_claim_min_amount": 0,
 	"blockchain_enabled": true,
+	"channel_event_dedup_blocks": 100,
+	"channel_events_poll_interval": "0s",
 	"daemon_type": "grpc",
 	"daemon_end_point": "127.0.0.1:8080",
+	"debug_log_bodies": false,
+	"debug_log_body_limit": 1024,
+	"diagnostics_enabled": false,
+	"diagnostics_token": "",
+	"endpoint_selection": "priority",
 	"ethereum_json_rpc_endpoint": "http://127.0.0.1:8545",
+	"etcd_startup_timeout": "1m",
+	"gas_price_multiplier_percent": 100,
+	"gas_price_refresh_interval": "0s",
+	"handler_queue_size": 100,
+	"handler_worker_count": 0,
 	"hdwallet_index": 0,
+	"http_max_request_bytes": 4194304,
 	"hdwallet_mnemonic": "",
+	"idle_burst_size": 0,
+	"idle_threshold": "5m",
+	"income_validation_mode": "enforce",
+	"income_validation_order": ["min_income", "price"],
+	"income_validation_record_sample_rate": 0.01,
+	"invoice_id_header": "snet-invoice-id",
 	"ipfs_end_point": "http://localhost:5002/", 
+	"job_value_header": "snet-job-value",
+	"max_block_lag": "0s",
+	"max_channel_expiry_blocks": 0,
+	"max_clock_skew": "0s",
+	"max_concurrent_claims": 1,
+	"max_metadata_bytes": 0,
+	"metadata_cache_file": "service_metadata.cache.json",
+	"metadata_cache_refresh_jitter": "0s",
+	"metadata_offline_start": false,
+	"min_income": 0,
+	"offline_auth_mode": "free",
 	"organization_id": "ExampleOrganizationId", 
+	"organization_id_header": "snet-organization-id",
 	"passthrough_enabled": false,
-	"registry_address_key": "0x4e74fefa82e83e0964f0d9f53c68e03f7298a8b2",
+	"passthrough_flush_on_timeout": false,
+	"passthrough_max_response_bytes": 0,
+	"passthrough_streaming_methods": [],
+	"per_sender_spend_cap": 0,
+	"per_sender_spend_cap_period": "24h",
+	"persist_rate_limits": false,
+	"price_cache_ttl": "5m",
+	"registry_address_key": "0x4E74FefA82E83E0964f0D9f53c68e03f7298a8b2",
+	"reconcile_concurrency": 4,
+	"reconcile_interval": "0s",
+	"rejected_call_log_per_minute": 60,
+	"replica_mode": false,
+	"response_cache_max_entries": 1000,
+	"response_cache_ttl": "1m",
 	"service_id": "ExampleServiceId", 
+	"service_id_header": "snet-service-id",
 	"private_key": "",
 	"ssl_cert": "",
 	"ssl_key": "",
+	"ssl_min_version": "1.2",
+	"streaming_income_validation": false,
+	"strict_address_checksum": false,
+	"subscription_admin_token": "",
+	"subscription_period": "720h",
+	"subscription_price": 0,
+	"tracing_enabled": false,
+	"tracing_otlp_endpoint": "http://localhost:4318/v1/traces",
+	"tx_gas_bump_percent": 0,
+	"tx_max_fee_per_gas": 0,
+	"tx_max_gas_price": 0,
+	"tx_max_priority_fee_per_gas": 0,
+	"tx_resubmit_interval": "2m",
+	"tx_type": "legacy",
+	"unknown_metadata_policy": "ignore",
+	"webhook_max_attempts": 10,
+	"webhook_max_retry_backoff": "1h",
+	"webhook_retry_backoff": "1m",
 	"log":  {
 		"level": "info",
 		"timezone": "UTC",
@@ -71,15 +237,21 @@ const (
 			"current_link": "./snet-daemon.log",
 			"rotation_time_in_sec": 86400,
 			"max_age_in_sec": 604800,
-			"rotation_count": 0
+			"rotation_count": 0,
+			"create_dirs": false
 		},
 		"hooks": []
 	},
+	"payment_channel_id_headers": ["snet-payment-channel-id"],
 	"payment_channel_storage_type": "etcd",
+	"payment_signature_scheme": "raw",
 	"payment_channel_storage_client": {
 		"connection_timeout": "5s",
 		"request_timeout": "3s",
-		"endpoints": ["http://127.0.0.1:2379"]
+		"keep_alive_time": "10s",
+		"keep_alive_timeout": "3s",
+		"endpoints": ["http://127.0.0.1:2379"],
+		"key_prefix": ""
 	},
 	"payment_channel_storage_server": {
 		"id": "storage-1",
@@ -100,21 +272,50 @@ const (
 
 var vip *viper.Viper
 
+// vipMutex guards vip: viper instance is not safe for concurrent use, and
+// configuration can be re-read while handlers are reading settings.
+var vipMutex = &sync.RWMutex{}
+
+const (
+	// EnvPrefixEnv is a name of the environment variable which sets prefix
+	// of the environment variables used to override configuration. It is
+	// read before configuration is loaded.
+	EnvPrefixEnv = "CONFIG_ENV_PREFIX"
+	// DefaultEnvPrefix is a prefix of the environment variables used when
+	// EnvPrefixEnv is not set.
+	DefaultEnvPrefix = "SNET"
+)
+
 func init() {
-	var err error
+	envPrefix = getEnvPrefix()
+	vip = newVip(envPrefix)
+}
 
-	vip = viper.New()
-	vip.SetEnvPrefix("SNET")
-	vip.AutomaticEnv()
+// getEnvPrefix returns prefix of the environment variables which override
+// configuration.
+func getEnvPrefix() string {
+	if prefix := os.Getenv(EnvPrefixEnv); prefix != "" {
+		return prefix
+	}
+	return DefaultEnvPrefix
+}
+
+// newVip returns viper instance with default configuration which reads
+// environment variables with given prefix.
+func newVip(envPrefix string) *viper.Viper {
+	config := viper.New()
+	config.SetEnvPrefix(envPrefix)
+	config.AutomaticEnv()
 
 	var defaults = viper.New()
-	err = ReadConfigFromJsonString(defaults, defaultConfigJson)
+	err := ReadConfigFromJsonString(defaults, defaultConfigJson)
 	if err != nil {
 		panic(fmt.Sprintf("Cannot load default config: %v", err))
 	}
-	SetDefaultFromConfig(vip, defaults)
+	SetDefaultFromConfig(config, defaults)
 
-	vip.AddConfigPath(".")
+	config.AddConfigPath(".")
+	return config
 }
 
 // ReadConfigFromJsonString function reads settigs from json string to the
@@ -132,11 +333,36 @@ func SetDefaultFromConfig(config *viper.Viper, defaults *viper.Viper) {
 	}
 }
 
+// Vip returns the global viper instance. Access to the returned instance is
+// not synchronized with ReloadConfig(), so it should be used during
+// initialization only; use Get* accessors to read settings at runtime.
 func Vip() *viper.Viper {
 	return vip
 }
 
+// WithDefaultConfig replaces the global configuration by the default one
+// and returns function which restores the previous configuration. It is used
+// by tests which load or change configuration.
+func WithDefaultConfig() (restore func()) {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
+	savedVip, savedFileSettings, savedSigner := vip, fileSettings, configSigner
+	vip = newVip(envPrefix)
+	fileSettings = viper.New()
+	configSigner = nil
+	return func() {
+		vipMutex.Lock()
+		defer vipMutex.Unlock()
+
+		vip, fileSettings, configSigner = savedVip, savedFileSettings, savedSigner
+	}
+}
+
 func Validate() error {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
 	switch dType := vip.GetString(DaemonTypeKey); dType {
 	case "grpc":
 	case "http":
@@ -149,36 +375,366 @@ func Validate() error {
 		return errors.New("SSL requires both key and certificate when enabled")
 	}
 
+	if _, err := GetRegistryAddressFromVip(vip); err != nil {
+		return err
+	}
+
+	if _, err := GetBlockedSendersFromVip(vip); err != nil {
+		return err
+	}
+
+	if _, err := GetUint64FromViper(vip, ExpectedChainIDKey); err != nil {
+		return err
+	}
+
+	if address := vip.GetString(ExpectedDaemonAddressKey); address != "" {
+		if _, err := canonicalizeAddressFromVip(vip, address); err != nil {
+			return fmt.Errorf("incorrect %v: %v", ExpectedDaemonAddressKey, err)
+		}
+	}
+
+	if _, err := NewTLSConfigFromVip(vip); err != nil {
+		return err
+	}
+
+	switch strategy := vip.GetString(EndpointSelectionKey); strategy {
+	case "priority", "round_robin", "random":
+	default:
+		return fmt.Errorf("unrecognized endpoint_selection '%+v'", strategy)
+	}
+
+	switch mode := vip.GetString(IncomeValidationModeKey); mode {
+	case "enforce":
+	case "observe":
+	default:
+		return fmt.Errorf("unrecognized income_validation_mode '%+v'", mode)
+	}
+
+	switch scheme := vip.GetString(PaymentSignatureSchemeKey); scheme {
+	case "raw":
+	case "eip712":
+	default:
+		return fmt.Errorf("unrecognized payment_signature_scheme '%+v'", scheme)
+	}
+
+	if minIncome, err := GetBigIntFromViper(vip, MinIncomeKey); err != nil || minIncome.Sign() < 0 {
+		return fmt.Errorf("min_income should be non-negative integer, got \"%v\"", vip.GetString(MinIncomeKey))
+	}
+
+	if amount, err := GetBigIntFromViper(vip, AutoClaimMinAmountKey); err != nil || amount.Sign() < 0 {
+		return fmt.Errorf("auto_claim_min_amount should be non-negative integer, got \"%v\"", vip.GetString(AutoClaimMinAmountKey))
+	}
+
+	if spendCap, err := GetBigIntFromViper(vip, PerSenderSpendCapKey); err != nil || spendCap.Sign() < 0 {
+		return fmt.Errorf("per_sender_spend_cap should be non-negative integer, got \"%v\"", vip.GetString(PerSenderSpendCapKey))
+	}
+
+	if vip.GetDuration(PerSenderSpendCapPeriodKey) <= 0 {
+		return fmt.Errorf("per_sender_spend_cap_period should be positive duration, got \"%v\"", vip.GetString(PerSenderSpendCapPeriodKey))
+	}
+
+	if err := validateValidationErrorMessagesFromVip(vip); err != nil {
+		return err
+	}
+
+	if err := validateCurrencyFromVip(vip); err != nil {
+		return err
+	}
+
+	if err := validateGasBumpFromVip(vip); err != nil {
+		return err
+	}
+
+	if err := validateGasPriceCacheFromVip(vip); err != nil {
+		return err
+	}
+
+	if err := validateTxTypeFromVip(vip); err != nil {
+		return err
+	}
+
+	if err := validateClaimRevertPolicyFromVip(vip); err != nil {
+		return err
+	}
+
+	if size := vip.GetInt(IdleBurstSizeKey); size < 0 {
+		return fmt.Errorf("idle_burst_size should be non-negative number, got \"%v\"", vip.GetString(IdleBurstSizeKey))
+	} else if size > 0 && vip.GetDuration(IdleThresholdKey) <= 0 {
+		return fmt.Errorf("idle_threshold should be positive duration, got \"%v\"", vip.GetString(IdleThresholdKey))
+	}
+
+	if err := validateReplicaModeFromVip(vip); err != nil {
+		return err
+	}
+
+	if vip.GetInt(MaxConcurrentClaimsKey) <= 0 {
+		return fmt.Errorf("max_concurrent_claims should be positive number, got \"%v\"", vip.GetString(MaxConcurrentClaimsKey))
+	}
+
+	if vip.GetInt(ReconcileConcurrencyKey) <= 0 {
+		return fmt.Errorf("reconcile_concurrency should be positive number, got \"%v\"", vip.GetString(ReconcileConcurrencyKey))
+	}
+
+	if vip.GetDuration(ReconcileIntervalKey) < 0 {
+		return fmt.Errorf("reconcile_interval cannot be negative, got \"%v\"", vip.GetString(ReconcileIntervalKey))
+	}
+
+	if err := validateIncomeValidationOrderFromVip(vip); err != nil {
+		return err
+	}
+
+	if rate := vip.GetFloat64(ValidationRecordSampleRateKey); rate < 0 || rate > 1 {
+		return fmt.Errorf("income_validation_record_sample_rate should be between 0 and 1, got %v", rate)
+	}
+
+	if err := validateSubscriptionFromVip(vip); err != nil {
+		return err
+	}
+
+	if vip.GetBool(DiagnosticsEnabledKey) && vip.GetString(DiagnosticsTokenKey) == "" {
+		return errors.New("diagnostics_token should be set when diagnostics_enabled is true")
+	}
+
+	if vip.IsSet(GrpcMaxConcurrentStreamsKey) && vip.GetInt(GrpcMaxConcurrentStreamsKey) <= 0 {
+		return fmt.Errorf("grpc_max_concurrent_streams should be positive number, got \"%v\"", vip.GetString(GrpcMaxConcurrentStreamsKey))
+	}
+
+	if vip.GetInt(HTTPMaxRequestBytesKey) < 0 {
+		return fmt.Errorf("http_max_request_bytes cannot be negative, got \"%v\"", vip.GetString(HTTPMaxRequestBytesKey))
+	}
+
+	if vip.GetDuration(StartupTimeoutKey) < 0 {
+		return fmt.Errorf("startup_timeout cannot be negative, got \"%v\"", vip.GetString(StartupTimeoutKey))
+	}
+
+	if vip.GetDuration(EtcdStartupTimeoutKey) < 0 {
+		return fmt.Errorf("etcd_startup_timeout cannot be negative, got \"%v\"", vip.GetString(EtcdStartupTimeoutKey))
+	}
+
+	if vip.GetDuration(MetadataCacheRefreshJitterKey) < 0 {
+		return fmt.Errorf("metadata_cache_refresh_jitter cannot be negative, got \"%v\"", vip.GetString(MetadataCacheRefreshJitterKey))
+	}
+
+	if vip.GetDuration(MaxBlockLagKey) < 0 {
+		return fmt.Errorf("max_block_lag cannot be negative, got \"%v\"", vip.GetString(MaxBlockLagKey))
+	}
+
+	if vip.GetDuration(MaxClockSkewKey) < 0 {
+		return fmt.Errorf("max_clock_skew cannot be negative, got \"%v\"", vip.GetString(MaxClockSkewKey))
+	}
+
+	if _, err := GetUint64FromViper(vip, MaxChannelExpiryBlocksKey); err != nil {
+		return err
+	}
+
+	if vip.GetDuration(ChannelEventsPollIntervalKey) < 0 {
+		return fmt.Errorf("channel_events_poll_interval cannot be negative, got \"%v\"", vip.GetString(ChannelEventsPollIntervalKey))
+	}
+
+	if _, err := GetUint64FromViper(vip, ChannelEventDedupBlocksKey); err != nil {
+		return err
+	}
+
+	if vip.GetInt(MaxMetadataBytesKey) < 0 {
+		return fmt.Errorf("max_metadata_bytes cannot be negative, got \"%v\"", vip.GetString(MaxMetadataBytesKey))
+	}
+
+	if err := validateUnknownMetadataPolicyFromVip(vip); err != nil {
+		return err
+	}
+
+	if vip.GetInt(PassthroughMaxResponseBytesKey) < 0 {
+		return errors.New("passthrough_max_response_bytes cannot be negative")
+	}
+
+	if vip.GetInt(HandlerWorkerCountKey) < 0 || vip.GetInt(HandlerQueueSizeKey) < 0 {
+		return errors.New("handler_worker_count and handler_queue_size cannot be negative")
+	}
+
+	if err := validateOfflineAuthFromVip(vip); err != nil {
+		return err
+	}
+
+	if err := validateMethodListsFromVip(vip); err != nil {
+		return err
+	}
+
+	if vip.GetInt(ResponseCacheMaxEntriesKey) < 0 {
+		return errors.New("response_cache_max_entries cannot be negative")
+	}
+
+	for _, webhook := range vip.GetStringSlice(ClaimWebhookURLsKey) {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("claim_webhook_urls contains incorrect URL \"%v\"", webhook)
+		}
+	}
+	if err := validateWebhookRetryFromVip(vip); err != nil {
+		return err
+	}
+
+	if vip.GetBool(TracingEnabledKey) {
+		endpoint := vip.GetString(TracingOTLPEndpointKey)
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("tracing_otlp_endpoint should be http or https URL, got \"%v\"", endpoint)
+		}
+	}
+
+	if _, err := GetServicesFromVip(vip); err != nil {
+		return err
+	}
+
+	if _, err := getMaintenanceWindowsFromVip(vip); err != nil {
+		return err
+	}
+
+	if _, err := getHTTPRequestSchemasFromVip(vip); err != nil {
+		return err
+	}
+
+	if _, err := getFeaturesFromVip(vip); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func LoadConfig(configFile string) error {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
 	vip.SetConfigFile(configFile)
-	return vip.ReadInConfig()
+	if err := vip.ReadInConfig(); err != nil {
+		return err
+	}
+	fileSettings = readFileSettings(configFile, nil)
+	configSigner = nil
+	return nil
+}
+
+// ReloadConfig re-reads configuration file which was loaded by LoadConfig()
+// or LoadSignedConfig(). Signature of the signed configuration is verified
+// again, current configuration is kept if it is not correct.
+// Readers are blocked until new configuration is read completely. Listeners
+// registered by AddReloadListener() are called after configuration is read.
+func ReloadConfig() error {
+	if err := readConfigAgain(); err != nil {
+		return err
+	}
+
+	reloadListenersMutex.Lock()
+	listeners := append([]func(){}, reloadListeners...)
+	reloadListenersMutex.Unlock()
+	for _, listener := range listeners {
+		listener()
+	}
+	return nil
+}
+
+func readConfigAgain() error {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
+	if configSigner != nil {
+		return readSignedConfig(vip.ConfigFileUsed(), *configSigner)
+	}
+	if err := vip.ReadInConfig(); err != nil {
+		return err
+	}
+	fileSettings = readFileSettings(vip.ConfigFileUsed(), nil)
+	return nil
+}
+
+var reloadListeners []func()
+var reloadListenersMutex = &sync.Mutex{}
+
+// AddReloadListener registers function which is called each time
+// configuration is reloaded by ReloadConfig(). Listener is used to apply
+// new settings to the components which read them once on start.
+func AddReloadListener(listener func()) {
+	reloadListenersMutex.Lock()
+	defer reloadListenersMutex.Unlock()
+
+	reloadListeners = append(reloadListeners, listener)
 }
 
 func WriteConfig(configFile string) error {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
 	vip.SetConfigFile(configFile)
 	return vip.WriteConfig()
 }
 
 func GetString(key string) string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
 	return vip.GetString(key)
 }
 
+// GetStringSlice returns value of the key as list of strings.
+func GetStringSlice(key string) []string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringSlice(key)
+}
+
 func GetInt(key string) int {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
 	return vip.GetInt(key)
 }
 
+// GetBigInt returns value of the key as big.Int or nil if value is not an
+// integer, use GetCheckedBigInt to get the parsing error.
 func GetBigInt(key string) *big.Int {
-	return big.NewInt(int64(vip.GetInt(key)))
+	value, err := GetCheckedBigInt(key)
+	if err != nil {
+		log.WithError(err).WithField("key", key).Warn("incorrect big integer value in config")
+		return nil
+	}
+	return value
+}
+
+// GetCheckedBigInt returns value of the key parsed as decimal integer of
+// arbitrary size. It should be used to read token amounts instead of GetInt
+// which silently truncates large values.
+func GetCheckedBigInt(key string) (value *big.Int, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return GetBigIntFromViper(vip, key)
+}
+
+// GetUint64 returns value of the key as uint64. Unlike GetInt it returns
+// error if value is negative or doesn't fit into uint64.
+func GetUint64(key string) (value uint64, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return GetUint64FromViper(vip, key)
 }
 
 func GetDuration(key string) time.Duration {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
 	return vip.GetDuration(key)
 }
 
+func GetFloat64(key string) float64 {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetFloat64(key)
+}
+
 func GetBool(key string) bool {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
 	return vip.GetBool(key)
 }
 
@@ -201,21 +757,30 @@ func SubWithDefault(config *viper.Viper, key string) *viper.Viper {
 	return sub
 }
 
-var hiddenKeys = map[string]bool{
-	strings.ToUpper(PrivateKeyKey):       true,
-	strings.ToUpper(HdwalletMnemonicKey): true,
+// GetMaskedSettings returns effective values of all configuration keys,
+// values of the secret keys are replaced by "***".
+func GetMaskedSettings() map[string]interface{} {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	settings := make(map[string]interface{})
+	for _, key := range vip.AllKeys() {
+		settings[key] = vip.Get(key)
+	}
+	return Redact(settings)
 }
 
 func LogConfig() {
+	settings := GetMaskedSettings()
+
 	log.Info("Final configuration:")
-	keys := vip.AllKeys()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
 	sort.Strings(keys)
 	for _, key := range keys {
-		if hiddenKeys[strings.ToUpper(key)] {
-			log.Infof("%v: ***", key)
-		} else {
-			log.Infof("%v: %v", key, vip.Get(key))
-		}
+		log.Infof("%v: %v", key, settings[key])
 	}
 }
 
@@ -224,3 +789,17 @@ func GetBigIntFromViper(config *viper.Viper, key string) (value *big.Int, err er
 	err = value.UnmarshalText([]byte(config.GetString(key)))
 	return
 }
+
+// GetUint64FromViper parses value of the key as uint64 and checks its range.
+func GetUint64FromViper(config *viper.Viper, key string) (value uint64, err error) {
+	str := config.GetString(key)
+	if str == "" {
+		return 0, nil
+	}
+
+	value, err = strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("incorrect value of \"%v\": \"%v\" is not an unsigned 64 bit integer", key, str)
+	}
+	return value, nil
+}
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 func TestCustomSubMap(t *testing.T) {
@@ -90,3 +96,150 @@ func TestSetDefaultFromConfig(t *testing.T) {
 
 	assertConfigIsEqualToJsonConfigString(t, config)
 }
+
+func writeTestConfigFile(t *testing.T, configJson string) string {
+	file, err := ioutil.TempFile("", "snetd-config-*.json")
+	assert.Nil(t, err)
+	defer file.Close()
+
+	_, err = file.WriteString(configJson)
+	assert.Nil(t, err)
+
+	return file.Name()
+}
+
+// TestGetWhileReload should be run with -race flag to detect concurrent
+// access to the configuration.
+func TestGetWhileReload(t *testing.T) {
+	configFile := writeTestConfigFile(t, `{ "daemon_type": "http" }`)
+	defer os.Remove(configFile)
+	defer vip.SetConfigFile("")
+
+	assert.Nil(t, LoadConfig(configFile))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.Nil(t, ReloadConfig())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.Equal(t, "http", GetString(DaemonTypeKey))
+			assert.True(t, GetBool(BlockchainEnabledKey))
+		}
+	}()
+	wg.Wait()
+}
+
+func TestGetBigIntFromViperLargeValue(t *testing.T) {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, `{ "amount": "123456789012345678901234567890" }`)
+	assert.Nil(t, err)
+
+	value, err := GetBigIntFromViper(config, "amount")
+
+	assert.Nil(t, err)
+	expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, expected, value)
+}
+
+func TestGetBigInt(t *testing.T) {
+	defer WithDefaultConfig()()
+	vip.Set("amount", "123456789012345678901234567890")
+	vip.Set("incorrect", "abc")
+
+	expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, expected, GetBigInt("amount"))
+	assert.Nil(t, GetBigInt("incorrect"))
+
+	value, err := GetCheckedBigInt("amount")
+	assert.Nil(t, err)
+	assert.Equal(t, expected, value)
+	_, err = GetCheckedBigInt("incorrect")
+	assert.NotNil(t, err)
+}
+
+func TestGetUint64FromViper(t *testing.T) {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, `{
+		"above_int32": 3000000000,
+		"max_uint64": "18446744073709551615",
+		"above_uint64": "18446744073709551616",
+		"negative": -1
+	}`)
+	assert.Nil(t, err)
+
+	value, err := GetUint64FromViper(config, "above_int32")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3000000000), value)
+
+	value, err = GetUint64FromViper(config, "max_uint64")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), value)
+
+	_, err = GetUint64FromViper(config, "above_uint64")
+	assert.Equal(t, "incorrect value of \"above_uint64\": \"18446744073709551616\" is not an unsigned 64 bit integer", err.Error())
+
+	_, err = GetUint64FromViper(config, "negative")
+	assert.Equal(t, "incorrect value of \"negative\": \"-1\" is not an unsigned 64 bit integer", err.Error())
+
+	value, err = GetUint64FromViper(config, "absent")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+}
+
+func TestEnvPrefixDefault(t *testing.T) {
+	os.Unsetenv(EnvPrefixEnv)
+
+	assert.Equal(t, "SNET", getEnvPrefix())
+}
+
+func TestEnvPrefixFromEnv(t *testing.T) {
+	os.Setenv(EnvPrefixEnv, "MYDAEMON")
+	defer os.Unsetenv(EnvPrefixEnv)
+
+	assert.Equal(t, "MYDAEMON", getEnvPrefix())
+}
+
+func TestNewVipCustomEnvPrefix(t *testing.T) {
+	os.Setenv("MYDAEMON_DAEMON_TYPE", "http")
+	defer os.Unsetenv("MYDAEMON_DAEMON_TYPE")
+	os.Setenv("SNET_DAEMON_END_POINT", "127.0.0.1:9999")
+	defer os.Unsetenv("SNET_DAEMON_END_POINT")
+
+	config := newVip("MYDAEMON")
+
+	assert.Equal(t, "http", config.GetString(DaemonTypeKey))
+	assert.Equal(t, "127.0.0.1:8080", config.GetString(DaemonEndPoint))
+}
+
+func TestReloadConfigCallsListeners(t *testing.T) {
+	configFile := writeTestConfigFile(t, `{ "rate_limit_per_minute": 60 }`)
+	defer os.Remove(configFile)
+	defer vip.SetConfigFile("")
+	assert.Nil(t, LoadConfig(configFile))
+	defer func() { reloadListeners = nil }()
+	var reloaded []int
+	AddReloadListener(func() { reloaded = append(reloaded, GetInt(RateLimitPerMinute)) })
+
+	assert.Nil(t, ioutil.WriteFile(configFile, []byte(`{ "rate_limit_per_minute": 120 }`), 0600))
+	assert.Nil(t, ReloadConfig())
+
+	assert.Equal(t, []int{120}, reloaded)
+}
+
+func TestWithDefaultConfigRestoresConfig(t *testing.T) {
+	defer WithDefaultConfig()()
+	Vip().Set(DaemonTypeKey, "http")
+
+	restore := WithDefaultConfig()
+	assert.Equal(t, "grpc", GetString(DaemonTypeKey))
+	Vip().Set(DaemonTypeKey, "custom")
+	restore()
+
+	assert.Equal(t, "http", GetString(DaemonTypeKey))
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// GetAcceptedCurrency returns currency which payments are made in, currency
+// name is in upper case.
+func GetAcceptedCurrency() string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return strings.ToUpper(vip.GetString(AcceptedCurrencyKey))
+}
+
+// GetPriceRate returns rate to convert service prices set in price_currency
+// to the accepted currency or nil if prices are set in the accepted
+// currency.
+func GetPriceRate() (rate *big.Rat, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return getPriceRateFromVip(vip)
+}
+
+func getPriceRateFromVip(config *viper.Viper) (rate *big.Rat, err error) {
+	currency := strings.ToUpper(config.GetString(PriceCurrencyKey))
+	if currency == "" || currency == strings.ToUpper(config.GetString(AcceptedCurrencyKey)) {
+		return nil, nil
+	}
+
+	rates, err := GetCurrencyRatesFromVip(config)
+	if err != nil {
+		return nil, err
+	}
+	rate, ok := rates[currency]
+	if !ok {
+		return nil, fmt.Errorf("currency_rates should contain rate of price_currency \"%v\"", currency)
+	}
+	return rate, nil
+}
+
+// GetCurrencyRatesFromVip returns currency_rates using viper config. Rate is
+// an amount in cogs of the accepted currency which is equal to the one price
+// unit of the currency.
+func GetCurrencyRatesFromVip(config *viper.Viper) (rates map[string]*big.Rat, err error) {
+	rates = make(map[string]*big.Rat)
+	for currency, value := range config.GetStringMapString(CurrencyRatesKey) {
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("currency_rates should contain positive rate of \"%v\", got \"%v\"", currency, value)
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return rates, nil
+}
+
+// validateCurrencyFromVip checks that accepted_currency is set,
+// currency_rates contains correct rates of other currencies and rate of
+// price_currency is known.
+func validateCurrencyFromVip(config *viper.Viper) error {
+	accepted := strings.ToUpper(config.GetString(AcceptedCurrencyKey))
+	if accepted == "" {
+		return fmt.Errorf("accepted_currency should not be empty")
+	}
+
+	rates, err := GetCurrencyRatesFromVip(config)
+	if err != nil {
+		return err
+	}
+	if _, ok := rates[accepted]; ok {
+		return fmt.Errorf("currency_rates should not contain accepted_currency \"%v\"", accepted)
+	}
+	_, err = getPriceRateFromVip(config)
+	return err
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func currencyConfig(json string) *viper.Viper {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, json)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func TestGetCurrencyRatesFromVip(t *testing.T) {
+	config := currencyConfig(`{"currency_rates": {"usd": 2.5, "Eur": "3"}}`)
+
+	rates, err := GetCurrencyRatesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]*big.Rat{"USD": big.NewRat(5, 2), "EUR": big.NewRat(3, 1)}, rates)
+}
+
+func TestValidateCurrencyIncorrectRate(t *testing.T) {
+	config := currencyConfig(`{"accepted_currency": "AGI", "currency_rates": {"usd": -1}}`)
+
+	assert.EqualError(t, validateCurrencyFromVip(config), "currency_rates should contain positive rate of \"usd\", got \"-1\"")
+}
+
+func TestValidateCurrencyRateOfAcceptedCurrency(t *testing.T) {
+	config := currencyConfig(`{"accepted_currency": "AGI", "currency_rates": {"agi": 1}}`)
+
+	assert.EqualError(t, validateCurrencyFromVip(config), "currency_rates should not contain accepted_currency \"AGI\"")
+}
+
+func TestGetPriceRateFromVip(t *testing.T) {
+	config := currencyConfig(`{"accepted_currency": "AGI", "price_currency": "usd", "currency_rates": {"USD": 2.5}}`)
+
+	rate, err := getPriceRateFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewRat(5, 2), rate)
+}
+
+func TestGetPriceRateFromVipAcceptedCurrency(t *testing.T) {
+	for _, json := range []string{
+		`{"accepted_currency": "AGI"}`,
+		`{"accepted_currency": "AGI", "price_currency": "agi", "currency_rates": {"USD": 2.5}}`,
+	} {
+		rate, err := getPriceRateFromVip(currencyConfig(json))
+
+		assert.Nil(t, err)
+		assert.Nil(t, rate, json)
+	}
+}
+
+func TestValidateCurrencyPriceCurrencyWithoutRate(t *testing.T) {
+	config := currencyConfig(`{"accepted_currency": "AGI", "price_currency": "EUR", "currency_rates": {"USD": 2.5}}`)
+
+	assert.EqualError(t, validateCurrencyFromVip(config), "currency_rates should contain rate of price_currency \"EUR\"")
+}
+
+func TestValidateCurrencyEmptyAcceptedCurrency(t *testing.T) {
+	config := currencyConfig(`{"accepted_currency": ""}`)
+
+	assert.EqualError(t, validateCurrencyFromVip(config), "accepted_currency should not be empty")
+}
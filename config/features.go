@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// IsFeatureEnabled returns true if the feature is switched on in the features
+// map. Features which are not listed are disabled. Feature names are
+// compared case insensitively because configuration keys are case
+// insensitive.
+func IsFeatureEnabled(name string) bool {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	features, err := getFeaturesFromVip(vip)
+	if err != nil {
+		return false
+	}
+	return features[strings.ToLower(name)]
+}
+
+func getFeaturesFromVip(config *viper.Viper) (features map[string]bool, err error) {
+	features = make(map[string]bool)
+	for name, value := range config.GetStringMap(FeaturesKey) {
+		enabled, err := cast.ToBoolE(value)
+		if err != nil {
+			return nil, fmt.Errorf("%v contains incorrect value \"%v\" of feature \"%v\", expected true or false", FeaturesKey, value, name)
+		}
+		features[strings.ToLower(name)] = enabled
+	}
+	return features, nil
+}
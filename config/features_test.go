@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsFeatureEnabled(t *testing.T) {
+	defer vip.Set(FeaturesKey, nil)
+
+	vip.Set(FeaturesKey, map[string]interface{}{"new_pricing": true, "old_pricing": false})
+
+	assert.True(t, IsFeatureEnabled("new_pricing"))
+	assert.True(t, IsFeatureEnabled("New_Pricing"), "names are case insensitive")
+	assert.False(t, IsFeatureEnabled("old_pricing"))
+	assert.False(t, IsFeatureEnabled("unknown"), "unknown feature is disabled")
+
+	vip.Set(FeaturesKey, map[string]interface{}{"new_pricing": false, "old_pricing": true})
+
+	assert.False(t, IsFeatureEnabled("new_pricing"))
+	assert.True(t, IsFeatureEnabled("old_pricing"))
+}
+
+func TestIsFeatureEnabledNotSet(t *testing.T) {
+	assert.False(t, IsFeatureEnabled("new_pricing"))
+}
+
+func TestGetFeatures(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"features": { "New_Pricing": true, "old_pricing": false }
+	}`)
+
+	features, err := getFeaturesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"new_pricing": true, "old_pricing": false}, features)
+}
+
+func TestValidateFeaturesIncorrectValue(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"features": { "new_pricing": "sometimes" }
+	}`)
+
+	_, err := getFeaturesFromVip(config)
+
+	assert.Equal(t, "features contains incorrect value \"sometimes\" of feature \"new_pricing\", expected true or false", err.Error())
+}
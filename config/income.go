@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// IncomeValidationStageMinIncome is a stage of the income validation
+	// which checks min_income. It is cheap as it doesn't access any storage.
+	IncomeValidationStageMinIncome = "min_income"
+	// IncomeValidationStagePrice is a stage of the income validation which
+	// checks income against the method price. It may be expensive when price
+	// is provided by the remote pricing source.
+	IncomeValidationStagePrice = "price"
+)
+
+// IncomeValidationStages contains all known stages of the income
+// validation.
+var IncomeValidationStages = []string{IncomeValidationStageMinIncome, IncomeValidationStagePrice}
+
+// GetIncomeValidationOrder returns list of income validation stages in order
+// they should be applied.
+func GetIncomeValidationOrder() []string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringSlice(IncomeValidationOrderKey)
+}
+
+// validateIncomeValidationOrderFromVip checks that income_validation_order
+// contains known stages without duplicates and price stage is not omitted.
+func validateIncomeValidationOrderFromVip(config *viper.Viper) error {
+	seen := make(map[string]bool)
+	for _, stage := range config.GetStringSlice(IncomeValidationOrderKey) {
+		if !isIncomeValidationStage(stage) {
+			return fmt.Errorf("income_validation_order contains unknown stage \"%v\", known stages are %v", stage, IncomeValidationStages)
+		}
+		if seen[stage] {
+			return fmt.Errorf("income_validation_order contains stage \"%v\" twice", stage)
+		}
+		seen[stage] = true
+	}
+	if !seen[IncomeValidationStagePrice] {
+		return fmt.Errorf("income_validation_order should contain \"%v\" stage", IncomeValidationStagePrice)
+	}
+	return nil
+}
+
+func isIncomeValidationStage(stage string) bool {
+	for _, known := range IncomeValidationStages {
+		if stage == known {
+			return true
+		}
+	}
+	return false
+}
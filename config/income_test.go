@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateIncomeValidationOrder(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"income_validation_order": ["price", "min_income"]
+	}`)
+
+	err := validateIncomeValidationOrderFromVip(config)
+
+	assert.Nil(t, err)
+}
+
+func TestValidateIncomeValidationOrderUnknownStage(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"income_validation_order": ["min_income", "signature", "price"]
+	}`)
+
+	err := validateIncomeValidationOrderFromVip(config)
+
+	assert.Equal(t, "income_validation_order contains unknown stage \"signature\", known stages are [min_income price]", err.Error())
+}
+
+func TestValidateIncomeValidationOrderDuplicateStage(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"income_validation_order": ["price", "price"]
+	}`)
+
+	err := validateIncomeValidationOrderFromVip(config)
+
+	assert.Equal(t, "income_validation_order contains stage \"price\" twice", err.Error())
+}
+
+func TestValidateIncomeValidationOrderWithoutPrice(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"income_validation_order": ["min_income"]
+	}`)
+
+	err := validateIncomeValidationOrderFromVip(config)
+
+	assert.Equal(t, "income_validation_order should contain \"price\" stage", err.Error())
+}
+
+func TestGetIncomeValidationOrderDefault(t *testing.T) {
+	assert.Equal(t, []string{"min_income", "price"}, GetIncomeValidationOrder())
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// timeOfDayLayout is a layout of the start and end of the daily maintenance
+// window.
+const timeOfDayLayout = "15:04"
+
+// MaintenanceWindowConf is an item of the maintenance_windows list.
+// Start - start of the window: RFC 3339 time or "HH:MM" UTC time of day
+// End   - end of the window in the same format as Start
+// Window which uses time of day is repeated daily.
+type MaintenanceWindowConf struct {
+	Start string `json:"start" mapstructure:"start"`
+	End   string `json:"end" mapstructure:"end"`
+}
+
+// MaintenanceWindow is a period of time when daemon rejects service calls.
+type MaintenanceWindow struct {
+	conf       MaintenanceWindowConf
+	start, end time.Time
+	// daily is true if window is repeated each day, then startOfDay and
+	// endOfDay are used instead of start and end
+	daily                bool
+	startOfDay, endOfDay time.Duration
+}
+
+func (window *MaintenanceWindow) String() string {
+	return fmt.Sprintf("%v-%v", window.conf.Start, window.conf.End)
+}
+
+// Active returns true if t is inside the window, until is the time the
+// window ends at.
+func (window *MaintenanceWindow) Active(t time.Time) (until time.Time, ok bool) {
+	if !window.daily {
+		return window.end, !t.Before(window.start) && t.Before(window.end)
+	}
+
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	sinceMidnight := t.Sub(midnight)
+	if window.startOfDay < window.endOfDay {
+		return midnight.Add(window.endOfDay), sinceMidnight >= window.startOfDay && sinceMidnight < window.endOfDay
+	}
+	// window crosses midnight, for example from 23:00 till 01:00
+	if sinceMidnight >= window.startOfDay {
+		return midnight.Add(24*time.Hour + window.endOfDay), true
+	}
+	return midnight.Add(window.endOfDay), sinceMidnight < window.endOfDay
+}
+
+// GetMaintenanceWindows returns list of the maintenance windows, empty list
+// means that daemon never enters maintenance mode.
+func GetMaintenanceWindows() (windows []*MaintenanceWindow, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return getMaintenanceWindowsFromVip(vip)
+}
+
+func getMaintenanceWindowsFromVip(config *viper.Viper) (windows []*MaintenanceWindow, err error) {
+	var confs []MaintenanceWindowConf
+	err = config.UnmarshalKey(MaintenanceWindowsKey, &confs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse \"%v\" configuration: %v", MaintenanceWindowsKey, err)
+	}
+
+	for _, conf := range confs {
+		window, err := NewMaintenanceWindow(conf)
+		if err != nil {
+			return nil, fmt.Errorf("%v contains incorrect window from \"%v\" till \"%v\": %v", MaintenanceWindowsKey, conf.Start, conf.End, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// NewMaintenanceWindow parses start and end of the window.
+func NewMaintenanceWindow(conf MaintenanceWindowConf) (window *MaintenanceWindow, err error) {
+	window = &MaintenanceWindow{conf: conf}
+
+	if startOfDay, e := parseTimeOfDay(conf.Start); e == nil {
+		endOfDay, e := parseTimeOfDay(conf.End)
+		if e != nil {
+			return nil, fmt.Errorf("end should be \"HH:MM\" time of day as start")
+		}
+		if startOfDay == endOfDay {
+			return nil, fmt.Errorf("window is empty")
+		}
+		window.daily = true
+		window.startOfDay, window.endOfDay = startOfDay, endOfDay
+		return window, nil
+	}
+
+	window.start, err = time.Parse(time.RFC3339, conf.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start is neither RFC 3339 time nor \"HH:MM\" time of day")
+	}
+	window.end, err = time.Parse(time.RFC3339, conf.End)
+	if err != nil {
+		return nil, fmt.Errorf("end should be RFC 3339 time as start")
+	}
+	if !window.end.After(window.start) {
+		return nil, fmt.Errorf("end should be after start")
+	}
+	return window, nil
+}
+
+func parseTimeOfDay(value string) (sinceMidnight time.Duration, err error) {
+	t, err := time.Parse(timeOfDayLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func maintenanceWindowsFromJson(t *testing.T, json string) []*MaintenanceWindow {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, json)
+	windows, err := getMaintenanceWindowsFromVip(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
+
+func maintenanceWindowsError(json string) error {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, json)
+	_, err := getMaintenanceWindowsFromVip(config)
+	return err
+}
+
+func TestGetMaintenanceWindowsNotSet(t *testing.T) {
+	windows := maintenanceWindowsFromJson(t, `{}`)
+
+	assert.Empty(t, windows)
+}
+
+func TestMaintenanceWindowTimeRange(t *testing.T) {
+	windows := maintenanceWindowsFromJson(t, `
+	{
+		"maintenance_windows": [
+			{ "start": "2018-10-20T01:00:00Z", "end": "2018-10-20T03:00:00Z" }
+		]
+	}`)
+	window := windows[0]
+	end := time.Date(2018, 10, 20, 3, 0, 0, 0, time.UTC)
+
+	_, ok := window.Active(time.Date(2018, 10, 20, 0, 59, 0, 0, time.UTC))
+	assert.False(t, ok)
+	until, ok := window.Active(time.Date(2018, 10, 20, 1, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, end, until)
+	_, ok = window.Active(time.Date(2018, 10, 20, 4, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)))
+	assert.True(t, ok, "time zone is taken into account")
+	_, ok = window.Active(end)
+	assert.False(t, ok)
+	_, ok = window.Active(time.Date(2018, 10, 21, 2, 0, 0, 0, time.UTC))
+	assert.False(t, ok, "time range is not repeated")
+	assert.Equal(t, "2018-10-20T01:00:00Z-2018-10-20T03:00:00Z", window.String())
+}
+
+func TestMaintenanceWindowDaily(t *testing.T) {
+	window := maintenanceWindowsFromJson(t, `
+	{
+		"maintenance_windows": [ { "start": "02:00", "end": "03:30" } ]
+	}`)[0]
+
+	_, ok := window.Active(time.Date(2018, 10, 20, 1, 59, 0, 0, time.UTC))
+	assert.False(t, ok)
+	until, ok := window.Active(time.Date(2018, 10, 20, 2, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2018, 10, 20, 3, 30, 0, 0, time.UTC), until)
+	until, ok = window.Active(time.Date(2018, 10, 25, 3, 29, 0, 0, time.UTC))
+	assert.True(t, ok, "window is repeated daily")
+	assert.Equal(t, time.Date(2018, 10, 25, 3, 30, 0, 0, time.UTC), until)
+	_, ok = window.Active(time.Date(2018, 10, 20, 3, 30, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestMaintenanceWindowDailyCrossesMidnight(t *testing.T) {
+	window := maintenanceWindowsFromJson(t, `
+	{
+		"maintenance_windows": [ { "start": "23:00", "end": "01:00" } ]
+	}`)[0]
+
+	until, ok := window.Active(time.Date(2018, 10, 20, 23, 30, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2018, 10, 21, 1, 0, 0, 0, time.UTC), until)
+	until, ok = window.Active(time.Date(2018, 10, 21, 0, 30, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2018, 10, 21, 1, 0, 0, 0, time.UTC), until)
+	_, ok = window.Active(time.Date(2018, 10, 21, 12, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestValidateMaintenanceWindowsIncorrectStart(t *testing.T) {
+	err := maintenanceWindowsError(`
+	{
+		"maintenance_windows": [ { "start": "tomorrow", "end": "03:00" } ]
+	}`)
+
+	assert.Equal(t, "maintenance_windows contains incorrect window from \"tomorrow\" till \"03:00\": start is neither RFC 3339 time nor \"HH:MM\" time of day", err.Error())
+}
+
+func TestValidateMaintenanceWindowsMixedFormats(t *testing.T) {
+	err := maintenanceWindowsError(`
+	{
+		"maintenance_windows": [ { "start": "02:00", "end": "2018-10-20T03:00:00Z" } ]
+	}`)
+
+	assert.Equal(t, "maintenance_windows contains incorrect window from \"02:00\" till \"2018-10-20T03:00:00Z\": end should be \"HH:MM\" time of day as start", err.Error())
+}
+
+func TestValidateMaintenanceWindowsEndBeforeStart(t *testing.T) {
+	err := maintenanceWindowsError(`
+	{
+		"maintenance_windows": [ { "start": "2018-10-20T03:00:00Z", "end": "2018-10-20T01:00:00Z" } ]
+	}`)
+
+	assert.Equal(t, "maintenance_windows contains incorrect window from \"2018-10-20T03:00:00Z\" till \"2018-10-20T01:00:00Z\": end should be after start", err.Error())
+}
+
+func TestValidateMaintenanceWindowsEmptyDailyWindow(t *testing.T) {
+	err := maintenanceWindowsError(`
+	{
+		"maintenance_windows": [ { "start": "02:00", "end": "02:00" } ]
+	}`)
+
+	assert.Equal(t, "maintenance_windows contains incorrect window from \"02:00\" till \"02:00\": window is empty", err.Error())
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Reasons of the payment validation failures which client-facing messages
+// can be customized by validation_error_messages.
+const (
+	// ValidationFailurePriceMismatch means that income is not equal to the
+	// price of the call.
+	ValidationFailurePriceMismatch = "price_mismatch"
+	// ValidationFailureMinIncome means that income is less than min_income.
+	ValidationFailureMinIncome = "min_income"
+	// ValidationFailureMessagePrice means that income doesn't cover all
+	// messages received in the stream.
+	ValidationFailureMessagePrice = "message_price"
+	// ValidationFailureSpendCap means that sender exceeds
+	// per_sender_spend_cap.
+	ValidationFailureSpendCap = "spend_cap"
+)
+
+// validationFailurePlaceholders contains placeholders which can be used in
+// the message template of each failure reason.
+var validationFailurePlaceholders = map[string][]string{
+	ValidationFailurePriceMismatch: {"expected", "received"},
+	ValidationFailureMinIncome:     {"min_income", "received"},
+	ValidationFailureMessagePrice:  {"expected", "received"},
+	ValidationFailureSpendCap:      {"spent", "cap", "income"},
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// GetValidationErrorMessages returns message templates by failure reason.
+func GetValidationErrorMessages() map[string]string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringMapString(ValidationErrorMessagesKey)
+}
+
+// RenderMessageTemplate replaces {name} placeholders in the template by
+// values, placeholders without value are kept as is.
+func RenderMessageTemplate(template string, values map[string]interface{}) string {
+	return placeholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := values[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		return fmt.Sprintf("%v", value)
+	})
+}
+
+// validateValidationErrorMessagesFromVip checks that templates are
+// configured for known failure reasons and use only placeholders which are
+// supported by the reason.
+func validateValidationErrorMessagesFromVip(config *viper.Viper) error {
+	for reason, template := range config.GetStringMapString(ValidationErrorMessagesKey) {
+		placeholders, ok := validationFailurePlaceholders[reason]
+		if !ok {
+			return fmt.Errorf("validation_error_messages contains unknown failure reason \"%v\", known reasons: %v", reason, knownValidationFailures())
+		}
+		for _, match := range placeholderRegexp.FindAllStringSubmatch(template, -1) {
+			if !contains(placeholders, match[1]) {
+				return fmt.Errorf("validation_error_messages template of \"%v\" contains unknown placeholder %v, supported placeholders: %v", reason, match[0], strings.Join(placeholders, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func knownValidationFailures() string {
+	reasons := make([]string, 0, len(validationFailurePlaceholders))
+	for reason := range validationFailurePlaceholders {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, ", ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMessageTemplate(t *testing.T) {
+	message := RenderMessageTemplate("Please pay {expected} cogs, you paid {received} cogs",
+		map[string]interface{}{"expected": big.NewInt(46), "received": big.NewInt(45)})
+
+	assert.Equal(t, "Please pay 46 cogs, you paid 45 cogs", message)
+}
+
+func TestRenderMessageTemplateKeepsUnknownPlaceholder(t *testing.T) {
+	message := RenderMessageTemplate("Price is {expected} {unknown}", map[string]interface{}{"expected": 46})
+
+	assert.Equal(t, "Price is 46 {unknown}", message)
+}
+
+func TestValidateValidationErrorMessages(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"validation_error_messages": {
+			"price_mismatch": "Please pay {expected} cogs, you paid {received} cogs",
+			"spend_cap": "Limit of {cap} cogs is reached"
+		}
+	}`)
+
+	err := validateValidationErrorMessagesFromVip(config)
+
+	assert.Nil(t, err)
+}
+
+func TestValidateValidationErrorMessagesUnknownReason(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"validation_error_messages": {
+			"underpayment": "Please pay more"
+		}
+	}`)
+
+	err := validateValidationErrorMessagesFromVip(config)
+
+	assert.Equal(t, "validation_error_messages contains unknown failure reason \"underpayment\", known reasons: message_price, min_income, price_mismatch, spend_cap", err.Error())
+}
+
+func TestValidateValidationErrorMessagesUnknownPlaceholder(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"validation_error_messages": {
+			"min_income": "Please pay at least {expected} cogs"
+		}
+	}`)
+
+	err := validateValidationErrorMessagesFromVip(config)
+
+	assert.Equal(t, "validation_error_messages template of \"min_income\" contains unknown placeholder {expected}, supported placeholders: min_income, received", err.Error())
+}
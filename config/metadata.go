@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// UnknownMetadataPolicyIgnore means that metadata headers which are not
+	// known to daemon are passed to the service as is.
+	UnknownMetadataPolicyIgnore = "ignore"
+	// UnknownMetadataPolicyReject means that calls containing metadata
+	// headers which are not known to daemon are rejected.
+	UnknownMetadataPolicyReject = "reject"
+)
+
+func validateUnknownMetadataPolicyFromVip(config *viper.Viper) error {
+	switch policy := config.GetString(UnknownMetadataPolicyKey); policy {
+	case UnknownMetadataPolicyIgnore, UnknownMetadataPolicyReject:
+		return nil
+	default:
+		return fmt.Errorf("unrecognized unknown_metadata_policy '%+v'", policy)
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateUnknownMetadataPolicyDefault(t *testing.T) {
+	assert.Nil(t, validateUnknownMetadataPolicyFromVip(Vip()))
+	assert.Equal(t, UnknownMetadataPolicyIgnore, Vip().GetString(UnknownMetadataPolicyKey))
+}
+
+func TestValidateUnknownMetadataPolicyReject(t *testing.T) {
+	config := viper.New()
+	config.Set(UnknownMetadataPolicyKey, "reject")
+
+	assert.Nil(t, validateUnknownMetadataPolicyFromVip(config))
+}
+
+func TestValidateUnknownMetadataPolicyUnknown(t *testing.T) {
+	config := viper.New()
+	config.Set(UnknownMetadataPolicyKey, "strict")
+
+	assert.EqualError(t, validateUnknownMetadataPolicyFromVip(config), "unrecognized unknown_metadata_policy 'strict'")
+}
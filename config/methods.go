@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// GetEnabledMethods returns list of full gRPC method names which are allowed
+// to be called, empty list means that all methods are allowed.
+func GetEnabledMethods() []string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringSlice(EnabledMethodsKey)
+}
+
+// GetDisabledMethods returns list of full gRPC method names which cannot be
+// called. It takes precedence over the list of enabled methods.
+func GetDisabledMethods() []string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringSlice(DisabledMethodsKey)
+}
+
+// GetCacheableMethods returns list of full gRPC method names which responses
+// can be cached, empty list means that response cache is disabled.
+func GetCacheableMethods() []string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringSlice(CacheableMethodsKey)
+}
+
+// GetPassthroughMethodTimeouts returns timeouts of the passthrough calls by
+// full gRPC method name. Method names are converted to lower case because
+// configuration keys are case insensitive.
+func GetPassthroughMethodTimeouts() (timeouts map[string]time.Duration, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return getPassthroughMethodTimeoutsFromVip(vip)
+}
+
+func getPassthroughMethodTimeoutsFromVip(config *viper.Viper) (timeouts map[string]time.Duration, err error) {
+	timeouts = make(map[string]time.Duration)
+	for method, value := range config.GetStringMapString(PassthroughMethodTimeoutsKey) {
+		if !isFullMethodName(method) {
+			return nil, fmt.Errorf("%v contains incorrect method name \"%v\", expected format is \"/package.Service/Method\"", PassthroughMethodTimeoutsKey, method)
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("%v contains incorrect timeout \"%v\" of method \"%v\", expected non-negative duration like \"30s\"", PassthroughMethodTimeoutsKey, value, method)
+		}
+		timeouts[strings.ToLower(method)] = timeout
+	}
+	return timeouts, nil
+}
+
+// GetPassthroughStreamingMethods returns set of full gRPC method names
+// which are server streaming. Method names are converted to lower case as
+// names in passthrough_method_timeouts.
+func GetPassthroughStreamingMethods() map[string]bool {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	methods := make(map[string]bool)
+	for _, method := range vip.GetStringSlice(PassthroughStreamingMethodsKey) {
+		methods[strings.ToLower(method)] = true
+	}
+	return methods
+}
+
+// GetHTTPRequestSchemas returns names of the JSON Schema files by URL path,
+// request bodies are validated against these schemas when daemon_type is
+// "http". Paths are converted to lower case because configuration keys are
+// case insensitive.
+func GetHTTPRequestSchemas() (schemas map[string]string, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return getHTTPRequestSchemasFromVip(vip)
+}
+
+func getHTTPRequestSchemasFromVip(config *viper.Viper) (schemas map[string]string, err error) {
+	schemas = make(map[string]string)
+	for path, file := range config.GetStringMapString(HTTPRequestSchemasKey) {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("%v contains incorrect URL path \"%v\", path should start with \"/\"", HTTPRequestSchemasKey, path)
+		}
+		if file == "" {
+			return nil, fmt.Errorf("%v contains empty schema file name for path \"%v\"", HTTPRequestSchemasKey, path)
+		}
+		schemas[strings.ToLower(path)] = file
+	}
+	return schemas, nil
+}
+
+// validateMethodListsFromVip checks that enabled_methods, disabled_methods,
+// cacheable_methods, passthrough_streaming_methods and
+// passthrough_method_timeouts contain full gRPC method names like
+// "/package.Service/Method".
+func validateMethodListsFromVip(config *viper.Viper) error {
+	for _, key := range []string{EnabledMethodsKey, DisabledMethodsKey, CacheableMethodsKey, PassthroughStreamingMethodsKey} {
+		for _, method := range config.GetStringSlice(key) {
+			if !isFullMethodName(method) {
+				return fmt.Errorf("%v contains incorrect method name \"%v\", expected format is \"/package.Service/Method\"", key, method)
+			}
+		}
+	}
+	_, err := getPassthroughMethodTimeoutsFromVip(config)
+	return err
+}
+
+func isFullMethodName(method string) bool {
+	if !strings.HasPrefix(method, "/") {
+		return false
+	}
+	parts := strings.Split(method[1:], "/")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMethodLists(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"enabled_methods": ["/example.Calculator/add", "/example.Calculator/sub"],
+		"disabled_methods": ["/example.Calculator/sub"]
+	}`)
+
+	err := validateMethodListsFromVip(config)
+
+	assert.Nil(t, err)
+}
+
+func TestValidateMethodListsIncorrectName(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"disabled_methods": ["example.Calculator.add"]
+	}`)
+
+	err := validateMethodListsFromVip(config)
+
+	assert.Equal(t, "disabled_methods contains incorrect method name \"example.Calculator.add\", expected format is \"/package.Service/Method\"", err.Error())
+}
+
+func TestValidateCacheableMethodsIncorrectName(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"cacheable_methods": ["/example.Calculator"]
+	}`)
+
+	err := validateMethodListsFromVip(config)
+
+	assert.Equal(t, "cacheable_methods contains incorrect method name \"/example.Calculator\", expected format is \"/package.Service/Method\"", err.Error())
+}
+
+func TestValidatePassthroughStreamingMethodsIncorrectName(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"passthrough_streaming_methods": ["example.Calculator/stream"]
+	}`)
+
+	err := validateMethodListsFromVip(config)
+
+	assert.Equal(t, "passthrough_streaming_methods contains incorrect method name \"example.Calculator/stream\", expected format is \"/package.Service/Method\"", err.Error())
+}
+
+func TestGetPassthroughStreamingMethods(t *testing.T) {
+	Vip().Set(PassthroughStreamingMethodsKey, []string{"/example.Calculator/Stream"})
+	defer Vip().Set(PassthroughStreamingMethodsKey, []string{})
+
+	assert.Equal(t, map[string]bool{"/example.calculator/stream": true}, GetPassthroughStreamingMethods())
+}
+
+func TestGetPassthroughMethodTimeouts(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"passthrough_method_timeouts": {
+			"/example.Calculator/add": "1s",
+			"/example.Calculator/Train": "10m"
+		}
+	}`)
+
+	timeouts, err := getPassthroughMethodTimeoutsFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"/example.calculator/add":   time.Second,
+		"/example.calculator/train": 10 * time.Minute,
+	}, timeouts)
+}
+
+func TestValidatePassthroughMethodTimeoutsIncorrectName(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"passthrough_method_timeouts": {"example.Calculator.add": "1s"}
+	}`)
+
+	err := validateMethodListsFromVip(config)
+
+	assert.Equal(t, "passthrough_method_timeouts contains incorrect method name \"example.calculator.add\", expected format is \"/package.Service/Method\"", err.Error())
+}
+
+func TestValidatePassthroughMethodTimeoutsIncorrectDuration(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"passthrough_method_timeouts": {"/example.Calculator/add": "fast"}
+	}`)
+
+	err := validateMethodListsFromVip(config)
+
+	assert.Equal(t, "passthrough_method_timeouts contains incorrect timeout \"fast\" of method \"/example.calculator/add\", expected non-negative duration like \"30s\"", err.Error())
+}
+
+func TestGetHTTPRequestSchemas(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"http_request_schemas": {
+			"/Add": "schemas/add.json",
+			"/sub": "schemas/sub.json"
+		}
+	}`)
+
+	schemas, err := getHTTPRequestSchemasFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"/add": "schemas/add.json", "/sub": "schemas/sub.json"}, schemas)
+}
+
+func TestValidateHTTPRequestSchemasIncorrectPath(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"http_request_schemas": { "add": "schemas/add.json" }
+	}`)
+
+	_, err := getHTTPRequestSchemasFromVip(config)
+
+	assert.Equal(t, "http_request_schemas contains incorrect URL path \"add\", path should start with \"/\"", err.Error())
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// OfflineAuthModeFree means that all calls are free when blockchain is
+	// disabled.
+	OfflineAuthModeFree = "free"
+	// OfflineAuthModeToken means that each call should contain one of the
+	// offline_auth_tokens when blockchain is disabled.
+	OfflineAuthModeToken = "token"
+)
+
+// GetOfflineAuthTokens returns list of tokens which are accepted in "token"
+// offline_auth_mode.
+func GetOfflineAuthTokens() []string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetStringSlice(OfflineAuthTokensKey)
+}
+
+// validateOfflineAuthFromVip checks that offline_auth_mode is known and it
+// is consistent with other settings. Offline authentication replaces income
+// validators, so it makes sense only when blockchain is disabled.
+func validateOfflineAuthFromVip(config *viper.Viper) error {
+	switch mode := config.GetString(OfflineAuthModeKey); mode {
+	case OfflineAuthModeFree:
+		return nil
+	case OfflineAuthModeToken:
+	default:
+		return fmt.Errorf("unrecognized offline_auth_mode '%+v'", mode)
+	}
+
+	if config.GetBool(BlockchainEnabledKey) {
+		return fmt.Errorf("offline_auth_mode '%v' requires blockchain_enabled to be false, calls are paid via payment channels otherwise", OfflineAuthModeToken)
+	}
+
+	tokens := config.GetStringSlice(OfflineAuthTokensKey)
+	if len(tokens) == 0 {
+		return fmt.Errorf("offline_auth_mode '%v' requires non-empty offline_auth_tokens", OfflineAuthModeToken)
+	}
+	for _, token := range tokens {
+		if token == "" {
+			return fmt.Errorf("offline_auth_tokens contains empty token")
+		}
+	}
+
+	return nil
+}
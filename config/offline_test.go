@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func offlineAuthConfig(json string) *viper.Viper {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, json)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func TestValidateOfflineAuthFreeMode(t *testing.T) {
+	config := offlineAuthConfig(`{"blockchain_enabled": false, "offline_auth_mode": "free"}`)
+
+	assert.Nil(t, validateOfflineAuthFromVip(config))
+}
+
+func TestValidateOfflineAuthFreeModeBlockchainEnabled(t *testing.T) {
+	config := offlineAuthConfig(`{"blockchain_enabled": true, "offline_auth_mode": "free"}`)
+
+	assert.Nil(t, validateOfflineAuthFromVip(config))
+}
+
+func TestValidateOfflineAuthTokenMode(t *testing.T) {
+	config := offlineAuthConfig(`{"blockchain_enabled": false, "offline_auth_mode": "token", "offline_auth_tokens": ["secret"]}`)
+
+	assert.Nil(t, validateOfflineAuthFromVip(config))
+}
+
+func TestValidateOfflineAuthTokenModeNoTokens(t *testing.T) {
+	config := offlineAuthConfig(`{"blockchain_enabled": false, "offline_auth_mode": "token"}`)
+
+	assert.EqualError(t, validateOfflineAuthFromVip(config), "offline_auth_mode 'token' requires non-empty offline_auth_tokens")
+}
+
+func TestValidateOfflineAuthTokenModeEmptyToken(t *testing.T) {
+	config := offlineAuthConfig(`{"blockchain_enabled": false, "offline_auth_mode": "token", "offline_auth_tokens": ["secret", ""]}`)
+
+	assert.EqualError(t, validateOfflineAuthFromVip(config), "offline_auth_tokens contains empty token")
+}
+
+func TestValidateOfflineAuthTokenModeBlockchainEnabled(t *testing.T) {
+	config := offlineAuthConfig(`{"blockchain_enabled": true, "offline_auth_mode": "token", "offline_auth_tokens": ["secret"]}`)
+
+	assert.EqualError(t, validateOfflineAuthFromVip(config), "offline_auth_mode 'token' requires blockchain_enabled to be false, calls are paid via payment channels otherwise")
+}
+
+func TestValidateOfflineAuthUnknownMode(t *testing.T) {
+	config := offlineAuthConfig(`{"offline_auth_mode": "unknown"}`)
+
+	assert.EqualError(t, validateOfflineAuthFromVip(config), "unrecognized offline_auth_mode 'unknown'")
+}
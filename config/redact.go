@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+var hiddenKeys = map[string]bool{
+	strings.ToUpper(PrivateKeyKey):             true,
+	strings.ToUpper(HdwalletMnemonicKey):       true,
+	strings.ToUpper(OfflineAuthTokensKey):      true,
+	strings.ToUpper(DiagnosticsTokenKey):       true,
+	strings.ToUpper(SubscriptionAdminTokenKey): true,
+}
+
+// hiddenValue replaces values of the secret keys.
+const hiddenValue = "***"
+
+// Redact returns deep copy of the configuration map where values of the
+// secret keys are replaced by "***". Key is secret if either its full dotted
+// path or its own name is one of the secret configuration keys, so secrets
+// are masked both in flat maps returned by viper.AllKeys() and in nested
+// maps and lists. Keys are compared case insensitively.
+func Redact(m map[string]interface{}) map[string]interface{} {
+	return redactMap("", m)
+}
+
+func redactMap(path string, m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if isHiddenKey(keyPath) || isHiddenKey(key) {
+			result[key] = hiddenValue
+		} else {
+			result[key] = redactValue(keyPath, value)
+		}
+	}
+	return result
+}
+
+func redactValue(path string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return redactMap(path, value)
+	case map[interface{}]interface{}:
+		return redactMap(path, cast.ToStringMap(value))
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = redactValue(path, item)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = redactMap(path, item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+func isHiddenKey(key string) bool {
+	return hiddenKeys[strings.ToUpper(key)]
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactFlatMap(t *testing.T) {
+	settings := map[string]interface{}{
+		"private_key":       "secret",
+		"DAEMON_PORT":       "8080",
+		"HDWALLET_MNEMONIC": "secret words",
+	}
+
+	redacted := Redact(settings)
+
+	assert.Equal(t, map[string]interface{}{
+		"private_key":       "***",
+		"DAEMON_PORT":       "8080",
+		"HDWALLET_MNEMONIC": "***",
+	}, redacted)
+	assert.Equal(t, "secret", settings["private_key"], "original map is not changed")
+}
+
+func TestRedactNestedSecrets(t *testing.T) {
+	settings := map[string]interface{}{
+		"log": map[string]interface{}{
+			"level": "info",
+		},
+		"wallets": []interface{}{
+			map[string]interface{}{"name": "main", "private_key": "secret-1"},
+			map[interface{}]interface{}{"name": "backup", "Private_Key": "secret-2"},
+		},
+		"diagnostics": map[string]interface{}{
+			"enabled": true,
+			"auth": map[string]interface{}{
+				"diagnostics_token": "token",
+			},
+		},
+	}
+
+	redacted := Redact(settings)
+
+	assert.Equal(t, map[string]interface{}{
+		"log": map[string]interface{}{
+			"level": "info",
+		},
+		"wallets": []interface{}{
+			map[string]interface{}{"name": "main", "private_key": "***"},
+			map[string]interface{}{"name": "backup", "Private_Key": "***"},
+		},
+		"diagnostics": map[string]interface{}{
+			"enabled": true,
+			"auth": map[string]interface{}{
+				"diagnostics_token": "***",
+			},
+		},
+	}, redacted)
+	assert.Equal(t, "secret-1", settings["wallets"].([]interface{})[0].(map[string]interface{})["private_key"])
+}
+
+func TestRedactNestedCopyIsIndependent(t *testing.T) {
+	settings := map[string]interface{}{
+		"log": map[string]interface{}{"level": "info"},
+	}
+
+	redacted := Redact(settings)
+	redacted["log"].(map[string]interface{})["level"] = "debug"
+
+	assert.Equal(t, "info", settings["log"].(map[string]interface{})["level"])
+}
+
+func TestRedactNil(t *testing.T) {
+	assert.Nil(t, Redact(nil))
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// validateReplicaModeFromVip checks that replica shares channel state with
+// primary daemon through etcd and doesn't use settings which write to the
+// storage other than payments.
+func validateReplicaModeFromVip(config *viper.Viper) error {
+	if !config.GetBool(ReplicaModeKey) {
+		return nil
+	}
+	if storageType := config.GetString(PaymentChannelStorageTypeKey); storageType != "etcd" {
+		return fmt.Errorf("payment_channel_storage_type should be \"etcd\" when replica_mode is set, got \"%v\"", storageType)
+	}
+	if config.GetBool(PersistRateLimitsKey) {
+		return fmt.Errorf("persist_rate_limits cannot be set when replica_mode is set as replica writes only payments to the storage")
+	}
+	if config.GetDuration(AutoClaimMaxChannelAgeKey) > 0 {
+		return fmt.Errorf("auto_claim_max_channel_age cannot be set when replica_mode is set as replica doesn't claim channels")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func replicaModeError(json string) error {
+	config := viper.New()
+	ReadConfigFromJsonString(config, json)
+	return validateReplicaModeFromVip(config)
+}
+
+func TestValidateReplicaModeDisabled(t *testing.T) {
+	assert.Nil(t, replicaModeError(`{"replica_mode": false, "payment_channel_storage_type": "memory", "persist_rate_limits": true}`))
+}
+
+func TestValidateReplicaMode(t *testing.T) {
+	assert.Nil(t, replicaModeError(`{"replica_mode": true, "payment_channel_storage_type": "etcd"}`))
+}
+
+func TestValidateReplicaModeMemoryStorage(t *testing.T) {
+	err := replicaModeError(`{"replica_mode": true, "payment_channel_storage_type": "memory"}`)
+
+	assert.EqualError(t, err, "payment_channel_storage_type should be \"etcd\" when replica_mode is set, got \"memory\"")
+}
+
+func TestValidateReplicaModePersistRateLimits(t *testing.T) {
+	err := replicaModeError(`{"replica_mode": true, "payment_channel_storage_type": "etcd", "persist_rate_limits": true}`)
+
+	assert.EqualError(t, err, "persist_rate_limits cannot be set when replica_mode is set as replica writes only payments to the storage")
+}
+
+func TestValidateReplicaModeAutoClaim(t *testing.T) {
+	err := replicaModeError(`{"replica_mode": true, "payment_channel_storage_type": "etcd", "auto_claim_max_channel_age": "168h"}`)
+
+	assert.EqualError(t, err, "auto_claim_max_channel_age cannot be set when replica_mode is set as replica doesn't claim channels")
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// jsonSchema is a node of JSON Schema document.
+type jsonSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Properties  map[string]*jsonSchema `json:"properties,omitempty"`
+	Items       *jsonSchema            `json:"items,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Description string                 `json:"description,omitempty"`
+}
+
+// schemaRequiredKeys are keys which should be set in configuration file.
+var schemaRequiredKeys = []string{RegistryAddressKey}
+
+// schemaEnums contains allowed values of the keys, nested keys are
+// separated by dot.
+var schemaEnums = map[string][]string{
+	DaemonTypeKey:                {"grpc", "http"},
+	EndpointSelectionKey:         {"priority", "round_robin", "random"},
+	IncomeValidationModeKey:      {"enforce", "observe"},
+	OfflineAuthModeKey:           {OfflineAuthModeFree, OfflineAuthModeToken},
+	PaymentChannelStorageTypeKey: {"etcd", "memory"},
+	PaymentSignatureSchemeKey:    {"raw", "eip712"},
+	SSLMinVersionKey:             {"1.0", "1.1", "1.2"},
+	TxTypeKey:                    {TxTypeLegacy, TxTypeDynamic},
+	UnknownMetadataPolicyKey:     {UnknownMetadataPolicyIgnore, UnknownMetadataPolicyReject},
+	"log.level":                  {"panic", "fatal", "error", "warn", "warning", "info", "debug"},
+	"log.formatter.type":         {"text", "json"},
+	"log.output.type":            {"file", "stdout", "both"},
+}
+
+// schemaOptionalKeys contains schema of the keys which have no default
+// values and so are absent in defaultConfigJson.
+var schemaOptionalKeys = map[string]*jsonSchema{
+	AutoClaimMaxChannelAgeKey:      {Type: "string", Description: "duration, for example \"24h\""},
+	BlockedSendersKey:              {Type: "array", Items: &jsonSchema{Type: "string"}},
+	BurstSize:                      {Type: "integer"},
+	CacheableMethodsKey:            {Type: "array", Items: &jsonSchema{Type: "string"}},
+	ClaimRevertPolicyKey:           {Type: "object", Description: "action by claim transaction revert reason"},
+	ClaimWebhookURLsKey:            {Type: "array", Items: &jsonSchema{Type: "string"}},
+	ConnectionIdleTimeoutKey:       {Type: "string", Description: "duration, for example \"5m\""},
+	CurrencyRatesKey:               {Type: "object", Description: "rates of currencies to accepted_currency"},
+	DisabledMethodsKey:             {Type: "array", Items: &jsonSchema{Type: "string"}},
+	EnabledMethodsKey:              {Type: "array", Items: &jsonSchema{Type: "string"}},
+	ExecutablePathKey:              {Type: "string"},
+	ExpectedChainIDKey:             {Type: "integer", Description: "chain id reported by ethereum_json_rpc_endpoint"},
+	ExpectedDaemonAddressKey:       {Type: "string", Description: "Ethereum address of private_key or hdwallet_mnemonic"},
+	FeaturesKey:                    {Type: "object", Description: "true or false by feature name"},
+	GrpcMaxConcurrentStreamsKey:    {Type: "integer"},
+	HTTPRequestSchemasKey:          {Type: "object", Description: "JSON Schema file names by URL path"},
+	IncomeValidationTimeoutKey:     {Type: "string", Description: "duration, for example \"2s\""},
+	MaxConnectionsKey:              {Type: "integer"},
+	MinChannelDepositKey:           {Type: "integer", Description: "minimal channel deposit in cogs"},
+	OfflineAuthTokensKey:           {Type: "array", Items: &jsonSchema{Type: "string"}},
+	PassthroughEndpointKey:         {Type: "string"},
+	PassthroughMethodTimeoutsKey:   {Type: "object", Description: "durations by full gRPC method name"},
+	PassthroughStreamingMethodsKey: {Type: "array", Items: &jsonSchema{Type: "string"}},
+	PassthroughTimeoutKey:          {Type: "string", Description: "duration, for example \"30s\""},
+	PriceCurrencyKey:               {Type: "string", Description: "currency which service prices are set in"},
+	PricingFileKey:                 {Type: "string"},
+	RateLimitPerMinute:             {Type: "integer"},
+	ServiceMetadataCIDKey:          {Type: "string", Description: "IPFS CID of the pinned service metadata"},
+	ShutdownOnConfigRemovalKey:     {Type: "boolean"},
+	SSLCipherSuitesKey:             {Type: "array", Items: &jsonSchema{Type: "string"}},
+	StartupTimeoutKey:              {Type: "string", Description: "duration, for example \"5m\""},
+	StatsdEndpointKey:              {Type: "string", Description: "host:port, for example \"127.0.0.1:8125\""},
+	ValidationErrorMessagesKey:     {Type: "object", Description: "message templates by failure reason"},
+	ValidationRecordFileKey:        {Type: "string"},
+	MaintenanceWindowsKey: {
+		Type:  "array",
+		Items: structSchema(reflect.TypeOf(MaintenanceWindowConf{}), "start", "end"),
+	},
+	ServicesKey: {
+		Type:  "array",
+		Items: structSchema(reflect.TypeOf(ServiceConf{}), "organization_id", "service_id"),
+	},
+}
+
+// GenerateJSONSchema returns JSON Schema of the configuration file. Schema
+// is built from the default configuration, typed configuration structures
+// and lists of allowed values of the keys.
+func GenerateJSONSchema() ([]byte, error) {
+	var defaults map[string]interface{}
+	if err := json.Unmarshal([]byte(defaultConfigJson), &defaults); err != nil {
+		return nil, fmt.Errorf("cannot parse default configuration: %v", err)
+	}
+
+	schema := valueSchema(defaults, "")
+	for key, property := range schemaOptionalKeys {
+		if _, ok := schema.Properties[key]; !ok {
+			schema.Properties[key] = property
+		}
+	}
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Required = schemaRequiredKeys
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// valueSchema infers schema from the default value of the key, path is a
+// dot separated path of the key.
+func valueSchema(value interface{}, path string) *jsonSchema {
+	schema := &jsonSchema{Enum: schemaEnums[path]}
+	switch value := value.(type) {
+	case string:
+		schema.Type = "string"
+	case bool:
+		schema.Type = "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			schema.Type = "integer"
+		} else {
+			schema.Type = "number"
+		}
+	case []interface{}:
+		schema.Type = "array"
+		if len(value) > 0 {
+			schema.Items = valueSchema(value[0], path)
+			schema.Items.Enum = nil
+		}
+	case map[string]interface{}:
+		schema.Type = "object"
+		schema.Properties = make(map[string]*jsonSchema)
+		for key, child := range value {
+			schema.Properties[key] = valueSchema(child, strings.TrimPrefix(path+"."+key, "."))
+		}
+	}
+	return schema
+}
+
+// structSchema returns schema of the structure using json tags as property
+// names.
+func structSchema(typ reflect.Type, required ...string) *jsonSchema {
+	schema := &jsonSchema{
+		Type:       "object",
+		Properties: make(map[string]*jsonSchema),
+		Required:   required,
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.String:
+			schema.Properties[name] = &jsonSchema{Type: "string"}
+		case reflect.Bool:
+			schema.Properties[name] = &jsonSchema{Type: "boolean"}
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			schema.Properties[name] = &jsonSchema{Type: "integer"}
+		case reflect.Float32, reflect.Float64:
+			schema.Properties[name] = &jsonSchema{Type: "number"}
+		}
+	}
+	return schema
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validateJSON is a minimal JSON Schema validator which supports subset of
+// keywords generated by GenerateJSONSchema.
+func validateJSON(schema *jsonSchema, value interface{}, path string) error {
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			found = found || allowed == value
+		}
+		if !found {
+			return fmt.Errorf("%v: value %v is not one of %v", path, value, schema.Enum)
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		return checkType(schema, path, "string")
+	case bool:
+		return checkType(schema, path, "boolean")
+	case float64:
+		if schema.Type == "integer" && value != math.Trunc(value) {
+			return fmt.Errorf("%v: %v is not an integer", path, value)
+		}
+		return checkType(schema, path, "number", "integer")
+	case []interface{}:
+		if err := checkType(schema, path, "array"); err != nil {
+			return err
+		}
+		for i, item := range value {
+			if schema.Items == nil {
+				continue
+			}
+			if err := validateJSON(schema.Items, item, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		if err := checkType(schema, path, "object"); err != nil {
+			return err
+		}
+		for _, key := range schema.Required {
+			if _, ok := value[key]; !ok {
+				return fmt.Errorf("%v: required key %v is missing", path, key)
+			}
+		}
+		for key, child := range value {
+			if property, ok := schema.Properties[key]; ok {
+				if err := validateJSON(property, child, path+"."+key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkType(schema *jsonSchema, path string, types ...string) error {
+	for _, typ := range types {
+		if schema.Type == typ {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: expected %v, got %v", path, schema.Type, types[0])
+}
+
+func generatedSchema(t *testing.T) *jsonSchema {
+	data, err := GenerateJSONSchema()
+	assert.Nil(t, err)
+	schema := &jsonSchema{}
+	assert.Nil(t, json.Unmarshal(data, schema))
+	return schema
+}
+
+func parseJSON(t *testing.T, data string) (value map[string]interface{}) {
+	assert.Nil(t, json.Unmarshal([]byte(data), &value))
+	return
+}
+
+func TestDefaultConfigMatchesSchema(t *testing.T) {
+	schema := generatedSchema(t)
+
+	assert.Nil(t, validateJSON(schema, parseJSON(t, defaultConfigJson), "$"))
+}
+
+func TestSchemaContainsTypesAndEnums(t *testing.T) {
+	schema := generatedSchema(t)
+
+	assert.Equal(t, "http://json-schema.org/draft-07/schema#", schema.Schema)
+	assert.Equal(t, []string{RegistryAddressKey}, schema.Required)
+	assert.Equal(t, &jsonSchema{Type: "string", Enum: []string{"grpc", "http"}}, schema.Properties[DaemonTypeKey])
+	assert.Equal(t, &jsonSchema{Type: "boolean"}, schema.Properties[BlockchainEnabledKey])
+	assert.Equal(t, &jsonSchema{Type: "integer"}, schema.Properties[HandlerQueueSizeKey])
+	assert.Equal(t, &jsonSchema{Type: "number"}, schema.Properties[ValidationRecordSampleRateKey])
+	assert.Equal(t, []string{"text", "json"}, schema.Properties[LogKey].Properties["formatter"].Properties["type"].Enum)
+	assert.Equal(t, &jsonSchema{Type: "array", Items: &jsonSchema{Type: "string"}}, schema.Properties[OfflineAuthTokensKey])
+	assert.Equal(t, []string{"organization_id", "service_id"}, schema.Properties[ServicesKey].Items.Required)
+	assert.Equal(t, &jsonSchema{Type: "string"}, schema.Properties[ServicesKey].Items.Properties["price_in_cogs"])
+}
+
+func TestSchemaRejectsIncorrectConfig(t *testing.T) {
+	schema := generatedSchema(t)
+
+	assert.EqualError(t, validateJSON(schema, parseJSON(t, `{"registry_address_key": "0x0", "daemon_type": "rest"}`), "$"),
+		"$.daemon_type: value rest is not one of [grpc http]")
+	assert.EqualError(t, validateJSON(schema, parseJSON(t, `{"registry_address_key": "0x0", "blockchain_enabled": "yes"}`), "$"),
+		"$.blockchain_enabled: expected boolean, got string")
+	assert.EqualError(t, validateJSON(schema, parseJSON(t, `{"registry_address_key": "0x0", "services": [{"organization_id": "org"}]}`), "$"),
+		"$.services[0]: required key service_id is missing")
+	assert.EqualError(t, validateJSON(schema, parseJSON(t, `{}`), "$"),
+		"$: required key registry_address_key is missing")
+}
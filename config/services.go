@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ServicesKey is a list of services which are served by the daemon. If it is
+// not set then organization_id, service_id and passthrough_* settings are
+// used to describe the only service.
+const ServicesKey = "services"
+
+// DefaultTokenDecimals is a number of decimals of the AGI token: price
+// expressed in AGI is multiplied by 10^8 to get price in cogs.
+const DefaultTokenDecimals = 8
+
+var (
+	// cidV0Regexp matches base58 encoded sha2-256 multihash
+	cidV0Regexp = regexp.MustCompile("^Qm[1-9A-HJ-NP-Za-km-z]{44}$")
+	// cidV1Regexp matches CIDv1 in lowercase base32 multibase encoding
+	cidV1Regexp = regexp.MustCompile("^b[a-z2-7]{58,}$")
+)
+
+// ServiceConf contains settings of the one (organization, service) pair which
+// is served by daemon.
+// OrganizationID      - id of the organization in Registry
+// ServiceID           - id of the service in Registry
+// PriceInCogs         - price of the call, service metadata price if empty
+// Price               - price of the call in tokens, alternative to PriceInCogs
+// TokenDecimals       - decimals of the token, DefaultTokenDecimals if not set
+// PassthroughEnabled  - whether calls are proxied to the service
+// PassthroughEndpoint - endpoint of the service to proxy calls to
+// MetadataCID         - IPFS CID of the service metadata, Registry is used if empty
+type ServiceConf struct {
+	OrganizationID      string `json:"organization_id" mapstructure:"organization_id"`
+	ServiceID           string `json:"service_id" mapstructure:"service_id"`
+	PriceInCogs         string `json:"price_in_cogs" mapstructure:"price_in_cogs"`
+	Price               string `json:"price" mapstructure:"price"`
+	TokenDecimals       *int   `json:"token_decimals" mapstructure:"token_decimals"`
+	PassthroughEnabled  bool   `json:"passthrough_enabled" mapstructure:"passthrough_enabled"`
+	PassthroughEndpoint string `json:"passthrough_endpoint" mapstructure:"passthrough_endpoint"`
+	MetadataCID         string `json:"service_metadata_cid" mapstructure:"service_metadata_cid"`
+	MinChannelDeposit   string `json:"min_channel_deposit" mapstructure:"min_channel_deposit"`
+}
+
+// GetPriceInCogs returns price of the service call in the smallest token
+// units. Price is converted from the price and token_decimals settings if
+// they are used instead of price_in_cogs. ok is false if price is not set in
+// configuration.
+func (conf *ServiceConf) GetPriceInCogs() (price *big.Int, ok bool, err error) {
+	if conf.Price != "" {
+		if conf.PriceInCogs != "" {
+			return nil, false, fmt.Errorf("both price_in_cogs and price are set for service %v/%v", conf.OrganizationID, conf.ServiceID)
+		}
+		return conf.convertPrice()
+	}
+
+	if conf.PriceInCogs == "" {
+		return nil, false, nil
+	}
+
+	price = &big.Int{}
+	err = price.UnmarshalText([]byte(conf.PriceInCogs))
+	if err != nil {
+		return nil, false, fmt.Errorf("incorrect price_in_cogs \"%v\" of service %v/%v", conf.PriceInCogs, conf.OrganizationID, conf.ServiceID)
+	}
+
+	return price, true, nil
+}
+
+// GetMinChannelDeposit returns minimal amount in cogs which should be
+// deposited in the payment channel to call the service, zero means that any
+// channel is accepted.
+func (conf *ServiceConf) GetMinChannelDeposit() (deposit *big.Int, err error) {
+	deposit = big.NewInt(0)
+	if conf.MinChannelDeposit == "" {
+		return deposit, nil
+	}
+
+	err = deposit.UnmarshalText([]byte(conf.MinChannelDeposit))
+	if err != nil || deposit.Sign() < 0 {
+		return nil, fmt.Errorf("incorrect min_channel_deposit \"%v\" of service %v/%v", conf.MinChannelDeposit, conf.OrganizationID, conf.ServiceID)
+	}
+
+	return deposit, nil
+}
+
+// GetTokenDecimals returns number of decimals of the service price unit.
+func (conf *ServiceConf) GetTokenDecimals() int {
+	if conf.TokenDecimals == nil {
+		return DefaultTokenDecimals
+	}
+	return *conf.TokenDecimals
+}
+
+func (conf *ServiceConf) convertPrice() (price *big.Int, ok bool, err error) {
+	decimals := conf.GetTokenDecimals()
+	if decimals < 0 || decimals > 77 {
+		return nil, false, fmt.Errorf("incorrect token_decimals %v of service %v/%v", decimals, conf.OrganizationID, conf.ServiceID)
+	}
+
+	value, ok := new(big.Rat).SetString(conf.Price)
+	if !ok || value.Sign() < 0 {
+		return nil, false, fmt.Errorf("incorrect price \"%v\" of service %v/%v", conf.Price, conf.OrganizationID, conf.ServiceID)
+	}
+
+	multiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	value.Mul(value, new(big.Rat).SetInt(multiplier))
+	if !value.IsInt() {
+		return nil, false, fmt.Errorf("price \"%v\" of service %v/%v has more than %v decimals", conf.Price, conf.OrganizationID, conf.ServiceID, decimals)
+	}
+
+	return new(big.Int).Set(value.Num()), true, nil
+}
+
+// ValidateCID returns error if cid is neither CIDv0 (base58 string starting
+// with "Qm") nor CIDv1 in base32 encoding. "ipfs://" prefix is allowed.
+func ValidateCID(cid string) error {
+	hash := strings.TrimPrefix(cid, "ipfs://")
+	if !cidV0Regexp.MatchString(hash) && !cidV1Regexp.MatchString(hash) {
+		return fmt.Errorf("incorrect IPFS CID \"%v\"", cid)
+	}
+	return nil
+}
+
+// GetServices returns list of the services served by daemon. If services
+// list is not configured then list of one service which is built from
+// organization_id, service_id, service_metadata_cid and passthrough_* keys is
+// returned.
+func GetServices() (services []*ServiceConf, err error) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return GetServicesFromVip(vip)
+}
+
+// GetServicesFromVip returns list of the services using viper config.
+func GetServicesFromVip(config *viper.Viper) (services []*ServiceConf, err error) {
+	if config.Get(ServicesKey) == nil {
+		services = []*ServiceConf{
+			{
+				OrganizationID:      config.GetString(OrganizationId),
+				ServiceID:           config.GetString(ServiceId),
+				PassthroughEnabled:  config.GetBool(PassthroughEnabledKey),
+				PassthroughEndpoint: config.GetString(PassthroughEndpointKey),
+				MetadataCID:         config.GetString(ServiceMetadataCIDKey),
+				MinChannelDeposit:   config.GetString(MinChannelDepositKey),
+			},
+		}
+		if _, err = services[0].GetMinChannelDeposit(); err != nil {
+			return nil, err
+		}
+		return services, validateMetadataCID(services[0])
+	}
+
+	err = config.UnmarshalKey(ServicesKey, &services)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse \"%v\" configuration: %v", ServicesKey, err)
+	}
+
+	return services, validateServices(services)
+}
+
+func validateServices(services []*ServiceConf) error {
+	if len(services) == 0 {
+		return fmt.Errorf("\"%v\" list is empty", ServicesKey)
+	}
+
+	unique := make(map[string]bool)
+	for _, service := range services {
+		if service.OrganizationID == "" || service.ServiceID == "" {
+			return fmt.Errorf("both organization_id and service_id should be set for each of \"%v\"", ServicesKey)
+		}
+		key := service.OrganizationID + "/" + service.ServiceID
+		if unique[key] {
+			return fmt.Errorf("service %v is listed twice in \"%v\"", key, ServicesKey)
+		}
+		unique[key] = true
+		if _, _, err := service.GetPriceInCogs(); err != nil {
+			return err
+		}
+		if _, err := service.GetMinChannelDeposit(); err != nil {
+			return err
+		}
+		if err := validateMetadataCID(service); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateMetadataCID(service *ServiceConf) error {
+	if service.MetadataCID == "" {
+		return nil
+	}
+	if err := ValidateCID(service.MetadataCID); err != nil {
+		return fmt.Errorf("%v of service %v/%v", err, service.OrganizationID, service.ServiceID)
+	}
+	return nil
+}
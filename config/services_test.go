@@ -0,0 +1,277 @@
+package config
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetServicesSingleServiceShorthand(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"organization_id": "org",
+		"service_id": "service",
+		"passthrough_enabled": true,
+		"passthrough_endpoint": "http://127.0.0.1:5001"
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*ServiceConf{
+		{
+			OrganizationID:      "org",
+			ServiceID:           "service",
+			PassthroughEnabled:  true,
+			PassthroughEndpoint: "http://127.0.0.1:5001",
+		},
+	}, services)
+}
+
+func TestGetServicesList(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"organization_id": "ignored",
+		"services": [
+			{
+				"organization_id": "org",
+				"service_id": "service-a",
+				"price_in_cogs": 10,
+				"passthrough_enabled": true,
+				"passthrough_endpoint": "http://127.0.0.1:5001"
+			},
+			{
+				"organization_id": "org",
+				"service_id": "service-b",
+				"price_in_cogs": "20"
+			}
+		]
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(services))
+	assert.Equal(t, "service-a", services[0].ServiceID)
+	assert.Equal(t, "http://127.0.0.1:5001", services[0].PassthroughEndpoint)
+	assert.Equal(t, "service-b", services[1].ServiceID)
+	assert.False(t, services[1].PassthroughEnabled)
+	price, ok, err := services[1].GetPriceInCogs()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(20), price)
+}
+
+func TestGetServicesDuplicate(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{ "organization_id": "org", "service_id": "service" },
+			{ "organization_id": "org", "service_id": "service" }
+		]
+	}`)
+
+	_, err := GetServicesFromVip(config)
+
+	assert.Equal(t, "service org/service is listed twice in \"services\"", err.Error())
+}
+
+func TestServicePriceIsNotSet(t *testing.T) {
+	service := &ServiceConf{OrganizationID: "org", ServiceID: "service"}
+
+	_, ok, err := service.GetPriceInCogs()
+
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestGetServicesPriceWithDifferentDecimals(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{
+				"organization_id": "org",
+				"service_id": "service-agi",
+				"price": "0.0000001"
+			},
+			{
+				"organization_id": "org",
+				"service_id": "service-usd",
+				"price": "1.5",
+				"token_decimals": 2
+			}
+		]
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultTokenDecimals, services[0].GetTokenDecimals())
+	price, ok, err := services[0].GetPriceInCogs()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(10), price)
+	assert.Equal(t, 2, services[1].GetTokenDecimals())
+	price, ok, err = services[1].GetPriceInCogs()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(150), price)
+}
+
+func TestServicePriceZeroDecimals(t *testing.T) {
+	decimals := 0
+	service := &ServiceConf{OrganizationID: "org", ServiceID: "service", Price: "7", TokenDecimals: &decimals}
+
+	price, ok, err := service.GetPriceInCogs()
+
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(7), price)
+}
+
+func TestServicePriceTooManyDecimals(t *testing.T) {
+	decimals := 2
+	service := &ServiceConf{OrganizationID: "org", ServiceID: "service", Price: "1.505", TokenDecimals: &decimals}
+
+	_, _, err := service.GetPriceInCogs()
+
+	assert.Equal(t, "price \"1.505\" of service org/service has more than 2 decimals", err.Error())
+}
+
+func TestServicePriceAndPriceInCogsAreSet(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{ "organization_id": "org", "service_id": "service", "price": "1", "price_in_cogs": 100000000 }
+		]
+	}`)
+
+	_, err := GetServicesFromVip(config)
+
+	assert.Equal(t, "both price_in_cogs and price are set for service org/service", err.Error())
+}
+
+func TestServicePriceIsIncorrect(t *testing.T) {
+	service := &ServiceConf{OrganizationID: "org", ServiceID: "service", Price: "-1"}
+
+	_, _, err := service.GetPriceInCogs()
+
+	assert.Equal(t, "incorrect price \"-1\" of service org/service", err.Error())
+}
+
+func TestGetServicesMetadataCID(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{ "organization_id": "org", "service_id": "service-a", "service_metadata_cid": "QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ" },
+			{ "organization_id": "org", "service_id": "service-b", "service_metadata_cid": "ipfs://bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi" },
+			{ "organization_id": "org", "service_id": "service-c" }
+		]
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ", services[0].MetadataCID)
+	assert.Equal(t, "ipfs://bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi", services[1].MetadataCID)
+	assert.Equal(t, "", services[2].MetadataCID)
+}
+
+func TestGetServicesMetadataCIDShorthand(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"organization_id": "org",
+		"service_id": "service",
+		"service_metadata_cid": "QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ"
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ", services[0].MetadataCID)
+}
+
+func TestGetServicesMetadataCIDIsIncorrect(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{ "organization_id": "org", "service_id": "service", "service_metadata_cid": "Qm0000" }
+		]
+	}`)
+
+	_, err := GetServicesFromVip(config)
+
+	assert.Equal(t, "incorrect IPFS CID \"Qm0000\" of service org/service", err.Error())
+}
+
+func TestGetServicesMinChannelDeposit(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{ "organization_id": "org", "service_id": "service-a", "min_channel_deposit": 1000 },
+			{ "organization_id": "org", "service_id": "service-b" }
+		]
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	deposit, err := services[0].GetMinChannelDeposit()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(1000), deposit)
+	deposit, err = services[1].GetMinChannelDeposit()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(0), deposit)
+}
+
+func TestGetServicesMinChannelDepositShorthand(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"organization_id": "org",
+		"service_id": "service",
+		"min_channel_deposit": 500
+	}`)
+
+	services, err := GetServicesFromVip(config)
+
+	assert.Nil(t, err)
+	deposit, err := services[0].GetMinChannelDeposit()
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(500), deposit)
+}
+
+func TestGetServicesMinChannelDepositIsIncorrect(t *testing.T) {
+	var config = viper.New()
+	ReadConfigFromJsonString(config, `
+	{
+		"services": [
+			{ "organization_id": "org", "service_id": "service", "min_channel_deposit": -1 }
+		]
+	}`)
+
+	_, err := GetServicesFromVip(config)
+
+	assert.Equal(t, "incorrect min_channel_deposit \"-1\" of service org/service", err.Error())
+}
+
+func TestValidateCID(t *testing.T) {
+	assert.Nil(t, ValidateCID("QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ"))
+	assert.Nil(t, ValidateCID("ipfs://QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6VeJ"))
+	assert.Nil(t, ValidateCID("bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"))
+	assert.NotNil(t, ValidateCID(""))
+	assert.NotNil(t, ValidateCID("QmQC9EoVdXRWmg8qm25Hkj4fG79YAgpNJCMDoCnknZ6Ve0"), "0 is not in base58 alphabet")
+	assert.NotNil(t, ValidateCID("bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdI"), "uppercase is not base32")
+	assert.NotNil(t, ValidateCID("https://example.com/metadata.json"))
+}
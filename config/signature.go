@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/viper"
+)
+
+// ConfigSignatureSuffix is appended to the configuration file name to get
+// name of the detached signature file.
+const ConfigSignatureSuffix = ".sig"
+
+// configSignaturePrefix is the same prefix which is added by Ethereum
+// wallets to the hash of the message before signing it.
+var configSignaturePrefix = []byte("\x19Ethereum Signed Message:\n32")
+
+// configSigner is a signer of the configuration loaded by
+// LoadSignedConfig, nil if configuration is not signed. ReloadConfig()
+// accepts new configuration only if it is signed by the same signer.
+var configSigner *common.Address
+
+// LoadSignedConfig loads configuration file after checking that it is signed
+// by the signer. Signature is read from the file with ConfigSignatureSuffix
+// and contains hex encoded Ethereum signature of the Keccak256 hash of the
+// configuration file content. Error is returned if signature is missing or
+// doesn't match the signer.
+func LoadSignedConfig(configFile string, signer common.Address) error {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
+	return readSignedConfig(configFile, signer)
+}
+
+// readSignedConfig reads configuration file and replaces current
+// configuration by it if signature is correct. Current configuration is
+// kept if file cannot be verified or parsed. vipMutex should be locked by
+// caller.
+func readSignedConfig(configFile string, signer common.Address) error {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	err = VerifyConfigSignature(data, configFile+ConfigSignatureSuffix, signer)
+	if err != nil {
+		return err
+	}
+
+	// file is parsed into separate instance first because ReadConfig()
+	// clears settings before parsing
+	parsed := viper.New()
+	parsed.SetConfigFile(configFile)
+	if err = parsed.ReadConfig(bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	vip.SetConfigFile(configFile)
+	if err = vip.ReadConfig(bytes.NewReader(data)); err != nil {
+		return err
+	}
+	fileSettings = readFileSettings(configFile, data)
+	configSigner = &signer
+	return nil
+}
+
+// VerifyConfigSignature checks that signature from the signatureFile is a
+// signature of the data made by the signer.
+func VerifyConfigSignature(data []byte, signatureFile string, signer common.Address) error {
+	encoded, err := ioutil.ReadFile(signatureFile)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("configuration signature file \"%v\" is not found", signatureFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(encoded)), "0x"))
+	if err != nil || len(signature) != 65 {
+		return fmt.Errorf("configuration signature file \"%v\" should contain 65 bytes hex encoded signature", signatureFile)
+	}
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+
+	hash := crypto.Keccak256(configSignaturePrefix, crypto.Keccak256(data))
+	publicKey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return fmt.Errorf("incorrect configuration signature: %v", err)
+	}
+
+	if actual := crypto.PubkeyToAddress(*publicKey); actual != signer {
+		return fmt.Errorf("configuration is signed by %v instead of %v", actual.Hex(), signer.Hex())
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+const signedConfigJson = `{"daemon_end_point": "127.0.0.1:9999"}`
+
+func signConfig(data []byte, key *ecdsa.PrivateKey) string {
+	hash := crypto.Keccak256(configSignaturePrefix, crypto.Keccak256(data))
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		panic(err)
+	}
+	signature[64] += 27
+	return "0x" + hex.EncodeToString(signature)
+}
+
+func writeSignedConfig(t *testing.T, signature string) (configFile string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "signed-config")
+	assert.Nil(t, err)
+	configFile = filepath.Join(dir, "snetd.config.json")
+	assert.Nil(t, ioutil.WriteFile(configFile, []byte(signedConfigJson), 0600))
+	if signature != "" {
+		assert.Nil(t, ioutil.WriteFile(configFile+ConfigSignatureSuffix, []byte(signature+"\n"), 0600))
+	}
+	return configFile, func() { os.RemoveAll(dir) }
+}
+
+func newSigner(t *testing.T) (key *ecdsa.PrivateKey, address common.Address) {
+	key, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestLoadSignedConfigValidSignature(t *testing.T) {
+	key, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, signConfig([]byte(signedConfigJson), key))
+	defer cleanup()
+	defer WithDefaultConfig()()
+
+	err := LoadSignedConfig(configFile, signer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:9999", GetString(DaemonEndPoint))
+}
+
+func TestReloadSignedConfigVerifiesSignature(t *testing.T) {
+	key, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, signConfig([]byte(signedConfigJson), key))
+	defer cleanup()
+	defer WithDefaultConfig()()
+	assert.Nil(t, LoadSignedConfig(configFile, signer))
+
+	tampered := []byte(`{"daemon_end_point": "127.0.0.1:8888"}`)
+	assert.Nil(t, ioutil.WriteFile(configFile, tampered, 0600))
+	err := ReloadConfig()
+
+	assert.Contains(t, err.Error(), "instead of "+signer.Hex())
+	assert.Equal(t, "127.0.0.1:9999", GetString(DaemonEndPoint), "previous configuration is kept")
+
+	assert.Nil(t, ioutil.WriteFile(configFile+ConfigSignatureSuffix, []byte(signConfig(tampered, key)), 0600))
+	assert.Nil(t, ReloadConfig())
+	assert.Equal(t, "127.0.0.1:8888", GetString(DaemonEndPoint))
+}
+
+func TestReloadSignedConfigKeepsConfigurationOnParseError(t *testing.T) {
+	key, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, signConfig([]byte(signedConfigJson), key))
+	defer cleanup()
+	defer WithDefaultConfig()()
+	assert.Nil(t, LoadSignedConfig(configFile, signer))
+
+	malformed := []byte(`{"daemon_end_point": `)
+	assert.Nil(t, ioutil.WriteFile(configFile, malformed, 0600))
+	assert.Nil(t, ioutil.WriteFile(configFile+ConfigSignatureSuffix, []byte(signConfig(malformed, key)), 0600))
+
+	assert.NotNil(t, ReloadConfig())
+	assert.Equal(t, "127.0.0.1:9999", GetString(DaemonEndPoint))
+}
+
+func TestLoadSignedConfigInvalidSignature(t *testing.T) {
+	key, _ := newSigner(t)
+	_, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, signConfig([]byte(signedConfigJson), key))
+	defer cleanup()
+
+	err := LoadSignedConfig(configFile, signer)
+
+	assert.EqualError(t, err, "configuration is signed by "+crypto.PubkeyToAddress(key.PublicKey).Hex()+" instead of "+signer.Hex())
+}
+
+func TestLoadSignedConfigTamperedContent(t *testing.T) {
+	key, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, signConfig([]byte(`{}`), key))
+	defer cleanup()
+
+	err := LoadSignedConfig(configFile, signer)
+
+	assert.Contains(t, err.Error(), "instead of "+signer.Hex())
+}
+
+func TestLoadSignedConfigMalformedSignature(t *testing.T) {
+	_, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, "0x1234")
+	defer cleanup()
+
+	err := LoadSignedConfig(configFile, signer)
+
+	assert.EqualError(t, err, "configuration signature file \""+configFile+ConfigSignatureSuffix+"\" should contain 65 bytes hex encoded signature")
+}
+
+func TestLoadSignedConfigMissingSignature(t *testing.T) {
+	_, signer := newSigner(t)
+	configFile, cleanup := writeSignedConfig(t, "")
+	defer cleanup()
+
+	err := LoadSignedConfig(configFile, signer)
+
+	assert.EqualError(t, err, "configuration signature file \""+configFile+ConfigSignatureSuffix+"\" is not found")
+}
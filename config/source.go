@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Sources of the configuration value in order of precedence.
+const (
+	// SourceOverride means that value is set by Set or by command line flag.
+	SourceOverride = "override"
+	// SourceEnv means that value is set by environment variable.
+	SourceEnv = "env"
+	// SourceFile means that value is read from the configuration file.
+	SourceFile = "file"
+	// SourceDefault means that value is a default one.
+	SourceDefault = "default"
+)
+
+// envPrefix is a prefix of the environment variables read by vip.
+var envPrefix string
+
+// overrides, flags and fileSettings keep information required to find source
+// of the configuration value, they are guarded by vipMutex.
+var (
+	overrides    = make(map[string]bool)
+	flags        = make(map[string]*pflag.Flag)
+	fileSettings = viper.New()
+)
+
+// Set overrides value of the key.
+func Set(key string, value interface{}) {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
+	overrides[strings.ToLower(key)] = true
+	vip.Set(key, value)
+}
+
+// BindPFlag binds key to the command line flag, value of the flag overrides
+// value of the key if flag is set.
+func BindPFlag(key string, flag *pflag.Flag) error {
+	vipMutex.Lock()
+	defer vipMutex.Unlock()
+
+	flags[strings.ToLower(key)] = flag
+	return vip.BindPFlag(key, flag)
+}
+
+// GetStringWithSource returns value of the key and source of this value: one
+// of SourceOverride, SourceEnv, SourceFile or SourceDefault. Source is empty
+// if key is not set at all. It helps to find out why value differs from the
+// one which is expected.
+func GetStringWithSource(key string) (value string, source string) {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.GetString(key), getSource(strings.ToLower(key))
+}
+
+func getSource(key string) string {
+	if flag, ok := flags[key]; overrides[key] || (ok && flag.Changed) {
+		return SourceOverride
+	}
+	if os.Getenv(strings.ToUpper(envPrefix+"_"+key)) != "" {
+		return SourceEnv
+	}
+	if fileSettings.IsSet(key) {
+		return SourceFile
+	}
+	if vip.IsSet(key) {
+		return SourceDefault
+	}
+	return ""
+}
+
+// readFileSettings returns settings from the configuration file only. data
+// is a content of the file, if it is nil then file is read.
+func readFileSettings(configFile string, data []byte) *viper.Viper {
+	settings := viper.New()
+	settings.SetConfigFile(configFile)
+
+	var err error
+	if data != nil {
+		err = settings.ReadConfig(bytes.NewReader(data))
+	} else {
+		err = settings.ReadInConfig()
+	}
+	if err != nil {
+		log.WithError(err).WithField("configFile", configFile).Warn("Cannot read configuration file to find sources of the values")
+		return viper.New()
+	}
+	return settings
+}
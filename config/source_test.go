@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// withCleanSources replaces global configuration by the default one and
+// returns function to restore it.
+func withCleanSources() (restore func()) {
+	savedVip, savedOverrides, savedFlags, savedFileSettings := vip, overrides, flags, fileSettings
+	vip = newVip(envPrefix)
+	overrides = make(map[string]bool)
+	flags = make(map[string]*pflag.Flag)
+	fileSettings = viper.New()
+	return func() {
+		vip, overrides, flags, fileSettings = savedVip, savedOverrides, savedFlags, savedFileSettings
+	}
+}
+
+func TestGetStringWithSourceDefault(t *testing.T) {
+	defer withCleanSources()()
+
+	value, source := GetStringWithSource(DaemonTypeKey)
+
+	assert.Equal(t, "grpc", value)
+	assert.Equal(t, SourceDefault, source)
+}
+
+func TestGetStringWithSourceFile(t *testing.T) {
+	defer withCleanSources()()
+	configFile := writeTestConfigFile(t, `{ "daemon_type": "http", "log": { "level": "debug" } }`)
+	defer os.Remove(configFile)
+	assert.Nil(t, LoadConfig(configFile))
+
+	value, source := GetStringWithSource(DaemonTypeKey)
+	nestedValue, nestedSource := GetStringWithSource("log.level")
+	_, defaultSource := GetStringWithSource(DaemonEndPoint)
+
+	assert.Equal(t, "http", value)
+	assert.Equal(t, SourceFile, source)
+	assert.Equal(t, "debug", nestedValue)
+	assert.Equal(t, SourceFile, nestedSource)
+	assert.Equal(t, SourceDefault, defaultSource)
+}
+
+func TestGetStringWithSourceEnv(t *testing.T) {
+	defer withCleanSources()()
+	configFile := writeTestConfigFile(t, `{ "daemon_type": "http" }`)
+	defer os.Remove(configFile)
+	assert.Nil(t, LoadConfig(configFile))
+	os.Setenv(envPrefix+"_DAEMON_TYPE", "env-type")
+	defer os.Unsetenv(envPrefix + "_DAEMON_TYPE")
+
+	value, source := GetStringWithSource(DaemonTypeKey)
+
+	assert.Equal(t, "env-type", value)
+	assert.Equal(t, SourceEnv, source)
+}
+
+func TestGetStringWithSourceOverride(t *testing.T) {
+	defer withCleanSources()()
+	os.Setenv(envPrefix+"_DAEMON_TYPE", "env-type")
+	defer os.Unsetenv(envPrefix + "_DAEMON_TYPE")
+
+	Set(DaemonTypeKey, "override-type")
+	value, source := GetStringWithSource(DaemonTypeKey)
+
+	assert.Equal(t, "override-type", value)
+	assert.Equal(t, SourceOverride, source)
+}
+
+func TestGetStringWithSourceFlag(t *testing.T) {
+	defer withCleanSources()()
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("type", "grpc", "daemon type")
+	assert.Nil(t, BindPFlag(DaemonTypeKey, flagSet.Lookup("type")))
+
+	_, notChangedSource := GetStringWithSource(DaemonTypeKey)
+	flagSet.Parse([]string{"--type", "http"})
+	value, source := GetStringWithSource(DaemonTypeKey)
+
+	assert.Equal(t, SourceDefault, notChangedSource)
+	assert.Equal(t, "http", value)
+	assert.Equal(t, SourceOverride, source)
+}
+
+func TestGetStringWithSourceNotSet(t *testing.T) {
+	defer withCleanSources()()
+
+	value, source := GetStringWithSource("unknown_key")
+
+	assert.Equal(t, "", value)
+	assert.Equal(t, "", source)
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+func validateSubscriptionFromVip(config *viper.Viper) error {
+	price, err := GetBigIntFromViper(config, SubscriptionPriceKey)
+	if err != nil || price.Sign() < 0 {
+		return fmt.Errorf("subscription_price should be non-negative integer, got \"%v\"", config.GetString(SubscriptionPriceKey))
+	}
+	if price.Sign() == 0 {
+		return nil
+	}
+
+	if config.GetDuration(SubscriptionPeriodKey) <= 0 {
+		return fmt.Errorf("subscription_period should be positive duration, got \"%v\"", config.GetString(SubscriptionPeriodKey))
+	}
+	if config.GetString(SubscriptionAdminTokenKey) == "" {
+		return errors.New("subscription_admin_token should be set when subscription_price is set")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func subscriptionConfig(configJSON string) *viper.Viper {
+	config := viper.New()
+	ReadConfigFromJsonString(config, configJSON)
+	return config
+}
+
+func TestValidateSubscriptionDisabledByDefault(t *testing.T) {
+	assert.Nil(t, validateSubscriptionFromVip(Vip()))
+}
+
+func TestValidateSubscriptionEnabled(t *testing.T) {
+	config := subscriptionConfig(`{"subscription_price": 1000, "subscription_period": "24h", "subscription_admin_token": "secret"}`)
+
+	assert.Nil(t, validateSubscriptionFromVip(config))
+}
+
+func TestValidateSubscriptionNegativePrice(t *testing.T) {
+	config := subscriptionConfig(`{"subscription_price": -1}`)
+
+	assert.EqualError(t, validateSubscriptionFromVip(config), "subscription_price should be non-negative integer, got \"-1\"")
+}
+
+func TestValidateSubscriptionIncorrectPeriod(t *testing.T) {
+	config := subscriptionConfig(`{"subscription_price": 1000, "subscription_period": "0s", "subscription_admin_token": "secret"}`)
+
+	assert.EqualError(t, validateSubscriptionFromVip(config), "subscription_period should be positive duration, got \"0s\"")
+}
+
+func TestValidateSubscriptionWithoutToken(t *testing.T) {
+	config := subscriptionConfig(`{"subscription_price": 1000, "subscription_period": "24h"}`)
+
+	assert.EqualError(t, validateSubscriptionFromVip(config), "subscription_admin_token should be set when subscription_price is set")
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTLSConfigDefault(t *testing.T) {
+	tlsConfig, err := NewTLSConfig()
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.CipherSuites)
+}
+
+func TestNewTLSConfigFromVip(t *testing.T) {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, `{
+		"ssl_min_version": "1.1",
+		"ssl_cipher_suites": ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "tls_ecdhe_rsa_with_aes_256_gcm_sha384"]
+	}`)
+	assert.Nil(t, err)
+
+	tlsConfig, err := NewTLSConfigFromVip(config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS11), tlsConfig.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, tlsConfig.CipherSuites)
+}
+
+func TestNewTLSConfigFromVipIncorrectVersion(t *testing.T) {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, `{ "ssl_min_version": "1.4" }`)
+	assert.Nil(t, err)
+
+	_, err = NewTLSConfigFromVip(config)
+
+	assert.Equal(t, "unrecognized ssl_min_version '1.4', supported values: 1.0, 1.1, 1.2", err.Error())
+}
+
+func TestNewTLSConfigFromVipUnknownCipherSuite(t *testing.T) {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, `{ "ssl_min_version": "1.2", "ssl_cipher_suites": ["TLS_FAKE_SUITE"] }`)
+	assert.Nil(t, err)
+
+	_, err = NewTLSConfigFromVip(config)
+
+	assert.Equal(t, "unknown cipher suite 'TLS_FAKE_SUITE' in ssl_cipher_suites", err.Error())
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// TxTypeLegacy means that transaction pays fixed gas price.
+	TxTypeLegacy = "legacy"
+	// TxTypeDynamic means that EIP-1559 transaction is sent, it pays base fee
+	// of the block plus priority fee limited by max fee per gas.
+	TxTypeDynamic = "dynamic"
+)
+
+// Reasons of the channel claim transaction revert which can be handled
+// differently using claim_revert_policy.
+const (
+	// ClaimRevertAlreadyClaimed means that payment is already claimed, for
+	// example by previous transaction which was not waited for.
+	ClaimRevertAlreadyClaimed = "already_claimed"
+	// ClaimRevertInsufficientFunds means that channel has less funds than
+	// amount of the payment.
+	ClaimRevertInsufficientFunds = "insufficient_funds"
+	// ClaimRevertUnknown is any other reason.
+	ClaimRevertUnknown = "unknown"
+)
+
+// Actions which are applied to the claim which transaction is reverted.
+const (
+	// ClaimRevertRetry keeps claim in progress, so it can be retried.
+	ClaimRevertRetry = "retry"
+	// ClaimRevertMarkFailed moves claim to the list of failed claims which
+	// are not retried.
+	ClaimRevertMarkFailed = "mark_failed"
+	// ClaimRevertMarkClaimed finishes claim as if transaction succeeded.
+	ClaimRevertMarkClaimed = "mark_claimed"
+)
+
+// defaultClaimRevertPolicy contains actions of the reasons which are not set
+// in claim_revert_policy.
+var defaultClaimRevertPolicy = map[string]string{
+	ClaimRevertAlreadyClaimed:    ClaimRevertMarkClaimed,
+	ClaimRevertInsufficientFunds: ClaimRevertRetry,
+	ClaimRevertUnknown:           ClaimRevertRetry,
+}
+
+// minGasBumpPercent is a minimal gas price increase of the replacement
+// transaction which is accepted by Ethereum nodes.
+const minGasBumpPercent = 10
+
+// validateGasBumpFromVip checks settings of the transaction resubmission.
+// tx_max_gas_price is required when tx_gas_bump_percent is set because
+// otherwise gas price of the stuck transaction would grow without limit.
+func validateGasBumpFromVip(config *viper.Viper) error {
+	percent := config.GetInt(TxGasBumpPercentKey)
+	if percent < 0 {
+		return fmt.Errorf("tx_gas_bump_percent should be non-negative number, got \"%v\"", config.GetString(TxGasBumpPercentKey))
+	}
+	if percent > 0 && percent < minGasBumpPercent {
+		return fmt.Errorf("tx_gas_bump_percent should be at least %v because nodes reject replacement transactions with lower gas price increase, got \"%v\"", minGasBumpPercent, config.GetString(TxGasBumpPercentKey))
+	}
+
+	maxGasPrice, err := GetBigIntFromViper(config, TxMaxGasPriceKey)
+	if err != nil || maxGasPrice.Sign() < 0 {
+		return fmt.Errorf("tx_max_gas_price should be non-negative integer, got \"%v\"", config.GetString(TxMaxGasPriceKey))
+	}
+
+	if percent == 0 {
+		return nil
+	}
+	if maxGasPrice.Sign() == 0 {
+		return fmt.Errorf("tx_max_gas_price should be set when tx_gas_bump_percent is set")
+	}
+	if config.GetDuration(TxResubmitIntervalKey) <= 0 {
+		return fmt.Errorf("tx_resubmit_interval should be positive duration, got \"%v\"", config.GetString(TxResubmitIntervalKey))
+	}
+	return nil
+}
+
+// validateGasPriceCacheFromVip checks settings of the gas price which is
+// fetched in background.
+func validateGasPriceCacheFromVip(config *viper.Viper) error {
+	if config.GetDuration(GasPriceRefreshIntervalKey) < 0 {
+		return fmt.Errorf("gas_price_refresh_interval should be non-negative duration, got \"%v\"", config.GetString(GasPriceRefreshIntervalKey))
+	}
+	if config.GetInt(GasPriceMultiplierPercentKey) <= 0 {
+		return fmt.Errorf("gas_price_multiplier_percent should be positive number, got \"%v\"", config.GetString(GasPriceMultiplierPercentKey))
+	}
+	return nil
+}
+
+// validateTxTypeFromVip checks type of the transactions and fees of the
+// EIP-1559 transactions, zero fee means that it is derived from the chain.
+func validateTxTypeFromVip(config *viper.Viper) error {
+	switch txType := config.GetString(TxTypeKey); txType {
+	case TxTypeLegacy, TxTypeDynamic:
+	default:
+		return fmt.Errorf("unrecognized tx_type '%+v'", txType)
+	}
+
+	maxFee, err := GetBigIntFromViper(config, TxMaxFeePerGasKey)
+	if err != nil || maxFee.Sign() < 0 {
+		return fmt.Errorf("tx_max_fee_per_gas should be non-negative integer, got \"%v\"", config.GetString(TxMaxFeePerGasKey))
+	}
+	maxPriorityFee, err := GetBigIntFromViper(config, TxMaxPriorityFeePerGasKey)
+	if err != nil || maxPriorityFee.Sign() < 0 {
+		return fmt.Errorf("tx_max_priority_fee_per_gas should be non-negative integer, got \"%v\"", config.GetString(TxMaxPriorityFeePerGasKey))
+	}
+	if maxFee.Sign() > 0 && maxPriorityFee.Cmp(maxFee) > 0 {
+		return fmt.Errorf("tx_max_priority_fee_per_gas should not exceed tx_max_fee_per_gas")
+	}
+	return nil
+}
+
+// GetClaimRevertAction returns action which is configured by
+// claim_revert_policy for the revert reason.
+func GetClaimRevertAction(reason string) string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return getClaimRevertActionFromVip(vip, reason)
+}
+
+func getClaimRevertActionFromVip(config *viper.Viper, reason string) string {
+	if action, ok := config.GetStringMapString(ClaimRevertPolicyKey)[reason]; ok {
+		return action
+	}
+	if action, ok := defaultClaimRevertPolicy[reason]; ok {
+		return action
+	}
+	return defaultClaimRevertPolicy[ClaimRevertUnknown]
+}
+
+// validateClaimRevertPolicyFromVip checks that claim_revert_policy contains
+// known reasons and actions only.
+func validateClaimRevertPolicyFromVip(config *viper.Viper) error {
+	for reason, action := range config.GetStringMapString(ClaimRevertPolicyKey) {
+		if _, ok := defaultClaimRevertPolicy[reason]; !ok {
+			return fmt.Errorf("claim_revert_policy contains unknown revert reason \"%v\", known reasons: %v", reason, knownClaimRevertReasons())
+		}
+		switch action {
+		case ClaimRevertRetry, ClaimRevertMarkFailed, ClaimRevertMarkClaimed:
+		default:
+			return fmt.Errorf("claim_revert_policy contains unknown action \"%v\" of \"%v\", known actions: %v, %v, %v", action, reason, ClaimRevertRetry, ClaimRevertMarkFailed, ClaimRevertMarkClaimed)
+		}
+	}
+	return nil
+}
+
+func knownClaimRevertReasons() string {
+	reasons := make([]string, 0, len(defaultClaimRevertPolicy))
+	for reason := range defaultClaimRevertPolicy {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func gasBumpConfig(json string) *viper.Viper {
+	config := viper.New()
+	err := ReadConfigFromJsonString(config, json)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func TestValidateGasBumpDisabled(t *testing.T) {
+	config := gasBumpConfig(`{"tx_gas_bump_percent": 0, "tx_max_gas_price": 0, "tx_resubmit_interval": "2m"}`)
+
+	assert.Nil(t, validateGasBumpFromVip(config))
+}
+
+func TestValidateGasBumpEnabled(t *testing.T) {
+	config := gasBumpConfig(`{"tx_gas_bump_percent": 10, "tx_max_gas_price": 100000000000, "tx_resubmit_interval": "2m"}`)
+
+	assert.Nil(t, validateGasBumpFromVip(config))
+}
+
+func TestValidateGasBumpNegativePercent(t *testing.T) {
+	config := gasBumpConfig(`{"tx_gas_bump_percent": -10, "tx_max_gas_price": 0, "tx_resubmit_interval": "2m"}`)
+
+	assert.EqualError(t, validateGasBumpFromVip(config), "tx_gas_bump_percent should be non-negative number, got \"-10\"")
+}
+
+func TestValidateGasBumpPercentTooLow(t *testing.T) {
+	config := gasBumpConfig(`{"tx_gas_bump_percent": 9, "tx_max_gas_price": 100000000000, "tx_resubmit_interval": "2m"}`)
+
+	assert.EqualError(t, validateGasBumpFromVip(config), "tx_gas_bump_percent should be at least 10 because nodes reject replacement transactions with lower gas price increase, got \"9\"")
+}
+
+func TestValidateGasBumpNoMaxGasPrice(t *testing.T) {
+	config := gasBumpConfig(`{"tx_gas_bump_percent": 10, "tx_max_gas_price": 0, "tx_resubmit_interval": "2m"}`)
+
+	assert.EqualError(t, validateGasBumpFromVip(config), "tx_max_gas_price should be set when tx_gas_bump_percent is set")
+}
+
+func TestValidateGasBumpIncorrectInterval(t *testing.T) {
+	config := gasBumpConfig(`{"tx_gas_bump_percent": 10, "tx_max_gas_price": 100000000000, "tx_resubmit_interval": "0s"}`)
+
+	assert.EqualError(t, validateGasBumpFromVip(config), "tx_resubmit_interval should be positive duration, got \"0s\"")
+}
+
+func TestValidateGasPriceCache(t *testing.T) {
+	config := gasBumpConfig(`{"gas_price_refresh_interval": "30s", "gas_price_multiplier_percent": 120}`)
+
+	assert.Nil(t, validateGasPriceCacheFromVip(config))
+}
+
+func TestValidateGasPriceCacheNegativeInterval(t *testing.T) {
+	config := gasBumpConfig(`{"gas_price_refresh_interval": "-30s", "gas_price_multiplier_percent": 100}`)
+
+	assert.EqualError(t, validateGasPriceCacheFromVip(config), "gas_price_refresh_interval should be non-negative duration, got \"-30s\"")
+}
+
+func TestValidateGasPriceCacheZeroMultiplierPercent(t *testing.T) {
+	config := gasBumpConfig(`{"gas_price_refresh_interval": "30s", "gas_price_multiplier_percent": 0}`)
+
+	assert.EqualError(t, validateGasPriceCacheFromVip(config), "gas_price_multiplier_percent should be positive number, got \"0\"")
+}
+
+func TestValidateTxTypeLegacy(t *testing.T) {
+	config := gasBumpConfig(`{"tx_type": "legacy", "tx_max_fee_per_gas": 0, "tx_max_priority_fee_per_gas": 0}`)
+
+	assert.Nil(t, validateTxTypeFromVip(config))
+}
+
+func TestValidateTxTypeDynamic(t *testing.T) {
+	config := gasBumpConfig(`{"tx_type": "dynamic", "tx_max_fee_per_gas": 100000000000, "tx_max_priority_fee_per_gas": 2000000000}`)
+
+	assert.Nil(t, validateTxTypeFromVip(config))
+}
+
+func TestValidateTxTypeUnknown(t *testing.T) {
+	config := gasBumpConfig(`{"tx_type": "blob", "tx_max_fee_per_gas": 0, "tx_max_priority_fee_per_gas": 0}`)
+
+	assert.EqualError(t, validateTxTypeFromVip(config), "unrecognized tx_type 'blob'")
+}
+
+func TestValidateTxTypeNegativeMaxFee(t *testing.T) {
+	config := gasBumpConfig(`{"tx_type": "dynamic", "tx_max_fee_per_gas": -1, "tx_max_priority_fee_per_gas": 0}`)
+
+	assert.EqualError(t, validateTxTypeFromVip(config), "tx_max_fee_per_gas should be non-negative integer, got \"-1\"")
+}
+
+func TestValidateTxTypePriorityFeeExceedsMaxFee(t *testing.T) {
+	config := gasBumpConfig(`{"tx_type": "dynamic", "tx_max_fee_per_gas": 1000, "tx_max_priority_fee_per_gas": 1001}`)
+
+	assert.EqualError(t, validateTxTypeFromVip(config), "tx_max_priority_fee_per_gas should not exceed tx_max_fee_per_gas")
+}
+
+func TestGetClaimRevertActionDefault(t *testing.T) {
+	config := gasBumpConfig(`{}`)
+
+	assert.Equal(t, ClaimRevertMarkClaimed, getClaimRevertActionFromVip(config, ClaimRevertAlreadyClaimed))
+	assert.Equal(t, ClaimRevertRetry, getClaimRevertActionFromVip(config, ClaimRevertInsufficientFunds))
+	assert.Equal(t, ClaimRevertRetry, getClaimRevertActionFromVip(config, ClaimRevertUnknown))
+}
+
+func TestGetClaimRevertActionConfigured(t *testing.T) {
+	config := gasBumpConfig(`{"claim_revert_policy": {"insufficient_funds": "mark_failed"}}`)
+
+	assert.Equal(t, ClaimRevertMarkFailed, getClaimRevertActionFromVip(config, ClaimRevertInsufficientFunds))
+	assert.Equal(t, ClaimRevertMarkClaimed, getClaimRevertActionFromVip(config, ClaimRevertAlreadyClaimed), "default is used for reason which is not configured")
+}
+
+func TestValidateClaimRevertPolicy(t *testing.T) {
+	config := gasBumpConfig(`{"claim_revert_policy": {"already_claimed": "mark_failed", "unknown": "retry"}}`)
+
+	assert.Nil(t, validateClaimRevertPolicyFromVip(config))
+}
+
+func TestValidateClaimRevertPolicyUnknownReason(t *testing.T) {
+	config := gasBumpConfig(`{"claim_revert_policy": {"out_of_gas": "retry"}}`)
+
+	assert.EqualError(t, validateClaimRevertPolicyFromVip(config), "claim_revert_policy contains unknown revert reason \"out_of_gas\", known reasons: already_claimed, insufficient_funds, unknown")
+}
+
+func TestValidateClaimRevertPolicyUnknownAction(t *testing.T) {
+	config := gasBumpConfig(`{"claim_revert_policy": {"already_claimed": "ignore"}}`)
+
+	assert.EqualError(t, validateClaimRevertPolicyFromVip(config), "claim_revert_policy contains unknown action \"ignore\" of \"already_claimed\", known actions: retry, mark_failed, mark_claimed")
+}
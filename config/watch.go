@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// ConfigFileUsed returns path of the configuration file loaded by
+// LoadConfig() or empty string if no file is loaded.
+func ConfigFileUsed() string {
+	vipMutex.RLock()
+	defer vipMutex.RUnlock()
+
+	return vip.ConfigFileUsed()
+}
+
+// WatchFileRemoval calls onRemoved when file is removed or renamed. Replacing
+// the file by renaming other file to its name is not treated as removal.
+// Returned function stops watching.
+func WatchFileRemoval(path string, onRemoved func()) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+
+	// directory is watched because watch of the file itself is lost when
+	// file is replaced
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		watcher.Close()
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == filepath.Clean(path) &&
+					event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+					onRemoved()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.WithError(err).WithField("path", path).Error("Error watching file")
+			}
+		}
+	}()
+
+	return func() { watcher.Close() }, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func watchTestFile(t *testing.T) (path string, removed chan bool, stop func()) {
+	dir, err := ioutil.TempDir("", "watch")
+	assert.Nil(t, err)
+	path = filepath.Join(dir, "snetd.config.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("{}"), 0600))
+
+	removed = make(chan bool, 10)
+	stopWatch, err := WatchFileRemoval(path, func() { removed <- true })
+	assert.Nil(t, err)
+
+	return path, removed, func() {
+		stopWatch()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWatchFileRemoval(t *testing.T) {
+	path, removed, stop := watchTestFile(t)
+	defer stop()
+
+	assert.Nil(t, os.Remove(path))
+
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "file removal is not detected")
+	}
+}
+
+func TestWatchFileRemovalIgnoresFileChange(t *testing.T) {
+	path, removed, stop := watchTestFile(t)
+	defer stop()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{ "daemon_type": "grpc" }`), 0600))
+	assert.Nil(t, ioutil.WriteFile(path+".new", []byte("{}"), 0600))
+	assert.Nil(t, os.Rename(path+".new", path))
+
+	select {
+	case <-removed:
+		assert.Fail(t, "file change is detected as removal")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
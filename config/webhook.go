@@ -0,0 +1,21 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+func validateWebhookRetryFromVip(config *viper.Viper) error {
+	if attempts := config.GetInt(WebhookMaxAttemptsKey); attempts < 1 {
+		return fmt.Errorf("webhook_max_attempts should be at least 1, got %v", attempts)
+	}
+	backoff := config.GetDuration(WebhookRetryBackoffKey)
+	if backoff <= 0 {
+		return fmt.Errorf("webhook_retry_backoff should be positive duration, got \"%v\"", config.GetString(WebhookRetryBackoffKey))
+	}
+	if config.GetDuration(WebhookMaxRetryBackoffKey) < backoff {
+		return fmt.Errorf("webhook_max_retry_backoff should not be less than webhook_retry_backoff, got \"%v\"", config.GetString(WebhookMaxRetryBackoffKey))
+	}
+	return nil
+}
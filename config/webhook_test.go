@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func webhookRetryConfig(configJSON string) *viper.Viper {
+	config := viper.New()
+	ReadConfigFromJsonString(config, configJSON)
+	return config
+}
+
+func TestValidateWebhookRetryDefault(t *testing.T) {
+	assert.Nil(t, validateWebhookRetryFromVip(Vip()))
+}
+
+func TestValidateWebhookRetryNoAttempts(t *testing.T) {
+	config := webhookRetryConfig(`{"webhook_max_attempts": 0, "webhook_retry_backoff": "1m", "webhook_max_retry_backoff": "1h"}`)
+
+	assert.EqualError(t, validateWebhookRetryFromVip(config), "webhook_max_attempts should be at least 1, got 0")
+}
+
+func TestValidateWebhookRetryIncorrectBackoff(t *testing.T) {
+	config := webhookRetryConfig(`{"webhook_max_attempts": 5, "webhook_retry_backoff": "0s", "webhook_max_retry_backoff": "1h"}`)
+
+	assert.EqualError(t, validateWebhookRetryFromVip(config), "webhook_retry_backoff should be positive duration, got \"0s\"")
+}
+
+func TestValidateWebhookRetryMaxBackoffLessThanBackoff(t *testing.T) {
+	config := webhookRetryConfig(`{"webhook_max_attempts": 5, "webhook_retry_backoff": "1m", "webhook_max_retry_backoff": "30s"}`)
+
+	assert.EqualError(t, validateWebhookRetryFromVip(config), "webhook_max_retry_backoff should not be less than webhook_retry_backoff, got \"30s\"")
+}
@@ -0,0 +1,113 @@
+package connlimit
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var errListenerClosed = errors.New("listener is closed")
+
+type limitListener struct {
+	net.Listener
+	slots       chan struct{}
+	idleTimeout time.Duration
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewListener wraps listener to limit number of simultaneously opened
+// connections and close connections which are idle longer than idleTimeout.
+// When maxConnections is reached new connections are not accepted until one
+// of opened connections is closed, so they are queued by OS. Zero
+// maxConnections or idleTimeout disables corresponding limit.
+func NewListener(listener net.Listener, maxConnections int, idleTimeout time.Duration) net.Listener {
+	limited := &limitListener{
+		Listener:    listener,
+		idleTimeout: idleTimeout,
+		done:        make(chan struct{}),
+	}
+	if maxConnections > 0 {
+		limited.slots = make(chan struct{}, maxConnections)
+	}
+	return limited
+}
+
+func (listener *limitListener) acquire() bool {
+	if listener.slots == nil {
+		return true
+	}
+
+	select {
+	case listener.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	log.WithField("maxConnections", cap(listener.slots)).Warn("Connection limit reached, new connections are queued")
+	select {
+	case listener.slots <- struct{}{}:
+		return true
+	case <-listener.done:
+		return false
+	}
+}
+
+func (listener *limitListener) release() {
+	if listener.slots != nil {
+		<-listener.slots
+	}
+}
+
+func (listener *limitListener) Accept() (net.Conn, error) {
+	if !listener.acquire() {
+		return nil, errListenerClosed
+	}
+
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		listener.release()
+		return nil, err
+	}
+
+	return &limitConn{Conn: conn, listener: listener}, nil
+}
+
+func (listener *limitListener) Close() error {
+	listener.closeOnce.Do(func() { close(listener.done) })
+	return listener.Listener.Close()
+}
+
+type limitConn struct {
+	net.Conn
+	listener    *limitListener
+	releaseOnce sync.Once
+}
+
+func (conn *limitConn) extendDeadline() {
+	if conn.listener.idleTimeout > 0 {
+		conn.Conn.SetDeadline(time.Now().Add(conn.listener.idleTimeout))
+	}
+}
+
+func (conn *limitConn) Read(b []byte) (n int, err error) {
+	conn.extendDeadline()
+	n, err = conn.Conn.Read(b)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() && conn.listener.idleTimeout > 0 {
+		log.WithField("remoteAddr", conn.RemoteAddr()).WithField("idleTimeout", conn.listener.idleTimeout).Info("Closing idle connection")
+	}
+	return
+}
+
+func (conn *limitConn) Write(b []byte) (n int, err error) {
+	conn.extendDeadline()
+	return conn.Conn.Write(b)
+}
+
+func (conn *limitConn) Close() error {
+	conn.releaseOnce.Do(conn.listener.release)
+	return conn.Conn.Close()
+}
@@ -0,0 +1,105 @@
+package connlimit
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listen(t *testing.T, maxConnections int, idleTimeout time.Duration) (listener net.Listener, accepted chan net.Conn) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	listener = NewListener(tcpListener, maxConnections, idleTimeout)
+
+	accepted = make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	return
+}
+
+func dial(t *testing.T, listener net.Listener) net.Conn {
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	return conn
+}
+
+func waitAccepted(accepted chan net.Conn) net.Conn {
+	select {
+	case conn := <-accepted:
+		return conn
+	case <-time.After(200 * time.Millisecond):
+		return nil
+	}
+}
+
+func TestConnectionsAboveLimitAreQueued(t *testing.T) {
+	listener, accepted := listen(t, 2, 0)
+	defer listener.Close()
+
+	dial(t, listener)
+	dial(t, listener)
+	dial(t, listener)
+
+	first := waitAccepted(accepted)
+	assert.NotNil(t, first)
+	assert.NotNil(t, waitAccepted(accepted))
+	assert.Nil(t, waitAccepted(accepted), "connection above limit is accepted")
+
+	first.Close()
+
+	assert.NotNil(t, waitAccepted(accepted), "queued connection is not accepted after slot is released")
+}
+
+func TestConnectionsAreNotLimitedByDefault(t *testing.T) {
+	listener, accepted := listen(t, 0, 0)
+	defer listener.Close()
+
+	for i := 0; i < 5; i++ {
+		dial(t, listener)
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.NotNil(t, waitAccepted(accepted))
+	}
+}
+
+func TestIdleConnectionIsClosed(t *testing.T) {
+	listener, accepted := listen(t, 1, 50*time.Millisecond)
+	defer listener.Close()
+
+	dial(t, listener)
+	conn := waitAccepted(accepted)
+	assert.NotNil(t, conn)
+
+	_, err := conn.Read(make([]byte, 1))
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok && netErr.Timeout(), "unexpected error: %v", err)
+	conn.Close()
+
+	dial(t, listener)
+	assert.NotNil(t, waitAccepted(accepted), "slot of the closed idle connection is not released")
+}
+
+func TestAcceptReturnsErrorWhenClosedWhileQueued(t *testing.T) {
+	listener, accepted := listen(t, 1, 0)
+
+	dial(t, listener)
+	dial(t, listener)
+	assert.NotNil(t, waitAccepted(accepted))
+
+	listener.Close()
+
+	_, ok := <-accepted
+	assert.False(t, ok)
+}
@@ -1,6 +1,8 @@
 package escrow
 
 import (
+	"context"
+	"errors"
 	"reflect"
 )
 
@@ -26,6 +28,51 @@ type AtomicStorage interface {
 	Delete(key string) (err error)
 }
 
+// ContextAtomicStorage is implemented by atomic storages which requests can
+// be cancelled by the context, for instance when the call is cancelled or
+// income validation times out. Storages which don't implement it are not
+// called when context is already done.
+type ContextAtomicStorage interface {
+	// GetContext is AtomicStorage.Get which is cancelled when ctx is done
+	GetContext(ctx context.Context, key string) (value string, ok bool, err error)
+	// PutIfAbsentContext is AtomicStorage.PutIfAbsent which is cancelled
+	// when ctx is done
+	PutIfAbsentContext(ctx context.Context, key string, value string) (ok bool, err error)
+	// CompareAndSwapContext is AtomicStorage.CompareAndSwap which is
+	// cancelled when ctx is done
+	CompareAndSwapContext(ctx context.Context, key string, prevValue string, newValue string) (ok bool, err error)
+}
+
+func getContext(ctx context.Context, storage AtomicStorage, key string) (value string, ok bool, err error) {
+	if contextStorage, isContext := storage.(ContextAtomicStorage); isContext {
+		return contextStorage.GetContext(ctx, key)
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return storage.Get(key)
+}
+
+func putIfAbsentContext(ctx context.Context, storage AtomicStorage, key string, value string) (ok bool, err error) {
+	if contextStorage, isContext := storage.(ContextAtomicStorage); isContext {
+		return contextStorage.PutIfAbsentContext(ctx, key, value)
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return storage.PutIfAbsent(key, value)
+}
+
+func compareAndSwapContext(ctx context.Context, storage AtomicStorage, key string, prevValue string, newValue string) (ok bool, err error) {
+	if contextStorage, isContext := storage.(ContextAtomicStorage); isContext {
+		return contextStorage.CompareAndSwapContext(ctx, key, prevValue, newValue)
+	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	return storage.CompareAndSwap(key, prevValue, newValue)
+}
+
 // PrefixedAtomicStorage is decorator for atomic storage which adds a prefix to
 // the storage keys.
 type PrefixedAtomicStorage struct {
@@ -38,6 +85,11 @@ func (storage *PrefixedAtomicStorage) Get(key string) (value string, ok bool, er
 	return storage.delegate.Get(storage.keyPrefix + "/" + key)
 }
 
+// GetContext is implementation of ContextAtomicStorage.GetContext
+func (storage *PrefixedAtomicStorage) GetContext(ctx context.Context, key string) (value string, ok bool, err error) {
+	return getContext(ctx, storage.delegate, storage.keyPrefix+"/"+key)
+}
+
 func (storage *PrefixedAtomicStorage) GetByKeyPrefix(prefix string) (values []string, err error) {
 	return storage.delegate.GetByKeyPrefix(storage.keyPrefix + "/" + prefix)
 }
@@ -52,29 +104,108 @@ func (storage *PrefixedAtomicStorage) PutIfAbsent(key string, value string) (ok
 	return storage.delegate.PutIfAbsent(storage.keyPrefix+"/"+key, value)
 }
 
+// PutIfAbsentContext is implementation of
+// ContextAtomicStorage.PutIfAbsentContext
+func (storage *PrefixedAtomicStorage) PutIfAbsentContext(ctx context.Context, key string, value string) (ok bool, err error) {
+	return putIfAbsentContext(ctx, storage.delegate, storage.keyPrefix+"/"+key, value)
+}
+
 // CompareAndSwap is implementation of AtomicStorage.CompareAndSwap
 func (storage *PrefixedAtomicStorage) CompareAndSwap(key string, prevValue string, newValue string) (ok bool, err error) {
 	return storage.delegate.CompareAndSwap(storage.keyPrefix+"/"+key, prevValue, newValue)
 }
 
+// CompareAndSwapContext is implementation of
+// ContextAtomicStorage.CompareAndSwapContext
+func (storage *PrefixedAtomicStorage) CompareAndSwapContext(ctx context.Context, key string, prevValue string, newValue string) (ok bool, err error) {
+	return compareAndSwapContext(ctx, storage.delegate, storage.keyPrefix+"/"+key, prevValue, newValue)
+}
+
 func (storage *PrefixedAtomicStorage) Delete(key string) (err error) {
 	return storage.delegate.Delete(storage.keyPrefix + "/" + key)
 }
 
+// ErrReadOnlyStorage is returned by ReadOnlyAtomicStorage on write.
+var ErrReadOnlyStorage = errors.New("storage is read only in replica mode")
+
+// ReadOnlyAtomicStorage is decorator for atomic storage which passes reads to
+// the delegate and rejects writes with ErrReadOnlyStorage. It is used by
+// replica daemon which reads channel state written by primary daemon.
+type ReadOnlyAtomicStorage struct {
+	delegate AtomicStorage
+}
+
+// NewReadOnlyAtomicStorage returns read only view of the delegate storage.
+func NewReadOnlyAtomicStorage(delegate AtomicStorage) *ReadOnlyAtomicStorage {
+	return &ReadOnlyAtomicStorage{delegate: delegate}
+}
+
+// Get is implementation of AtomicStorage.Get
+func (storage *ReadOnlyAtomicStorage) Get(key string) (value string, ok bool, err error) {
+	return storage.delegate.Get(key)
+}
+
+// GetContext is implementation of ContextAtomicStorage.GetContext
+func (storage *ReadOnlyAtomicStorage) GetContext(ctx context.Context, key string) (value string, ok bool, err error) {
+	return getContext(ctx, storage.delegate, key)
+}
+
+func (storage *ReadOnlyAtomicStorage) GetByKeyPrefix(prefix string) (values []string, err error) {
+	return storage.delegate.GetByKeyPrefix(prefix)
+}
+
+// Put is implementation of AtomicStorage.Put
+func (storage *ReadOnlyAtomicStorage) Put(key string, value string) (err error) {
+	return ErrReadOnlyStorage
+}
+
+// PutIfAbsent is implementation of AtomicStorage.PutIfAbsent
+func (storage *ReadOnlyAtomicStorage) PutIfAbsent(key string, value string) (ok bool, err error) {
+	return false, ErrReadOnlyStorage
+}
+
+// PutIfAbsentContext is implementation of
+// ContextAtomicStorage.PutIfAbsentContext
+func (storage *ReadOnlyAtomicStorage) PutIfAbsentContext(ctx context.Context, key string, value string) (ok bool, err error) {
+	return false, ErrReadOnlyStorage
+}
+
+// CompareAndSwap is implementation of AtomicStorage.CompareAndSwap
+func (storage *ReadOnlyAtomicStorage) CompareAndSwap(key string, prevValue string, newValue string) (ok bool, err error) {
+	return false, ErrReadOnlyStorage
+}
+
+// CompareAndSwapContext is implementation of
+// ContextAtomicStorage.CompareAndSwapContext
+func (storage *ReadOnlyAtomicStorage) CompareAndSwapContext(ctx context.Context, key string, prevValue string, newValue string) (ok bool, err error) {
+	return false, ErrReadOnlyStorage
+}
+
+func (storage *ReadOnlyAtomicStorage) Delete(key string) (err error) {
+	return ErrReadOnlyStorage
+}
+
 // TypedAtomicStorage is an atomic storage which automatically
 // serializes/deserializes values and keys
 type TypedAtomicStorage interface {
 	// Get returns value by key
 	Get(key interface{}) (value interface{}, ok bool, err error)
+	// GetContext returns value by key, request is cancelled when ctx is done
+	GetContext(ctx context.Context, key interface{}) (value interface{}, ok bool, err error)
 	// GetAll returns an array which contains all values from storage
 	GetAll() (array interface{}, err error)
 	// Put puts value by key unconditionally
 	Put(key interface{}, value interface{}) (err error)
 	// PutIfAbsent puts value by key if and only if key is absent in storage
 	PutIfAbsent(key interface{}, value interface{}) (ok bool, err error)
+	// PutIfAbsentContext is PutIfAbsent which is cancelled when ctx is done
+	PutIfAbsentContext(ctx context.Context, key interface{}, value interface{}) (ok bool, err error)
 	// CompareAndSwap puts newValue by key if and only if previous value is equal
 	// to prevValue
 	CompareAndSwap(key interface{}, prevValue interface{}, newValue interface{}) (ok bool, err error)
+	// CompareAndSwapContext is CompareAndSwap which is cancelled when ctx is
+	// done
+	CompareAndSwapContext(ctx context.Context, key interface{}, prevValue interface{}, newValue interface{}) (ok bool, err error)
 	// Delete removes value by key
 	Delete(key interface{}) (err error)
 }
@@ -90,12 +221,17 @@ type TypedAtomicStorageImpl struct {
 
 // Get implements TypedAtomicStorage.Get
 func (storage *TypedAtomicStorageImpl) Get(key interface{}) (value interface{}, ok bool, err error) {
+	return storage.GetContext(context.Background(), key)
+}
+
+// GetContext implements TypedAtomicStorage.GetContext
+func (storage *TypedAtomicStorageImpl) GetContext(ctx context.Context, key interface{}) (value interface{}, ok bool, err error) {
 	keyString, err := storage.keySerializer(key)
 	if err != nil {
 		return
 	}
 
-	valueString, ok, err := storage.atomicStorage.Get(keyString)
+	valueString, ok, err := getContext(ctx, storage.atomicStorage, keyString)
 	if err != nil {
 		return
 	}
@@ -151,6 +287,11 @@ func (storage *TypedAtomicStorageImpl) Put(key interface{}, value interface{}) (
 
 // PutIfAbsent implements TypedAtomicStorage.PutIfAbsent
 func (storage *TypedAtomicStorageImpl) PutIfAbsent(key interface{}, value interface{}) (ok bool, err error) {
+	return storage.PutIfAbsentContext(context.Background(), key, value)
+}
+
+// PutIfAbsentContext implements TypedAtomicStorage.PutIfAbsentContext
+func (storage *TypedAtomicStorageImpl) PutIfAbsentContext(ctx context.Context, key interface{}, value interface{}) (ok bool, err error) {
 	keyString, err := storage.keySerializer(key)
 	if err != nil {
 		return
@@ -161,11 +302,16 @@ func (storage *TypedAtomicStorageImpl) PutIfAbsent(key interface{}, value interf
 		return
 	}
 
-	return storage.atomicStorage.PutIfAbsent(keyString, valueString)
+	return putIfAbsentContext(ctx, storage.atomicStorage, keyString, valueString)
 }
 
 // CompareAndSwap implements TypedAtomicStorage.CompareAndSwap
 func (storage *TypedAtomicStorageImpl) CompareAndSwap(key interface{}, prevValue interface{}, newValue interface{}) (ok bool, err error) {
+	return storage.CompareAndSwapContext(context.Background(), key, prevValue, newValue)
+}
+
+// CompareAndSwapContext implements TypedAtomicStorage.CompareAndSwapContext
+func (storage *TypedAtomicStorageImpl) CompareAndSwapContext(ctx context.Context, key interface{}, prevValue interface{}, newValue interface{}) (ok bool, err error) {
 	keyString, err := storage.keySerializer(key)
 	if err != nil {
 		return
@@ -181,7 +327,7 @@ func (storage *TypedAtomicStorageImpl) CompareAndSwap(key interface{}, prevValue
 		return
 	}
 
-	return storage.atomicStorage.CompareAndSwap(keyString, prevValueString, newValueString)
+	return compareAndSwapContext(ctx, storage.atomicStorage, keyString, prevValueString, newValueString)
 }
 
 func (storage *TypedAtomicStorageImpl) Delete(key interface{}) (err error) {
@@ -0,0 +1,26 @@
+package escrow
+
+import (
+	"math/big"
+	"time"
+)
+
+// ChannelsToAutoClaim returns channels which have payments unclaimed for
+// maxAge or longer and unclaimed amount not less than minAmount. It allows
+// claiming channels which are not used actively before funds are left
+// unclaimed for too long.
+func ChannelsToAutoClaim(channels []*PaymentChannelData, now time.Time, maxAge time.Duration, minAmount *big.Int) (result []*PaymentChannelData) {
+	for _, channel := range channels {
+		if channel.UnclaimedSince.IsZero() || channel.AuthorizedAmount == nil || channel.AuthorizedAmount.Sign() <= 0 {
+			continue
+		}
+		if now.Sub(channel.UnclaimedSince) < maxAge {
+			continue
+		}
+		if channel.AuthorizedAmount.Cmp(minAmount) < 0 {
+			continue
+		}
+		result = append(result, channel)
+	}
+	return
+}
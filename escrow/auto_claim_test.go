@@ -0,0 +1,63 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func channelWithAge(id int64, amount int64, age time.Duration, now time.Time) *PaymentChannelData {
+	channel := &PaymentChannelData{ChannelID: big.NewInt(id), AuthorizedAmount: big.NewInt(amount)}
+	if age >= 0 {
+		channel.UnclaimedSince = now.Add(-age)
+	}
+	return channel
+}
+
+func channelIDs(channels []*PaymentChannelData) (ids []int64) {
+	for _, channel := range channels {
+		ids = append(ids, channel.ChannelID.Int64())
+	}
+	return
+}
+
+func TestChannelsToAutoClaimByAge(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	channels := []*PaymentChannelData{
+		channelWithAge(1, 100, time.Hour, now),
+		channelWithAge(2, 100, 24*time.Hour, now),
+		channelWithAge(3, 100, 48*time.Hour, now),
+		channelWithAge(4, 100, 23*time.Hour+59*time.Minute, now),
+	}
+
+	result := ChannelsToAutoClaim(channels, now, 24*time.Hour, big.NewInt(0))
+
+	assert.Equal(t, []int64{2, 3}, channelIDs(result))
+}
+
+func TestChannelsToAutoClaimMinAmount(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	channels := []*PaymentChannelData{
+		channelWithAge(1, 9, 48*time.Hour, now),
+		channelWithAge(2, 10, 48*time.Hour, now),
+		channelWithAge(3, 1000, time.Hour, now),
+	}
+
+	result := ChannelsToAutoClaim(channels, now, 24*time.Hour, big.NewInt(10))
+
+	assert.Equal(t, []int64{2}, channelIDs(result))
+}
+
+func TestChannelsToAutoClaimSkipsClaimedChannels(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	channels := []*PaymentChannelData{
+		channelWithAge(1, 100, -1, now),
+		channelWithAge(2, 0, 48*time.Hour, now),
+	}
+
+	result := ChannelsToAutoClaim(channels, now, 24*time.Hour, big.NewInt(0))
+
+	assert.Nil(t, result)
+}
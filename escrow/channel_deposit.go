@@ -0,0 +1,84 @@
+package escrow
+
+import (
+	"math/big"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+// ChannelValidator checks that payment channel can be used to pay for the
+// called service, for instance that it has enough funds deposited.
+type ChannelValidator interface {
+	// Validate returns nil if channel is accepted or PaymentError to be sent
+	// to client otherwise.
+	Validate(context *handler.GrpcStreamContext, channel *PaymentChannelData) error
+}
+
+type minChannelDepositValidator struct {
+	minDeposit *big.Int
+}
+
+// NewMinChannelDepositValidator returns validator which rejects channels
+// which full amount is less than minDeposit. Deposit is checked only until the
+// channel is claimed first time: while nonce is zero full amount is the
+// deposit (probably extended by the sender), each claim decreases it by the
+// claimed amount, so accepted channel is not rejected after claims.
+func NewMinChannelDepositValidator(minDeposit *big.Int) ChannelValidator {
+	return &minChannelDepositValidator{minDeposit: minDeposit}
+}
+
+func (validator *minChannelDepositValidator) Validate(context *handler.GrpcStreamContext, channel *PaymentChannelData) error {
+	if channel.Nonce.Sign() != 0 {
+		return nil
+	}
+	if channel.FullAmount.Cmp(validator.minDeposit) < 0 {
+		return NewPaymentError(FailedPrecondition, "payment channel %v deposit %v is less than minimal deposit %v", channel.ChannelID, channel.FullAmount, validator.minDeposit)
+	}
+	return nil
+}
+
+type serviceChannelValidator struct {
+	headers    handler.ServiceKeyHeaders
+	validators map[handler.ServiceKey]ChannelValidator
+}
+
+// NewServiceChannelValidator returns channel validator for the
+// daemon which serves few services. It passes channel to the validator of the
+// service which is called, channels of the services without validator are
+// accepted. Called service is determined using given metadata headers.
+func NewServiceChannelValidator(headers handler.ServiceKeyHeaders, validators map[handler.ServiceKey]ChannelValidator) ChannelValidator {
+	return &serviceChannelValidator{headers: headers, validators: validators}
+}
+
+func (validator *serviceChannelValidator) Validate(context *handler.GrpcStreamContext, channel *PaymentChannelData) error {
+	key, e := validator.headers.GetServiceKey(context.MD)
+	if e != nil {
+		return NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+	}
+
+	delegate, ok := validator.validators[key]
+	if !ok {
+		return nil
+	}
+
+	return delegate.Validate(context, channel)
+}
+
+type compositeChannelValidator struct {
+	validators []ChannelValidator
+}
+
+// NewCompositeChannelValidator returns validator which accepts channel only
+// if all of the given validators accept it.
+func NewCompositeChannelValidator(validators ...ChannelValidator) ChannelValidator {
+	return &compositeChannelValidator{validators: validators}
+}
+
+func (validator *compositeChannelValidator) Validate(context *handler.GrpcStreamContext, channel *PaymentChannelData) error {
+	for _, delegate := range validator.validators {
+		if err := delegate.Validate(context, channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func channelWithDeposit(deposit int64) *PaymentChannelData {
+	return &PaymentChannelData{ChannelID: big.NewInt(42), Nonce: big.NewInt(0), FullAmount: big.NewInt(deposit)}
+}
+
+func serviceStreamContext(organizationID, serviceID string) *handler.GrpcStreamContext {
+	return &handler.GrpcStreamContext{MD: metadata.Pairs(
+		handler.OrganizationIDHeader, organizationID,
+		handler.ServiceIDHeader, serviceID,
+	)}
+}
+
+func TestMinChannelDepositValidator(t *testing.T) {
+	validator := NewMinChannelDepositValidator(big.NewInt(1000))
+
+	assert.Nil(t, validator.Validate(&handler.GrpcStreamContext{}, channelWithDeposit(1000)))
+	assert.Equal(t, NewPaymentError(FailedPrecondition, "payment channel 42 deposit 999 is less than minimal deposit 1000"),
+		validator.Validate(&handler.GrpcStreamContext{}, channelWithDeposit(999)))
+}
+
+func TestMinChannelDepositValidatorClaimedChannel(t *testing.T) {
+	validator := NewMinChannelDepositValidator(big.NewInt(1000))
+	claimed := channelWithDeposit(400)
+	claimed.Nonce = big.NewInt(1)
+
+	assert.Nil(t, validator.Validate(&handler.GrpcStreamContext{}, claimed), "full amount of claimed channel is decreased by claims")
+}
+
+func TestServiceChannelDepositValidator(t *testing.T) {
+	validator := NewServiceChannelValidator(handler.DefaultServiceKeyHeaders, map[handler.ServiceKey]ChannelValidator{
+		{OrganizationID: "org", ServiceID: "service-a"}: NewMinChannelDepositValidator(big.NewInt(1000)),
+		{OrganizationID: "org", ServiceID: "service-b"}: NewMinChannelDepositValidator(big.NewInt(5000)),
+	})
+
+	assert.Nil(t, validator.Validate(serviceStreamContext("org", "service-a"), channelWithDeposit(1000)))
+	assert.Equal(t, NewPaymentError(FailedPrecondition, "payment channel 42 deposit 999 is less than minimal deposit 1000"),
+		validator.Validate(serviceStreamContext("org", "service-a"), channelWithDeposit(999)))
+	assert.Nil(t, validator.Validate(serviceStreamContext("org", "service-b"), channelWithDeposit(5000)))
+	assert.Equal(t, NewPaymentError(FailedPrecondition, "payment channel 42 deposit 1000 is less than minimal deposit 5000"),
+		validator.Validate(serviceStreamContext("org", "service-b"), channelWithDeposit(1000)))
+}
+
+func TestServiceChannelDepositValidatorServiceWithoutMinimum(t *testing.T) {
+	validator := NewServiceChannelValidator(handler.DefaultServiceKeyHeaders, map[handler.ServiceKey]ChannelValidator{
+		{OrganizationID: "org", ServiceID: "service-a"}: NewMinChannelDepositValidator(big.NewInt(1000)),
+	})
+
+	assert.Nil(t, validator.Validate(serviceStreamContext("org", "service-c"), channelWithDeposit(1)))
+}
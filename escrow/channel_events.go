@@ -0,0 +1,91 @@
+package escrow
+
+import (
+	"math/big"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/blockchain"
+)
+
+// channelEventKey identifies the event on the chain, the same ChannelOpen
+// event delivered twice has the same key.
+type channelEventKey struct {
+	channelID string
+	block     uint64
+	logIndex  uint
+}
+
+// ChannelOpenEventProcessor puts channels opened by ChannelOpen events into
+// the payment channel storage. Events are delivered again when blocks are
+// scanned again or after chain reorganization, so processor remembers the
+// events of the last dedupBlocks blocks and skips the duplicates.
+type ChannelOpenEventProcessor struct {
+	storage     *PaymentChannelStorage
+	dedupBlocks uint64
+
+	lock sync.Mutex
+	seen map[channelEventKey]bool
+}
+
+// NewChannelOpenEventProcessor returns new instance of
+// ChannelOpenEventProcessor which remembers events of the last dedupBlocks
+// blocks.
+func NewChannelOpenEventProcessor(storage *PaymentChannelStorage, dedupBlocks uint64) *ChannelOpenEventProcessor {
+	return &ChannelOpenEventProcessor{
+		storage:     storage,
+		dedupBlocks: dedupBlocks,
+		seen:        make(map[channelEventKey]bool),
+	}
+}
+
+// Process applies the event unless it was already processed. applied is false
+// for duplicate event and for the channel which is already in the storage,
+// stored channel is never replaced by the event as it can contain payments.
+// currentBlock is used to forget the events which are out of the dedup window.
+func (processor *ChannelOpenEventProcessor) Process(event *blockchain.ChannelOpenEvent, currentBlock uint64) (applied bool, err error) {
+	processor.lock.Lock()
+	defer processor.lock.Unlock()
+
+	processor.forgetOldEvents(currentBlock)
+
+	key := channelEventKey{channelID: event.ChannelID.String(), block: event.BlockNumber, logIndex: event.LogIndex}
+	if processor.seen[key] {
+		log.WithField("channelId", event.ChannelID).WithField("block", event.BlockNumber).Debug("Duplicate ChannelOpen event is skipped")
+		return false, nil
+	}
+
+	applied, err = processor.storage.PutIfAbsent(&PaymentChannelKey{ID: event.ChannelID}, &PaymentChannelData{
+		ChannelID:        event.ChannelID,
+		Nonce:            event.Nonce,
+		State:            Open,
+		Sender:           event.Sender,
+		Recipient:        event.Recipient,
+		GroupID:          event.GroupID,
+		FullAmount:       event.Amount,
+		Expiration:       event.Expiration,
+		Signer:           event.Signer,
+		AuthorizedAmount: big.NewInt(0),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !processor.outOfWindow(event.BlockNumber, currentBlock) {
+		processor.seen[key] = true
+	}
+	return applied, nil
+}
+
+func (processor *ChannelOpenEventProcessor) forgetOldEvents(currentBlock uint64) {
+	for key := range processor.seen {
+		if processor.outOfWindow(key.block, currentBlock) {
+			delete(processor.seen, key)
+		}
+	}
+}
+
+func (processor *ChannelOpenEventProcessor) outOfWindow(block, currentBlock uint64) bool {
+	return block+processor.dedupBlocks < currentBlock
+}
@@ -0,0 +1,101 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/blockchain"
+)
+
+func channelOpenEvent(channelID int64, block uint64, logIndex uint) *blockchain.ChannelOpenEvent {
+	return &blockchain.ChannelOpenEvent{
+		ChannelID:   big.NewInt(channelID),
+		Nonce:       big.NewInt(0),
+		Sender:      common.HexToAddress("0x1"),
+		Signer:      common.HexToAddress("0x2"),
+		Recipient:   common.HexToAddress("0x3"),
+		GroupID:     [32]byte{123},
+		Amount:      big.NewInt(100),
+		Expiration:  big.NewInt(1000),
+		BlockNumber: block,
+		LogIndex:    logIndex,
+	}
+}
+
+func TestChannelOpenEventProcessorPutsChannel(t *testing.T) {
+	storage := NewPaymentChannelStorage(NewMemStorage())
+	processor := NewChannelOpenEventProcessor(storage, 10)
+
+	applied, err := processor.Process(channelOpenEvent(42, 100, 1), 100)
+
+	assert.Nil(t, err)
+	assert.True(t, applied)
+	channel, ok, err := storage.Get(&PaymentChannelKey{ID: big.NewInt(42)})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &PaymentChannelData{
+		ChannelID:        big.NewInt(42),
+		Nonce:            big.NewInt(0),
+		State:            Open,
+		Sender:           common.HexToAddress("0x1"),
+		Recipient:        common.HexToAddress("0x3"),
+		GroupID:          [32]byte{123},
+		FullAmount:       big.NewInt(100),
+		Expiration:       big.NewInt(1000),
+		Signer:           common.HexToAddress("0x2"),
+		AuthorizedAmount: big.NewInt(0),
+	}, channel)
+}
+
+func TestChannelOpenEventProcessorSkipsDuplicate(t *testing.T) {
+	storage := NewPaymentChannelStorage(NewMemStorage())
+	processor := NewChannelOpenEventProcessor(storage, 10)
+	_, err := processor.Process(channelOpenEvent(42, 100, 1), 100)
+	assert.Nil(t, err)
+	paid, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(42)})
+	paid.AuthorizedAmount = big.NewInt(30)
+	assert.Nil(t, storage.Put(&PaymentChannelKey{ID: big.NewInt(42)}, paid))
+
+	applied, err := processor.Process(channelOpenEvent(42, 100, 1), 105)
+
+	assert.Nil(t, err)
+	assert.False(t, applied)
+	assert.Equal(t, 1, len(processor.seen))
+	channel, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(42)})
+	assert.Equal(t, big.NewInt(30), channel.AuthorizedAmount, "duplicate event is not applied")
+}
+
+func TestChannelOpenEventProcessorDoesNotReplaceStoredChannel(t *testing.T) {
+	storage := NewPaymentChannelStorage(NewMemStorage())
+	processor := NewChannelOpenEventProcessor(storage, 10)
+	_, err := processor.Process(channelOpenEvent(42, 100, 1), 100)
+	assert.Nil(t, err)
+	paid, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(42)})
+	paid.AuthorizedAmount = big.NewInt(30)
+	assert.Nil(t, storage.Put(&PaymentChannelKey{ID: big.NewInt(42)}, paid))
+
+	applied, err := processor.Process(channelOpenEvent(42, 102, 3), 102)
+
+	assert.Nil(t, err)
+	assert.False(t, applied, "event moved to another block by reorg is not applied")
+	channel, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(42)})
+	assert.Equal(t, big.NewInt(30), channel.AuthorizedAmount)
+}
+
+func TestChannelOpenEventProcessorForgetsEventsOutOfWindow(t *testing.T) {
+	processor := NewChannelOpenEventProcessor(NewPaymentChannelStorage(NewMemStorage()), 10)
+	_, err := processor.Process(channelOpenEvent(42, 100, 1), 100)
+	assert.Nil(t, err)
+	_, err = processor.Process(channelOpenEvent(43, 105, 1), 110)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(processor.seen))
+
+	_, err = processor.Process(channelOpenEvent(44, 111, 1), 111)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(processor.seen), "event of block 100 is out of window")
+	assert.False(t, processor.seen[channelEventKey{channelID: "42", block: 100, logIndex: 1}])
+}
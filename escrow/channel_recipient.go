@@ -0,0 +1,36 @@
+package escrow
+
+import (
+	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/handler"
+)
+
+type channelRecipientValidator struct {
+	recipient func() ChannelRecipient
+}
+
+// NewChannelRecipientValidator returns validator which rejects channels
+// opened to the group of replicas or payment address other than ones of the
+// given service metadata. It is used when daemon serves few services to
+// check that channel pays to the called service.
+func NewChannelRecipientValidator(metadata *blockchain.ServiceMetadata) ChannelValidator {
+	return &channelRecipientValidator{
+		recipient: func() ChannelRecipient {
+			return ChannelRecipient{
+				GroupID:        metadata.GetDaemonGroupID(),
+				PaymentAddress: metadata.GetPaymentAddress(),
+			}
+		},
+	}
+}
+
+func (validator *channelRecipientValidator) Validate(context *handler.GrpcStreamContext, channel *PaymentChannelData) error {
+	recipient := validator.recipient()
+	if channel.GroupID != recipient.GroupID {
+		return NewPaymentError(PermissionDenied, "payment channel %v belongs to another group of replicas, service group: %v, channel group: %v", channel.ChannelID, recipient.GroupID, channel.GroupID)
+	}
+	if channel.Recipient != recipient.PaymentAddress {
+		return NewPaymentError(PermissionDenied, "payment channel %v recipient %v does not match service payment address %v", channel.ChannelID, channel.Recipient.Hex(), recipient.PaymentAddress.Hex())
+	}
+	return nil
+}
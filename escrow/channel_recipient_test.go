@@ -0,0 +1,51 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func newTestChannelRecipientValidator(groupID [32]byte, paymentAddress common.Address) ChannelValidator {
+	return &channelRecipientValidator{
+		recipient: func() ChannelRecipient {
+			return ChannelRecipient{GroupID: groupID, PaymentAddress: paymentAddress}
+		},
+	}
+}
+
+func channelOpenedTo(groupID [32]byte, recipient common.Address) *PaymentChannelData {
+	return &PaymentChannelData{ChannelID: big.NewInt(42), Nonce: big.NewInt(0), FullAmount: big.NewInt(1000), GroupID: groupID, Recipient: recipient}
+}
+
+func TestServiceChannelRecipientValidator(t *testing.T) {
+	recipientA := common.HexToAddress("0xD6C6344f1D122dC6f4C1782A4622B683b9008081")
+	recipientB := common.HexToAddress("0x52653A9091b5d5021bed06c5118D24b23620c529")
+	validator := NewServiceChannelValidator(handler.DefaultServiceKeyHeaders, map[handler.ServiceKey]ChannelValidator{
+		{OrganizationID: "org", ServiceID: "service-a"}: newTestChannelRecipientValidator([32]byte{1}, recipientA),
+		{OrganizationID: "org", ServiceID: "service-b"}: newTestChannelRecipientValidator([32]byte{2}, recipientB),
+	})
+
+	assert.Nil(t, validator.Validate(serviceStreamContext("org", "service-a"), channelOpenedTo([32]byte{1}, recipientA)))
+	assert.Nil(t, validator.Validate(serviceStreamContext("org", "service-b"), channelOpenedTo([32]byte{2}, recipientB)))
+	assert.Equal(t, NewPaymentError(PermissionDenied, "payment channel 42 belongs to another group of replicas, service group: [2 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0], channel group: [1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0]"),
+		validator.Validate(serviceStreamContext("org", "service-b"), channelOpenedTo([32]byte{1}, recipientA)))
+	assert.Equal(t, NewPaymentError(PermissionDenied, "payment channel 42 recipient 0xD6C6344f1D122dC6f4C1782A4622B683b9008081 does not match service payment address 0x52653A9091b5d5021bed06c5118D24b23620c529"),
+		validator.Validate(serviceStreamContext("org", "service-b"), channelOpenedTo([32]byte{2}, recipientA)))
+}
+
+func TestCompositeChannelValidator(t *testing.T) {
+	recipient := common.HexToAddress("0xD6C6344f1D122dC6f4C1782A4622B683b9008081")
+	validator := NewCompositeChannelValidator(
+		newTestChannelRecipientValidator([32]byte{1}, recipient),
+		NewMinChannelDepositValidator(big.NewInt(5000)),
+	)
+
+	assert.Equal(t, NewPaymentError(FailedPrecondition, "payment channel 42 deposit 1000 is less than minimal deposit 5000"),
+		validator.Validate(serviceStreamContext("org", "service-a"), channelOpenedTo([32]byte{1}, recipient)))
+	assert.Equal(t, PermissionDenied, validator.Validate(serviceStreamContext("org", "service-a"), channelOpenedTo([32]byte{2}, recipient)).(*PaymentError).Code)
+}
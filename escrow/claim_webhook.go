@@ -0,0 +1,158 @@
+package escrow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/singnet/snet-daemon/util/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClaimNotification is sent to the claim webhooks after funds are
+// successfully claimed from the payment channel.
+type ClaimNotification struct {
+	ChannelID *big.Int `json:"channel_id"`
+	Amount    *big.Int `json:"amount"`
+	TxHash    string   `json:"tx_hash"`
+}
+
+// webhookClient is used to post notifications to the webhooks.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// ClaimWebhookNotifier posts ClaimNotification as JSON to the list of URLs.
+// Delivery is retried when receiver is unreachable or responds with server
+// error.
+type ClaimWebhookNotifier struct {
+	urls   []string
+	client *http.Client
+	policy retry.Policy
+	// queue is used to deliver notifications when it is set, otherwise
+	// notifications are delivered by Notify and are lost if all attempts
+	// fail
+	queue *WebhookDeliveryQueue
+}
+
+// NewClaimWebhookNotifier returns notifier which posts notifications to the
+// given URLs.
+func NewClaimWebhookNotifier(urls []string) *ClaimWebhookNotifier {
+	return &ClaimWebhookNotifier{
+		urls:   urls,
+		client: webhookClient,
+		policy: DefaultWebhookRetryPolicy(5, time.Second, 30*time.Second),
+	}
+}
+
+// NewQueuedClaimWebhookNotifier returns notifier which puts notifications
+// to the delivery queue, so failed deliveries are retried later instead of
+// being lost.
+func NewQueuedClaimWebhookNotifier(urls []string, queue *WebhookDeliveryQueue) *ClaimWebhookNotifier {
+	notifier := NewClaimWebhookNotifier(urls)
+	notifier.queue = queue
+	return notifier
+}
+
+// DefaultWebhookRetryPolicy returns policy of the webhook delivery retries,
+// it doesn't retry deliveries rejected by receiver with client error.
+func DefaultWebhookRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) retry.Policy {
+	return retry.Policy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: initialBackoff,
+		MaxBackoff:     maxBackoff,
+		Jitter:         0.2,
+		Retryable:      isRetryableDeliveryError,
+	}
+}
+
+// webhookDeliveryError is returned when webhook responds with unexpected
+// HTTP status.
+type webhookDeliveryError struct {
+	url        string
+	statusCode int
+}
+
+func (err *webhookDeliveryError) Error() string {
+	return fmt.Sprintf("webhook %v responded with status %v", err.url, err.statusCode)
+}
+
+// isRetryableDeliveryError returns false only when receiver rejected the
+// notification with client error, repeating the same request doesn't help
+// in this case.
+func isRetryableDeliveryError(err error) bool {
+	if deliveryErr, ok := err.(*webhookDeliveryError); ok {
+		return deliveryErr.statusCode >= 500
+	}
+	return true
+}
+
+// Notify sends notification to all webhooks. Error is returned if
+// notification is not delivered to any of them.
+func (notifier *ClaimWebhookNotifier) Notify(notification *ClaimNotification) (err error) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("cannot serialize claim notification: %v", err)
+	}
+	if notifier.queue != nil {
+		return notifier.enqueue(body)
+	}
+
+	var failed []string
+	for _, url := range notifier.urls {
+		e := retry.Do(context.Background(), notifier.policy, func(ctx context.Context) error {
+			return notifier.post(url, body)
+		})
+		if e != nil {
+			log.WithError(e).WithField("url", url).WithField("notification", notification).Error("Cannot deliver claim notification")
+			failed = append(failed, url)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("claim notification is not delivered to %v", failed)
+	}
+	return nil
+}
+
+// enqueue puts notification to the delivery queue and makes the first
+// delivery attempt of it, failed deliveries are retried by the queue.
+func (notifier *ClaimWebhookNotifier) enqueue(body []byte) (err error) {
+	var failed []string
+	for _, url := range notifier.urls {
+		if e := notifier.queue.EnqueueAndDeliver(url, body); e != nil {
+			log.WithError(e).WithField("url", url).Error("Cannot enqueue claim notification")
+			failed = append(failed, url)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("claim notification is not enqueued for %v", failed)
+	}
+	return nil
+}
+
+func (notifier *ClaimWebhookNotifier) post(url string, body []byte) error {
+	return postWebhook(notifier.client, url, body)
+}
+
+// PostWebhook posts JSON body to the webhook url, error is returned when
+// receiver is unreachable or responds with status other than 2xx.
+func PostWebhook(url string, body []byte) error {
+	return postWebhook(webhookClient, url, body)
+}
+
+func postWebhook(client *http.Client, url string, body []byte) error {
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &webhookDeliveryError{url: url, statusCode: response.StatusCode}
+	}
+	return nil
+}
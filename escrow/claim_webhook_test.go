@@ -0,0 +1,106 @@
+package escrow
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookReceiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (receiver *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	receiver.bodies = append(receiver.bodies, string(body))
+	status := http.StatusOK
+	if len(receiver.bodies) <= len(receiver.statuses) {
+		status = receiver.statuses[len(receiver.bodies)-1]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestClaimWebhookNotifier(urls ...string) *ClaimWebhookNotifier {
+	notifier := NewClaimWebhookNotifier(urls)
+	notifier.policy.InitialBackoff = time.Millisecond
+	notifier.policy.MaxAttempts = 3
+	return notifier
+}
+
+var testClaimNotification = &ClaimNotification{
+	ChannelID: big.NewInt(42),
+	Amount:    big.NewInt(12345),
+	TxHash:    "0x0102030405060708091011121314151617181920212223242526272829303132",
+}
+
+const testClaimNotificationJson = `{"channel_id":42,"amount":12345,"tx_hash":"0x0102030405060708091011121314151617181920212223242526272829303132"}`
+
+func TestClaimWebhookNotifierPayload(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	err := newTestClaimWebhookNotifier(server.URL).Notify(testClaimNotification)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{testClaimNotificationJson}, receiver.bodies)
+}
+
+func TestClaimWebhookNotifierRetryOnServerError(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	err := newTestClaimWebhookNotifier(server.URL).Notify(testClaimNotification)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(receiver.bodies))
+	assert.Equal(t, testClaimNotificationJson, receiver.bodies[2])
+}
+
+func TestClaimWebhookNotifierMaxAttempts(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{500, 500, 500, 500}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	err := newTestClaimWebhookNotifier(server.URL).Notify(testClaimNotification)
+
+	assert.Equal(t, "claim notification is not delivered to ["+server.URL+"]", err.Error())
+	assert.Equal(t, 3, len(receiver.bodies))
+}
+
+func TestClaimWebhookNotifierNoRetryOnClientError(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	err := newTestClaimWebhookNotifier(server.URL).Notify(testClaimNotification)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(receiver.bodies))
+}
+
+func TestClaimWebhookNotifierSeveralUrls(t *testing.T) {
+	failing := &webhookReceiver{statuses: []int{400}}
+	failingServer := httptest.NewServer(failing)
+	defer failingServer.Close()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	err := newTestClaimWebhookNotifier(failingServer.URL, server.URL).Notify(testClaimNotification)
+
+	assert.Equal(t, "claim notification is not delivered to ["+failingServer.URL+"]", err.Error())
+	assert.Equal(t, []string{testClaimNotificationJson}, receiver.bodies)
+}
@@ -0,0 +1,165 @@
+package escrow
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CounterStore keeps named counters which expire after the given time to
+// live. Currently the only per sender counter in the daemon is the spend cap
+// (see NewSpendCapIncomeValidator); the daemon has no nonce replay protection
+// or free call quotas, new per sender limits should keep their counters here
+// instead of adding another storage. Storage requests are cancelled when
+// ctx is done.
+type CounterStore interface {
+	// Get returns current value of the counter, counter which is absent or
+	// expired has zero value.
+	Get(ctx context.Context, key string) (value *big.Int, err error)
+	// Increment atomically adds delta to the counter and returns the new
+	// value. Counter which is absent or expired starts from zero and expires
+	// after ttl; ttl of the existing counter is not changed.
+	Increment(ctx context.Context, key string, delta *big.Int, ttl time.Duration) (value *big.Int, err error)
+	// Reset removes the counter.
+	Reset(key string) (err error)
+}
+
+// counter is a value of the counter and the time it expires at.
+type counter struct {
+	Value     *big.Int
+	ExpiresAt time.Time
+}
+
+func (c *counter) expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+type memoryCounterStore struct {
+	counters map[string]*counter
+	mutex    sync.Mutex
+	now      func() time.Time
+}
+
+// NewMemCounterStore returns new in-memory CounterStore implementation,
+// counters are not shared between daemon replicas.
+func NewMemCounterStore() CounterStore {
+	return &memoryCounterStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+func (store *memoryCounterStore) Get(ctx context.Context, key string) (value *big.Int, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	c, ok := store.counters[key]
+	if !ok || c.expired(store.now()) {
+		return big.NewInt(0), nil
+	}
+	return new(big.Int).Set(c.Value), nil
+}
+
+func (store *memoryCounterStore) Increment(ctx context.Context, key string, delta *big.Int, ttl time.Duration) (value *big.Int, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	c, ok := store.counters[key]
+	if !ok || c.expired(now) {
+		store.removeExpired(now)
+		c = &counter{Value: big.NewInt(0), ExpiresAt: now.Add(ttl)}
+		store.counters[key] = c
+	}
+	c.Value.Add(c.Value, delta)
+	return new(big.Int).Set(c.Value), nil
+}
+
+// removeExpired releases memory allocated by expired counters, it is called
+// when new counter is added.
+func (store *memoryCounterStore) removeExpired(now time.Time) {
+	for key, c := range store.counters {
+		if c.expired(now) {
+			delete(store.counters, key)
+		}
+	}
+}
+
+func (store *memoryCounterStore) Reset(key string) (err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.counters, key)
+	return nil
+}
+
+type atomicCounterStore struct {
+	delegate TypedAtomicStorage
+	now      func() time.Time
+}
+
+// NewAtomicCounterStore returns CounterStore implementation which keeps
+// counters in the atomic storage. It is used with etcd storage to share
+// counters between daemon replicas. Expiration time is kept together with
+// the counter value, expired counter is overwritten on the next increment.
+func NewAtomicCounterStore(atomicStorage AtomicStorage) CounterStore {
+	return &atomicCounterStore{
+		delegate: &TypedAtomicStorageImpl{
+			atomicStorage: &PrefixedAtomicStorage{
+				delegate:  atomicStorage,
+				keyPrefix: "/payment-channel/counter",
+			},
+			keySerializer:     serialize,
+			valueSerializer:   serialize,
+			valueDeserializer: deserialize,
+			valueType:         reflect.TypeOf(counter{}),
+		},
+		now: time.Now,
+	}
+}
+
+func (store *atomicCounterStore) Get(ctx context.Context, key string) (value *big.Int, err error) {
+	c, ok, err := store.delegate.GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || c.(*counter).expired(store.now()) {
+		return big.NewInt(0), nil
+	}
+	return c.(*counter).Value, nil
+}
+
+func (store *atomicCounterStore) Increment(ctx context.Context, key string, delta *big.Int, ttl time.Duration) (value *big.Int, err error) {
+	for {
+		prev, ok, err := store.delegate.GetContext(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		now := store.now()
+		next := &counter{Value: new(big.Int).Set(delta), ExpiresAt: now.Add(ttl)}
+		if ok && !prev.(*counter).expired(now) {
+			next.Value.Add(next.Value, prev.(*counter).Value)
+			next.ExpiresAt = prev.(*counter).ExpiresAt
+		}
+
+		var swapped bool
+		if ok {
+			swapped, err = store.delegate.CompareAndSwapContext(ctx, key, prev, next)
+		} else {
+			swapped, err = store.delegate.PutIfAbsentContext(ctx, key, next)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if swapped {
+			return next.Value, nil
+		}
+	}
+}
+
+func (store *atomicCounterStore) Reset(key string) (err error) {
+	return store.delegate.Delete(key)
+}
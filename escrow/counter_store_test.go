@@ -0,0 +1,136 @@
+package escrow
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCounterNow = time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// newTestCounterStores returns all CounterStore implementations using the
+// same fake clock.
+func newTestCounterStores(now *time.Time) map[string]CounterStore {
+	memory := NewMemCounterStore().(*memoryCounterStore)
+	memory.now = func() time.Time { return *now }
+	atomic := NewAtomicCounterStore(NewMemStorage()).(*atomicCounterStore)
+	atomic.now = func() time.Time { return *now }
+	return map[string]CounterStore{"memory": memory, "atomic": atomic}
+}
+
+func TestCounterStoreIncrement(t *testing.T) {
+	now := testCounterNow
+	for name, store := range newTestCounterStores(&now) {
+		value, err := store.Increment(context.Background(), "key", big.NewInt(3), time.Minute)
+		assert.Nil(t, err, name)
+		assert.Equal(t, big.NewInt(3), value, name)
+
+		value, err = store.Increment(context.Background(), "key", big.NewInt(4), time.Minute)
+		assert.Nil(t, err, name)
+		assert.Equal(t, big.NewInt(7), value, name)
+
+		value, err = store.Get(context.Background(), "key")
+		assert.Nil(t, err, name)
+		assert.Equal(t, big.NewInt(7), value, name)
+	}
+}
+
+func TestCounterStoreGetAbsent(t *testing.T) {
+	now := testCounterNow
+	for name, store := range newTestCounterStores(&now) {
+		value, err := store.Get(context.Background(), "absent")
+
+		assert.Nil(t, err, name)
+		assert.Equal(t, big.NewInt(0), value, name)
+	}
+}
+
+func TestCounterStoreIncrementIsAtomic(t *testing.T) {
+	now := testCounterNow
+	for name, store := range newTestCounterStores(&now) {
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := store.Increment(context.Background(), "key", big.NewInt(1), time.Minute)
+				assert.Nil(t, err, name)
+			}()
+		}
+		wg.Wait()
+
+		value, err := store.Get(context.Background(), "key")
+		assert.Nil(t, err, name)
+		assert.Equal(t, big.NewInt(50), value, name)
+	}
+}
+
+func TestCounterStoreTTLExpiry(t *testing.T) {
+	now := testCounterNow
+	for name, store := range newTestCounterStores(&now) {
+		now = testCounterNow
+		store.Increment(context.Background(), "key", big.NewInt(5), time.Minute)
+		now = now.Add(30 * time.Second)
+		store.Increment(context.Background(), "key", big.NewInt(1), time.Minute)
+
+		value, _ := store.Get(context.Background(), "key")
+		assert.Equal(t, big.NewInt(6), value, name)
+
+		now = now.Add(30 * time.Second)
+		value, _ = store.Get(context.Background(), "key")
+		assert.Equal(t, big.NewInt(0), value, name, "ttl is counted from the first increment")
+
+		value, err := store.Increment(context.Background(), "key", big.NewInt(2), time.Minute)
+		assert.Nil(t, err, name)
+		assert.Equal(t, big.NewInt(2), value, name)
+	}
+}
+
+func TestCounterStoreReset(t *testing.T) {
+	now := testCounterNow
+	for name, store := range newTestCounterStores(&now) {
+		store.Increment(context.Background(), "key", big.NewInt(5), time.Minute)
+		store.Increment(context.Background(), "another-key", big.NewInt(1), time.Minute)
+
+		assert.Nil(t, store.Reset("key"), name)
+
+		value, _ := store.Get(context.Background(), "key")
+		assert.Equal(t, big.NewInt(0), value, name)
+		value, _ = store.Get(context.Background(), "another-key")
+		assert.Equal(t, big.NewInt(1), value, name)
+	}
+}
+
+func TestMemCounterStoreRemovesExpiredCounters(t *testing.T) {
+	now := testCounterNow
+	store := newTestCounterStores(&now)["memory"].(*memoryCounterStore)
+
+	store.Increment(context.Background(), "key", big.NewInt(1), time.Minute)
+	now = now.Add(time.Minute)
+	store.Increment(context.Background(), "another-key", big.NewInt(1), time.Minute)
+
+	assert.Equal(t, 1, len(store.counters))
+}
+
+func TestAtomicCounterStoreError(t *testing.T) {
+	store := NewAtomicCounterStore(&failingAtomicStorage{})
+
+	_, err := store.Increment(context.Background(), "key", big.NewInt(1), time.Minute)
+
+	assert.Equal(t, errors.New("storage is unavailable"), err)
+}
+
+func TestAtomicCounterStoreContextDone(t *testing.T) {
+	store := NewAtomicCounterStore(NewMemStorage())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.Increment(ctx, "key", big.NewInt(1), time.Minute)
+
+	assert.Equal(t, context.Canceled, err)
+}
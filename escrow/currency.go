@@ -0,0 +1,57 @@
+package escrow
+
+import (
+	"strings"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+// PaymentCurrencyHeader is a optional header which contains currency of the
+// payment declared by client. If it is absent then payment is considered to
+// be made in accepted currency.
+const PaymentCurrencyHeader = "snet-payment-currency"
+
+type currencyIncomeValidator struct {
+	delegate IncomeValidator
+	accepted string
+}
+
+// NewCurrencyIncomeValidator returns income validator which checks that
+// currency declared by the call is the accepted currency before passing
+// income to the delegate validator. Income is always received in cogs of
+// the accepted currency, so calls declaring other currencies are rejected
+// with InvalidArgument error instead of converting their income. Prices set
+// in other currencies are converted by NewCurrencyPriceProvider.
+func NewCurrencyIncomeValidator(delegate IncomeValidator, accepted string) (validator IncomeValidator) {
+	return &currencyIncomeValidator{
+		delegate: delegate,
+		accepted: strings.ToUpper(accepted),
+	}
+}
+
+func (validator *currencyIncomeValidator) Validate(data *IncomeData) (err error) {
+	currency, e := validator.currency(data)
+	if e != nil {
+		return NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+	}
+	if currency != validator.accepted {
+		return NewPaymentError(InvalidArgument, "currency \"%v\" is not accepted, accepted currency: %v", currency, validator.accepted)
+	}
+	return validator.delegate.Validate(data)
+}
+
+func (validator *currencyIncomeValidator) currency(data *IncomeData) (currency string, err *handler.GrpcError) {
+	if data.GrpcContext == nil || len(data.GrpcContext.MD.Get(PaymentCurrencyHeader)) == 0 {
+		return validator.accepted, nil
+	}
+	currency, err = handler.GetSingleValue(data.GrpcContext.MD, PaymentCurrencyHeader)
+	return strings.ToUpper(currency), err
+}
+
+func (validator *currencyIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:       "currency",
+		Parameters: map[string]string{"accepted": validator.accepted},
+		Children:   []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
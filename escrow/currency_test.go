@@ -0,0 +1,100 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func newTestCurrencyIncomeValidator() IncomeValidator {
+	return NewCurrencyIncomeValidator(NewIncomeValidator(big.NewInt(100)), "AGI")
+}
+
+func incomeInCurrency(income int64, md metadata.MD) *IncomeData {
+	return &IncomeData{
+		Income:      big.NewInt(income),
+		GrpcContext: &handler.GrpcStreamContext{MD: md},
+	}
+}
+
+func TestCurrencyIncomeValidatorAcceptedCurrency(t *testing.T) {
+	validator := newTestCurrencyIncomeValidator()
+
+	err := validator.Validate(incomeInCurrency(100, metadata.Pairs(PaymentCurrencyHeader, "agi")))
+
+	assert.Nil(t, err)
+}
+
+func TestCurrencyIncomeValidatorNoCurrencyHeader(t *testing.T) {
+	validator := newTestCurrencyIncomeValidator()
+
+	err := validator.Validate(incomeInCurrency(100, metadata.Pairs()))
+
+	assert.Nil(t, err)
+}
+
+func TestCurrencyIncomeValidatorAcceptedCurrencyUnderpayment(t *testing.T) {
+	validator := newTestCurrencyIncomeValidator()
+
+	err := validator.Validate(incomeInCurrency(99, metadata.Pairs(PaymentCurrencyHeader, "AGI")))
+
+	assert.Equal(t, incomeMismatchError(big.NewInt(99), big.NewInt(100)), err)
+}
+
+func TestCurrencyIncomeValidatorHighRateCurrencyIsRejected(t *testing.T) {
+	// income is received in cogs of accepted currency, declaring currency
+	// with high rate doesn't allow paying less
+	validator := newTestCurrencyIncomeValidator()
+
+	err := validator.Validate(incomeInCurrency(1, metadata.Pairs(PaymentCurrencyHeader, "BTC")))
+
+	assert.Equal(t, NewPaymentError(InvalidArgument, "currency \"BTC\" is not accepted, accepted currency: AGI"), err)
+}
+
+func TestCurrencyIncomeValidatorUnsupportedCurrency(t *testing.T) {
+	validator := newTestCurrencyIncomeValidator()
+
+	err := validator.Validate(incomeInCurrency(100, metadata.Pairs(PaymentCurrencyHeader, "EUR")))
+
+	assert.Equal(t, NewPaymentError(InvalidArgument, "currency \"EUR\" is not accepted, accepted currency: AGI"), err)
+}
+
+func TestCurrencyIncomeValidatorTooManyCurrencies(t *testing.T) {
+	validator := newTestCurrencyIncomeValidator()
+
+	err := validator.Validate(incomeInCurrency(100, metadata.Pairs(PaymentCurrencyHeader, "AGI", PaymentCurrencyHeader, "USD")))
+
+	assert.Equal(t, InvalidArgument, err.(*PaymentError).Code)
+}
+
+func TestCurrencyPriceProviderConvertsPriceIntoCogs(t *testing.T) {
+	provider := NewCurrencyPriceProvider(NewFixedPriceProvider(big.NewInt(40)), big.NewRat(5, 2))
+
+	price, err := provider.GetPriceInCogs("/service/method")
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(100), price)
+}
+
+func TestCurrencyPriceProviderRoundsPriceUp(t *testing.T) {
+	provider := NewCurrencyPriceProvider(NewFixedPriceProvider(big.NewInt(39)), big.NewRat(5, 2))
+
+	price, err := provider.GetPriceInCogs("/service/method")
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(98), price)
+}
+
+func TestCurrencyPriceProviderHighRateIncomeIsRejected(t *testing.T) {
+	// price is 1 BTC and 1 BTC is 1000000 cogs, income declared in any
+	// currency is compared with the converted price in cogs
+	validator := NewCurrencyIncomeValidator(NewPriceIncomeValidator(NewCurrencyPriceProvider(NewFixedPriceProvider(big.NewInt(1)), big.NewRat(1000000, 1))), "AGI")
+
+	err := validator.Validate(incomeInCurrency(1, metadata.Pairs()))
+
+	assert.Equal(t, incomeMismatchError(big.NewInt(1), big.NewInt(1000000)), err)
+}
@@ -0,0 +1,69 @@
+package escrow
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// signatureSchemeRaw means that client signs Keccak256 hash of the
+	// payment fields prefixed by "\x19Ethereum Signed Message:\n32".
+	signatureSchemeRaw = "raw"
+	// signatureSchemeEIP712 means that client signs payment as EIP-712 typed
+	// data, see https://eips.ethereum.org/EIPS/eip-712
+	signatureSchemeEIP712 = "eip712"
+)
+
+const (
+	// typedDataDomainName and typedDataDomainVersion are name and version
+	// of the signing domain, chain id is the id of the chain daemon is
+	// connected to and verifying contract is the MultiPartyEscrow contract
+	// from the service metadata
+	typedDataDomainName    = "MultiPartyEscrow"
+	typedDataDomainVersion = "1"
+)
+
+var (
+	domainTypeHash  = crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	paymentTypeHash = crypto.Keccak256([]byte("Payment(uint256 channelId,uint256 nonce,uint256 amount)"))
+	// typedDataPrefix is a prefix of the signed EIP-712 message
+	typedDataPrefix = []byte("\x19\x01")
+)
+
+// typedDataDomain is an EIP-712 domain of the payment signature. Chain id
+// prevents replaying the signature on another chain where the same contract
+// address is deployed.
+type typedDataDomain struct {
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// separator returns hashStruct of the domain.
+func (domain *typedDataDomain) separator() []byte {
+	return crypto.Keccak256(
+		domainTypeHash,
+		crypto.Keccak256([]byte(typedDataDomainName)),
+		crypto.Keccak256([]byte(typedDataDomainVersion)),
+		bigIntToBytes(domain.ChainID),
+		common.BytesToHash(domain.VerifyingContract.Bytes()).Bytes(),
+	)
+}
+
+// typedDataHash returns hash of the EIP-712 payment message which is signed
+// by client.
+func typedDataHash(domain *typedDataDomain, payment *Payment) []byte {
+	paymentHash := crypto.Keccak256(
+		paymentTypeHash,
+		bigIntToBytes(payment.ChannelID),
+		bigIntToBytes(payment.ChannelNonce),
+		bigIntToBytes(payment.Amount),
+	)
+	return crypto.Keccak256(bytes.Join([][]byte{
+		typedDataPrefix,
+		domain.separator(),
+		paymentHash,
+	}, nil))
+}
@@ -1,7 +1,9 @@
 package escrow
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -15,6 +17,10 @@ type lockingPaymentChannelService struct {
 	blockchainReader *BlockchainChannelReader
 	locker           Locker
 	validator        *ChannelPaymentValidator
+	now              func() time.Time
+	// replica is true if channel state and locks are shared with primary
+	// daemon, then channels cannot be claimed
+	replica bool
 }
 
 // NewPaymentChannelService returns instance of PaymentChannelService to work
@@ -32,16 +38,40 @@ func NewPaymentChannelService(
 		blockchainReader: blockchainReader,
 		locker:           locker,
 		validator:        channelPaymentValidator,
+		now:              time.Now,
 	}
 }
 
+// NewReplicaPaymentChannelService returns PaymentChannelService of the
+// replica daemon. Replica shares channel storage and locker with primary
+// daemon, so payment accepted by replica is applied to the same channel state
+// and cannot be replayed to primary or other replicas, and primary claims it
+// as usual. Channels cannot be claimed by replica, paymentStorage is used to
+// read claims only.
+func NewReplicaPaymentChannelService(
+	storage *PaymentChannelStorage,
+	paymentStorage *PaymentStorage,
+	blockchainReader *BlockchainChannelReader,
+	locker Locker,
+	channelPaymentValidator *ChannelPaymentValidator) PaymentChannelService {
+
+	service := NewPaymentChannelService(storage, paymentStorage, blockchainReader,
+		locker, channelPaymentValidator).(*lockingPaymentChannelService)
+	service.replica = true
+	return service
+}
+
 func (h *lockingPaymentChannelService) PaymentChannel(key *PaymentChannelKey) (channel *PaymentChannelData, ok bool, err error) {
-	storageChannel, storageOk, err := h.storage.Get(key)
+	return h.paymentChannel(context.Background(), key)
+}
+
+func (h *lockingPaymentChannelService) paymentChannel(ctx context.Context, key *PaymentChannelKey) (channel *PaymentChannelData, ok bool, err error) {
+	storageChannel, storageOk, err := h.storage.GetContext(ctx, key)
 	if err != nil {
 		return
 	}
 
-	blockchainChannel, blockchainOk, err := h.blockchainReader.GetChannelStateFromBlockchain(key)
+	blockchainChannel, blockchainOk, err := h.blockchainReader.GetChannelStateFromBlockchain(ctx, key)
 	if !storageOk {
 		return blockchainChannel, blockchainOk, err
 	}
@@ -69,7 +99,15 @@ func (claim *claimImpl) Finish() (err error) {
 	return claim.paymentStorage.Delete(claim.payment)
 }
 
+func (claim *claimImpl) Fail() (err error) {
+	return claim.paymentStorage.MarkFailed(claim.payment)
+}
+
 func (h *lockingPaymentChannelService) StartClaim(key *PaymentChannelKey, update ChannelUpdate) (claim Claim, err error) {
+	if h.replica {
+		return nil, fmt.Errorf("channels cannot be claimed in replica mode")
+	}
+
 	lock, ok, err := h.locker.Lock(key.String())
 	if err != nil {
 		return nil, fmt.Errorf("cannot get mutex for channel: %v", key)
@@ -132,6 +170,10 @@ func (h *lockingPaymentChannelService) ListClaims() (claims []Claim, err error)
 	return
 }
 
+func (h *lockingPaymentChannelService) ListFailedClaims() (payments []*Payment, err error) {
+	return h.paymentStorage.GetAllFailed()
+}
+
 func getPaymentFromChannel(channel *PaymentChannelData) *Payment {
 	return &Payment{
 		// TODO: add MpeContractAddress to channel state
@@ -158,7 +200,7 @@ func (payment *paymentTransaction) Channel() *PaymentChannelData {
 	return payment.channel
 }
 
-func (h *lockingPaymentChannelService) StartPaymentTransaction(payment *Payment) (transaction PaymentTransaction, err error) {
+func (h *lockingPaymentChannelService) StartPaymentTransaction(ctx context.Context, payment *Payment) (transaction PaymentTransaction, err error) {
 	channelKey := &PaymentChannelKey{ID: payment.ChannelID}
 
 	lock, ok, err := h.locker.Lock(channelKey.String())
@@ -177,7 +219,7 @@ func (h *lockingPaymentChannelService) StartPaymentTransaction(payment *Payment)
 		}
 	}(lock)
 
-	channel, ok, err := h.PaymentChannel(channelKey)
+	channel, ok, err := h.paymentChannel(ctx, channelKey)
 	if err != nil {
 		return nil, NewPaymentError(Internal, "payment channel storage error")
 	}
@@ -186,7 +228,7 @@ func (h *lockingPaymentChannelService) StartPaymentTransaction(payment *Payment)
 		return nil, NewPaymentError(Unauthenticated, "payment channel \"%v\" not found", channelKey)
 	}
 
-	err = h.validator.Validate(payment, channel)
+	err = h.validator.Validate(ctx, payment, channel)
 	if err != nil {
 		return
 	}
@@ -206,20 +248,31 @@ func (payment *paymentTransaction) Commit() error {
 			log.WithError(err).WithField("payment", payment).Error("Channel cannot be unlocked because of error. All other transactions on this channel will be blocked until unlock. Please unlock channel manually.")
 		}
 	}(payment)
+	unclaimedSince := payment.channel.UnclaimedSince
+	if unclaimedSince.IsZero() {
+		unclaimedSince = payment.service.now()
+	}
+	maxAuthorizedAmount := payment.payment.Amount
+	if payment.channel.MaxAuthorizedAmount != nil && payment.channel.MaxAuthorizedAmount.Cmp(maxAuthorizedAmount) > 0 {
+		maxAuthorizedAmount = payment.channel.MaxAuthorizedAmount
+	}
 	e := payment.service.storage.Put(
 		&PaymentChannelKey{ID: payment.payment.ChannelID},
 		&PaymentChannelData{
-			ChannelID:        payment.channel.ChannelID,
-			Nonce:            payment.channel.Nonce,
-			State:            payment.channel.State,
-			Sender:           payment.channel.Sender,
-			Recipient:        payment.channel.Recipient,
-			FullAmount:       payment.channel.FullAmount,
-			Expiration:       payment.channel.Expiration,
-			Signer:           payment.channel.Signer,
-			AuthorizedAmount: payment.payment.Amount,
-			Signature:        payment.payment.Signature,
-			GroupID:          payment.channel.GroupID,
+			ChannelID:           payment.channel.ChannelID,
+			Nonce:               payment.channel.Nonce,
+			State:               payment.channel.State,
+			Sender:              payment.channel.Sender,
+			Recipient:           payment.channel.Recipient,
+			FullAmount:          payment.channel.FullAmount,
+			Expiration:          payment.channel.Expiration,
+			Signer:              payment.channel.Signer,
+			AuthorizedAmount:    payment.payment.Amount,
+			MaxAuthorizedAmount: maxAuthorizedAmount,
+			Signature:           payment.payment.Signature,
+			GroupID:             payment.channel.GroupID,
+			UnclaimedSince:      unclaimedSince,
+			Service:             payment.channel.Service,
 		},
 	)
 	if e != nil {
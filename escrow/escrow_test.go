@@ -1,10 +1,12 @@
 package escrow
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -12,6 +14,8 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/metrics"
 )
 
 type paymentChannelServiceMock struct {
@@ -47,7 +51,7 @@ func (p *paymentChannelServiceMock) Clear() {
 	p.err = nil
 }
 
-func (p *paymentChannelServiceMock) StartPaymentTransaction(payment *Payment) (PaymentTransaction, error) {
+func (p *paymentChannelServiceMock) StartPaymentTransaction(ctx context.Context, payment *Payment) (PaymentTransaction, error) {
 	if p.err != nil {
 		return nil, p.err
 	}
@@ -59,8 +63,10 @@ func (p *paymentChannelServiceMock) StartPaymentTransaction(payment *Payment) (P
 }
 
 type paymentTransactionMock struct {
-	channel *PaymentChannelData
-	err     error
+	channel    *PaymentChannelData
+	err        error
+	committed  bool
+	rolledBack bool
 }
 
 func (transaction *paymentTransactionMock) Channel() *PaymentChannelData {
@@ -68,10 +74,12 @@ func (transaction *paymentTransactionMock) Channel() *PaymentChannelData {
 }
 
 func (transaction *paymentTransactionMock) Commit() error {
+	transaction.committed = true
 	return transaction.err
 }
 
 func (transaction *paymentTransactionMock) Rollback() error {
+	transaction.rolledBack = true
 	return transaction.err
 }
 
@@ -86,6 +94,7 @@ type PaymentChannelServiceSuite struct {
 	memoryStorage      *memoryStorage
 	storage            *PaymentChannelStorage
 	paymentStorage     *PaymentStorage
+	now                time.Time
 
 	service PaymentChannelService
 }
@@ -109,22 +118,21 @@ func (suite *PaymentChannelServiceSuite) SetupSuite() {
 		suite.storage,
 		suite.paymentStorage,
 		&BlockchainChannelReader{
-			replicaGroupID: func() ([32]byte, error) {
-				return [32]byte{123}, nil
-			},
-			readChannelFromBlockchain: func(channelID *big.Int) (*blockchain.MultiPartyEscrowChannel, bool, error) {
+			readChannelFromBlockchain: func(ctx context.Context, channelID *big.Int) (*blockchain.MultiPartyEscrowChannel, bool, error) {
 				return suite.mpeChannel(), true, nil
 			},
-			recipientPaymentAddress: func() common.Address {
-				return suite.recipientAddress
+			recipients: func() []ChannelRecipient {
+				return []ChannelRecipient{{GroupID: [32]byte{123}, PaymentAddress: suite.recipientAddress}}
 			},
 		},
 		NewEtcdLocker(suite.memoryStorage),
 		&ChannelPaymentValidator{
-			currentBlock:               func() (*big.Int, error) { return big.NewInt(99), nil },
+			currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
 			paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
 		},
 	)
+	suite.now = time.Unix(1546300800, 0).UTC()
+	suite.service.(*lockingPaymentChannelService).now = func() time.Time { return suite.now }
 }
 
 func (suite *PaymentChannelServiceSuite) SetupTest() {
@@ -183,13 +191,15 @@ func (suite *PaymentChannelServiceSuite) channelPlusPayment(payment *Payment) *P
 	channel := suite.channel()
 	channel.Signature = payment.Signature
 	channel.AuthorizedAmount = payment.Amount
+	channel.MaxAuthorizedAmount = payment.Amount
+	channel.UnclaimedSince = suite.now
 	return channel
 }
 
 func (suite *PaymentChannelServiceSuite) TestPaymentTransaction() {
 	payment := suite.payment()
 
-	transaction, errA := suite.service.StartPaymentTransaction(payment)
+	transaction, errA := suite.service.StartPaymentTransaction(context.Background(), payment)
 	errB := transaction.Commit()
 	channel, ok, errC := suite.storage.Get(suite.channelKey())
 
@@ -208,8 +218,8 @@ func (suite *PaymentChannelServiceSuite) TestPaymentParallelTransaction() {
 	paymentB.Amount = big.NewInt(17)
 	SignTestPayment(paymentB, suite.signerPrivateKey)
 
-	transactionA, errA := suite.service.StartPaymentTransaction(paymentA)
-	transactionB, errB := suite.service.StartPaymentTransaction(paymentB)
+	transactionA, errA := suite.service.StartPaymentTransaction(context.Background(), paymentA)
+	transactionB, errB := suite.service.StartPaymentTransaction(context.Background(), paymentB)
 	errC := transactionA.Commit()
 	channel, ok, errD := suite.storage.Get(suite.channelKey())
 
@@ -230,9 +240,9 @@ func (suite *PaymentChannelServiceSuite) TestPaymentSequentialTransaction() {
 	paymentB.Amount = big.NewInt(17)
 	SignTestPayment(paymentB, suite.signerPrivateKey)
 
-	transactionA, errA := suite.service.StartPaymentTransaction(paymentA)
+	transactionA, errA := suite.service.StartPaymentTransaction(context.Background(), paymentA)
 	errAC := transactionA.Commit()
-	transactionB, errB := suite.service.StartPaymentTransaction(paymentB)
+	transactionB, errB := suite.service.StartPaymentTransaction(context.Background(), paymentB)
 	errBC := transactionB.Commit()
 	channel, ok, errD := suite.storage.Get(suite.channelKey())
 
@@ -253,9 +263,9 @@ func (suite *PaymentChannelServiceSuite) TestPaymentSequentialTransactionAfterRo
 	paymentB.Amount = big.NewInt(13)
 	SignTestPayment(paymentB, suite.signerPrivateKey)
 
-	transactionA, errA := suite.service.StartPaymentTransaction(paymentA)
+	transactionA, errA := suite.service.StartPaymentTransaction(context.Background(), paymentA)
 	errAC := transactionA.Rollback()
-	transactionB, errB := suite.service.StartPaymentTransaction(paymentB)
+	transactionB, errB := suite.service.StartPaymentTransaction(context.Background(), paymentB)
 	errBC := transactionB.Commit()
 	channel, ok, errD := suite.storage.Get(suite.channelKey())
 
@@ -268,8 +278,74 @@ func (suite *PaymentChannelServiceSuite) TestPaymentSequentialTransactionAfterRo
 	assert.Equal(suite.T(), suite.channelPlusPayment(paymentB), channel)
 }
 
+func (suite *PaymentChannelServiceSuite) TestPaymentTransactionKeepsMaxAuthorizedAmount() {
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Channel().MaxAuthorizedAmount = big.NewInt(20000)
+	transaction.Commit()
+	channel, _, err := suite.storage.Get(suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.Equal(suite.T(), big.NewInt(12300), channel.AuthorizedAmount)
+	assert.Equal(suite.T(), big.NewInt(20000), channel.MaxAuthorizedAmount)
+}
+
+func (suite *PaymentChannelServiceSuite) TestStartClaimResetsMaxAuthorizedAmount() {
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Commit()
+
+	_, err := suite.service.StartClaim(suite.channelKey(), IncrementChannelNonce)
+	channel, _, _ := suite.storage.Get(suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.Equal(suite.T(), big.NewInt(0), channel.MaxAuthorizedAmount)
+}
+
+func (suite *PaymentChannelServiceSuite) TestPaymentTransactionKeepsUnclaimedSince() {
+	paymentA := suite.payment()
+	paymentA.Amount = big.NewInt(13)
+	SignTestPayment(paymentA, suite.signerPrivateKey)
+	paymentB := suite.payment()
+	paymentB.Amount = big.NewInt(17)
+	SignTestPayment(paymentB, suite.signerPrivateKey)
+	firstPaymentTime := suite.now
+	defer func() { suite.now = firstPaymentTime }()
+
+	transactionA, _ := suite.service.StartPaymentTransaction(context.Background(), paymentA)
+	transactionA.Commit()
+	suite.now = suite.now.Add(time.Hour)
+	transactionB, _ := suite.service.StartPaymentTransaction(context.Background(), paymentB)
+	transactionB.Commit()
+	channel, _, err := suite.storage.Get(suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.Equal(suite.T(), firstPaymentTime, channel.UnclaimedSince)
+}
+
+func (suite *PaymentChannelServiceSuite) TestPaymentTransactionKeepsService() {
+	service := handler.ServiceKey{OrganizationID: "org", ServiceID: "service"}
+
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Channel().Service = service
+	transaction.Commit()
+	channel, _, err := suite.storage.Get(suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.Equal(suite.T(), service, channel.Service)
+}
+
+func (suite *PaymentChannelServiceSuite) TestStartClaimResetsUnclaimedSince() {
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Commit()
+
+	_, err := suite.service.StartClaim(suite.channelKey(), IncrementChannelNonce)
+	channel, _, _ := suite.storage.Get(suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.True(suite.T(), channel.UnclaimedSince.IsZero())
+}
+
 func (suite *PaymentChannelServiceSuite) TestStartClaim() {
-	transaction, _ := suite.service.StartPaymentTransaction(suite.payment())
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
 	transaction.Commit()
 
 	claim, errA := suite.service.StartClaim(suite.channelKey(), IncrementChannelNonce)
@@ -280,3 +356,130 @@ func (suite *PaymentChannelServiceSuite) TestStartClaim() {
 	assert.Equal(suite.T(), suite.payment(), claim.Payment())
 	assert.Equal(suite.T(), []*Payment{suite.payment()}, claims)
 }
+
+func (suite *PaymentChannelServiceSuite) TestFailClaim() {
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Commit()
+	claim, _ := suite.service.StartClaim(suite.channelKey(), IncrementChannelNonce)
+
+	err := claim.Fail()
+
+	assert.Nil(suite.T(), err)
+	claims, err := suite.service.ListClaims()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, len(claims), "failed claim is not in progress")
+	failed, err := suite.service.ListFailedClaims()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*Payment{suite.payment()}, failed)
+}
+
+// replicaService returns service which shares channel storage and locks
+// with suite.service, other storages are read only.
+func (suite *PaymentChannelServiceSuite) replicaService() PaymentChannelService {
+	primary := suite.service.(*lockingPaymentChannelService)
+	return NewReplicaPaymentChannelService(
+		NewPaymentChannelStorage(suite.memoryStorage),
+		NewPaymentStorage(NewReadOnlyAtomicStorage(suite.memoryStorage)),
+		primary.blockchainReader,
+		primary.locker,
+		primary.validator,
+	)
+}
+
+func (suite *PaymentChannelServiceSuite) TestReplicaReadsChannelStateOfPrimary() {
+	paymentA := suite.payment()
+	paymentA.Amount = big.NewInt(13)
+	SignTestPayment(paymentA, suite.signerPrivateKey)
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), paymentA)
+	transaction.Commit()
+	replica := suite.replicaService()
+
+	channel, ok, err := replica.PaymentChannel(suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), big.NewInt(13), channel.AuthorizedAmount)
+}
+
+func (suite *PaymentChannelServiceSuite) TestReplicaValidatesPaymentAgainstPrimaryState() {
+	paymentA := suite.payment()
+	paymentA.Amount = big.NewInt(13)
+	SignTestPayment(paymentA, suite.signerPrivateKey)
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), paymentA)
+	transaction.Commit()
+	paymentB := suite.payment()
+	paymentB.Amount = big.NewInt(12)
+	SignTestPayment(paymentB, suite.signerPrivateKey)
+	replica := suite.replicaService()
+
+	_, err := replica.StartPaymentTransaction(context.Background(), paymentB)
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionUnderpaid, Unauthenticated, "payment amount 12 is less than previously authorized amount 13"), err, "payment is validated against state written by primary")
+}
+
+func (suite *PaymentChannelServiceSuite) TestReplicaPaymentCannotBeReplayedToPrimary() {
+	paymentA := suite.payment()
+	paymentA.Amount = big.NewInt(20)
+	SignTestPayment(paymentA, suite.signerPrivateKey)
+	paymentB := suite.payment()
+	paymentB.Amount = big.NewInt(15)
+	SignTestPayment(paymentB, suite.signerPrivateKey)
+	replica := suite.replicaService()
+	transaction, _ := replica.StartPaymentTransaction(context.Background(), paymentA)
+	errA := transaction.Commit()
+
+	channel, _, _ := suite.storage.Get(suite.channelKey())
+	_, errB := suite.service.StartPaymentTransaction(context.Background(), paymentB)
+	_, errC := suite.replicaService().StartPaymentTransaction(context.Background(), paymentB)
+
+	assert.Nil(suite.T(), errA, "Unexpected error: %v", errA)
+	assert.Equal(suite.T(), big.NewInt(20), channel.AuthorizedAmount, "payment accepted by replica is stored to shared storage")
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionUnderpaid, Unauthenticated, "payment amount 15 is less than previously authorized amount 20"), errB, "primary validates payment against state written by replica")
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionUnderpaid, Unauthenticated, "payment amount 15 is less than previously authorized amount 20"), errC, "other replica validates payment against state written by replica")
+}
+
+func (suite *PaymentChannelServiceSuite) TestReplicaSharesChannelLockWithPrimary() {
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	replica := suite.replicaService()
+
+	_, err := replica.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Rollback()
+
+	assert.Equal(suite.T(), NewPaymentError(FailedPrecondition, "another transaction on channel: {ID: 42} is in progress"), err)
+}
+
+func (suite *PaymentChannelServiceSuite) TestReplicaCannotClaimChannel() {
+	transaction, _ := suite.service.StartPaymentTransaction(context.Background(), suite.payment())
+	transaction.Commit()
+	replica := suite.replicaService()
+
+	claim, err := replica.StartClaim(suite.channelKey(), IncrementChannelNonce)
+	claims, _ := suite.paymentStorage.GetAll()
+
+	assert.EqualError(suite.T(), err, "channels cannot be claimed in replica mode")
+	assert.Nil(suite.T(), claim)
+	assert.Empty(suite.T(), claims)
+}
+
+func TestReadOnlyAtomicStorage(t *testing.T) {
+	delegate := NewMemStorage()
+	delegate.Put("key", "value")
+	storage := NewReadOnlyAtomicStorage(delegate)
+
+	value, ok, err := storage.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+	values, err := storage.GetByKeyPrefix("k")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"value"}, values)
+
+	assert.Equal(t, ErrReadOnlyStorage, storage.Put("key", "new"))
+	_, err = storage.PutIfAbsent("other", "new")
+	assert.Equal(t, ErrReadOnlyStorage, err)
+	_, err = storage.CompareAndSwap("key", "value", "new")
+	assert.Equal(t, ErrReadOnlyStorage, err)
+	assert.Equal(t, ErrReadOnlyStorage, storage.Delete("key"))
+	value, _, _ = delegate.Get("key")
+	assert.Equal(t, "value", value)
+}
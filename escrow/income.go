@@ -1,9 +1,20 @@
 package escrow
 
 import (
+	"context"
+	"fmt"
 	"math/big"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
 	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/metrics"
+	"github.com/singnet/snet-daemon/tracing"
 )
 
 // IncomeData is used to pass information to the pricing validation system.
@@ -13,9 +24,57 @@ type IncomeData struct {
 	// Income is a difference between previous authorized amount and amount
 	// which was received with current call.
 	Income *big.Int
+	// Sender is an Ethereum address of the payment channel sender.
+	Sender common.Address
 	// GrpcContext contains gRPC stream context information. For instance
 	// metadata could be used to pass invoice id to check pricing.
 	GrpcContext *handler.GrpcStreamContext
+	// MessageCount is a number of messages which should be paid by the
+	// client streaming call validated after each message, it is 0 for other
+	// calls.
+	MessageCount int
+	// Context is passed to the storage requests made by validators, it is
+	// done when the call is cancelled or income validation times out. nil
+	// means context.Background().
+	Context context.Context
+	// rollbacks revert changes made by validators when the payment is not
+	// applied, nil means that changes are never reverted. It is a pointer
+	// so copies of the income share it.
+	rollbacks *[]func()
+}
+
+// onRollback registers function which reverts changes made by validator
+// when the payment of the call is not applied.
+func (data *IncomeData) onRollback(rollback func()) {
+	if data.rollbacks != nil {
+		*data.rollbacks = append(*data.rollbacks, rollback)
+	}
+}
+
+// rollback reverts changes made by validators, each change is reverted once.
+func (data *IncomeData) rollback() {
+	if data.rollbacks == nil {
+		return
+	}
+	rollbacks := *data.rollbacks
+	*data.rollbacks = nil
+	for _, rollback := range rollbacks {
+		rollback()
+	}
+}
+
+func (data *IncomeData) context() context.Context {
+	if data.Context == nil {
+		return context.Background()
+	}
+	return data.Context
+}
+
+func (data *IncomeData) method() string {
+	if data.GrpcContext == nil || data.GrpcContext.Info == nil {
+		return ""
+	}
+	return data.GrpcContext.Info.FullMethod
 }
 
 // IncomeValidator uses pricing information to check that call was payed
@@ -30,23 +89,383 @@ type IncomeValidator interface {
 	Validate(*IncomeData) (err error)
 }
 
+// ValidatorInfo describes income validator and its parameters for
+// diagnostics.
+type ValidatorInfo struct {
+	// Type is a type of the validator
+	Type string `json:"type"`
+	// Parameters contains key parameters of the validator
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Children contains descriptions of the validators which are called by
+	// this validator
+	Children []ValidatorInfo `json:"children,omitempty"`
+}
+
+type describableValidator interface {
+	describe() ValidatorInfo
+}
+
+// DescribeValidator returns description of the validator and all validators
+// it delegates to.
+func DescribeValidator(validator IncomeValidator) ValidatorInfo {
+	if describable, ok := validator.(describableValidator); ok {
+		return describable.describe()
+	}
+	return ValidatorInfo{Type: fmt.Sprintf("%T", validator)}
+}
+
 type incomeValidator struct {
-	priceInCogs *big.Int
+	priceProvider  PriceProvider
+	jobValueHeader string
 }
 
 // NewIncomeValidator returns new income validator instance
 func NewIncomeValidator(priceInCogs *big.Int) (validator IncomeValidator) {
-	return &incomeValidator{priceInCogs: priceInCogs}
+	return NewPriceIncomeValidator(NewFixedPriceProvider(priceInCogs))
+}
+
+// NewPriceIncomeValidator returns income validator which checks that income
+// is equal to the price returned by price provider.
+func NewPriceIncomeValidator(priceProvider PriceProvider) (validator IncomeValidator) {
+	return &incomeValidator{priceProvider: priceProvider}
+}
+
+// NewJobValueIncomeValidator returns income validator which checks that
+// income is equal to the price returned by price provider multiplied by the
+// job value passed in the header. Job value is 1 when header is not passed.
+func NewJobValueIncomeValidator(priceProvider PriceProvider, jobValueHeader string) (validator IncomeValidator) {
+	return &incomeValidator{priceProvider: priceProvider, jobValueHeader: jobValueHeader}
 }
 
 func (validator *incomeValidator) Validate(data *IncomeData) (err error) {
+	jobValue, err := getJobValue(data, validator.jobValueHeader)
+	if err != nil {
+		return
+	}
 
-	price := validator.priceInCogs
+	price, err := validator.priceProvider.GetPriceInCogs(data.method())
+	if err != nil {
+		return NewPaymentError(Internal, "cannot determine price of the call")
+	}
+	price = new(big.Int).Mul(price, jobValue)
 
 	if data.Income.Cmp(price) != 0 {
-		err = NewPaymentError(Unauthenticated, "income %d does not equal to price %d", data.Income, price)
-		return
+		e := NewPaymentError(Unauthenticated, "income %d does not equal to price %d", data.Income, price)
+		e.Details = map[string]interface{}{"expected": price, "received": data.Income}
+		e.Reason = config.ValidationFailurePriceMismatch
+		return e
+	}
+
+	return
+}
+
+func (validator *incomeValidator) describe() ValidatorInfo {
+	info := ValidatorInfo{
+		Type:       "price",
+		Parameters: map[string]string{"price_provider": fmt.Sprintf("%v", validator.priceProvider)},
+	}
+	if validator.jobValueHeader != "" {
+		info.Parameters["job_value_header"] = validator.jobValueHeader
+	}
+	return info
+}
+
+// GetJobValueHeader returns name of the header which contains job value or
+// empty string if job value is not used to calculate price.
+func GetJobValueHeader() string {
+	return strings.ToLower(config.GetString(config.JobValueHeaderKey))
+}
+
+func getJobValue(data *IncomeData, header string) (value *big.Int, err error) {
+	if header == "" || data.GrpcContext == nil || len(data.GrpcContext.MD.Get(header)) == 0 {
+		return big.NewInt(1), nil
+	}
+
+	value, e := handler.GetBigInt(data.GrpcContext.MD, header)
+	if e != nil {
+		return nil, NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+	}
+	if value.Sign() <= 0 {
+		return nil, NewPaymentError(InvalidArgument, "incorrect job value %v, positive value is expected", value)
+	}
+	return value, nil
+}
+
+type serviceIncomeValidator struct {
+	headers    handler.ServiceKeyHeaders
+	validators map[handler.ServiceKey]IncomeValidator
+}
+
+// NewServiceIncomeValidator returns income validator for the daemon which
+// serves few services. It passes income to the validator of the service which
+// is called. Called service is determined using given metadata headers.
+func NewServiceIncomeValidator(headers handler.ServiceKeyHeaders, validators map[handler.ServiceKey]IncomeValidator) (validator IncomeValidator) {
+	return &serviceIncomeValidator{headers: headers, validators: validators}
+}
+
+func (validator *serviceIncomeValidator) Validate(data *IncomeData) (err error) {
+	key, e := validator.headers.GetServiceKey(data.GrpcContext.MD)
+	if e != nil {
+		return NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+	}
+
+	delegate, ok := validator.validators[key]
+	if !ok {
+		return NewPaymentError(InvalidArgument, "service \"%v\" is not served", key)
+	}
+
+	return delegate.Validate(data)
+}
+
+func (validator *serviceIncomeValidator) describe() ValidatorInfo {
+	keys := make([]handler.ServiceKey, 0, len(validator.validators))
+	for key := range validator.validators {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	info := ValidatorInfo{
+		Type: "service",
+		Parameters: map[string]string{
+			"organization_id_header": validator.headers.OrganizationID,
+			"service_id_header":      validator.headers.ServiceID,
+		},
+	}
+	for _, key := range keys {
+		child := DescribeValidator(validator.validators[key])
+		if child.Parameters == nil {
+			child.Parameters = make(map[string]string)
+		}
+		child.Parameters["service"] = key.String()
+		info.Children = append(info.Children, child)
+	}
+	return info
+}
+
+type timeoutIncomeValidator struct {
+	delegate IncomeValidator
+	timeout  time.Duration
+}
+
+// NewTimeoutIncomeValidator returns income validator which passes to the
+// delegate validator IncomeData with Context which is done after given
+// timeout. Storage requests made by delegate are cancelled when timeout
+// expires and validation fails with DeadlineExceeded error. It is used to
+// not stall the call when validator consults remote storage which hangs.
+func NewTimeoutIncomeValidator(delegate IncomeValidator, timeout time.Duration) (validator IncomeValidator) {
+	return &timeoutIncomeValidator{delegate: delegate, timeout: timeout}
+}
+
+func (validator *timeoutIncomeValidator) Validate(data *IncomeData) (err error) {
+	ctx, cancel := context.WithTimeout(data.context(), validator.timeout)
+	defer cancel()
+
+	bounded := *data
+	bounded.Context = ctx
+	err = validator.delegate.Validate(&bounded)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return NewPaymentError(DeadlineExceeded, "income validation timed out after %v", validator.timeout)
+	}
+	return
+}
+
+func (validator *timeoutIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:       "timeout",
+		Parameters: map[string]string{"timeout": validator.timeout.String()},
+		Children:   []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
+
+type fallbackIncomeValidator struct {
+	primary  IncomeValidator
+	fallback IncomeValidator
+}
+
+// NewFallbackIncomeValidator returns income validator which validates income
+// using fallback validator when primary validator fails with infrastructure
+// error (see IsInfrastructureError). Genuine rejections of the primary
+// validator are returned as is.
+func NewFallbackIncomeValidator(primary, fallback IncomeValidator) (validator IncomeValidator) {
+	return &fallbackIncomeValidator{primary: primary, fallback: fallback}
+}
+
+func (validator *fallbackIncomeValidator) Validate(data *IncomeData) (err error) {
+	err = validator.primary.Validate(data)
+	if !IsInfrastructureError(err) {
+		return err
+	}
+
+	log.WithError(err).WithField("method", data.method()).Warn("Primary income validator failed, use fallback validator")
+	return validator.fallback.Validate(data)
+}
+
+func (validator *fallbackIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:     "fallback",
+		Children: []ValidatorInfo{DescribeValidator(validator.primary), DescribeValidator(validator.fallback)},
 	}
+}
+
+type minIncomeValidator struct {
+	minIncome *big.Int
+}
+
+// NewMinIncomeValidator returns income validator which rejects calls with
+// income less than minIncome with InvalidArgument error regardless of the
+// method price. It is cheap and so it should be placed before price
+// validator in the composite validator.
+func NewMinIncomeValidator(minIncome *big.Int) (validator IncomeValidator) {
+	return &minIncomeValidator{minIncome: minIncome}
+}
+
+func (validator *minIncomeValidator) Validate(data *IncomeData) (err error) {
+	if data.Income.Cmp(validator.minIncome) < 0 {
+		e := NewPaymentError(InvalidArgument, "income %d is less than minimal income %d", data.Income, validator.minIncome)
+		e.Details = map[string]interface{}{"min_income": validator.minIncome, "expected": validator.minIncome, "received": data.Income}
+		e.Reason = config.ValidationFailureMinIncome
+		return e
+	}
+	return nil
+}
+
+func (validator *minIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:       "min_income",
+		Parameters: map[string]string{"min_income": validator.minIncome.String()},
+	}
+}
+
+type compositeIncomeValidator struct {
+	validators []IncomeValidator
+}
+
+// NewCompositeIncomeValidator returns income validator which calls
+// validators in the given order and returns the first rejection, next
+// validators are not called in this case. Cheap validators should be placed
+// first so calls which are rejected by them don't wait for the expensive
+// ones, for instance for the remote price provider.
+func NewCompositeIncomeValidator(validators ...IncomeValidator) (validator IncomeValidator) {
+	return &compositeIncomeValidator{validators: validators}
+}
+
+func (validator *compositeIncomeValidator) Validate(data *IncomeData) (err error) {
+	for _, delegate := range validator.validators {
+		if err = delegate.Validate(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (validator *compositeIncomeValidator) describe() ValidatorInfo {
+	info := ValidatorInfo{Type: "composite"}
+	for _, delegate := range validator.validators {
+		info.Children = append(info.Children, DescribeValidator(delegate))
+	}
+	return info
+}
 
+type tracingIncomeValidator struct {
+	delegate IncomeValidator
+}
+
+// NewTracingIncomeValidator returns income validator which reports
+// "income_validation" span as a child of the call span.
+func NewTracingIncomeValidator(delegate IncomeValidator) (validator IncomeValidator) {
+	return &tracingIncomeValidator{delegate: delegate}
+}
+
+func (validator *tracingIncomeValidator) Validate(data *IncomeData) (err error) {
+	_, span := tracing.StartSpan(spanContext(data.GrpcContext), "income_validation")
+	span.SetAttribute("income", data.Income.String())
+	defer span.End()
+
+	err = validator.delegate.Validate(data)
+	span.SetError(err)
 	return
 }
+
+func (validator *tracingIncomeValidator) describe() ValidatorInfo {
+	return DescribeValidator(validator.delegate)
+}
+
+// spanContext returns context of the call which contains span of the call,
+// spans started using it become children of the call span.
+func spanContext(grpcContext *handler.GrpcStreamContext) context.Context {
+	ctx := context.Background()
+	if grpcContext != nil {
+		if grpcContext.Context != nil {
+			ctx = grpcContext.Context
+		}
+		ctx = tracing.ContextWithSpan(ctx, grpcContext.Span)
+	}
+	return ctx
+}
+
+type metricsIncomeValidator struct {
+	delegate IncomeValidator
+	recorder metrics.Recorder
+}
+
+// NewMetricsIncomeValidator returns income validator which counts accepted
+// and rejected calls and total accepted income. Rejections are also counted
+// by failure reason when it is known.
+func NewMetricsIncomeValidator(delegate IncomeValidator, recorder metrics.Recorder) (validator IncomeValidator) {
+	return &metricsIncomeValidator{delegate: delegate, recorder: recorder}
+}
+
+func (validator *metricsIncomeValidator) Validate(data *IncomeData) (err error) {
+	err = validator.delegate.Validate(data)
+	if err != nil {
+		validator.recorder.Count("income_validation.rejected", 1)
+		if paymentErr, ok := err.(*PaymentError); ok && paymentErr.Reason != "" {
+			validator.recorder.Count("income_validation.rejected."+paymentErr.Reason, 1)
+		}
+		return
+	}
+
+	validator.recorder.Count("income_validation.accepted", 1)
+	validator.recorder.Count("income", data.Income.Int64())
+	return nil
+}
+
+func (validator *metricsIncomeValidator) describe() ValidatorInfo {
+	return DescribeValidator(validator.delegate)
+}
+
+type observingIncomeValidator struct {
+	delegate IncomeValidator
+	logger   *log.Logger
+}
+
+// NewObservingIncomeValidator returns income validator which never rejects
+// calls. It logs calls which would be rejected by delegate validator. It is
+// used to try new pricing on real traffic.
+func NewObservingIncomeValidator(delegate IncomeValidator) (validator IncomeValidator) {
+	return &observingIncomeValidator{delegate: delegate, logger: log.StandardLogger()}
+}
+
+func (validator *observingIncomeValidator) Validate(data *IncomeData) (err error) {
+	e := validator.delegate.Validate(data)
+	if e == nil {
+		return nil
+	}
+
+	fields := log.Fields{"income": data.Income, "method": data.method()}
+	if paymentErr, ok := e.(*PaymentError); ok {
+		for key, value := range paymentErr.Details {
+			fields[key] = value
+		}
+	}
+	validator.logger.WithError(e).WithFields(fields).Warn("Call would be rejected by income validator, allowed in observe mode")
+
+	return nil
+}
+
+func (validator *observingIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:     "observe",
+		Children: []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
@@ -1,11 +1,20 @@
 package escrow
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"testing"
+	"time"
 
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/tracing"
 )
 
 type incomeValidatorMockType struct {
@@ -16,6 +25,28 @@ func (incomeValidator *incomeValidatorMockType) Validate(income *IncomeData) (er
 	return incomeValidator.err
 }
 
+type slowIncomeValidatorMock struct {
+	delay time.Duration
+}
+
+// Validate waits for delay like a slow storage request which is cancelled
+// when income context is done
+func (validator *slowIncomeValidatorMock) Validate(income *IncomeData) (err error) {
+	select {
+	case <-time.After(validator.delay):
+		return nil
+	case <-income.context().Done():
+		return income.context().Err()
+	}
+}
+
+func incomeMismatchError(income, price *big.Int) *PaymentError {
+	err := NewPaymentError(Unauthenticated, "income %s does not equal to price %s", income, price)
+	err.Details = map[string]interface{}{"expected": price, "received": income}
+	err.Reason = config.ValidationFailurePriceMismatch
+	return err
+}
+
 func TestIncomeValidate(t *testing.T) {
 	one := big.NewInt(1)
 	income := big.NewInt(0)
@@ -25,7 +56,8 @@ func TestIncomeValidate(t *testing.T) {
 	income.Sub(price, one)
 	err := incomeValidator.Validate(&IncomeData{Income: income})
 	msg := fmt.Sprintf("income %s does not equal to price %s", income, price)
-	assert.Equal(t, NewPaymentError(Unauthenticated, msg), err)
+	assert.Equal(t, NewPaymentError(Unauthenticated, msg).Message, err.(*PaymentError).Message)
+	assert.Equal(t, incomeMismatchError(income, price), err)
 
 	income.Set(price)
 	err = incomeValidator.Validate(&IncomeData{Income: income})
@@ -34,5 +66,362 @@ func TestIncomeValidate(t *testing.T) {
 	income.Add(price, one)
 	err = incomeValidator.Validate(&IncomeData{Income: income})
 	msg = fmt.Sprintf("income %s does not equal to price %s", income, price)
-	assert.Equal(t, NewPaymentError(Unauthenticated, msg), err)
+	assert.Equal(t, NewPaymentError(Unauthenticated, msg).Message, err.(*PaymentError).Message)
+	assert.Equal(t, incomeMismatchError(income, price), err)
+}
+
+func TestServiceIncomeValidate(t *testing.T) {
+	incomeValidator := NewServiceIncomeValidator(handler.DefaultServiceKeyHeaders, map[handler.ServiceKey]IncomeValidator{
+		{OrganizationID: "org", ServiceID: "service-a"}: NewIncomeValidator(big.NewInt(10)),
+		{OrganizationID: "org", ServiceID: "service-b"}: NewIncomeValidator(big.NewInt(20)),
+	})
+	context := func(serviceID string) *handler.GrpcStreamContext {
+		return &handler.GrpcStreamContext{MD: metadata.Pairs(
+			handler.OrganizationIDHeader, "org",
+			handler.ServiceIDHeader, serviceID)}
+	}
+
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context("service-a")}))
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(20), GrpcContext: context("service-b")}))
+	assert.Equal(t, incomeMismatchError(big.NewInt(10), big.NewInt(20)),
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context("service-b")}))
+	assert.Equal(t, NewPaymentError(InvalidArgument, "service \"org/service-c\" is not served"),
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context("service-c")}))
+}
+
+func TestServiceIncomeValidateCustomHeaders(t *testing.T) {
+	incomeValidator := NewServiceIncomeValidator(handler.ServiceKeyHeaders{OrganizationID: "x-org", ServiceID: "x-service"},
+		map[handler.ServiceKey]IncomeValidator{
+			{OrganizationID: "org", ServiceID: "service-a"}: NewIncomeValidator(big.NewInt(10)),
+		})
+	context := &handler.GrpcStreamContext{MD: metadata.Pairs("x-org", "org", "x-service", "service-a")}
+
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context}))
+	assert.Equal(t, NewPaymentError(InvalidArgument, "missing \"x-org\""),
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: &handler.GrpcStreamContext{
+			MD: metadata.Pairs(handler.OrganizationIDHeader, "org", handler.ServiceIDHeader, "service-a")}}))
+}
+
+func TestTimeoutIncomeValidate(t *testing.T) {
+	incomeValidator := NewTimeoutIncomeValidator(&slowIncomeValidatorMock{delay: time.Millisecond}, time.Second)
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(0)})
+
+	assert.Nil(t, err)
+}
+
+func TestTimeoutIncomeValidateDeadlineExceeded(t *testing.T) {
+	incomeValidator := NewTimeoutIncomeValidator(&slowIncomeValidatorMock{delay: time.Second}, 10*time.Millisecond)
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(0)})
+
+	assert.Equal(t, NewPaymentError(DeadlineExceeded, "income validation timed out after 10ms"), err)
+}
+
+func TestTimeoutIncomeValidateCallCancelled(t *testing.T) {
+	incomeValidator := NewTimeoutIncomeValidator(&slowIncomeValidatorMock{delay: time.Second}, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(0), Context: ctx})
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTimeoutIncomeValidateCancelsStorageRequests(t *testing.T) {
+	incomeValidator := NewTimeoutIncomeValidator(NewSpendCapIncomeValidator(&incomeValidatorMockType{},
+		NewAtomicCounterStore(&hangingAtomicStorage{}), big.NewInt(10), time.Hour), 10*time.Millisecond)
+
+	err := incomeValidator.Validate(spendFrom(testSpendSender, 1))
+
+	assert.Equal(t, NewPaymentError(DeadlineExceeded, "income validation timed out after 10ms"), err)
+}
+
+// hangingAtomicStorage doesn't respond until request context is done
+type hangingAtomicStorage struct {
+	AtomicStorage
+}
+
+func (storage *hangingAtomicStorage) GetContext(ctx context.Context, key string) (value string, ok bool, err error) {
+	<-ctx.Done()
+	return "", false, ctx.Err()
+}
+
+func (storage *hangingAtomicStorage) PutIfAbsentContext(ctx context.Context, key string, value string) (ok bool, err error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func (storage *hangingAtomicStorage) CompareAndSwapContext(ctx context.Context, key string, prevValue string, newValue string) (ok bool, err error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func TestTimeoutIncomeValidateReturnsDelegateError(t *testing.T) {
+	delegateErr := NewPaymentError(Unauthenticated, "income is incorrect")
+	incomeValidator := NewTimeoutIncomeValidator(&incomeValidatorMockType{err: delegateErr}, time.Second)
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(0)})
+
+	assert.Equal(t, delegateErr, err)
+}
+
+func TestMinIncomeValidateAtFloor(t *testing.T) {
+	incomeValidator := NewMinIncomeValidator(big.NewInt(10))
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(10)})
+
+	assert.Nil(t, err)
+}
+
+func TestMinIncomeValidateBelowFloor(t *testing.T) {
+	incomeValidator := NewMinIncomeValidator(big.NewInt(10))
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(9)})
+
+	expectedErr := NewPaymentError(InvalidArgument, "income 9 is less than minimal income 10")
+	expectedErr.Details = map[string]interface{}{"min_income": big.NewInt(10), "expected": big.NewInt(10), "received": big.NewInt(9)}
+	expectedErr.Reason = config.ValidationFailureMinIncome
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestMinIncomeValidateIsIndependentOfPrice(t *testing.T) {
+	incomeValidator := NewCompositeIncomeValidator(NewMinIncomeValidator(big.NewInt(10)), NewIncomeValidator(big.NewInt(5)))
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(5)})
+
+	assert.Equal(t, InvalidArgument, err.(*PaymentError).Code)
+}
+
+type countingIncomeValidatorMock struct {
+	err   error
+	calls int
+}
+
+func (validator *countingIncomeValidatorMock) Validate(income *IncomeData) (err error) {
+	validator.calls++
+	return validator.err
+}
+
+func TestCompositeIncomeValidatorCallsAllValidators(t *testing.T) {
+	first, second := &countingIncomeValidatorMock{}, &countingIncomeValidatorMock{}
+	incomeValidator := NewCompositeIncomeValidator(first, second)
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(11)})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 1, second.calls)
+}
+
+func TestCompositeIncomeValidatorReturnsDelegateError(t *testing.T) {
+	delegateErr := NewPaymentError(Unauthenticated, "income is incorrect")
+	incomeValidator := NewCompositeIncomeValidator(NewMinIncomeValidator(big.NewInt(10)), &incomeValidatorMockType{err: delegateErr})
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(11)})
+
+	assert.Equal(t, delegateErr, err)
+}
+
+func TestCompositeIncomeValidatorCheapRejectSkipsExpensiveValidator(t *testing.T) {
+	expensive := &countingIncomeValidatorMock{}
+	incomeValidator := NewCompositeIncomeValidator(NewMinIncomeValidator(big.NewInt(10)), expensive)
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(1)})
+
+	assert.Equal(t, InvalidArgument, err.(*PaymentError).Code)
+	assert.Equal(t, 0, expensive.calls)
+}
+
+func TestCompositeIncomeValidatorOrder(t *testing.T) {
+	expensive := &countingIncomeValidatorMock{err: NewPaymentError(Unauthenticated, "income is incorrect")}
+	incomeValidator := NewCompositeIncomeValidator(expensive, NewMinIncomeValidator(big.NewInt(10)))
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(1)})
+
+	assert.Equal(t, Unauthenticated, err.(*PaymentError).Code)
+	assert.Equal(t, 1, expensive.calls)
+}
+
+func TestDescribeCompositeIncomeValidator(t *testing.T) {
+	incomeValidator := NewCompositeIncomeValidator(NewMinIncomeValidator(big.NewInt(10)), NewIncomeValidator(big.NewInt(20)))
+
+	info := DescribeValidator(incomeValidator)
+
+	assert.Equal(t, ValidatorInfo{
+		Type: "composite",
+		Children: []ValidatorInfo{
+			{Type: "min_income", Parameters: map[string]string{"min_income": "10"}},
+			{Type: "price", Parameters: map[string]string{"price_provider": "fixed(20)"}},
+		},
+	}, info)
+}
+
+func TestJobValueIncomeValidate(t *testing.T) {
+	incomeValidator := NewJobValueIncomeValidator(NewFixedPriceProvider(big.NewInt(10)), "x-units")
+	context := func(pairs ...string) *handler.GrpcStreamContext {
+		return &handler.GrpcStreamContext{MD: metadata.Pairs(pairs...)}
+	}
+
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(30), GrpcContext: context("x-units", "3")}))
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context()}))
+	assert.Equal(t, incomeMismatchError(big.NewInt(10), big.NewInt(30)),
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context("x-units", "3")}))
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context("snet-job-value", "3")}),
+		"default header is used instead of configured one")
+	assert.Equal(t, NewPaymentError(InvalidArgument, "incorrect job value 0, positive value is expected"),
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(0), GrpcContext: context("x-units", "0")}))
+	assert.Equal(t, InvalidArgument,
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context("x-units", "abc")}).(*PaymentError).Code)
+}
+
+func TestGetJobValueHeader(t *testing.T) {
+	defer config.WithDefaultConfig()()
+	assert.Equal(t, "snet-job-value", GetJobValueHeader())
+
+	config.Vip().Set(config.JobValueHeaderKey, "X-Units")
+	assert.Equal(t, "x-units", GetJobValueHeader())
+}
+
+func TestDescribeValidator(t *testing.T) {
+	incomeValidator := NewTimeoutIncomeValidator(NewServiceIncomeValidator(handler.DefaultServiceKeyHeaders, map[handler.ServiceKey]IncomeValidator{
+		{OrganizationID: "org", ServiceID: "service-b"}: NewIncomeValidator(big.NewInt(20)),
+		{OrganizationID: "org", ServiceID: "service-a"}: NewPriceIncomeValidator(
+			NewCachingPriceProvider(NewFixedPriceProvider(big.NewInt(10)), time.Minute, 0)),
+	}), time.Second)
+
+	info := DescribeValidator(incomeValidator)
+
+	assert.Equal(t, ValidatorInfo{
+		Type:       "timeout",
+		Parameters: map[string]string{"timeout": "1s"},
+		Children: []ValidatorInfo{
+			{
+				Type:       "service",
+				Parameters: map[string]string{"organization_id_header": "snet-organization-id", "service_id_header": "snet-service-id"},
+				Children: []ValidatorInfo{
+					{Type: "price", Parameters: map[string]string{"service": "org/service-a", "price_provider": "cached(fixed(10), ttl: 1m0s)"}},
+					{Type: "price", Parameters: map[string]string{"service": "org/service-b", "price_provider": "fixed(20)"}},
+				},
+			},
+		},
+	}, info)
+}
+
+func TestDescribeUnknownValidator(t *testing.T) {
+	info := DescribeValidator(&incomeValidatorMockType{})
+
+	assert.Equal(t, ValidatorInfo{Type: "*escrow.incomeValidatorMockType"}, info)
+}
+
+func TestObservingIncomeValidateLetsRejectedCallThrough(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	incomeValidator := NewObservingIncomeValidator(NewIncomeValidator(big.NewInt(10))).(*observingIncomeValidator)
+	incomeValidator.logger = logger
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(9)})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(hook.Entries))
+	entry := hook.LastEntry()
+	assert.Equal(t, log.WarnLevel, entry.Level)
+	assert.Equal(t, "Call would be rejected by income validator, allowed in observe mode", entry.Message)
+	assert.Equal(t, incomeMismatchError(big.NewInt(9), big.NewInt(10)), entry.Data[log.ErrorKey])
+	assert.Equal(t, big.NewInt(10), entry.Data["expected"])
+	assert.Equal(t, big.NewInt(9), entry.Data["received"])
+}
+
+func TestObservingIncomeValidateDoesNotLogAcceptedCall(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	incomeValidator := NewObservingIncomeValidator(NewIncomeValidator(big.NewInt(10))).(*observingIncomeValidator)
+	incomeValidator.logger = logger
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(10)})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(hook.Entries))
+}
+
+func TestFallbackIncomeValidateBackendError(t *testing.T) {
+	primary := &incomeValidatorMockType{err: NewPaymentError(Internal, "cannot determine price of the call")}
+	validator := NewFallbackIncomeValidator(primary, NewIncomeValidator(big.NewInt(10)))
+
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(10)}))
+	assert.Equal(t, incomeMismatchError(big.NewInt(5), big.NewInt(10)), validator.Validate(&IncomeData{Income: big.NewInt(5)}))
+}
+
+func TestFallbackIncomeValidateTimeout(t *testing.T) {
+	primary := NewTimeoutIncomeValidator(&slowIncomeValidatorMock{delay: time.Second}, 10*time.Millisecond)
+	validator := NewFallbackIncomeValidator(primary, NewIncomeValidator(big.NewInt(10)))
+
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(10)}))
+}
+
+func TestFallbackIncomeValidateUntypedError(t *testing.T) {
+	primary := &incomeValidatorMockType{err: fmt.Errorf("etcd is unavailable")}
+	validator := NewFallbackIncomeValidator(primary, NewIncomeValidator(big.NewInt(10)))
+
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(10)}))
+}
+
+func TestFallbackIncomeValidateGenuineRejection(t *testing.T) {
+	rejection := incomeMismatchError(big.NewInt(10), big.NewInt(20))
+	primary := &incomeValidatorMockType{err: rejection}
+	fallback := &incomeValidatorMockType{err: fmt.Errorf("fallback must not be called")}
+	validator := NewFallbackIncomeValidator(primary, fallback)
+
+	assert.Equal(t, rejection, validator.Validate(&IncomeData{Income: big.NewInt(10)}))
+}
+
+func TestFallbackIncomeValidatePrimaryAccepts(t *testing.T) {
+	fallback := &incomeValidatorMockType{err: fmt.Errorf("fallback must not be called")}
+	validator := NewFallbackIncomeValidator(&incomeValidatorMockType{}, fallback)
+
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(10)}))
+}
+
+func TestTracingIncomeValidator(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(exporter)
+	tracing.SetGlobalTracer(tracer)
+	defer tracing.SetGlobalTracer(nil)
+	_, callSpan := tracer.Start(context.Background(), "request")
+	delegateErr := NewPaymentError(Unauthenticated, "income is incorrect")
+	incomeValidator := NewTracingIncomeValidator(&incomeValidatorMockType{err: delegateErr})
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: &handler.GrpcStreamContext{Span: callSpan}})
+
+	assert.Equal(t, delegateErr, err)
+	assert.Equal(t, []string{"income_validation"}, exporter.SpanNames())
+	span := exporter.Spans()[0]
+	assert.Equal(t, callSpan.SpanContext.SpanID, span.ParentSpanID)
+	assert.Equal(t, callSpan.SpanContext.TraceID, span.SpanContext.TraceID)
+	assert.Equal(t, delegateErr, span.Err)
+}
+
+type recorderMock struct {
+	counters map[string]int64
+}
+
+func (recorder *recorderMock) Count(name string, value int64) {
+	recorder.counters[name] += value
+}
+
+func TestMetricsIncomeValidator(t *testing.T) {
+	recorder := &recorderMock{counters: make(map[string]int64)}
+	delegate := &incomeValidatorMockType{}
+	incomeValidator := NewMetricsIncomeValidator(delegate, recorder)
+
+	incomeValidator.Validate(&IncomeData{Income: big.NewInt(10)})
+	incomeValidator.Validate(&IncomeData{Income: big.NewInt(15)})
+	delegate.err = incomeMismatchError(big.NewInt(5), big.NewInt(10))
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(5)})
+
+	assert.Equal(t, delegate.err, err)
+	assert.Equal(t, map[string]int64{
+		"income_validation.accepted":                2,
+		"income":                                    25,
+		"income_validation.rejected":                1,
+		"income_validation.rejected.price_mismatch": 1,
+	}, recorder.counters)
 }
@@ -0,0 +1,128 @@
+package escrow
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/handler"
+)
+
+// Invoice is issued by the service and contains amount which should be paid
+// by the call passing invoice id in metadata.
+type Invoice struct {
+	ID     string   `json:"id"`
+	Amount *big.Int `json:"amount"`
+}
+
+// InvoiceStorage keeps invoices by id. Invoices are serialized as JSON to
+// allow services to put them into the storage directly.
+type InvoiceStorage struct {
+	delegate TypedAtomicStorage
+}
+
+// NewInvoiceStorage returns new instance of InvoiceStorage.
+func NewInvoiceStorage(atomicStorage AtomicStorage) *InvoiceStorage {
+	return &InvoiceStorage{
+		delegate: &TypedAtomicStorageImpl{
+			atomicStorage: &PrefixedAtomicStorage{
+				delegate:  atomicStorage,
+				keyPrefix: "/invoice/storage",
+			},
+			keySerializer:     serializeInvoiceID,
+			valueSerializer:   serializeJson,
+			valueDeserializer: deserializeJson,
+			valueType:         reflect.TypeOf(Invoice{}),
+		},
+	}
+}
+
+func serializeInvoiceID(key interface{}) (serialized string, err error) {
+	return key.(string), nil
+}
+
+func serializeJson(value interface{}) (serialized string, err error) {
+	bytes, err := json.Marshal(value)
+	return string(bytes), err
+}
+
+func deserializeJson(serialized string, value interface{}) (err error) {
+	return json.Unmarshal([]byte(serialized), value)
+}
+
+// Get returns invoice by id.
+func (storage *InvoiceStorage) Get(id string) (invoice *Invoice, ok bool, err error) {
+	return storage.GetContext(context.Background(), id)
+}
+
+// GetContext returns invoice by id, request is cancelled when ctx is done.
+func (storage *InvoiceStorage) GetContext(ctx context.Context, id string) (invoice *Invoice, ok bool, err error) {
+	value, ok, err := storage.delegate.GetContext(ctx, id)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return value.(*Invoice), true, nil
+}
+
+// Put saves invoice replacing previous invoice with the same id.
+func (storage *InvoiceStorage) Put(invoice *Invoice) (err error) {
+	return storage.delegate.Put(invoice.ID, invoice)
+}
+
+// GetInvoiceIDHeader returns name of the header which contains invoice id
+// or empty string if invoices are disabled.
+func GetInvoiceIDHeader() string {
+	return strings.ToLower(config.GetString(config.InvoiceIDHeaderKey))
+}
+
+type invoiceIncomeValidator struct {
+	delegate IncomeValidator
+	header   string
+	storage  *InvoiceStorage
+}
+
+// NewInvoiceIncomeValidator returns income validator which checks that
+// income of the call passing invoice id in the header is equal to the amount
+// of the invoice. Calls without invoice id are validated by delegate.
+func NewInvoiceIncomeValidator(delegate IncomeValidator, header string, storage *InvoiceStorage) (validator IncomeValidator) {
+	return &invoiceIncomeValidator{delegate: delegate, header: header, storage: storage}
+}
+
+func (validator *invoiceIncomeValidator) Validate(data *IncomeData) (err error) {
+	if validator.header == "" || data.GrpcContext == nil || len(data.GrpcContext.MD.Get(validator.header)) == 0 {
+		return validator.delegate.Validate(data)
+	}
+
+	id, e := handler.GetSingleValue(data.GrpcContext.MD, validator.header)
+	if e != nil {
+		return NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+	}
+
+	invoice, ok, err := validator.storage.GetContext(data.context(), id)
+	if err != nil {
+		return NewPaymentError(Internal, "cannot get invoice from storage")
+	}
+	if !ok {
+		return NewPaymentError(InvalidArgument, "invoice \"%v\" is not found", id)
+	}
+
+	if data.Income.Cmp(invoice.Amount) != 0 {
+		e := NewPaymentError(Unauthenticated, "income %d does not equal to invoice %v amount %d", data.Income, id, invoice.Amount)
+		e.Details = map[string]interface{}{"expected": invoice.Amount, "received": data.Income}
+		e.Reason = config.ValidationFailurePriceMismatch
+		return e
+	}
+
+	return
+}
+
+func (validator *invoiceIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:       "invoice",
+		Parameters: map[string]string{"invoice_id_header": validator.header},
+		Children:   []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
@@ -0,0 +1,70 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func newInvoiceIncomeValidatorTest(t *testing.T, header string) (validator IncomeValidator, storage *InvoiceStorage) {
+	storage = NewInvoiceStorage(NewMemStorage())
+	assert.Nil(t, storage.Put(&Invoice{ID: "42", Amount: big.NewInt(100)}))
+	return NewInvoiceIncomeValidator(NewIncomeValidator(big.NewInt(10)), header, storage), storage
+}
+
+func invoiceContext(pairs ...string) *handler.GrpcStreamContext {
+	return &handler.GrpcStreamContext{MD: metadata.Pairs(pairs...)}
+}
+
+func TestInvoiceIncomeValidate(t *testing.T) {
+	validator, _ := newInvoiceIncomeValidatorTest(t, "x-invoice")
+
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(100), GrpcContext: invoiceContext("x-invoice", "42")}))
+
+	err := validator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: invoiceContext("x-invoice", "42")})
+	expected := NewPaymentError(Unauthenticated, "income 10 does not equal to invoice 42 amount 100")
+	expected.Details = map[string]interface{}{"expected": big.NewInt(100), "received": big.NewInt(10)}
+	expected.Reason = config.ValidationFailurePriceMismatch
+	assert.Equal(t, expected, err)
+}
+
+func TestInvoiceIncomeValidateInvoiceNotFound(t *testing.T) {
+	validator, _ := newInvoiceIncomeValidatorTest(t, "x-invoice")
+
+	err := validator.Validate(&IncomeData{Income: big.NewInt(100), GrpcContext: invoiceContext("x-invoice", "43")})
+
+	assert.Equal(t, NewPaymentError(InvalidArgument, "invoice \"43\" is not found"), err)
+}
+
+func TestInvoiceIncomeValidateWithoutInvoice(t *testing.T) {
+	validator, _ := newInvoiceIncomeValidatorTest(t, "x-invoice")
+
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: invoiceContext()}))
+	assert.Nil(t, validator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: invoiceContext("snet-invoice-id", "42")}),
+		"default header is used instead of configured one")
+	assert.NotNil(t, validator.Validate(&IncomeData{Income: big.NewInt(100), GrpcContext: invoiceContext()}))
+}
+
+func TestInvoiceStorageReadsJson(t *testing.T) {
+	atomicStorage := NewMemStorage()
+	assert.Nil(t, atomicStorage.Put("/invoice/storage/42", `{"id": "42", "amount": 100}`))
+
+	invoice, ok, err := NewInvoiceStorage(atomicStorage).Get("42")
+
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &Invoice{ID: "42", Amount: big.NewInt(100)}, invoice)
+}
+
+func TestGetInvoiceIDHeader(t *testing.T) {
+	defer config.WithDefaultConfig()()
+	assert.Equal(t, "snet-invoice-id", GetInvoiceIDHeader())
+
+	config.Vip().Set(config.InvoiceIDHeaderKey, "X-Invoice")
+	assert.Equal(t, "x-invoice", GetInvoiceIDHeader())
+}
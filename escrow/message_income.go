@@ -0,0 +1,78 @@
+package escrow
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// MessageIncomeValidator validates income of the client streaming call after
+// each message received from client. It allows charging streaming calls by
+// the number of messages sent by client.
+type MessageIncomeValidator interface {
+	// ValidateMessage returns nil if income covers messageCount messages or
+	// correct PaymentError status to be sent to client otherwise.
+	ValidateMessage(data *IncomeData, messageCount int) (err error)
+}
+
+type messageIncomeValidator struct {
+	delegate IncomeValidator
+}
+
+// NewMessageIncomeValidator returns message income validator which passes
+// income with the number of received messages to the delegate income
+// validator. It allows applying the same decorators (timeout, subscription,
+// spend cap, observe mode and so on) to the streaming calls income which
+// are applied to the income of other calls.
+func NewMessageIncomeValidator(delegate IncomeValidator) (validator MessageIncomeValidator) {
+	return &messageIncomeValidator{delegate: delegate}
+}
+
+func (validator *messageIncomeValidator) ValidateMessage(data *IncomeData, messageCount int) (err error) {
+	message := *data
+	message.MessageCount = messageCount
+	return validator.delegate.Validate(&message)
+}
+
+func (validator *messageIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:     "message",
+		Children: []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
+
+type messagePriceIncomeValidator struct {
+	priceProvider PriceProvider
+}
+
+// NewMessagePriceIncomeValidator returns income validator which checks that
+// income is enough to pay price returned by priceProvider for each of
+// IncomeData.MessageCount messages.
+func NewMessagePriceIncomeValidator(priceProvider PriceProvider) (validator IncomeValidator) {
+	return &messagePriceIncomeValidator{priceProvider: priceProvider}
+}
+
+func (validator *messagePriceIncomeValidator) Validate(data *IncomeData) (err error) {
+	price, err := validator.priceProvider.GetPriceInCogs(data.method())
+	if err != nil {
+		return NewPaymentError(Internal, "cannot determine price of the call")
+	}
+
+	expected := new(big.Int).Mul(price, big.NewInt(int64(data.MessageCount)))
+	if data.Income.Cmp(expected) < 0 {
+		e := NewPaymentError(FailedPrecondition, "income %d does not cover %d messages, price of message %d", data.Income, data.MessageCount, price)
+		e.Details = map[string]interface{}{"expected": expected, "received": data.Income}
+		e.Reason = config.ValidationFailureMessagePrice
+		return e
+	}
+
+	return nil
+}
+
+func (validator *messagePriceIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:       "message_price",
+		Parameters: map[string]string{"price_provider": fmt.Sprintf("%v", validator.priceProvider)},
+	}
+}
@@ -0,0 +1,119 @@
+package escrow
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func newTestMessageIncomeValidator(price int64) MessageIncomeValidator {
+	return NewMessageIncomeValidator(NewMessagePriceIncomeValidator(NewFixedPriceProvider(big.NewInt(price))))
+}
+
+func TestMessageIncomeValidateIncrementalIncome(t *testing.T) {
+	validator := newTestMessageIncomeValidator(10)
+
+	for messageCount := 1; messageCount <= 3; messageCount++ {
+		income := big.NewInt(int64(10 * messageCount))
+		assert.Nil(t, validator.ValidateMessage(&IncomeData{Income: income}, messageCount))
+	}
+}
+
+func TestMessageIncomeValidatePaymentStalls(t *testing.T) {
+	validator := newTestMessageIncomeValidator(10)
+
+	assert.Nil(t, validator.ValidateMessage(&IncomeData{Income: big.NewInt(20)}, 2))
+
+	err := validator.ValidateMessage(&IncomeData{Income: big.NewInt(20)}, 3)
+
+	expected := NewPaymentError(FailedPrecondition, "income 20 does not cover 3 messages, price of message 10")
+	expected.Details = map[string]interface{}{"expected": big.NewInt(30), "received": big.NewInt(20)}
+	expected.Reason = config.ValidationFailureMessagePrice
+	assert.Equal(t, expected, err)
+}
+
+func TestMessageIncomeValidateDoesNotChangeIncomeData(t *testing.T) {
+	validator := newTestMessageIncomeValidator(10)
+	data := &IncomeData{Income: big.NewInt(20)}
+
+	validator.ValidateMessage(data, 2)
+
+	assert.Equal(t, 0, data.MessageCount)
+}
+
+func TestServiceMessageIncomeValidate(t *testing.T) {
+	validator := NewMessageIncomeValidator(NewServiceIncomeValidator(handler.DefaultServiceKeyHeaders, map[handler.ServiceKey]IncomeValidator{
+		{OrganizationID: "org", ServiceID: "service-a"}: NewMessagePriceIncomeValidator(NewFixedPriceProvider(big.NewInt(10))),
+	}))
+	context := func(serviceID string) *handler.GrpcStreamContext {
+		return &handler.GrpcStreamContext{MD: metadata.Pairs(
+			handler.OrganizationIDHeader, "org",
+			handler.ServiceIDHeader, serviceID)}
+	}
+
+	assert.Nil(t, validator.ValidateMessage(&IncomeData{Income: big.NewInt(10), GrpcContext: context("service-a")}, 1))
+	assert.Equal(t, NewPaymentError(InvalidArgument, "service \"org/service-b\" is not served"),
+		validator.ValidateMessage(&IncomeData{Income: big.NewInt(10), GrpcContext: context("service-b")}, 1))
+}
+
+func TestObservingMessageIncomeValidateLetsRejectedMessageThrough(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	observing := NewObservingIncomeValidator(NewMessagePriceIncomeValidator(NewFixedPriceProvider(big.NewInt(10)))).(*observingIncomeValidator)
+	observing.logger = logger
+	validator := NewMessageIncomeValidator(observing)
+
+	assert.Nil(t, validator.ValidateMessage(&IncomeData{Income: big.NewInt(20)}, 2))
+	assert.Equal(t, 0, len(hook.Entries))
+
+	err := validator.ValidateMessage(&IncomeData{Income: big.NewInt(20)}, 3)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(hook.Entries))
+	entry := hook.LastEntry()
+	assert.Equal(t, log.WarnLevel, entry.Level)
+	assert.Equal(t, "Call would be rejected by income validator, allowed in observe mode", entry.Message)
+	assert.Equal(t, big.NewInt(30), entry.Data["expected"])
+	assert.Equal(t, big.NewInt(20), entry.Data["received"])
+}
+
+func TestMessageIncomeValidateSubscription(t *testing.T) {
+	now := testSubscriptionNow
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(now.Add(time.Hour)))
+	subscription := NewSubscriptionIncomeValidator(NewMessagePriceIncomeValidator(NewFixedPriceProvider(big.NewInt(10))),
+		storage, nil, testSubscribedService).(*subscriptionIncomeValidator)
+	subscription.now = func() time.Time { return now }
+	validator := NewMessageIncomeValidator(subscription)
+
+	assert.Nil(t, validator.ValidateMessage(spendFrom(testSubscriber, 0), 3))
+	assert.NotNil(t, validator.ValidateMessage(spendFrom(testSpendSender, 0), 3))
+}
+
+func TestMessageIncomeValidateTimeout(t *testing.T) {
+	validator := NewMessageIncomeValidator(NewTimeoutIncomeValidator(&slowIncomeValidatorMock{delay: time.Second}, 10*time.Millisecond))
+
+	err := validator.ValidateMessage(&IncomeData{Income: big.NewInt(10)}, 1)
+
+	assert.Equal(t, DeadlineExceeded, err.(*PaymentError).Code)
+}
+
+func TestMessageIncomeValidateSpendCapCountsStreamOnce(t *testing.T) {
+	now := testSpendCapNow
+	validator := NewMessageIncomeValidator(newTestSpendCapIncomeValidator(
+		NewMessagePriceIncomeValidator(NewFixedPriceProvider(big.NewInt(4))), 10, &now))
+
+	for messageCount := 1; messageCount <= 2; messageCount++ {
+		assert.Nil(t, validator.ValidateMessage(spendFrom(testSpendSender, 8), messageCount))
+	}
+
+	err := validator.ValidateMessage(spendFrom(testSpendSender, 8), 1)
+	assert.Equal(t, ResourceExhausted, err.(*PaymentError).Code, "income of the first stream is counted once")
+}
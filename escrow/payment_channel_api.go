@@ -1,12 +1,15 @@
 package escrow
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/handler"
 )
 
 // Payment contains MultiPartyEscrow payment details
@@ -93,14 +96,25 @@ type PaymentChannelData struct {
 	// service provider. This amount increments on price after each successful
 	// RPC call.
 	AuthorizedAmount *big.Int
+	// MaxAuthorizedAmount is a maximum amount authorized by sender using the
+	// current channel nonce. Payments with smaller amount are rejected even
+	// if they are greater than AuthorizedAmount, nil means zero.
+	MaxAuthorizedAmount *big.Int
 	// Signature is a signature of last message containing Authorized amount.
 	// It is required to claim tokens from channel.
 	Signature []byte
+	// UnclaimedSince is a time of the first payment received after the last
+	// claim, it is zero if there are no unclaimed payments.
+	UnclaimedSince time.Time
+	// Service is a key of the service which channel is used for. Channel is
+	// bound to the service by the first payment when daemon serves few
+	// services, it is empty otherwise.
+	Service handler.ServiceKey
 }
 
 func (data *PaymentChannelData) String() string {
-	return fmt.Sprintf("{ChannelID: %v, Nonce: %v, State: %v, Sender: %v, Recipient: %v, GroupId: %v, FullAmount: %v, Expiration: %v, Signer: %v, AuthorizedAmount: %v, Signature: %v",
-		data.ChannelID, data.Nonce, data.State, blockchain.AddressToHex(&data.Sender), blockchain.AddressToHex(&data.Recipient), data.GroupID, data.FullAmount, data.Expiration, data.Signer, data.AuthorizedAmount, blockchain.BytesToBase64(data.Signature))
+	return fmt.Sprintf("{ChannelID: %v, Nonce: %v, State: %v, Sender: %v, Recipient: %v, GroupId: %v, FullAmount: %v, Expiration: %v, Signer: %v, AuthorizedAmount: %v, MaxAuthorizedAmount: %v, Signature: %v",
+		data.ChannelID, data.Nonce, data.State, blockchain.AddressToHex(&data.Sender), blockchain.AddressToHex(&data.Recipient), data.GroupID, data.FullAmount, data.Expiration, data.Signer, data.AuthorizedAmount, data.MaxAuthorizedAmount, blockchain.BytesToBase64(data.Signature))
 }
 
 // PaymentChannelService interface is API for payment channel functionality.
@@ -118,9 +132,13 @@ type PaymentChannelService interface {
 	StartClaim(key *PaymentChannelKey, update ChannelUpdate) (claim Claim, err error)
 	// ListClaims returns list of payment claims in progress
 	ListClaims() (claim []Claim, err error)
+	// ListFailedClaims returns payments which claims are marked as failed by
+	// Claim.Fail()
+	ListFailedClaims() (payments []*Payment, err error)
 
-	// StartPaymentTransaction validates payment and starts payment transaction
-	StartPaymentTransaction(payment *Payment) (transaction PaymentTransaction, err error)
+	// StartPaymentTransaction validates payment and starts payment
+	// transaction, ctx is passed to the blockchain calls
+	StartPaymentTransaction(ctx context.Context, payment *Payment) (transaction PaymentTransaction, err error)
 }
 
 // PaymentErrorCode contains all types of errors which we need to handle on the
@@ -139,6 +157,19 @@ const (
 	FailedPrecondition PaymentErrorCode = 3
 	// IncorrectNonce is returned when nonce value sent by client is incorrect.
 	IncorrectNonce PaymentErrorCode = 4
+	// InvalidArgument means that client sent incorrect call parameters.
+	InvalidArgument PaymentErrorCode = 5
+	// DeadlineExceeded means that payment validation took too long.
+	DeadlineExceeded PaymentErrorCode = 6
+	// PermissionDenied means that payment cannot be used for the called
+	// service.
+	PermissionDenied PaymentErrorCode = 7
+	// ResourceExhausted means that client has spent all funds it is allowed
+	// to spend during the period.
+	ResourceExhausted PaymentErrorCode = 8
+	// Unavailable means that payment cannot be validated at the moment, for
+	// instance because daemon is behind on block sync.
+	Unavailable PaymentErrorCode = 9
 )
 
 // PaymentError contains error code and message and implements Error interface.
@@ -147,6 +178,17 @@ type PaymentError struct {
 	Code PaymentErrorCode
 	// Message is message
 	Message string
+	// Details contains additional information about error, for instance
+	// expected and received values. It is logged but not sent to client.
+	Details map[string]interface{}
+	// Reason identifies income validation failure which message can be
+	// customized by validation_error_messages, Details are used as template
+	// values.
+	Reason string
+	// Rejection is a reason of the rejection which is used as a metrics
+	// label, see metrics.RejectionReason. It is set when the reason cannot be
+	// derived from Reason.
+	Rejection string
 }
 
 // NewPaymentError constructs new PaymentError instance with given error code
@@ -155,10 +197,32 @@ func NewPaymentError(code PaymentErrorCode, format string, msg ...interface{}) *
 	return &PaymentError{Code: code, Message: fmt.Sprintf(format, msg...)}
 }
 
+// newRejectionError constructs new PaymentError instance with given
+// rejection reason, error code and message.
+func newRejectionError(rejection string, code PaymentErrorCode, format string, msg ...interface{}) *PaymentError {
+	err := NewPaymentError(code, format, msg...)
+	err.Rejection = rejection
+	return err
+}
+
 func (err *PaymentError) Error() string {
 	return err.Message
 }
 
+// IsInfrastructureError returns true if error means that payment cannot be
+// validated because daemon or its backend (storage, blockchain, remote
+// pricing) fails, and false if error is a genuine rejection of the payment.
+func IsInfrastructureError(err error) bool {
+	if err == nil {
+		return false
+	}
+	paymentErr, ok := err.(*PaymentError)
+	if !ok {
+		return true
+	}
+	return paymentErr.Code == Internal || paymentErr.Code == DeadlineExceeded || paymentErr.Code == Unavailable
+}
+
 // PaymentTransaction is a payment transaction in progress.
 type PaymentTransaction interface {
 	// Channel returns the channel which is used to apply the payment
@@ -181,6 +245,9 @@ type Claim interface {
 	// Finish to be called after blockchain transaction is finished successfully.
 	// Updates repository state.
 	Finish() error
+	// Fail to be called when payment cannot be claimed. Payment is moved to
+	// the list of failed claims and is not retried.
+	Fail() error
 }
 
 // ChannelUpdate is an type of channel update which should be applied when
@@ -193,6 +260,7 @@ var (
 	// continue working.
 	CloseChannel ChannelUpdate = func(channel *PaymentChannelData) {
 		channel.FullAmount = big.NewInt(0)
+		channel.UnclaimedSince = time.Time{}
 	}
 	// IncrementChannelNonce is an update which increments channel nonce and
 	// descreases full amount to allow channel sender continue working with
@@ -201,6 +269,8 @@ var (
 		channel.Nonce = (&big.Int{}).Add(channel.Nonce, big.NewInt(1))
 		channel.FullAmount = (&big.Int{}).Sub(channel.FullAmount, channel.AuthorizedAmount)
 		channel.AuthorizedAmount = big.NewInt(0)
+		channel.MaxAuthorizedAmount = big.NewInt(0)
 		channel.Signature = nil
+		channel.UnclaimedSince = time.Time{}
 	}
 )
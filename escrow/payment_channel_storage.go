@@ -2,6 +2,7 @@ package escrow
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
@@ -58,7 +59,13 @@ func deserialize(slice string, value interface{}) (err error) {
 
 // Get returns payment channel by key
 func (storage *PaymentChannelStorage) Get(key *PaymentChannelKey) (state *PaymentChannelData, ok bool, err error) {
-	value, ok, err := storage.delegate.Get(key)
+	return storage.GetContext(context.Background(), key)
+}
+
+// GetContext returns payment channel by key, request is cancelled when ctx
+// is done
+func (storage *PaymentChannelStorage) GetContext(ctx context.Context, key *PaymentChannelKey) (state *PaymentChannelData, ok bool, err error) {
+	value, ok, err := storage.delegate.GetContext(ctx, key)
 	if err != nil || !ok {
 		return nil, ok, err
 	}
@@ -90,50 +97,75 @@ func (storage *PaymentChannelStorage) CompareAndSwap(key *PaymentChannelKey, pre
 	return storage.delegate.CompareAndSwap(key, prevState, newState)
 }
 
-// BlockchainChannelReader reads channel state from blockchain
+// BlockchainChannelReader reads channel state from blockchain. Channel is
+// read when a payment for the channel arrives and when stored channels are
+// reconciled with blockchain, see ChannelReconciler.
 type BlockchainChannelReader struct {
-	replicaGroupID            func() ([32]byte, error)
-	readChannelFromBlockchain func(channelID *big.Int) (channel *blockchain.MultiPartyEscrowChannel, ok bool, err error)
-	recipientPaymentAddress   func() common.Address
+	readChannelFromBlockchain func(ctx context.Context, channelID *big.Int) (channel *blockchain.MultiPartyEscrowChannel, ok bool, err error)
+	recipients                func() []ChannelRecipient
 }
 
-// NewBlockchainChannelReader returns new instance of blockchain channel reader
-func NewBlockchainChannelReader(processor *blockchain.Processor, cfg *viper.Viper, metadata *blockchain.ServiceMetadata) *BlockchainChannelReader {
-	return &BlockchainChannelReader{
-		replicaGroupID: func() ([32]byte, error) {
-			s := metadata.GetDaemonGroupID()
+// ChannelRecipient is a group of replicas and payment address of the
+// service, channels opened to other recipients are not accepted.
+type ChannelRecipient struct {
+	GroupID        [32]byte
+	PaymentAddress common.Address
+}
 
-			return s, nil
-		},
+// NewBlockchainChannelReader returns new instance of blockchain channel
+// reader. Channel is accepted if it is opened to the group and payment
+// address of any of the given services metadata.
+func NewBlockchainChannelReader(processor *blockchain.Processor, cfg *viper.Viper, metadata ...*blockchain.ServiceMetadata) *BlockchainChannelReader {
+	return &BlockchainChannelReader{
 		readChannelFromBlockchain: processor.MultiPartyEscrowChannel,
-		recipientPaymentAddress: func() common.Address {
-			address := metadata.GetPaymentAddress()
-			return address
+		recipients: func() []ChannelRecipient {
+			recipients := make([]ChannelRecipient, 0, len(metadata))
+			for _, service := range metadata {
+				recipients = append(recipients, ChannelRecipient{
+					GroupID:        service.GetDaemonGroupID(),
+					PaymentAddress: service.GetPaymentAddress(),
+				})
+			}
+			return recipients
 		},
 	}
 }
 
 // GetChannelStateFromBlockchain returns channel state from Ethereum
 // blockchain. ok is false if channel was not found.
-func (reader *BlockchainChannelReader) GetChannelStateFromBlockchain(key *PaymentChannelKey) (channel *PaymentChannelData, ok bool, err error) {
-	ch, ok, err := reader.readChannelFromBlockchain(key.ID)
+func (reader *BlockchainChannelReader) GetChannelStateFromBlockchain(ctx context.Context, key *PaymentChannelKey) (channel *PaymentChannelData, ok bool, err error) {
+	ch, ok, err := reader.readChannelFromBlockchain(ctx, key.ID)
 	if err != nil || !ok {
 		return
 	}
 
-	configGroupID, err := reader.replicaGroupID()
-	if err != nil {
-		return nil, false, err
+	recipients := reader.recipients()
+	configGroupIDs := make([][32]byte, 0, len(recipients))
+	groupFound := false
+	recipientFound := false
+	for _, recipient := range recipients {
+		configGroupIDs = append(configGroupIDs, recipient.GroupID)
+		if ch.GroupId != recipient.GroupID {
+			continue
+		}
+		groupFound = true
+		if ch.Recipient == recipient.PaymentAddress {
+			recipientFound = true
+			break
+		}
 	}
-	recipientPaymentAddress := reader.recipientPaymentAddress()
 
-	if ch.GroupId != configGroupID {
+	if !groupFound {
+		var configGroupID interface{} = configGroupIDs
+		if len(configGroupIDs) == 1 {
+			configGroupID = configGroupIDs[0]
+		}
 		log.WithField("configGroupId", configGroupID).Warn("Channel received belongs to another group of replicas")
 		return nil, false, fmt.Errorf("Channel received belongs to another group of replicas, current group: %v, channel group: %v", configGroupID, ch.GroupId)
 	}
 
-	if recipientPaymentAddress != ch.Recipient {
-		log.WithField("recipientPaymentAddress", recipientPaymentAddress).
+	if !recipientFound {
+		log.WithField("ch.GroupId", ch.GroupId).
 			WithField("ch.Recipient", ch.Recipient).
 			Warn("Recipient Address from service metadata not Match on what was retrieved from Channel")
 		return nil, false, fmt.Errorf("recipient Address from service metadata does not Match on what was retrieved from Channel")
@@ -1,6 +1,7 @@
 package escrow
 
 import (
+	"context"
 	"errors"
 	"math/big"
 	"testing"
@@ -15,10 +16,10 @@ import (
 
 func NewBlockchainChannelReaderMock() *BlockchainChannelReader {
 	return &BlockchainChannelReader{
-		replicaGroupID: func() ([32]byte, error) { return [32]byte{123}, nil },
-		readChannelFromBlockchain: func(channelID *big.Int) (*blockchain.MultiPartyEscrowChannel, bool, error) {
+		readChannelFromBlockchain: func(ctx context.Context, channelID *big.Int) (*blockchain.MultiPartyEscrowChannel, bool, error) {
 			return nil, false, nil
 		},
+		recipients: func() []ChannelRecipient { return []ChannelRecipient{{GroupID: [32]byte{123}}} },
 	}
 }
 
@@ -97,13 +98,11 @@ func (suite *BlockchainChannelReaderSuite) SetupSuite() {
 	suite.recipientAddress = crypto.PubkeyToAddress(GenerateTestPrivateKey().PublicKey)
 
 	suite.reader = BlockchainChannelReader{
-		replicaGroupID: func() ([32]byte, error) { return [32]byte{123}, nil },
-		readChannelFromBlockchain: func(channelID *big.Int) (*blockchain.MultiPartyEscrowChannel, bool, error) {
+		readChannelFromBlockchain: func(ctx context.Context, channelID *big.Int) (*blockchain.MultiPartyEscrowChannel, bool, error) {
 			return suite.mpeChannel(), true, nil
 		},
-		recipientPaymentAddress: func() common.Address {
-			address := suite.recipientAddress
-			return address
+		recipients: func() []ChannelRecipient {
+			return []ChannelRecipient{{GroupID: [32]byte{123}, PaymentAddress: suite.recipientAddress}}
 		},
 	}
 }
@@ -146,7 +145,7 @@ func (suite *BlockchainChannelReaderSuite) channelKey() *PaymentChannelKey {
 }
 
 func (suite *BlockchainChannelReaderSuite) TestGetChannelState() {
-	channel, ok, err := suite.reader.GetChannelStateFromBlockchain(suite.channelKey())
+	channel, ok, err := suite.reader.GetChannelStateFromBlockchain(context.Background(), suite.channelKey())
 
 	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
 	assert.True(suite.T(), ok)
@@ -155,10 +154,11 @@ func (suite *BlockchainChannelReaderSuite) TestGetChannelState() {
 
 func (suite *BlockchainChannelReaderSuite) TestGetChannelStateIncorrectGroupId() {
 	reader := suite.reader
-	reader.replicaGroupID = func() ([32]byte, error) { return [32]byte{32}, nil }
-	reader.recipientPaymentAddress = func() common.Address { return suite.recipientAddress }
+	reader.recipients = func() []ChannelRecipient {
+		return []ChannelRecipient{{GroupID: [32]byte{32}, PaymentAddress: suite.recipientAddress}}
+	}
 
-	channel, ok, err := reader.GetChannelStateFromBlockchain(suite.channelKey())
+	channel, ok, err := reader.GetChannelStateFromBlockchain(context.Background(), suite.channelKey())
 
 	assert.Equal(suite.T(), errors.New("Channel received belongs to another group of replicas, current group: [32 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0], channel group: [123 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0]"), err)
 	assert.False(suite.T(), ok)
@@ -167,10 +167,43 @@ func (suite *BlockchainChannelReaderSuite) TestGetChannelStateIncorrectGroupId()
 
 func (suite *BlockchainChannelReaderSuite) TestGetChannelStateIncorrectRecipeintAddress() {
 	reader := suite.reader
-	reader.replicaGroupID = func() ([32]byte, error) { return [32]byte{123}, nil }
-	reader.recipientPaymentAddress = func() common.Address { return crypto.PubkeyToAddress(GenerateTestPrivateKey().PublicKey) }
-	channel, ok, err := reader.GetChannelStateFromBlockchain(suite.channelKey())
+	reader.recipients = func() []ChannelRecipient {
+		return []ChannelRecipient{{GroupID: [32]byte{123}, PaymentAddress: crypto.PubkeyToAddress(GenerateTestPrivateKey().PublicKey)}}
+	}
+	channel, ok, err := reader.GetChannelStateFromBlockchain(context.Background(), suite.channelKey())
 	assert.Equal(suite.T(), errors.New("recipient Address from service metadata does not Match on what was retrieved from Channel"), err)
 	assert.False(suite.T(), ok)
 	assert.Nil(suite.T(), channel)
 }
+
+func (suite *BlockchainChannelReaderSuite) TestGetChannelStateFewServices() {
+	reader := suite.reader
+	reader.recipients = func() []ChannelRecipient {
+		return []ChannelRecipient{
+			{GroupID: [32]byte{32}, PaymentAddress: crypto.PubkeyToAddress(GenerateTestPrivateKey().PublicKey)},
+			{GroupID: [32]byte{123}, PaymentAddress: suite.recipientAddress},
+		}
+	}
+
+	channel, ok, err := reader.GetChannelStateFromBlockchain(context.Background(), suite.channelKey())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), suite.channel(), channel)
+}
+
+func (suite *BlockchainChannelReaderSuite) TestGetChannelStateFewServicesIncorrectGroupId() {
+	reader := suite.reader
+	reader.recipients = func() []ChannelRecipient {
+		return []ChannelRecipient{
+			{GroupID: [32]byte{32}, PaymentAddress: suite.recipientAddress},
+			{GroupID: [32]byte{64}, PaymentAddress: suite.recipientAddress},
+		}
+	}
+
+	channel, ok, err := reader.GetChannelStateFromBlockchain(context.Background(), suite.channelKey())
+
+	assert.Equal(suite.T(), errors.New("Channel received belongs to another group of replicas, current group: [[32 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0] [64 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0]], channel group: [123 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0]"), err)
+	assert.False(suite.T(), ok)
+	assert.Nil(suite.T(), channel)
+}
@@ -2,12 +2,19 @@ package escrow
 
 import (
 	"math/big"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc/codes"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
 	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/metrics"
 )
 
 const (
@@ -31,23 +38,63 @@ const (
 )
 
 type paymentChannelPaymentHandler struct {
-	service            PaymentChannelService
-	mpeContractAddress func() common.Address
-	incomeValidator    IncomeValidator
+	service                PaymentChannelService
+	mpeContractAddress     func() common.Address
+	incomeValidator        IncomeValidator
+	messageIncomeValidator MessageIncomeValidator
+	blockedSenders         *SenderDenylist
+	channelValidator       ChannelValidator
+	rejectedCallLogger     *rejectedCallLogger
+	serviceKeyHeaders      *handler.ServiceKeyHeaders
+	errorMessages          map[string]string
+	channelIDHeaders       []string
 }
 
 // NewPaymentHandler retuns new MultiPartyEscrow contract payment handler.
+// If messageIncomeValidator is not nil then income of client streaming calls
+// is validated after each received message instead of validating it once
+// using incomeValidator. If serviceKeyHeaders is not nil then daemon serves
+// few services and each channel can be used to pay for the single service
+// only. Calls of the channel senders which are in blockedSenders are
+// rejected before income validation. If channelValidator is not nil then
+// channels which it doesn't accept, for example channels which have not
+// enough funds deposited, are rejected.
 func NewPaymentHandler(
 	service PaymentChannelService,
 	processor *blockchain.Processor,
-	incomeValidator IncomeValidator) handler.PaymentHandler {
+	incomeValidator IncomeValidator,
+	messageIncomeValidator MessageIncomeValidator,
+	serviceKeyHeaders *handler.ServiceKeyHeaders,
+	blockedSenders *SenderDenylist,
+	channelValidator ChannelValidator) handler.PaymentHandler {
 	return &paymentChannelPaymentHandler{
-		service:            service,
-		mpeContractAddress: processor.EscrowContractAddress,
-		incomeValidator:    incomeValidator,
+		service:                service,
+		mpeContractAddress:     processor.EscrowContractAddress,
+		incomeValidator:        incomeValidator,
+		messageIncomeValidator: messageIncomeValidator,
+		blockedSenders:         blockedSenders,
+		channelValidator:       channelValidator,
+		rejectedCallLogger:     newRejectedCallLogger(log.StandardLogger(), config.GetInt(config.RejectedCallLogPerMinuteKey)),
+		serviceKeyHeaders:      serviceKeyHeaders,
+		errorMessages:          config.GetValidationErrorMessages(),
+		channelIDHeaders:       GetPaymentChannelIDHeaders(),
 	}
 }
 
+// GetPaymentChannelIDHeaders returns names of the headers which can contain
+// payment channel id in order they are tried. Names are converted to lower
+// case as gRPC metadata keys are lower case.
+func GetPaymentChannelIDHeaders() []string {
+	headers := config.GetStringSlice(config.PaymentChannelIDHeadersKey)
+	if len(headers) == 0 {
+		return []string{PaymentChannelIDHeader}
+	}
+	for i, header := range headers {
+		headers[i] = strings.ToLower(header)
+	}
+	return headers
+}
+
 func (h *paymentChannelPaymentHandler) Type() (typ string) {
 	return EscrowPaymentType
 }
@@ -55,26 +102,146 @@ func (h *paymentChannelPaymentHandler) Type() (typ string) {
 func (h *paymentChannelPaymentHandler) Payment(context *handler.GrpcStreamContext) (payment handler.Payment, err *handler.GrpcError) {
 	internalPayment, err := h.getPaymentFromContext(context)
 	if err != nil {
+		h.rejectedCallLogger.log(context, nil, nil, nil, err)
 		return
 	}
 
-	transaction, e := h.service.StartPaymentTransaction(internalPayment)
+	transaction, e := h.service.StartPaymentTransaction(spanContext(context), internalPayment)
+	if e != nil {
+		err = paymentErrorToGrpcError(e)
+		h.rejectedCallLogger.log(context, internalPayment, nil, e, err)
+		return nil, err
+	}
+
+	if sender := transaction.Channel().Sender; h.blockedSenders.Blocked(sender) {
+		transaction.Rollback()
+		e = NewPaymentError(PermissionDenied, "sender %v is blocked", sender.Hex())
+		err = paymentErrorToGrpcError(e)
+		h.rejectedCallLogger.log(context, internalPayment, transaction.Channel(), e, err)
+		return nil, err
+	}
+
+	e = h.bindChannelToService(context, transaction.Channel())
 	if e != nil {
-		return nil, paymentErrorToGrpcError(e)
+		transaction.Rollback()
+		err = paymentErrorToGrpcError(e)
+		h.rejectedCallLogger.log(context, internalPayment, transaction.Channel(), e, err)
+		return nil, err
+	}
+
+	if h.channelValidator != nil {
+		if e = h.channelValidator.Validate(context, transaction.Channel()); e != nil {
+			transaction.Rollback()
+			err = paymentErrorToGrpcError(e)
+			h.rejectedCallLogger.log(context, internalPayment, transaction.Channel(), e, err)
+			return nil, err
+		}
 	}
 
 	income := big.NewInt(0)
 	income.Sub(internalPayment.Amount, transaction.Channel().AuthorizedAmount)
-	e = h.incomeValidator.Validate(&IncomeData{Income: income, GrpcContext: context})
+	data := &IncomeData{Income: income, Sender: transaction.Channel().Sender, GrpcContext: context, Context: spanContext(context), rollbacks: &[]func(){}}
+	if h.validatesEachMessage(context) {
+		// stream is started only if it pays for the first message at least
+		e = h.messageIncomeValidator.ValidateMessage(data, 1)
+	} else {
+		e = h.incomeValidator.Validate(data)
+	}
 	if e != nil {
-		return nil, paymentErrorToGrpcError(e)
+		data.rollback()
+		transaction.Rollback()
+		err = h.validationErrorToGrpcError(e)
+		h.rejectedCallLogger.log(context, internalPayment, transaction.Channel(), e, err)
+		return nil, err
 	}
 
+	transaction = &incomeTransaction{PaymentTransaction: transaction, income: data}
+	if h.validatesEachMessage(context) {
+		return &streamPayment{PaymentTransaction: transaction, payment: internalPayment}, nil
+	}
 	return transaction, nil
 }
 
+// incomeTransaction reverts changes made by the income validators, for
+// example spend counted by the spend cap, when the payment is not applied.
+type incomeTransaction struct {
+	PaymentTransaction
+	income *IncomeData
+}
+
+func (transaction *incomeTransaction) Commit() (err error) {
+	if err = transaction.PaymentTransaction.Commit(); err != nil {
+		transaction.income.rollback()
+	}
+	return
+}
+
+func (transaction *incomeTransaction) Rollback() error {
+	transaction.income.rollback()
+	return transaction.PaymentTransaction.Rollback()
+}
+
+// streamPayment is a payment of the client streaming call which income is
+// validated after each received message. gRPC metadata cannot be changed
+// after the call is started, so payment is read once at the stream start and
+// the stream is cancelled as soon as it doesn't cover received messages.
+type streamPayment struct {
+	PaymentTransaction
+	payment  *Payment
+	messages int
+}
+
+// bindChannelToService checks that channel is used to pay for the service it
+// is bound to. Channel which is not bound yet is bound to the called service,
+// binding is stored when payment transaction is committed.
+func (h *paymentChannelPaymentHandler) bindChannelToService(context *handler.GrpcStreamContext, channel *PaymentChannelData) error {
+	if h.serviceKeyHeaders == nil {
+		return nil
+	}
+
+	key, e := h.serviceKeyHeaders.GetServiceKey(context.MD)
+	if e != nil {
+		return NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+	}
+	if channel.Service == (handler.ServiceKey{}) {
+		channel.Service = key
+		return nil
+	}
+	if channel.Service != key {
+		return NewPaymentError(PermissionDenied, "payment channel %v is used for service \"%v\" and cannot be used for service \"%v\"", channel.ChannelID, channel.Service, key)
+	}
+	return nil
+}
+
+func (h *paymentChannelPaymentHandler) validatesEachMessage(context *handler.GrpcStreamContext) bool {
+	return h.messageIncomeValidator != nil && context.Info != nil && context.Info.IsClientStream
+}
+
+func (h *paymentChannelPaymentHandler) MessageReceived(payment handler.Payment, context *handler.GrpcStreamContext, messageCount int) (err *handler.GrpcError) {
+	stream, ok := payment.(*streamPayment)
+	if !ok {
+		return nil
+	}
+
+	// first message is validated when stream is started
+	if messageCount > 1 {
+		channel := stream.Channel()
+		income := big.NewInt(0)
+		income.Sub(stream.payment.Amount, channel.AuthorizedAmount)
+		e := h.messageIncomeValidator.ValidateMessage(&IncomeData{Income: income, Sender: channel.Sender, GrpcContext: context, Context: spanContext(context)}, messageCount)
+		if e != nil {
+			err = h.validationErrorToGrpcError(e)
+			h.rejectedCallLogger.log(context, stream.payment, channel, e, err)
+			return err
+		}
+	}
+
+	stream.messages = messageCount
+	return nil
+}
+
 func (h *paymentChannelPaymentHandler) getPaymentFromContext(context *handler.GrpcStreamContext) (payment *Payment, err *handler.GrpcError) {
-	channelID, err := handler.GetBigInt(context.MD, PaymentChannelIDHeader)
+	channelID, err := context.GetBigIntFromHeaders(h.channelIDHeaders)
 	if err != nil {
 		return
 	}
@@ -103,12 +270,34 @@ func (h *paymentChannelPaymentHandler) getPaymentFromContext(context *handler.Gr
 	}, nil
 }
 
+// Complete applies payment of the call. Payment of the streaming call which
+// received no messages is rolled back because nothing is consumed. Otherwise
+// the whole amount signed by client is applied as it cannot be reduced
+// without client signature, so client should authorize amount of the
+// messages it is going to send.
 func (h *paymentChannelPaymentHandler) Complete(payment handler.Payment) (err *handler.GrpcError) {
-	return paymentErrorToGrpcError(payment.(*paymentTransaction).Commit())
+	if stream, ok := payment.(*streamPayment); ok && stream.messages == 0 {
+		return paymentErrorToGrpcError(stream.Rollback())
+	}
+	return paymentErrorToGrpcError(payment.(PaymentTransaction).Commit())
 }
 
 func (h *paymentChannelPaymentHandler) CompleteAfterError(payment handler.Payment, result error) (err *handler.GrpcError) {
-	return paymentErrorToGrpcError(payment.(*paymentTransaction).Rollback())
+	return paymentErrorToGrpcError(payment.(PaymentTransaction).Rollback())
+}
+
+// validationErrorToGrpcError converts income validation error to gRPC error,
+// status message is built using template configured for the failure reason
+// if any.
+func (h *paymentChannelPaymentHandler) validationErrorToGrpcError(err error) *handler.GrpcError {
+	if paymentErr, ok := err.(*PaymentError); ok && paymentErr.Reason != "" {
+		if template, ok := h.errorMessages[paymentErr.Reason]; ok {
+			rendered := *paymentErr
+			rendered.Message = config.RenderMessageTemplate(template, paymentErr.Details)
+			err = &rendered
+		}
+	}
+	return paymentErrorToGrpcError(err)
 }
 
 func paymentErrorToGrpcError(err error) *handler.GrpcError {
@@ -130,9 +319,106 @@ func paymentErrorToGrpcError(err error) *handler.GrpcError {
 		grpcCode = codes.FailedPrecondition
 	case IncorrectNonce:
 		grpcCode = handler.IncorrectNonce
+	case InvalidArgument:
+		grpcCode = codes.InvalidArgument
+	case DeadlineExceeded:
+		grpcCode = codes.DeadlineExceeded
+	case PermissionDenied:
+		grpcCode = codes.PermissionDenied
+	case ResourceExhausted:
+		grpcCode = codes.ResourceExhausted
+	case Unavailable:
+		grpcCode = codes.Unavailable
 	default:
 		grpcCode = codes.Internal
 	}
 
-	return handler.NewGrpcErrorf(grpcCode, err.(*PaymentError).Message)
+	grpcErr := handler.NewGrpcErrorf(grpcCode, err.(*PaymentError).Message)
+	grpcErr.Reason = rejectionReason(err.(*PaymentError))
+	return grpcErr
+}
+
+// rejectionReason returns reason of the payment rejection which is used as a
+// metrics label, infrastructure errors are not counted as rejections.
+func rejectionReason(err *PaymentError) string {
+	if IsInfrastructureError(err) {
+		return ""
+	}
+	if err.Rejection != "" {
+		return err.Rejection
+	}
+	switch err.Reason {
+	case config.ValidationFailurePriceMismatch, config.ValidationFailureMinIncome, config.ValidationFailureMessagePrice:
+		return metrics.RejectionUnderpaid
+	}
+	return metrics.RejectionOther
+}
+
+// rejectedCallLogger writes single structured log line per rejected call.
+// Number of lines per minute is limited to not flood log when daemon is
+// attacked, number of suppressed lines is reported with the next line.
+type rejectedCallLogger struct {
+	logger     *log.Logger
+	limiter    *rate.Limiter
+	mutex      sync.Mutex
+	suppressed int
+}
+
+func newRejectedCallLogger(logger *log.Logger, perMinute int) *rejectedCallLogger {
+	limit := rate.Inf
+	if perMinute > 0 {
+		limit = rate.Every(time.Minute / time.Duration(perMinute))
+	}
+	return &rejectedCallLogger{
+		logger:  logger,
+		limiter: rate.NewLimiter(limit, 1),
+	}
+}
+
+func (rejected *rejectedCallLogger) log(context *handler.GrpcStreamContext, payment *Payment, channel *PaymentChannelData, e error, err *handler.GrpcError) {
+	if rejected == nil {
+		return
+	}
+
+	rejected.mutex.Lock()
+	if !rejected.limiter.Allow() {
+		rejected.suppressed++
+		rejected.mutex.Unlock()
+		return
+	}
+	suppressed := rejected.suppressed
+	rejected.suppressed = 0
+	rejected.mutex.Unlock()
+
+	fields := log.Fields{
+		"code":  err.Status.Code().String(),
+		"error": err.Status.Message(),
+	}
+	if err.Reason != "" {
+		fields["reason"] = err.Reason
+	}
+	if context.Info != nil {
+		fields["method"] = context.Info.FullMethod
+	}
+	if payment != nil {
+		fields["channel"] = payment.ChannelID
+	}
+	if channel != nil {
+		fields["sender"] = channel.Sender.Hex()
+	}
+	if paymentErr, ok := e.(*PaymentError); ok {
+		// original message is logged when client receives customized one
+		fields["error"] = paymentErr.Message
+		if paymentErr.Reason != "" {
+			fields["reason"] = paymentErr.Reason
+		}
+		for key, value := range paymentErr.Details {
+			fields[key] = value
+		}
+	}
+	if suppressed > 0 {
+		fields["suppressed"] = suppressed
+	}
+
+	rejected.logger.WithFields(fields).Warn("Call rejected")
 }
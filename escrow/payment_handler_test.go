@@ -1,20 +1,36 @@
 package escrow
 
 import (
+	"context"
+	"errors"
 	"math/big"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
 	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/metrics"
 )
 
+// rejectedGrpcError returns error which payment handler returns when
+// payment is rejected for the reason.
+func rejectedGrpcError(code codes.Code, message string, reason string) *handler.GrpcError {
+	err := handler.NewGrpcError(code, message)
+	err.Reason = reason
+	return err
+}
+
 type PaymentHandlerTestSuite struct {
 	suite.Suite
 
@@ -35,6 +51,7 @@ func (suite *PaymentHandlerTestSuite) SetupSuite() {
 		service:            suite.paymentChannelServiceMock,
 		mpeContractAddress: func() common.Address { return blockchain.HexToAddress("0xf25186b5081ff5ce73482ad761db0eb0d25abfbf") },
 		incomeValidator:    suite.incomeValidatorMock,
+		channelIDHeaders:   []string{PaymentChannelIDHeader},
 	}
 }
 
@@ -86,6 +103,43 @@ func (suite *PaymentHandlerTestSuite) TestGetPaymentNoChannelId() {
 	assert.Nil(suite.T(), payment)
 }
 
+func (suite *PaymentHandlerTestSuite) TestGetPaymentChannelIdPrimaryHeader() {
+	context := suite.grpcContext(func(md *metadata.MD) {})
+	paymentHandler := suite.paymentHandler
+	paymentHandler.channelIDHeaders = []string{PaymentChannelIDHeader, "snet-channel-id"}
+
+	payment, err := paymentHandler.getPaymentFromContext(context)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), big.NewInt(42), payment.ChannelID)
+}
+
+func (suite *PaymentHandlerTestSuite) TestGetPaymentChannelIdAlternateHeader() {
+	context := suite.grpcContext(func(md *metadata.MD) {
+		delete(*md, PaymentChannelIDHeader)
+		md.Set("snet-channel-id", "43")
+	})
+	paymentHandler := suite.paymentHandler
+	paymentHandler.channelIDHeaders = []string{PaymentChannelIDHeader, "snet-channel-id"}
+
+	payment, err := paymentHandler.getPaymentFromContext(context)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), big.NewInt(43), payment.ChannelID)
+}
+
+func (suite *PaymentHandlerTestSuite) TestGetPaymentNoChannelIdInAnyHeader() {
+	context := suite.grpcContext(func(md *metadata.MD) {
+		delete(*md, PaymentChannelIDHeader)
+	})
+	paymentHandler := suite.paymentHandler
+	paymentHandler.channelIDHeaders = []string{PaymentChannelIDHeader, "snet-channel-id"}
+
+	_, err := paymentHandler.getPaymentFromContext(context)
+
+	assert.Equal(suite.T(), handler.NewGrpcError(codes.InvalidArgument, "missing \"snet-payment-channel-id\" or \"snet-channel-id\""), err)
+}
+
 func (suite *PaymentHandlerTestSuite) TestGetPaymentNoChannelNonce() {
 	context := suite.grpcContext(func(md *metadata.MD) {
 		delete(*md, PaymentChannelNonceHeader)
@@ -128,7 +182,7 @@ func (suite *PaymentHandlerTestSuite) TestStartTransactionError() {
 
 	payment, err := paymentHandler.Payment(context)
 
-	assert.Equal(suite.T(), handler.NewGrpcError(codes.FailedPrecondition, "another transaction in progress"), err)
+	assert.Equal(suite.T(), rejectedGrpcError(codes.FailedPrecondition, "another transaction in progress", metrics.RejectionOther), err)
 	assert.Nil(suite.T(), payment)
 }
 
@@ -140,6 +194,411 @@ func (suite *PaymentHandlerTestSuite) TestValidatePaymentIncorrectIncome() {
 
 	payment, err := paymentHandler.Payment(context)
 
-	assert.Equal(suite.T(), handler.NewGrpcError(codes.Unauthenticated, "incorrect payment income: \"45\", expected \"46\""), err)
+	assert.Equal(suite.T(), rejectedGrpcError(codes.Unauthenticated, "incorrect payment income: \"45\", expected \"46\"", metrics.RejectionOther), err)
+	assert.Nil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentUnderpaymentCustomMessage() {
+	context := suite.grpcContext(func(md *metadata.MD) {})
+	context.Info = &grpc.StreamServerInfo{FullMethod: "/Service/Method"}
+	logger, hook := test.NewNullLogger()
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = NewIncomeValidator(big.NewInt(46))
+	paymentHandler.rejectedCallLogger = newRejectedCallLogger(logger, 0)
+	paymentHandler.errorMessages = map[string]string{
+		config.ValidationFailurePriceMismatch: "Please pay {expected} cogs for the call, you paid {received} cogs",
+	}
+
+	payment, err := paymentHandler.Payment(context)
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.Unauthenticated, "Please pay 46 cogs for the call, you paid 45 cogs", metrics.RejectionUnderpaid), err)
+	assert.Nil(suite.T(), payment)
+	assert.Equal(suite.T(), "income 45 does not equal to price 46", hook.LastEntry().Data["error"])
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentCustomMessageOfOtherReason() {
+	context := suite.grpcContext(func(md *metadata.MD) {})
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = NewMinIncomeValidator(big.NewInt(46))
+	paymentHandler.errorMessages = map[string]string{
+		config.ValidationFailurePriceMismatch: "Please pay {expected} cogs for the call",
+	}
+
+	_, err := paymentHandler.Payment(context)
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.InvalidArgument, "income 45 is less than minimal income 46", metrics.RejectionUnderpaid), err)
+}
+
+func (suite *PaymentHandlerTestSuite) multiServicePaymentHandler(channelService handler.ServiceKey) (paymentHandler paymentChannelPaymentHandler, channel *PaymentChannelData) {
+	channel = suite.channel()
+	channel.ChannelID = big.NewInt(42)
+	channel.Service = channelService
+	paymentHandler = suite.paymentHandler
+	paymentHandler.service = &paymentChannelServiceMock{data: channel}
+	paymentHandler.serviceKeyHeaders = &handler.DefaultServiceKeyHeaders
+	return
+}
+
+func (suite *PaymentHandlerTestSuite) serviceContext(organizationID, serviceID string) *handler.GrpcStreamContext {
+	return suite.grpcContext(func(md *metadata.MD) {
+		md.Set(handler.OrganizationIDHeader, organizationID)
+		md.Set(handler.ServiceIDHeader, serviceID)
+	})
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentForChannelService() {
+	paymentHandler, _ := suite.multiServicePaymentHandler(handler.ServiceKey{OrganizationID: "org", ServiceID: "service-a"})
+
+	payment, err := paymentHandler.Payment(suite.serviceContext("org", "service-a"))
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.NotNil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentForForeignServiceIsRejected() {
+	paymentHandler, _ := suite.multiServicePaymentHandler(handler.ServiceKey{OrganizationID: "org", ServiceID: "service-a"})
+
+	payment, err := paymentHandler.Payment(suite.serviceContext("org", "service-b"))
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.PermissionDenied, "payment channel 42 is used for service \"org/service-a\" and cannot be used for service \"org/service-b\"", metrics.RejectionOther), err)
+	assert.Nil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentBindsChannelToService() {
+	paymentHandler, channel := suite.multiServicePaymentHandler(handler.ServiceKey{})
+
+	_, err := paymentHandler.Payment(suite.serviceContext("org", "service-a"))
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.Equal(suite.T(), handler.ServiceKey{OrganizationID: "org", ServiceID: "service-a"}, channel.Service)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentWithoutServiceKey() {
+	paymentHandler, _ := suite.multiServicePaymentHandler(handler.ServiceKey{})
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.InvalidArgument, "missing \"snet-organization-id\"", metrics.RejectionOther), err)
+	assert.Nil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestRejectedCallIsLogged() {
+	context := suite.grpcContext(func(md *metadata.MD) {})
+	context.Info = &grpc.StreamServerInfo{FullMethod: "/Service/Method"}
+	logger, hook := test.NewNullLogger()
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = NewIncomeValidator(big.NewInt(46))
+	paymentHandler.rejectedCallLogger = newRejectedCallLogger(logger, 0)
+
+	_, err := paymentHandler.Payment(context)
+
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(hook.Entries))
+	entry := hook.LastEntry()
+	assert.Equal(suite.T(), log.WarnLevel, entry.Level)
+	assert.Equal(suite.T(), "Call rejected", entry.Message)
+	assert.Equal(suite.T(), config.ValidationFailurePriceMismatch, entry.Data["reason"])
+	assert.Equal(suite.T(), "Unauthenticated", entry.Data["code"])
+	assert.Equal(suite.T(), big.NewInt(46), entry.Data["expected"])
+	assert.Equal(suite.T(), big.NewInt(45), entry.Data["received"])
+	assert.Equal(suite.T(), suite.channel().Sender.Hex(), entry.Data["sender"])
+	assert.Equal(suite.T(), big.NewInt(42), entry.Data["channel"])
+	assert.Equal(suite.T(), "/Service/Method", entry.Data["method"])
+}
+
+func (suite *PaymentHandlerTestSuite) TestRejectedCallBelowMinIncomeIsLogged() {
+	logger, hook := test.NewNullLogger()
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = NewMinIncomeValidator(big.NewInt(50))
+	paymentHandler.rejectedCallLogger = newRejectedCallLogger(logger, 0)
+
+	_, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.NotNil(suite.T(), err)
+	entry := hook.LastEntry()
+	assert.Equal(suite.T(), config.ValidationFailureMinIncome, entry.Data["reason"])
+	assert.Equal(suite.T(), "InvalidArgument", entry.Data["code"])
+	assert.Equal(suite.T(), big.NewInt(50), entry.Data["expected"])
+	assert.Equal(suite.T(), big.NewInt(45), entry.Data["received"])
+}
+
+func (suite *PaymentHandlerTestSuite) TestRejectedCallLogIsRateLimited() {
+	context := suite.grpcContext(func(md *metadata.MD) {
+		delete(*md, PaymentChannelIDHeader)
+	})
+	logger, hook := test.NewNullLogger()
+	paymentHandler := suite.paymentHandler
+	paymentHandler.rejectedCallLogger = newRejectedCallLogger(logger, 1)
+
+	for i := 0; i < 3; i++ {
+		paymentHandler.Payment(context)
+	}
+
+	assert.Equal(suite.T(), 1, len(hook.Entries))
+	assert.Equal(suite.T(), "InvalidArgument", hook.LastEntry().Data["code"])
+	assert.Equal(suite.T(), 2, paymentHandler.rejectedCallLogger.suppressed)
+}
+
+func (suite *PaymentHandlerTestSuite) streamPaymentHandler(price int64) paymentChannelPaymentHandler {
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = &incomeValidatorMockType{err: NewPaymentError(Unauthenticated, "must not be called")}
+	paymentHandler.messageIncomeValidator = NewMessageIncomeValidator(NewMessagePriceIncomeValidator(NewFixedPriceProvider(big.NewInt(price))))
+	return paymentHandler
+}
+
+func (suite *PaymentHandlerTestSuite) streamContext() *handler.GrpcStreamContext {
+	context := suite.grpcContext(func(md *metadata.MD) {})
+	context.Info = &grpc.StreamServerInfo{FullMethod: "/service/method", IsClientStream: true}
+	return context
+}
+
+func (suite *PaymentHandlerTestSuite) TestMessageReceivedStopsStreamWhenPaymentStalls() {
+	paymentHandler := suite.streamPaymentHandler(20)
+	context := suite.streamContext()
+
+	payment, err := paymentHandler.Payment(context)
+	assert.Nil(suite.T(), err)
+
+	assert.Nil(suite.T(), paymentHandler.MessageReceived(payment, context, 1))
+	assert.Nil(suite.T(), paymentHandler.MessageReceived(payment, context, 2))
+	err = paymentHandler.MessageReceived(payment, context, 3)
+	assert.Equal(suite.T(), codes.FailedPrecondition, err.Status.Code())
+}
+
+func (suite *PaymentHandlerTestSuite) TestStreamPaymentIsValidatedAtStart() {
+	paymentHandler := suite.streamPaymentHandler(50)
+
+	payment, err := paymentHandler.Payment(suite.streamContext())
+
+	assert.Nil(suite.T(), payment)
+	assert.Equal(suite.T(), codes.FailedPrecondition, err.Status.Code())
+	assert.Equal(suite.T(), "income 45 does not cover 1 messages, price of message 50", err.Status.Message())
+}
+
+func (suite *PaymentHandlerTestSuite) TestStreamPaymentIsReadOnceAtStart() {
+	paymentHandler := suite.streamPaymentHandler(20)
+	context := suite.streamContext()
+	payment, err := paymentHandler.Payment(context)
+	assert.Nil(suite.T(), err)
+
+	context.MD.Set(PaymentChannelAmountHeader, "1000000")
+
+	err = paymentHandler.MessageReceived(payment, context, 3)
+	assert.Equal(suite.T(), codes.FailedPrecondition, err.Status.Code())
+}
+
+func (suite *PaymentHandlerTestSuite) TestStreamWithoutMessagesIsNotCharged() {
+	paymentHandler := suite.streamPaymentHandler(20)
+	payment, err := paymentHandler.Payment(suite.streamContext())
+	assert.Nil(suite.T(), err)
+
+	assert.Nil(suite.T(), paymentHandler.Complete(payment))
+
+	transaction := transactionMock(payment)
+	assert.True(suite.T(), transaction.rolledBack)
+	assert.False(suite.T(), transaction.committed)
+}
+
+func (suite *PaymentHandlerTestSuite) TestStreamWithMessagesIsCharged() {
+	paymentHandler := suite.streamPaymentHandler(20)
+	context := suite.streamContext()
+	payment, err := paymentHandler.Payment(context)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), paymentHandler.MessageReceived(payment, context, 1))
+
+	assert.Nil(suite.T(), paymentHandler.Complete(payment))
+
+	transaction := transactionMock(payment)
+	assert.True(suite.T(), transaction.committed)
+	assert.False(suite.T(), transaction.rolledBack)
+}
+
+// transactionMock returns transaction mock which is wrapped by the payment
+// returned by handler.
+func transactionMock(payment handler.Payment) *paymentTransactionMock {
+	if stream, ok := payment.(*streamPayment); ok {
+		payment = stream.PaymentTransaction
+	}
+	return payment.(*incomeTransaction).PaymentTransaction.(*paymentTransactionMock)
+}
+
+func (suite *PaymentHandlerTestSuite) spendCapPaymentHandler(now *time.Time) paymentChannelPaymentHandler {
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 45, now)
+	paymentHandler.messageIncomeValidator = NewMessageIncomeValidator(paymentHandler.incomeValidator)
+	return paymentHandler
+}
+
+func (suite *PaymentHandlerTestSuite) TestSpendIsRefundedWhenPaymentIsRolledBack() {
+	now := testSpendCapNow
+	paymentHandler := suite.spendCapPaymentHandler(&now)
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Nil(suite.T(), err)
+	_, err = paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Equal(suite.T(), codes.ResourceExhausted, err.Status.Code(), "spend is counted at validation")
+
+	assert.Nil(suite.T(), paymentHandler.CompleteAfterError(payment, errors.New("service error")))
+
+	_, err = paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PaymentHandlerTestSuite) TestSpendIsRefundedWhenCommitFails() {
+	now := testSpendCapNow
+	paymentHandler := suite.spendCapPaymentHandler(&now)
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Nil(suite.T(), err)
+	transactionMock(payment).err = NewPaymentError(Internal, "storage error")
+
+	assert.NotNil(suite.T(), paymentHandler.Complete(payment))
+
+	_, err = paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PaymentHandlerTestSuite) TestSpendIsKeptWhenPaymentIsCommitted() {
+	now := testSpendCapNow
+	paymentHandler := suite.spendCapPaymentHandler(&now)
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), paymentHandler.Complete(payment))
+
+	_, err = paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Equal(suite.T(), codes.ResourceExhausted, err.Status.Code())
+}
+
+func (suite *PaymentHandlerTestSuite) TestStreamSpendIsRefundedWithoutMessages() {
+	now := testSpendCapNow
+	paymentHandler := suite.spendCapPaymentHandler(&now)
+
+	payment, err := paymentHandler.Payment(suite.streamContext())
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), paymentHandler.Complete(payment))
+
+	_, err = paymentHandler.Payment(suite.streamContext())
+	assert.Nil(suite.T(), err)
+}
+
+// transactionServiceMock returns the same transaction on each call.
+type transactionServiceMock struct {
+	paymentChannelServiceMock
+	transaction PaymentTransaction
+}
+
+func (service *transactionServiceMock) StartPaymentTransaction(ctx context.Context, payment *Payment) (PaymentTransaction, error) {
+	return service.transaction, nil
+}
+
+func (suite *PaymentHandlerTestSuite) TestRejectedPaymentIsRolledBack() {
+	paymentHandler := suite.paymentHandler
+	paymentHandler.incomeValidator = NewIncomeValidator(big.NewInt(46))
+	transaction := &paymentTransactionMock{channel: suite.channel()}
+	paymentHandler.service = &transactionServiceMock{transaction: transaction}
+
+	_, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.NotNil(suite.T(), err)
+	assert.True(suite.T(), transaction.rolledBack)
+}
+
+func TestPaymentErrorToGrpcErrorRejectionReason(t *testing.T) {
+	priceMismatch := NewPaymentError(Unauthenticated, "income 45 does not equal to price 46")
+	priceMismatch.Reason = config.ValidationFailurePriceMismatch
+	messagePrice := NewPaymentError(FailedPrecondition, "income 45 does not cover 2 messages, price of message 46")
+	messagePrice.Reason = config.ValidationFailureMessagePrice
+	spendCap := NewPaymentError(ResourceExhausted, "spend cap is reached")
+	spendCap.Reason = config.ValidationFailureSpendCap
+
+	for _, test := range []struct {
+		err      *PaymentError
+		expected string
+	}{
+		{priceMismatch, metrics.RejectionUnderpaid},
+		{messagePrice, metrics.RejectionUnderpaid},
+		{newRejectionError(metrics.RejectionExpired, Unauthenticated, "payment channel is near to be expired"), metrics.RejectionExpired},
+		{newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), metrics.RejectionBadSignature},
+		{spendCap, metrics.RejectionOther},
+		{newRejectionError(metrics.RejectionUnderpaid, Unauthenticated, "payment amount 12 is less than previously authorized amount 13"), metrics.RejectionUnderpaid},
+		{newRejectionError(metrics.RejectionUnderpaid, FailedPrecondition, "not enough tokens on payment channel, channel amount: 12, payment amount: 13"), metrics.RejectionUnderpaid},
+		{NewPaymentError(Unavailable, "daemon is behind on block sync"), ""},
+	} {
+		assert.Equal(t, test.expected, paymentErrorToGrpcError(test.err).Reason, test.err.Message)
+	}
+}
+
+func (suite *PaymentHandlerTestSuite) blockedSendersPaymentHandler(blockedSenders *SenderDenylist) paymentChannelPaymentHandler {
+	channel := suite.channel()
+	channel.Sender = blockchain.HexToAddress("0xf17f52151EbEF6C7334FAD080c5704D77216b732")
+	paymentHandler := suite.paymentHandler
+	paymentHandler.service = &paymentChannelServiceMock{data: channel}
+	paymentHandler.blockedSenders = blockedSenders
+	return paymentHandler
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentOfBlockedSenderIsRejected() {
+	paymentHandler := suite.blockedSendersPaymentHandler(NewSenderDenylist([]common.Address{
+		blockchain.HexToAddress("0xf17f52151EbEF6C7334FAD080c5704D77216b732"),
+	}))
+	paymentHandler.incomeValidator = &incomeValidatorMockType{err: NewPaymentError(Unauthenticated, "income is not validated")}
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.PermissionDenied, "sender 0xf17f52151EbEF6C7334FAD080c5704D77216b732 is blocked", metrics.RejectionOther), err)
 	assert.Nil(suite.T(), payment)
 }
+
+func (suite *PaymentHandlerTestSuite) TestPaymentOfAllowedSender() {
+	paymentHandler := suite.blockedSendersPaymentHandler(NewSenderDenylist([]common.Address{
+		blockchain.HexToAddress("0x627306090abaB3A6e1400e9345bC60c78a8BEf57"),
+	}))
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.NotNil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentOfSenderBlockedOnReload() {
+	blockedSenders := NewSenderDenylist(nil)
+	paymentHandler := suite.blockedSendersPaymentHandler(blockedSenders)
+	_, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+
+	blockedSenders.Update([]common.Address{blockchain.HexToAddress("0xf17f52151EbEF6C7334FAD080c5704D77216b732")})
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.PermissionDenied, "sender 0xf17f52151EbEF6C7334FAD080c5704D77216b732 is blocked", metrics.RejectionOther), err)
+	assert.Nil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentOfUndersizedChannelIsRejected() {
+	channel := suite.channel()
+	channel.ChannelID = big.NewInt(42)
+	channel.Nonce = big.NewInt(0)
+	channel.FullAmount = big.NewInt(999)
+	paymentHandler := suite.paymentHandler
+	paymentHandler.service = &paymentChannelServiceMock{data: channel}
+	paymentHandler.channelValidator = NewMinChannelDepositValidator(big.NewInt(1000))
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.Equal(suite.T(), rejectedGrpcError(codes.FailedPrecondition, "payment channel 42 deposit 999 is less than minimal deposit 1000", metrics.RejectionOther), err)
+	assert.Nil(suite.T(), payment)
+}
+
+func (suite *PaymentHandlerTestSuite) TestPaymentOfChannelWithMinimalDeposit() {
+	channel := suite.channel()
+	channel.Nonce = big.NewInt(0)
+	channel.FullAmount = big.NewInt(1000)
+	paymentHandler := suite.paymentHandler
+	paymentHandler.service = &paymentChannelServiceMock{data: channel}
+	paymentHandler.channelValidator = NewMinChannelDepositValidator(big.NewInt(1000))
+
+	payment, err := paymentHandler.Payment(suite.grpcContext(func(md *metadata.MD) {}))
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.NotNil(suite.T(), payment)
+}
@@ -8,22 +8,30 @@ import (
 // PaymentChannelKey based on TypedAtomicStorage implementation
 type PaymentStorage struct {
 	delegate TypedAtomicStorage
+	// failed keeps payments which claims are failed and should not be
+	// retried
+	failed TypedAtomicStorage
 }
 
 // NewPaymentStorage returns new instance of PaymentStorage
 // implementation
 func NewPaymentStorage(atomicStorage AtomicStorage) *PaymentStorage {
 	return &PaymentStorage{
-		delegate: &TypedAtomicStorageImpl{
-			atomicStorage: &PrefixedAtomicStorage{
-				delegate:  atomicStorage,
-				keyPrefix: "/payment/storage",
-			},
-			keySerializer:     serialize,
-			valueSerializer:   serialize,
-			valueDeserializer: deserialize,
-			valueType:         reflect.TypeOf(Payment{}),
+		delegate: newTypedPaymentStorage(atomicStorage, "/payment/storage"),
+		failed:   newTypedPaymentStorage(atomicStorage, "/payment/failed"),
+	}
+}
+
+func newTypedPaymentStorage(atomicStorage AtomicStorage, keyPrefix string) TypedAtomicStorage {
+	return &TypedAtomicStorageImpl{
+		atomicStorage: &PrefixedAtomicStorage{
+			delegate:  atomicStorage,
+			keyPrefix: keyPrefix,
 		},
+		keySerializer:     serialize,
+		valueSerializer:   serialize,
+		valueDeserializer: deserialize,
+		valueType:         reflect.TypeOf(Payment{}),
 	}
 }
 
@@ -43,3 +51,21 @@ func (storage *PaymentStorage) Put(payment *Payment) (err error) {
 func (storage *PaymentStorage) Delete(payment *Payment) (err error) {
 	return storage.delegate.Delete(payment.ID())
 }
+
+// MarkFailed moves payment to the list of failed payments.
+func (storage *PaymentStorage) MarkFailed(payment *Payment) (err error) {
+	if err = storage.failed.Put(payment.ID(), payment); err != nil {
+		return
+	}
+	return storage.delegate.Delete(payment.ID())
+}
+
+// GetAllFailed returns payments which claims are failed.
+func (storage *PaymentStorage) GetAllFailed() (payments []*Payment, err error) {
+	values, err := storage.failed.GetAll()
+	if err != nil {
+		return
+	}
+
+	return values.([]*Payment), nil
+}
@@ -0,0 +1,251 @@
+package escrow
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/singnet/snet-daemon/util/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+// PriceProvider returns price of the call of the service method. Each
+// provider instance belongs to the one service.
+type PriceProvider interface {
+	// GetPriceInCogs returns price of the method call or error if price
+	// cannot be determined.
+	GetPriceInCogs(method string) (price *big.Int, err error)
+}
+
+// EffectivePriceTable returns prices which are returned by the provider.
+// Pricing table is returned as is if provider uses it, other providers return
+// the same price for all methods which is put under DefaultPriceKey.
+func EffectivePriceTable(provider PriceProvider) (table PriceTable, err error) {
+	if tableProvider, ok := provider.(interface{ PriceTable() PriceTable }); ok {
+		return tableProvider.PriceTable(), nil
+	}
+
+	price, err := provider.GetPriceInCogs(DefaultPriceKey)
+	if err != nil {
+		return
+	}
+	return PriceTable{DefaultPriceKey: price}, nil
+}
+
+type fixedPriceProvider struct {
+	price *big.Int
+}
+
+// NewFixedPriceProvider returns price provider which returns the same price
+// for all methods.
+func NewFixedPriceProvider(price *big.Int) PriceProvider {
+	return &fixedPriceProvider{price: price}
+}
+
+func (provider *fixedPriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	return provider.price, nil
+}
+
+func (provider *fixedPriceProvider) String() string {
+	return fmt.Sprintf("fixed(%v)", provider.price)
+}
+
+type currencyPriceProvider struct {
+	delegate PriceProvider
+	rate     *big.Rat
+}
+
+// NewCurrencyPriceProvider returns price provider which converts prices set
+// in other currency into cogs of the accepted currency. Rate is an amount in
+// cogs which is equal to one price unit, converted price is rounded up so
+// call is never underpaid.
+func NewCurrencyPriceProvider(delegate PriceProvider, rate *big.Rat) PriceProvider {
+	return &currencyPriceProvider{delegate: delegate, rate: rate}
+}
+
+func (provider *currencyPriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	price, err = provider.delegate.GetPriceInCogs(method)
+	if err != nil {
+		return
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt(price), provider.rate)
+	price, remainder := new(big.Int).QuoRem(converted.Num(), converted.Denom(), new(big.Int))
+	if remainder.Sign() > 0 {
+		price.Add(price, big.NewInt(1))
+	}
+	return price, nil
+}
+
+func (provider *currencyPriceProvider) String() string {
+	return fmt.Sprintf("currency(%v, %v)", provider.delegate, provider.rate.RatString())
+}
+
+type cachedPrice struct {
+	price     *big.Int
+	refreshAt time.Time
+	// refreshing is true while price is reloaded in background
+	refreshing bool
+	// failures is a number of failed refreshes since price was loaded,
+	// next refresh is not started before retryAt
+	failures int
+	retryAt  time.Time
+}
+
+// priceLoad is a price which is being loaded by one of the callers, other
+// callers wait until done is closed and get the same result.
+type priceLoad struct {
+	done  chan struct{}
+	price *big.Int
+	err   error
+}
+
+// priceRefreshPolicy is a backoff of the repeated refreshes of the cached
+// price after failures.
+var priceRefreshPolicy = retry.Policy{
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     5 * time.Minute,
+	Jitter:         0.2,
+}
+
+type cachingPriceProvider struct {
+	delegate PriceProvider
+	ttl      time.Duration
+	jitter   time.Duration
+	policy   retry.Policy
+	now      func() time.Time
+	// random returns random number in [0, n)
+	random func(n int64) int64
+	// background runs price refresh asynchronously
+	background func(refresh func())
+	mutex      sync.Mutex
+	cache      map[string]*cachedPrice
+	loads      map[string]*priceLoad
+}
+
+// NewCachingPriceProvider returns price provider which keeps prices returned
+// by delegate. Price is refreshed in background after ttl while cached price
+// is still returned, when jitter is not zero the refresh moment is chosen
+// randomly between ttl and ttl+jitter, so prices cached at the same time are
+// not reloaded simultaneously. Failed refresh is retried with backoff and the
+// last loaded price is returned meanwhile. Concurrent loads of the price
+// which is not cached yet are merged into one call of delegate, errors are
+// not cached.
+func NewCachingPriceProvider(delegate PriceProvider, ttl time.Duration, jitter time.Duration) PriceProvider {
+	return &cachingPriceProvider{
+		delegate:   delegate,
+		ttl:        ttl,
+		jitter:     jitter,
+		policy:     priceRefreshPolicy,
+		now:        time.Now,
+		random:     rand.Int63n,
+		background: func(refresh func()) { go refresh() },
+		cache:      make(map[string]*cachedPrice),
+		loads:      make(map[string]*priceLoad),
+	}
+}
+
+func (provider *cachingPriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	provider.mutex.Lock()
+	now := provider.now()
+	if cached, ok := provider.cache[method]; ok {
+		if !now.Before(cached.refreshAt) && !cached.refreshing && !now.Before(cached.retryAt) {
+			cached.refreshing = true
+			provider.background(func() { provider.refresh(method) })
+		}
+		provider.mutex.Unlock()
+		return cached.price, nil
+	}
+
+	load, loading := provider.loads[method]
+	if !loading {
+		load = &priceLoad{done: make(chan struct{})}
+		provider.loads[method] = load
+	}
+	provider.mutex.Unlock()
+
+	if loading {
+		<-load.done
+		return load.price, load.err
+	}
+
+	load.price, load.err = provider.delegate.GetPriceInCogs(method)
+
+	provider.mutex.Lock()
+	delete(provider.loads, method)
+	if load.err == nil {
+		provider.cache[method] = provider.newCachedPrice(load.price, provider.now())
+	}
+	provider.mutex.Unlock()
+	close(load.done)
+
+	return load.price, load.err
+}
+
+// refresh reloads price of the method, cached price is kept and refresh is
+// retried with backoff if price cannot be reloaded.
+func (provider *cachingPriceProvider) refresh(method string) {
+	price, err := provider.delegate.GetPriceInCogs(method)
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	cached, ok := provider.cache[method]
+	if err == nil {
+		provider.cache[method] = provider.newCachedPrice(price, provider.now())
+		return
+	}
+	if !ok {
+		return
+	}
+	cached.refreshing = false
+	cached.failures++
+	cached.retryAt = provider.now().Add(provider.policy.Backoff(cached.failures))
+	log.WithError(err).WithField("method", method).WithField("retryAt", cached.retryAt).
+		Warn("Cannot refresh price, last loaded price is used")
+}
+
+func (provider *cachingPriceProvider) newCachedPrice(price *big.Int, now time.Time) *cachedPrice {
+	refreshAt := now.Add(provider.ttl)
+	if provider.jitter > 0 {
+		refreshAt = refreshAt.Add(time.Duration(provider.random(int64(provider.jitter))))
+	}
+	return &cachedPrice{
+		price:     price,
+		refreshAt: refreshAt,
+	}
+}
+
+func (provider *cachingPriceProvider) String() string {
+	if provider.jitter > 0 {
+		return fmt.Sprintf("cached(%v, ttl: %v, jitter: %v)", provider.delegate, provider.ttl, provider.jitter)
+	}
+	return fmt.Sprintf("cached(%v, ttl: %v)", provider.delegate, provider.ttl)
+}
+
+type fallbackPriceProvider struct {
+	primary  PriceProvider
+	fallback PriceProvider
+}
+
+// NewFallbackPriceProvider returns price provider which returns price from
+// fallback provider when primary provider fails.
+func NewFallbackPriceProvider(primary PriceProvider, fallback PriceProvider) PriceProvider {
+	return &fallbackPriceProvider{primary: primary, fallback: fallback}
+}
+
+func (provider *fallbackPriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	price, err = provider.primary.GetPriceInCogs(method)
+	if err == nil {
+		return
+	}
+
+	log.WithError(err).WithField("method", method).Warn("Cannot get price, using fallback price")
+	return provider.fallback.GetPriceInCogs(method)
+}
+
+func (provider *fallbackPriceProvider) String() string {
+	return fmt.Sprintf("fallback(%v, %v)", provider.primary, provider.fallback)
+}
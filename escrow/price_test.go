@@ -0,0 +1,282 @@
+package escrow
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+type priceProviderMock struct {
+	price *big.Int
+	err   error
+	calls int
+}
+
+func (provider *priceProviderMock) GetPriceInCogs(method string) (price *big.Int, err error) {
+	provider.calls++
+	return provider.price, provider.err
+}
+
+func TestPriceIncomeValidate(t *testing.T) {
+	incomeValidator := NewPriceIncomeValidator(&priceProviderMock{price: big.NewInt(10)})
+	context := &handler.GrpcStreamContext{Info: &grpc.StreamServerInfo{FullMethod: "/Service/Method"}}
+
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context}))
+	assert.Equal(t, incomeMismatchError(big.NewInt(11), big.NewInt(10)),
+		incomeValidator.Validate(&IncomeData{Income: big.NewInt(11), GrpcContext: context}))
+}
+
+func TestPriceIncomeValidatePriceUnavailable(t *testing.T) {
+	incomeValidator := NewPriceIncomeValidator(&priceProviderMock{err: errors.New("registry is unavailable")})
+
+	err := incomeValidator.Validate(&IncomeData{Income: big.NewInt(10)})
+
+	assert.Equal(t, NewPaymentError(Internal, "cannot determine price of the call"), err)
+}
+
+func TestCachingPriceProvider(t *testing.T) {
+	delegate := &priceProviderMock{price: big.NewInt(10)}
+	provider := NewCachingPriceProvider(delegate, time.Minute, 0).(*cachingPriceProvider)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+	var refreshes []func()
+	provider.background = func(refresh func()) { refreshes = append(refreshes, refresh) }
+
+	price, err := provider.GetPriceInCogs("/Service/Method")
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), price)
+
+	delegate.price = big.NewInt(20)
+	price, _ = provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(10), price)
+	assert.Equal(t, 1, delegate.calls)
+
+	now = now.Add(2 * time.Minute)
+	price, _ = provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(10), price, "price is refreshed in background")
+	runRefreshes(&refreshes)
+	price, _ = provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(20), price)
+	assert.Equal(t, 2, delegate.calls)
+}
+
+func TestCachingPriceProviderDoesNotCacheErrors(t *testing.T) {
+	delegate := &priceProviderMock{err: errors.New("registry is unavailable")}
+	provider := NewCachingPriceProvider(delegate, time.Minute, 0)
+
+	_, err := provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, errors.New("registry is unavailable"), err)
+
+	delegate.err = nil
+	delegate.price = big.NewInt(10)
+	price, err := provider.GetPriceInCogs("/Service/Method")
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), price)
+}
+
+// newTestJitteredPriceProvider returns provider which keeps background
+// refreshes in the refreshes list instead of running them.
+func newTestJitteredPriceProvider(delegate PriceProvider, now *time.Time, refreshes *[]func()) *cachingPriceProvider {
+	provider := NewCachingPriceProvider(delegate, time.Minute, time.Minute).(*cachingPriceProvider)
+	provider.now = func() time.Time { return *now }
+	provider.random = rand.New(rand.NewSource(1)).Int63n
+	provider.background = func(refresh func()) { *refreshes = append(*refreshes, refresh) }
+	provider.policy.Jitter = 0
+	return provider
+}
+
+func runRefreshes(refreshes *[]func()) {
+	for _, refresh := range *refreshes {
+		refresh()
+	}
+	*refreshes = nil
+}
+
+func TestCachingPriceProviderSpreadsRefreshes(t *testing.T) {
+	delegate := &priceProviderMock{price: big.NewInt(10)}
+	now := time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+	var refreshes []func()
+	provider := newTestJitteredPriceProvider(delegate, &now, &refreshes)
+	methods := 10
+	for i := 0; i < methods; i++ {
+		provider.GetPriceInCogs(fmt.Sprintf("/Service/Method%v", i))
+	}
+	assert.Equal(t, methods, delegate.calls)
+
+	start := now
+	stepsWithRefreshes, maxRefreshesPerStep := 0, 0
+	for step := time.Minute; step <= 2*time.Minute; step += time.Second {
+		now = start.Add(step)
+		callsBefore := delegate.calls
+		for i := 0; i < methods; i++ {
+			provider.GetPriceInCogs(fmt.Sprintf("/Service/Method%v", i))
+		}
+		runRefreshes(&refreshes)
+		if refreshed := delegate.calls - callsBefore; refreshed > 0 {
+			stepsWithRefreshes++
+			if refreshed > maxRefreshesPerStep {
+				maxRefreshesPerStep = refreshed
+			}
+		}
+	}
+
+	assert.Equal(t, 2*methods, delegate.calls, "each price should be refreshed once")
+	assert.True(t, maxRefreshesPerStep < methods, "refreshes are synchronized: %v of %v in one step", maxRefreshesPerStep, methods)
+	assert.True(t, stepsWithRefreshes > methods/2, "refreshes are not spread out: %v steps", stepsWithRefreshes)
+}
+
+func TestCachingPriceProviderReturnsCachedPriceWhileRefreshing(t *testing.T) {
+	delegate := &priceProviderMock{price: big.NewInt(10)}
+	now := time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+	var refreshes []func()
+	provider := newTestJitteredPriceProvider(delegate, &now, &refreshes)
+	provider.GetPriceInCogs("/Service/Method")
+	delegate.price = big.NewInt(20)
+
+	now = now.Add(2*time.Minute - time.Nanosecond)
+	priceA, _ := provider.GetPriceInCogs("/Service/Method")
+	priceB, _ := provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(10), priceA)
+	assert.Equal(t, big.NewInt(10), priceB)
+	assert.Equal(t, 1, len(refreshes))
+
+	runRefreshes(&refreshes)
+	price, _ := provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(20), price)
+	assert.Equal(t, 2, delegate.calls)
+}
+
+func TestCachingPriceProviderKeepsPriceWhenRefreshFails(t *testing.T) {
+	delegate := &priceProviderMock{price: big.NewInt(10)}
+	now := time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+	var refreshes []func()
+	provider := newTestJitteredPriceProvider(delegate, &now, &refreshes)
+	provider.GetPriceInCogs("/Service/Method")
+	delegate.err = errors.New("registry is unavailable")
+
+	now = now.Add(2*time.Minute - time.Nanosecond)
+	provider.GetPriceInCogs("/Service/Method")
+	runRefreshes(&refreshes)
+	price, err := provider.GetPriceInCogs("/Service/Method")
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), price)
+	assert.Equal(t, 2, delegate.calls)
+	assert.Equal(t, 0, len(refreshes), "refresh should not be retried before backoff")
+
+	now = now.Add(5 * time.Second)
+	provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, 1, len(refreshes), "refresh should be retried after backoff")
+}
+
+func TestCachingPriceProviderBacksOffRepeatedFailures(t *testing.T) {
+	delegate := &priceProviderMock{price: big.NewInt(10)}
+	now := time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+	var refreshes []func()
+	provider := newTestJitteredPriceProvider(delegate, &now, &refreshes)
+	provider.GetPriceInCogs("/Service/Method")
+	delegate.err = errors.New("registry is unavailable")
+	now = now.Add(2 * time.Minute)
+
+	for second := 0; second < 60; second++ {
+		price, err := provider.GetPriceInCogs("/Service/Method")
+		assert.Nil(t, err)
+		assert.Equal(t, big.NewInt(10), price, "last loaded price is returned")
+		runRefreshes(&refreshes)
+		now = now.Add(time.Second)
+	}
+
+	// refreshes after 0, 5, 15 and 35 seconds
+	assert.Equal(t, 1+4, delegate.calls)
+}
+
+// blockingPriceProvider returns price after release is closed.
+type blockingPriceProvider struct {
+	mutex   sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (provider *blockingPriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	provider.mutex.Lock()
+	provider.calls++
+	provider.mutex.Unlock()
+	if method == "/Service/Slow" {
+		provider.started <- struct{}{}
+		<-provider.release
+	}
+	return big.NewInt(10), nil
+}
+
+func TestCachingPriceProviderMergesConcurrentLoads(t *testing.T) {
+	delegate := &blockingPriceProvider{started: make(chan struct{}, 10), release: make(chan struct{})}
+	provider := NewCachingPriceProvider(delegate, time.Minute, 0)
+	provider.GetPriceInCogs("/Service/Fast")
+
+	prices := make(chan *big.Int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			price, _ := provider.GetPriceInCogs("/Service/Slow")
+			prices <- price
+		}()
+	}
+	<-delegate.started
+
+	price, err := provider.GetPriceInCogs("/Service/Fast")
+	assert.Nil(t, err, "cached price is returned while other price is loaded")
+	assert.Equal(t, big.NewInt(10), price)
+
+	close(delegate.release)
+	assert.Equal(t, big.NewInt(10), <-prices)
+	assert.Equal(t, big.NewInt(10), <-prices)
+	assert.Equal(t, 2, delegate.calls, "concurrent loads are merged")
+}
+
+func TestFallbackPriceProvider(t *testing.T) {
+	primary := &priceProviderMock{price: big.NewInt(10)}
+	provider := NewFallbackPriceProvider(primary, NewFixedPriceProvider(big.NewInt(20)))
+
+	price, err := provider.GetPriceInCogs("/Service/Method")
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), price)
+
+	primary.err = errors.New("registry is unavailable")
+	price, err = provider.GetPriceInCogs("/Service/Method")
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(20), price)
+}
+
+func TestEffectivePriceTableOfPricingTable(t *testing.T) {
+	provider := NewReloadablePriceProvider(PriceTable{"default": big.NewInt(10), "/service/method": big.NewInt(20)})
+
+	table, err := EffectivePriceTable(provider)
+
+	assert.Nil(t, err)
+	assert.Equal(t, PriceTable{"default": big.NewInt(10), "/service/method": big.NewInt(20)}, table)
+	table["default"] = big.NewInt(1)
+	price, _ := provider.GetPriceInCogs("/other/method")
+	assert.Equal(t, big.NewInt(10), price, "returned table is a copy")
+}
+
+func TestEffectivePriceTableOfFixedPrice(t *testing.T) {
+	table, err := EffectivePriceTable(NewFixedPriceProvider(big.NewInt(7)))
+
+	assert.Nil(t, err)
+	assert.Equal(t, PriceTable{"default": big.NewInt(7)}, table)
+}
+
+func TestEffectivePriceTableError(t *testing.T) {
+	_, err := EffectivePriceTable(&priceProviderMock{err: errors.New("metadata is not available")})
+
+	assert.EqualError(t, err, "metadata is not available")
+}
@@ -0,0 +1,242 @@
+package escrow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultPriceKey is a key of the pricing table entry which contains price
+// of the methods which are not listed in the table explicitly.
+const DefaultPriceKey = "default"
+
+// PriceTable contains price in cogs for each full gRPC method name, for
+// example "/example_service.Calculator/add".
+type PriceTable map[string]*big.Int
+
+// ParsePriceTable parses and validates pricing table JSON.
+func ParsePriceTable(data []byte) (table PriceTable, err error) {
+	err = json.Unmarshal(data, &table)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse pricing table: %v", err)
+	}
+
+	if len(table) == 0 {
+		return nil, fmt.Errorf("pricing table is empty")
+	}
+	for method, price := range table {
+		if price == nil || price.Sign() < 0 {
+			return nil, fmt.Errorf("incorrect price %v of method \"%v\"", price, method)
+		}
+	}
+
+	return table, nil
+}
+
+// ServicePriceTables contains pricing table of each service by
+// "organization_id/service_id" key.
+type ServicePriceTables map[string]PriceTable
+
+// ServicePricingKey returns key of the service in ServicePriceTables.
+func ServicePricingKey(organizationID, serviceID string) string {
+	return organizationID + "/" + serviceID
+}
+
+// ParseServicePriceTables parses and validates pricing file JSON which
+// contains pricing table of each service.
+func ParseServicePriceTables(data []byte) (tables ServicePriceTables, err error) {
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse pricing file: %v", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("pricing file doesn't contain any service")
+	}
+
+	tables = make(ServicePriceTables, len(raw))
+	for service, tableJson := range raw {
+		parts := strings.Split(service, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("incorrect service \"%v\" in pricing file, \"organization_id/service_id\" is expected", service)
+		}
+		if tables[service], err = ParsePriceTable(tableJson); err != nil {
+			return nil, fmt.Errorf("service %v: %v", service, err)
+		}
+	}
+	return tables, nil
+}
+
+// ReloadablePriceProvider returns prices from the pricing table which can be
+// replaced at any time without locking calls in progress.
+type ReloadablePriceProvider struct {
+	table atomic.Value
+}
+
+// NewReloadablePriceProvider returns price provider which uses given
+// pricing table.
+func NewReloadablePriceProvider(table PriceTable) *ReloadablePriceProvider {
+	provider := &ReloadablePriceProvider{}
+	provider.table.Store(table)
+	return provider
+}
+
+// Update atomically replaces current pricing table by the new one.
+func (provider *ReloadablePriceProvider) Update(table PriceTable) {
+	provider.table.Store(table)
+}
+
+// GetPriceInCogs returns price of the method from pricing table or default
+// price if method is not listed.
+func (provider *ReloadablePriceProvider) GetPriceInCogs(method string) (price *big.Int, err error) {
+	table := provider.table.Load().(PriceTable)
+
+	price, ok := table[method]
+	if ok {
+		return price, nil
+	}
+
+	price, ok = table[DefaultPriceKey]
+	if ok {
+		return price, nil
+	}
+
+	return nil, fmt.Errorf("price of method \"%v\" is not found in pricing table", method)
+}
+
+// PriceTable returns copy of the current pricing table.
+func (provider *ReloadablePriceProvider) PriceTable() PriceTable {
+	table := provider.table.Load().(PriceTable)
+
+	result := make(PriceTable, len(table))
+	for method, price := range table {
+		result[method] = price
+	}
+	return result
+}
+
+func (provider *ReloadablePriceProvider) String() string {
+	return "pricing table"
+}
+
+// PricingFileWatcher reloads pricing tables of the services when pricing
+// file is changed.
+type PricingFileWatcher struct {
+	path string
+	// providers contains price provider of each service listed in the
+	// file, set of services is not changed after start
+	providers map[string]*ReloadablePriceProvider
+	watcher   *fsnotify.Watcher
+}
+
+// NewPricingFileWatcher reads pricing tables from the file and starts
+// watching the file. Pricing tables are reloaded on each file change; if new
+// version of the file cannot be parsed or doesn't contain one of the
+// services then previous tables are kept.
+func NewPricingFileWatcher(path string) (pricingWatcher *PricingFileWatcher, err error) {
+	tables, err := readServicePriceTables(path)
+	if err != nil {
+		return
+	}
+
+	// directory is watched to not lose the file when it is replaced by
+	// editor or by renaming new version
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		watcher.Close()
+		return
+	}
+
+	providers := make(map[string]*ReloadablePriceProvider, len(tables))
+	for service, table := range tables {
+		providers[service] = NewReloadablePriceProvider(table)
+	}
+	pricingWatcher = &PricingFileWatcher{
+		path:      path,
+		providers: providers,
+		watcher:   watcher,
+	}
+	go pricingWatcher.watch()
+
+	return pricingWatcher, nil
+}
+
+// PriceProvider returns price provider which uses the latest pricing table
+// of the service, error is returned if service is not listed in the file.
+func (pricingWatcher *PricingFileWatcher) PriceProvider(organizationID, serviceID string) (provider *ReloadablePriceProvider, err error) {
+	provider, ok := pricingWatcher.providers[ServicePricingKey(organizationID, serviceID)]
+	if !ok {
+		return nil, fmt.Errorf("service %v/%v is not found in pricing file %v", organizationID, serviceID, pricingWatcher.path)
+	}
+	return provider, nil
+}
+
+// Close stops watching pricing file.
+func (pricingWatcher *PricingFileWatcher) Close() {
+	pricingWatcher.watcher.Close()
+}
+
+func (pricingWatcher *PricingFileWatcher) watch() {
+	for {
+		select {
+		case event, ok := <-pricingWatcher.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(pricingWatcher.path) ||
+				event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			pricingWatcher.reload()
+		case err, ok := <-pricingWatcher.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).Error("Error watching pricing file")
+		}
+	}
+}
+
+func (pricingWatcher *PricingFileWatcher) reload() {
+	logger := log.WithField("path", pricingWatcher.path)
+
+	tables, err := readServicePriceTables(pricingWatcher.path)
+	if err != nil {
+		logger.WithError(err).Error("Cannot reload pricing file, previous prices are kept")
+		return
+	}
+	for service := range pricingWatcher.providers {
+		if _, ok := tables[service]; !ok {
+			logger.WithField("service", service).Error("Service is removed from pricing file, previous prices are kept")
+			return
+		}
+	}
+
+	for service, table := range tables {
+		if provider, ok := pricingWatcher.providers[service]; ok {
+			provider.Update(table)
+		} else {
+			logger.WithField("service", service).Warn("New service in pricing file is ignored until restart")
+		}
+	}
+	logger.Info("Pricing file reloaded")
+}
+
+func readServicePriceTables(path string) (tables ServicePriceTables, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read pricing file: %v", err)
+	}
+	return ParseServicePriceTables(data)
+}
@@ -0,0 +1,161 @@
+package escrow
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func TestParsePriceTable(t *testing.T) {
+	table, err := ParsePriceTable([]byte(`{"default": 10, "/Service/Method": 20}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, PriceTable{"default": big.NewInt(10), "/Service/Method": big.NewInt(20)}, table)
+}
+
+func TestParsePriceTableErrors(t *testing.T) {
+	_, err := ParsePriceTable([]byte(`{"default": 10`))
+	assert.NotNil(t, err)
+
+	_, err = ParsePriceTable([]byte(`{}`))
+	assert.Equal(t, "pricing table is empty", err.Error())
+
+	_, err = ParsePriceTable([]byte(`{"default": -1}`))
+	assert.Equal(t, "incorrect price -1 of method \"default\"", err.Error())
+}
+
+func TestParseServicePriceTables(t *testing.T) {
+	tables, err := ParseServicePriceTables([]byte(`{"org/first": {"default": 10}, "org/second": {"default": 20, "/Service/Method": 30}}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, ServicePriceTables{
+		"org/first":  {"default": big.NewInt(10)},
+		"org/second": {"default": big.NewInt(20), "/Service/Method": big.NewInt(30)},
+	}, tables)
+}
+
+func TestParseServicePriceTablesErrors(t *testing.T) {
+	_, err := ParseServicePriceTables([]byte(`{}`))
+	assert.EqualError(t, err, "pricing file doesn't contain any service")
+
+	_, err = ParseServicePriceTables([]byte(`{"default": 10}`))
+	assert.EqualError(t, err, "incorrect service \"default\" in pricing file, \"organization_id/service_id\" is expected")
+
+	_, err = ParseServicePriceTables([]byte(`{"org/service": {"default": -1}}`))
+	assert.EqualError(t, err, "service org/service: incorrect price -1 of method \"default\"")
+}
+
+func TestReloadablePriceProvider(t *testing.T) {
+	provider := NewReloadablePriceProvider(PriceTable{"default": big.NewInt(10), "/Service/Method": big.NewInt(20)})
+
+	price, _ := provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(20), price)
+	price, _ = provider.GetPriceInCogs("/Service/Other")
+	assert.Equal(t, big.NewInt(10), price)
+
+	provider.Update(PriceTable{"/Service/Method": big.NewInt(30)})
+
+	price, _ = provider.GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(30), price)
+	_, err := provider.GetPriceInCogs("/Service/Other")
+	assert.Equal(t, "price of method \"/Service/Other\" is not found in pricing table", err.Error())
+}
+
+func waitForPrice(provider PriceProvider, method string, expected *big.Int) (price *big.Int) {
+	for i := 0; i < 100; i++ {
+		price, _ = provider.GetPriceInCogs(method)
+		if price.Cmp(expected) == 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return
+}
+
+func writeTestPricingFile(t *testing.T, content string) (path string, remove func()) {
+	dir, err := ioutil.TempDir("", "pricing")
+	assert.Nil(t, err)
+	path = filepath.Join(dir, "pricing.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func testServicePriceProvider(t *testing.T, watcher *PricingFileWatcher, serviceID string) PriceProvider {
+	provider, err := watcher.PriceProvider("org", serviceID)
+	assert.Nil(t, err)
+	return provider
+}
+
+func TestPricingFileWatcherReloadsPrices(t *testing.T) {
+	path, remove := writeTestPricingFile(t, `{"org/service": {"default": 10}}`)
+	defer remove()
+
+	watcher, err := NewPricingFileWatcher(path)
+	assert.Nil(t, err)
+	defer watcher.Close()
+	provider := testServicePriceProvider(t, watcher, "service")
+	incomeValidator := NewPriceIncomeValidator(provider)
+	context := &handler.GrpcStreamContext{Info: &grpc.StreamServerInfo{FullMethod: "/Service/Method"}}
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context}))
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"org/service": {"default": 20}}`), 0600))
+
+	assert.Equal(t, big.NewInt(20), waitForPrice(provider, "/Service/Method", big.NewInt(20)))
+	assert.Nil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(20), GrpcContext: context}))
+	assert.NotNil(t, incomeValidator.Validate(&IncomeData{Income: big.NewInt(10), GrpcContext: context}))
+}
+
+func TestPricingFileWatcherKeepsPricesOnParseError(t *testing.T) {
+	path, remove := writeTestPricingFile(t, `{"org/service": {"default": 10}}`)
+	defer remove()
+
+	watcher, err := NewPricingFileWatcher(path)
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"org/service": {"default": `), 0600))
+	time.Sleep(100 * time.Millisecond)
+
+	price, err := testServicePriceProvider(t, watcher, "service").GetPriceInCogs("/Service/Method")
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(10), price)
+}
+
+func TestPricingFileWatcherPricesOfServices(t *testing.T) {
+	path, remove := writeTestPricingFile(t, `{"org/first": {"default": 10}, "org/second": {"default": 20}}`)
+	defer remove()
+
+	watcher, err := NewPricingFileWatcher(path)
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	price, _ := testServicePriceProvider(t, watcher, "first").GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(10), price)
+	price, _ = testServicePriceProvider(t, watcher, "second").GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(20), price)
+	_, err = watcher.PriceProvider("org", "third")
+	assert.EqualError(t, err, "service org/third is not found in pricing file "+path)
+}
+
+func TestPricingFileWatcherKeepsPricesWhenServiceIsRemoved(t *testing.T) {
+	path, remove := writeTestPricingFile(t, `{"org/first": {"default": 10}, "org/second": {"default": 20}}`)
+	defer remove()
+
+	watcher, err := NewPricingFileWatcher(path)
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"org/first": {"default": 30}}`), 0600))
+	time.Sleep(100 * time.Millisecond)
+
+	price, _ := testServicePriceProvider(t, watcher, "first").GetPriceInCogs("/Service/Method")
+	assert.Equal(t, big.NewInt(10), price)
+}
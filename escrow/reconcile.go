@@ -0,0 +1,97 @@
+package escrow
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ChannelReconciler updates stored channels with their state in blockchain:
+// deposit and expiration changed by the sender and nonce incremented by the
+// claim. Channels are read from blockchain by the pool of workers, so number
+// of concurrent chain reads doesn't exceed the concurrency.
+type ChannelReconciler struct {
+	storage     *PaymentChannelStorage
+	readChannel func(ctx context.Context, key *PaymentChannelKey) (channel *PaymentChannelData, ok bool, err error)
+	concurrency int
+}
+
+// NewChannelReconciler returns new instance of ChannelReconciler which reads
+// up to concurrency channels from blockchain at once.
+func NewChannelReconciler(storage *PaymentChannelStorage, reader *BlockchainChannelReader, concurrency int) *ChannelReconciler {
+	return &ChannelReconciler{
+		storage:     storage,
+		readChannel: reader.GetChannelStateFromBlockchain,
+		concurrency: concurrency,
+	}
+}
+
+// Reconcile merges each stored channel with its blockchain state using
+// MergeStorageAndBlockchainChannelState and returns number of the updated
+// channels. Channel is updated by compare and swap, so the channel which is
+// changed by payment meanwhile is left to the next reconciliation. Channels
+// which cannot be read are logged and skipped.
+func (reconciler *ChannelReconciler) Reconcile(ctx context.Context) (updated int, err error) {
+	channels, err := reconciler.storage.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	concurrency := reconciler.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan *PaymentChannelData)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for channel := range jobs {
+				if reconciler.reconcileChannel(ctx, channel) {
+					mutex.Lock()
+					updated++
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for _, channel := range channels {
+		jobs <- channel
+	}
+	close(jobs)
+	wg.Wait()
+	return updated, nil
+}
+
+func (reconciler *ChannelReconciler) reconcileChannel(ctx context.Context, stored *PaymentChannelData) (updated bool) {
+	log := log.WithField("channelId", stored.ChannelID)
+	key := &PaymentChannelKey{ID: stored.ChannelID}
+	blockchainChannel, ok, err := reconciler.readChannel(ctx, key)
+	if err != nil {
+		log.WithError(err).Warn("Cannot read channel from blockchain to reconcile it")
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	merged := MergeStorageAndBlockchainChannelState(stored, blockchainChannel)
+	if merged.Nonce.Cmp(stored.Nonce) == 0 && merged.FullAmount.Cmp(stored.FullAmount) == 0 &&
+		merged.Expiration.Cmp(stored.Expiration) == 0 {
+		return false
+	}
+	ok, err = reconciler.storage.CompareAndSwap(key, stored, merged)
+	if err != nil {
+		log.WithError(err).Warn("Cannot update reconciled channel")
+		return false
+	}
+	if !ok {
+		log.Debug("Channel is changed during reconciliation, it will be reconciled next time")
+		return false
+	}
+	log.WithField("channel", merged).Debug("Channel is updated from blockchain")
+	return true
+}
@@ -0,0 +1,122 @@
+package escrow
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reconcileTestChannel(id int64, nonce int64, amount int64) *PaymentChannelData {
+	return &PaymentChannelData{
+		ChannelID:        big.NewInt(id),
+		Nonce:            big.NewInt(nonce),
+		State:            Open,
+		FullAmount:       big.NewInt(amount),
+		Expiration:       big.NewInt(1000),
+		AuthorizedAmount: big.NewInt(10),
+	}
+}
+
+func newTestReconciler(t *testing.T, concurrency int, stored ...*PaymentChannelData) (reconciler *ChannelReconciler, storage *PaymentChannelStorage) {
+	storage = NewPaymentChannelStorage(NewMemStorage())
+	for _, channel := range stored {
+		assert.Nil(t, storage.Put(&PaymentChannelKey{ID: channel.ChannelID}, channel))
+	}
+	return &ChannelReconciler{storage: storage, concurrency: concurrency}, storage
+}
+
+func TestChannelReconcilerUpdatesChannels(t *testing.T) {
+	reconciler, storage := newTestReconciler(t, 2,
+		reconcileTestChannel(1, 0, 100),
+		reconcileTestChannel(2, 0, 100),
+		reconcileTestChannel(3, 0, 100),
+		reconcileTestChannel(4, 0, 100))
+	reconciler.readChannel = func(ctx context.Context, key *PaymentChannelKey) (*PaymentChannelData, bool, error) {
+		switch key.ID.Int64() {
+		case 1:
+			channel := reconcileTestChannel(1, 0, 150)
+			channel.Expiration = big.NewInt(2000)
+			channel.AuthorizedAmount = big.NewInt(0)
+			return channel, true, nil
+		case 2:
+			channel := reconcileTestChannel(2, 1, 90)
+			channel.AuthorizedAmount = big.NewInt(0)
+			return channel, true, nil
+		case 3:
+			return nil, false, errors.New("node is unavailable")
+		default:
+			channel := reconcileTestChannel(4, 0, 100)
+			channel.AuthorizedAmount = big.NewInt(0)
+			return channel, true, nil
+		}
+	}
+
+	updated, err := reconciler.Reconcile(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, updated)
+	deposited, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(1)})
+	assert.Equal(t, big.NewInt(150), deposited.FullAmount)
+	assert.Equal(t, big.NewInt(2000), deposited.Expiration)
+	assert.Equal(t, big.NewInt(10), deposited.AuthorizedAmount, "payments are kept")
+	claimed, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(2)})
+	assert.Equal(t, big.NewInt(1), claimed.Nonce)
+	assert.Equal(t, big.NewInt(0), claimed.AuthorizedAmount)
+	failed, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(3)})
+	assert.Equal(t, reconcileTestChannel(3, 0, 100), failed)
+}
+
+func TestChannelReconcilerSkipsChannelChangedByPayment(t *testing.T) {
+	reconciler, storage := newTestReconciler(t, 1, reconcileTestChannel(1, 0, 100))
+	reconciler.readChannel = func(ctx context.Context, key *PaymentChannelKey) (*PaymentChannelData, bool, error) {
+		paid := reconcileTestChannel(1, 0, 100)
+		paid.AuthorizedAmount = big.NewInt(20)
+		assert.Nil(t, storage.Put(key, paid))
+		return reconcileTestChannel(1, 0, 150), true, nil
+	}
+
+	updated, err := reconciler.Reconcile(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, updated)
+	channel, _, _ := storage.Get(&PaymentChannelKey{ID: big.NewInt(1)})
+	assert.Equal(t, big.NewInt(20), channel.AuthorizedAmount)
+	assert.Equal(t, big.NewInt(100), channel.FullAmount)
+}
+
+func TestChannelReconcilerBoundsConcurrentReads(t *testing.T) {
+	var channels []*PaymentChannelData
+	for id := int64(1); id <= 20; id++ {
+		channels = append(channels, reconcileTestChannel(id, 0, 100))
+	}
+	reconciler, _ := newTestReconciler(t, 3, channels...)
+	var mutex sync.Mutex
+	active, maxActive, reads := 0, 0, 0
+	reconciler.readChannel = func(ctx context.Context, key *PaymentChannelKey) (*PaymentChannelData, bool, error) {
+		mutex.Lock()
+		active++
+		reads++
+		if active > maxActive {
+			maxActive = active
+		}
+		mutex.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+		return nil, false, nil
+	}
+
+	_, err := reconciler.Reconcile(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 20, reads)
+	assert.Equal(t, 3, maxActive)
+}
@@ -0,0 +1,168 @@
+package escrow
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+// secretHeaders are not recorded because they allow spending client's funds
+// or authorizing calls.
+var secretHeaders = []string{
+	PaymentChannelSignatureHeader,
+	handler.OfflineAuthTokenHeader,
+}
+
+// ValidationDecision is a result of the income validation.
+type ValidationDecision struct {
+	// Allowed is true if call is allowed by validator
+	Allowed bool `json:"allowed"`
+	// Code is an error code if call is rejected
+	Code PaymentErrorCode `json:"code,omitempty"`
+	// Message is an error message if call is rejected
+	Message string `json:"message,omitempty"`
+}
+
+// ValidationRecord contains input and decision of the income validator.
+type ValidationRecord struct {
+	Method   string              `json:"method"`
+	Income   *big.Int            `json:"income"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+	Decision ValidationDecision  `json:"decision"`
+}
+
+func newValidationDecision(err error) ValidationDecision {
+	if err == nil {
+		return ValidationDecision{Allowed: true}
+	}
+	if paymentErr, ok := err.(*PaymentError); ok {
+		return ValidationDecision{Code: paymentErr.Code, Message: paymentErr.Message}
+	}
+	return ValidationDecision{Code: Internal, Message: err.Error()}
+}
+
+func newValidationRecord(data *IncomeData, err error) *ValidationRecord {
+	record := &ValidationRecord{
+		Method:   data.method(),
+		Income:   data.Income,
+		Decision: newValidationDecision(err),
+	}
+	if data.GrpcContext != nil && len(data.GrpcContext.MD) > 0 {
+		md := data.GrpcContext.MD.Copy()
+		for _, header := range secretHeaders {
+			delete(md, header)
+		}
+		record.Metadata = md
+	}
+	return record
+}
+
+// IncomeData restores validator input from the record.
+func (record *ValidationRecord) IncomeData() *IncomeData {
+	return &IncomeData{
+		Income: record.Income,
+		GrpcContext: &handler.GrpcStreamContext{
+			MD:   metadata.MD(record.Metadata),
+			Info: &grpc.StreamServerInfo{FullMethod: record.Method},
+		},
+	}
+}
+
+type recordingIncomeValidator struct {
+	delegate   IncomeValidator
+	sampleRate float64
+	sample     func() float64
+	mutex      sync.Mutex
+	encoder    *json.Encoder
+}
+
+// NewRecordingIncomeValidator returns income validator which writes input
+// and decision of the delegate validator into the writer as JSON lines.
+// Only sampleRate part of the calls is recorded, secret headers are removed.
+// Records can be replayed against different validator using
+// ReplayValidationRecords.
+func NewRecordingIncomeValidator(delegate IncomeValidator, writer io.Writer, sampleRate float64) (validator IncomeValidator) {
+	return &recordingIncomeValidator{
+		delegate:   delegate,
+		sampleRate: sampleRate,
+		sample:     rand.Float64,
+		encoder:    json.NewEncoder(writer),
+	}
+}
+
+func (validator *recordingIncomeValidator) Validate(data *IncomeData) (err error) {
+	err = validator.delegate.Validate(data)
+
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	if validator.sample() >= validator.sampleRate {
+		return
+	}
+	if e := validator.encoder.Encode(newValidationRecord(data, err)); e != nil {
+		log.WithError(e).Warn("Cannot record income validation decision")
+	}
+	return
+}
+
+func (validator *recordingIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:       "record",
+		Parameters: map[string]string{"sampleRate": strconv.FormatFloat(validator.sampleRate, 'g', -1, 64)},
+		Children:   []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
+
+// ReadValidationRecords reads records written by recording income validator.
+func ReadValidationRecords(reader io.Reader) (records []*ValidationRecord, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &ValidationRecord{}
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, fmt.Errorf("cannot parse validation record at line %v: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// ReplayResult contains recorded decision and decision made by the validator
+// on replay.
+type ReplayResult struct {
+	Record   *ValidationRecord
+	Decision ValidationDecision
+}
+
+// Changed returns true if validator made different decision on replay. Error
+// messages are not compared as they may contain prices.
+func (result *ReplayResult) Changed() bool {
+	return result.Record.Decision.Allowed != result.Decision.Allowed ||
+		result.Record.Decision.Code != result.Decision.Code
+}
+
+// ReplayValidationRecords runs recorded validator inputs through the
+// validator and returns decisions made.
+func ReplayValidationRecords(records []*ValidationRecord, validator IncomeValidator) (results []*ReplayResult) {
+	for _, record := range records {
+		results = append(results, &ReplayResult{
+			Record:   record,
+			Decision: newValidationDecision(validator.Validate(record.IncomeData())),
+		})
+	}
+	return results
+}
@@ -0,0 +1,99 @@
+package escrow
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+func incomeData(method string, income int64) *IncomeData {
+	return &IncomeData{
+		Income: big.NewInt(income),
+		GrpcContext: &handler.GrpcStreamContext{
+			MD: metadata.Pairs(
+				PaymentChannelIDHeader, "42",
+				PaymentChannelSignatureHeader, "secret-signature",
+			),
+			Info: &grpc.StreamServerInfo{FullMethod: method},
+		},
+	}
+}
+
+func TestRecordAndReplayValidationDecisions(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	recorder := NewRecordingIncomeValidator(NewIncomeValidator(big.NewInt(10)), buffer, 1)
+
+	assert.Nil(t, recorder.Validate(incomeData("/service/method", 10)))
+	assert.NotNil(t, recorder.Validate(incomeData("/service/method", 5)))
+	assert.NotNil(t, recorder.Validate(incomeData("/service/method", 20)))
+
+	records, err := ReadValidationRecords(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(records))
+
+	results := ReplayValidationRecords(records, NewIncomeValidator(big.NewInt(20)))
+
+	assert.Equal(t, 3, len(results))
+	assert.True(t, results[0].Changed())
+	assert.Equal(t, ValidationDecision{Allowed: true}, results[0].Record.Decision)
+	assert.Equal(t, newValidationDecision(incomeMismatchError(big.NewInt(10), big.NewInt(20))), results[0].Decision)
+	assert.False(t, results[1].Changed())
+	assert.True(t, results[2].Changed())
+	assert.Equal(t, ValidationDecision{Allowed: true}, results[2].Decision)
+}
+
+func TestRecordedDataRoundTrip(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	recorder := NewRecordingIncomeValidator(NewIncomeValidator(big.NewInt(10)), buffer, 1)
+
+	recorder.Validate(incomeData("/service/method", 10))
+	records, err := ReadValidationRecords(buffer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &ValidationRecord{
+		Method:   "/service/method",
+		Income:   big.NewInt(10),
+		Metadata: map[string][]string{PaymentChannelIDHeader: {"42"}},
+		Decision: ValidationDecision{Allowed: true},
+	}, records[0])
+	assert.Equal(t, &IncomeData{
+		Income: big.NewInt(10),
+		GrpcContext: &handler.GrpcStreamContext{
+			MD:   metadata.Pairs(PaymentChannelIDHeader, "42"),
+			Info: &grpc.StreamServerInfo{FullMethod: "/service/method"},
+		},
+	}, records[0].IncomeData())
+}
+
+func TestRecordingIsSampled(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	recorder := NewRecordingIncomeValidator(NewIncomeValidator(big.NewInt(10)), buffer, 0.5).(*recordingIncomeValidator)
+	samples := []float64{0.1, 0.7, 0.4}
+	recorder.sample = func() float64 {
+		sample := samples[0]
+		samples = samples[1:]
+		return sample
+	}
+
+	recorder.Validate(incomeData("/service/first", 10))
+	recorder.Validate(incomeData("/service/second", 10))
+	recorder.Validate(incomeData("/service/third", 10))
+
+	records, err := ReadValidationRecords(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "/service/first", records[0].Method)
+	assert.Equal(t, "/service/third", records[1].Method)
+}
+
+func TestReadValidationRecordsIncorrectLine(t *testing.T) {
+	_, err := ReadValidationRecords(bytes.NewBufferString("{\"method\": \"/service/method\"}\nnot a json\n"))
+
+	assert.EqualError(t, err, "cannot parse validation record at line 2: invalid character 'o' in literal null (expecting 'u')")
+}
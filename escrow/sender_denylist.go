@@ -0,0 +1,43 @@
+package escrow
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SenderDenylist contains addresses of the senders which calls are rejected.
+// List can be replaced at any time without locking calls in progress.
+type SenderDenylist struct {
+	senders atomic.Value
+}
+
+// NewSenderDenylist returns denylist which blocks given senders.
+func NewSenderDenylist(senders []common.Address) *SenderDenylist {
+	denylist := &SenderDenylist{}
+	denylist.Update(senders)
+	return denylist
+}
+
+// Update atomically replaces list of blocked senders by the new one.
+func (denylist *SenderDenylist) Update(senders []common.Address) {
+	blocked := make(map[common.Address]bool, len(senders))
+	for _, sender := range senders {
+		blocked[sender] = true
+	}
+	denylist.senders.Store(blocked)
+}
+
+// Blocked returns true if calls of the sender should be rejected. Nil
+// denylist blocks nobody.
+func (denylist *SenderDenylist) Blocked(sender common.Address) bool {
+	if denylist == nil {
+		return false
+	}
+	return denylist.senders.Load().(map[common.Address]bool)[sender]
+}
+
+// Len returns number of blocked senders.
+func (denylist *SenderDenylist) Len() int {
+	return len(denylist.senders.Load().(map[common.Address]bool))
+}
@@ -0,0 +1,113 @@
+package escrow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ChannelStateSnapshotVersion is a version of the format of the exported
+// channel state.
+const ChannelStateSnapshotVersion = 1
+
+// channelStateSnapshot is a portable representation of the payment channel
+// storage content.
+type channelStateSnapshot struct {
+	Version  int                   `json:"version"`
+	Channels []*PaymentChannelData `json:"channels"`
+}
+
+// ImportConflictPolicy defines what to do when imported channel is already
+// present in the storage.
+type ImportConflictPolicy int
+
+const (
+	// ImportFailOnConflict fails import before writing any channel if any of
+	// imported channels is present in the storage.
+	ImportFailOnConflict ImportConflictPolicy = iota
+	// ImportSkipExisting keeps channels which are present in the storage and
+	// imports others.
+	ImportSkipExisting
+	// ImportOverwrite replaces channels which are present in the storage by
+	// imported ones.
+	ImportOverwrite
+)
+
+// ExportChannelState writes state of all payment channels to w in JSON
+// format. Channels are sorted by id.
+func (storage *PaymentChannelStorage) ExportChannelState(w io.Writer) (err error) {
+	channels, err := storage.GetAll()
+	if err != nil {
+		return
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].ChannelID.Cmp(channels[j].ChannelID) < 0
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&channelStateSnapshot{
+		Version:  ChannelStateSnapshotVersion,
+		Channels: channels,
+	})
+}
+
+// ImportChannelState reads channels state exported by ExportChannelState
+// from r and puts it into the storage. Channels which are already present in
+// the storage are handled according to policy. It returns number of
+// channels written. Import is not atomic: if storage fails in the middle
+// then channels imported before failure are kept.
+func (storage *PaymentChannelStorage) ImportChannelState(r io.Reader, policy ImportConflictPolicy) (imported int, err error) {
+	snapshot := &channelStateSnapshot{}
+	if err = json.NewDecoder(r).Decode(snapshot); err != nil {
+		return 0, fmt.Errorf("cannot parse channel state snapshot: %v", err)
+	}
+	if snapshot.Version != ChannelStateSnapshotVersion {
+		return 0, fmt.Errorf("unsupported channel state snapshot version %v, expected %v", snapshot.Version, ChannelStateSnapshotVersion)
+	}
+	for _, channel := range snapshot.Channels {
+		if channel == nil || channel.ChannelID == nil {
+			return 0, fmt.Errorf("channel state snapshot contains channel without id")
+		}
+	}
+
+	if policy == ImportFailOnConflict {
+		for _, channel := range snapshot.Channels {
+			_, ok, e := storage.Get(&PaymentChannelKey{ID: channel.ChannelID})
+			if e != nil {
+				return 0, e
+			}
+			if ok {
+				return 0, fmt.Errorf("channel %v is already present in storage", channel.ChannelID)
+			}
+		}
+	}
+
+	for _, channel := range snapshot.Channels {
+		key := &PaymentChannelKey{ID: channel.ChannelID}
+		ok := true
+		switch policy {
+		case ImportOverwrite:
+			err = storage.Put(key, channel)
+		case ImportSkipExisting:
+			ok, err = storage.PutIfAbsent(key, channel)
+		case ImportFailOnConflict:
+			ok, err = storage.PutIfAbsent(key, channel)
+			if err == nil && !ok {
+				return imported, fmt.Errorf("channel %v is already present in storage", channel.ChannelID)
+			}
+		default:
+			return imported, fmt.Errorf("unknown import conflict policy %v", policy)
+		}
+		if err != nil {
+			return
+		}
+		if ok {
+			imported++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,152 @@
+package escrow
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ChannelStateSnapshotSuite struct {
+	suite.Suite
+
+	senderAddress common.Address
+	storage       *PaymentChannelStorage
+	target        *PaymentChannelStorage
+}
+
+func TestChannelStateSnapshotSuite(t *testing.T) {
+	suite.Run(t, new(ChannelStateSnapshotSuite))
+}
+
+func (suite *ChannelStateSnapshotSuite) SetupSuite() {
+	suite.senderAddress = crypto.PubkeyToAddress(GenerateTestPrivateKey().PublicKey)
+}
+
+func (suite *ChannelStateSnapshotSuite) SetupTest() {
+	suite.storage = NewPaymentChannelStorage(NewMemStorage())
+	suite.target = NewPaymentChannelStorage(NewMemStorage())
+}
+
+func (suite *ChannelStateSnapshotSuite) key(channelID int64) *PaymentChannelKey {
+	return &PaymentChannelKey{ID: big.NewInt(channelID)}
+}
+
+func (suite *ChannelStateSnapshotSuite) channelWithID(id int64, nonce int64) *PaymentChannelData {
+	return &PaymentChannelData{
+		ChannelID:        big.NewInt(id),
+		Nonce:            big.NewInt(nonce),
+		Sender:           suite.senderAddress,
+		Recipient:        suite.senderAddress,
+		GroupID:          [32]byte{123},
+		FullAmount:       big.NewInt(12345),
+		Expiration:       big.NewInt(100),
+		Signer:           suite.senderAddress,
+		AuthorizedAmount: big.NewInt(100),
+		Signature:        []byte{1, 2, 3},
+	}
+}
+
+func (suite *ChannelStateSnapshotSuite) export() *bytes.Buffer {
+	buffer := &bytes.Buffer{}
+	err := suite.storage.ExportChannelState(buffer)
+	assert.Nil(suite.T(), err)
+	return buffer
+}
+
+func (suite *ChannelStateSnapshotSuite) TestRoundTrip() {
+	channelA := suite.channelWithID(2, 1)
+	channelB := suite.channelWithID(1, 5)
+	suite.storage.Put(suite.key(2), channelA)
+	suite.storage.Put(suite.key(1), channelB)
+
+	imported, err := suite.target.ImportChannelState(suite.export(), ImportFailOnConflict)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, imported)
+	channel, ok, err := suite.target.Get(suite.key(2))
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), channelA, channel)
+	channel, ok, err = suite.target.Get(suite.key(1))
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), channelB, channel)
+}
+
+func (suite *ChannelStateSnapshotSuite) TestExportFormat() {
+	suite.storage.Put(suite.key(2), suite.channelWithID(2, 1))
+	suite.storage.Put(suite.key(1), suite.channelWithID(1, 1))
+
+	exported := suite.export().String()
+
+	assert.True(suite.T(), strings.HasPrefix(exported, "{\n  \"version\": 1,\n"), exported)
+	assert.True(suite.T(), strings.Index(exported, "\"ChannelID\": 1,") < strings.Index(exported, "\"ChannelID\": 2,"), "channels are not sorted")
+}
+
+func (suite *ChannelStateSnapshotSuite) TestImportFailOnConflict() {
+	suite.storage.Put(suite.key(1), suite.channelWithID(1, 1))
+	suite.storage.Put(suite.key(2), suite.channelWithID(2, 1))
+	existing := suite.channelWithID(2, 7)
+	suite.target.Put(suite.key(2), existing)
+
+	imported, err := suite.target.ImportChannelState(suite.export(), ImportFailOnConflict)
+
+	assert.Equal(suite.T(), "channel 2 is already present in storage", err.Error())
+	assert.Equal(suite.T(), 0, imported)
+	_, ok, _ := suite.target.Get(suite.key(1))
+	assert.False(suite.T(), ok, "channel is imported despite conflict")
+	channel, _, _ := suite.target.Get(suite.key(2))
+	assert.Equal(suite.T(), existing, channel)
+}
+
+func (suite *ChannelStateSnapshotSuite) TestImportSkipExisting() {
+	suite.storage.Put(suite.key(1), suite.channelWithID(1, 1))
+	suite.storage.Put(suite.key(2), suite.channelWithID(2, 1))
+	existing := suite.channelWithID(2, 7)
+	suite.target.Put(suite.key(2), existing)
+
+	imported, err := suite.target.ImportChannelState(suite.export(), ImportSkipExisting)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, imported)
+	channel, _, _ := suite.target.Get(suite.key(1))
+	assert.Equal(suite.T(), suite.channelWithID(1, 1), channel)
+	channel, _, _ = suite.target.Get(suite.key(2))
+	assert.Equal(suite.T(), existing, channel)
+}
+
+func (suite *ChannelStateSnapshotSuite) TestImportOverwrite() {
+	suite.storage.Put(suite.key(2), suite.channelWithID(2, 1))
+	suite.target.Put(suite.key(2), suite.channelWithID(2, 7))
+
+	imported, err := suite.target.ImportChannelState(suite.export(), ImportOverwrite)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, imported)
+	channel, _, _ := suite.target.Get(suite.key(2))
+	assert.Equal(suite.T(), suite.channelWithID(2, 1), channel)
+}
+
+func (suite *ChannelStateSnapshotSuite) TestImportUnsupportedVersion() {
+	_, err := suite.target.ImportChannelState(strings.NewReader(`{"version": 2, "channels": []}`), ImportOverwrite)
+
+	assert.Equal(suite.T(), "unsupported channel state snapshot version 2, expected 1", err.Error())
+}
+
+func (suite *ChannelStateSnapshotSuite) TestImportChannelWithoutID() {
+	_, err := suite.target.ImportChannelState(strings.NewReader(`{"version": 1, "channels": [{"Nonce": 1}]}`), ImportOverwrite)
+
+	assert.Equal(suite.T(), "channel state snapshot contains channel without id", err.Error())
+}
+
+func (suite *ChannelStateSnapshotSuite) TestImportIncorrectJson() {
+	_, err := suite.target.ImportChannelState(strings.NewReader(`{"version": `), ImportOverwrite)
+
+	assert.NotNil(suite.T(), err)
+}
@@ -0,0 +1,102 @@
+package escrow
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+type spendCapIncomeValidator struct {
+	delegate IncomeValidator
+	storage  CounterStore
+	cap      *big.Int
+	period   time.Duration
+	now      func() time.Time
+}
+
+// NewSpendCapIncomeValidator returns income validator which limits total
+// income validated from the same sender during the period. Income accepted
+// by delegate validator is added to the sender spend; call which makes spend
+// greater than cap is rejected with ResourceExhausted error. Spend is added
+// at validation so concurrent calls cannot exceed the cap, and it is
+// subtracted back if the payment of the call is rolled back. Periods are
+// aligned to the multiples of the period duration since zero time. Spend is
+// kept in the storage under the sender key and expires at the end of the
+// period.
+func NewSpendCapIncomeValidator(delegate IncomeValidator, storage CounterStore, cap *big.Int, period time.Duration) (validator IncomeValidator) {
+	return &spendCapIncomeValidator{
+		delegate: delegate,
+		storage:  storage,
+		cap:      cap,
+		period:   period,
+		now:      time.Now,
+	}
+}
+
+func (validator *spendCapIncomeValidator) Validate(data *IncomeData) (err error) {
+	if err = validator.delegate.Validate(data); err != nil {
+		return
+	}
+	if data.MessageCount > 1 {
+		// income of the streaming call doesn't change after the stream is
+		// started and it is counted once when the first message is validated
+		return
+	}
+
+	now := validator.now().UTC()
+	periodEnd := now.Truncate(validator.period).Add(validator.period)
+	key := "spend/" + data.Sender.Hex()
+	spent, err := validator.storage.Increment(data.context(), key, data.Income, periodEnd.Sub(now))
+	if err != nil {
+		return NewPaymentError(Internal, "cannot update spend of the sender: %v", err)
+	}
+	if spent.Cmp(validator.cap) > 0 {
+		// rejected income is subtracted back, concurrent calls may be
+		// rejected meanwhile but accepted spend never exceeds the cap; it is
+		// subtracted even if validation times out meanwhile
+		spent, err = validator.storage.Increment(context.Background(), key, new(big.Int).Neg(data.Income), periodEnd.Sub(now))
+		if err != nil {
+			return NewPaymentError(Internal, "cannot update spend of the sender: %v", err)
+		}
+		e := NewPaymentError(ResourceExhausted, "sender %v spent %v of %v allowed until %v", data.Sender.Hex(), spent, validator.cap, periodEnd.Format(time.RFC3339))
+		// expected is the income which sender is still allowed to spend
+		e.Details = map[string]interface{}{
+			"spent":    spent,
+			"cap":      validator.cap,
+			"income":   data.Income,
+			"expected": new(big.Int).Sub(validator.cap, spent),
+			"received": data.Income,
+		}
+		e.Reason = config.ValidationFailureSpendCap
+		return e
+	}
+
+	income := data.Income
+	data.onRollback(func() {
+		// spend of the finished period is not refunded into the next one
+		now := validator.now().UTC()
+		if !now.Before(periodEnd) {
+			return
+		}
+		if _, err := validator.storage.Increment(context.Background(), key, new(big.Int).Neg(income), periodEnd.Sub(now)); err != nil {
+			log.WithError(err).WithField("sender", data.Sender.Hex()).Error("Cannot refund spend of the rolled back payment")
+		}
+	})
+	return nil
+}
+
+func (validator *spendCapIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type: "spend_cap",
+		Parameters: map[string]string{
+			"cap":    fmt.Sprintf("%v", validator.cap),
+			"period": validator.period.String(),
+		},
+		Children: []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
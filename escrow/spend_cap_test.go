@@ -0,0 +1,140 @@
+package escrow
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSpendCapNow = time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestSpendCapIncomeValidator(delegate IncomeValidator, cap int64, now *time.Time) *spendCapIncomeValidator {
+	storage := NewAtomicCounterStore(NewMemStorage()).(*atomicCounterStore)
+	storage.now = func() time.Time { return *now }
+	validator := NewSpendCapIncomeValidator(delegate, storage, big.NewInt(cap), 24*time.Hour).(*spendCapIncomeValidator)
+	validator.now = func() time.Time { return *now }
+	return validator
+}
+
+func spendFrom(sender common.Address, income int64) *IncomeData {
+	return &IncomeData{Income: big.NewInt(income), Sender: sender}
+}
+
+var testSpendSender = common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+func TestSpendCapAccumulatesUpToCap(t *testing.T) {
+	now := testSpendCapNow
+	validator := newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 10, &now)
+
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 4)))
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 4)))
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 2)))
+	err := validator.Validate(spendFrom(testSpendSender, 1))
+
+	assert.Equal(t, ResourceExhausted, err.(*PaymentError).Code)
+	assert.Equal(t, "sender 0x0000000000000000000000000000000000000001 spent 10 of 10 allowed until 2018-10-16T00:00:00Z", err.(*PaymentError).Message)
+}
+
+func TestSpendCapRejectsCallExceedingCap(t *testing.T) {
+	now := testSpendCapNow
+	validator := newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 10, &now)
+
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 8)))
+	err := validator.Validate(spendFrom(testSpendSender, 3))
+	assert.Equal(t, ResourceExhausted, err.(*PaymentError).Code)
+	assert.Equal(t, big.NewInt(2), err.(*PaymentError).Details["expected"])
+	assert.Equal(t, big.NewInt(3), err.(*PaymentError).Details["received"])
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 2)))
+}
+
+func TestSpendCapIsPerSender(t *testing.T) {
+	now := testSpendCapNow
+	validator := newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 10, &now)
+	anotherSender := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 10)))
+	assert.Nil(t, validator.Validate(spendFrom(anotherSender, 10)))
+}
+
+func TestSpendCapIsResetInNextPeriod(t *testing.T) {
+	now := testSpendCapNow
+	validator := newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 10, &now)
+
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 10)))
+	now = now.Add(12 * time.Hour)
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 10)))
+}
+
+func TestSpendCapDoesNotCountRejectedIncome(t *testing.T) {
+	now := testSpendCapNow
+	delegate := &incomeValidatorMockType{err: NewPaymentError(Unauthenticated, "income 10 does not equal to price 5")}
+	validator := newTestSpendCapIncomeValidator(delegate, 10, &now)
+
+	err := validator.Validate(spendFrom(testSpendSender, 10))
+	delegate.err = nil
+
+	assert.Equal(t, Unauthenticated, err.(*PaymentError).Code)
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 10)))
+}
+
+func rollbackableSpendFrom(sender common.Address, income int64) *IncomeData {
+	data := spendFrom(sender, income)
+	data.rollbacks = &[]func(){}
+	return data
+}
+
+func TestSpendCapRefundsRolledBackIncome(t *testing.T) {
+	now := testSpendCapNow
+	validator := newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 10, &now)
+
+	data := rollbackableSpendFrom(testSpendSender, 8)
+	assert.Nil(t, validator.Validate(data))
+	data.rollback()
+	data.rollback()
+
+	assert.Nil(t, validator.Validate(rollbackableSpendFrom(testSpendSender, 10)))
+	assert.Equal(t, ResourceExhausted, validator.Validate(spendFrom(testSpendSender, 1)).(*PaymentError).Code, "income is refunded once")
+}
+
+func TestSpendCapDoesNotRefundIntoNextPeriod(t *testing.T) {
+	now := testSpendCapNow
+	validator := newTestSpendCapIncomeValidator(&incomeValidatorMockType{}, 10, &now)
+
+	data := rollbackableSpendFrom(testSpendSender, 8)
+	assert.Nil(t, validator.Validate(data))
+	now = now.Add(12 * time.Hour)
+	data.rollback()
+
+	assert.Nil(t, validator.Validate(spendFrom(testSpendSender, 10)))
+	assert.Equal(t, ResourceExhausted, validator.Validate(spendFrom(testSpendSender, 1)).(*PaymentError).Code)
+}
+
+type failingAtomicStorage struct {
+	AtomicStorage
+}
+
+func (storage *failingAtomicStorage) Get(key string) (value string, ok bool, err error) {
+	return "", false, errors.New("storage is unavailable")
+}
+
+func TestSpendCapStorageError(t *testing.T) {
+	validator := NewSpendCapIncomeValidator(&incomeValidatorMockType{}, NewAtomicCounterStore(&failingAtomicStorage{}), big.NewInt(10), time.Hour)
+
+	err := validator.Validate(spendFrom(testSpendSender, 1))
+
+	assert.Equal(t, NewPaymentError(Internal, "cannot update spend of the sender: storage is unavailable"), err)
+}
+
+func TestDescribeSpendCapIncomeValidator(t *testing.T) {
+	validator := NewSpendCapIncomeValidator(&incomeValidatorMockType{}, nil, big.NewInt(10), time.Hour)
+
+	info := DescribeValidator(validator)
+
+	assert.Equal(t, "spend_cap", info.Type)
+	assert.Equal(t, map[string]string{"cap": "10", "period": "1h0m0s"}, info.Parameters)
+	assert.Equal(t, 1, len(info.Children))
+}
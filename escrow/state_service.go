@@ -7,6 +7,7 @@ import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"math/big"
 )
 
 // PaymentChannelStateService is an implementation of
@@ -32,6 +33,61 @@ func (service *PaymentChannelStateService) GetChannelState(context context.Conte
 		"request": request,
 	}).Debug("GetChannelState called")
 
+	channel, err := service.getAuthorizedChannel(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.Signature == nil {
+		return &ChannelStateReply{
+			CurrentNonce: bigIntToBytes(channel.Nonce),
+		}, nil
+	}
+
+	return &ChannelStateReply{
+		CurrentNonce:        bigIntToBytes(channel.Nonce),
+		CurrentSignedAmount: bigIntToBytes(channel.AuthorizedAmount),
+		CurrentSignature:    channel.Signature,
+	}, nil
+}
+
+// GetChannelBalance returns full, authorized and available amount of the
+// channel which id is passed in request. Request is authenticated in the same
+// way as in GetChannelState.
+func (service *PaymentChannelStateService) GetChannelBalance(context context.Context, request *ChannelStateRequest) (reply *ChannelBalanceReply, err error) {
+	log.WithFields(log.Fields{
+		"context": context,
+		"request": request,
+	}).Debug("GetChannelBalance called")
+
+	channel, err := service.getAuthorizedChannel(request)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizedAmount := channel.AuthorizedAmount
+	if authorizedAmount == nil {
+		authorizedAmount = big.NewInt(0)
+	}
+	fullAmount := channel.FullAmount
+	if fullAmount == nil {
+		fullAmount = big.NewInt(0)
+	}
+	availableAmount := new(big.Int).Sub(fullAmount, authorizedAmount)
+	if availableAmount.Sign() < 0 {
+		availableAmount.SetInt64(0)
+	}
+
+	return &ChannelBalanceReply{
+		FullAmount:       bigIntToBytes(fullAmount),
+		AuthorizedAmount: bigIntToBytes(authorizedAmount),
+		AvailableAmount:  bigIntToBytes(availableAmount),
+	}, nil
+}
+
+// getAuthorizedChannel returns the channel which id is passed in request if
+// request is signed by the channel signer.
+func (service *PaymentChannelStateService) getAuthorizedChannel(request *ChannelStateRequest) (channel *PaymentChannelData, err error) {
 	channelID := bytesToBigInt(request.GetChannelId())
 	signature := request.GetSignature()
 	sender, err := getSignerAddressFromMessage(bigIntToBytes(channelID), signature)
@@ -51,15 +107,5 @@ func (service *PaymentChannelStateService) GetChannelState(context context.Conte
 		return nil, errors.New("only channel signer can get latest channel state")
 	}
 
-	if channel.Signature == nil {
-		return &ChannelStateReply{
-			CurrentNonce: bigIntToBytes(channel.Nonce),
-		}, nil
-	}
-
-	return &ChannelStateReply{
-		CurrentNonce:        bigIntToBytes(channel.Nonce),
-		CurrentSignedAmount: bigIntToBytes(channel.AuthorizedAmount),
-		CurrentSignature:    channel.Signature,
-	}, nil
+	return channel, nil
 }
@@ -182,3 +182,59 @@ func TestGetChannelStateNoOperationsOnThisChannelYet(t *testing.T) {
 	expectedReply.CurrentSignature = nil
 	assert.Equal(t, expectedReply, reply)
 }
+
+func TestGetChannelBalance(t *testing.T) {
+	channelData := *stateServiceTest.defaultChannelData
+	channelData.AuthorizedAmount = big.NewInt(12345)
+	channelData.FullAmount = big.NewInt(20000)
+	stateServiceTest.channelServiceMock.Put(stateServiceTest.defaultChannelKey, &channelData)
+	defer stateServiceTest.channelServiceMock.Clear()
+
+	reply, err := stateServiceTest.service.GetChannelBalance(nil, stateServiceTest.defaultRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &ChannelBalanceReply{
+		FullAmount:       bigIntToBytes(big.NewInt(20000)),
+		AuthorizedAmount: bigIntToBytes(big.NewInt(12345)),
+		AvailableAmount:  bigIntToBytes(big.NewInt(7655)),
+	}, reply)
+}
+
+func TestGetChannelBalanceNoPaymentsYet(t *testing.T) {
+	channelData := *stateServiceTest.defaultChannelData
+	channelData.AuthorizedAmount = nil
+	channelData.Signature = nil
+	channelData.FullAmount = big.NewInt(20000)
+	stateServiceTest.channelServiceMock.Put(stateServiceTest.defaultChannelKey, &channelData)
+	defer stateServiceTest.channelServiceMock.Clear()
+
+	reply, err := stateServiceTest.service.GetChannelBalance(nil, stateServiceTest.defaultRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &ChannelBalanceReply{
+		FullAmount:       bigIntToBytes(big.NewInt(20000)),
+		AuthorizedAmount: bigIntToBytes(big.NewInt(0)),
+		AvailableAmount:  bigIntToBytes(big.NewInt(20000)),
+	}, reply)
+}
+
+func TestGetChannelBalanceIncorrectSender(t *testing.T) {
+	stateServiceTest.channelServiceMock.Put(
+		stateServiceTest.defaultChannelKey,
+		stateServiceTest.defaultChannelData,
+	)
+	defer stateServiceTest.channelServiceMock.Clear()
+
+	reply, err := stateServiceTest.service.GetChannelBalance(
+		nil,
+		&ChannelStateRequest{
+			ChannelId: bigIntToBytes(stateServiceTest.defaultChannelId),
+			Signature: getSignature(
+				bigIntToBytes(stateServiceTest.defaultChannelId),
+				GenerateTestPrivateKey()),
+		},
+	)
+
+	assert.Equal(t, errors.New("only channel signer can get latest channel state"), err)
+	assert.Nil(t, reply)
+}
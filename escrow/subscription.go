@@ -0,0 +1,253 @@
+package escrow
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+// Subscription allows the sender to call the service without paying for each
+// call until ExpiresAt.
+type Subscription struct {
+	Service   handler.ServiceKey
+	Sender    common.Address
+	ExpiresAt time.Time
+}
+
+// Active returns true if subscription is not expired at the moment t.
+func (subscription *Subscription) Active(t time.Time) bool {
+	return t.Before(subscription.ExpiresAt)
+}
+
+// subscriptionKey identifies subscription of the sender to the service.
+type subscriptionKey struct {
+	service handler.ServiceKey
+	sender  common.Address
+}
+
+// SubscriptionStorage keeps subscriptions by service and sender address.
+type SubscriptionStorage struct {
+	delegate TypedAtomicStorage
+}
+
+// NewSubscriptionStorage returns new instance of SubscriptionStorage.
+func NewSubscriptionStorage(atomicStorage AtomicStorage) *SubscriptionStorage {
+	return &SubscriptionStorage{
+		delegate: &TypedAtomicStorageImpl{
+			atomicStorage: &PrefixedAtomicStorage{
+				delegate:  atomicStorage,
+				keyPrefix: "/subscription/storage",
+			},
+			keySerializer:     serializeSubscriptionKey,
+			valueSerializer:   serialize,
+			valueDeserializer: deserialize,
+			valueType:         reflect.TypeOf(Subscription{}),
+		},
+	}
+}
+
+func serializeSubscriptionKey(key interface{}) (serialized string, err error) {
+	subscriptionKey := key.(subscriptionKey)
+	return subscriptionKey.service.String() + "/" + subscriptionKey.sender.Hex(), nil
+}
+
+func (subscription *Subscription) key() subscriptionKey {
+	return subscriptionKey{service: subscription.Service, sender: subscription.Sender}
+}
+
+// Get returns subscription of the sender to the service.
+func (storage *SubscriptionStorage) Get(service handler.ServiceKey, sender common.Address) (subscription *Subscription, ok bool, err error) {
+	return storage.GetContext(context.Background(), service, sender)
+}
+
+// GetContext returns subscription of the sender to the service, request is
+// cancelled when ctx is done.
+func (storage *SubscriptionStorage) GetContext(ctx context.Context, service handler.ServiceKey, sender common.Address) (subscription *Subscription, ok bool, err error) {
+	value, ok, err := storage.delegate.GetContext(ctx, subscriptionKey{service: service, sender: sender})
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return value.(*Subscription), true, nil
+}
+
+// Put saves subscription replacing previous subscription of the same sender
+// to the same service.
+func (storage *SubscriptionStorage) Put(subscription *Subscription) (err error) {
+	return storage.delegate.Put(subscription.key(), subscription)
+}
+
+// PutIfAbsent saves subscription if the sender has no subscription to the
+// service.
+func (storage *SubscriptionStorage) PutIfAbsent(subscription *Subscription) (ok bool, err error) {
+	return storage.delegate.PutIfAbsent(subscription.key(), subscription)
+}
+
+// CompareAndSwap replaces prev subscription by the next one if stored
+// subscription is equal to prev.
+func (storage *SubscriptionStorage) CompareAndSwap(prev *Subscription, next *Subscription) (ok bool, err error) {
+	return storage.delegate.CompareAndSwap(prev.key(), prev, next)
+}
+
+type subscriptionIncomeValidator struct {
+	delegate       IncomeValidator
+	storage        *SubscriptionStorage
+	headers        *handler.ServiceKeyHeaders
+	defaultService handler.ServiceKey
+	now            func() time.Time
+}
+
+// NewSubscriptionIncomeValidator returns income validator which accepts any
+// call of the sender which has active subscription to the called service
+// regardless of the call income. Calls of the senders without subscription
+// or with expired one are validated by delegate. Called service is
+// determined using headers, if headers is nil then daemon serves the only
+// defaultService.
+func NewSubscriptionIncomeValidator(delegate IncomeValidator, storage *SubscriptionStorage, headers *handler.ServiceKeyHeaders, defaultService handler.ServiceKey) (validator IncomeValidator) {
+	return &subscriptionIncomeValidator{
+		delegate:       delegate,
+		storage:        storage,
+		headers:        headers,
+		defaultService: defaultService,
+		now:            time.Now,
+	}
+}
+
+func (validator *subscriptionIncomeValidator) Validate(data *IncomeData) (err error) {
+	service := validator.defaultService
+	if validator.headers != nil {
+		var e *handler.GrpcError
+		if service, e = validator.headers.GetServiceKey(data.GrpcContext.MD); e != nil {
+			return NewPaymentError(InvalidArgument, "%v", e.Status.Message())
+		}
+	}
+
+	subscription, ok, err := validator.storage.GetContext(data.context(), service, data.Sender)
+	if err != nil {
+		return NewPaymentError(Internal, "cannot read subscription of the sender: %v", err)
+	}
+	if ok && subscription.Active(validator.now()) {
+		return nil
+	}
+	return validator.delegate.Validate(data)
+}
+
+func (validator *subscriptionIncomeValidator) describe() ValidatorInfo {
+	return ValidatorInfo{
+		Type:     "subscription",
+		Children: []ValidatorInfo{DescribeValidator(validator.delegate)},
+	}
+}
+
+// SubscriptionService creates subscriptions paid by payments from the
+// payment channels.
+type SubscriptionService struct {
+	channelService PaymentChannelService
+	storage        *SubscriptionStorage
+	price          *big.Int
+	period         time.Duration
+	now            func() time.Time
+}
+
+// NewSubscriptionService returns service which sells subscriptions of the
+// period duration for price in cogs.
+func NewSubscriptionService(channelService PaymentChannelService, storage *SubscriptionStorage, price *big.Int, period time.Duration) *SubscriptionService {
+	return &SubscriptionService{
+		channelService: channelService,
+		storage:        storage,
+		price:          price,
+		period:         period,
+		now:            time.Now,
+	}
+}
+
+// Subscribe validates payment and applies it to the channel if its income
+// covers the subscription price. Subscription of the channel sender to the
+// service is extended by the period, expired or absent subscription starts
+// now.
+func (service *SubscriptionService) Subscribe(ctx context.Context, key handler.ServiceKey, payment *Payment) (subscription *Subscription, err error) {
+	transaction, err := service.channelService.StartPaymentTransaction(ctx, payment)
+	if err != nil {
+		return
+	}
+
+	subscription, err = service.extend(key, transaction.Channel(), payment)
+	if err != nil {
+		if e := transaction.Rollback(); e != nil {
+			log.WithError(e).WithField("payment", payment).Error("Cannot rollback subscription payment")
+		}
+		return nil, err
+	}
+
+	if err = transaction.Commit(); err != nil {
+		// subscription is reverted because payment is not applied
+		if e := service.revert(subscription); e != nil {
+			log.WithError(e).WithField("service", key).WithField("sender", subscription.Sender.Hex()).Error("Cannot revert subscription which payment is not committed")
+		}
+		return nil, NewPaymentError(Internal, "cannot apply subscription payment")
+	}
+
+	log.WithField("service", key).WithField("sender", subscription.Sender.Hex()).WithField("expiresAt", subscription.ExpiresAt).Info("Subscription is paid")
+	return subscription, nil
+}
+
+// extend checks income of the payment and saves extended subscription of
+// the channel sender. Subscription is updated by compare and swap, so
+// concurrent payments of the same sender extend it one after another.
+func (service *SubscriptionService) extend(key handler.ServiceKey, channel *PaymentChannelData, payment *Payment) (subscription *Subscription, err error) {
+	if channel.Service != (handler.ServiceKey{}) && channel.Service != key {
+		return nil, NewPaymentError(PermissionDenied, "payment channel %v is used for service \"%v\" and cannot be used for service \"%v\"", channel.ChannelID, channel.Service, key)
+	}
+	income := new(big.Int).Sub(payment.Amount, channel.AuthorizedAmount)
+	if income.Cmp(service.price) < 0 {
+		return nil, NewPaymentError(Unauthenticated, "income %v is less than subscription price %v", income, service.price)
+	}
+
+	for {
+		previous, ok, err := service.storage.Get(key, channel.Sender)
+		if err != nil {
+			return nil, NewPaymentError(Internal, "cannot read subscription of the sender: %v", err)
+		}
+		start := service.now()
+		if ok && previous.Active(start) {
+			start = previous.ExpiresAt
+		}
+		subscription = &Subscription{Service: key, Sender: channel.Sender, ExpiresAt: start.Add(service.period).UTC()}
+
+		var saved bool
+		if ok {
+			saved, err = service.storage.CompareAndSwap(previous, subscription)
+		} else {
+			saved, err = service.storage.PutIfAbsent(subscription)
+		}
+		if err != nil {
+			return nil, NewPaymentError(Internal, "cannot save subscription of the sender: %v", err)
+		}
+		if saved {
+			return subscription, nil
+		}
+	}
+}
+
+// revert takes back the period added to the subscription by extend. The
+// subscription may be extended by other payments meanwhile, so the period is
+// subtracted from its current expiration time instead of restoring the
+// previous subscription.
+func (service *SubscriptionService) revert(subscription *Subscription) (err error) {
+	for {
+		current, ok, err := service.storage.Get(subscription.Service, subscription.Sender)
+		if err != nil || !ok {
+			return err
+		}
+		reverted := &Subscription{Service: current.Service, Sender: current.Sender, ExpiresAt: current.ExpiresAt.Add(-service.period)}
+		swapped, err := service.storage.CompareAndSwap(current, reverted)
+		if err != nil || swapped {
+			return err
+		}
+	}
+}
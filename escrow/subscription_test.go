@@ -0,0 +1,214 @@
+package escrow
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/handler"
+)
+
+var testSubscriptionNow = time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+
+var testSubscriber = common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+var testSubscribedService = handler.ServiceKey{OrganizationID: "org", ServiceID: "service-a"}
+
+func newTestSubscriptionIncomeValidator(storage *SubscriptionStorage, now *time.Time) *subscriptionIncomeValidator {
+	validator := NewSubscriptionIncomeValidator(NewIncomeValidator(big.NewInt(10)), storage, nil, testSubscribedService).(*subscriptionIncomeValidator)
+	validator.now = func() time.Time { return *now }
+	return validator
+}
+
+func testSubscription(expiresAt time.Time) *Subscription {
+	return &Subscription{Service: testSubscribedService, Sender: testSubscriber, ExpiresAt: expiresAt}
+}
+
+func TestSubscriptionActiveAcceptsAnyIncome(t *testing.T) {
+	now := testSubscriptionNow
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(now.Add(time.Hour)))
+	validator := newTestSubscriptionIncomeValidator(storage, &now)
+
+	assert.Nil(t, validator.Validate(spendFrom(testSubscriber, 0)))
+	assert.Nil(t, validator.Validate(spendFrom(testSubscriber, 3)))
+}
+
+func TestSubscriptionExpiredIsRejected(t *testing.T) {
+	now := testSubscriptionNow
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(now))
+	validator := newTestSubscriptionIncomeValidator(storage, &now)
+
+	err := validator.Validate(spendFrom(testSubscriber, 0))
+
+	assert.Equal(t, Unauthenticated, err.(*PaymentError).Code)
+	assert.Equal(t, "income 0 does not equal to price 10", err.(*PaymentError).Message)
+	assert.Nil(t, validator.Validate(spendFrom(testSubscriber, 10)), "call paid in full is accepted")
+}
+
+func TestSubscriptionAbsentIsValidatedByDelegate(t *testing.T) {
+	now := testSubscriptionNow
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(now.Add(time.Hour)))
+	validator := newTestSubscriptionIncomeValidator(storage, &now)
+
+	err := validator.Validate(spendFrom(testSpendSender, 0))
+
+	assert.Equal(t, Unauthenticated, err.(*PaymentError).Code)
+	assert.Equal(t, "income 0 does not equal to price 10", err.(*PaymentError).Message)
+}
+
+func TestSubscriptionOfOtherServiceIsNotApplied(t *testing.T) {
+	now := testSubscriptionNow
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(now.Add(time.Hour)))
+	validator := NewSubscriptionIncomeValidator(NewIncomeValidator(big.NewInt(10)), storage,
+		&handler.DefaultServiceKeyHeaders, testSubscribedService).(*subscriptionIncomeValidator)
+	validator.now = func() time.Time { return now }
+	income := func(serviceID string, amount int64) *IncomeData {
+		data := spendFrom(testSubscriber, amount)
+		data.GrpcContext = serviceStreamContext("org", serviceID)
+		return data
+	}
+
+	assert.Nil(t, validator.Validate(income("service-a", 0)))
+	err := validator.Validate(income("service-b", 0))
+	assert.Equal(t, Unauthenticated, err.(*PaymentError).Code)
+	assert.Equal(t, "income 0 does not equal to price 10", err.(*PaymentError).Message)
+	assert.Nil(t, validator.Validate(income("service-b", 10)), "call paid in full is accepted")
+}
+
+func TestSubscriptionStorageError(t *testing.T) {
+	storage := &SubscriptionStorage{delegate: &failingTypedStorage{err: errors.New("storage is down")}}
+	validator := NewSubscriptionIncomeValidator(&incomeValidatorMockType{}, storage, nil, testSubscribedService)
+
+	err := validator.Validate(spendFrom(testSubscriber, 0))
+
+	assert.Equal(t, NewPaymentError(Internal, "cannot read subscription of the sender: storage is down"), err)
+}
+
+func newTestSubscriptionService(channel *PaymentChannelData, storage *SubscriptionStorage) *SubscriptionService {
+	channelService := &paymentChannelServiceMock{data: channel}
+	service := NewSubscriptionService(channelService, storage, big.NewInt(100), 24*time.Hour)
+	service.now = func() time.Time { return testSubscriptionNow }
+	return service
+}
+
+func subscriptionChannel(authorized int64) *PaymentChannelData {
+	return &PaymentChannelData{ChannelID: big.NewInt(42), Sender: testSubscriber, AuthorizedAmount: big.NewInt(authorized)}
+}
+
+func TestSubscribeCreatesSubscription(t *testing.T) {
+	storage := NewSubscriptionStorage(NewMemStorage())
+	service := newTestSubscriptionService(subscriptionChannel(20), storage)
+
+	subscription, err := service.Subscribe(context.Background(), testSubscribedService, &Payment{ChannelID: big.NewInt(42), Amount: big.NewInt(120)})
+
+	assert.Nil(t, err)
+	expected := testSubscription(testSubscriptionNow.Add(24 * time.Hour))
+	assert.Equal(t, expected, subscription)
+	stored, ok, err := storage.Get(testSubscribedService, testSubscriber)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, expected.ExpiresAt.Unix(), stored.ExpiresAt.Unix())
+}
+
+func TestSubscribeExtendsActiveSubscription(t *testing.T) {
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(testSubscriptionNow.Add(time.Hour)))
+	service := newTestSubscriptionService(subscriptionChannel(0), storage)
+
+	subscription, err := service.Subscribe(context.Background(), testSubscribedService, &Payment{ChannelID: big.NewInt(42), Amount: big.NewInt(100)})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testSubscriptionNow.Add(25*time.Hour), subscription.ExpiresAt)
+}
+
+func TestSubscribeInsufficientIncome(t *testing.T) {
+	storage := NewSubscriptionStorage(NewMemStorage())
+	service := newTestSubscriptionService(subscriptionChannel(20), storage)
+
+	subscription, err := service.Subscribe(context.Background(), testSubscribedService, &Payment{ChannelID: big.NewInt(42), Amount: big.NewInt(119)})
+
+	assert.Equal(t, NewPaymentError(Unauthenticated, "income 99 is less than subscription price 100"), err)
+	assert.Nil(t, subscription)
+	_, ok, _ := storage.Get(testSubscribedService, testSubscriber)
+	assert.False(t, ok)
+}
+
+func TestSubscribeChannelOfOtherService(t *testing.T) {
+	storage := NewSubscriptionStorage(NewMemStorage())
+	channel := subscriptionChannel(20)
+	channel.Service = handler.ServiceKey{OrganizationID: "org", ServiceID: "service-b"}
+	service := newTestSubscriptionService(channel, storage)
+
+	subscription, err := service.Subscribe(context.Background(), testSubscribedService, &Payment{ChannelID: big.NewInt(42), Amount: big.NewInt(120)})
+
+	assert.Equal(t, NewPaymentError(PermissionDenied, "payment channel 42 is used for service \"org/service-b\" and cannot be used for service \"org/service-a\""), err)
+	assert.Nil(t, subscription)
+}
+
+func TestSubscribeRetriesConcurrentlyChangedSubscription(t *testing.T) {
+	storage := NewSubscriptionStorage(NewMemStorage())
+	service := newTestSubscriptionService(subscriptionChannel(0), storage)
+	calls := 0
+	service.now = func() time.Time {
+		calls++
+		if calls == 1 {
+			// other payment extends subscription after it is read
+			storage.Put(testSubscription(testSubscriptionNow.Add(time.Hour)))
+		}
+		return testSubscriptionNow
+	}
+
+	subscription, err := service.Subscribe(context.Background(), testSubscribedService, &Payment{ChannelID: big.NewInt(42), Amount: big.NewInt(100)})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testSubscriptionNow.Add(25*time.Hour), subscription.ExpiresAt)
+	stored, _, _ := storage.Get(testSubscribedService, testSubscriber)
+	assert.Equal(t, testSubscriptionNow.Add(25*time.Hour).Unix(), stored.ExpiresAt.Unix())
+}
+
+func TestSubscribeRevertsSubscriptionWhenPaymentIsNotCommitted(t *testing.T) {
+	storage := NewSubscriptionStorage(NewMemStorage())
+	storage.Put(testSubscription(testSubscriptionNow.Add(time.Hour)))
+	service := newTestSubscriptionService(subscriptionChannel(0), storage)
+	service.channelService = &commitFailingChannelService{paymentChannelServiceMock{data: subscriptionChannel(0)}}
+
+	subscription, err := service.Subscribe(context.Background(), testSubscribedService, &Payment{ChannelID: big.NewInt(42), Amount: big.NewInt(100)})
+
+	assert.Equal(t, NewPaymentError(Internal, "cannot apply subscription payment"), err)
+	assert.Nil(t, subscription)
+	stored, _, _ := storage.Get(testSubscribedService, testSubscriber)
+	assert.Equal(t, testSubscriptionNow.Add(time.Hour).Unix(), stored.ExpiresAt.Unix())
+}
+
+// commitFailingChannelService starts payment transactions which cannot be
+// committed
+type commitFailingChannelService struct {
+	paymentChannelServiceMock
+}
+
+func (service *commitFailingChannelService) StartPaymentTransaction(ctx context.Context, payment *Payment) (PaymentTransaction, error) {
+	return &paymentTransactionMock{channel: service.data, err: errors.New("storage is down")}, nil
+}
+
+// failingTypedStorage returns err from all operations
+type failingTypedStorage struct {
+	TypedAtomicStorage
+	err error
+}
+
+func (storage *failingTypedStorage) Get(key interface{}) (value interface{}, ok bool, err error) {
+	return nil, false, storage.err
+}
+
+func (storage *failingTypedStorage) GetContext(ctx context.Context, key interface{}) (value interface{}, ok bool, err error) {
+	return nil, false, storage.err
+}
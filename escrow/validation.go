@@ -2,21 +2,52 @@ package escrow
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"math/big"
+	"time"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/metrics"
 )
 
+// signatureLength is a length of the Ethereum signature: 32 bytes of r, 32
+// bytes of s and one byte of v.
+const signatureLength = 65
+
 // ChannelPaymentValidator validates payment using payment channel state.
 type ChannelPaymentValidator struct {
-	currentBlock               func() (currentBlock *big.Int, err error)
+	currentBlock               func(ctx context.Context) (currentBlock *big.Int, err error)
 	paymentExpirationThreshold func() (threshold *big.Int)
+	// maxChannelExpiryBlocks returns maximum number of blocks channel can
+	// expire after, zero means unlimited
+	maxChannelExpiryBlocks func() (blocks *big.Int)
+	currentBlockTime       func() (blockTime time.Time, err error)
+	// maxBlockLag returns maximum age of the current block, zero means that
+	// age is not checked
+	maxBlockLag func() (lag time.Duration)
+	// maxClockSkew returns clock difference which is tolerated by channel
+	// expiration checks, nil or zero means that skew is not tolerated
+	maxClockSkew func() (skew time.Duration)
+	// blockInterval returns average interval between blocks which is used
+	// to convert clock skew into the number of blocks
+	blockInterval func() (interval time.Duration, err error)
+	now           func() time.Time
+	// signatureScheme returns the way payment is signed by client: "raw" or
+	// "eip712", nil means "raw"
+	signatureScheme func() (scheme string)
+	// typedDataDomain returns EIP-712 domain of the payment signature
+	typedDataDomain func() (domain *typedDataDomain, err error)
+	// allowZeroBalanceChannels returns false if payments via channels
+	// without funds should be rejected, nil means true
+	allowZeroBalanceChannels func() bool
 }
 
 // NewChannelPaymentValidator returns new payment validator instance
@@ -26,12 +57,38 @@ func NewChannelPaymentValidator(processor *blockchain.Processor, cfg *viper.Vipe
 		paymentExpirationThreshold: func() *big.Int {
 			return metadata.GetPaymentExpirationThreshold()
 		},
+		maxChannelExpiryBlocks: func() *big.Int {
+			blocks, _ := config.GetUint64FromViper(cfg, config.MaxChannelExpiryBlocksKey)
+			return new(big.Int).SetUint64(blocks)
+		},
+		currentBlockTime: processor.CurrentBlockTime,
+		maxBlockLag: func() time.Duration {
+			return cfg.GetDuration(config.MaxBlockLagKey)
+		},
+		maxClockSkew: func() time.Duration {
+			return cfg.GetDuration(config.MaxClockSkewKey)
+		},
+		blockInterval: processor.BlockInterval,
+		now:           time.Now,
+		signatureScheme: func() string {
+			return cfg.GetString(config.PaymentSignatureSchemeKey)
+		},
+		typedDataDomain: func() (*typedDataDomain, error) {
+			chainID, err := processor.ChainID()
+			if err != nil {
+				return nil, err
+			}
+			return &typedDataDomain{ChainID: chainID, VerifyingContract: metadata.GetMpeAddress()}, nil
+		},
+		allowZeroBalanceChannels: func() bool {
+			return cfg.GetBool(config.AllowZeroBalanceChannelsKey)
+		},
 	}
 }
 
 // Validate returns instance of PaymentError as error if validation fails, nil
-// otherwise.
-func (validator *ChannelPaymentValidator) Validate(payment *Payment, channel *PaymentChannelData) (err error) {
+// otherwise. ctx is passed to the blockchain calls.
+func (validator *ChannelPaymentValidator) Validate(ctx context.Context, payment *Payment, channel *PaymentChannelData) (err error) {
 	var log = log.WithField("payment", payment).WithField("channel", channel)
 
 	if payment.ChannelNonce.Cmp(channel.Nonce) != 0 {
@@ -39,35 +96,153 @@ func (validator *ChannelPaymentValidator) Validate(payment *Payment, channel *Pa
 		return NewPaymentError(IncorrectNonce, "incorrect payment channel nonce, latest: %v, sent: %v", channel.Nonce, payment.ChannelNonce)
 	}
 
-	signerAddress, err := getSignerAddressFromPayment(payment)
+	// malformed signature is rejected before hashing the message and
+	// recovering the public key which are more expensive
+	if e := validateSignatureFormat(payment.Signature); e != nil {
+		log.WithError(e).Warn("Malformed payment signature is sent by client")
+		return newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment signature is malformed: %v", e)
+	}
+
+	signerAddress, err := validator.getSignerAddress(payment)
+	if err == errUnknownTypedDataDomain {
+		return NewPaymentError(Internal, "cannot determine chain id")
+	}
 	if err != nil {
-		return NewPaymentError(Unauthenticated, "payment signature is not valid")
+		return newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment signature is not valid")
 	}
 
 	log = log.WithField("signerAddress", blockchain.AddressToHex(signerAddress))
 	if *signerAddress != channel.Signer {
 		log.WithField("signerAddress", blockchain.AddressToHex(signerAddress)).Warn("Channel signer is not equal to payment signer")
-		return NewPaymentError(Unauthenticated, "payment is not signed by channel signer")
+		return newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer")
 	}
-	currentBlock, e := validator.currentBlock()
+	// channel expiration is compared with the current block number returned
+	// by Ethereum node, clients choose expiration using their own clock so
+	// max_clock_skew converted into blocks is tolerated in both directions
+	currentBlock, e := validator.currentBlock(ctx)
 	if e != nil {
 		return NewPaymentError(Internal, "cannot determine current block")
 	}
+	if e = validator.checkBlockLag(); e != nil {
+		return e
+	}
+	skewBlocks, e := validator.clockSkewBlocks()
+	if e != nil {
+		return NewPaymentError(Internal, "cannot determine block interval")
+	}
 	expirationThreshold := validator.paymentExpirationThreshold()
 	currentBlockWithThreshold := new(big.Int).Add(currentBlock, expirationThreshold)
+	currentBlockWithThreshold.Sub(currentBlockWithThreshold, skewBlocks)
 	if currentBlockWithThreshold.Cmp(channel.Expiration) >= 0 {
 		log.WithField("currentBlock", currentBlock).WithField("expirationThreshold", expirationThreshold).Warn("Channel expiration time is after expiration threshold")
-		return NewPaymentError(Unauthenticated, "payment channel is near to be expired, expiration time: %v, current block: %v, expiration threshold: %v", channel.Expiration, currentBlock, expirationThreshold)
+		return newRejectionError(metrics.RejectionExpired, Unauthenticated, "payment channel is near to be expired, expiration time: %v, current block: %v, expiration threshold: %v", channel.Expiration, currentBlock, expirationThreshold)
+	}
+	if validator.maxChannelExpiryBlocks != nil {
+		maxExpiryBlocks := validator.maxChannelExpiryBlocks()
+		expiryBlocks := new(big.Int).Sub(channel.Expiration, currentBlock)
+		if maxExpiryBlocks.Sign() > 0 && expiryBlocks.Cmp(new(big.Int).Add(maxExpiryBlocks, skewBlocks)) > 0 {
+			log.WithField("currentBlock", currentBlock).WithField("maxExpiryBlocks", maxExpiryBlocks).Warn("Channel expiration time is too far in the future")
+			return NewPaymentError(InvalidArgument, "payment channel expires too far in the future, expiration time: %v, current block: %v, max expiry blocks: %v", channel.Expiration, currentBlock, maxExpiryBlocks)
+		}
+	}
+
+	// amount is compared with the maximum amount ever authorized using the
+	// current nonce, not only with the last one, to prevent spending the same
+	// amount twice
+	if channel.MaxAuthorizedAmount != nil && payment.Amount.Cmp(channel.MaxAuthorizedAmount) < 0 {
+		log.WithField("maxAuthorizedAmount", channel.MaxAuthorizedAmount).Warn("Payment amount is less than previously authorized amount")
+		return newRejectionError(metrics.RejectionUnderpaid, Unauthenticated, "payment amount %v is less than previously authorized amount %v", payment.Amount, channel.MaxAuthorizedAmount)
+	}
+
+	// channel can be opened without deposit and funded later, the balance is
+	// read from blockchain so payments are accepted after funding
+	if channel.FullAmount.Sign() == 0 && validator.allowZeroBalanceChannels != nil && !validator.allowZeroBalanceChannels() {
+		log.Warn("Payment channel has zero balance")
+		return NewPaymentError(FailedPrecondition, "payment channel has zero balance, channels without funds are not accepted")
 	}
 
+	// payment amount is a total amount authorized by sender so it should be
+	// covered by channel balance
 	if channel.FullAmount.Cmp(payment.Amount) < 0 {
 		log.Warn("Not enough tokens on payment channel")
-		return NewPaymentError(Unauthenticated, "not enough tokens on payment channel, channel amount: %v, payment amount: %v", channel.FullAmount, payment.Amount)
+		return newRejectionError(metrics.RejectionUnderpaid, FailedPrecondition, "not enough tokens on payment channel, channel amount: %v, payment amount: %v", channel.FullAmount, payment.Amount)
 	}
 
 	return
 }
 
+// checkBlockLag returns Unavailable error if the current block is older than
+// max_block_lag, in such case the daemon's view of the channels may be stale.
+// Block timestamp is set by the clock of the block producer, so max_clock_skew
+// is tolerated in addition to max_block_lag.
+func (validator *ChannelPaymentValidator) checkBlockLag() error {
+	if validator.maxBlockLag == nil {
+		return nil
+	}
+	maxLag := validator.maxBlockLag()
+	if maxLag <= 0 {
+		return nil
+	}
+	var skew time.Duration
+	if validator.maxClockSkew != nil && validator.maxClockSkew() > 0 {
+		skew = validator.maxClockSkew()
+	}
+
+	blockTime, err := validator.currentBlockTime()
+	if err != nil {
+		return NewPaymentError(Internal, "cannot determine current block time")
+	}
+	if lag := validator.now().Sub(blockTime); lag > maxLag+skew {
+		log.WithField("blockTime", blockTime).WithField("maxBlockLag", maxLag).WithField("maxClockSkew", skew).Warn("Current block is too old, daemon is behind on block sync")
+		return NewPaymentError(Unavailable, "daemon is behind on block sync, current block is %v old, max block lag: %v", lag, maxLag)
+	}
+	return nil
+}
+
+// clockSkewBlocks returns max_clock_skew converted into the number of blocks,
+// partial block is counted as a whole one.
+func (validator *ChannelPaymentValidator) clockSkewBlocks() (blocks *big.Int, err error) {
+	if validator.maxClockSkew == nil {
+		return big.NewInt(0), nil
+	}
+	skew := validator.maxClockSkew()
+	if skew <= 0 {
+		return big.NewInt(0), nil
+	}
+
+	interval, err := validator.blockInterval()
+	if err != nil {
+		log.WithError(err).Error("Cannot estimate block interval")
+		return nil, err
+	}
+	return big.NewInt(int64((skew + interval - 1) / interval)), nil
+}
+
+// errUnknownTypedDataDomain is returned by getSignerAddress when EIP-712
+// domain cannot be determined because chain id is not known.
+var errUnknownTypedDataDomain = errors.New("cannot get EIP-712 domain")
+
+// getSignerAddress returns address of the payment signer using configured
+// payment_signature_scheme.
+func (validator *ChannelPaymentValidator) getSignerAddress(payment *Payment) (signer *common.Address, err error) {
+	if validator.signatureScheme == nil || validator.signatureScheme() != signatureSchemeEIP712 {
+		return getSignerAddressFromPayment(payment)
+	}
+
+	domain, err := validator.typedDataDomain()
+	if err != nil {
+		log.WithError(err).Error("Cannot get EIP-712 domain of the payment signature")
+		return nil, errUnknownTypedDataDomain
+	}
+	hash := typedDataHash(domain, payment)
+	signer, err = getSignerAddressFromHash(hash, payment.Signature)
+	if err != nil {
+		log.WithField("payment", payment).WithError(err).Error("Cannot get signer from EIP-712 payment")
+		return nil, err
+	}
+	return signer, nil
+}
+
 func getSignerAddressFromPayment(payment *Payment) (signer *common.Address, err error) {
 	message := bytes.Join([][]byte{
 		payment.MpeContractAddress.Bytes(),
@@ -85,25 +260,48 @@ func getSignerAddressFromPayment(payment *Payment) (signer *common.Address, err
 	return signer, err
 }
 
-func getSignerAddressFromMessage(message, signature []byte) (signer *common.Address, err error) {
-	log := log.WithFields(log.Fields{
-		"message":   blockchain.BytesToBase64(message),
-		"signature": blockchain.BytesToBase64(signature),
-	})
+// validateSignatureFormat checks that signature is a secp256k1 signature in
+// the [r || s || v] form with v equal to 0, 1, 27 or 28 and r, s within the
+// curve order.
+func validateSignatureFormat(signature []byte) error {
+	if len(signature) != signatureLength {
+		return fmt.Errorf("incorrect signature length %v, expected %v", len(signature), signatureLength)
+	}
+	v := signature[64]
+	if v >= 27 {
+		v -= 27
+	}
+	r := new(big.Int).SetBytes(signature[0:32])
+	s := new(big.Int).SetBytes(signature[32:64])
+	if !crypto.ValidateSignatureValues(v, r, s, false) {
+		return errors.New("incorrect signature values")
+	}
+	return nil
+}
 
+func getSignerAddressFromMessage(message, signature []byte) (signer *common.Address, err error) {
 	messageHash := crypto.Keccak256(
 		blockchain.HashPrefix32Bytes,
 		crypto.Keccak256(message),
 	)
-	log = log.WithField("messageHash", hex.EncodeToString(messageHash))
 
-	v, _, _, e := blockchain.ParseSignature(signature)
-	if e != nil {
-		log.WithError(e).Warn("Error parsing signature")
-		return nil, errors.New("incorrect signature length")
+	return getSignerAddressFromHash(messageHash, signature)
+}
+
+// getSignerAddressFromHash recovers address of the key which is used to sign
+// the hash.
+func getSignerAddressFromHash(messageHash, signature []byte) (signer *common.Address, err error) {
+	log := log.WithFields(log.Fields{
+		"messageHash": hex.EncodeToString(messageHash),
+		"signature":   blockchain.BytesToBase64(signature),
+	})
+
+	if e := validateSignatureFormat(signature); e != nil {
+		log.WithError(e).Warn("Malformed signature")
+		return nil, e
 	}
 
-	modifiedSignature := bytes.Join([][]byte{signature[0:64], {v % 27}}, nil)
+	modifiedSignature := bytes.Join([][]byte{signature[0:64], {signature[64] % 27}}, nil)
 	publicKey, e := crypto.SigToPub(messageHash, modifiedSignature)
 	if e != nil {
 		log.WithError(e).WithField("modifiedSignature", modifiedSignature).Warn("Incorrect signature")
@@ -2,23 +2,28 @@ package escrow
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/metrics"
 )
 
 func ChannelPaymentValidatorMock() *ChannelPaymentValidator {
 	return &ChannelPaymentValidator{
-		currentBlock:               func() (*big.Int, error) { return big.NewInt(99), nil },
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
 		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
 	}
 }
@@ -80,7 +85,7 @@ func (suite *ValidationTestSuite) SetupSuite() {
 	suite.mpeContractAddress = blockchain.HexToAddress("0xf25186b5081ff5ce73482ad761db0eb0d25abfbf")
 
 	suite.validator = ChannelPaymentValidator{
-		currentBlock:               func() (*big.Int, error) { return big.NewInt(99), nil },
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
 		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
 	}
 }
@@ -115,7 +120,7 @@ func (suite *ValidationTestSuite) TestPaymentIsValid() {
 	payment := suite.payment()
 	channel := suite.channel()
 
-	err := suite.validator.Validate(payment, channel)
+	err := suite.validator.Validate(context.Background(), payment, channel)
 
 	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
 }
@@ -127,7 +132,7 @@ func (suite *ValidationTestSuite) TestValidatePaymentChannelNonce() {
 	channel := suite.channel()
 	channel.Nonce = big.NewInt(3)
 
-	err := suite.validator.Validate(payment, channel)
+	err := suite.validator.Validate(context.Background(), payment, channel)
 
 	assert.Equal(suite.T(), NewPaymentError(IncorrectNonce, "incorrect payment channel nonce, latest: 3, sent: 2"), err)
 }
@@ -136,63 +141,283 @@ func (suite *ValidationTestSuite) TestValidatePaymentIncorrectSignatureLength()
 	payment := suite.payment()
 	payment.Signature = blockchain.HexToBytes("0x0000")
 
-	err := suite.validator.Validate(payment, suite.channel())
+	err := suite.validator.Validate(context.Background(), payment, suite.channel())
 
-	assert.Equal(suite.T(), NewPaymentError(Unauthenticated, "payment signature is not valid"), err)
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment signature is malformed: incorrect signature length 2, expected 65"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentTruncatedSignature() {
+	payment := suite.payment()
+	payment.Signature = payment.Signature[:64]
+
+	err := suite.validator.Validate(context.Background(), payment, suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment signature is malformed: incorrect signature length 64, expected 65"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentZeroSignature() {
+	payment := suite.payment()
+	payment.Signature = make([]byte, 65)
+
+	err := suite.validator.Validate(context.Background(), payment, suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment signature is malformed: incorrect signature values"), err)
 }
 
 func (suite *ValidationTestSuite) TestValidatePaymentIncorrectSignatureChecksum() {
 	payment := suite.payment()
 	payment.Signature = blockchain.HexToBytes("0xa4d2ae6f3edd1f7fe77e4f6f78ba18d62e6093bcae01ef86d5de902d33662fa372011287ea2d8d8436d9db8a366f43480678df25453b484c67f80941ef2c05ef21")
 
-	err := suite.validator.Validate(payment, suite.channel())
+	err := suite.validator.Validate(context.Background(), payment, suite.channel())
 
-	assert.Equal(suite.T(), NewPaymentError(Unauthenticated, "payment signature is not valid"), err)
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment signature is malformed: incorrect signature values"), err)
 }
 
 func (suite *ValidationTestSuite) TestValidatePaymentIncorrectSigner() {
 	payment := suite.payment()
 	payment.Signature = blockchain.HexToBytes("0xa4d2ae6f3edd1f7fe77e4f6f78ba18d62e6093bcae01ef86d5de902d33662fa372011287ea2d8d8436d9db8a366f43480678df25453b484c67f80941ef2c05ef01")
 
-	err := suite.validator.Validate(payment, suite.channel())
+	err := suite.validator.Validate(context.Background(), payment, suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentPassesContextToCurrentBlock() {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "call")
+	var passed context.Context
+	validator := &ChannelPaymentValidator{
+		currentBlock: func(ctx context.Context) (*big.Int, error) {
+			passed = ctx
+			return big.NewInt(99), nil
+		},
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+	}
+
+	err := validator.Validate(ctx, suite.payment(), suite.channel())
 
-	assert.Equal(suite.T(), NewPaymentError(Unauthenticated, "payment is not signed by channel signer"), err)
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+	assert.Equal(suite.T(), "call", passed.Value(key{}))
 }
 
 func (suite *ValidationTestSuite) TestValidatePaymentChannelCannotGetCurrentBlock() {
 	validator := &ChannelPaymentValidator{
-		currentBlock: func() (*big.Int, error) { return nil, errors.New("blockchain error") },
+		currentBlock: func(context.Context) (*big.Int, error) { return nil, errors.New("blockchain error") },
 	}
 
-	err := validator.Validate(suite.payment(), suite.channel())
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
 
 	assert.Equal(suite.T(), NewPaymentError(Internal, "cannot determine current block"), err)
 }
 
 func (suite *ValidationTestSuite) TestValidatePaymentExpiredChannel() {
 	validator := &ChannelPaymentValidator{
-		currentBlock:               func() (*big.Int, error) { return big.NewInt(99), nil },
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
 		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
 	}
 	channel := suite.channel()
 	channel.Expiration = big.NewInt(99)
 
-	err := validator.Validate(suite.payment(), channel)
+	err := validator.Validate(context.Background(), suite.payment(), channel)
 
-	assert.Equal(suite.T(), NewPaymentError(Unauthenticated, "payment channel is near to be expired, expiration time: 99, current block: 99, expiration threshold: 0"), err)
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionExpired, Unauthenticated, "payment channel is near to be expired, expiration time: 99, current block: 99, expiration threshold: 0"), err)
 }
 
 func (suite *ValidationTestSuite) TestValidatePaymentChannelExpirationThreshold() {
 	validator := &ChannelPaymentValidator{
-		currentBlock:               func() (*big.Int, error) { return big.NewInt(98), nil },
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(98), nil },
 		paymentExpirationThreshold: func() *big.Int { return big.NewInt(1) },
 	}
 	channel := suite.channel()
 	channel.Expiration = big.NewInt(99)
 
-	err := validator.Validate(suite.payment(), channel)
+	err := validator.Validate(context.Background(), suite.payment(), channel)
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionExpired, Unauthenticated, "payment channel is near to be expired, expiration time: 99, current block: 98, expiration threshold: 1"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiryAtMax() {
+	validator := &ChannelPaymentValidator{
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+		maxChannelExpiryBlocks:     func() *big.Int { return big.NewInt(100) },
+	}
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(199)
+
+	err := validator.Validate(context.Background(), suite.payment(), channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiryBeyondMax() {
+	validator := &ChannelPaymentValidator{
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+		maxChannelExpiryBlocks:     func() *big.Int { return big.NewInt(100) },
+	}
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(200)
+
+	err := validator.Validate(context.Background(), suite.payment(), channel)
+
+	assert.Equal(suite.T(), NewPaymentError(InvalidArgument, "payment channel expires too far in the future, expiration time: 200, current block: 99, max expiry blocks: 100"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiryUnlimited() {
+	validator := &ChannelPaymentValidator{
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+		maxChannelExpiryBlocks:     func() *big.Int { return big.NewInt(0) },
+	}
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(1000000)
+
+	err := validator.Validate(context.Background(), suite.payment(), channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+// clockSkewValidator returns validator which tolerates 30 seconds clock skew
+// with 12 seconds block interval, i.e. 3 blocks.
+func clockSkewValidator(blockInterval func() (time.Duration, error)) *ChannelPaymentValidator {
+	return &ChannelPaymentValidator{
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+		maxChannelExpiryBlocks:     func() *big.Int { return big.NewInt(100) },
+		maxClockSkew:               func() time.Duration { return 30 * time.Second },
+		blockInterval:              blockInterval,
+	}
+}
+
+func twelveSecondBlocks() (time.Duration, error) {
+	return 12 * time.Second, nil
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiredWithinClockSkew() {
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(97)
+
+	err := clockSkewValidator(twelveSecondBlocks).Validate(context.Background(), suite.payment(), channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiredBeyondClockSkew() {
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(96)
+
+	err := clockSkewValidator(twelveSecondBlocks).Validate(context.Background(), suite.payment(), channel)
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionExpired, Unauthenticated, "payment channel is near to be expired, expiration time: 96, current block: 99, expiration threshold: 0"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiryBeyondMaxWithinClockSkew() {
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(202)
+
+	err := clockSkewValidator(twelveSecondBlocks).Validate(context.Background(), suite.payment(), channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentChannelExpiryBeyondMaxAndClockSkew() {
+	channel := suite.channel()
+	channel.Expiration = big.NewInt(203)
+
+	err := clockSkewValidator(twelveSecondBlocks).Validate(context.Background(), suite.payment(), channel)
+
+	assert.Equal(suite.T(), NewPaymentError(InvalidArgument, "payment channel expires too far in the future, expiration time: 203, current block: 99, max expiry blocks: 100"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentCannotGetBlockInterval() {
+	validator := clockSkewValidator(func() (time.Duration, error) {
+		return 0, errors.New("blockchain error")
+	})
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Equal(suite.T(), NewPaymentError(Internal, "cannot determine block interval"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentAmountLessThanMaxAuthorized() {
+	payment := suite.payment()
+	channel := suite.channel()
+	channel.AuthorizedAmount = big.NewInt(12000)
+	channel.MaxAuthorizedAmount = big.NewInt(12400)
+
+	err := suite.validator.Validate(context.Background(), payment, channel)
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionUnderpaid, Unauthenticated, "payment amount 12345 is less than previously authorized amount 12400"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentAmountGreaterThanMaxAuthorized() {
+	payment := suite.payment()
+	channel := suite.channel()
+	channel.AuthorizedAmount = big.NewInt(12000)
+	channel.MaxAuthorizedAmount = big.NewInt(12300)
+
+	err := suite.validator.Validate(context.Background(), payment, channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) blockLagValidator(blockTime time.Time) *ChannelPaymentValidator {
+	return &ChannelPaymentValidator{
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+		currentBlockTime:           func() (time.Time, error) { return blockTime, nil },
+		maxBlockLag:                func() time.Duration { return time.Minute },
+		now:                        func() time.Time { return testBlockLagNow },
+	}
+}
+
+var testBlockLagNow = time.Date(2018, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func (suite *ValidationTestSuite) TestValidatePaymentFreshBlock() {
+	validator := suite.blockLagValidator(testBlockLagNow.Add(-time.Minute))
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentStaleBlock() {
+	validator := suite.blockLagValidator(testBlockLagNow.Add(-time.Minute - time.Second))
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Equal(suite.T(), NewPaymentError(Unavailable, "daemon is behind on block sync, current block is 1m1s old, max block lag: 1m0s"), err)
+	assert.Equal(suite.T(), codes.Unavailable, paymentErrorToGrpcError(err).Status.Code())
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentBlockLagWithinClockSkew() {
+	validator := suite.blockLagValidator(testBlockLagNow.Add(-time.Minute - 10*time.Second))
+	validator.maxClockSkew = func() time.Duration { return 10 * time.Second }
+	validator.blockInterval = func() (time.Duration, error) { return 12 * time.Second, nil }
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentBlockLagExceedsClockSkew() {
+	validator := suite.blockLagValidator(testBlockLagNow.Add(-time.Minute - 11*time.Second))
+	validator.maxClockSkew = func() time.Duration { return 10 * time.Second }
+	validator.blockInterval = func() (time.Duration, error) { return 12 * time.Second, nil }
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
 
-	assert.Equal(suite.T(), NewPaymentError(Unauthenticated, "payment channel is near to be expired, expiration time: 99, current block: 98, expiration threshold: 1"), err)
+	assert.Equal(suite.T(), NewPaymentError(Unavailable, "daemon is behind on block sync, current block is 1m11s old, max block lag: 1m0s"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentCannotGetBlockTime() {
+	validator := suite.blockLagValidator(testBlockLagNow)
+	validator.currentBlockTime = func() (time.Time, error) { return time.Time{}, errors.New("blockchain error") }
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Equal(suite.T(), NewPaymentError(Internal, "cannot determine current block time"), err)
 }
 
 func (suite *ValidationTestSuite) TestValidatePaymentAmountIsTooBig() {
@@ -202,9 +427,71 @@ func (suite *ValidationTestSuite) TestValidatePaymentAmountIsTooBig() {
 	channel := suite.channel()
 	channel.FullAmount = big.NewInt(12345)
 
-	err := suite.validator.Validate(payment, suite.channel())
+	err := suite.validator.Validate(context.Background(), payment, suite.channel())
 
-	assert.Equal(suite.T(), NewPaymentError(Unauthenticated, "not enough tokens on payment channel, channel amount: 12345, payment amount: 12346"), err)
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionUnderpaid, FailedPrecondition, "not enough tokens on payment channel, channel amount: 12345, payment amount: 12346"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentAmountIsEqualToChannelAmount() {
+	payment := suite.payment()
+	payment.Amount = big.NewInt(12345)
+	SignTestPayment(payment, suite.signerPrivateKey)
+	channel := suite.channel()
+	channel.FullAmount = big.NewInt(12345)
+
+	err := suite.validator.Validate(context.Background(), payment, channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) zeroBalancePayment() (*Payment, *PaymentChannelData) {
+	payment := suite.payment()
+	payment.Amount = big.NewInt(0)
+	SignTestPayment(payment, suite.signerPrivateKey)
+	channel := suite.channel()
+	channel.FullAmount = big.NewInt(0)
+	return payment, channel
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentZeroBalanceChannelAllowed() {
+	validator := suite.validator
+	validator.allowZeroBalanceChannels = func() bool { return true }
+	payment, channel := suite.zeroBalancePayment()
+
+	err := validator.Validate(context.Background(), payment, channel)
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentZeroBalanceChannelAllowedNotEnoughTokens() {
+	validator := suite.validator
+	validator.allowZeroBalanceChannels = func() bool { return true }
+	payment := suite.payment()
+	channel := suite.channel()
+	channel.FullAmount = big.NewInt(0)
+
+	err := validator.Validate(context.Background(), payment, channel)
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionUnderpaid, FailedPrecondition, "not enough tokens on payment channel, channel amount: 0, payment amount: 12345"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentZeroBalanceChannelRejected() {
+	validator := suite.validator
+	validator.allowZeroBalanceChannels = func() bool { return false }
+	payment, channel := suite.zeroBalancePayment()
+
+	err := validator.Validate(context.Background(), payment, channel)
+
+	assert.Equal(suite.T(), NewPaymentError(FailedPrecondition, "payment channel has zero balance, channels without funds are not accepted"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidatePaymentFundedChannelZeroBalanceDisallowed() {
+	validator := suite.validator
+	validator.allowZeroBalanceChannels = func() bool { return false }
+
+	err := validator.Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
 }
 
 func (suite *ValidationTestSuite) TestGetPublicKeyFromPayment() {
@@ -237,3 +524,111 @@ func (suite *ValidationTestSuite) TestGetPublicKeyFromPayment2() {
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), blockchain.HexToAddress("0x592E3C0f3B038A0D673F19a18a773F993d4b2610"), *address)
 }
+
+func (suite *ValidationTestSuite) TestValidateSignatureFormat() {
+	signature := blockchain.HexToBytes("0xde4e998341307b036e460b1cc1593ddefe2e9ea261bd6c3d75967b29b2c3d0a24969b4a32b099ae2eded90bbc213ad0a159a66af6d55be7e04f724ffa52ce3cc1b")
+	withV := func(v byte) []byte {
+		return append(append([]byte{}, signature[:64]...), v)
+	}
+
+	assert.Nil(suite.T(), validateSignatureFormat(signature))
+	assert.Nil(suite.T(), validateSignatureFormat(withV(0)))
+	assert.Nil(suite.T(), validateSignatureFormat(withV(1)))
+	assert.Nil(suite.T(), validateSignatureFormat(withV(27)))
+	assert.Equal(suite.T(), errors.New("incorrect signature values"), validateSignatureFormat(withV(29)))
+	assert.Equal(suite.T(), errors.New("incorrect signature length 66, expected 65"), validateSignatureFormat(append(withV(27), 0)))
+	assert.Equal(suite.T(), errors.New("incorrect signature length 0, expected 65"), validateSignatureFormat(nil))
+}
+
+func (suite *ValidationTestSuite) eip712Validator() *ChannelPaymentValidator {
+	return &ChannelPaymentValidator{
+		currentBlock:               func(context.Context) (*big.Int, error) { return big.NewInt(99), nil },
+		paymentExpirationThreshold: func() *big.Int { return big.NewInt(0) },
+		signatureScheme:            func() string { return signatureSchemeEIP712 },
+		typedDataDomain: func() (*typedDataDomain, error) {
+			return &typedDataDomain{ChainID: big.NewInt(1), VerifyingContract: suite.mpeContractAddress}, nil
+		},
+	}
+}
+
+func (suite *ValidationTestSuite) eip712Payment() *Payment {
+	payment := suite.payment()
+	domain := &typedDataDomain{ChainID: big.NewInt(1), VerifyingContract: suite.mpeContractAddress}
+	signature, err := crypto.Sign(typedDataHash(domain, payment), suite.signerPrivateKey)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	payment.Signature = signature
+	return payment
+}
+
+func (suite *ValidationTestSuite) TestValidateEIP712Payment() {
+	err := suite.eip712Validator().Validate(context.Background(), suite.eip712Payment(), suite.channel())
+
+	assert.Nil(suite.T(), err, "Unexpected error: %v", err)
+}
+
+func (suite *ValidationTestSuite) TestValidateEIP712PaymentTampered() {
+	payment := suite.eip712Payment()
+	payment.Amount = big.NewInt(12346)
+	channel := suite.channel()
+	channel.FullAmount = big.NewInt(20000)
+
+	err := suite.eip712Validator().Validate(context.Background(), payment, channel)
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidateEIP712PaymentAnotherContract() {
+	validator := suite.eip712Validator()
+	validator.typedDataDomain = func() (*typedDataDomain, error) {
+		return &typedDataDomain{ChainID: big.NewInt(1), VerifyingContract: blockchain.HexToAddress("0x39ee715b50e78a920120c1ded58b1a47f571ab75")}, nil
+	}
+
+	err := validator.Validate(context.Background(), suite.eip712Payment(), suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidateEIP712PaymentAnotherChain() {
+	validator := suite.eip712Validator()
+	validator.typedDataDomain = func() (*typedDataDomain, error) {
+		return &typedDataDomain{ChainID: big.NewInt(3), VerifyingContract: suite.mpeContractAddress}, nil
+	}
+
+	err := validator.Validate(context.Background(), suite.eip712Payment(), suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidateEIP712PaymentUnknownChainID() {
+	validator := suite.eip712Validator()
+	validator.typedDataDomain = func() (*typedDataDomain, error) {
+		return nil, errors.New("cannot get chain id: connection refused")
+	}
+
+	err := validator.Validate(context.Background(), suite.eip712Payment(), suite.channel())
+
+	assert.Equal(suite.T(), NewPaymentError(Internal, "cannot determine chain id"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidateEIP712PaymentSignedAsRaw() {
+	err := suite.eip712Validator().Validate(context.Background(), suite.payment(), suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), err)
+}
+
+func (suite *ValidationTestSuite) TestValidateRawPaymentSignedAsEIP712() {
+	err := suite.validator.Validate(context.Background(), suite.eip712Payment(), suite.channel())
+
+	assert.Equal(suite.T(), newRejectionError(metrics.RejectionBadSignature, Unauthenticated, "payment is not signed by channel signer"), err)
+}
+
+func TestTypedDataHash(t *testing.T) {
+	domain := &typedDataDomain{ChainID: big.NewInt(1), VerifyingContract: blockchain.HexToAddress("0xf25186b5081ff5ce73482ad761db0eb0d25abfbf")}
+	payment := &Payment{ChannelID: big.NewInt(42), ChannelNonce: big.NewInt(3), Amount: big.NewInt(12345)}
+
+	hash := typedDataHash(domain, payment)
+
+	assert.Equal(t, "00cadc155ff2fdaf3ac7cfdbb3ec6e79e0706dc7b6b785c7b68f7614ac78533f", hex.EncodeToString(hash))
+}
@@ -0,0 +1,237 @@
+package escrow
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/singnet/snet-daemon/metrics"
+	"github.com/singnet/snet-daemon/util/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+// WebhookDelivery is a notification which should be posted to the webhook.
+type WebhookDelivery struct {
+	ID   string
+	URL  string
+	Body []byte
+	// Attempts is a number of failed delivery attempts
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+}
+
+// WebhookDeliveryStorage keeps deliveries which are waiting for the next
+// attempt and dead-lettered deliveries which are not retried anymore.
+type WebhookDeliveryStorage struct {
+	pending    TypedAtomicStorage
+	deadLetter TypedAtomicStorage
+}
+
+// NewWebhookDeliveryStorage returns new instance of WebhookDeliveryStorage.
+func NewWebhookDeliveryStorage(atomicStorage AtomicStorage) *WebhookDeliveryStorage {
+	return &WebhookDeliveryStorage{
+		pending:    newTypedWebhookDeliveryStorage(atomicStorage, "/webhook/pending"),
+		deadLetter: newTypedWebhookDeliveryStorage(atomicStorage, "/webhook/dead-letter"),
+	}
+}
+
+func newTypedWebhookDeliveryStorage(atomicStorage AtomicStorage, keyPrefix string) TypedAtomicStorage {
+	return &TypedAtomicStorageImpl{
+		atomicStorage: &PrefixedAtomicStorage{
+			delegate:  atomicStorage,
+			keyPrefix: keyPrefix,
+		},
+		keySerializer:     serialize,
+		valueSerializer:   serialize,
+		valueDeserializer: deserialize,
+		valueType:         reflect.TypeOf(WebhookDelivery{}),
+	}
+}
+
+// GetAllPending returns deliveries which are waiting for the next attempt.
+func (storage *WebhookDeliveryStorage) GetAllPending() (deliveries []*WebhookDelivery, err error) {
+	values, err := storage.pending.GetAll()
+	if err != nil {
+		return
+	}
+	return values.([]*WebhookDelivery), nil
+}
+
+// GetPending returns pending delivery by id.
+func (storage *WebhookDeliveryStorage) GetPending(id string) (delivery *WebhookDelivery, ok bool, err error) {
+	value, ok, err := storage.pending.Get(id)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return value.(*WebhookDelivery), true, nil
+}
+
+// Put saves pending delivery.
+func (storage *WebhookDeliveryStorage) Put(delivery *WebhookDelivery) (err error) {
+	return storage.pending.Put(delivery.ID, delivery)
+}
+
+// Delete removes pending delivery.
+func (storage *WebhookDeliveryStorage) Delete(delivery *WebhookDelivery) (err error) {
+	return storage.pending.Delete(delivery.ID)
+}
+
+// MarkDeadLettered moves delivery to the dead-letter store.
+func (storage *WebhookDeliveryStorage) MarkDeadLettered(delivery *WebhookDelivery) (err error) {
+	if err = storage.deadLetter.Put(delivery.ID, delivery); err != nil {
+		return
+	}
+	return storage.pending.Delete(delivery.ID)
+}
+
+// GetAllDeadLettered returns deliveries which are not delivered after all
+// attempts.
+func (storage *WebhookDeliveryStorage) GetAllDeadLettered() (deliveries []*WebhookDelivery, err error) {
+	values, err := storage.deadLetter.GetAll()
+	if err != nil {
+		return
+	}
+	return values.([]*WebhookDelivery), nil
+}
+
+// WebhookDeliveryQueue delivers notifications kept in the storage, so they
+// are not lost when receiver is unavailable or daemon is restarted. Failed
+// delivery is retried with exponential backoff until MaxAttempts of the
+// policy is reached or receiver rejects it with client error, then it is
+// moved to the dead-letter store. Notification can be delivered more than
+// once if daemon stops right after posting it.
+type WebhookDeliveryQueue struct {
+	storage  *WebhookDeliveryStorage
+	post     func(url string, body []byte) error
+	policy   retry.Policy
+	recorder metrics.Recorder
+	now      func() time.Time
+
+	// lock prevents concurrent delivery of the same notification by
+	// the daemon
+	lock sync.Mutex
+}
+
+// NewWebhookDeliveryQueue returns queue which posts notifications using
+// post, recorder can be nil if metrics are disabled.
+func NewWebhookDeliveryQueue(storage *WebhookDeliveryStorage, post func(url string, body []byte) error, policy retry.Policy, recorder metrics.Recorder) *WebhookDeliveryQueue {
+	return &WebhookDeliveryQueue{
+		storage:  storage,
+		post:     post,
+		policy:   policy,
+		recorder: recorder,
+		now:      time.Now,
+	}
+}
+
+// Enqueue saves notification for delivery to the url, it is delivered by
+// the next DeliverDue call.
+func (queue *WebhookDeliveryQueue) Enqueue(url string, body []byte) (err error) {
+	_, err = queue.enqueue(url, body)
+	return
+}
+
+// EnqueueAndDeliver saves notification for delivery to the url and makes
+// the first delivery attempt of this notification only, other pending
+// notifications are left to DeliverDue. Error is returned if notification
+// cannot be saved, failed delivery is retried by DeliverDue.
+func (queue *WebhookDeliveryQueue) EnqueueAndDeliver(url string, body []byte) (err error) {
+	delivery, err := queue.enqueue(url, body)
+	if err != nil {
+		return
+	}
+
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	// delivery could be attempted by DeliverDue already
+	pending, ok, e := queue.storage.GetPending(delivery.ID)
+	if e != nil {
+		log.WithError(e).WithField("id", delivery.ID).Warn("Cannot read webhook delivery, it is left to the next retry")
+		return nil
+	}
+	if !ok || pending.Attempts > 0 {
+		return nil
+	}
+	if e = queue.deliver(pending); e != nil {
+		log.WithError(e).WithField("id", delivery.ID).Warn("Cannot save result of the webhook delivery attempt")
+	}
+	return nil
+}
+
+func (queue *WebhookDeliveryQueue) enqueue(url string, body []byte) (delivery *WebhookDelivery, err error) {
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return nil, fmt.Errorf("cannot generate delivery id: %v", err)
+	}
+	delivery = &WebhookDelivery{
+		ID:          hex.EncodeToString(id),
+		URL:         url,
+		Body:        body,
+		NextAttempt: queue.now(),
+	}
+	if err = queue.storage.Put(delivery); err != nil {
+		return nil, fmt.Errorf("cannot save webhook delivery: %v", err)
+	}
+	queue.count("webhook.enqueued")
+	return delivery, nil
+}
+
+// DeliverDue tries to deliver all pending notifications which next attempt
+// time has come. Error is returned only if pending notifications cannot be
+// read, delivery which result cannot be saved is logged and attempted again
+// next time.
+func (queue *WebhookDeliveryQueue) DeliverDue() (err error) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	deliveries, err := queue.storage.GetAllPending()
+	if err != nil {
+		return fmt.Errorf("cannot read pending webhook deliveries: %v", err)
+	}
+	for _, delivery := range deliveries {
+		if delivery.NextAttempt.After(queue.now()) {
+			continue
+		}
+		if e := queue.deliver(delivery); e != nil {
+			log.WithError(e).WithField("id", delivery.ID).Warn("Cannot save result of the webhook delivery attempt")
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt to deliver the notification, error is returned
+// only if the result of the attempt cannot be saved.
+func (queue *WebhookDeliveryQueue) deliver(delivery *WebhookDelivery) (err error) {
+	logger := log.WithField("url", delivery.URL).WithField("id", delivery.ID)
+
+	e := queue.post(delivery.URL, delivery.Body)
+	if e == nil {
+		queue.count("webhook.delivered")
+		return queue.storage.Delete(delivery)
+	}
+
+	delivery.Attempts++
+	delivery.LastError = e.Error()
+	retryable := queue.policy.Retryable == nil || queue.policy.Retryable(e)
+	if !retryable || delivery.Attempts >= queue.policy.MaxAttempts {
+		logger.WithError(e).WithField("attempts", delivery.Attempts).Error("Webhook delivery failed, notification is moved to the dead-letter store")
+		queue.count("webhook.dead_lettered")
+		return queue.storage.MarkDeadLettered(delivery)
+	}
+
+	delivery.NextAttempt = queue.now().Add(queue.policy.Backoff(delivery.Attempts))
+	logger.WithError(e).WithField("nextAttempt", delivery.NextAttempt).Warn("Webhook delivery failed, it will be retried")
+	queue.count("webhook.retried")
+	return queue.storage.Put(delivery)
+}
+
+func (queue *WebhookDeliveryQueue) count(name string) {
+	if queue.recorder != nil {
+		queue.recorder.Count(name, 1)
+	}
+}
@@ -0,0 +1,212 @@
+package escrow
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookPostMock struct {
+	errors []error
+	posted []string
+}
+
+func (mock *webhookPostMock) post(url string, body []byte) error {
+	mock.posted = append(mock.posted, url+" "+string(body))
+	if len(mock.posted) > len(mock.errors) {
+		return nil
+	}
+	return mock.errors[len(mock.posted)-1]
+}
+
+type webhookQueueTest struct {
+	queue    *WebhookDeliveryQueue
+	storage  *WebhookDeliveryStorage
+	post     *webhookPostMock
+	recorder *recorderMock
+	now      time.Time
+}
+
+func newWebhookQueueTest(errs ...error) *webhookQueueTest {
+	test := &webhookQueueTest{
+		storage:  NewWebhookDeliveryStorage(NewMemStorage()),
+		post:     &webhookPostMock{errors: errs},
+		recorder: &recorderMock{counters: make(map[string]int64)},
+		now:      time.Unix(1546300800, 0),
+	}
+	policy := DefaultWebhookRetryPolicy(3, time.Minute, time.Hour)
+	policy.Jitter = 0
+	test.queue = NewWebhookDeliveryQueue(test.storage, test.post.post, policy, test.recorder)
+	test.queue.now = func() time.Time { return test.now }
+	return test
+}
+
+func (test *webhookQueueTest) pending(t *testing.T) []*WebhookDelivery {
+	deliveries, err := test.storage.GetAllPending()
+	assert.Nil(t, err)
+	return deliveries
+}
+
+func (test *webhookQueueTest) deadLettered(t *testing.T) []*WebhookDelivery {
+	deliveries, err := test.storage.GetAllDeadLettered()
+	assert.Nil(t, err)
+	return deliveries
+}
+
+var errWebhookUnavailable = errors.New("connection refused")
+
+func TestWebhookDeliveryQueueDeliversEnqueued(t *testing.T) {
+	test := newWebhookQueueTest()
+
+	assert.Nil(t, test.queue.Enqueue("http://receiver", []byte("{}")))
+	assert.Nil(t, test.queue.DeliverDue())
+
+	assert.Equal(t, []string{"http://receiver {}"}, test.post.posted)
+	assert.Empty(t, test.pending(t))
+	assert.Equal(t, int64(1), test.recorder.counters["webhook.enqueued"])
+	assert.Equal(t, int64(1), test.recorder.counters["webhook.delivered"])
+}
+
+func TestWebhookDeliveryQueueSucceedsOnRetry(t *testing.T) {
+	test := newWebhookQueueTest(errWebhookUnavailable)
+	test.queue.Enqueue("http://receiver", []byte("{}"))
+
+	test.queue.DeliverDue()
+
+	pending := test.pending(t)
+	if assert.Equal(t, 1, len(pending)) {
+		assert.Equal(t, 1, pending[0].Attempts)
+		assert.Equal(t, "connection refused", pending[0].LastError)
+		assert.Equal(t, test.now.Add(time.Minute), pending[0].NextAttempt)
+	}
+
+	test.now = test.now.Add(time.Minute)
+	test.queue.DeliverDue()
+
+	assert.Equal(t, 2, len(test.post.posted))
+	assert.Empty(t, test.pending(t))
+	assert.Empty(t, test.deadLettered(t))
+	assert.Equal(t, int64(1), test.recorder.counters["webhook.retried"])
+	assert.Equal(t, int64(1), test.recorder.counters["webhook.delivered"])
+}
+
+func TestWebhookDeliveryQueueDoesNotRetryBeforeBackoff(t *testing.T) {
+	test := newWebhookQueueTest(errWebhookUnavailable)
+	test.queue.Enqueue("http://receiver", []byte("{}"))
+	test.queue.DeliverDue()
+
+	test.now = test.now.Add(30 * time.Second)
+	test.queue.DeliverDue()
+
+	assert.Equal(t, 1, len(test.post.posted))
+	assert.Equal(t, 1, len(test.pending(t)))
+}
+
+func TestWebhookDeliveryQueueDeadLettersAfterMaxAttempts(t *testing.T) {
+	test := newWebhookQueueTest(errWebhookUnavailable, errWebhookUnavailable, errWebhookUnavailable)
+	test.queue.Enqueue("http://receiver", []byte("{}"))
+
+	for i := 0; i < 5; i++ {
+		test.queue.DeliverDue()
+		test.now = test.now.Add(time.Hour)
+	}
+
+	assert.Equal(t, 3, len(test.post.posted), "delivery is not retried after max attempts")
+	assert.Empty(t, test.pending(t))
+	deadLettered := test.deadLettered(t)
+	if assert.Equal(t, 1, len(deadLettered)) {
+		assert.Equal(t, "http://receiver", deadLettered[0].URL)
+		assert.Equal(t, []byte("{}"), deadLettered[0].Body)
+		assert.Equal(t, 3, deadLettered[0].Attempts)
+	}
+	assert.Equal(t, int64(2), test.recorder.counters["webhook.retried"])
+	assert.Equal(t, int64(1), test.recorder.counters["webhook.dead_lettered"])
+}
+
+func TestWebhookDeliveryQueueDeadLettersClientError(t *testing.T) {
+	test := newWebhookQueueTest(&webhookDeliveryError{url: "http://receiver", statusCode: http.StatusBadRequest})
+	test.queue.Enqueue("http://receiver", []byte("{}"))
+
+	test.queue.DeliverDue()
+
+	assert.Empty(t, test.pending(t))
+	assert.Equal(t, 1, len(test.deadLettered(t)))
+}
+
+// deadLetterFailingStorage cannot save deliveries
+type deadLetterFailingStorage struct {
+	TypedAtomicStorage
+}
+
+func (storage *deadLetterFailingStorage) Put(key interface{}, value interface{}) (err error) {
+	return errors.New("storage is down")
+}
+
+func TestWebhookDeliveryQueueContinuesAfterStorageError(t *testing.T) {
+	test := newWebhookQueueTest()
+	test.storage.deadLetter = &deadLetterFailingStorage{}
+	test.queue.post = func(url string, body []byte) error {
+		test.post.posted = append(test.post.posted, url)
+		if url == "http://rejecting" {
+			return &webhookDeliveryError{url: url, statusCode: http.StatusBadRequest}
+		}
+		return nil
+	}
+	test.queue.Enqueue("http://rejecting", []byte("{}"))
+	test.queue.Enqueue("http://receiver", []byte("{}"))
+
+	err := test.queue.DeliverDue()
+
+	assert.Nil(t, err)
+	sort.Strings(test.post.posted)
+	assert.Equal(t, []string{"http://receiver", "http://rejecting"}, test.post.posted)
+	pending := test.pending(t)
+	if assert.Equal(t, 1, len(pending)) {
+		assert.Equal(t, "http://rejecting", pending[0].URL, "delivery is kept when it cannot be dead-lettered")
+	}
+}
+
+func TestWebhookDeliveryQueueEnqueueAndDeliver(t *testing.T) {
+	test := newWebhookQueueTest()
+	test.queue.Enqueue("http://other", []byte("{}"))
+
+	err := test.queue.EnqueueAndDeliver("http://receiver", []byte("{}"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://receiver {}"}, test.post.posted, "only enqueued notification is delivered")
+	pending := test.pending(t)
+	if assert.Equal(t, 1, len(pending)) {
+		assert.Equal(t, "http://other", pending[0].URL)
+		assert.Equal(t, 0, pending[0].Attempts)
+	}
+}
+
+func TestWebhookDeliveryQueueWithoutRecorder(t *testing.T) {
+	test := newWebhookQueueTest()
+	test.queue.recorder = nil
+
+	assert.Nil(t, test.queue.Enqueue("http://receiver", []byte("{}")))
+	assert.Nil(t, test.queue.DeliverDue())
+}
+
+func TestQueuedClaimWebhookNotifier(t *testing.T) {
+	test := newWebhookQueueTest(errWebhookUnavailable)
+	notifier := NewQueuedClaimWebhookNotifier([]string{"http://first", "http://second"}, test.queue)
+
+	err := notifier.Notify(testClaimNotification)
+
+	assert.Nil(t, err, "failed delivery is retried by queue")
+	sort.Strings(test.post.posted)
+	assert.Equal(t, []string{
+		"http://first " + testClaimNotificationJson,
+		"http://second " + testClaimNotificationJson,
+	}, test.post.posted)
+	pending := test.pending(t)
+	if assert.Equal(t, 1, len(pending)) {
+		assert.Equal(t, 1, pending[0].Attempts)
+	}
+}
@@ -3,15 +3,22 @@ package etcddb
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/util/endpoint"
+	retryutil "github.com/singnet/snet-daemon/util/retry"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // EtcdClientMutex mutex struct for etcd client
@@ -34,6 +41,11 @@ type EtcdClient struct {
 	timeout time.Duration
 	session *concurrency.Session
 	etcdv3  *clientv3.Client
+	// keyPrefix is added to all keys passed to the client
+	keyPrefix string
+	// endpoints are used to check which cluster members are available when
+	// request fails
+	endpoints []string
 }
 
 // NewEtcdClient create new etcd storage client.
@@ -52,37 +64,185 @@ func NewEtcdClientFromVip(vip *viper.Viper) (client *EtcdClient, err error) {
 
 	log.WithField("PaymentChannelStorageClient", fmt.Sprintf("%+v", conf)).Info()
 
+	endpoints, err := orderEndpoints(vip.GetString(config.EndpointSelectionKey), conf.Endpoints)
+	if err != nil {
+		return
+	}
+
 	etcdv3, err := clientv3.New(clientv3.Config{
-		Endpoints:   conf.Endpoints,
-		DialTimeout: conf.ConnectionTimeout,
+		Endpoints:            endpoints,
+		DialTimeout:          conf.ConnectionTimeout,
+		DialKeepAliveTime:    conf.KeepAliveTime,
+		DialKeepAliveTimeout: conf.KeepAliveTimeout,
 	})
 
 	if err != nil {
 		return
 	}
 
+	status := statusOf(etcdv3)
+	err = waitForReady(func(ctx context.Context) error {
+		_, err := etcdv3.Get(ctx, "health")
+		return err
+	}, conf.StartupTimeout, conf.RequestTimeout)
+	if err != nil {
+		etcdv3.Close()
+		available, _ := checkEndpoints(status, endpoints, conf.RequestTimeout)
+		return nil, fmt.Errorf("etcd cluster %v is not ready (%v of %v endpoints are available, quorum is %v): %v",
+			conf.Endpoints, len(available), len(endpoints), quorum(len(endpoints)), err)
+	}
+
+	// cluster is ready when quorum is available, unavailable members are
+	// moved to the end of the list to connect to available ones first
+	_, unavailable := checkEndpoints(status, endpoints, conf.RequestTimeout)
+	if len(unavailable) > 0 {
+		for endpoint, e := range unavailable {
+			log.WithError(e).WithField("endpoint", endpoint).Warn("etcd endpoint is unavailable, it is used after available ones")
+		}
+		etcdv3.SetEndpoints(healthyFirst(endpoints, unavailable)...)
+	}
+
 	session, err := concurrency.NewSession(etcdv3)
 	if err != nil {
 		return
 	}
 
 	client = &EtcdClient{
-		timeout: conf.RequestTimeout,
-		session: session,
-		etcdv3:  etcdv3,
+		timeout:   conf.RequestTimeout,
+		session:   session,
+		etcdv3:    etcdv3,
+		keyPrefix: conf.KeyPrefix,
+		endpoints: endpoints,
+	}
+	return
+}
+
+func statusOf(etcdv3 *clientv3.Client) endpointStatus {
+	return func(ctx context.Context, endpoint string) error {
+		_, err := etcdv3.Status(ctx, endpoint)
+		return err
 	}
+}
+
+// describeUnavailable adds number of available cluster members to the error
+// if request failed because etcd cluster is unavailable.
+func (client *EtcdClient) describeUnavailable(err error) error {
+	return describeUnavailable(err, statusOf(client.etcdv3), client.endpoints, client.timeout)
+}
+
+// prefixed returns key which is actually stored in etcd.
+func (client *EtcdClient) prefixed(key string) string {
+	return client.keyPrefix + key
+}
+
+// readinessCheckInterval is a delay between attempts to reach etcd cluster
+// at startup
+var readinessCheckInterval = 500 * time.Millisecond
+
+// waitForReady calls check until it succeeds or startupTimeout elapses. Each
+// check is limited by requestTimeout.
+func waitForReady(check func(ctx context.Context) error, startupTimeout time.Duration, requestTimeout time.Duration) (err error) {
+	deadline := time.Now().Add(startupTimeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		err = check(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(readinessCheckInterval).After(deadline) {
+			return fmt.Errorf("no response after %v, last error: %v", startupTimeout, err)
+		}
+		log.WithError(err).Debug("etcd cluster is not ready yet")
+		time.Sleep(readinessCheckInterval)
+	}
+}
+
+var (
+	endpointSelectorsMutex sync.Mutex
+	// endpointSelectors keeps selectors between clients creation, so
+	// round_robin strategy starts each next client from the next endpoint
+	endpointSelectors = make(map[string]endpoint.Selector)
+)
+
+// orderEndpoints returns cluster endpoints in order they should be used
+// according to endpoint_selection strategy. Client connects to the first
+// available endpoint.
+func orderEndpoints(strategy string, endpoints []string) (ordered []string, err error) {
+	endpointSelectorsMutex.Lock()
+	defer endpointSelectorsMutex.Unlock()
+
+	key := strategy + " " + strings.Join(endpoints, ",")
+	selector, ok := endpointSelectors[key]
+	if !ok {
+		selector, err = endpoint.NewSelector(strategy, endpoints, rand.New(rand.NewSource(time.Now().UnixNano())))
+		if err != nil {
+			return
+		}
+		endpointSelectors[key] = selector
+	}
+
+	ordered = selector.Order()
+	log.WithField("strategy", strategy).WithField("endpoints", ordered).Debug("etcd endpoints order")
 	return
 }
 
+var (
+	// retryInitialBackoff is a delay before the first retry of the request
+	// which failed because etcd cluster is unavailable
+	retryInitialBackoff = 50 * time.Millisecond
+	// retryMaxBackoff limits the delay between retries
+	retryMaxBackoff = time.Second
+)
+
+// retry calls operation until it succeeds, returns non-transient error or
+// ctx is done. Delay between attempts grows exponentially, so brief etcd
+// outage is survived within the request timeout budget.
+func retry(ctx context.Context, operation func(ctx context.Context) error) (err error) {
+	policy := retryutil.Policy{
+		InitialBackoff: retryInitialBackoff,
+		MaxBackoff:     retryMaxBackoff,
+		Retryable: func(err error) bool {
+			if !isTransientError(err) {
+				return false
+			}
+			log.WithError(err).Debug("etcd cluster is unavailable, retry request")
+			return true
+		},
+	}
+	return retryutil.Do(ctx, policy, operation)
+}
+
+// isTransientError returns true if error means that etcd cluster cannot be
+// reached at the moment and request can be repeated.
+func isTransientError(err error) bool {
+	if etcdErr, ok := err.(rpctypes.EtcdError); ok {
+		return etcdErr.Code() == codes.Unavailable
+	}
+	return status.Code(err) == codes.Unavailable
+}
+
 // Get gets value from etcd by key
 func (client *EtcdClient) Get(key string) (value string, ok bool, err error) {
+	return client.GetContext(context.Background(), key)
+}
+
+// GetContext gets value from etcd by key, request is cancelled when ctx is
+// done
+func (client *EtcdClient) GetContext(ctx context.Context, key string) (value string, ok bool, err error) {
 
 	log := log.WithField("func", "Get").WithField("key", key).WithField("client", client)
 
-	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	ctx, cancel := context.WithTimeout(ctx, client.timeout)
 	defer cancel()
 
-	response, err := client.etcdv3.Get(ctx, key)
+	var response *clientv3.GetResponse
+	err = retry(ctx, func(ctx context.Context) (err error) {
+		response, err = client.etcdv3.Get(ctx, client.prefixed(key))
+		return
+	})
+	err = client.describeUnavailable(err)
 
 	if err != nil {
 		log.WithError(err).Error("Unable to get value by key")
@@ -106,8 +266,13 @@ func (client *EtcdClient) GetByKeyPrefix(key string) (values []string, err error
 	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
 	defer cancel()
 
-	keyEnd := clientv3.GetPrefixRangeEnd(key)
-	response, err := client.etcdv3.Get(ctx, key, clientv3.WithRange(keyEnd))
+	keyEnd := clientv3.GetPrefixRangeEnd(client.prefixed(key))
+	var response *clientv3.GetResponse
+	err = retry(ctx, func(ctx context.Context) (err error) {
+		response, err = client.etcdv3.Get(ctx, client.prefixed(key), clientv3.WithRange(keyEnd))
+		return
+	})
+	err = client.describeUnavailable(err)
 
 	if err != nil {
 		log.WithError(err).Error("Unable to get value by key prefix")
@@ -130,7 +295,11 @@ func (client *EtcdClient) Put(key string, value string) (err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
 	defer cancel()
 
-	_, err = etcdv3.Put(ctx, key, value)
+	err = retry(ctx, func(ctx context.Context) (err error) {
+		_, err = etcdv3.Put(ctx, client.prefixed(key), value)
+		return
+	})
+	err = client.describeUnavailable(err)
 	if err != nil {
 		log.WithError(err).Error("Unable to put value by key")
 	}
@@ -146,7 +315,11 @@ func (client *EtcdClient) Delete(key string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
 	defer cancel()
 
-	_, err := etcdv3.Delete(ctx, key)
+	err := retry(ctx, func(ctx context.Context) (err error) {
+		_, err = etcdv3.Delete(ctx, client.prefixed(key))
+		return
+	})
+	err = client.describeUnavailable(err)
 	if err != nil {
 		log.WithError(err).Error("Unable to delete value by key")
 	}
@@ -162,8 +335,14 @@ type EtcdKeyValue struct {
 
 // CompareAndSwap uses CAS operation to set a value
 func (client *EtcdClient) CompareAndSwap(key string, prevValue string, newValue string) (ok bool, err error) {
+	return client.CompareAndSwapContext(context.Background(), key, prevValue, newValue)
+}
 
-	return client.Transaction(
+// CompareAndSwapContext uses CAS operation to set a value, request is
+// cancelled when ctx is done
+func (client *EtcdClient) CompareAndSwapContext(ctx context.Context, key string, prevValue string, newValue string) (ok bool, err error) {
+
+	return client.transaction(ctx,
 		[]EtcdKeyValue{EtcdKeyValue{key: key, value: prevValue}},
 		[]EtcdKeyValue{EtcdKeyValue{key: key, value: newValue}},
 	)
@@ -171,25 +350,34 @@ func (client *EtcdClient) CompareAndSwap(key string, prevValue string, newValue
 
 // Transaction uses CAS operation to compare and set multiple key values
 func (client *EtcdClient) Transaction(compare []EtcdKeyValue, swap []EtcdKeyValue) (ok bool, err error) {
+	return client.transaction(context.Background(), compare, swap)
+}
+
+func (client *EtcdClient) transaction(ctx context.Context, compare []EtcdKeyValue, swap []EtcdKeyValue) (ok bool, err error) {
 
 	log := log.WithField("func", "CompareAndSwap").WithField("client", client)
 
 	etcdv3 := client.etcdv3
-	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	ctx, cancel := context.WithTimeout(ctx, client.timeout)
 	defer cancel()
 
 	cmps := make([]clientv3.Cmp, len(compare))
 
 	for index, cmp := range compare {
-		cmps[index] = clientv3.Compare(clientv3.Value(cmp.key), "=", cmp.value)
+		cmps[index] = clientv3.Compare(clientv3.Value(client.prefixed(cmp.key)), "=", cmp.value)
 	}
 
 	ops := make([]clientv3.Op, len(swap))
 	for index, op := range swap {
-		ops[index] = clientv3.OpPut(op.key, op.value)
+		ops[index] = clientv3.OpPut(client.prefixed(op.key), op.value)
 	}
 
-	response, err := etcdv3.KV.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	var response *clientv3.TxnResponse
+	err = retry(ctx, func(ctx context.Context) (err error) {
+		response, err = etcdv3.KV.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		return
+	})
+	err = client.describeUnavailable(err)
 
 	if err != nil {
 		keys := []string{}
@@ -206,9 +394,15 @@ func (client *EtcdClient) Transaction(compare []EtcdKeyValue, swap []EtcdKeyValu
 
 // PutIfAbsent puts value if absent
 func (client *EtcdClient) PutIfAbsent(key string, value string) (ok bool, err error) {
+	return client.PutIfAbsentContext(context.Background(), key, value)
+}
+
+// PutIfAbsentContext puts value if absent, request is cancelled when ctx is
+// done
+func (client *EtcdClient) PutIfAbsentContext(ctx context.Context, key string, value string) (ok bool, err error) {
 	log := log.WithField("func", "PutIfAbsent").WithField("key", key).WithField("client", client)
 
-	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	ctx, cancel := context.WithTimeout(ctx, client.timeout)
 	defer cancel()
 
 	etcdv3 := client.etcdv3
@@ -220,6 +414,7 @@ func (client *EtcdClient) PutIfAbsent(key string, value string) (ok bool, err er
 	}
 
 	// TODO: implement it using etcdv3.KV.Txn(ctx).If(...).Then(...).Commit() as in CompareAndSwap()
+	key = client.prefixed(key)
 	mu := concurrency.NewMutex(session, key)
 	err = mu.Lock(ctx)
 
@@ -254,7 +449,7 @@ func (client *EtcdClient) PutIfAbsent(key string, value string) (ok bool, err er
 // NewMutex Create a mutex for the given key
 func (client *EtcdClient) NewMutex(key string) (mutex *EtcdClientMutex, err error) {
 
-	m := concurrency.NewMutex(client.session, key)
+	m := concurrency.NewMutex(client.session, client.prefixed(key))
 	mutex = &EtcdClientMutex{mutex: m}
 	return
 }
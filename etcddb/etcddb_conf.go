@@ -1,6 +1,9 @@
 package etcddb
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,11 +16,22 @@ import (
 // EtcdClientConf config
 // ConnectionTimeout - timeout for failing to establish a connection
 // RequestTimeout    - per request timeout
+// StartupTimeout    - time to wait until etcd cluster is ready to handle
+//                     requests, it is set by etcd_startup_timeout
+// KeepAliveTime     - period of pinging the connected endpoint, client
+//                     switches to another endpoint when ping fails
+// KeepAliveTimeout  - time to wait for the ping response
 // Endpoints         - cluster endpoints
+// KeyPrefix         - prefix added to all keys stored by the client, it
+//                     isolates services which share the same etcd cluster
 type EtcdClientConf struct {
 	ConnectionTimeout time.Duration `json:"connection_timeout" mapstructure:"connection_timeout"`
 	RequestTimeout    time.Duration `json:"request_timeout" mapstructure:"request_timeout"`
+	StartupTimeout    time.Duration `json:"startup_timeout" mapstructure:"startup_timeout"`
+	KeepAliveTime     time.Duration `json:"keep_alive_time" mapstructure:"keep_alive_time"`
+	KeepAliveTimeout  time.Duration `json:"keep_alive_timeout" mapstructure:"keep_alive_timeout"`
 	Endpoints         []string
+	KeyPrefix         string `json:"key_prefix" mapstructure:"key_prefix"`
 }
 
 // GetEtcdClientConf gets EtcdServerConf from viper
@@ -29,6 +43,19 @@ func GetEtcdClientConf(vip *viper.Viper) (conf *EtcdClientConf, err error) {
 	conf = &EtcdClientConf{}
 	subVip := config.SubWithDefault(vip, key)
 	err = subVip.Unmarshal(conf)
+	if err != nil {
+		return
+	}
+
+	// startup_timeout of the client is deprecated, it is used only when
+	// etcd_startup_timeout is not set in the configuration file
+	deprecated := subVip.IsSet("startup_timeout")
+	if deprecated {
+		log.Warnf("%v.startup_timeout is deprecated, use %v instead", key, config.EtcdStartupTimeoutKey)
+	}
+	if !deprecated || vip.InConfig(config.EtcdStartupTimeoutKey) {
+		conf.StartupTimeout = vip.GetDuration(config.EtcdStartupTimeoutKey)
+	}
 	return
 }
 
@@ -81,6 +108,7 @@ func GetEtcdServerConf(vip *viper.Viper) (conf *EtcdServerConf, err error) {
 	}
 
 	if !vip.InConfig(strings.ToLower(key)) {
+		err = conf.validateCluster()
 		return
 	}
 
@@ -92,11 +120,51 @@ func GetEtcdServerConf(vip *viper.Viper) (conf *EtcdServerConf, err error) {
 		return
 	}
 
+	err = conf.validateCluster()
+	if err != nil {
+		return
+	}
+
 	err = initEtcdLogger(conf)
 
 	return
 }
 
+// validateCluster checks that Cluster string has "name=peerURL,..." format
+// and contains the local node with ID and peer URL composed from Scheme,
+// Host and PeerPort. Otherwise etcd fails to start with unclear error.
+func (conf *EtcdServerConf) validateCluster() error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	localPeerURL := fmt.Sprintf("%v://%v:%v", conf.Scheme, conf.Host, conf.PeerPort)
+	var localURLs []string
+	for _, member := range strings.Split(conf.Cluster, ",") {
+		parts := strings.SplitN(strings.TrimSpace(member), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("incorrect member \"%v\" in payment_channel_storage_server.cluster, expected format is \"id=peer_url\"", member)
+		}
+		peerURL, err := url.Parse(parts[1])
+		if err != nil || peerURL.Scheme == "" || peerURL.Host == "" {
+			return fmt.Errorf("incorrect peer URL \"%v\" of member \"%v\" in payment_channel_storage_server.cluster", parts[1], parts[0])
+		}
+		if parts[0] != conf.ID {
+			continue
+		}
+		localURLs = append(localURLs, parts[1])
+		if peerURL.Scheme == conf.Scheme && peerURL.Hostname() == conf.Host && peerURL.Port() == strconv.Itoa(conf.PeerPort) {
+			return nil
+		}
+	}
+
+	if len(localURLs) == 0 {
+		return fmt.Errorf("payment_channel_storage_server.cluster \"%v\" doesn't contain member with id \"%v\"", conf.Cluster, conf.ID)
+	}
+	return fmt.Errorf("peer URL of the member \"%v\" in payment_channel_storage_server.cluster is %v, expected %v according to scheme, host and peer_port",
+		conf.ID, localURLs, localPeerURL)
+}
+
 // capnslog to logrus formatter implementation
 // with methods Format and Flush
 type capnslogToLogrusLogFormatter struct {
@@ -18,7 +18,11 @@ func TestDefaultEtcdClientConf(t *testing.T) {
 
 	assert.Equal(t, 5*time.Second, conf.ConnectionTimeout)
 	assert.Equal(t, 3*time.Second, conf.RequestTimeout)
+	assert.Equal(t, time.Minute, conf.StartupTimeout)
+	assert.Equal(t, 10*time.Second, conf.KeepAliveTime)
+	assert.Equal(t, 3*time.Second, conf.KeepAliveTimeout)
 	assert.Equal(t, []string{"http://127.0.0.1:2379"}, conf.Endpoints)
+	assert.Equal(t, "", conf.KeyPrefix)
 }
 
 func TestCustomEtcdClientConf(t *testing.T) {
@@ -28,7 +32,8 @@ func TestCustomEtcdClientConf(t *testing.T) {
 		"payment_channel_storage_client": {
 			"connection_timeout": "15s",
 			"request_timeout": "5s",
-			"endpoints": ["http://127.0.0.1:2479"]
+			"endpoints": ["http://127.0.0.1:2479"],
+			"key_prefix": "service-a/"
 		}
 	}`
 
@@ -41,6 +46,7 @@ func TestCustomEtcdClientConf(t *testing.T) {
 	assert.Equal(t, 15*time.Second, conf.ConnectionTimeout)
 	assert.Equal(t, 5*time.Second, conf.RequestTimeout)
 	assert.Equal(t, []string{"http://127.0.0.1:2479"}, conf.Endpoints)
+	assert.Equal(t, "service-a/", conf.KeyPrefix)
 }
 func TestCustomEtcdClientConfWithDefault(t *testing.T) {
 
@@ -62,6 +68,36 @@ func TestCustomEtcdClientConfWithDefault(t *testing.T) {
 	assert.Equal(t, []string{"http://127.0.0.1:2379"}, conf.Endpoints)
 }
 
+func TestEtcdClientConfStartupTimeout(t *testing.T) {
+	vip := readConfig(t, `{ "etcd_startup_timeout": "15s" }`)
+
+	conf, err := GetEtcdClientConf(vip)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 15*time.Second, conf.StartupTimeout)
+}
+
+func TestEtcdClientConfDeprecatedStartupTimeout(t *testing.T) {
+	vip := readConfig(t, `{ "payment_channel_storage_client": { "startup_timeout": "20s" } }`)
+
+	conf, err := GetEtcdClientConf(vip)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 20*time.Second, conf.StartupTimeout)
+}
+
+func TestEtcdClientConfStartupTimeoutOverridesDeprecated(t *testing.T) {
+	vip := readConfig(t, `{
+		"etcd_startup_timeout": "15s",
+		"payment_channel_storage_client": { "startup_timeout": "20s" }
+	}`)
+
+	conf, err := GetEtcdClientConf(vip)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 15*time.Second, conf.StartupTimeout)
+}
+
 func TestDefaultEtcdServerConf(t *testing.T) {
 
 	enabled, err := IsEtcdServerEnabled()
@@ -169,3 +205,106 @@ func readConfig(t *testing.T, configJSON string) (vip *viper.Viper) {
 	assert.Nil(t, err)
 	return
 }
+
+func TestEtcdServerConfConsistentCluster(t *testing.T) {
+	conf := &EtcdServerConf{
+		ID:       "storage-2",
+		Scheme:   "http",
+		Host:     "10.0.0.2",
+		PeerPort: 2380,
+		Cluster:  "storage-1=http://10.0.0.1:2380, storage-2=http://10.0.0.2:2380,storage-3=http://10.0.0.3:2380",
+		Enabled:  true,
+	}
+
+	err := conf.validateCluster()
+
+	assert.Nil(t, err)
+}
+
+func TestEtcdServerConfClusterWithoutLocalMember(t *testing.T) {
+	conf := &EtcdServerConf{
+		ID:       "storage-2",
+		Scheme:   "http",
+		Host:     "127.0.0.1",
+		PeerPort: 2380,
+		Cluster:  "storage-1=http://127.0.0.1:2380",
+		Enabled:  true,
+	}
+
+	err := conf.validateCluster()
+
+	assert.Equal(t, "payment_channel_storage_server.cluster \"storage-1=http://127.0.0.1:2380\" doesn't contain member with id \"storage-2\"", err.Error())
+}
+
+func TestEtcdServerConfClusterPeerPortMismatch(t *testing.T) {
+	conf := &EtcdServerConf{
+		ID:       "storage-1",
+		Scheme:   "http",
+		Host:     "127.0.0.1",
+		PeerPort: 2390,
+		Cluster:  "storage-1=http://127.0.0.1:2380",
+		Enabled:  true,
+	}
+
+	err := conf.validateCluster()
+
+	assert.Equal(t, "peer URL of the member \"storage-1\" in payment_channel_storage_server.cluster is [http://127.0.0.1:2380], expected http://127.0.0.1:2390 according to scheme, host and peer_port", err.Error())
+}
+
+func TestEtcdServerConfClusterHostMismatch(t *testing.T) {
+	conf := &EtcdServerConf{
+		ID:       "storage-1",
+		Scheme:   "https",
+		Host:     "127.0.0.1",
+		PeerPort: 2380,
+		Cluster:  "storage-1=http://127.0.0.1:2380",
+		Enabled:  true,
+	}
+
+	err := conf.validateCluster()
+
+	assert.NotNil(t, err)
+}
+
+func TestEtcdServerConfIncorrectClusterFormat(t *testing.T) {
+	conf := &EtcdServerConf{
+		ID:       "storage-1",
+		Scheme:   "http",
+		Host:     "127.0.0.1",
+		PeerPort: 2380,
+		Cluster:  "http://127.0.0.1:2380",
+		Enabled:  true,
+	}
+
+	err := conf.validateCluster()
+
+	assert.Equal(t, "incorrect member \"http://127.0.0.1:2380\" in payment_channel_storage_server.cluster, expected format is \"id=peer_url\"", err.Error())
+}
+
+func TestEtcdServerConfMismatchedClusterIsRejected(t *testing.T) {
+
+	const confJSON = `
+	{
+		"payment_channel_storage_server": {
+			"id": "storage-1",
+			"host" : "127.0.0.1",
+			"client_port": 2379,
+			"peer_port": 2380,
+			"token": "unique-token",
+			"cluster": "storage-2=http://127.0.0.1:2380",
+			"enabled": true
+		}
+	}`
+
+	vip := readConfig(t, confJSON)
+
+	_, err := GetEtcdServerConf(vip)
+
+	assert.NotNil(t, err)
+}
+
+func TestEtcdServerConfClusterIsNotValidatedWhenDisabled(t *testing.T) {
+	conf := &EtcdServerConf{ID: "storage-1", Cluster: "incorrect", Enabled: false}
+
+	assert.Nil(t, conf.validateCluster())
+}
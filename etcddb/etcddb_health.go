@@ -0,0 +1,106 @@
+package etcddb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// endpointStatus checks whether etcd cluster member is available at
+// endpoint, it is implemented by clientv3.Client.Status.
+type endpointStatus func(ctx context.Context, endpoint string) error
+
+// checkEndpoints calls status for each endpoint concurrently and returns
+// available and unavailable endpoints keeping their original order. Each
+// call is limited by timeout.
+func checkEndpoints(status endpointStatus, endpoints []string, timeout time.Duration) (available []string, unavailable map[string]error) {
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			errs[i] = status(ctx, endpoint)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	unavailable = make(map[string]error)
+	for i, endpoint := range endpoints {
+		if errs[i] != nil {
+			unavailable[endpoint] = errs[i]
+		} else {
+			available = append(available, endpoint)
+		}
+	}
+	return
+}
+
+// healthyFirst returns endpoints where available ones precede unavailable
+// ones, so client connects to the available member while unavailable
+// members are still used when they recover.
+func healthyFirst(endpoints []string, unavailable map[string]error) (ordered []string) {
+	for _, endpoint := range endpoints {
+		if _, down := unavailable[endpoint]; !down {
+			ordered = append(ordered, endpoint)
+		}
+	}
+	for _, endpoint := range endpoints {
+		if _, down := unavailable[endpoint]; down {
+			ordered = append(ordered, endpoint)
+		}
+	}
+	return
+}
+
+// quorum returns number of members which should be available to let etcd
+// cluster of size members handle requests.
+func quorum(size int) int {
+	return size/2 + 1
+}
+
+// ClusterUnavailableError is returned when request failed because etcd
+// cluster cannot be reached, it tells how many cluster endpoints are
+// available and whether it is enough for quorum.
+type ClusterUnavailableError struct {
+	// Available is a number of endpoints which responded to status request
+	Available int
+	// Total is a number of configured endpoints
+	Total int
+	// Err is an original error of the request
+	Err error
+}
+
+// HasQuorum returns true if enough endpoints are available to form quorum,
+// in this case error is probably caused by the network or by leader
+// election in progress.
+func (err *ClusterUnavailableError) HasQuorum() bool {
+	return err.Available >= quorum(err.Total)
+}
+
+func (err *ClusterUnavailableError) Error() string {
+	if !err.HasQuorum() {
+		return fmt.Sprintf("etcd cluster has no quorum: %v of %v endpoints are available, at least %v are required: %v",
+			err.Available, err.Total, quorum(err.Total), err.Err)
+	}
+	return fmt.Sprintf("etcd cluster is unavailable while %v of %v endpoints are available: %v",
+		err.Available, err.Total, err.Err)
+}
+
+// describeUnavailable returns ClusterUnavailableError if err means that
+// etcd cluster cannot be reached, other errors are returned as is.
+func describeUnavailable(err error, status endpointStatus, endpoints []string, timeout time.Duration) error {
+	if err == nil || !isTransientError(err) {
+		return err
+	}
+	available, unavailable := checkEndpoints(status, endpoints, timeout)
+	for endpoint, e := range unavailable {
+		log.WithError(e).WithField("endpoint", endpoint).Warn("etcd endpoint is unavailable")
+	}
+	return &ClusterUnavailableError{Available: len(available), Total: len(endpoints), Err: err}
+}
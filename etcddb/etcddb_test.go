@@ -9,8 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TODO: initialize client and server only once to make test faster
@@ -338,6 +341,95 @@ func (suite *EtcdTestSuite) TestEtcdMutex() {
 	assert.Equal(t, res1, res2)
 }
 
+func (suite *EtcdTestSuite) newPrefixedClient(keyPrefix string) *EtcdClient {
+	t := suite.T()
+	vip := readConfig(t, `
+	{
+		"payment_channel_storage_client": {
+			"connection_timeout": "5s",
+			"request_timeout": "3s",
+			"endpoints": ["http://127.0.0.1:2379"],
+			"key_prefix": "`+keyPrefix+`"
+		}
+	}`)
+	client, err := NewEtcdClientFromVip(vip)
+	assert.Nil(t, err)
+	return client
+}
+
+func (suite *EtcdTestSuite) TestEtcdKeyPrefix() {
+
+	t := suite.T()
+
+	clientA := suite.newPrefixedClient("service-a/")
+	defer clientA.Close()
+
+	err := clientA.Put("key", "value-a")
+	assert.Nil(t, err)
+
+	value, ok, err := clientA.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value-a", value)
+
+	assertGet(suite, "service-a/key", "value-a")
+	_, ok, err = suite.client.Get("key")
+	assert.Nil(t, err)
+	assert.False(t, ok, "key is stored under the prefix only")
+
+	ok, err = clientA.CompareAndSwap("key", "value-a", "value-a-2")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assertGet(suite, "service-a/key", "value-a-2")
+
+	ok, err = clientA.PutIfAbsent("absent", "value-a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assertGet(suite, "service-a/absent", "value-a")
+
+	values, err := clientA.GetByKeyPrefix("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"value-a", "value-a-2"}, values)
+
+	err = clientA.Delete("key")
+	assert.Nil(t, err)
+	_, ok, err = suite.client.Get("service-a/key")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func (suite *EtcdTestSuite) TestEtcdKeyPrefixesDontCollide() {
+
+	t := suite.T()
+
+	clientA := suite.newPrefixedClient("service-a/")
+	defer clientA.Close()
+	clientB := suite.newPrefixedClient("service-b/")
+	defer clientB.Close()
+
+	err := clientA.Put("key", "value-a")
+	assert.Nil(t, err)
+	_, ok, err := clientB.Get("key")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	err = clientB.Put("key", "value-b")
+	assert.Nil(t, err)
+
+	value, ok, err := clientA.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value-a", value)
+	value, ok, err = clientB.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value-b", value)
+
+	values, err := clientB.GetByKeyPrefix("k")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"value-b"}, values)
+}
+
 func assertGet(suite *EtcdTestSuite, key string, value string) {
 	t := suite.T()
 	updateResult, ok, err := suite.client.Get(key)
@@ -369,3 +461,218 @@ func removeWorkDir(t *testing.T, workDir string) {
 	err = os.RemoveAll(dir + "/" + workDir)
 	assert.Nil(t, err)
 }
+
+func (suite *EtcdTestSuite) TestEtcdOneOfEndpointsIsUnavailable() {
+
+	t := suite.T()
+	vip := readConfig(t, `
+	{
+		"payment_channel_storage_client": {
+			"connection_timeout": "1s",
+			"request_timeout": "3s",
+			"endpoints": ["http://127.0.0.1:2479", "http://127.0.0.1:2379"]
+		},
+		"endpoint_selection": "priority"
+	}`)
+	client, err := NewEtcdClientFromVip(vip)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	err = client.Put("key", "value")
+	assert.Nil(t, err)
+
+	value, ok, err := client.Get("key")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+	assertGet(suite, "key", "value")
+}
+
+type etcdReadinessMock struct {
+	readyAt time.Time
+	calls   int
+}
+
+func (etcd *etcdReadinessMock) check(ctx context.Context) error {
+	etcd.calls++
+	if time.Now().Before(etcd.readyAt) {
+		return fmt.Errorf("connection refused")
+	}
+	return nil
+}
+
+func TestWaitForReady(t *testing.T) {
+	readinessCheckInterval = 10 * time.Millisecond
+	defer func() { readinessCheckInterval = 500 * time.Millisecond }()
+	etcd := &etcdReadinessMock{readyAt: time.Now().Add(50 * time.Millisecond)}
+
+	err := waitForReady(etcd.check, time.Second, time.Second)
+
+	assert.Nil(t, err)
+	assert.True(t, etcd.calls > 1)
+}
+
+func TestWaitForReadyTimeout(t *testing.T) {
+	readinessCheckInterval = 10 * time.Millisecond
+	defer func() { readinessCheckInterval = 500 * time.Millisecond }()
+	etcd := &etcdReadinessMock{readyAt: time.Now().Add(time.Hour)}
+
+	err := waitForReady(etcd.check, 50*time.Millisecond, time.Second)
+
+	assert.Equal(t, "no response after 50ms, last error: connection refused", err.Error())
+}
+
+// etcdOutageMock refuses connections until failures count is exhausted
+type etcdOutageMock struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (etcd *etcdOutageMock) request(ctx context.Context) error {
+	etcd.calls++
+	if etcd.calls <= etcd.failures {
+		return etcd.err
+	}
+	return nil
+}
+
+func TestRetryAfterOutage(t *testing.T) {
+	retryInitialBackoff = time.Millisecond
+	defer func() { retryInitialBackoff = 50 * time.Millisecond }()
+	etcd := &etcdOutageMock{failures: 3, err: status.Error(codes.Unavailable, "connection refused")}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := retry(ctx, etcd.request)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, etcd.calls)
+}
+
+func TestRetryEtcdErrorAfterOutage(t *testing.T) {
+	retryInitialBackoff = time.Millisecond
+	defer func() { retryInitialBackoff = 50 * time.Millisecond }()
+	etcd := &etcdOutageMock{failures: 1, err: rpctypes.ErrNoLeader}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := retry(ctx, etcd.request)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, etcd.calls)
+}
+
+func TestRetryOutageLongerThanTimeout(t *testing.T) {
+	retryInitialBackoff = time.Millisecond
+	defer func() { retryInitialBackoff = 50 * time.Millisecond }()
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	etcd := &etcdOutageMock{failures: 1000000, err: unavailable}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := retry(ctx, etcd.request)
+
+	assert.Equal(t, unavailable, err)
+	assert.True(t, etcd.calls > 1)
+}
+
+func TestRetryNonTransientError(t *testing.T) {
+	etcd := &etcdOutageMock{failures: 1, err: rpctypes.ErrCompacted}
+
+	err := retry(context.Background(), etcd.request)
+
+	assert.Equal(t, rpctypes.ErrCompacted, err)
+	assert.Equal(t, 1, etcd.calls)
+}
+
+func TestOrderEndpointsPriority(t *testing.T) {
+	endpoints := []string{"http://priority-a:2379", "http://priority-b:2379"}
+
+	first, err := orderEndpoints("priority", endpoints)
+	assert.Nil(t, err)
+	second, err := orderEndpoints("priority", endpoints)
+	assert.Nil(t, err)
+
+	assert.Equal(t, endpoints, first)
+	assert.Equal(t, endpoints, second)
+}
+
+func TestOrderEndpointsRoundRobin(t *testing.T) {
+	endpoints := []string{"http://rr-a:2379", "http://rr-b:2379"}
+
+	first, err := orderEndpoints("round_robin", endpoints)
+	assert.Nil(t, err)
+	second, err := orderEndpoints("round_robin", endpoints)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"http://rr-a:2379", "http://rr-b:2379"}, first)
+	assert.Equal(t, []string{"http://rr-b:2379", "http://rr-a:2379"}, second)
+}
+
+func TestOrderEndpointsUnknownStrategy(t *testing.T) {
+	_, err := orderEndpoints("fastest", []string{"http://127.0.0.1:2379"})
+
+	assert.NotNil(t, err)
+}
+
+// clusterStatusMock returns error for the endpoints which are down
+type clusterStatusMock struct {
+	down map[string]bool
+}
+
+func (cluster *clusterStatusMock) status(ctx context.Context, endpoint string) error {
+	if cluster.down[endpoint] {
+		return fmt.Errorf("connection refused")
+	}
+	return nil
+}
+
+var clusterEndpoints = []string{"http://node-1:2379", "http://node-2:2379", "http://node-3:2379"}
+
+func TestCheckEndpointsOneUnavailable(t *testing.T) {
+	cluster := &clusterStatusMock{down: map[string]bool{"http://node-1:2379": true}}
+
+	available, unavailable := checkEndpoints(cluster.status, clusterEndpoints, time.Second)
+
+	assert.Equal(t, []string{"http://node-2:2379", "http://node-3:2379"}, available)
+	assert.Equal(t, map[string]error{"http://node-1:2379": fmt.Errorf("connection refused")}, unavailable)
+	assert.Equal(t, []string{"http://node-2:2379", "http://node-3:2379", "http://node-1:2379"}, healthyFirst(clusterEndpoints, unavailable))
+}
+
+func TestCheckEndpointsAllAvailable(t *testing.T) {
+	cluster := &clusterStatusMock{}
+
+	available, unavailable := checkEndpoints(cluster.status, clusterEndpoints, time.Second)
+
+	assert.Equal(t, clusterEndpoints, available)
+	assert.Empty(t, unavailable)
+	assert.Equal(t, clusterEndpoints, healthyFirst(clusterEndpoints, unavailable))
+}
+
+func TestDescribeUnavailableWithQuorum(t *testing.T) {
+	cluster := &clusterStatusMock{down: map[string]bool{"http://node-3:2379": true}}
+
+	err := describeUnavailable(rpctypes.ErrNoLeader, cluster.status, clusterEndpoints, time.Second)
+
+	assert.Equal(t, &ClusterUnavailableError{Available: 2, Total: 3, Err: rpctypes.ErrNoLeader}, err)
+	assert.True(t, err.(*ClusterUnavailableError).HasQuorum())
+	assert.Equal(t, "etcd cluster is unavailable while 2 of 3 endpoints are available: etcdserver: no leader", err.Error())
+}
+
+func TestDescribeUnavailableWithoutQuorum(t *testing.T) {
+	cluster := &clusterStatusMock{down: map[string]bool{"http://node-1:2379": true, "http://node-3:2379": true}}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	err := describeUnavailable(unavailable, cluster.status, clusterEndpoints, time.Second)
+
+	assert.False(t, err.(*ClusterUnavailableError).HasQuorum())
+	assert.Equal(t, "etcd cluster has no quorum: 1 of 3 endpoints are available, at least 2 are required: rpc error: code = Unavailable desc = connection refused", err.Error())
+}
+
+func TestDescribeUnavailableKeepsOtherErrors(t *testing.T) {
+	cluster := &clusterStatusMock{down: map[string]bool{"http://node-1:2379": true}}
+
+	assert.Equal(t, rpctypes.ErrCompacted, describeUnavailable(rpctypes.ErrCompacted, cluster.status, clusterEndpoints, time.Second))
+	assert.Nil(t, describeUnavailable(nil, cluster.status, clusterEndpoints, time.Second))
+}
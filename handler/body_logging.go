@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/singnet/snet-daemon/codec"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// redactedValue replaces values of the secret metadata headers in logs.
+const redactedValue = "<redacted>"
+
+// GrpcBodyLoggingInterceptor returns gRPC interceptor which logs request and
+// response messages at debug level. Messages longer than limit bytes are
+// truncated, values of secretHeaders are replaced in logged metadata. It is
+// intended for debugging only as message content is written to the log.
+func GrpcBodyLoggingInterceptor(limit int, secretHeaders []string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !log.IsLevelEnabled(log.DebugLevel) {
+			return handler(srv, ss)
+		}
+
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		logger := log.WithField("method", info.FullMethod)
+		logger.WithField("metadata", redactMetadata(md, secretHeaders)).Debug("gRPC call metadata")
+
+		return handler(srv, &bodyLoggingServerStream{ServerStream: ss, logger: logger, limit: limit})
+	}
+}
+
+func redactMetadata(md metadata.MD, secretHeaders []string) metadata.MD {
+	md = md.Copy()
+	for _, header := range secretHeaders {
+		if _, ok := md[header]; ok {
+			md[header] = []string{redactedValue}
+		}
+	}
+	return md
+}
+
+// truncateBody returns body as string, body which is longer than limit bytes
+// is truncated.
+func truncateBody(data []byte, limit int) string {
+	if len(data) <= limit {
+		return string(data)
+	}
+	return fmt.Sprintf("%s...(%d bytes truncated)", data[:limit], len(data)-limit)
+}
+
+type bodyLoggingServerStream struct {
+	grpc.ServerStream
+	logger *log.Entry
+	limit  int
+}
+
+func (stream *bodyLoggingServerStream) RecvMsg(m interface{}) error {
+	err := stream.ServerStream.RecvMsg(m)
+	if err == nil {
+		stream.logBody("request", m)
+	}
+	return err
+}
+
+func (stream *bodyLoggingServerStream) SendMsg(m interface{}) error {
+	stream.logBody("response", m)
+	return stream.ServerStream.SendMsg(m)
+}
+
+func (stream *bodyLoggingServerStream) logBody(direction string, m interface{}) {
+	frame, ok := m.(*codec.GrpcFrame)
+	if !ok {
+		return
+	}
+	stream.logger.WithFields(log.Fields{
+		"size": len(frame.Data),
+		"body": truncateBody(frame.Data, stream.limit),
+	}).Debugf("gRPC %v body", direction)
+}
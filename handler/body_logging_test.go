@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/singnet/snet-daemon/codec"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type frameServerStreamMock struct {
+	serverStreamMock
+	request []byte
+	sent    [][]byte
+}
+
+func (stream *frameServerStreamMock) RecvMsg(m interface{}) error {
+	m.(*codec.GrpcFrame).Data = stream.request
+	return nil
+}
+
+func (stream *frameServerStreamMock) SendMsg(m interface{}) error {
+	stream.sent = append(stream.sent, m.(*codec.GrpcFrame).Data)
+	return nil
+}
+
+func echoHandler(srv interface{}, stream grpc.ServerStream) error {
+	frame := &codec.GrpcFrame{}
+	if err := stream.RecvMsg(frame); err != nil {
+		return err
+	}
+	return stream.SendMsg(frame)
+}
+
+func callWithBodyLogging(t *testing.T, level log.Level, limit int, md metadata.MD, request string) (hook *test.Hook, stream *frameServerStreamMock) {
+	hook = test.NewGlobal()
+	previousLevel := log.GetLevel()
+	log.SetLevel(level)
+	defer log.SetLevel(previousLevel)
+
+	stream = &frameServerStreamMock{serverStreamMock: *newServerStreamMock(md), request: []byte(request)}
+	interceptor := GrpcBodyLoggingInterceptor(limit, []string{"snet-payment-channel-signature-bin"})
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/service/method"}, echoHandler)
+
+	assert.Nil(t, err)
+	return
+}
+
+func findLogEntry(hook *test.Hook, message string) *log.Entry {
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == message {
+			return entry
+		}
+	}
+	return nil
+}
+
+func TestBodyLoggingLogsBodies(t *testing.T) {
+	hook, stream := callWithBodyLogging(t, log.DebugLevel, 100, metadata.Pairs(), `{"a": 1}`)
+	defer hook.Reset()
+
+	assert.Equal(t, [][]byte{[]byte(`{"a": 1}`)}, stream.sent)
+	request := findLogEntry(hook, "gRPC request body")
+	assert.NotNil(t, request)
+	assert.Equal(t, `{"a": 1}`, request.Data["body"])
+	assert.Equal(t, 8, request.Data["size"])
+	assert.Equal(t, "/service/method", request.Data["method"])
+	response := findLogEntry(hook, "gRPC response body")
+	assert.NotNil(t, response)
+	assert.Equal(t, `{"a": 1}`, response.Data["body"])
+}
+
+func TestBodyLoggingTruncatesLargeBodies(t *testing.T) {
+	hook, _ := callWithBodyLogging(t, log.DebugLevel, 10, metadata.Pairs(), strings.Repeat("x", 25))
+	defer hook.Reset()
+
+	request := findLogEntry(hook, "gRPC request body")
+	assert.NotNil(t, request)
+	assert.Equal(t, "xxxxxxxxxx...(15 bytes truncated)", request.Data["body"])
+	assert.Equal(t, 25, request.Data["size"])
+}
+
+func TestBodyLoggingRedactsSecretHeaders(t *testing.T) {
+	md := metadata.Pairs("snet-payment-channel-signature-bin", "secret", "snet-payment-type", "escrow")
+	hook, _ := callWithBodyLogging(t, log.DebugLevel, 100, md, "body")
+	defer hook.Reset()
+
+	entry := findLogEntry(hook, "gRPC call metadata")
+	assert.NotNil(t, entry)
+	assert.Equal(t, metadata.Pairs("snet-payment-channel-signature-bin", "<redacted>", "snet-payment-type", "escrow"), entry.Data["metadata"])
+	assert.Equal(t, []string{"secret"}, md["snet-payment-channel-signature-bin"])
+}
+
+func TestBodyLoggingDisabledAboveDebugLevel(t *testing.T) {
+	hook, stream := callWithBodyLogging(t, log.InfoLevel, 100, metadata.Pairs(), "body")
+	defer hook.Reset()
+
+	assert.Equal(t, [][]byte{[]byte("body")}, stream.sent)
+	assert.Nil(t, findLogEntry(hook, "gRPC request body"))
+}
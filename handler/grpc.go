@@ -10,10 +10,12 @@ import (
 	"net/url"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/gorilla/rpc/v2/json2"
 	"github.com/singnet/snet-daemon/codec"
 	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/tracing"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -28,19 +30,67 @@ type grpcHandler struct {
 	enc                 string
 	passthroughEndpoint string
 	executable          string
+	timeout             time.Duration
+	// methodTimeouts contains timeouts of the methods which override
+	// timeout, keys are lower case full method names
+	methodTimeouts map[string]time.Duration
+	flushOnTimeout bool
+	// maxResponseBytes limits size of the service response, zero means no
+	// limit
+	maxResponseBytes int
+	// streamingMethods contains server streaming methods, keys are lower
+	// case full method names
+	streamingMethods map[string]bool
 }
 
-func NewGrpcHandler(serviceMetadata *blockchain.ServiceMetadata) grpc.StreamHandler {
-	passthroughEnabled := config.GetBool(config.PassthroughEnabledKey)
+// NewGrpcHandler returns handler which passes calls to the service. If more
+// than one service is configured then call is routed to the service using
+// metadata headers returned by GetServiceKeyHeaders. Requests are checked by
+// validators added via RegisterRequestValidator before passing them to the
+// service. Wire encoding and service type of each service are taken from the
+// metadata returned by serviceMetadata for this service.
+func NewGrpcHandler(serviceMetadata func(service *config.ServiceConf) *blockchain.ServiceMetadata) grpc.StreamHandler {
+	return NewRequestValidationHandler(newGrpcHandler(serviceMetadata), registeredRequestValidators()...)
+}
+
+func newGrpcHandler(serviceMetadata func(service *config.ServiceConf) *blockchain.ServiceMetadata) grpc.StreamHandler {
+	services, err := config.GetServices()
+	if err != nil {
+		log.WithError(err).Panic("error reading services configuration")
+	}
 
-	if !passthroughEnabled {
+	if len(services) == 1 {
+		return newServiceGrpcHandler(serviceMetadata(services[0]), services[0])
+	}
+
+	handlers := make(map[ServiceKey]grpc.StreamHandler)
+	for _, service := range services {
+		key := ServiceKey{OrganizationID: service.OrganizationID, ServiceID: service.ServiceID}
+		handlers[key] = newServiceGrpcHandler(serviceMetadata(service), service)
+		log.WithField("service", key).Info("Handler for service registered")
+	}
+	return NewServiceRoutingHandler(GetServiceKeyHeaders(), handlers)
+}
+
+func newServiceGrpcHandler(serviceMetadata *blockchain.ServiceMetadata, service *config.ServiceConf) grpc.StreamHandler {
+	if !service.PassthroughEnabled {
 		return grpcLoopback
 	}
 
+	methodTimeouts, err := config.GetPassthroughMethodTimeouts()
+	if err != nil {
+		log.WithError(err).Panic("error reading passthrough_method_timeouts")
+	}
+
 	h := grpcHandler{
 		enc:                 serviceMetadata.GetWireEncoding(),
-		passthroughEndpoint: config.GetString(config.PassthroughEndpointKey),
+		passthroughEndpoint: service.PassthroughEndpoint,
 		executable:          config.GetString(config.ExecutablePathKey),
+		timeout:             config.GetDuration(config.PassthroughTimeoutKey),
+		methodTimeouts:      methodTimeouts,
+		flushOnTimeout:      config.GetBool(config.PassthroughFlushOnTimeoutKey),
+		maxResponseBytes:    config.GetInt(config.PassthroughMaxResponseBytesKey),
+		streamingMethods:    config.GetPassthroughStreamingMethods(),
 	}
 
 	switch serviceMetadata.GetServiceType() {
@@ -69,7 +119,7 @@ Modified from https://github.com/mwitkow/grpc-proxy/blob/67591eb23c48346a480470e
 Original Copyright 2017 Michal Witkowski. All Rights Reserved. See LICENSE-GRPC-PROXY for licensing terms.
 Modifications Copyright 2018 SingularityNET Foundation. All Rights Reserved. See LICENSE for licensing terms.
 */
-func (g grpcHandler) grpcToGRPC(srv interface{}, inStream grpc.ServerStream) error {
+func (g grpcHandler) grpcToGRPC(srv interface{}, inStream grpc.ServerStream) (err error) {
 	method, ok := grpc.MethodFromServerStream(inStream)
 
 	if !ok {
@@ -83,18 +133,42 @@ func (g grpcHandler) grpcToGRPC(srv interface{}, inStream grpc.ServerStream) err
 		return status.Errorf(codes.Internal, "could not get metadata from incoming context")
 	}
 
+	_, span := tracing.StartSpan(inCtx, "passthrough")
+	span.SetAttribute("rpc.method", method)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	outMD := md.Copy()
+	tracing.Inject(span, outMD)
+
 	outCtx, outCancel := context.WithCancel(inCtx)
-	outCtx = metadata.NewOutgoingContext(outCtx, md.Copy())
+	outCtx = metadata.NewOutgoingContext(outCtx, outMD)
 	outStream, err := g.grpcConn.NewStream(outCtx, grpcDesc, method, grpc.CallContentSubtype(g.enc))
 	if err != nil {
 		return err
 	}
 
 	s2cErrChan := forwardServerToClient(inStream, outStream)
-	c2sErrChan := forwardClientToServer(outStream, inStream)
+	c2sErrChan := forwardClientToServer(outStream, inStream, g.maxResponseBytes)
+
+	callTimeout := g.methodTimeout(method)
+	var timeout <-chan time.Time
+	if callTimeout > 0 {
+		timer := time.NewTimer(callTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
 
 	for i := 0; i < 2; i++ {
 		select {
+		case <-timeout:
+			// wait until all messages received from the service are passed
+			// to the client
+			outCancel()
+			<-c2sErrChan
+			inStream.SetTrailer(outStream.Trailer())
+			return g.timeoutError(method)
 		case s2cErr := <-s2cErrChan:
 			if s2cErr == io.EOF {
 				// this is the happy case where the sender has encountered io.EOF, and won't be sending anymore./
@@ -123,20 +197,73 @@ func (g grpcHandler) grpcToGRPC(srv interface{}, inStream grpc.ServerStream) err
 	return status.Errorf(codes.Internal, "gRPC proxying should never reach this stage.")
 }
 
+// methodTimeout returns timeout of the method call, timeout set in
+// passthrough_method_timeouts takes precedence over passthrough_timeout.
+func (g grpcHandler) methodTimeout(method string) time.Duration {
+	if timeout, ok := g.methodTimeouts[strings.ToLower(method)]; ok {
+		return timeout
+	}
+	return g.timeout
+}
+
+// callContext returns context of the call to the service which is cancelled
+// when timeout expires, zero timeout means no timeout.
+func callContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// callError returns timeoutError if the call to the service is failed
+// because its context deadline is exceeded and err otherwise.
+func (g grpcHandler) callError(ctx context.Context, method string, err error) error {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return g.timeoutError(method)
+	}
+	return err
+}
+
+// serverStreaming returns true if method is listed in
+// passthrough_streaming_methods.
+func (g grpcHandler) serverStreaming(method string) bool {
+	return g.streamingMethods[strings.ToLower(method)]
+}
+
+// timeoutError returns result of the method call which is not finished by
+// service in time. When flushOnTimeout is set the call of the server
+// streaming method is finished successfully and client gets messages which
+// are received from the service before timeout. Unary call has no partial
+// result, so it is always finished with DeadlineExceeded.
+func (g grpcHandler) timeoutError(method string) error {
+	timeout := g.methodTimeout(method)
+	if g.flushOnTimeout && g.serverStreaming(method) {
+		log.WithField("timeout", timeout).Debug("Passthrough timeout, stream is closed with received data")
+		return nil
+	}
+	return status.Errorf(codes.DeadlineExceeded, "service didn't finish call in %v", timeout)
+}
+
 /*
 Modified from https://github.com/mwitkow/grpc-proxy/blob/67591eb23c48346a480470e462289835d96f70da/proxy/handler.go#L115
 Original Copyright 2017 Michal Witkowski. All Rights Reserved. See LICENSE-GRPC-PROXY for licensing terms.
 Modifications Copyright 2018 SingularityNET Foundation. All Rights Reserved. See LICENSE for licensing terms.
 */
-func forwardClientToServer(src grpc.ClientStream, dst grpc.ServerStream) chan error {
+func forwardClientToServer(src grpc.ClientStream, dst grpc.ServerStream, maxBytes int) chan error {
 	ret := make(chan error, 1)
 	go func() {
 		f := &codec.GrpcFrame{}
+		forwarded := 0
 		for i := 0; ; i++ {
 			if err := src.RecvMsg(f); err != nil {
 				ret <- err // this can be io.EOF which is happy case
 				break
 			}
+			forwarded += len(f.Data)
+			if maxBytes > 0 && forwarded > maxBytes {
+				ret <- responseTooLargeError(maxBytes)
+				break
+			}
 			if i == 0 {
 				// This is a bit of a hack, but client to server headers are only readable after first client msg is
 				// received but must be written to server stream before the first msg is flushed.
@@ -184,14 +311,14 @@ func forwardServerToClient(src grpc.ServerStream, dst grpc.ClientStream) chan er
 }
 
 func (g grpcHandler) grpcToJSONRPC(srv interface{}, inStream grpc.ServerStream) error {
-	method, ok := grpc.MethodFromServerStream(inStream)
+	fullMethod, ok := grpc.MethodFromServerStream(inStream)
 
 	if !ok {
 		return status.Errorf(codes.Internal, "could not determine method from server stream")
 	}
 
-	methodSegs := strings.Split(method, "/")
-	method = methodSegs[len(methodSegs)-1]
+	methodSegs := strings.Split(fullMethod, "/")
+	method := methodSegs[len(methodSegs)-1]
 
 	if !ok {
 		return status.Errorf(codes.Internal, "could not get metadata from incoming context")
@@ -220,17 +347,25 @@ func (g grpcHandler) grpcToJSONRPC(srv interface{}, inStream grpc.ServerStream)
 		return status.Errorf(codes.Internal, "error creating http request; error: %+v", err)
 	}
 
+	ctx, cancel := callContext(inStream.Context(), g.methodTimeout(fullMethod))
+	defer cancel()
+
 	httpReq.Header.Set("content-type", "application/json")
-	httpResp, err := http.DefaultClient.Do(httpReq)
+	httpResp, err := http.DefaultClient.Do(httpReq.WithContext(ctx))
 
 	if err != nil {
-		return status.Errorf(codes.Internal, "error executing http call; error: %+v", err)
+		return g.callError(ctx, fullMethod, status.Errorf(codes.Internal, "error executing http call; error: %+v", err))
 	}
+	defer httpResp.Body.Close()
 
 	result := new(interface{})
 
-	if err = json2.DecodeClientResponse(httpResp.Body, result); err != nil {
-		return status.Errorf(codes.Internal, "json-rpc error; error: %+v", err)
+	body := newLimitedResponseReader(httpResp.Body, g.maxResponseBytes)
+	if err = json2.DecodeClientResponse(body, result); err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			return err
+		}
+		return g.callError(ctx, fullMethod, status.Errorf(codes.Internal, "json-rpc error; error: %+v", err))
 	}
 
 	respBytes, err := json.Marshal(result)
@@ -249,36 +384,52 @@ func (g grpcHandler) grpcToJSONRPC(srv interface{}, inStream grpc.ServerStream)
 }
 
 func (g grpcHandler) grpcToProcess(srv interface{}, inStream grpc.ServerStream) error {
-	method, ok := grpc.MethodFromServerStream(inStream)
+	fullMethod, ok := grpc.MethodFromServerStream(inStream)
 
 	if !ok {
 		return status.Errorf(codes.Internal, "could not determine method from server stream")
 	}
 
-	methodSegs := strings.Split(method, "/")
-	method = methodSegs[len(methodSegs)-1]
+	methodSegs := strings.Split(fullMethod, "/")
+	method := methodSegs[len(methodSegs)-1]
 
 	f := &codec.GrpcFrame{}
 	if err := inStream.RecvMsg(f); err != nil {
 		return status.Errorf(codes.Internal, "error receiving request; error: %+v", err)
 	}
 
-	cmd := exec.Command(g.executable, method)
-	stdin, err := cmd.StdinPipe()
+	// process is killed when timeout expires
+	ctx, cancel := callContext(inStream.Context(), g.methodTimeout(fullMethod))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, g.executable, method)
+	cmd.Stdin = bytes.NewReader(f.Data)
+	stdout, err := cmd.StdoutPipe()
 
 	if err != nil {
-		return status.Errorf(codes.Internal, "error creating stdin pipe; error: %+v", err)
+		return status.Errorf(codes.Internal, "error creating stdout pipe; error: %+v", err)
 	}
+	// stderr is passed to the client together with stdout
+	cmd.Stderr = cmd.Stdout
 
-	if _, err := stdin.Write(f.Data); err != nil {
-		return status.Errorf(codes.Internal, "error writing to stdin; error: %+v", err)
+	if err = cmd.Start(); err != nil {
+		return status.Errorf(codes.Internal, "error executing process; error: %+v", err)
 	}
-	stdin.Close()
 
-	out, err := cmd.CombinedOutput()
+	if g.serverStreaming(fullMethod) {
+		err = g.streamProcessOutput(cmd, stdout, inStream)
+		return g.callError(ctx, fullMethod, err)
+	}
 
+	out, err := readResponse(stdout, g.maxResponseBytes)
 	if err != nil {
-		return status.Errorf(codes.Internal, "error executing process; error: %+v", err)
+		cmd.Process.Kill()
+		cmd.Wait()
+		return g.callError(ctx, fullMethod, err)
+	}
+
+	if err = cmd.Wait(); err != nil {
+		return g.callError(ctx, fullMethod, status.Errorf(codes.Internal, "error executing process; error: %+v", err))
 	}
 
 	f = &codec.GrpcFrame{Data: out}
@@ -290,6 +441,30 @@ func (g grpcHandler) grpcToProcess(srv interface{}, inStream grpc.ServerStream)
 	return nil
 }
 
+// streamProcessOutput sends messages of the server streaming method to the
+// client as soon as executable writes them, so large output is not kept in
+// memory. Each message is written by executable as length-delimited frame,
+// see streamResponse. Messages sent before the process fails are received
+// by the client.
+func (g grpcHandler) streamProcessOutput(cmd *exec.Cmd, stdout io.Reader, inStream grpc.ServerStream) error {
+	err := streamResponse(stdout, g.maxResponseBytes, func(data []byte) error {
+		if err := inStream.SendMsg(&codec.GrpcFrame{Data: data}); err != nil {
+			return status.Errorf(codes.Internal, "error sending response; error: %+v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	if err = cmd.Wait(); err != nil {
+		return status.Errorf(codes.Internal, "error executing process; error: %+v", err)
+	}
+	return nil
+}
+
 func grpcLoopback(srv interface{}, inStream grpc.ServerStream) error {
 	f := &codec.GrpcFrame{}
 	if err := inStream.RecvMsg(f); err != nil {
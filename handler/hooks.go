@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// HookStage is a stage of the call processing at which hook is called.
+type HookStage int
+
+const (
+	// PreValidationStage hooks are called before payment is validated.
+	PreValidationStage HookStage = iota
+	// PostValidationStage hooks are called after payment is validated and
+	// before call is passed to the service.
+	PostValidationStage
+	// PostPassthroughStage hooks are called after service successfully
+	// processed the call and before payment is completed.
+	PostPassthroughStage
+)
+
+func (stage HookStage) String() string {
+	switch stage {
+	case PreValidationStage:
+		return "pre-validation"
+	case PostValidationStage:
+		return "post-validation"
+	case PostPassthroughStage:
+		return "post-passthrough"
+	default:
+		return fmt.Sprintf("HookStage(%d)", int(stage))
+	}
+}
+
+// Hook is called at the stage it is registered for. Returning error aborts
+// the call: next hooks and next stages are not called and error is returned
+// to the client. If call is aborted after payment validation then payment is
+// not completed, exactly as if service returned error.
+type Hook func(context *GrpcStreamContext) (err *GrpcError)
+
+// HookRegistry keeps hooks registered for each stage. Hooks of the same
+// stage are called in order of registration.
+type HookRegistry struct {
+	mutex sync.RWMutex
+	hooks map[HookStage][]Hook
+}
+
+// NewHookRegistry returns new empty hook registry.
+func NewHookRegistry() *HookRegistry {
+	return &HookRegistry{hooks: make(map[HookStage][]Hook)}
+}
+
+// DefaultHookRegistry contains hooks which are applied by daemon.
+var DefaultHookRegistry = NewHookRegistry()
+
+// RegisterHook adds hook to the DefaultHookRegistry.
+func RegisterHook(stage HookStage, hook Hook) {
+	DefaultHookRegistry.Register(stage, hook)
+}
+
+// Register adds hook which is called at the given stage.
+func (registry *HookRegistry) Register(stage HookStage, hook Hook) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.hooks[stage] = append(registry.hooks[stage], hook)
+}
+
+func (registry *HookRegistry) stageHooks(stage HookStage) []Hook {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	return registry.hooks[stage]
+}
+
+// Run calls hooks of the stage in order and stops on the first error.
+func (registry *HookRegistry) Run(stage HookStage, context *GrpcStreamContext) (err *GrpcError) {
+	for _, hook := range registry.stageHooks(stage) {
+		if err = hook(context); err != nil {
+			log.WithField("stage", stage).WithField("context", context).WithField("error", err).Warn("Call is aborted by hook")
+			return err
+		}
+	}
+	return nil
+}
+
+// PreValidationInterceptor returns gRPC interceptor which calls
+// PreValidationStage hooks, it should be chained before payment validation
+// interceptor.
+func (registry *HookRegistry) PreValidationInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if len(registry.stageHooks(PreValidationStage)) == 0 {
+			return handler(srv, ss)
+		}
+
+		context, err := getGrpcContext(ss, info)
+		if err != nil {
+			return err.Err()
+		}
+		if err = registry.Run(PreValidationStage, context); err != nil {
+			return err.Err()
+		}
+		return handler(srv, ss)
+	}
+}
+
+// PostValidationInterceptor returns gRPC interceptor which calls
+// PostValidationStage hooks before the handler and PostPassthroughStage
+// hooks after handler succeeds, it should be chained after payment
+// validation interceptor.
+func (registry *HookRegistry) PostValidationInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if len(registry.stageHooks(PostValidationStage)) == 0 && len(registry.stageHooks(PostPassthroughStage)) == 0 {
+			return handler(srv, ss)
+		}
+
+		context, err := getGrpcContext(ss, info)
+		if err != nil {
+			return err.Err()
+		}
+		if err = registry.Run(PostValidationStage, context); err != nil {
+			return err.Err()
+		}
+		if e := handler(srv, ss); e != nil {
+			return e
+		}
+		if err = registry.Run(PostPassthroughStage, context); err != nil {
+			return err.Err()
+		}
+		return nil
+	}
+}
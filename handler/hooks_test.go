@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type hookCallRecorder struct {
+	calls []string
+}
+
+func (recorder *hookCallRecorder) hook(name string, err *GrpcError) Hook {
+	return func(context *GrpcStreamContext) *GrpcError {
+		recorder.calls = append(recorder.calls, name)
+		return err
+	}
+}
+
+func (recorder *hookCallRecorder) interceptor(registry *HookRegistry) grpc.StreamServerInterceptor {
+	validation := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		recorder.calls = append(recorder.calls, "validation")
+		return handler(srv, ss)
+	}
+	return grpc_middleware.ChainStreamServer(
+		registry.PreValidationInterceptor(),
+		validation,
+		registry.PostValidationInterceptor())
+}
+
+func (recorder *hookCallRecorder) call(registry *HookRegistry) error {
+	return recorder.interceptor(registry)(nil, newServerStreamMock(metadata.Pairs()), &grpc.StreamServerInfo{FullMethod: "/service/method"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			recorder.calls = append(recorder.calls, "passthrough")
+			return nil
+		})
+}
+
+func TestHooksAreCalledInOrder(t *testing.T) {
+	recorder := &hookCallRecorder{}
+	registry := NewHookRegistry()
+	registry.Register(PostPassthroughStage, recorder.hook("post-passthrough-1", nil))
+	registry.Register(PreValidationStage, recorder.hook("pre-validation-1", nil))
+	registry.Register(PostValidationStage, recorder.hook("post-validation-1", nil))
+	registry.Register(PreValidationStage, recorder.hook("pre-validation-2", nil))
+	registry.Register(PostPassthroughStage, recorder.hook("post-passthrough-2", nil))
+
+	err := recorder.call(registry)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"pre-validation-1",
+		"pre-validation-2",
+		"validation",
+		"post-validation-1",
+		"passthrough",
+		"post-passthrough-1",
+		"post-passthrough-2",
+	}, recorder.calls)
+}
+
+func TestHookErrorAbortsCall(t *testing.T) {
+	recorder := &hookCallRecorder{}
+	registry := NewHookRegistry()
+	registry.Register(PreValidationStage, recorder.hook("pre-validation-1", NewGrpcError(codes.PermissionDenied, "rejected by hook")))
+	registry.Register(PreValidationStage, recorder.hook("pre-validation-2", nil))
+	registry.Register(PostValidationStage, recorder.hook("post-validation", nil))
+
+	err := recorder.call(registry)
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, []string{"pre-validation-1"}, recorder.calls)
+}
+
+func TestPostValidationHookErrorAbortsPassthrough(t *testing.T) {
+	recorder := &hookCallRecorder{}
+	registry := NewHookRegistry()
+	registry.Register(PostValidationStage, recorder.hook("post-validation", NewGrpcError(codes.Aborted, "rejected by hook")))
+	registry.Register(PostPassthroughStage, recorder.hook("post-passthrough", nil))
+
+	err := recorder.call(registry)
+
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, []string{"validation", "post-validation"}, recorder.calls)
+}
+
+func TestNoHooksRegistered(t *testing.T) {
+	recorder := &hookCallRecorder{}
+
+	err := recorder.call(NewHookRegistry())
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"validation", "passthrough"}, recorder.calls)
+}
+
+func TestHookStageString(t *testing.T) {
+	assert.Equal(t, "pre-validation", PreValidationStage.String())
+	assert.Equal(t, "post-validation", PostValidationStage.String())
+	assert.Equal(t, "post-passthrough", PostPassthroughStage.String())
+}
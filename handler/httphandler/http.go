@@ -1,10 +1,13 @@
 package httphandler
 
 import (
+	"bytes"
 	"github.com/singnet/snet-daemon/ratelimit"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/singnet/snet-daemon/blockchain"
 	"github.com/singnet/snet-daemon/config"
@@ -14,6 +17,11 @@ type httpHandler struct {
 	passthroughEnabled  bool
 	passthroughEndpoint string
 	rateLimiter         rate.Limiter
+	// requestSchemas contains JSON Schemas of the request bodies by lower
+	// case URL path
+	requestSchemas map[string]*requestSchema
+	// maxRequestBytes limits size of the request body, zero means unlimited
+	maxRequestBytes int64
 }
 
 func NewHTTPHandler(blockProc blockchain.Processor) http.Handler {
@@ -21,10 +29,61 @@ func NewHTTPHandler(blockProc blockchain.Processor) http.Handler {
 		passthroughEnabled:  config.GetBool(config.PassthroughEnabledKey),
 		passthroughEndpoint: config.GetString(config.PassthroughEndpointKey),
 		rateLimiter:         ratelimit.NewRateLimiter(),
+		requestSchemas:      readRequestSchemas(),
+		maxRequestBytes:     int64(config.GetInt(config.HTTPMaxRequestBytesKey)),
 	}
 }
 
+func readRequestSchemas() map[string]*requestSchema {
+	files, err := config.GetHTTPRequestSchemas()
+	if err != nil {
+		log.WithError(err).Panic("error reading http_request_schemas")
+	}
+
+	schemas := make(map[string]*requestSchema, len(files))
+	for path, file := range files {
+		schema, err := readRequestSchema(file)
+		if err != nil {
+			log.WithError(err).WithField("path", path).Panic("error reading JSON Schema of the request body")
+		}
+		schemas[path] = schema
+	}
+	return schemas
+}
+
+// validateRequestBody checks request body against JSON Schema configured for
+// the URL path, body is read and replaced by the buffered copy. Body is
+// expected to be limited by http.MaxBytesReader to maxBytes.
+func validateRequestBody(schemas map[string]*requestSchema, maxBytes int64, req *http.Request) error {
+	schema, ok := schemas[strings.ToLower(req.URL.Path)]
+	if !ok {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		if maxBytes > 0 && int64(len(body)) >= maxBytes {
+			return &requestBodyTooLargeError{limit: maxBytes}
+		}
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return schema.validateBody(body)
+}
+
 func (h httpHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if h.maxRequestBytes > 0 {
+		req.Body = http.MaxBytesReader(resp, req.Body, h.maxRequestBytes)
+	}
+	if err := validateRequestBody(h.requestSchemas, h.maxRequestBytes, req); err != nil {
+		log.WithError(err).WithField("path", req.URL.Path).Debug("Request body is rejected")
+		status := http.StatusBadRequest
+		if _, ok := err.(*requestBodyTooLargeError); ok {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(resp, err.Error(), status)
+		return
+	}
 	if h.passthroughEnabled {
 		if h.rateLimiter.Allow() == false {
 			http.Error(resp, http.StatusText(429), http.StatusTooManyRequests)
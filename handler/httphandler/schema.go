@@ -0,0 +1,218 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// requestSchema is a subset of JSON Schema which is used to validate request
+// bodies. Supported keywords are type, enum, properties, required,
+// additionalProperties, items, minimum, maximum, minLength, maxLength,
+// minItems and maxItems. Annotations which don't affect validation are
+// allowed, schema with any other keyword is rejected when it is read as
+// ignoring the keyword would accept bodies which the author meant to reject.
+type requestSchema struct {
+	Type                 schemaTypes               `json:"type"`
+	Enum                 []interface{}             `json:"enum"`
+	Properties           map[string]*requestSchema `json:"properties"`
+	Required             []string                  `json:"required"`
+	AdditionalProperties *bool                     `json:"additionalProperties"`
+	Items                *requestSchema            `json:"items"`
+	Minimum              *float64                  `json:"minimum"`
+	Maximum              *float64                  `json:"maximum"`
+	MinLength            *int                      `json:"minLength"`
+	MaxLength            *int                      `json:"maxLength"`
+	MinItems             *int                      `json:"minItems"`
+	MaxItems             *int                      `json:"maxItems"`
+
+	// annotations are not used by validation
+	SchemaURI   string        `json:"$schema"`
+	ID          string        `json:"$id"`
+	Comment     string        `json:"$comment"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Default     interface{}   `json:"default"`
+	Examples    []interface{} `json:"examples"`
+}
+
+// schemaTypes is a value of the type keyword which is either a single type
+// or a list of types.
+type schemaTypes []string
+
+func (types *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*types = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type should be a string or a list of strings")
+	}
+	*types = list
+	return nil
+}
+
+func (types schemaTypes) matches(value interface{}) bool {
+	for _, typ := range types {
+		if typeOf(value) == typ || (typ == "number" && typeOf(value) == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns JSON Schema type of the value decoded by encoding/json.
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// readRequestSchema reads schema from JSON file.
+func readRequestSchema(file string) (schema *requestSchema, err error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	schema = &requestSchema{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(schema); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			return nil, fmt.Errorf("JSON Schema %v uses unsupported keyword %v", file, strings.TrimPrefix(err.Error(), "json: unknown field "))
+		}
+		return nil, fmt.Errorf("cannot parse JSON Schema %v: %v", file, err)
+	}
+	return schema, nil
+}
+
+// requestBodyTooLargeError is returned when request body exceeds
+// http_max_request_bytes.
+type requestBodyTooLargeError struct {
+	limit int64
+}
+
+func (err *requestBodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body should not exceed %v bytes", err.limit)
+}
+
+// validateBody returns error if body is not a JSON document which matches
+// the schema.
+func (schema *requestSchema) validateBody(body []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("body is not a valid JSON: %v", err)
+	}
+	return schema.validate("body", value)
+}
+
+// validate checks value, path is used in error message to point to the
+// incorrect part of the document.
+func (schema *requestSchema) validate(path string, value interface{}) error {
+	if len(schema.Type) > 0 && !schema.Type.matches(value) {
+		return fmt.Errorf("%v should be %v, got %v", path, strings.Join(schema.Type, " or "), typeOf(value))
+	}
+	if len(schema.Enum) > 0 && !containsValue(schema.Enum, value) {
+		return fmt.Errorf("%v should be one of %v", path, schema.Enum)
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return schema.validateObject(path, value)
+	case []interface{}:
+		return schema.validateArray(path, value)
+	case string:
+		length := utf8.RuneCountInString(value)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return fmt.Errorf("%v should be at least %v characters long", path, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return fmt.Errorf("%v should be at most %v characters long", path, *schema.MaxLength)
+		}
+	case float64:
+		if schema.Minimum != nil && value < *schema.Minimum {
+			return fmt.Errorf("%v should be at least %v", path, *schema.Minimum)
+		}
+		if schema.Maximum != nil && value > *schema.Maximum {
+			return fmt.Errorf("%v should be at most %v", path, *schema.Maximum)
+		}
+	}
+	return nil
+}
+
+func (schema *requestSchema) validateObject(path string, object map[string]interface{}) error {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%v.%v is required", path, name)
+		}
+	}
+
+	// names are sorted to return the same error for the same body
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				return fmt.Errorf("%v.%v is not allowed", path, name)
+			}
+			continue
+		}
+		if err := property.validate(path+"."+name, object[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (schema *requestSchema) validateArray(path string, array []interface{}) error {
+	if schema.MinItems != nil && len(array) < *schema.MinItems {
+		return fmt.Errorf("%v should contain at least %v items", path, *schema.MinItems)
+	}
+	if schema.MaxItems != nil && len(array) > *schema.MaxItems {
+		return fmt.Errorf("%v should contain at most %v items", path, *schema.MaxItems)
+	}
+	if schema.Items == nil {
+		return nil
+	}
+	for i, item := range array {
+		if err := schema.Items.validate(fmt.Sprintf("%v[%v]", path, i), item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAddSchema = `{
+	"type": "object",
+	"required": ["a", "b"],
+	"additionalProperties": false,
+	"properties": {
+		"a": { "type": "integer", "minimum": 0 },
+		"b": { "type": "integer", "maximum": 100 },
+		"op": { "type": "string", "enum": ["add", "sub"] },
+		"tags": { "type": "array", "maxItems": 2, "items": { "type": "string", "minLength": 1 } }
+	}
+}`
+
+func newTestSchema(t *testing.T, schemaJson string) *requestSchema {
+	schema := &requestSchema{}
+	if err := json.Unmarshal([]byte(schemaJson), schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestRequestSchemaConformingBody(t *testing.T) {
+	schema := newTestSchema(t, testAddSchema)
+
+	err := schema.validateBody([]byte(`{"a": 1, "b": 2, "op": "add", "tags": ["x"]}`))
+
+	assert.Nil(t, err)
+}
+
+func TestRequestSchemaNonConformingBody(t *testing.T) {
+	schema := newTestSchema(t, testAddSchema)
+
+	for body, expected := range map[string]string{
+		`[1, 2]`:                                    "body should be object, got array",
+		`{"a": 1}`:                                  "body.b is required",
+		`{"a": 1.5, "b": 2}`:                        "body.a should be integer, got number",
+		`{"a": -1, "b": 2}`:                         "body.a should be at least 0",
+		`{"a": 1, "b": 101}`:                        "body.b should be at most 100",
+		`{"a": 1, "b": 2, "op": "mul"}`:             "body.op should be one of [add sub]",
+		`{"a": 1, "b": 2, "c": 3}`:                  "body.c is not allowed",
+		`{"a": 1, "b": 2, "tags": [""]}`:            "body.tags[0] should be at least 1 characters long",
+		`{"a": 1, "b": 2, "tags": [1]}`:             "body.tags[0] should be string, got integer",
+		`{"a": 1, "b": 2, "tags": ["x", "y", "z"]}`: "body.tags should contain at most 2 items",
+	} {
+		err := schema.validateBody([]byte(body))
+
+		if assert.NotNil(t, err, body) {
+			assert.Equal(t, expected, err.Error(), body)
+		}
+	}
+}
+
+func TestRequestSchemaInvalidJson(t *testing.T) {
+	schema := newTestSchema(t, testAddSchema)
+
+	err := schema.validateBody([]byte(`{"a": `))
+
+	assert.Equal(t, "body is not a valid JSON: unexpected end of JSON input", err.Error())
+}
+
+func TestRequestSchemaListOfTypes(t *testing.T) {
+	schema := newTestSchema(t, `{ "type": ["number", "null"] }`)
+
+	assert.Nil(t, schema.validateBody([]byte(`1.5`)))
+	assert.Nil(t, schema.validateBody([]byte(`2`)), "integer is a number")
+	assert.Nil(t, schema.validateBody([]byte(`null`)))
+	assert.Equal(t, "body should be number or null, got string", schema.validateBody([]byte(`"1"`)).Error())
+}
+
+func TestReadRequestSchema(t *testing.T) {
+	file, err := ioutil.TempFile("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(testAddSchema)
+	file.Close()
+
+	schema, err := readRequestSchema(file.Name())
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, schema.Required)
+}
+
+func writeTestSchema(t *testing.T, schemaJson string) (file string) {
+	f, err := ioutil.TempFile("", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(schemaJson)
+	f.Close()
+	return f.Name()
+}
+
+func TestReadRequestSchemaUnsupportedKeyword(t *testing.T) {
+	file := writeTestSchema(t, `{"type": "object", "properties": {"email": {"type": "string", "format": "email"}}}`)
+	defer os.Remove(file)
+
+	_, err := readRequestSchema(file)
+
+	assert.Equal(t, "JSON Schema "+file+" uses unsupported keyword \"format\"", err.Error())
+}
+
+func TestReadRequestSchemaAnnotations(t *testing.T) {
+	file := writeTestSchema(t, `{"$schema": "http://json-schema.org/draft-07/schema#", "title": "add",
+		"description": "adds numbers", "properties": {"a": {"type": "integer", "default": 0, "examples": [1]}}}`)
+	defer os.Remove(file)
+
+	_, err := readRequestSchema(file)
+
+	assert.Nil(t, err)
+}
+
+func serveTestRequest(handler http.Handler, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", path, strings.NewReader(body)))
+	return recorder
+}
+
+func TestHTTPHandlerConformingBody(t *testing.T) {
+	handler := &httpHandler{requestSchemas: map[string]*requestSchema{"/add": newTestSchema(t, testAddSchema)}}
+
+	recorder := serveTestRequest(handler, "/Add", `{"a": 1, "b": 2}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `{"a": 1, "b": 2}`, recorder.Body.String(), "body is passed to the service")
+}
+
+func TestHTTPHandlerNonConformingBody(t *testing.T) {
+	handler := &httpHandler{requestSchemas: map[string]*requestSchema{"/add": newTestSchema(t, testAddSchema)}}
+
+	recorder := serveTestRequest(handler, "/add", `{"a": 1}`)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "body.b is required\n", recorder.Body.String())
+}
+
+func TestHTTPHandlerPathWithoutSchema(t *testing.T) {
+	handler := &httpHandler{requestSchemas: map[string]*requestSchema{"/add": newTestSchema(t, testAddSchema)}}
+
+	recorder := serveTestRequest(handler, "/sub", `not a JSON`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, `not a JSON`, recorder.Body.String())
+}
+
+func TestHTTPHandlerBodyTooLarge(t *testing.T) {
+	handler := &httpHandler{requestSchemas: map[string]*requestSchema{"/add": newTestSchema(t, testAddSchema)}, maxRequestBytes: 10}
+
+	recorder := serveTestRequest(handler, "/add", `{"a": 1, "b": 2}`)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Equal(t, "request body should not exceed 10 bytes\n", recorder.Body.String())
+}
+
+func TestHTTPHandlerBodyWithinLimit(t *testing.T) {
+	handler := &httpHandler{requestSchemas: map[string]*requestSchema{"/add": newTestSchema(t, testAddSchema)}, maxRequestBytes: 16}
+
+	recorder := serveTestRequest(handler, "/add", `{"a": 1, "b": 2}`)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/singnet/snet-daemon/metrics"
 	"github.com/singnet/snet-daemon/ratelimit"
+	"github.com/singnet/snet-daemon/tracing"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -26,12 +28,30 @@ const (
 type GrpcStreamContext struct {
 	MD   metadata.MD
 	Info *grpc.StreamServerInfo
+	// Span is a tracing span of the call, it is nil when tracing is
+	// disabled.
+	Span *tracing.Span
+	// Context is a context of the call, it is done when the call is
+	// cancelled. It is nil when call is not received by the gRPC server.
+	Context context.Context
 }
 
 func (context *GrpcStreamContext) String() string {
 	return fmt.Sprintf("{MD: %v, Info: %v", context.MD, *context.Info)
 }
 
+// GetBigIntFromHeaders gets big.Int value from the first of headers which is
+// present in the call metadata. It is used to support few names of the same
+// header sent by different clients.
+func (context *GrpcStreamContext) GetBigIntFromHeaders(headers []string) (value *big.Int, err *GrpcError) {
+	for _, header := range headers {
+		if len(context.MD.Get(header)) > 0 {
+			return GetBigInt(context.MD, header)
+		}
+	}
+	return nil, NewGrpcErrorf(codes.InvalidArgument, "missing \"%v\"", strings.Join(headers, "\" or \""))
+}
+
 // Payment represents payment handler specific data which is validated
 // and used to complete payment.
 type Payment interface{}
@@ -49,6 +69,10 @@ const (
 type GrpcError struct {
 	// Status is a gRPC call status
 	Status *status.Status
+	// Reason is a reason of the call rejection which is used as a metrics
+	// label, see metrics.RejectionReason. Empty reason means that error is
+	// not counted as rejection.
+	Reason string
 }
 
 // Err returns error to return correct gRPC error to the caller
@@ -56,6 +80,9 @@ func (err *GrpcError) Err() error {
 	if err.Status == nil {
 		return nil
 	}
+	if err.Reason != "" {
+		return metrics.NewRejectedCallError(err.Status, err.Reason)
+	}
 	return err.Status.Err()
 }
 
@@ -98,13 +125,37 @@ type PaymentHandler interface {
 	CompleteAfterError(payment Payment, result error) (err *GrpcError)
 }
 
+// MessagePaymentHandler can be implemented by PaymentHandler to check
+// payment after each message received from client in the stream. It allows
+// validating income which accrues during long streaming calls.
+type MessagePaymentHandler interface {
+	// MessageReceived is called after each message received from client,
+	// messageCount is a number of messages received so far including this
+	// one. Returning error cancels the stream.
+	MessageReceived(payment Payment, context *GrpcStreamContext, messageCount int) (err *GrpcError)
+}
+
+// RateLimiter decides whether incoming call is allowed, it is implemented
+// by rate.Limiter and ratelimit.ReloadableLimiter.
+type RateLimiter interface {
+	Allow() bool
+	Burst() int
+}
+
 type rateLimitInterceptor struct {
-	rateLimiter rate.Limiter
+	rateLimiter RateLimiter
 }
 
 func GrpcRateLimitInterceptor() grpc.StreamServerInterceptor {
+	limiter := ratelimit.NewRateLimiter()
+	return NewGrpcRateLimitInterceptor(&limiter)
+}
+
+// NewGrpcRateLimitInterceptor returns gRPC interceptor which rejects calls
+// with ResourceExhausted status when rate limit is reached.
+func NewGrpcRateLimitInterceptor(limiter RateLimiter) grpc.StreamServerInterceptor {
 	interceptor := &rateLimitInterceptor{
-		rateLimiter: ratelimit.NewRateLimiter(),
+		rateLimiter: limiter,
 	}
 	return interceptor.intercept
 }
@@ -112,7 +163,7 @@ func GrpcRateLimitInterceptor() grpc.StreamServerInterceptor {
 func (interceptor *rateLimitInterceptor) intercept(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 	if !interceptor.rateLimiter.Allow() {
 		log.WithField("rateLimiter.Burst()", interceptor.rateLimiter.Burst()).Info("rate limit reached, too many requests to handle")
-		return status.New(codes.ResourceExhausted, "rate limiting , too many requests to handle").Err()
+		return metrics.NewRejectedCallError(status.New(codes.ResourceExhausted, "rate limiting , too many requests to handle"), metrics.RejectionRateLimited)
 	}
 	e := handler(srv, ss)
 	if e != nil {
@@ -184,6 +235,15 @@ func (interceptor *paymentValidationInterceptor) intercept(srv interface{}, ss g
 
 	log.WithField("payment", payment).Debug("New payment received")
 
+	if messagePaymentHandler, ok := paymentHandler.(MessagePaymentHandler); ok {
+		ss = &paymentServerStream{
+			ServerStream:   ss,
+			paymentHandler: messagePaymentHandler,
+			payment:        payment,
+			context:        context,
+		}
+	}
+
 	e = handler(srv, ss)
 
 	if e != nil {
@@ -202,6 +262,31 @@ func (interceptor *paymentValidationInterceptor) intercept(srv interface{}, ss g
 	return nil
 }
 
+// paymentServerStream calls MessagePaymentHandler after each message received
+// from client.
+type paymentServerStream struct {
+	grpc.ServerStream
+	paymentHandler MessagePaymentHandler
+	payment        Payment
+	context        *GrpcStreamContext
+	messageCount   int
+}
+
+func (stream *paymentServerStream) RecvMsg(m interface{}) error {
+	err := stream.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+
+	stream.messageCount++
+	if e := stream.paymentHandler.MessageReceived(stream.payment, stream.context, stream.messageCount); e != nil {
+		log.WithField("messageCount", stream.messageCount).WithField("error", e).Warn("Stream is cancelled by payment handler")
+		return e.Err()
+	}
+
+	return nil
+}
+
 func getGrpcContext(serverStream grpc.ServerStream, info *grpc.StreamServerInfo) (context *GrpcStreamContext, err *GrpcError) {
 	md, ok := metadata.FromIncomingContext(serverStream.Context())
 	if !ok {
@@ -210,8 +295,10 @@ func getGrpcContext(serverStream grpc.ServerStream, info *grpc.StreamServerInfo)
 	}
 
 	return &GrpcStreamContext{
-		MD:   md,
-		Info: info,
+		MD:      md,
+		Info:    info,
+		Span:    tracing.SpanFromContext(serverStream.Context()),
+		Context: serverStream.Context(),
 	}, nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 )
@@ -76,6 +77,32 @@ func TestGetBigIntTooManyValues(t *testing.T) {
 	assert.Equal(t, NewGrpcErrorf(codes.InvalidArgument, "too many values for key \"big-int-key\": [12345 54321]"), err)
 }
 
+func TestGetBigIntFromHeadersPrimaryHeader(t *testing.T) {
+	context := &GrpcStreamContext{MD: metadata.Pairs("primary-key", "12345", "alternate-key", "54321")}
+
+	value, err := context.GetBigIntFromHeaders([]string{"primary-key", "alternate-key"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(12345), value)
+}
+
+func TestGetBigIntFromHeadersAlternateHeader(t *testing.T) {
+	context := &GrpcStreamContext{MD: metadata.Pairs("alternate-key", "54321")}
+
+	value, err := context.GetBigIntFromHeaders([]string{"primary-key", "alternate-key"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(54321), value)
+}
+
+func TestGetBigIntFromHeadersNoValue(t *testing.T) {
+	context := &GrpcStreamContext{MD: metadata.Pairs()}
+
+	_, err := context.GetBigIntFromHeaders([]string{"primary-key", "alternate-key"})
+
+	assert.Equal(t, NewGrpcErrorf(codes.InvalidArgument, "missing \"primary-key\" or \"alternate-key\""), err)
+}
+
 func TestGetBytes(t *testing.T) {
 	md := metadata.Pairs("binary-key-bin", string([]byte{0x00, 0x01, 0xFE, 0xFF}))
 
@@ -92,3 +119,76 @@ func TestGetBytesIncorrectBinaryKey(t *testing.T) {
 
 	assert.Equal(t, NewGrpcErrorf(codes.InvalidArgument, "incorrect binary key name \"binary-key\""), err)
 }
+
+type messagePaymentHandlerMock struct {
+	paidMessages int
+	completed    bool
+	failed       bool
+}
+
+func (h *messagePaymentHandlerMock) Type() (typ string) {
+	return "message-mock"
+}
+
+func (h *messagePaymentHandlerMock) Payment(context *GrpcStreamContext) (payment Payment, err *GrpcError) {
+	return "payment", nil
+}
+
+func (h *messagePaymentHandlerMock) Complete(payment Payment) (err *GrpcError) {
+	h.completed = true
+	return nil
+}
+
+func (h *messagePaymentHandlerMock) CompleteAfterError(payment Payment, result error) (err *GrpcError) {
+	h.failed = true
+	return nil
+}
+
+func (h *messagePaymentHandlerMock) MessageReceived(payment Payment, context *GrpcStreamContext, messageCount int) (err *GrpcError) {
+	if messageCount > h.paidMessages {
+		return NewGrpcErrorf(codes.FailedPrecondition, "message %v is not paid", messageCount)
+	}
+	return nil
+}
+
+type recvServerStreamMock struct {
+	*serverStreamMock
+}
+
+func (stream *recvServerStreamMock) RecvMsg(m interface{}) error {
+	return nil
+}
+
+func receiveMessages(count int) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		for i := 0; i < count; i++ {
+			if err := stream.RecvMsg(nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestPaymentValidationInterceptorValidatesEachMessage(t *testing.T) {
+	paymentHandler := &messagePaymentHandlerMock{paidMessages: 3}
+	interceptor := GrpcPaymentValidationInterceptor(paymentHandler)
+	stream := &recvServerStreamMock{newServerStreamMock(metadata.Pairs())}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{IsClientStream: true}, receiveMessages(3))
+
+	assert.Nil(t, err)
+	assert.True(t, paymentHandler.completed)
+}
+
+func TestPaymentValidationInterceptorCancelsStreamWhenPaymentStalls(t *testing.T) {
+	paymentHandler := &messagePaymentHandlerMock{paidMessages: 2}
+	interceptor := GrpcPaymentValidationInterceptor(paymentHandler)
+	stream := &recvServerStreamMock{newServerStreamMock(metadata.Pairs())}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{IsClientStream: true}, receiveMessages(3))
+
+	assert.Equal(t, NewGrpcErrorf(codes.FailedPrecondition, "message 3 is not paid").Err(), err)
+	assert.False(t, paymentHandler.completed)
+	assert.True(t, paymentHandler.failed)
+}
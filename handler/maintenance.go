@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// MaintenanceSchedule tells whether daemon is in maintenance mode. During
+// maintenance service calls and admin HTTP writes are rejected and channels
+// are not claimed automatically.
+type MaintenanceSchedule struct {
+	windows []*config.MaintenanceWindow
+	now     func() time.Time
+	// active is true if daemon is in maintenance mode, it is used to log
+	// entering and leaving the mode once
+	active bool
+	mutex  sync.Mutex
+}
+
+// NewMaintenanceSchedule returns schedule of the maintenance windows or nil
+// if there are no windows. nil schedule is never active.
+func NewMaintenanceSchedule(windows []*config.MaintenanceWindow) *MaintenanceSchedule {
+	if len(windows) == 0 {
+		return nil
+	}
+	return &MaintenanceSchedule{windows: windows, now: time.Now}
+}
+
+// Active returns true if current time is inside one of the windows, until is
+// the end of this window.
+func (schedule *MaintenanceSchedule) Active() (until time.Time, ok bool) {
+	if schedule == nil {
+		return
+	}
+	for _, window := range schedule.windows {
+		if until, ok = window.Active(schedule.now()); ok {
+			break
+		}
+	}
+
+	schedule.mutex.Lock()
+	defer schedule.mutex.Unlock()
+	if ok != schedule.active {
+		schedule.active = ok
+		if ok {
+			log.WithField("until", until).Info("Maintenance window is started, service calls, admin writes and auto claim are paused")
+		} else {
+			log.Info("Maintenance window is finished, service calls, admin writes and auto claim are resumed")
+		}
+	}
+	return
+}
+
+// GrpcMaintenanceInterceptor returns gRPC interceptor which rejects calls
+// with Unavailable status while the schedule is active. Only service calls
+// which change payment channel state are intercepted, channel state can still
+// be read during maintenance. Interceptor should be chained before payment
+// validation so rejected calls are not paid.
+func GrpcMaintenanceInterceptor(schedule *MaintenanceSchedule) grpc.StreamServerInterceptor {
+	if schedule == nil {
+		return NoOpInterceptor
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if until, ok := schedule.Active(); ok {
+			log.WithField("method", info.FullMethod).Debug("Call is rejected during maintenance")
+			return status.Errorf(codes.Unavailable, "daemon is in maintenance until %v", until.UTC().Format(time.RFC3339))
+		}
+		return handler(srv, ss)
+	}
+}
+
+// HTTPMaintenanceHandler returns handler which rejects HTTP requests
+// changing the state (all methods except GET, HEAD and OPTIONS) with 503
+// status while the schedule is active, other requests are passed to the
+// delegate.
+func HTTPMaintenanceHandler(schedule *MaintenanceSchedule, delegate http.Handler) http.Handler {
+	if schedule == nil {
+		return delegate
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method == "GET" || req.Method == "HEAD" || req.Method == "OPTIONS" {
+			delegate.ServeHTTP(resp, req)
+			return
+		}
+		if until, ok := schedule.Active(); ok {
+			log.WithField("path", req.URL.Path).Debug("Request is rejected during maintenance")
+			resp.Header().Set("Retry-After", until.UTC().Format(http.TimeFormat))
+			http.Error(resp, "daemon is in maintenance until "+until.UTC().Format(time.RFC3339), http.StatusServiceUnavailable)
+			return
+		}
+		delegate.ServeHTTP(resp, req)
+	})
+}
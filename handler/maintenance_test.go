@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// newTestMaintenanceSchedule returns maintenance schedule which uses fake
+// clock.
+func newTestMaintenanceSchedule(t *testing.T, now *time.Time, confs ...config.MaintenanceWindowConf) *MaintenanceSchedule {
+	var windows []*config.MaintenanceWindow
+	for _, conf := range confs {
+		window, err := config.NewMaintenanceWindow(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		windows = append(windows, window)
+	}
+	return &MaintenanceSchedule{windows: windows, now: func() time.Time { return *now }}
+}
+
+func TestMaintenanceInterceptorOutsideWindow(t *testing.T) {
+	now := time.Date(2018, 10, 20, 0, 30, 0, 0, time.UTC)
+	schedule := newTestMaintenanceSchedule(t, &now, config.MaintenanceWindowConf{Start: "01:00", End: "02:00"})
+
+	called, err := callMethod(GrpcMaintenanceInterceptor(schedule), "/example.Calculator/add")
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
+
+func TestMaintenanceInterceptorInsideWindow(t *testing.T) {
+	now := time.Date(2018, 10, 20, 1, 30, 0, 0, time.UTC)
+	schedule := newTestMaintenanceSchedule(t, &now,
+		config.MaintenanceWindowConf{Start: "2018-10-19T00:00:00Z", End: "2018-10-19T01:00:00Z"},
+		config.MaintenanceWindowConf{Start: "01:00", End: "02:00"})
+
+	called, err := callMethod(GrpcMaintenanceInterceptor(schedule), "/example.Calculator/add")
+
+	assert.Equal(t, status.Error(codes.Unavailable, "daemon is in maintenance until 2018-10-20T02:00:00Z"), err)
+	assert.False(t, called)
+}
+
+func TestMaintenanceInterceptorLeavesMaintenance(t *testing.T) {
+	now := time.Date(2018, 10, 20, 1, 30, 0, 0, time.UTC)
+	schedule := newTestMaintenanceSchedule(t, &now, config.MaintenanceWindowConf{Start: "01:00", End: "02:00"})
+
+	_, err := callMethod(GrpcMaintenanceInterceptor(schedule), "/example.Calculator/add")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.True(t, schedule.active)
+
+	now = now.Add(time.Hour)
+	called, err := callMethod(GrpcMaintenanceInterceptor(schedule), "/example.Calculator/add")
+	assert.Nil(t, err)
+	assert.True(t, called)
+	assert.False(t, schedule.active)
+}
+
+func TestMaintenanceInterceptorNoWindows(t *testing.T) {
+	interceptor := GrpcMaintenanceInterceptor(nil)
+
+	called, err := callMethod(interceptor, "/example.Calculator/add")
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
+
+func TestMaintenanceScheduleNil(t *testing.T) {
+	var schedule *MaintenanceSchedule
+
+	_, ok := schedule.Active()
+
+	assert.False(t, ok)
+	assert.Nil(t, NewMaintenanceSchedule(nil))
+}
+
+func serveMaintenanceRequest(schedule *MaintenanceSchedule, method string) (resp *httptest.ResponseRecorder, called bool) {
+	resp = httptest.NewRecorder()
+	handler := HTTPMaintenanceHandler(schedule, http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	handler.ServeHTTP(resp, httptest.NewRequest(method, "/subscriptions", nil))
+	return
+}
+
+func TestMaintenanceHTTPHandlerRejectsWrites(t *testing.T) {
+	now := time.Date(2018, 10, 20, 1, 30, 0, 0, time.UTC)
+	schedule := newTestMaintenanceSchedule(t, &now, config.MaintenanceWindowConf{Start: "01:00", End: "02:00"})
+
+	resp, called := serveMaintenanceRequest(schedule, "POST")
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, "daemon is in maintenance until 2018-10-20T02:00:00Z\n", resp.Body.String())
+	assert.Equal(t, "Sat, 20 Oct 2018 02:00:00 GMT", resp.Header().Get("Retry-After"))
+}
+
+func TestMaintenanceHTTPHandlerPassesReads(t *testing.T) {
+	now := time.Date(2018, 10, 20, 1, 30, 0, 0, time.UTC)
+	schedule := newTestMaintenanceSchedule(t, &now, config.MaintenanceWindowConf{Start: "01:00", End: "02:00"})
+
+	_, called := serveMaintenanceRequest(schedule, "GET")
+
+	assert.True(t, called)
+}
+
+func TestMaintenanceHTTPHandlerOutsideWindow(t *testing.T) {
+	now := time.Date(2018, 10, 20, 2, 30, 0, 0, time.UTC)
+	schedule := newTestMaintenanceSchedule(t, &now, config.MaintenanceWindowConf{Start: "01:00", End: "02:00"})
+
+	_, called := serveMaintenanceRequest(schedule, "POST")
+
+	assert.True(t, called)
+}
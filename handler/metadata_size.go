@@ -0,0 +1,37 @@
+package handler
+
+import (
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcMetadataSizeInterceptor returns gRPC interceptor which rejects calls
+// with incoming metadata larger than maxBytes with ResourceExhausted status.
+// Size is a sum of lengths of all keys and values. Zero maxBytes disables the
+// check. Interceptor should be chained before payment validation so
+// oversized metadata is not parsed.
+func GrpcMetadataSizeInterceptor(maxBytes int) grpc.StreamServerInterceptor {
+	if maxBytes <= 0 {
+		return NoOpInterceptor
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		if size := metadataSize(md); size > maxBytes {
+			log.WithField("method", info.FullMethod).WithField("size", size).Debug("Call with too large metadata is rejected")
+			return status.Errorf(codes.ResourceExhausted, "metadata size %v bytes exceeds limit of %v bytes", size, maxBytes)
+		}
+		return handler(srv, ss)
+	}
+}
+
+func metadataSize(md metadata.MD) (size int) {
+	for key, values := range md {
+		for _, value := range values {
+			size += len(key) + len(value)
+		}
+	}
+	return
+}
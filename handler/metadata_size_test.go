@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func callWithMetadata(interceptor grpc.StreamServerInterceptor, md metadata.MD) (called bool, err error) {
+	err = interceptor(nil, newServerStreamMock(md), &grpc.StreamServerInfo{FullMethod: "/example.Calculator/add"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			called = true
+			return nil
+		})
+	return
+}
+
+func TestMetadataSizeUnderLimit(t *testing.T) {
+	interceptor := GrpcMetadataSizeInterceptor(100)
+
+	called, err := callWithMetadata(interceptor, metadata.Pairs("key", strings.Repeat("v", 97)))
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
+
+func TestMetadataSizeOverLimit(t *testing.T) {
+	interceptor := GrpcMetadataSizeInterceptor(100)
+
+	called, err := callWithMetadata(interceptor, metadata.Pairs("key", strings.Repeat("v", 90), "key", strings.Repeat("v", 10)))
+
+	assert.Equal(t, status.Error(codes.ResourceExhausted, "metadata size 106 bytes exceeds limit of 100 bytes"), err)
+	assert.False(t, called)
+}
+
+func TestMetadataSizeNoLimit(t *testing.T) {
+	called, err := callWithMetadata(GrpcMetadataSizeInterceptor(0), metadata.Pairs("key", strings.Repeat("v", 10000)))
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcMethodFilterInterceptor returns gRPC interceptor which rejects calls
+// of the methods which are disabled with Unimplemented status. If
+// enabledMethods is not empty then only these methods can be called.
+// disabledMethods takes precedence over enabledMethods. Interceptor should
+// be chained before payment validation so rejected calls are not paid.
+func GrpcMethodFilterInterceptor(enabledMethods, disabledMethods []string) grpc.StreamServerInterceptor {
+	if len(enabledMethods) == 0 && len(disabledMethods) == 0 {
+		return NoOpInterceptor
+	}
+
+	filter := &methodFilter{
+		enabled:  toSet(enabledMethods),
+		disabled: toSet(disabledMethods),
+	}
+	for method := range filter.disabled {
+		if filter.enabled[method] {
+			log.WithField("method", method).Warn("Method is both enabled and disabled, it is disabled")
+		}
+	}
+	return filter.intercept
+}
+
+type methodFilter struct {
+	enabled  map[string]bool
+	disabled map[string]bool
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func (filter *methodFilter) allowed(method string) bool {
+	if filter.disabled[method] {
+		return false
+	}
+	return len(filter.enabled) == 0 || filter.enabled[method]
+}
+
+func (filter *methodFilter) intercept(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !filter.allowed(info.FullMethod) {
+		log.WithField("method", info.FullMethod).Debug("Call of disabled method is rejected")
+		return status.Errorf(codes.Unimplemented, "method %v is disabled", info.FullMethod)
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func callMethod(interceptor grpc.StreamServerInterceptor, method string) (called bool, err error) {
+	err = interceptor(nil, newServerStreamMock(metadata.Pairs()), &grpc.StreamServerInfo{FullMethod: method},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			called = true
+			return nil
+		})
+	return
+}
+
+func TestMethodFilterAllowlistOnly(t *testing.T) {
+	interceptor := GrpcMethodFilterInterceptor([]string{"/example.Calculator/add"}, nil)
+
+	called, err := callMethod(interceptor, "/example.Calculator/add")
+	assert.Nil(t, err)
+	assert.True(t, called)
+
+	called, err = callMethod(interceptor, "/example.Calculator/sub")
+	assert.Equal(t, status.Error(codes.Unimplemented, "method /example.Calculator/sub is disabled"), err)
+	assert.False(t, called)
+}
+
+func TestMethodFilterDenylistOnly(t *testing.T) {
+	interceptor := GrpcMethodFilterInterceptor(nil, []string{"/example.Calculator/sub"})
+
+	called, err := callMethod(interceptor, "/example.Calculator/add")
+	assert.Nil(t, err)
+	assert.True(t, called)
+
+	called, err = callMethod(interceptor, "/example.Calculator/sub")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.False(t, called)
+}
+
+func TestMethodFilterDenyTakesPrecedence(t *testing.T) {
+	interceptor := GrpcMethodFilterInterceptor(
+		[]string{"/example.Calculator/add", "/example.Calculator/sub"},
+		[]string{"/example.Calculator/sub"})
+
+	called, err := callMethod(interceptor, "/example.Calculator/add")
+	assert.Nil(t, err)
+	assert.True(t, called)
+
+	called, err = callMethod(interceptor, "/example.Calculator/sub")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.False(t, called)
+}
+
+func TestMethodFilterNoLists(t *testing.T) {
+	called, err := callMethod(GrpcMethodFilterInterceptor(nil, nil), "/example.Calculator/add")
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
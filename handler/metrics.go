@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/singnet/snet-daemon/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcMetricsInterceptor returns gRPC interceptor which counts calls as
+// "requests" and finished calls by status code as "responses.<code>", for
+// instance "responses.ok" or "responses.unauthenticated". Calls rejected by
+// daemon are also counted by reason as "rejections.<reason>", see
+// metrics.RejectionReason.
+func GrpcMetricsInterceptor(recorder metrics.Recorder) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		recorder.Count("requests", 1)
+		err := handler(srv, ss)
+		recorder.Count("responses."+strings.ToLower(status.Code(err).String()), 1)
+		if reason := metrics.RejectionReason(err); reason != "" {
+			recorder.Count("rejections."+reason, 1)
+		}
+		return err
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/singnet/snet-daemon/metrics"
+)
+
+type recorderMock struct {
+	counters map[string]int64
+}
+
+func (recorder *recorderMock) Count(name string, value int64) {
+	recorder.counters[name] += value
+}
+
+func TestGrpcMetricsInterceptor(t *testing.T) {
+	recorder := &recorderMock{counters: make(map[string]int64)}
+	interceptor := GrpcMetricsInterceptor(recorder)
+	info := &grpc.StreamServerInfo{FullMethod: "/service/method"}
+
+	interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error { return nil })
+	interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.Unauthenticated, "payment is not signed by channel signer")
+	})
+
+	assert.Equal(t, map[string]int64{
+		"requests":                  2,
+		"responses.ok":              1,
+		"responses.unauthenticated": 1,
+	}, recorder.counters)
+}
+
+func TestGrpcMetricsInterceptorCountsRejections(t *testing.T) {
+	recorder := &recorderMock{counters: make(map[string]int64)}
+	interceptor := GrpcMetricsInterceptor(recorder)
+	info := &grpc.StreamServerInfo{FullMethod: "/service/method"}
+	reject := func(reason string) {
+		interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+			err := NewGrpcError(codes.Unauthenticated, "payment is rejected")
+			err.Reason = reason
+			return err.Err()
+		})
+	}
+
+	reject(metrics.RejectionUnderpaid)
+	reject(metrics.RejectionExpired)
+	reject(metrics.RejectionBadSignature)
+	reject(metrics.RejectionBadSignature)
+	reject("unknown")
+	interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.Internal, "service error")
+	})
+
+	assert.Equal(t, map[string]int64{
+		"requests":                  6,
+		"responses.unauthenticated": 5,
+		"responses.internal":        1,
+		"rejections.underpaid":      1,
+		"rejections.expired":        1,
+		"rejections.bad_signature":  2,
+		"rejections.other":          1,
+	}, recorder.counters)
+}
+
+func TestGrpcMetricsInterceptorCountsRateLimitedCalls(t *testing.T) {
+	recorder := &recorderMock{counters: make(map[string]int64)}
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	interceptor := grpc_middleware.ChainStreamServer(GrpcMetricsInterceptor(recorder), NewGrpcRateLimitInterceptor(limiter))
+	info := &grpc.StreamServerInfo{FullMethod: "/service/method"}
+
+	interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error { return nil })
+	err := interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error { return nil })
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, map[string]int64{
+		"requests":                    2,
+		"responses.ok":                1,
+		"responses.resourceexhausted": 1,
+		"rejections.rate_limited":     1,
+	}, recorder.counters)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"crypto/subtle"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// OfflineAuthTokenHeader contains token which authorizes the call when
+	// blockchain is disabled and daemon is in "token" offline_auth_mode.
+	OfflineAuthTokenHeader = "snet-offline-auth-token"
+	// OfflineAuthPaymentType is a payment type of the offline token
+	// authorization.
+	OfflineAuthPaymentType = "offline-token"
+)
+
+type offlineTokenPaymentHandler struct {
+	tokens [][]byte
+}
+
+// NewOfflineTokenPaymentHandler returns payment handler which allows only
+// calls which contain one of the given tokens in the OfflineAuthTokenHeader.
+// It is used instead of the payment channels when blockchain is disabled.
+func NewOfflineTokenPaymentHandler(tokens []string) PaymentHandler {
+	handler := &offlineTokenPaymentHandler{}
+	for _, token := range tokens {
+		handler.tokens = append(handler.tokens, []byte(token))
+	}
+	return handler
+}
+
+func (h *offlineTokenPaymentHandler) Type() (typ string) {
+	return OfflineAuthPaymentType
+}
+
+func (h *offlineTokenPaymentHandler) Payment(context *GrpcStreamContext) (payment Payment, err *GrpcError) {
+	token, err := GetSingleValue(context.MD, OfflineAuthTokenHeader)
+	if err != nil {
+		return nil, NewGrpcError(codes.Unauthenticated, err.Status.Message())
+	}
+
+	for _, expected := range h.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), expected) == 1 {
+			return nil, nil
+		}
+	}
+
+	return nil, NewGrpcErrorf(codes.Unauthenticated, "incorrect \"%v\"", OfflineAuthTokenHeader)
+}
+
+func (h *offlineTokenPaymentHandler) Complete(payment Payment) (err *GrpcError) {
+	return nil
+}
+
+func (h *offlineTokenPaymentHandler) CompleteAfterError(payment Payment, result error) (err *GrpcError) {
+	return nil
+}
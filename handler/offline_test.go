@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestOfflineTokenPaymentHandlerCorrectToken(t *testing.T) {
+	handler := NewOfflineTokenPaymentHandler([]string{"token-1", "token-2"})
+
+	_, err := handler.Payment(&GrpcStreamContext{MD: metadata.Pairs(OfflineAuthTokenHeader, "token-2")})
+
+	assert.Nil(t, err)
+}
+
+func TestOfflineTokenPaymentHandlerIncorrectToken(t *testing.T) {
+	handler := NewOfflineTokenPaymentHandler([]string{"token-1"})
+
+	_, err := handler.Payment(&GrpcStreamContext{MD: metadata.Pairs(OfflineAuthTokenHeader, "token-2")})
+
+	assert.Equal(t, NewGrpcError(codes.Unauthenticated, "incorrect \"snet-offline-auth-token\""), err)
+}
+
+func TestOfflineTokenPaymentHandlerNoToken(t *testing.T) {
+	handler := NewOfflineTokenPaymentHandler([]string{"token-1"})
+
+	_, err := handler.Payment(&GrpcStreamContext{MD: metadata.Pairs()})
+
+	assert.Equal(t, NewGrpcError(codes.Unauthenticated, "missing \"snet-offline-auth-token\""), err)
+}
+
+func callInterceptor(interceptor grpc.StreamServerInterceptor, md metadata.MD) (called bool, err error) {
+	err = interceptor(nil, newServerStreamMock(md), &grpc.StreamServerInfo{FullMethod: "/service/method"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			called = true
+			return nil
+		})
+	return
+}
+
+func TestOfflineFreeModeAllowsAnyCall(t *testing.T) {
+	called, err := callInterceptor(NoOpInterceptor, metadata.Pairs())
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
+
+func TestOfflineTokenModeInterceptor(t *testing.T) {
+	interceptor := GrpcPaymentValidationInterceptor(NewOfflineTokenPaymentHandler([]string{"token-1"}))
+
+	called, err := callInterceptor(interceptor, metadata.Pairs(OfflineAuthTokenHeader, "token-1"))
+	assert.Nil(t, err)
+	assert.True(t, called)
+
+	called, err = callInterceptor(interceptor, metadata.Pairs(OfflineAuthTokenHeader, "token-2"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// responseReadBufferSize is a size of the buffer used to read service
+// response which is sent to the client as a single message.
+const responseReadBufferSize = 32 * 1024
+
+// responseTooLargeError is returned when service response exceeds
+// passthrough_max_response_bytes.
+func responseTooLargeError(maxBytes int) error {
+	return status.Errorf(codes.ResourceExhausted, "service response exceeds limit of %v bytes", maxBytes)
+}
+
+// limitedResponseReader returns error as soon as service response exceeds
+// maxBytes, bytes over the limit are not returned. Zero maxBytes means no
+// limit.
+type limitedResponseReader struct {
+	src      io.Reader
+	maxBytes int
+	read     int
+}
+
+func newLimitedResponseReader(src io.Reader, maxBytes int) *limitedResponseReader {
+	return &limitedResponseReader{src: src, maxBytes: maxBytes}
+}
+
+func (reader *limitedResponseReader) Read(p []byte) (n int, err error) {
+	n, err = reader.src.Read(p)
+	if reader.maxBytes > 0 && reader.read+n > reader.maxBytes {
+		n = reader.maxBytes - reader.read
+		reader.read = reader.maxBytes
+		return n, responseTooLargeError(reader.maxBytes)
+	}
+	reader.read += n
+	return n, err
+}
+
+// readResponse reads whole service response, reading stops as soon as
+// response exceeds maxBytes.
+func readResponse(src io.Reader, maxBytes int) (data []byte, err error) {
+	reader := newLimitedResponseReader(src, maxBytes)
+	buffer := make([]byte, responseReadBufferSize)
+	for {
+		n, err := reader.Read(buffer)
+		data = append(data, buffer[:n]...)
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// streamResponse passes messages of the service response to send as soon
+// as each message is read, so no more than one message is kept in memory.
+// Each message is a length-delimited frame: 4 bytes big-endian length of the
+// message followed by the message itself. Frame which makes response
+// greater than maxBytes is not read.
+func streamResponse(src io.Reader, maxBytes int, send func(data []byte) error) error {
+	read := 0
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(src, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return status.Errorf(codes.Internal, "error reading response frame header; error: %+v", err)
+		}
+
+		length := int(binary.BigEndian.Uint32(header))
+		read += length
+		if maxBytes > 0 && read > maxBytes {
+			return responseTooLargeError(maxBytes)
+		}
+
+		// new message is allocated each time because gRPC transport may
+		// still keep the previous one after send returns; buffer grows as
+		// data is read, so incorrect length doesn't allocate memory upfront
+		message := bytes.NewBuffer(make([]byte, 0, minInt(length, responseReadBufferSize)))
+		if _, err := io.CopyN(message, src, int64(length)); err != nil {
+			return status.Errorf(codes.Internal, "error reading response frame of %v bytes; error: %+v", length, err)
+		}
+		if err := send(message.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
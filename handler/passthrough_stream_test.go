@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/singnet/snet-daemon/codec"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// countingReader generates size bytes of deterministic data without
+// keeping it in memory and counts bytes which are read.
+type countingReader struct {
+	size int
+	read int
+}
+
+func (reader *countingReader) Read(p []byte) (n int, err error) {
+	if reader.read >= reader.size {
+		return 0, io.EOF
+	}
+	if len(p) > reader.size-reader.read {
+		p = p[:reader.size-reader.read]
+	}
+	for i := range p {
+		p[i] = byte((reader.read + i) % 251)
+	}
+	reader.read += len(p)
+	return len(p), nil
+}
+
+func sha256Of(reader io.Reader) []byte {
+	hash := sha256.New()
+	io.Copy(hash, reader)
+	return hash.Sum(nil)
+}
+
+// frames returns messages encoded as length-delimited frames
+func frames(messages ...string) []byte {
+	var data []byte
+	for _, message := range messages {
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint32(header, uint32(len(message)))
+		data = append(append(data, header...), message...)
+	}
+	return data
+}
+
+// framedReader returns count frames of frameSize bytes generated by
+// countingReader, so large response is not kept in memory.
+func framedReader(count int, frameSize int) (reader io.Reader, content *countingReader) {
+	content = &countingReader{size: count * frameSize}
+	readers := make([]io.Reader, 0, 2*count)
+	for i := 0; i < count; i++ {
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint32(header, uint32(frameSize))
+		readers = append(readers, bytes.NewReader(header), io.LimitReader(content, int64(frameSize)))
+	}
+	return io.MultiReader(readers...), content
+}
+
+func TestStreamResponse(t *testing.T) {
+	var received []string
+
+	err := streamResponse(bytes.NewReader(frames("first", "", "second")), 0, func(data []byte) error {
+		received = append(received, string(data))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "", "second"}, received)
+}
+
+func TestStreamResponseLargeResponse(t *testing.T) {
+	const count = 160
+	const frameSize = 64*1024 + 3
+	src, content := framedReader(count, frameSize)
+	received := sha256.New()
+	sent, messages := 0, 0
+
+	err := streamResponse(src, 0, func(data []byte) error {
+		assert.Equal(t, frameSize, len(data))
+		assert.Equal(t, sent+frameSize, content.read, "no more than one message is read ahead")
+		received.Write(data)
+		sent += len(data)
+		messages++
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, count*frameSize, sent)
+	assert.Equal(t, count, messages)
+	assert.Equal(t, sha256Of(&countingReader{size: count * frameSize}), received.Sum(nil))
+}
+
+func TestStreamResponseExceedsLimit(t *testing.T) {
+	const frameSize = 64 * 1024
+	const maxBytes = 1024 * 1024
+	src, content := framedReader(160, frameSize)
+	sent := 0
+
+	err := streamResponse(src, maxBytes, func(data []byte) error {
+		sent += len(data)
+		return nil
+	})
+
+	assert.Equal(t, status.Error(codes.ResourceExhausted, "service response exceeds limit of 1048576 bytes"), err)
+	assert.Equal(t, maxBytes, sent)
+	assert.Equal(t, maxBytes, content.read, "frame over the limit is not read")
+}
+
+func TestStreamResponseTruncatedFrame(t *testing.T) {
+	var received []string
+	data := frames("first", "second")
+
+	err := streamResponse(bytes.NewReader(data[:len(data)-1]), 0, func(data []byte) error {
+		received = append(received, string(data))
+		return nil
+	})
+
+	assert.Equal(t, status.Error(codes.Internal, "error reading response frame of 6 bytes; error: EOF"), err)
+	assert.Equal(t, []string{"first"}, received)
+}
+
+func TestStreamResponseSendError(t *testing.T) {
+	sendErr := status.Error(codes.Canceled, "client is gone")
+	calls := 0
+
+	err := streamResponse(bytes.NewReader(frames("first", "second")), 0, func(data []byte) error {
+		calls++
+		return sendErr
+	})
+
+	assert.Equal(t, sendErr, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestReadResponse(t *testing.T) {
+	data, err := readResponse(bytes.NewReader([]byte("response")), 8)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("response"), data)
+}
+
+func TestReadResponseExceedsLimit(t *testing.T) {
+	const maxBytes = 1024 * 1024
+	src := &countingReader{size: 10 * 1024 * 1024}
+
+	data, err := readResponse(src, maxBytes)
+
+	assert.Equal(t, status.Error(codes.ResourceExhausted, "service response exceeds limit of 1048576 bytes"), err)
+	assert.Nil(t, data)
+	assert.True(t, src.read <= maxBytes+responseReadBufferSize, "reading stops right after the limit")
+}
+
+// callProcessPassthrough calls executable which runs given shell script via
+// handler h and returns sizes of the received messages and the call error.
+func callProcessPassthrough(t *testing.T, h grpcHandler, script string) (received []int, err error) {
+	dir, e := ioutil.TempDir("", "passthrough")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	h.executable = filepath.Join(dir, "service.sh")
+	if e = ioutil.WriteFile(h.executable, []byte("#!/bin/sh\n"+script+"\n"), 0700); e != nil {
+		t.Fatal(e)
+	}
+
+	daemonAddress, daemon := startGrpcServer(t, h.grpcToProcess)
+	defer daemon.Stop()
+	daemonConn, e := grpc.Dial(daemonAddress, grpc.WithInsecure())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer daemonConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, e := daemonConn.NewStream(ctx, grpcDesc, "/service/method", grpc.CallContentSubtype("proto"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = stream.SendMsg(&codec.GrpcFrame{Data: []byte("request")}); e != nil {
+		t.Fatal(e)
+	}
+	stream.CloseSend()
+
+	for {
+		response := &codec.GrpcFrame{}
+		if err = stream.RecvMsg(response); err != nil {
+			break
+		}
+		received = append(received, len(response.Data))
+	}
+	return
+}
+
+// printZeros returns script which prints size zero bytes
+func printZeros(size int) string {
+	return "head -c " + strconv.Itoa(size) + " /dev/zero"
+}
+
+// printFrames returns script which prints messages of the given sizes as
+// length-delimited frames
+func printFrames(sizes ...int) string {
+	script := ""
+	for _, size := range sizes {
+		script += fmt.Sprintf("printf '\\%03o\\%03o\\%03o\\%03o'\n", size>>24&0xff, size>>16&0xff, size>>8&0xff, size&0xff)
+		script += printZeros(size) + "\n"
+	}
+	return script
+}
+
+var streamingMethodHandler = grpcHandler{streamingMethods: map[string]bool{"/service/method": true}}
+
+func TestProcessPassthroughStreamsMessages(t *testing.T) {
+	received, err := callProcessPassthrough(t, streamingMethodHandler, printFrames(65536, 0, 300000))
+
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []int{65536, 0, 300000}, received)
+}
+
+func TestProcessPassthroughSendsWholeOutputOfUnaryMethod(t *testing.T) {
+	h := grpcHandler{streamingMethods: map[string]bool{"/service/stream": true}}
+
+	received, err := callProcessPassthrough(t, h, printZeros(300000))
+
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []int{300000}, received)
+}
+
+func TestProcessPassthroughUnaryOutputExceedsLimit(t *testing.T) {
+	received, err := callProcessPassthrough(t, grpcHandler{maxResponseBytes: 100000}, printZeros(300000))
+
+	assert.Equal(t, status.Error(codes.ResourceExhausted, "service response exceeds limit of 100000 bytes"), err)
+	assert.Empty(t, received)
+}
+
+func TestProcessPassthroughStreamExceedsLimit(t *testing.T) {
+	h := streamingMethodHandler
+	h.maxResponseBytes = 100000
+
+	received, err := callProcessPassthrough(t, h, printFrames(65536, 65536, 65536))
+
+	assert.Equal(t, status.Error(codes.ResourceExhausted, "service response exceeds limit of 100000 bytes"), err)
+	assert.Equal(t, []int{65536}, received)
+}
+
+func TestGrpcPassthroughResponseExceedsLimit(t *testing.T) {
+	received, err := callMethodThroughPassthrough(t, grpcHandler{enc: "proto", maxResponseBytes: 8}, "/service/method")
+
+	assert.Equal(t, status.Error(codes.ResourceExhausted, "service response exceeds limit of 8 bytes"), err)
+	assert.Equal(t, []string{"first"}, received)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/singnet/snet-daemon/codec"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startGrpcServer starts server which passes all calls to handler and
+// returns its address.
+func startGrpcServer(t *testing.T, handler grpc.StreamHandler) (address string, server *grpc.Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = grpc.NewServer(grpc.UnknownServiceHandler(handler))
+	go server.Serve(listener)
+	return listener.Addr().String(), server
+}
+
+// slowStreamingService sends two messages and then doesn't finish the call
+// until it is cancelled.
+func slowStreamingService(srv interface{}, stream grpc.ServerStream) error {
+	request := &codec.GrpcFrame{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	for _, data := range []string{"first", "second"} {
+		if err := stream.SendMsg(&codec.GrpcFrame{Data: []byte(data)}); err != nil {
+			return err
+		}
+	}
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+func callThroughPassthrough(t *testing.T, flushOnTimeout bool) (received []string, err error) {
+	return callMethodThroughPassthrough(t, grpcHandler{
+		enc:              "proto",
+		timeout:          100 * time.Millisecond,
+		flushOnTimeout:   flushOnTimeout,
+		streamingMethods: map[string]bool{"/service/method": true},
+	}, "/service/method")
+}
+
+// callMethodThroughPassthrough calls method of the slowStreamingService via
+// handler h and returns received messages and the call error.
+func callMethodThroughPassthrough(t *testing.T, h grpcHandler, method string) (received []string, err error) {
+	serviceAddress, service := startGrpcServer(t, slowStreamingService)
+	defer service.Stop()
+
+	serviceConn, e := grpc.Dial(serviceAddress, grpc.WithInsecure())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer serviceConn.Close()
+
+	h.grpcConn = serviceConn
+	daemonAddress, daemon := startGrpcServer(t, h.grpcToGRPC)
+	defer daemon.Stop()
+
+	daemonConn, e := grpc.Dial(daemonAddress, grpc.WithInsecure())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer daemonConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, e := daemonConn.NewStream(ctx, grpcDesc, method, grpc.CallContentSubtype("proto"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = stream.SendMsg(&codec.GrpcFrame{Data: []byte("request")}); e != nil {
+		t.Fatal(e)
+	}
+	stream.CloseSend()
+
+	for {
+		response := &codec.GrpcFrame{}
+		if err = stream.RecvMsg(response); err != nil {
+			break
+		}
+		received = append(received, string(response.Data))
+	}
+	return
+}
+
+func TestPassthroughTimeoutFlushesReceivedData(t *testing.T) {
+	received, err := callThroughPassthrough(t, true)
+
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []string{"first", "second"}, received)
+}
+
+func TestPassthroughTimeoutReturnsDeadlineExceeded(t *testing.T) {
+	received, err := callThroughPassthrough(t, false)
+
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, []string{"first", "second"}, received)
+}
+
+func TestPassthroughTimeoutOfUnaryMethodReturnsDeadlineExceeded(t *testing.T) {
+	h := grpcHandler{
+		enc:              "proto",
+		timeout:          100 * time.Millisecond,
+		flushOnTimeout:   true,
+		streamingMethods: map[string]bool{"/service/stream": true},
+	}
+
+	_, err := callMethodThroughPassthrough(t, h, "/service/method")
+
+	assert.Equal(t, status.Error(codes.DeadlineExceeded, "service didn't finish call in 100ms"), err)
+}
+
+func TestPassthroughMethodTimeouts(t *testing.T) {
+	h := grpcHandler{
+		enc:            "proto",
+		timeout:        50 * time.Millisecond,
+		methodTimeouts: map[string]time.Duration{"/service/slow": 300 * time.Millisecond},
+	}
+
+	_, fastErr := callMethodThroughPassthrough(t, h, "/service/fast")
+	_, slowErr := callMethodThroughPassthrough(t, h, "/service/Slow")
+
+	assert.Equal(t, status.Error(codes.DeadlineExceeded, "service didn't finish call in 50ms"), fastErr)
+	assert.Equal(t, status.Error(codes.DeadlineExceeded, "service didn't finish call in 300ms"), slowErr)
+}
+
+func TestPassthroughMethodTimeoutFallsBackToDefault(t *testing.T) {
+	h := grpcHandler{
+		timeout:        time.Minute,
+		methodTimeouts: map[string]time.Duration{"/service/slow": time.Hour},
+	}
+
+	assert.Equal(t, time.Hour, h.methodTimeout("/service/slow"))
+	assert.Equal(t, time.Minute, h.methodTimeout("/service/fast"))
+}
+
+// callHandler sends request to the method of the daemon which passes all
+// calls to handler and returns received messages and the call error.
+func callHandler(t *testing.T, handler grpc.StreamHandler, method string, request string) (received []string, err error) {
+	daemonAddress, daemon := startGrpcServer(t, handler)
+	defer daemon.Stop()
+
+	daemonConn, e := grpc.Dial(daemonAddress, grpc.WithInsecure())
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer daemonConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, e := daemonConn.NewStream(ctx, grpcDesc, method, grpc.CallContentSubtype("proto"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e = stream.SendMsg(&codec.GrpcFrame{Data: []byte(request)}); e != nil {
+		t.Fatal(e)
+	}
+	stream.CloseSend()
+
+	for {
+		response := &codec.GrpcFrame{}
+		if err = stream.RecvMsg(response); err != nil {
+			break
+		}
+		received = append(received, string(response.Data))
+	}
+	return
+}
+
+func TestJSONRPCPassthroughMethodTimeout(t *testing.T) {
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// request context is cancelled on disconnect only after body is read
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+	}))
+	defer service.Close()
+	h := grpcHandler{
+		passthroughEndpoint: service.URL,
+		timeout:             time.Minute,
+		methodTimeouts:      map[string]time.Duration{"/service/slow": 100 * time.Millisecond},
+	}
+
+	received, err := callHandler(t, h.grpcToJSONRPC, "/service/slow", "{}")
+
+	assert.Equal(t, status.Error(codes.DeadlineExceeded, "service didn't finish call in 100ms"), err)
+	assert.Empty(t, received)
+}
+
+func TestProcessPassthroughMethodTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passthrough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	executable := filepath.Join(dir, "service.sh")
+	if err = ioutil.WriteFile(executable, []byte("#!/bin/sh\nexec sleep 10\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	h := grpcHandler{
+		executable:     executable,
+		timeout:        time.Minute,
+		methodTimeouts: map[string]time.Duration{"/service/slow": 100 * time.Millisecond},
+	}
+
+	start := time.Now()
+	received, err := callHandler(t, h.grpcToProcess, "/service/slow", "request")
+
+	assert.Equal(t, status.Error(codes.DeadlineExceeded, "service didn't finish call in 100ms"), err)
+	assert.Empty(t, received)
+	assert.True(t, time.Since(start) < 5*time.Second, "process is killed on timeout")
+}
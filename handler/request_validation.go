@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/singnet/snet-daemon/codec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestValidator checks request message received from client before it is
+// passed to the service. message contains raw message data. Returning error
+// rejects the call and error is returned to the client.
+type RequestValidator func(context *GrpcStreamContext, message []byte) (err *GrpcError)
+
+var (
+	requestValidatorsMutex sync.Mutex
+	requestValidators      []RequestValidator
+)
+
+// RegisterRequestValidator adds validator which is applied to requests of
+// the handler returned by NewGrpcHandler. Validators should be registered
+// before handler is created.
+func RegisterRequestValidator(validator RequestValidator) {
+	requestValidatorsMutex.Lock()
+	defer requestValidatorsMutex.Unlock()
+
+	requestValidators = append(requestValidators, validator)
+}
+
+func registeredRequestValidators() []RequestValidator {
+	requestValidatorsMutex.Lock()
+	defer requestValidatorsMutex.Unlock()
+
+	return append([]RequestValidator(nil), requestValidators...)
+}
+
+// NewRequestValidationHandler returns handler which checks each request
+// message using validators before passing it to the handler. First message
+// is checked before handler is called, so service is not called at all when
+// first message is rejected. As handler is called after interceptors,
+// validators are applied after payment validation.
+func NewRequestValidationHandler(handler grpc.StreamHandler, validators ...RequestValidator) grpc.StreamHandler {
+	if len(validators) == 0 {
+		return handler
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		method, _ := grpc.MethodFromServerStream(ss)
+		stream := &validatingServerStream{
+			ServerStream: ss,
+			context:      &GrpcStreamContext{MD: md, Info: &grpc.StreamServerInfo{FullMethod: method}},
+			validators:   validators,
+		}
+
+		first := &codec.GrpcFrame{}
+		stream.firstErr = ss.RecvMsg(first)
+		if stream.firstErr == nil {
+			if err := stream.validate(first); err != nil {
+				return err.Err()
+			}
+			stream.first = first
+		}
+		stream.firstReceived = true
+
+		e := handler(srv, stream)
+		if stream.err != nil {
+			return stream.err.Err()
+		}
+		return e
+	}
+}
+
+// validatingServerStream replays the first message which is received and
+// checked before calling handler and checks all next messages.
+type validatingServerStream struct {
+	grpc.ServerStream
+	context       *GrpcStreamContext
+	validators    []RequestValidator
+	firstReceived bool
+	first         *codec.GrpcFrame
+	firstErr      error
+	err           *GrpcError
+}
+
+func (stream *validatingServerStream) RecvMsg(m interface{}) error {
+	if stream.firstReceived {
+		stream.firstReceived = false
+		if stream.firstErr != nil {
+			return stream.firstErr
+		}
+		frame, ok := m.(*codec.GrpcFrame)
+		if !ok {
+			return NewGrpcErrorf(codes.Internal, "unexpected message type %T", m).Err()
+		}
+		frame.Data = stream.first.Data
+		return nil
+	}
+
+	if err := stream.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	frame, ok := m.(*codec.GrpcFrame)
+	if !ok {
+		return NewGrpcErrorf(codes.Internal, "unexpected message type %T", m).Err()
+	}
+	if err := stream.validate(frame); err != nil {
+		stream.err = err
+		return err.Err()
+	}
+	return nil
+}
+
+func (stream *validatingServerStream) validate(frame *codec.GrpcFrame) *GrpcError {
+	for _, validator := range stream.validators {
+		if err := validator(stream.context, frame.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
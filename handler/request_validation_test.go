@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/singnet/snet-daemon/codec"
+)
+
+type framesServerStreamMock struct {
+	*serverStreamMock
+	frames []string
+}
+
+func (stream *framesServerStreamMock) RecvMsg(m interface{}) error {
+	if len(stream.frames) == 0 {
+		return io.EOF
+	}
+	m.(*codec.GrpcFrame).Data = []byte(stream.frames[0])
+	stream.frames = stream.frames[1:]
+	return nil
+}
+
+func newFramesServerStreamMock(frames ...string) *framesServerStreamMock {
+	return &framesServerStreamMock{serverStreamMock: newServerStreamMock(metadata.Pairs()), frames: frames}
+}
+
+// passthroughMock records messages which are passed to the service
+type passthroughMock struct {
+	called   bool
+	received []string
+}
+
+func (passthrough *passthroughMock) handle(srv interface{}, stream grpc.ServerStream) error {
+	passthrough.called = true
+	for {
+		frame := &codec.GrpcFrame{}
+		err := stream.RecvMsg(frame)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return NewGrpcErrorf(codes.Internal, "failed proxying s2c: %v", err).Err()
+		}
+		passthrough.received = append(passthrough.received, string(frame.Data))
+	}
+}
+
+func rejectMessage(rejected string) RequestValidator {
+	return func(context *GrpcStreamContext, message []byte) *GrpcError {
+		if string(message) == rejected {
+			return NewGrpcErrorf(codes.InvalidArgument, "malformed request: %v", rejected)
+		}
+		return nil
+	}
+}
+
+func TestRequestValidationHandlerRejectsRequest(t *testing.T) {
+	passthrough := &passthroughMock{}
+	handler := NewRequestValidationHandler(passthrough.handle, rejectMessage("bad"))
+
+	err := handler(nil, newFramesServerStreamMock("bad"))
+
+	assert.Equal(t, NewGrpcError(codes.InvalidArgument, "malformed request: bad").Err(), err)
+	assert.False(t, passthrough.called)
+}
+
+func TestRequestValidationHandlerPassesRequest(t *testing.T) {
+	passthrough := &passthroughMock{}
+	handler := NewRequestValidationHandler(passthrough.handle, rejectMessage("bad"))
+
+	err := handler(nil, newFramesServerStreamMock("good-1", "good-2"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"good-1", "good-2"}, passthrough.received)
+}
+
+func TestRequestValidationHandlerRejectsMessageInStream(t *testing.T) {
+	passthrough := &passthroughMock{}
+	handler := NewRequestValidationHandler(passthrough.handle, rejectMessage("bad"))
+
+	err := handler(nil, newFramesServerStreamMock("good", "bad", "good"))
+
+	assert.Equal(t, NewGrpcError(codes.InvalidArgument, "malformed request: bad").Err(), err)
+	assert.Equal(t, []string{"good"}, passthrough.received)
+}
+
+func TestRequestValidationHandlerNoValidators(t *testing.T) {
+	passthrough := &passthroughMock{}
+	handler := NewRequestValidationHandler(passthrough.handle)
+
+	err := handler(nil, newFramesServerStreamMock("bad"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bad"}, passthrough.received)
+}
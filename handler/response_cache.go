@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/singnet/snet-daemon/codec"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// ResponseCache keeps responses of the unary calls of idempotent methods.
+// Response is identified by full method name and hash of the request body
+// and expires after TTL.
+type ResponseCache struct {
+	mutex      sync.Mutex
+	methods    map[string]bool
+	ttl        time.Duration
+	maxEntries int
+	entries    map[responseCacheKey]*responseCacheEntry
+	now        func() time.Time
+}
+
+type responseCacheKey struct {
+	method  string
+	request [sha256.Size]byte
+}
+
+type responseCacheEntry struct {
+	response []byte
+	expires  time.Time
+}
+
+// NewResponseCache returns cache of the responses of methods. Cache keeps
+// no more than maxEntries responses, zero means no limit.
+func NewResponseCache(methods []string, ttl time.Duration, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		methods:    toSet(methods),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[responseCacheKey]*responseCacheEntry),
+		now:        time.Now,
+	}
+}
+
+func newResponseCacheKey(method string, request []byte) responseCacheKey {
+	return responseCacheKey{method: method, request: sha256.Sum256(request)}
+}
+
+func (cache *ResponseCache) get(key responseCacheKey) (response []byte, ok bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !cache.now().Before(entry.expires) {
+		delete(cache.entries, key)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+func (cache *ResponseCache) put(key responseCacheKey, response []byte) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	if cache.maxEntries > 0 && len(cache.entries) >= cache.maxEntries {
+		for k, entry := range cache.entries {
+			if !now.Before(entry.expires) {
+				delete(cache.entries, k)
+			}
+		}
+		if len(cache.entries) >= cache.maxEntries {
+			log.WithField("maxEntries", cache.maxEntries).Debug("Response cache is full, response is not cached")
+			return
+		}
+	}
+	cache.entries[key] = &responseCacheEntry{response: response, expires: now.Add(cache.ttl)}
+}
+
+// Interceptor returns gRPC interceptor which returns cached response of the
+// cacheable method without calling the service. It should be chained after
+// payment validation interceptor so calls served from the cache are paid as
+// usual. Only unary calls are supported: response is cached when service
+// returns exactly one message.
+func (cache *ResponseCache) Interceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !cache.methods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		request := &codec.GrpcFrame{}
+		if err := ss.RecvMsg(request); err != nil {
+			return err
+		}
+
+		key := newResponseCacheKey(info.FullMethod, request.Data)
+		if response, ok := cache.get(key); ok {
+			log.WithField("method", info.FullMethod).Debug("Response is returned from cache")
+			return ss.SendMsg(&codec.GrpcFrame{Data: response})
+		}
+
+		stream := &responseCachingServerStream{ServerStream: ss, request: request}
+		if err := handler(srv, stream); err != nil {
+			return err
+		}
+		if len(stream.responses) == 1 {
+			cache.put(key, stream.responses[0])
+		}
+		return nil
+	}
+}
+
+// responseCachingServerStream returns already received request to the
+// handler and keeps responses sent by handler.
+type responseCachingServerStream struct {
+	grpc.ServerStream
+	request   *codec.GrpcFrame
+	responses [][]byte
+}
+
+func (stream *responseCachingServerStream) RecvMsg(m interface{}) error {
+	if stream.request == nil {
+		return stream.ServerStream.RecvMsg(m)
+	}
+	m.(*codec.GrpcFrame).Data = stream.request.Data
+	stream.request = nil
+	return nil
+}
+
+func (stream *responseCachingServerStream) SendMsg(m interface{}) error {
+	if frame, ok := m.(*codec.GrpcFrame); ok {
+		stream.responses = append(stream.responses, frame.Data)
+	}
+	return stream.ServerStream.SendMsg(m)
+}
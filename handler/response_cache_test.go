@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/singnet/snet-daemon/codec"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type countingService struct {
+	calls    int
+	response string
+	err      error
+}
+
+func (service *countingService) handle(srv interface{}, stream grpc.ServerStream) error {
+	service.calls++
+	if err := stream.RecvMsg(&codec.GrpcFrame{}); err != nil {
+		return err
+	}
+	if service.err != nil {
+		return service.err
+	}
+	return stream.SendMsg(&codec.GrpcFrame{Data: []byte(service.response)})
+}
+
+var cacheableMethodInfo = &grpc.StreamServerInfo{FullMethod: "/service/cacheable"}
+
+func callWithCache(t *testing.T, cache *ResponseCache, service *countingService, info *grpc.StreamServerInfo, request string) *frameServerStreamMock {
+	stream := &frameServerStreamMock{serverStreamMock: *newServerStreamMock(metadata.Pairs()), request: []byte(request)}
+
+	err := cache.Interceptor()(nil, stream, info, service.handle)
+
+	assert.Nil(t, err)
+	return stream
+}
+
+func TestResponseCacheHit(t *testing.T) {
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 0)
+	service := &countingService{response: "response"}
+
+	first := callWithCache(t, cache, service, cacheableMethodInfo, "request")
+	service.response = "new response"
+	second := callWithCache(t, cache, service, cacheableMethodInfo, "request")
+
+	assert.Equal(t, 1, service.calls)
+	assert.Equal(t, [][]byte{[]byte("response")}, first.sent)
+	assert.Equal(t, [][]byte{[]byte("response")}, second.sent)
+}
+
+func TestResponseCacheMissOnDifferentRequest(t *testing.T) {
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 0)
+	service := &countingService{response: "response"}
+
+	callWithCache(t, cache, service, cacheableMethodInfo, "request")
+	callWithCache(t, cache, service, cacheableMethodInfo, "another request")
+
+	assert.Equal(t, 2, service.calls)
+}
+
+func TestResponseCacheMissAfterTtl(t *testing.T) {
+	now := time.Now()
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 0)
+	cache.now = func() time.Time { return now }
+	service := &countingService{response: "response"}
+
+	callWithCache(t, cache, service, cacheableMethodInfo, "request")
+	now = now.Add(time.Minute)
+	callWithCache(t, cache, service, cacheableMethodInfo, "request")
+
+	assert.Equal(t, 2, service.calls)
+}
+
+func TestResponseCacheSkipsNotCacheableMethod(t *testing.T) {
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 0)
+	service := &countingService{response: "response"}
+	info := &grpc.StreamServerInfo{FullMethod: "/service/other"}
+
+	callWithCache(t, cache, service, info, "request")
+	callWithCache(t, cache, service, info, "request")
+
+	assert.Equal(t, 2, service.calls)
+}
+
+func TestResponseCacheDoesNotKeepErrors(t *testing.T) {
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 0)
+	service := &countingService{err: errors.New("service error")}
+	stream := &frameServerStreamMock{serverStreamMock: *newServerStreamMock(metadata.Pairs()), request: []byte("request")}
+
+	err := cache.Interceptor()(nil, stream, cacheableMethodInfo, service.handle)
+	service.err = nil
+	service.response = "response"
+	second := callWithCache(t, cache, service, cacheableMethodInfo, "request")
+
+	assert.Equal(t, errors.New("service error"), err)
+	assert.Equal(t, 2, service.calls)
+	assert.Equal(t, [][]byte{[]byte("response")}, second.sent)
+}
+
+func TestResponseCacheMaxEntries(t *testing.T) {
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 1)
+	service := &countingService{response: "response"}
+
+	callWithCache(t, cache, service, cacheableMethodInfo, "first")
+	callWithCache(t, cache, service, cacheableMethodInfo, "second")
+	callWithCache(t, cache, service, cacheableMethodInfo, "second")
+
+	assert.Equal(t, 3, service.calls)
+	assert.Equal(t, 1, len(cache.entries))
+}
+
+type countingPaymentHandlerMock struct {
+	payments  int
+	completed int
+}
+
+func (h *countingPaymentHandlerMock) Type() (typ string) {
+	return "counting-mock"
+}
+
+func (h *countingPaymentHandlerMock) Payment(context *GrpcStreamContext) (payment Payment, err *GrpcError) {
+	h.payments++
+	return "payment", nil
+}
+
+func (h *countingPaymentHandlerMock) Complete(payment Payment) (err *GrpcError) {
+	h.completed++
+	return nil
+}
+
+func (h *countingPaymentHandlerMock) CompleteAfterError(payment Payment, result error) (err *GrpcError) {
+	return nil
+}
+
+func TestResponseCacheHitIsPaid(t *testing.T) {
+	cache := NewResponseCache([]string{"/service/cacheable"}, time.Minute, 0)
+	service := &countingService{response: "response"}
+	paymentHandler := &countingPaymentHandlerMock{}
+	payment := GrpcPaymentValidationInterceptor(paymentHandler)
+	call := func() *frameServerStreamMock {
+		stream := &frameServerStreamMock{serverStreamMock: *newServerStreamMock(metadata.Pairs()), request: []byte("request")}
+		err := payment(nil, stream, cacheableMethodInfo, func(srv interface{}, ss grpc.ServerStream) error {
+			return cache.Interceptor()(srv, ss, cacheableMethodInfo, service.handle)
+		})
+		assert.Nil(t, err)
+		return stream
+	}
+
+	call()
+	second := call()
+
+	assert.Equal(t, 1, service.calls)
+	assert.Equal(t, [][]byte{[]byte("response")}, second.sent)
+	assert.Equal(t, 2, paymentHandler.payments)
+	assert.Equal(t, 2, paymentHandler.completed)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/singnet/snet-daemon/config"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// OrganizationIDHeader is an id of the organization which service is
+	// called. It is required only when daemon serves more than one service.
+	OrganizationIDHeader = "snet-organization-id"
+	// ServiceIDHeader is an id of the service which is called. It is required
+	// only when daemon serves more than one service.
+	ServiceIDHeader = "snet-service-id"
+)
+
+// ServiceKey identifies one of the services served by daemon.
+type ServiceKey struct {
+	OrganizationID string
+	ServiceID      string
+}
+
+func (key ServiceKey) String() string {
+	return fmt.Sprintf("%v/%v", key.OrganizationID, key.ServiceID)
+}
+
+// ServiceKeyHeaders contains names of the metadata headers which are used
+// to pass key of the called service.
+type ServiceKeyHeaders struct {
+	OrganizationID string
+	ServiceID      string
+}
+
+// DefaultServiceKeyHeaders are OrganizationIDHeader and ServiceIDHeader.
+var DefaultServiceKeyHeaders = ServiceKeyHeaders{
+	OrganizationID: OrganizationIDHeader,
+	ServiceID:      ServiceIDHeader,
+}
+
+// GetServiceKeyHeaders returns service key headers names from configuration.
+// Names are converted to lower case as gRPC metadata keys are lower case.
+func GetServiceKeyHeaders() ServiceKeyHeaders {
+	headers := ServiceKeyHeaders{
+		OrganizationID: strings.ToLower(config.GetString(config.OrganizationIDHeaderKey)),
+		ServiceID:      strings.ToLower(config.GetString(config.ServiceIDHeaderKey)),
+	}
+	if headers.OrganizationID == "" {
+		headers.OrganizationID = OrganizationIDHeader
+	}
+	if headers.ServiceID == "" {
+		headers.ServiceID = ServiceIDHeader
+	}
+	return headers
+}
+
+// GetServiceKey gets key of the called service from gRPC metadata using
+// default header names.
+func GetServiceKey(md metadata.MD) (key ServiceKey, err *GrpcError) {
+	return DefaultServiceKeyHeaders.GetServiceKey(md)
+}
+
+// GetServiceKey gets key of the called service from gRPC metadata.
+func (headers ServiceKeyHeaders) GetServiceKey(md metadata.MD) (key ServiceKey, err *GrpcError) {
+	organizationID, err := GetSingleValue(md, headers.OrganizationID)
+	if err != nil {
+		return
+	}
+
+	serviceID, err := GetSingleValue(md, headers.ServiceID)
+	if err != nil {
+		return
+	}
+
+	return ServiceKey{OrganizationID: organizationID, ServiceID: serviceID}, nil
+}
+
+type serviceRoutingHandler struct {
+	headers  ServiceKeyHeaders
+	handlers map[ServiceKey]grpc.StreamHandler
+}
+
+// NewServiceRoutingHandler returns gRPC handler which passes each call to the
+// handler of the service specified in the call metadata.
+func NewServiceRoutingHandler(headers ServiceKeyHeaders, handlers map[ServiceKey]grpc.StreamHandler) grpc.StreamHandler {
+	return (&serviceRoutingHandler{headers: headers, handlers: handlers}).handle
+}
+
+func (router *serviceRoutingHandler) handle(srv interface{}, inStream grpc.ServerStream) error {
+	md, ok := metadata.FromIncomingContext(inStream.Context())
+	if !ok {
+		return NewGrpcError(codes.InvalidArgument, "missing metadata").Err()
+	}
+
+	key, err := router.headers.GetServiceKey(md)
+	if err != nil {
+		return err.Err()
+	}
+
+	handler, ok := router.handlers[key]
+	if !ok {
+		log.WithField("service", key).Warn("Call to the unknown service")
+		return NewGrpcErrorf(codes.NotFound, "service \"%v\" is not served", key).Err()
+	}
+
+	return handler(srv, inStream)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+type serverStreamMock struct {
+	grpc.ServerStream
+	context context.Context
+}
+
+func (stream *serverStreamMock) Context() context.Context {
+	return stream.context
+}
+
+func newServerStreamMock(md metadata.MD) *serverStreamMock {
+	return &serverStreamMock{context: metadata.NewIncomingContext(context.Background(), md)}
+}
+
+func TestServiceRoutingHandler(t *testing.T) {
+	var called []string
+	handlerByName := func(name string) grpc.StreamHandler {
+		return func(srv interface{}, stream grpc.ServerStream) error {
+			called = append(called, name)
+			return nil
+		}
+	}
+	router := NewServiceRoutingHandler(DefaultServiceKeyHeaders, map[ServiceKey]grpc.StreamHandler{
+		{OrganizationID: "org", ServiceID: "service-a"}: handlerByName("a"),
+		{OrganizationID: "org", ServiceID: "service-b"}: handlerByName("b"),
+	})
+
+	errB := router(nil, newServerStreamMock(metadata.Pairs(OrganizationIDHeader, "org", ServiceIDHeader, "service-b")))
+	errA := router(nil, newServerStreamMock(metadata.Pairs(OrganizationIDHeader, "org", ServiceIDHeader, "service-a")))
+
+	assert.Nil(t, errA)
+	assert.Nil(t, errB)
+	assert.Equal(t, []string{"b", "a"}, called)
+}
+
+func TestServiceRoutingHandlerUnknownService(t *testing.T) {
+	router := NewServiceRoutingHandler(DefaultServiceKeyHeaders, map[ServiceKey]grpc.StreamHandler{})
+
+	err := router(nil, newServerStreamMock(metadata.Pairs(OrganizationIDHeader, "org", ServiceIDHeader, "service-c")))
+
+	assert.Equal(t, NewGrpcErrorf(codes.NotFound, "service \"org/service-c\" is not served").Err(), err)
+}
+
+func TestServiceRoutingHandlerNoServiceId(t *testing.T) {
+	router := NewServiceRoutingHandler(DefaultServiceKeyHeaders, map[ServiceKey]grpc.StreamHandler{})
+
+	err := router(nil, newServerStreamMock(metadata.Pairs(OrganizationIDHeader, "org")))
+
+	assert.Equal(t, NewGrpcErrorf(codes.InvalidArgument, "missing \"snet-service-id\"").Err(), err)
+}
+
+func TestServiceRoutingHandlerCustomHeaders(t *testing.T) {
+	called := false
+	router := NewServiceRoutingHandler(ServiceKeyHeaders{OrganizationID: "x-org", ServiceID: "x-service"},
+		map[ServiceKey]grpc.StreamHandler{
+			{OrganizationID: "org", ServiceID: "service-a"}: func(srv interface{}, stream grpc.ServerStream) error {
+				called = true
+				return nil
+			},
+		})
+
+	err := router(nil, newServerStreamMock(metadata.Pairs("x-org", "org", "x-service", "service-a")))
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
+
+func TestGetServiceKeyHeaders(t *testing.T) {
+	config.Vip().Set(config.OrganizationIDHeaderKey, "X-Org")
+	config.Vip().Set(config.ServiceIDHeaderKey, "X-Service")
+	defer config.Vip().Set(config.OrganizationIDHeaderKey, OrganizationIDHeader)
+	defer config.Vip().Set(config.ServiceIDHeaderKey, ServiceIDHeader)
+
+	headers := GetServiceKeyHeaders()
+
+	assert.Equal(t, ServiceKeyHeaders{OrganizationID: "x-org", ServiceID: "x-service"}, headers)
+}
+
+func TestGetServiceKeyHeadersDefault(t *testing.T) {
+	assert.Equal(t, DefaultServiceKeyHeaders, GetServiceKeyHeaders())
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/singnet/snet-daemon/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// GrpcTracingInterceptor returns gRPC interceptor which starts "request"
+// span for each call using global tracer. Span context passed by the caller
+// in the traceparent metadata becomes the parent of the span. Interceptor
+// should be the first in the chain to cover time spent in the others.
+func GrpcTracingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := ss.Context()
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if parent, ok := tracing.Extract(md); ok {
+				ctx = tracing.ContextWithRemoteParent(ctx, parent)
+			}
+		}
+
+		ctx, span := tracing.StartSpan(ctx, "request")
+		span.SetAttribute("rpc.method", info.FullMethod)
+		defer func() {
+			span.SetError(err)
+			span.End()
+		}()
+
+		return handler(srv, &tracingServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// tracingServerStream replaces context of the stream by the context which
+// contains request span.
+type tracingServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *tracingServerStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/singnet/snet-daemon/codec"
+	"github.com/singnet/snet-daemon/tracing"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func withTestTracer() (exporter *tracing.InMemoryExporter, reset func()) {
+	exporter = tracing.NewInMemoryExporter()
+	tracing.SetGlobalTracer(tracing.NewTracer(exporter))
+	return exporter, func() { tracing.SetGlobalTracer(nil) }
+}
+
+func TestGrpcTracingInterceptor(t *testing.T) {
+	exporter, reset := withTestTracer()
+	defer reset()
+	stream := newServerStreamMock(metadata.Pairs(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	var grpcContext *GrpcStreamContext
+
+	err := GrpcTracingInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/service/method"}, func(srv interface{}, ss grpc.ServerStream) error {
+		grpcContext, _ = getGrpcContext(ss, &grpc.StreamServerInfo{})
+		_, span := tracing.StartSpan(ss.Context(), "child")
+		span.End()
+		return errors.New("service error")
+	})
+
+	assert.Equal(t, errors.New("service error"), err)
+	assert.Equal(t, []string{"child", "request"}, exporter.SpanNames())
+	child, request := exporter.Spans()[0], exporter.Spans()[1]
+	assert.Equal(t, request, grpcContext.Span)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", request.ParentSpanID.String())
+	assert.Equal(t, map[string]string{"rpc.method": "/service/method"}, request.Attributes)
+	assert.Equal(t, errors.New("service error"), request.Err)
+	assert.Equal(t, request.SpanContext.SpanID, child.ParentSpanID)
+}
+
+func TestGrpcTracingInterceptorWithoutParent(t *testing.T) {
+	exporter, reset := withTestTracer()
+	defer reset()
+
+	err := GrpcTracingInterceptor()(nil, newServerStreamMock(metadata.Pairs()), &grpc.StreamServerInfo{FullMethod: "/service/method"}, func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"request"}, exporter.SpanNames())
+	assert.True(t, exporter.Spans()[0].SpanContext.IsValid())
+	assert.Nil(t, exporter.Spans()[0].Err)
+}
+
+func TestPassthroughSpanIsPropagatedToService(t *testing.T) {
+	exporter, reset := withTestTracer()
+	defer reset()
+	received := make(chan metadata.MD, 1)
+	serviceAddress, service := startGrpcServer(t, func(srv interface{}, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		received <- md
+		return echoHandler(srv, stream)
+	})
+	defer service.Stop()
+	serviceConn, err := grpc.Dial(serviceAddress, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serviceConn.Close()
+	h := grpcHandler{grpcConn: serviceConn, enc: "proto"}
+	daemonAddress, daemon := startGrpcServer(t, h.grpcToGRPC)
+	defer daemon.Stop()
+	daemonConn, err := grpc.Dial(daemonAddress, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer daemonConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := daemonConn.NewStream(ctx, grpcDesc, "/service/method", grpc.CallContentSubtype("proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.SendMsg(&codec.GrpcFrame{Data: []byte("request")})
+	stream.CloseSend()
+	stream.RecvMsg(&codec.GrpcFrame{})
+	daemon.GracefulStop()
+
+	assert.Equal(t, []string{"passthrough"}, exporter.SpanNames())
+	passthrough := exporter.Spans()[0]
+	assert.Equal(t, map[string]string{"rpc.method": "/service/method"}, passthrough.Attributes)
+	parent, ok := tracing.Extract(<-received)
+	assert.True(t, ok)
+	assert.Equal(t, passthrough.SpanContext, parent)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/singnet/snet-daemon/config"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// transportHeaders are metadata headers which are added by gRPC and HTTP/2
+// transport, they are always known.
+var transportHeaders = map[string]bool{
+	"content-type": true,
+	"user-agent":   true,
+	"te":           true,
+}
+
+// GrpcUnknownMetadataInterceptor returns gRPC interceptor which enforces
+// unknown_metadata_policy. When policy is config.UnknownMetadataPolicyReject
+// calls containing metadata headers other than knownHeaders are rejected
+// with InvalidArgument status. Pseudo-headers, "grpc-" headers and other
+// transport headers are always accepted. Other policies accept all calls.
+func GrpcUnknownMetadataInterceptor(policy string, knownHeaders []string) grpc.StreamServerInterceptor {
+	if policy != config.UnknownMetadataPolicyReject {
+		return NoOpInterceptor
+	}
+
+	known := make(map[string]bool, len(knownHeaders))
+	for _, header := range knownHeaders {
+		known[strings.ToLower(header)] = true
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		if unknown := unknownHeaders(md, known); len(unknown) > 0 {
+			log.WithField("method", info.FullMethod).WithField("headers", unknown).Debug("Call with unknown metadata is rejected")
+			return status.Errorf(codes.InvalidArgument, "unknown metadata headers: %v", strings.Join(unknown, ", "))
+		}
+		return handler(srv, ss)
+	}
+}
+
+// unknownHeaders returns sorted names of the md headers which are neither
+// known nor transport ones.
+func unknownHeaders(md metadata.MD, known map[string]bool) (unknown []string) {
+	for header := range md {
+		if known[header] || transportHeaders[header] ||
+			strings.HasPrefix(header, ":") || strings.HasPrefix(header, "grpc-") {
+			continue
+		}
+		unknown = append(unknown, header)
+	}
+	sort.Strings(unknown)
+	return
+}
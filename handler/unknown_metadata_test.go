@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var knownTestHeaders = []string{PaymentTypeHeader, "Snet-Payment-Channel-Id"}
+
+func TestUnknownMetadataRejectKnownHeaders(t *testing.T) {
+	interceptor := GrpcUnknownMetadataInterceptor(config.UnknownMetadataPolicyReject, knownTestHeaders)
+
+	called, err := callWithMetadata(interceptor, metadata.Pairs(
+		PaymentTypeHeader, "escrow",
+		"snet-payment-channel-id", "42",
+		":authority", "localhost:8080",
+		"content-type", "application/grpc",
+		"user-agent", "grpc-go/1.16.0",
+		"grpc-accept-encoding", "gzip",
+	))
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
+
+func TestUnknownMetadataRejectUnknownHeaders(t *testing.T) {
+	interceptor := GrpcUnknownMetadataInterceptor(config.UnknownMetadataPolicyReject, knownTestHeaders)
+
+	called, err := callWithMetadata(interceptor, metadata.Pairs(
+		PaymentTypeHeader, "escrow",
+		"x-custom", "value",
+		"snet-payment-channel-idd", "42",
+	))
+
+	assert.Equal(t, status.Error(codes.InvalidArgument, "unknown metadata headers: snet-payment-channel-idd, x-custom"), err)
+	assert.False(t, called)
+}
+
+func TestUnknownMetadataIgnore(t *testing.T) {
+	interceptor := GrpcUnknownMetadataInterceptor(config.UnknownMetadataPolicyIgnore, knownTestHeaders)
+
+	called, err := callWithMetadata(interceptor, metadata.Pairs("x-custom", "value"))
+
+	assert.Nil(t, err)
+	assert.True(t, called)
+}
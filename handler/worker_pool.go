@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WorkerPool executes tasks using fixed number of goroutines. Tasks which
+// cannot be started immediately wait in the bounded queue.
+type WorkerPool struct {
+	tasks  chan func()
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// NewWorkerPool starts workerCount workers which take tasks from the queue
+// of queueSize length.
+func NewWorkerPool(workerCount, queueSize int) *WorkerPool {
+	pool := &WorkerPool{
+		tasks: make(chan func(), queueSize),
+	}
+	for i := 0; i < workerCount; i++ {
+		go pool.work()
+	}
+	return pool
+}
+
+func (pool *WorkerPool) work() {
+	for task := range pool.tasks {
+		task()
+	}
+}
+
+// Submit puts task into the queue without blocking. It returns false if
+// queue is full or pool is closed.
+func (pool *WorkerPool) Submit(task func()) bool {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	if pool.closed {
+		return false
+	}
+
+	select {
+	case pool.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting new tasks; workers exit after completing tasks
+// which are already queued.
+func (pool *WorkerPool) Close() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if !pool.closed {
+		pool.closed = true
+		close(pool.tasks)
+	}
+}
+
+// GrpcWorkerPoolInterceptor returns gRPC interceptor which processes calls
+// using worker pool. Call is rejected with ResourceExhausted status when
+// queue of the pool is full.
+func GrpcWorkerPoolInterceptor(pool *WorkerPool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done := make(chan error, 1)
+		if !pool.Submit(func() { done <- handler(srv, ss) }) {
+			log.WithField("method", info.FullMethod).Warn("handler queue is full, call rejected")
+			return status.New(codes.ResourceExhausted, "handler queue is full, too many requests to handle").Err()
+		}
+		return <-done
+	}
+}
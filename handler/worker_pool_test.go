@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// saturate occupies all workers and queue slots of the pool by tasks which
+// are blocked until returned channel is closed.
+func saturate(t *testing.T, pool *WorkerPool, workerCount, queueSize int) (release chan struct{}) {
+	release = make(chan struct{})
+	started := make(chan struct{}, workerCount)
+	for i := 0; i < workerCount; i++ {
+		assert.True(t, pool.Submit(func() {
+			started <- struct{}{}
+			<-release
+		}))
+	}
+	for i := 0; i < workerCount; i++ {
+		<-started
+	}
+	for i := 0; i < queueSize; i++ {
+		assert.True(t, pool.Submit(func() { <-release }))
+	}
+	return release
+}
+
+func TestWorkerPoolRunsTasks(t *testing.T) {
+	pool := NewWorkerPool(2, 0)
+	defer pool.Close()
+	done := make(chan struct{})
+
+	assert.True(t, submitWhenIdle(pool, func() { close(done) }))
+
+	<-done
+}
+
+// submitWhenIdle retries submitting to the pool without queue until one of
+// the workers is ready to take task.
+func submitWhenIdle(pool *WorkerPool, task func()) bool {
+	for i := 0; i < 1000; i++ {
+		if pool.Submit(task) {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func TestWorkerPoolQueueIsFull(t *testing.T) {
+	pool := NewWorkerPool(2, 3)
+	defer pool.Close()
+	release := saturate(t, pool, 2, 3)
+	defer close(release)
+
+	assert.False(t, pool.Submit(func() {}))
+}
+
+func TestWorkerPoolQueuedTasksAreProcessed(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	defer pool.Close()
+	release := saturate(t, pool, 1, 0)
+	done := make(chan struct{})
+
+	assert.True(t, pool.Submit(func() { close(done) }))
+	assert.False(t, pool.Submit(func() {}))
+
+	close(release)
+	<-done
+	assert.True(t, submitWhenIdle(pool, func() {}))
+}
+
+func TestWorkerPoolClosed(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	pool.Close()
+
+	assert.False(t, pool.Submit(func() {}))
+}
+
+func TestGrpcWorkerPoolInterceptor(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	defer pool.Close()
+	interceptor := GrpcWorkerPoolInterceptor(pool)
+	handlerErr := errors.New("handler error")
+
+	err := interceptor(nil, newServerStreamMock(metadata.Pairs()), &grpc.StreamServerInfo{FullMethod: "/service/method"},
+		func(srv interface{}, stream grpc.ServerStream) error { return handlerErr })
+
+	assert.Equal(t, handlerErr, err)
+}
+
+func TestGrpcWorkerPoolInterceptorBackpressure(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	defer pool.Close()
+	release := saturate(t, pool, 1, 1)
+	defer close(release)
+	interceptor := GrpcWorkerPoolInterceptor(pool)
+
+	called, err := callInterceptor(interceptor, metadata.Pairs())
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.False(t, called)
+}
@@ -1,6 +1,7 @@
 package ipfsutils
 
 import (
+	"fmt"
 	"github.com/ipfs/go-ipfs-api"
 	"github.com/singnet/snet-daemon/config"
 	log "github.com/sirupsen/logrus"
@@ -8,26 +9,33 @@ import (
 )
 
 func GetIpfsFile(hash string) string {
+	jsondata, err := ReadIpfsFile(hash)
+	if err != nil {
+		log.WithError(err).WithField("hashFromMetaData", hash).Panic("error reading file from ipfs")
+	}
+	return jsondata
+}
+
+// ReadIpfsFile reads file with given hash from IPFS, unlike GetIpfsFile it
+// returns error instead of panicking.
+func ReadIpfsFile(hash string) (jsondata string, err error) {
 
 	log.WithField("hash", hash).Debug("Hash Used to retrieve from IPFS")
 
 	sh := GetIpfsShell()
 	cid, err := sh.Cat(hash)
 	if err != nil {
-		log.WithError(err).WithField("hashFromMetaData", hash).Panic("error executing the cat command in ipfs")
+		return "", fmt.Errorf("error executing the cat command in ipfs: %v", err)
 	}
+	defer cid.Close()
 
 	blob, err := ioutil.ReadAll(cid)
 	if err != nil {
-		log.WithError(err).WithField("hashFromMetaData", hash).Panic("error: in Reading the meta data file %s", err)
-
+		return "", fmt.Errorf("error in reading the meta data file: %v", err)
 	}
 	log.WithField("hash", hash).WithField("blob", string(blob)).Debug("Blob of IPFS file with hash")
 
-	jsondata := string(blob)
-
-	cid.Close()
-	return jsondata
+	return string(blob), nil
 }
 
 func GetIpfsShell() *shell.Shell {
@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"github.com/lestrrat-go/file-rotatelogs"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -28,6 +32,7 @@ const (
 	LogOutputFileRotationTimeInSecKey = "rotation_time_in_sec"
 	LogOutputFileMaxAgeInSecKey       = "max_age_in_sec"
 	LogOutputFileRotationCountKey     = "rotation_count"
+	LogOutputFileCreateDirsKey        = "create_dirs"
 )
 
 // InitLogger initializes logger using configuration provided by viper
@@ -117,10 +122,46 @@ func (formatter *timezoneFormatter) Format(entry *log.Entry) ([]byte, error) {
 	return formatter.delegate.Format(entry)
 }
 
+// stdout is a writer used by stdout output type.
+var stdout io.Writer = os.Stdout
+
+// newOutputByConfig returns writer of the output type configured. Type is
+// either single type name or list of types, "both" is a shortcut for file and
+// stdout; log entries are written to each of the listed outputs.
 func newOutputByConfig(config *viper.Viper) (io.Writer, error) {
+	var outputTypes []string
+	switch outputType := config.Get(LogOutputTypeKey).(type) {
+	case []interface{}, []string:
+		outputTypes = cast.ToStringSlice(outputType)
+	default:
+		if cast.ToString(outputType) == "both" {
+			outputTypes = []string{"file", "stdout"}
+		} else {
+			outputTypes = []string{cast.ToString(outputType)}
+		}
+	}
+	if len(outputTypes) == 0 {
+		return nil, fmt.Errorf("Output types list is empty")
+	}
+
+	var writers []io.Writer
+	for _, outputType := range outputTypes {
+		writer, err := newOutputByType(outputType, config)
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, writer)
+	}
+	if len(writers) == 1 {
+		return writers[0], nil
+	}
+	return io.MultiWriter(writers...), nil
+}
+
+func newOutputByType(outputType string, config *viper.Viper) (io.Writer, error) {
 	var err error
 
-	switch outputType := config.GetString(LogOutputTypeKey); outputType {
+	switch outputType {
 	case "file":
 
 		var location *time.Location
@@ -128,8 +169,13 @@ func newOutputByConfig(config *viper.Viper) (io.Writer, error) {
 			return nil, err
 		}
 
+		var filePattern = config.GetString(LogOutputFileFilePatternKey)
+		if err = checkLogDirectory(filePattern, config.GetBool(LogOutputFileCreateDirsKey)); err != nil {
+			return nil, err
+		}
+
 		var fileWriter io.Writer
-		fileWriter, err = rotatelogs.New(config.GetString(LogOutputFileFilePatternKey),
+		fileWriter, err = rotatelogs.New(filePattern,
 			rotatelogs.WithLocation(location),
 			rotatelogs.WithLinkName(config.GetString(LogOutputFileCurrentLinkKey)),
 			rotatelogs.WithRotationTime(config.GetDuration(LogOutputFileRotationTimeInSecKey)*time.Second),
@@ -142,9 +188,40 @@ func newOutputByConfig(config *viper.Viper) (io.Writer, error) {
 
 		return fileWriter, nil
 	case "stdout":
-		return os.Stdout, nil
+		return stdout, nil
 	default:
 		return nil, fmt.Errorf("Unexpected output type: %v", outputType)
 	}
 
 }
+
+// checkLogDirectory checks that directory of the log files exists and
+// writable, directory is created if createDirs is true. Directory which name
+// contains date/time pattern is not checked because it changes in time.
+func checkLogDirectory(filePattern string, createDirs bool) error {
+	var dir = filepath.Dir(filePattern)
+	if strings.Contains(dir, "%") {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		if !createDirs {
+			return fmt.Errorf("Log directory \"%v\" does not exist, create it or set create_dirs to true", dir)
+		}
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("Unable to create log directory \"%v\", error: %v", dir, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("Unable to access log directory \"%v\", error: %v", dir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("Log directory \"%v\" is not a directory", dir)
+	}
+
+	file, err := ioutil.TempFile(dir, ".snet-daemon-write-check")
+	if err != nil {
+		return fmt.Errorf("Log directory \"%v\" is not writable, error: %v", dir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
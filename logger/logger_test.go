@@ -10,6 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -232,6 +233,51 @@ func TestNewOutputStdout(t *testing.T) {
 	assert.Equal(t, os.Stdout, writer, "Unexpected writer type")
 }
 
+func TestNewOutputBoth(t *testing.T) {
+	testOutputReachesFileAndStdout(t, `"both"`)
+}
+
+func TestNewOutputList(t *testing.T) {
+	testOutputReachesFileAndStdout(t, `["file", "stdout"]`)
+}
+
+func testOutputReachesFileAndStdout(t *testing.T, outputType string) {
+	dir, err := ioutil.TempDir("", "snet-daemon-log-test")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	var stdoutBuffer = &bytes.Buffer{}
+	stdout = stdoutBuffer
+	defer func() { stdout = os.Stdout }()
+	var outputConfigJSON = `{
+        "type": ` + outputType + `,
+        "file_pattern": "` + filepath.Join(dir, "snet-daemon.log") + `",
+        "current_link": ""
+    }`
+	var outputConfig = newConfigFromString(outputConfigJSON, defaultOutputConfig)
+
+	writer, err := newOutputByConfig(outputConfig)
+	assert.Nil(t, err)
+	var logger = log.New()
+	logger.SetOutput(writer)
+	logger.Info("test message")
+
+	fileContent, err := ioutil.ReadFile(filepath.Join(dir, "snet-daemon.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(fileContent), "test message")
+	assert.Contains(t, stdoutBuffer.String(), "test message")
+}
+
+func TestNewOutputListIncorrectType(t *testing.T) {
+	var outputConfigJSON = `{
+        "type": ["stdout", "UNKNOWN"]
+    }`
+	var outputConfig = newConfigFromString(outputConfigJSON, defaultOutputConfig)
+
+	var _, err = newOutputByConfig(outputConfig)
+
+	assert.Equal(t, errors.New("Unexpected output type: UNKNOWN"), err)
+}
+
 func TestNewOutputIncorrectType(t *testing.T) {
 	var outputConfigJSON = `{
         "type": "UNKNOWN"
@@ -269,6 +315,70 @@ func TestNewIncorrectFileOutputFilePattern(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func newTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "snet-daemon-logger-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCheckLogDirectoryWritable(t *testing.T) {
+	var dir = newTempDir(t)
+	defer os.RemoveAll(dir)
+
+	var err = checkLogDirectory(filepath.Join(dir, "snet-daemon.%Y%m%d.log"), false)
+
+	assert.Nil(t, err)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 0, len(files))
+}
+
+func TestCheckLogDirectoryMissing(t *testing.T) {
+	var dir = newTempDir(t)
+	defer os.RemoveAll(dir)
+	var logDir = filepath.Join(dir, "logs")
+
+	var err = checkLogDirectory(filepath.Join(logDir, "snet-daemon.%Y%m%d.log"), false)
+
+	assert.Equal(t, fmt.Errorf("Log directory \"%v\" does not exist, create it or set create_dirs to true", logDir), err)
+}
+
+func TestCheckLogDirectoryMissingCreateDirs(t *testing.T) {
+	var dir = newTempDir(t)
+	defer os.RemoveAll(dir)
+	var logDir = filepath.Join(dir, "logs", "daemon")
+
+	var err = checkLogDirectory(filepath.Join(logDir, "snet-daemon.%Y%m%d.log"), true)
+
+	assert.Nil(t, err)
+	info, e := os.Stat(logDir)
+	assert.Nil(t, e)
+	assert.True(t, info.IsDir())
+}
+
+func TestCheckLogDirectoryIsFile(t *testing.T) {
+	var dir = newTempDir(t)
+	defer os.RemoveAll(dir)
+	var file = filepath.Join(dir, "file")
+	ioutil.WriteFile(file, []byte{}, 0644)
+
+	var err = checkLogDirectory(filepath.Join(file, "snet-daemon.%Y%m%d.log"), true)
+
+	assert.Equal(t, fmt.Errorf("Log directory \"%v\" is not a directory", file), err)
+}
+
+func TestNewOutputFileMissingDirectory(t *testing.T) {
+	var outputConfigJSON = `{
+        "file_pattern": "/tmp/snet-daemon-missing-dir/snet-daemon.%Y%m%d.log"
+    }`
+	var outputConfig = newConfigFromString(outputConfigJSON, defaultOutputConfig)
+
+	var _, err = newOutputByConfig(outputConfig)
+
+	assert.Equal(t, errors.New("Log directory \"/tmp/snet-daemon-missing-dir\" does not exist, create it or set create_dirs to true"), err)
+}
+
 func TestNewOutputDefault(t *testing.T) {
 	var outputConfig = viper.New()
 	config.SetDefaultFromConfig(outputConfig, defaultOutputConfig)
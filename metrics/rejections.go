@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"google.golang.org/grpc/status"
+)
+
+// Reasons of the call rejections which are used as a label of the
+// "rejections.<reason>" metric. Set of reasons is fixed to keep number of
+// metrics bounded, reasons which are not in the set are counted as
+// RejectionOther.
+const (
+	// RejectionUnderpaid means that payment doesn't cover price of the call.
+	RejectionUnderpaid = "underpaid"
+	// RejectionExpired means that payment channel is expired or near to be
+	// expired.
+	RejectionExpired = "expired"
+	// RejectionBadSignature means that payment signature is malformed or is
+	// not made by the channel signer.
+	RejectionBadSignature = "bad_signature"
+	// RejectionRateLimited means that daemon rate limit is reached.
+	RejectionRateLimited = "rate_limited"
+	// RejectionOther is any other reason.
+	RejectionOther = "other"
+)
+
+var rejectionReasons = map[string]bool{
+	RejectionUnderpaid:    true,
+	RejectionExpired:      true,
+	RejectionBadSignature: true,
+	RejectionRateLimited:  true,
+	RejectionOther:        true,
+}
+
+// RejectedCallError is returned to the gRPC server when daemon rejects the
+// call, it keeps status returned to the client and reason of the rejection.
+type RejectedCallError struct {
+	Status *status.Status
+	Reason string
+}
+
+// NewRejectedCallError returns error which is sent to the client as st and
+// is counted as rejection for the reason.
+func NewRejectedCallError(st *status.Status, reason string) *RejectedCallError {
+	return &RejectedCallError{Status: st, Reason: reason}
+}
+
+func (err *RejectedCallError) Error() string {
+	return err.Status.Err().Error()
+}
+
+// GRPCStatus returns status which is sent to the client.
+func (err *RejectedCallError) GRPCStatus() *status.Status {
+	return err.Status
+}
+
+// RejectionReason returns reason of the rejection if err is
+// RejectedCallError and empty string otherwise.
+func RejectionReason(err error) string {
+	rejected, ok := err.(*RejectedCallError)
+	if !ok {
+		return ""
+	}
+	if !rejectionReasons[rejected.Reason] {
+		return RejectionOther
+	}
+	return rejected.Reason
+}
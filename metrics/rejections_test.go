@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRejectedCallErrorStatus(t *testing.T) {
+	err := NewRejectedCallError(status.New(codes.ResourceExhausted, "too many requests"), RejectionRateLimited)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "rpc error: code = ResourceExhausted desc = too many requests", err.Error())
+}
+
+func TestRejectionReason(t *testing.T) {
+	st := status.New(codes.Unauthenticated, "payment is rejected")
+
+	assert.Equal(t, RejectionExpired, RejectionReason(NewRejectedCallError(st, RejectionExpired)))
+	assert.Equal(t, RejectionOther, RejectionReason(NewRejectedCallError(st, "unknown")), "unknown reason is not used as a label")
+	assert.Equal(t, "", RejectionReason(st.Err()))
+	assert.Equal(t, "", RejectionReason(errors.New("service error")))
+	assert.Equal(t, "", RejectionReason(nil))
+}
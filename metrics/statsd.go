@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	statsdQueueSize     = 4096
+	statsdFlushInterval = time.Second
+	// statsdMaxPacketSize keeps UDP packet within Ethernet MTU
+	statsdMaxPacketSize = 1432
+)
+
+// Recorder records daemon metrics.
+type Recorder interface {
+	// Count adds value to the counter.
+	Count(name string, value int64)
+}
+
+// StatsdClient sends metrics to the StatsD server over UDP. Metric lines are
+// batched into packets which are sent from the background goroutine, lines
+// which don't fit into the queue are dropped.
+type StatsdClient struct {
+	conn          net.Conn
+	prefix        string
+	flushInterval time.Duration
+	maxPacketSize int
+	queue         chan string
+	closeOnce     sync.Once
+	done          chan struct{}
+}
+
+// NewStatsdClient returns client which sends metrics to the endpoint in
+// "host:port" format, prefix is prepended to the names of the metrics.
+func NewStatsdClient(endpoint string, prefix string) (client *StatsdClient, err error) {
+	conn, err := net.Dial("udp", endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to StatsD endpoint %v: %v", endpoint, err)
+	}
+	client = &StatsdClient{
+		conn:          conn,
+		prefix:        prefix,
+		flushInterval: statsdFlushInterval,
+		maxPacketSize: statsdMaxPacketSize,
+		queue:         make(chan string, statsdQueueSize),
+		done:          make(chan struct{}),
+	}
+	go client.run()
+	return client, nil
+}
+
+// Count puts counter increment into the queue of metrics to send.
+func (client *StatsdClient) Count(name string, value int64) {
+	client.enqueue(fmt.Sprintf("%v%v:%v|c", client.prefix, name, value))
+}
+
+func (client *StatsdClient) enqueue(line string) {
+	select {
+	case client.queue <- line:
+	default:
+		log.WithField("line", line).Debug("StatsD queue is full, metric is dropped")
+	}
+}
+
+// Close sends metrics which are in the queue and stops the client.
+func (client *StatsdClient) Close() {
+	client.closeOnce.Do(func() {
+		close(client.queue)
+		<-client.done
+		client.conn.Close()
+	})
+}
+
+func (client *StatsdClient) run() {
+	defer close(client.done)
+
+	ticker := time.NewTicker(client.flushInterval)
+	defer ticker.Stop()
+
+	var packet bytes.Buffer
+	for {
+		select {
+		case line, ok := <-client.queue:
+			if !ok {
+				client.send(&packet)
+				return
+			}
+			if packet.Len() > 0 && packet.Len()+1+len(line) > client.maxPacketSize {
+				client.send(&packet)
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		case <-ticker.C:
+			client.send(&packet)
+		}
+	}
+}
+
+func (client *StatsdClient) send(packet *bytes.Buffer) {
+	if packet.Len() == 0 {
+		return
+	}
+	if _, err := client.conn.Write(packet.Bytes()); err != nil {
+		log.WithError(err).WithField("endpoint", client.conn.RemoteAddr()).Warn("Cannot send metrics to StatsD")
+	}
+	packet.Reset()
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startStatsdListener starts fake StatsD server and returns its address and
+// channel of received packets.
+func startStatsdListener(t *testing.T) (address string, packets chan string, stop func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets = make(chan string, 100)
+	go func() {
+		buffer := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			packets <- string(buffer[:n])
+		}
+	}()
+	return conn.LocalAddr().String(), packets, func() { conn.Close() }
+}
+
+func receivePacket(t *testing.T, packets chan string) string {
+	select {
+	case packet := <-packets:
+		return packet
+	case <-time.After(5 * time.Second):
+		t.Fatal("StatsD packet is not received")
+		return ""
+	}
+}
+
+func TestStatsdClientSendsCounters(t *testing.T) {
+	address, packets, stop := startStatsdListener(t)
+	defer stop()
+	client, err := NewStatsdClient(address, "snetd.")
+	assert.Nil(t, err)
+
+	client.Count("requests", 1)
+	client.Count("income", 12345)
+	client.Close()
+
+	assert.Equal(t, "snetd.requests:1|c\nsnetd.income:12345|c", receivePacket(t, packets))
+}
+
+func TestStatsdClientFlushesByInterval(t *testing.T) {
+	address, packets, stop := startStatsdListener(t)
+	defer stop()
+	client, err := NewStatsdClient(address, "")
+	assert.Nil(t, err)
+	defer client.Close()
+
+	client.Count("requests", 1)
+
+	assert.Equal(t, "requests:1|c", receivePacket(t, packets))
+}
+
+func TestStatsdClientBatchesByPacketSize(t *testing.T) {
+	address, packets, stop := startStatsdListener(t)
+	defer stop()
+	client, err := NewStatsdClient(address, "snetd.")
+	assert.Nil(t, err)
+	client.maxPacketSize = 64
+
+	for i := 0; i < 10; i++ {
+		client.Count("requests", 1)
+	}
+	client.Close()
+
+	var lines []string
+	for len(lines) < 10 {
+		packet := receivePacket(t, packets)
+		assert.True(t, len(packet) <= 64, "packet is too large: %v", len(packet))
+		lines = append(lines, strings.Split(packet, "\n")...)
+	}
+	assert.Equal(t, 10, len(lines))
+	for _, line := range lines {
+		assert.Equal(t, "snetd.requests:1|c", line)
+	}
+}
+
+func TestNewStatsdClientIncorrectEndpoint(t *testing.T) {
+	_, err := NewStatsdClient("incorrect endpoint", "")
+
+	assert.NotNil(t, err)
+}
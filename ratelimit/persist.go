@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// DefaultSaveInterval is an interval between saving rate limiter state to
+// the storage.
+const DefaultSaveInterval = 10 * time.Second
+
+// LimiterState is a state of the rate limiter which is persisted to restore
+// limits after restart.
+type LimiterState struct {
+	// Tokens is a number of tokens available at SavedAt time
+	Tokens float64 `json:"tokens"`
+	// SavedAt is a time when state is saved
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// LimiterStorage is a storage to keep rate limiter state.
+type LimiterStorage interface {
+	Get(key string) (value string, ok bool, err error)
+	Put(key string, value string) (err error)
+}
+
+// isPersistable returns false if limiter doesn't limit rate, so nothing is
+// to be persisted.
+func isPersistable(limiter *rate.Limiter) bool {
+	return limiter.Limit() != rate.Inf && limiter.Burst() != math.MaxInt64
+}
+
+// GetLimiterState returns current state of the limiter.
+func GetLimiterState(limiter *rate.Limiter, now time.Time) *LimiterState {
+	// reservation of the whole burst shows how long it takes to refill the
+	// bucket, so number of available tokens can be calculated
+	burst := limiter.Burst()
+	reservation := limiter.ReserveN(now, burst)
+	delay := reservation.DelayFrom(now)
+	reservation.CancelAt(now)
+
+	return &LimiterState{
+		Tokens:  float64(burst) - delay.Seconds()*float64(limiter.Limit()),
+		SavedAt: now,
+	}
+}
+
+// RestoreLimiterState takes tokens from the newly created limiter to make
+// its state equal to the saved one taking into account tokens refilled
+// after the state was saved.
+func RestoreLimiterState(limiter *rate.Limiter, state *LimiterState, now time.Time) {
+	burst := limiter.Burst()
+	tokens := state.Tokens + now.Sub(state.SavedAt).Seconds()*float64(limiter.Limit())
+	if tokens >= float64(burst) {
+		return
+	}
+
+	taken := burst - int(math.Floor(tokens))
+	if taken > burst {
+		taken = burst
+	}
+	limiter.ReserveN(now, taken)
+}
+
+// LimiterPersister periodically saves state of the rate limiter to the
+// storage and restores it on startup, so limits survive daemon restart.
+type LimiterPersister struct {
+	limiter *ReloadableLimiter
+	storage LimiterStorage
+	key     string
+	now     func() time.Time
+
+	stopOnce sync.Once
+	started  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewLimiterPersister returns persister which keeps state of the limiter in
+// the storage by the key.
+func NewLimiterPersister(limiter *ReloadableLimiter, storage LimiterStorage, key string) *LimiterPersister {
+	return &LimiterPersister{
+		limiter: limiter,
+		storage: storage,
+		key:     key,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Restore reads limiter state from storage and applies it to the limiter.
+func (persister *LimiterPersister) Restore() (err error) {
+	limiter := persister.limiter.Limiter()
+	if !isPersistable(limiter) {
+		return nil
+	}
+
+	value, ok, err := persister.storage.Get(persister.key)
+	if err != nil || !ok {
+		return
+	}
+
+	state := &LimiterState{}
+	err = json.Unmarshal([]byte(value), state)
+	if err != nil {
+		return
+	}
+
+	RestoreLimiterState(limiter, state, persister.now())
+	log.WithField("state", state).Info("Rate limiter state restored")
+	return nil
+}
+
+// Save writes current limiter state to the storage.
+func (persister *LimiterPersister) Save() (err error) {
+	limiter := persister.limiter.Limiter()
+	if !isPersistable(limiter) {
+		return nil
+	}
+
+	value, err := json.Marshal(GetLimiterState(limiter, persister.now()))
+	if err != nil {
+		return
+	}
+
+	return persister.storage.Put(persister.key, string(value))
+}
+
+// Start saves limiter state each interval until Stop is called.
+func (persister *LimiterPersister) Start(interval time.Duration) {
+	persister.started = true
+	go func() {
+		defer close(persister.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := persister.Save(); err != nil {
+					log.WithError(err).Warn("Cannot save rate limiter state")
+				}
+			case <-persister.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops periodic saving started by Start and saves the latest state.
+func (persister *LimiterPersister) Stop() {
+	persister.stopOnce.Do(func() {
+		close(persister.stop)
+		if persister.started {
+			<-persister.done
+		}
+		if err := persister.Save(); err != nil {
+			log.WithError(err).Warn("Cannot save rate limiter state")
+		}
+	})
+}
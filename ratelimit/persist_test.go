@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+type limiterStorageMock struct {
+	data map[string]string
+	err  error
+}
+
+func (storage *limiterStorageMock) Get(key string) (value string, ok bool, err error) {
+	if storage.err != nil {
+		return "", false, storage.err
+	}
+	value, ok = storage.data[key]
+	return
+}
+
+func (storage *limiterStorageMock) Put(key string, value string) (err error) {
+	if storage.err != nil {
+		return storage.err
+	}
+	storage.data[key] = value
+	return nil
+}
+
+// oneTokenPerSecond returns limiter which gets one token per second and has
+// burst of 10 tokens
+func oneTokenPerSecond() *rate.Limiter {
+	return rate.NewLimiter(rate.Every(time.Second), 10)
+}
+
+func exhaust(limiter *rate.Limiter, now time.Time) {
+	for limiter.AllowN(now, 1) {
+	}
+}
+
+func TestGetLimiterState(t *testing.T) {
+	now := time.Now()
+	limiter := oneTokenPerSecond()
+	limiter.AllowN(now, 7)
+
+	state := GetLimiterState(limiter, now)
+
+	assert.InDelta(t, 3, state.Tokens, 0.001)
+	assert.True(t, limiter.AllowN(now, 3), "getting state should not consume tokens")
+}
+
+func TestRestartContinuesThrottling(t *testing.T) {
+	now := time.Now()
+	storage := &limiterStorageMock{data: make(map[string]string)}
+	limiter := oneTokenPerSecond()
+	persister := NewLimiterPersister(NewReloadableLimiter(limiter), storage, "limiter")
+	persister.now = func() time.Time { return now }
+	exhaust(limiter, now)
+	assert.Nil(t, persister.Save())
+
+	restarted := oneTokenPerSecond()
+	restartedPersister := NewLimiterPersister(NewReloadableLimiter(restarted), storage, "limiter")
+	restartedPersister.now = func() time.Time { return now.Add(2 * time.Second) }
+	assert.Nil(t, restartedPersister.Restore())
+
+	later := now.Add(2 * time.Second)
+	assert.True(t, restarted.AllowN(later, 2), "tokens refilled during restart should be available")
+	assert.False(t, restarted.AllowN(later, 1), "sender should still be throttled after restart")
+}
+
+func TestRestoreAfterLongDowntimeGivesFullBurst(t *testing.T) {
+	now := time.Now()
+	limiter := oneTokenPerSecond()
+
+	RestoreLimiterState(limiter, &LimiterState{Tokens: 0, SavedAt: now.Add(-time.Hour)}, now)
+
+	assert.True(t, limiter.AllowN(now, 10))
+}
+
+func TestRestoreNoSavedState(t *testing.T) {
+	storage := &limiterStorageMock{data: make(map[string]string)}
+	limiter := oneTokenPerSecond()
+
+	assert.Nil(t, NewLimiterPersister(NewReloadableLimiter(limiter), storage, "limiter").Restore())
+	assert.True(t, limiter.AllowN(time.Now(), 10))
+}
+
+func TestRestoreStorageError(t *testing.T) {
+	storage := &limiterStorageMock{err: errors.New("storage error")}
+
+	err := NewLimiterPersister(NewReloadableLimiter(oneTokenPerSecond()), storage, "limiter").Restore()
+
+	assert.Equal(t, errors.New("storage error"), err)
+}
+
+func TestUnlimitedLimiterIsNotPersisted(t *testing.T) {
+	storage := &limiterStorageMock{data: make(map[string]string)}
+	persister := NewLimiterPersister(NewReloadableLimiter(rate.NewLimiter(rate.Inf, 1)), storage, "limiter")
+
+	assert.Nil(t, persister.Save())
+	assert.Equal(t, 0, len(storage.data))
+}
+
+func TestStopSavesState(t *testing.T) {
+	storage := &limiterStorageMock{data: make(map[string]string)}
+	persister := NewLimiterPersister(NewReloadableLimiter(oneTokenPerSecond()), storage, "limiter")
+	persister.Start(time.Hour)
+
+	persister.Stop()
+
+	_, ok := storage.data["limiter"]
+	assert.True(t, ok)
+}
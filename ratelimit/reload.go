@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ReloadableLimiter is a rate limiter which rate and burst can be changed at
+// runtime. Tokens available before the change are kept, so changing the
+// settings doesn't refill the bucket.
+type ReloadableLimiter struct {
+	mutex   sync.RWMutex
+	limiter *rate.Limiter
+	// idle is nil if idle burst is disabled
+	idle *idleBurst
+	now  func() time.Time
+}
+
+// idleBurst is an allowance of calls above the rate limit which is given to
+// the clients after the daemon has no calls for the threshold time. Unlike
+// tokens of the limiter it is not replenished under continuous load.
+type idleBurst struct {
+	size      int
+	threshold time.Duration
+	tokens    int
+	lastCall  time.Time
+}
+
+// NewReloadableLimiter returns limiter which initially uses given limiter.
+func NewReloadableLimiter(limiter *rate.Limiter) *ReloadableLimiter {
+	return &ReloadableLimiter{limiter: limiter, now: time.Now}
+}
+
+// Limiter returns current underlying limiter.
+func (reloadable *ReloadableLimiter) Limiter() *rate.Limiter {
+	reloadable.mutex.RLock()
+	defer reloadable.mutex.RUnlock()
+
+	return reloadable.limiter
+}
+
+// Allow reports whether an event may happen now. Event which is not allowed
+// by the underlying limiter takes one of the idle burst tokens if any.
+func (reloadable *ReloadableLimiter) Allow() bool {
+	now := reloadable.now()
+	allowed := reloadable.Limiter().AllowN(now, 1)
+
+	reloadable.mutex.Lock()
+	defer reloadable.mutex.Unlock()
+
+	idle := reloadable.idle
+	if idle == nil {
+		return allowed
+	}
+	if now.Sub(idle.lastCall) >= idle.threshold {
+		idle.tokens = idle.size
+	}
+	idle.lastCall = now
+	if allowed {
+		return true
+	}
+	if idle.tokens > 0 {
+		idle.tokens--
+		return true
+	}
+	return false
+}
+
+// SetIdleBurst sets number of calls which are allowed above the limit after
+// no calls were made for threshold time, zero size disables idle burst. When
+// idle burst is enabled first time its tokens are available immediately,
+// otherwise tokens left are kept as on Update.
+func (reloadable *ReloadableLimiter) SetIdleBurst(size int, threshold time.Duration) {
+	reloadable.mutex.Lock()
+	defer reloadable.mutex.Unlock()
+
+	if size <= 0 {
+		reloadable.idle = nil
+		return
+	}
+	idle := &idleBurst{size: size, threshold: threshold, tokens: size}
+	if previous := reloadable.idle; previous != nil {
+		idle.lastCall = previous.lastCall
+		if previous.tokens < size {
+			idle.tokens = previous.tokens
+		}
+	}
+	reloadable.idle = idle
+}
+
+// Burst returns maximum burst size of the current limiter.
+func (reloadable *ReloadableLimiter) Burst() int {
+	return reloadable.Limiter().Burst()
+}
+
+// Update replaces current limiter by the new one moving tokens which are
+// available at now time to the new limiter. Tokens above burst of the new
+// limiter are dropped. If current limiter is unlimited then new limiter
+// starts with the full bucket.
+func (reloadable *ReloadableLimiter) Update(limiter *rate.Limiter, now time.Time) {
+	reloadable.mutex.Lock()
+	defer reloadable.mutex.Unlock()
+
+	if isPersistable(reloadable.limiter) && isPersistable(limiter) {
+		RestoreLimiterState(limiter, GetLimiterState(reloadable.limiter, now), now)
+	}
+	reloadable.limiter = limiter
+}
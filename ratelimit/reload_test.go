@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestReloadKeepsAvailableTokens(t *testing.T) {
+	now := time.Now()
+	limiter := NewReloadableLimiter(oneTokenPerSecond())
+	limiter.Limiter().AllowN(now, 7)
+
+	limiter.Update(rate.NewLimiter(rate.Every(time.Second/2), 20), now)
+
+	assert.Equal(t, 20, limiter.Burst())
+	assert.True(t, limiter.Limiter().AllowN(now, 3))
+	assert.False(t, limiter.Limiter().AllowN(now, 1), "reload should not refill the bucket")
+	assert.True(t, limiter.Limiter().AllowN(now.Add(time.Second), 2), "tokens should be refilled with new rate")
+}
+
+func TestReloadNoBurstBeyondNewCapacity(t *testing.T) {
+	now := time.Now()
+	limiter := NewReloadableLimiter(oneTokenPerSecond())
+
+	limiter.Update(rate.NewLimiter(rate.Every(time.Second), 5), now)
+
+	assert.True(t, limiter.Limiter().AllowN(now, 5))
+	assert.False(t, limiter.Limiter().AllowN(now, 1))
+}
+
+func TestReloadExhaustedLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewReloadableLimiter(oneTokenPerSecond())
+	exhaust(limiter.Limiter(), now)
+
+	limiter.Update(rate.NewLimiter(rate.Every(time.Second), 100), now)
+
+	assert.False(t, limiter.Limiter().AllowN(now, 1))
+}
+
+func TestReloadFromUnlimitedGivesFullBurst(t *testing.T) {
+	now := time.Now()
+	limiter := NewReloadableLimiter(rate.NewLimiter(rate.Inf, math.MaxInt64))
+
+	limiter.Update(oneTokenPerSecond(), now)
+
+	assert.True(t, limiter.Limiter().AllowN(now, 10))
+	assert.False(t, limiter.Limiter().AllowN(now, 1))
+}
+
+// idleBurstLimiter returns limiter which allows one call per second with
+// burst of 2 and idle burst of 3 after a minute without calls.
+func idleBurstLimiter(now *time.Time) *ReloadableLimiter {
+	limiter := NewReloadableLimiter(rate.NewLimiter(rate.Every(time.Second), 2))
+	limiter.now = func() time.Time { return *now }
+	limiter.SetIdleBurst(3, time.Minute)
+	return limiter
+}
+
+func allowed(limiter *ReloadableLimiter, calls int) (count int) {
+	for i := 0; i < calls; i++ {
+		if limiter.Allow() {
+			count++
+		}
+	}
+	return
+}
+
+func TestIdleBurstAfterStart(t *testing.T) {
+	now := time.Now()
+	limiter := idleBurstLimiter(&now)
+
+	assert.Equal(t, 5, allowed(limiter, 10), "burst and idle burst are allowed")
+}
+
+func TestIdleBurstAfterIdle(t *testing.T) {
+	now := time.Now()
+	limiter := idleBurstLimiter(&now)
+	allowed(limiter, 10)
+
+	now = now.Add(time.Minute)
+
+	assert.Equal(t, 5, allowed(limiter, 10), "idle burst is replenished after threshold")
+}
+
+func TestIdleBurstUnderContinuousLoad(t *testing.T) {
+	now := time.Now()
+	limiter := idleBurstLimiter(&now)
+	allowed(limiter, 10)
+
+	for i := 0; i < 120; i++ {
+		now = now.Add(time.Second)
+		assert.Equal(t, 1, allowed(limiter, 10), "only rate limit is allowed at %v second", i)
+	}
+}
+
+func TestIdleBurstNotReplenishedBeforeThreshold(t *testing.T) {
+	now := time.Now()
+	limiter := idleBurstLimiter(&now)
+	allowed(limiter, 10)
+
+	now = now.Add(59 * time.Second)
+
+	assert.Equal(t, 2, allowed(limiter, 10), "only regular burst is refilled")
+}
+
+func TestIdleBurstDisabled(t *testing.T) {
+	now := time.Now()
+	limiter := idleBurstLimiter(&now)
+
+	limiter.SetIdleBurst(0, time.Minute)
+
+	assert.Equal(t, 2, allowed(limiter, 10))
+}
+
+func TestIdleBurstKeptOnReload(t *testing.T) {
+	now := time.Now()
+	limiter := idleBurstLimiter(&now)
+	allowed(limiter, 10)
+
+	limiter.SetIdleBurst(5, time.Minute)
+
+	assert.Equal(t, 0, allowed(limiter, 10), "reload doesn't replenish idle burst")
+	now = now.Add(time.Minute)
+	assert.Equal(t, 7, allowed(limiter, 10))
+}
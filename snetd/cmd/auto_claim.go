@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+const (
+	autoClaimCheckInterval = time.Minute
+	autoClaimTimeout       = time.Minute
+)
+
+// autoClaimer periodically claims funds from the channels which have
+// payments unclaimed for longer than auto_claim_max_channel_age.
+type autoClaimer struct {
+	channelService escrow.PaymentChannelService
+	claim          func(channelID *big.Int) error
+	maxAge         time.Duration
+	minAmount      *big.Int
+	now            func() time.Time
+	// concurrency is a number of channels which are claimed at once
+	concurrency int
+	// maintenance returns true while daemon is in maintenance mode, channels
+	// are not claimed during maintenance
+	maintenance func() bool
+
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// newAutoClaimer returns auto claimer configured by
+// auto_claim_max_channel_age and auto_claim_min_amount or nil if auto claim
+// is disabled or daemon cannot claim funds. Daemon in replica_mode never
+// claims funds, channels are claimed by the primary daemon.
+func newAutoClaimer(components *Components) *autoClaimer {
+	maxAge := config.GetDuration(config.AutoClaimMaxChannelAgeKey)
+	if maxAge <= 0 || config.GetBool(config.ReplicaModeKey) {
+		return nil
+	}
+	processor := components.Blockchain()
+	if !processor.Enabled() || !processor.HasIdentity() {
+		log.Warn("Auto claim is disabled as blockchain is disabled or neither private key nor HD wallet is specified")
+		return nil
+	}
+	minAmount, err := config.GetCheckedBigInt(config.AutoClaimMinAmountKey)
+	if err != nil {
+		log.WithError(err).Panic("error reading auto_claim_min_amount")
+	}
+
+	notifier := components.ClaimWebhookNotifier()
+	return &autoClaimer{
+		channelService: components.PaymentChannelService(),
+		claim: func(channelID *big.Int) error {
+			command := &claimCommand{
+				channelService: components.PaymentChannelService(),
+				blockchain:     processor,
+				notifier:       notifier,
+				revertAction:   config.GetClaimRevertAction,
+				channelId:      channelID,
+				timeout:        autoClaimTimeout,
+			}
+			return command.claimChannel()
+		},
+		maxAge:      maxAge,
+		minAmount:   minAmount,
+		now:         time.Now,
+		concurrency: config.GetInt(config.MaxConcurrentClaimsKey),
+		maintenance: func() bool {
+			_, ok := components.MaintenanceSchedule().Active()
+			return ok
+		},
+	}
+}
+
+func (claimer *autoClaimer) start() {
+	claimer.stopChan = make(chan struct{})
+	claimer.done = make(chan struct{})
+	go func() {
+		defer close(claimer.done)
+
+		ticker := time.NewTicker(autoClaimCheckInterval)
+		defer ticker.Stop()
+		for {
+			claimer.claimOldChannels()
+			select {
+			case <-ticker.C:
+			case <-claimer.stopChan:
+				return
+			}
+		}
+	}()
+	log.WithField("maxChannelAge", claimer.maxAge).WithField("minAmount", claimer.minAmount).Info("Auto claim is started")
+}
+
+func (claimer *autoClaimer) stop() {
+	close(claimer.stopChan)
+	<-claimer.done
+}
+
+// claimOldChannels claims channels selected by escrow.ChannelsToAutoClaim,
+// up to max_concurrent_claims channels are claimed at once. Failed claim is
+// logged and can be finished by "snetd claim --payment-id". Nothing is
+// claimed during maintenance, channels are claimed by the next check after
+// the window ends.
+func (claimer *autoClaimer) claimOldChannels() {
+	if claimer.maintenance != nil && claimer.maintenance() {
+		log.Debug("Auto claim is paused during maintenance")
+		return
+	}
+
+	channels, err := claimer.channelService.ListChannels()
+	if err != nil {
+		log.WithError(err).Error("Cannot list channels to auto claim")
+		return
+	}
+
+	concurrency := claimer.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, channel := range escrow.ChannelsToAutoClaim(channels, claimer.now(), claimer.maxAge, claimer.minAmount) {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(channel *escrow.PaymentChannelData) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			claimer.claimChannel(channel)
+		}(channel)
+	}
+	wg.Wait()
+}
+
+func (claimer *autoClaimer) claimChannel(channel *escrow.PaymentChannelData) {
+	log := log.WithField("channelId", channel.ChannelID).WithField("amount", channel.AuthorizedAmount).WithField("unclaimedSince", channel.UnclaimedSince)
+	if err := claimer.claim(channel.ChannelID); err != nil {
+		log.WithError(err).Error("Auto claim failed, see 'snetd list claims' to finish claims in progress")
+		return
+	}
+	log.Info("Channel is claimed automatically")
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+type channelListMock struct {
+	escrow.PaymentChannelService
+	channels []*escrow.PaymentChannelData
+	err      error
+}
+
+func (service *channelListMock) ListChannels() ([]*escrow.PaymentChannelData, error) {
+	return service.channels, service.err
+}
+
+func newTestAutoClaimer(service escrow.PaymentChannelService, now time.Time) (claimer *autoClaimer, claimed *[]int64) {
+	claimed = &[]int64{}
+	claimer = &autoClaimer{
+		channelService: service,
+		claim: func(channelID *big.Int) error {
+			*claimed = append(*claimed, channelID.Int64())
+			if channelID.Int64() == 3 {
+				return errors.New("claim failed")
+			}
+			return nil
+		},
+		maxAge:    24 * time.Hour,
+		minAmount: big.NewInt(10),
+		now:       func() time.Time { return now },
+	}
+	return
+}
+
+func TestAutoClaimerClaimsOldChannels(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	channel := func(id int64, amount int64, age time.Duration) *escrow.PaymentChannelData {
+		return &escrow.PaymentChannelData{ChannelID: big.NewInt(id), AuthorizedAmount: big.NewInt(amount), UnclaimedSince: now.Add(-age)}
+	}
+	service := &channelListMock{channels: []*escrow.PaymentChannelData{
+		channel(1, 100, time.Hour),
+		channel(2, 100, 25*time.Hour),
+		channel(3, 100, 72*time.Hour),
+		channel(4, 5, 72*time.Hour),
+		channel(5, 10, 24*time.Hour),
+	}}
+	claimer, claimed := newTestAutoClaimer(service, now)
+
+	claimer.claimOldChannels()
+
+	assert.Equal(t, []int64{2, 3, 5}, *claimed)
+}
+
+func TestAutoClaimerPausedDuringMaintenance(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	service := &channelListMock{channels: []*escrow.PaymentChannelData{
+		{ChannelID: big.NewInt(2), AuthorizedAmount: big.NewInt(100), UnclaimedSince: now.Add(-25 * time.Hour)},
+	}}
+	claimer, claimed := newTestAutoClaimer(service, now)
+	maintenance := true
+	claimer.maintenance = func() bool { return maintenance }
+
+	claimer.claimOldChannels()
+	assert.Equal(t, []int64{}, *claimed)
+
+	maintenance = false
+	claimer.claimOldChannels()
+	assert.Equal(t, []int64{2}, *claimed)
+}
+
+func TestAutoClaimerListChannelsError(t *testing.T) {
+	claimer, claimed := newTestAutoClaimer(&channelListMock{err: errors.New("storage error")}, time.Now())
+
+	claimer.claimOldChannels()
+
+	assert.Equal(t, []int64{}, *claimed)
+}
+
+func TestAutoClaimerStartStop(t *testing.T) {
+	claimer, _ := newTestAutoClaimer(&channelListMock{}, time.Now())
+
+	claimer.start()
+	claimer.stop()
+}
+
+func TestAutoClaimerBoundsConcurrentClaims(t *testing.T) {
+	now := time.Unix(1546300800, 0)
+	var channels []*escrow.PaymentChannelData
+	for id := int64(1); id <= 20; id++ {
+		channels = append(channels, &escrow.PaymentChannelData{ChannelID: big.NewInt(id), AuthorizedAmount: big.NewInt(100), UnclaimedSince: now.Add(-48 * time.Hour)})
+	}
+	var lock sync.Mutex
+	inProgress, maxInProgress := 0, 0
+	claimed := map[int64]bool{}
+	claimer := &autoClaimer{
+		channelService: &channelListMock{channels: channels},
+		claim: func(channelID *big.Int) error {
+			lock.Lock()
+			inProgress++
+			if inProgress > maxInProgress {
+				maxInProgress = inProgress
+			}
+			claimed[channelID.Int64()] = true
+			lock.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			lock.Lock()
+			inProgress--
+			lock.Unlock()
+			return nil
+		},
+		maxAge:      24 * time.Hour,
+		minAmount:   big.NewInt(10),
+		now:         func() time.Time { return now },
+		concurrency: 4,
+	}
+
+	claimer.claimOldChannels()
+
+	assert.Equal(t, 20, len(claimed))
+	assert.True(t, maxInProgress > 1, "channels are claimed concurrently")
+	assert.True(t, maxInProgress <= 4, "max claims in progress: %v", maxInProgress)
+}
+
+func TestAutoClaimerDisabledInReplicaMode(t *testing.T) {
+	defer config.WithDefaultConfig()()
+	config.Vip().Set(config.AutoClaimMaxChannelAgeKey, "24h")
+	config.Vip().Set(config.ReplicaModeKey, true)
+
+	assert.Nil(t, newAutoClaimer(&Components{}))
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+// channelEventWatcher periodically reads ChannelOpen events of the channels
+// opened to the daemon and puts the channels into the payment channel
+// storage. Each poll scans the last dedupBlocks blocks again to pick up
+// events which are moved to other blocks by chain reorganization, duplicates
+// are skipped by the processor.
+type channelEventWatcher struct {
+	processor    *escrow.ChannelOpenEventProcessor
+	currentBlock func(ctx context.Context) (*big.Int, error)
+	events       func(ctx context.Context, fromBlock, toBlock uint64) ([]*blockchain.ChannelOpenEvent, error)
+	dedupBlocks  uint64
+	interval     time.Duration
+	// lastBlock is the last scanned block, zero before the first scan
+	lastBlock uint64
+
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// newChannelEventWatcher returns watcher configured by
+// channel_events_poll_interval and channel_event_dedup_blocks or nil if it is
+// disabled or daemon runs in replica_mode, in the latter case channels are
+// stored by the primary daemon.
+func newChannelEventWatcher(components *Components) *channelEventWatcher {
+	interval := config.GetDuration(config.ChannelEventsPollIntervalKey)
+	if interval <= 0 || config.GetBool(config.ReplicaModeKey) {
+		return nil
+	}
+	processor := components.Blockchain()
+	if !processor.Enabled() {
+		log.Warn("Channel events are not watched as blockchain is disabled")
+		return nil
+	}
+	dedupBlocks, err := config.GetUint64(config.ChannelEventDedupBlocksKey)
+	if err != nil {
+		log.WithError(err).Panic("error reading channel_event_dedup_blocks")
+	}
+
+	metadata := components.allServicesMetaData()
+	return &channelEventWatcher{
+		processor:    escrow.NewChannelOpenEventProcessor(escrow.NewPaymentChannelStorage(components.AtomicStorage()), dedupBlocks),
+		currentBlock: processor.CurrentBlock,
+		events: func(ctx context.Context, fromBlock, toBlock uint64) (events []*blockchain.ChannelOpenEvent, err error) {
+			// services can share the recipient, events are read once for it
+			read := make(map[escrow.ChannelRecipient]bool)
+			for _, service := range metadata {
+				recipient := escrow.ChannelRecipient{GroupID: service.GetDaemonGroupID(), PaymentAddress: service.GetPaymentAddress()}
+				if read[recipient] {
+					continue
+				}
+				read[recipient] = true
+				recipientEvents, err := processor.ChannelOpenEvents(ctx, fromBlock, toBlock, recipient.PaymentAddress, recipient.GroupID)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, recipientEvents...)
+			}
+			return events, nil
+		},
+		dedupBlocks: dedupBlocks,
+		interval:    interval,
+	}
+}
+
+func (watcher *channelEventWatcher) start() {
+	watcher.stopChan = make(chan struct{})
+	watcher.done = make(chan struct{})
+	go func() {
+		defer close(watcher.done)
+
+		ticker := time.NewTicker(watcher.interval)
+		defer ticker.Stop()
+		for {
+			if err := watcher.poll(); err != nil {
+				log.WithError(err).Warn("Cannot process ChannelOpen events")
+			}
+			select {
+			case <-ticker.C:
+			case <-watcher.stopChan:
+				return
+			}
+		}
+	}()
+	log.WithField("pollInterval", watcher.interval).WithField("dedupBlocks", watcher.dedupBlocks).Info("Watching ChannelOpen events")
+}
+
+func (watcher *channelEventWatcher) stop() {
+	close(watcher.stopChan)
+	<-watcher.done
+}
+
+// poll processes events from the block after the last scanned one minus the
+// dedup window up to the current block. The first poll scans the dedup
+// window only, channels opened earlier are read from blockchain when the
+// first payment arrives.
+func (watcher *channelEventWatcher) poll() error {
+	ctx := context.Background()
+	current, err := watcher.currentBlock(ctx)
+	if err != nil {
+		return err
+	}
+	toBlock := current.Uint64()
+
+	fromBlock := watcher.lastBlock + 1
+	if watcher.lastBlock == 0 {
+		fromBlock = toBlock
+	}
+	if fromBlock > watcher.dedupBlocks {
+		fromBlock -= watcher.dedupBlocks
+	} else {
+		fromBlock = 0
+	}
+	if fromBlock > toBlock {
+		return nil
+	}
+
+	events, err := watcher.events(ctx, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		applied, err := watcher.processor.Process(event, toBlock)
+		if err != nil {
+			return err
+		}
+		if applied {
+			log.WithField("channelId", event.ChannelID).WithField("sender", event.Sender).WithField("amount", event.Amount).Info("Channel is opened")
+		}
+	}
+	watcher.lastBlock = toBlock
+	return nil
+}
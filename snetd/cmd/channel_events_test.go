@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+type scannedRange struct {
+	from, to uint64
+}
+
+func newTestChannelEventWatcher(storage *escrow.PaymentChannelStorage, current *int64, events []*blockchain.ChannelOpenEvent) (watcher *channelEventWatcher, scanned *[]scannedRange) {
+	scanned = &[]scannedRange{}
+	watcher = &channelEventWatcher{
+		processor: escrow.NewChannelOpenEventProcessor(storage, 10),
+		currentBlock: func(context.Context) (*big.Int, error) {
+			return big.NewInt(*current), nil
+		},
+		events: func(ctx context.Context, fromBlock, toBlock uint64) ([]*blockchain.ChannelOpenEvent, error) {
+			*scanned = append(*scanned, scannedRange{fromBlock, toBlock})
+			return events, nil
+		},
+		dedupBlocks: 10,
+	}
+	return
+}
+
+func TestChannelEventWatcherRescansDedupWindow(t *testing.T) {
+	storage := escrow.NewPaymentChannelStorage(escrow.NewMemStorage())
+	current := int64(100)
+	event := &blockchain.ChannelOpenEvent{ChannelID: big.NewInt(42), Nonce: big.NewInt(0),
+		Amount: big.NewInt(100), Expiration: big.NewInt(1000), BlockNumber: 95}
+	watcher, scanned := newTestChannelEventWatcher(storage, &current, []*blockchain.ChannelOpenEvent{event})
+
+	assert.Nil(t, watcher.poll())
+	channel, _, _ := storage.Get(&escrow.PaymentChannelKey{ID: big.NewInt(42)})
+	channel.AuthorizedAmount = big.NewInt(30)
+	assert.Nil(t, storage.Put(&escrow.PaymentChannelKey{ID: big.NewInt(42)}, channel))
+	current = 103
+	assert.Nil(t, watcher.poll())
+
+	assert.Equal(t, []scannedRange{{90, 100}, {91, 103}}, *scanned)
+	channel, _, _ = storage.Get(&escrow.PaymentChannelKey{ID: big.NewInt(42)})
+	assert.Equal(t, big.NewInt(30), channel.AuthorizedAmount, "event delivered again is not applied")
+}
+
+func TestChannelEventWatcherRetriesFailedScan(t *testing.T) {
+	current := int64(100)
+	watcher, _ := newTestChannelEventWatcher(escrow.NewPaymentChannelStorage(escrow.NewMemStorage()), &current, nil)
+	assert.Nil(t, watcher.poll())
+	watcher.events = func(ctx context.Context, fromBlock, toBlock uint64) ([]*blockchain.ChannelOpenEvent, error) {
+		return nil, errors.New("node is unavailable")
+	}
+	current = 120
+
+	assert.Equal(t, errors.New("node is unavailable"), watcher.poll())
+	assert.Equal(t, uint64(100), watcher.lastBlock)
+}
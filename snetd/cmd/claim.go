@@ -5,10 +5,13 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
 
 	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
 	"github.com/singnet/snet-daemon/escrow"
+	log "github.com/sirupsen/logrus"
 )
 
 var ClaimCmd = &cobra.Command{
@@ -25,14 +28,29 @@ var ClaimCmd = &cobra.Command{
 	},
 }
 
+// claimBlockchain sends channel claim transactions, it is implemented by
+// blockchain.Processor.
+type claimBlockchain interface {
+	Enabled() bool
+	HasIdentity() bool
+	ClaimFundsFromChannel(timeout time.Duration, channelId, amount *big.Int, signature []byte, sendBack bool) (common.Hash, error)
+	ClaimRevertReason(channelID, nonce, amount *big.Int) (string, error)
+}
+
 type claimCommand struct {
 	channelService escrow.PaymentChannelService
-	blockchain     *blockchain.Processor
+	blockchain     claimBlockchain
+	notifier       *escrow.ClaimWebhookNotifier
+	// revertAction returns claim_revert_policy action of the revert reason
+	revertAction func(reason string) string
 
 	channelId *big.Int
 	paymentId string
 	sendBack  bool
 	timeout   time.Duration
+	// replica is true if daemon runs in replica_mode and cannot send
+	// transactions
+	replica bool
 }
 
 func newClaimCommand(cmd *cobra.Command, args []string, components *Components) (command Command, err error) {
@@ -48,11 +66,14 @@ func newClaimCommand(cmd *cobra.Command, args []string, components *Components)
 	command = &claimCommand{
 		channelService: components.PaymentChannelService(),
 		blockchain:     components.Blockchain(),
+		notifier:       components.ClaimWebhookNotifier(),
+		revertAction:   config.GetClaimRevertAction,
 
 		channelId: channelId,
 		paymentId: claimPaymentId,
 		sendBack:  claimSendBack,
 		timeout:   timeout,
+		replica:   config.GetBool(config.ReplicaModeKey),
 	}
 
 	return
@@ -71,6 +92,9 @@ func getChannelId(cmd *cobra.Command) (id *big.Int, err error) {
 }
 
 func (command *claimCommand) Run() (err error) {
+	if command.replica {
+		return fmt.Errorf("channels cannot be claimed in replica mode, claim them using primary daemon")
+	}
 	if !command.blockchain.Enabled() {
 		return fmt.Errorf("blockchain should be enabled to claim money from channel")
 	}
@@ -137,16 +161,63 @@ func (command *claimCommand) findClaim() (claim escrow.Claim, err error) {
 func (command *claimCommand) claimPaymentFromChannel(claim escrow.Claim) (err error) {
 	payment := claim.Payment()
 
-	err = command.blockchain.ClaimFundsFromChannel(
+	txHash, err := command.blockchain.ClaimFundsFromChannel(
 		command.timeout,
 		payment.ChannelID,
 		payment.Amount,
 		payment.Signature,
 		command.sendBack,
 	)
+	if reverted, ok := err.(*blockchain.ClaimRevertedError); ok {
+		return command.handleRevert(claim, reverted)
+	}
 	if err != nil {
 		return
 	}
 
-	return claim.Finish()
+	err = claim.Finish()
+	if err != nil {
+		return
+	}
+
+	// funds are already claimed, so notification failure is logged but
+	// doesn't fail the command
+	e := command.notifier.Notify(&escrow.ClaimNotification{
+		ChannelID: payment.ChannelID,
+		Amount:    payment.Amount,
+		TxHash:    txHash.Hex(),
+	})
+	if e != nil {
+		log.WithError(e).Error("Claim notification is not delivered")
+	}
+	return nil
+}
+
+// handleRevert applies claim_revert_policy action of the revert reason to
+// the claim which transaction is reverted.
+func (command *claimCommand) handleRevert(claim escrow.Claim, reverted *blockchain.ClaimRevertedError) (err error) {
+	payment := claim.Payment()
+
+	reason, err := command.blockchain.ClaimRevertReason(payment.ChannelID, payment.ChannelNonce, payment.Amount)
+	if err != nil {
+		log.WithError(err).Warn("Cannot get reason of the claim transaction revert")
+		reason = config.ClaimRevertUnknown
+	}
+	action := command.revertAction(reason)
+	log := log.WithField("paymentId", payment.ID()).WithField("txHash", reverted.TxHash.Hex()).WithField("reason", reason).WithField("action", action)
+
+	switch action {
+	case config.ClaimRevertMarkClaimed:
+		log.Warn("Claim transaction is reverted, claim is marked as finished")
+		return claim.Finish()
+	case config.ClaimRevertMarkFailed:
+		log.Error("Claim transaction is reverted, claim is marked as failed")
+		if err = claim.Fail(); err != nil {
+			return
+		}
+		return fmt.Errorf("%v (%v), claim is marked as failed", reverted, reason)
+	default:
+		log.Error("Claim transaction is reverted, claim can be retried using --payment-id")
+		return fmt.Errorf("%v (%v)", reverted, reason)
+	}
 }
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+type claimBlockchainMock struct {
+	claimErr     error
+	revertReason string
+	claims       int
+}
+
+func (mock *claimBlockchainMock) Enabled() bool     { return true }
+func (mock *claimBlockchainMock) HasIdentity() bool { return true }
+
+func (mock *claimBlockchainMock) ClaimFundsFromChannel(timeout time.Duration, channelId, amount *big.Int, signature []byte, sendBack bool) (common.Hash, error) {
+	mock.claims++
+	return common.HexToHash("0x01"), mock.claimErr
+}
+
+func (mock *claimBlockchainMock) ClaimRevertReason(channelID, nonce, amount *big.Int) (string, error) {
+	if mock.revertReason == "" {
+		return "", errors.New("node error")
+	}
+	return mock.revertReason, nil
+}
+
+type claimMock struct {
+	finished bool
+	failed   bool
+}
+
+func (claim *claimMock) Payment() *escrow.Payment {
+	return &escrow.Payment{ChannelID: big.NewInt(42), ChannelNonce: big.NewInt(3), Amount: big.NewInt(100)}
+}
+
+func (claim *claimMock) Finish() error {
+	claim.finished = true
+	return nil
+}
+
+func (claim *claimMock) Fail() error {
+	claim.failed = true
+	return nil
+}
+
+func newTestClaimCommand(chain *claimBlockchainMock, policy map[string]string) *claimCommand {
+	return &claimCommand{
+		blockchain: chain,
+		notifier:   escrow.NewClaimWebhookNotifier(nil),
+		revertAction: func(reason string) string {
+			return policy[reason]
+		},
+	}
+}
+
+var testRevertPolicy = map[string]string{
+	config.ClaimRevertAlreadyClaimed:    config.ClaimRevertMarkClaimed,
+	config.ClaimRevertInsufficientFunds: config.ClaimRevertMarkFailed,
+	config.ClaimRevertUnknown:           config.ClaimRevertRetry,
+}
+
+func TestClaimAlreadyClaimedIsMarkedClaimed(t *testing.T) {
+	chain := &claimBlockchainMock{
+		claimErr:     &blockchain.ClaimRevertedError{TxHash: common.HexToHash("0x01")},
+		revertReason: config.ClaimRevertAlreadyClaimed,
+	}
+	claim := &claimMock{}
+
+	err := newTestClaimCommand(chain, testRevertPolicy).claimPaymentFromChannel(claim)
+
+	assert.Nil(t, err)
+	assert.True(t, claim.finished, "claim is finished")
+	assert.False(t, claim.failed)
+	assert.Equal(t, 1, chain.claims, "transaction is not retried")
+}
+
+func TestClaimRevertIsMarkedFailed(t *testing.T) {
+	chain := &claimBlockchainMock{
+		claimErr:     &blockchain.ClaimRevertedError{TxHash: common.HexToHash("0x01")},
+		revertReason: config.ClaimRevertInsufficientFunds,
+	}
+	claim := &claimMock{}
+
+	err := newTestClaimCommand(chain, testRevertPolicy).claimPaymentFromChannel(claim)
+
+	assert.EqualError(t, err, "claim transaction 0x0000000000000000000000000000000000000000000000000000000000000001 is reverted (insufficient_funds), claim is marked as failed")
+	assert.False(t, claim.finished)
+	assert.True(t, claim.failed)
+	assert.Equal(t, 1, chain.claims)
+}
+
+func TestClaimRevertIsRetried(t *testing.T) {
+	chain := &claimBlockchainMock{
+		claimErr:     &blockchain.ClaimRevertedError{TxHash: common.HexToHash("0x01")},
+		revertReason: config.ClaimRevertUnknown,
+	}
+	claim := &claimMock{}
+
+	err := newTestClaimCommand(chain, testRevertPolicy).claimPaymentFromChannel(claim)
+
+	assert.EqualError(t, err, "claim transaction 0x0000000000000000000000000000000000000000000000000000000000000001 is reverted (unknown)")
+	assert.False(t, claim.finished, "claim is kept in progress")
+	assert.False(t, claim.failed)
+}
+
+func TestClaimRevertReasonError(t *testing.T) {
+	chain := &claimBlockchainMock{
+		claimErr: &blockchain.ClaimRevertedError{TxHash: common.HexToHash("0x01")},
+	}
+	claim := &claimMock{}
+
+	err := newTestClaimCommand(chain, testRevertPolicy).claimPaymentFromChannel(claim)
+
+	assert.EqualError(t, err, "claim transaction 0x0000000000000000000000000000000000000000000000000000000000000001 is reverted (unknown)")
+	assert.False(t, claim.finished)
+	assert.False(t, claim.failed)
+}
+
+func TestClaimFinishedOnSuccess(t *testing.T) {
+	chain := &claimBlockchainMock{}
+	claim := &claimMock{}
+
+	err := newTestClaimCommand(chain, testRevertPolicy).claimPaymentFromChannel(claim)
+
+	assert.Nil(t, err)
+	assert.True(t, claim.finished)
+}
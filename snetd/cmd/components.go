@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"math/big"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -14,18 +18,39 @@ import (
 	"github.com/singnet/snet-daemon/escrow"
 	"github.com/singnet/snet-daemon/etcddb"
 	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/metrics"
+	"github.com/singnet/snet-daemon/ratelimit"
+	"github.com/singnet/snet-daemon/tracing"
 )
 
 type Components struct {
 	serviceMetadata            *blockchain.ServiceMetadata
+	servicesMetadata           map[handler.ServiceKey]*blockchain.ServiceMetadata
 	blockchain                 *blockchain.Processor
 	etcdClient                 *etcddb.EtcdClient
 	etcdServer                 *etcddb.EtcdServer
 	atomicStorage              escrow.AtomicStorage
+	counterStore               escrow.CounterStore
+	invoiceStorage             *escrow.InvoiceStorage
+	subscriptionStorage        *escrow.SubscriptionStorage
+	subscriptionService        *escrow.SubscriptionService
+	webhookDeliveryQueue       *escrow.WebhookDeliveryQueue
 	paymentChannelService      escrow.PaymentChannelService
 	escrowPaymentHandler       handler.PaymentHandler
+	incomeValidator            escrow.IncomeValidator
+	messageIncomeValidator     escrow.MessageIncomeValidator
+	pricingFileWatcher         *escrow.PricingFileWatcher
+	priceProviders             map[handler.ServiceKey]escrow.PriceProvider
+	workerPool                 *handler.WorkerPool
+	validationRecordFile       *os.File
+	rateLimiter                *ratelimit.ReloadableLimiter
+	rateLimiterPersister       *ratelimit.LimiterPersister
 	grpcInterceptor            grpc.StreamServerInterceptor
+	maintenanceSchedule        *handler.MaintenanceSchedule
+	maintenanceScheduleRead    bool
 	paymentChannelStateService *escrow.PaymentChannelStateService
+	tracer                     *tracing.Tracer
+	statsd                     *metrics.StatsdClient
 }
 
 func InitComponents(cmd *cobra.Command) (components *Components) {
@@ -39,13 +64,27 @@ func InitComponents(cmd *cobra.Command) (components *Components) {
 		}
 	}()
 
-	loadConfigFileFromCommandLine(cmd.Flags().Lookup("config"))
+	loadConfigFileFromCommandLine(cmd.Flags().Lookup("config"), cmd.Flags().Lookup("config-signer"))
+	components.Tracer()
 
 	return
 }
 
-func loadConfigFileFromCommandLine(configFlag *pflag.Flag) {
+func loadConfigFileFromCommandLine(configFlag *pflag.Flag, signerFlag *pflag.Flag) {
 	var configFile = configFlag.Value.String()
+	var signer = signerFlag.Value.String()
+
+	if signer != "" {
+		if !common.IsHexAddress(signer) {
+			log.WithField("signer", signer).Panic("Config signer is not a valid Ethereum address")
+		}
+		err := config.LoadSignedConfig(configFile, common.HexToAddress(signer))
+		if err != nil {
+			log.WithError(err).WithField("configFile", configFile).Panic("Error reading signed configuration file")
+		}
+		log.WithField("configFile", configFile).WithField("signer", signer).Info("Using signed configuration file")
+		return
+	}
 
 	// if file is not specified by user then configFile contains default name
 	if configFlag.Changed || isFileExist(configFile) {
@@ -66,6 +105,9 @@ func isFileExist(fileName string) bool {
 }
 
 func (components *Components) Close() {
+	if components.rateLimiterPersister != nil {
+		components.rateLimiterPersister.Stop()
+	}
 	if components.etcdClient != nil {
 		components.etcdClient.Close()
 	}
@@ -75,6 +117,58 @@ func (components *Components) Close() {
 	if components.blockchain != nil {
 		components.blockchain.Close()
 	}
+	if components.pricingFileWatcher != nil {
+		components.pricingFileWatcher.Close()
+	}
+	if components.workerPool != nil {
+		components.workerPool.Close()
+	}
+	if components.validationRecordFile != nil {
+		components.validationRecordFile.Close()
+	}
+	if components.tracer != nil {
+		tracing.SetGlobalTracer(nil)
+		components.tracer.Close()
+	}
+	if components.statsd != nil {
+		components.statsd.Close()
+	}
+}
+
+// Tracer returns tracer which sends spans to the tracing_otlp_endpoint and
+// sets it as global tracer. It returns nil if tracing_enabled is false.
+func (components *Components) Tracer() *tracing.Tracer {
+	if components.tracer != nil || !config.GetBool(config.TracingEnabledKey) {
+		return components.tracer
+	}
+
+	endpoint := config.GetString(config.TracingOTLPEndpointKey)
+	components.tracer = tracing.NewTracer(tracing.NewOTLPExporter(endpoint, "snet-daemon"))
+	tracing.SetGlobalTracer(components.tracer)
+	log.WithField("endpoint", endpoint).Info("Tracing is enabled")
+
+	return components.tracer
+}
+
+// Statsd returns client which sends metrics to the statsd_endpoint. It
+// returns nil if statsd_endpoint is not set.
+func (components *Components) Statsd() *metrics.StatsdClient {
+	if components.statsd != nil {
+		return components.statsd
+	}
+	endpoint := config.GetString(config.StatsdEndpointKey)
+	if endpoint == "" {
+		return nil
+	}
+
+	statsd, err := metrics.NewStatsdClient(endpoint, "snetd.")
+	if err != nil {
+		log.WithError(err).Panic("error initializing StatsD client")
+	}
+	components.statsd = statsd
+	log.WithField("endpoint", endpoint).Info("Metrics are sent to StatsD")
+
+	return components.statsd
 }
 
 func (components *Components) Blockchain() *blockchain.Processor {
@@ -99,11 +193,52 @@ func (components *Components) ServiceMetaData() *blockchain.ServiceMetadata {
 	return components.serviceMetadata
 }
 
+// ServiceMetaDataOf returns metadata of the given service. Metadata of the
+// service set by organization_id and service_id is ServiceMetaData(),
+// metadata of other services is read once and cached.
+func (components *Components) ServiceMetaDataOf(service *config.ServiceConf) *blockchain.ServiceMetadata {
+	if service.OrganizationID == config.GetString(config.OrganizationId) &&
+		service.ServiceID == config.GetString(config.ServiceId) {
+		return components.ServiceMetaData()
+	}
+
+	key := serviceKey(service)
+	if metadata, ok := components.servicesMetadata[key]; ok {
+		return metadata
+	}
+	metadata, err := blockchain.ServiceMetaDataOf(service.OrganizationID, service.ServiceID, service.MetadataCID)
+	if err != nil {
+		log.WithError(err).WithField("service", key).Panic("error on determining service metadata")
+	}
+	if components.servicesMetadata == nil {
+		components.servicesMetadata = make(map[handler.ServiceKey]*blockchain.ServiceMetadata)
+	}
+	components.servicesMetadata[key] = metadata
+	return metadata
+}
+
+// allServicesMetaData returns metadata of each configured service.
+func (components *Components) allServicesMetaData() []*blockchain.ServiceMetadata {
+	services, err := config.GetServices()
+	if err != nil {
+		log.WithError(err).Panic("error reading services configuration")
+	}
+	metadata := make([]*blockchain.ServiceMetadata, 0, len(services))
+	for _, service := range services {
+		metadata = append(metadata, components.ServiceMetaDataOf(service))
+	}
+	return metadata
+}
+
 func (components *Components) EtcdServer() *etcddb.EtcdServer {
 	if components.etcdServer != nil {
 		return components.etcdServer
 	}
 
+	// replica uses etcd of the primary daemon
+	if config.GetBool(config.ReplicaModeKey) {
+		return nil
+	}
 	enabled, err := etcddb.IsEtcdServerEnabled()
 	if err != nil {
 		log.WithError(err).Panic("error during etcd config parsing")
@@ -145,7 +280,9 @@ func (components *Components) AtomicStorage() escrow.AtomicStorage {
 		return components.atomicStorage
 	}
 
-	if config.GetString(config.PaymentChannelStorageTypeKey) == "etcd" {
+	if config.GetBool(config.ReplicaModeKey) {
+		components.atomicStorage = escrow.NewReadOnlyAtomicStorage(components.EtcdClient())
+	} else if config.GetString(config.PaymentChannelStorageTypeKey) == "etcd" {
 		components.atomicStorage = components.EtcdClient()
 	} else {
 		components.atomicStorage = escrow.NewMemStorage()
@@ -154,15 +291,112 @@ func (components *Components) AtomicStorage() escrow.AtomicStorage {
 	return components.atomicStorage
 }
 
+func (components *Components) SubscriptionStorage() *escrow.SubscriptionStorage {
+	if components.subscriptionStorage != nil {
+		return components.subscriptionStorage
+	}
+
+	components.subscriptionStorage = escrow.NewSubscriptionStorage(components.AtomicStorage())
+	return components.subscriptionStorage
+}
+
+// InvoiceStorage returns storage of the invoices issued by services.
+func (components *Components) InvoiceStorage() *escrow.InvoiceStorage {
+	if components.invoiceStorage != nil {
+		return components.invoiceStorage
+	}
+
+	components.invoiceStorage = escrow.NewInvoiceStorage(components.AtomicStorage())
+	return components.invoiceStorage
+}
+
+// SubscriptionService returns service which creates subscriptions paid by
+// payments or nil if subscription_price is not set.
+func (components *Components) SubscriptionService() *escrow.SubscriptionService {
+	if components.subscriptionService != nil {
+		return components.subscriptionService
+	}
+
+	price := subscriptionPrice()
+	if price.Sign() == 0 {
+		return nil
+	}
+	components.subscriptionService = escrow.NewSubscriptionService(components.PaymentChannelService(),
+		components.SubscriptionStorage(), price, config.GetDuration(config.SubscriptionPeriodKey))
+	return components.subscriptionService
+}
+
+// WebhookDeliveryQueue returns queue which keeps webhook notifications in
+// the storage until they are delivered or dead-lettered.
+func (components *Components) WebhookDeliveryQueue() *escrow.WebhookDeliveryQueue {
+	if components.webhookDeliveryQueue != nil {
+		return components.webhookDeliveryQueue
+	}
+
+	var recorder metrics.Recorder
+	if components.Statsd() != nil {
+		recorder = components.Statsd()
+	}
+	policy := escrow.DefaultWebhookRetryPolicy(config.GetInt(config.WebhookMaxAttemptsKey),
+		config.GetDuration(config.WebhookRetryBackoffKey), config.GetDuration(config.WebhookMaxRetryBackoffKey))
+	components.webhookDeliveryQueue = escrow.NewWebhookDeliveryQueue(
+		escrow.NewWebhookDeliveryStorage(components.AtomicStorage()), escrow.PostWebhook, policy, recorder)
+	return components.webhookDeliveryQueue
+}
+
+// ClaimWebhookNotifier returns notifier which delivers claim notifications
+// to the claim_webhook_urls through the WebhookDeliveryQueue.
+func (components *Components) ClaimWebhookNotifier() *escrow.ClaimWebhookNotifier {
+	return escrow.NewQueuedClaimWebhookNotifier(config.GetStringSlice(config.ClaimWebhookURLsKey), components.WebhookDeliveryQueue())
+}
+
+func subscriptionPrice() *big.Int {
+	price, err := config.GetCheckedBigInt(config.SubscriptionPriceKey)
+	if err != nil {
+		log.WithError(err).Panic("error reading subscription_price")
+	}
+	return price
+}
+
+func (components *Components) CounterStore() escrow.CounterStore {
+	if components.counterStore != nil {
+		return components.counterStore
+	}
+
+	// replica shares spending of the senders with primary daemon
+	if config.GetBool(config.ReplicaModeKey) {
+		components.counterStore = escrow.NewAtomicCounterStore(components.EtcdClient())
+	} else if config.GetString(config.PaymentChannelStorageTypeKey) == "etcd" {
+		components.counterStore = escrow.NewAtomicCounterStore(components.AtomicStorage())
+	} else {
+		components.counterStore = escrow.NewMemCounterStore()
+	}
+
+	return components.counterStore
+}
+
 func (components *Components) PaymentChannelService() escrow.PaymentChannelService {
 	if components.paymentChannelService != nil {
 		return components.paymentChannelService
 	}
 
+	// replica writes payments and channel locks to the etcd of the primary
+	// daemon, so they are applied to the same channel state
+	if config.GetBool(config.ReplicaModeKey) {
+		components.paymentChannelService = escrow.NewReplicaPaymentChannelService(
+			escrow.NewPaymentChannelStorage(components.EtcdClient()),
+			escrow.NewPaymentStorage(components.AtomicStorage()),
+			escrow.NewBlockchainChannelReader(components.Blockchain(), config.Vip(), components.allServicesMetaData()...),
+			escrow.NewEtcdLocker(components.EtcdClient()),
+			escrow.NewChannelPaymentValidator(components.Blockchain(), config.Vip(), components.ServiceMetaData()),
+		)
+		return components.paymentChannelService
+	}
+
 	components.paymentChannelService = escrow.NewPaymentChannelService(
 		escrow.NewPaymentChannelStorage(components.AtomicStorage()),
 		escrow.NewPaymentStorage(components.AtomicStorage()),
-		escrow.NewBlockchainChannelReader(components.Blockchain(), config.Vip(), components.ServiceMetaData()),
+		escrow.NewBlockchainChannelReader(components.Blockchain(), config.Vip(), components.allServicesMetaData()...),
 		escrow.NewEtcdLocker(components.AtomicStorage()),
 		escrow.NewChannelPaymentValidator(components.Blockchain(), config.Vip(), components.ServiceMetaData()),
 	)
@@ -170,34 +404,487 @@ func (components *Components) PaymentChannelService() escrow.PaymentChannelServi
 	return components.paymentChannelService
 }
 
+func serviceKey(service *config.ServiceConf) handler.ServiceKey {
+	return handler.ServiceKey{OrganizationID: service.OrganizationID, ServiceID: service.ServiceID}
+}
+
+// calledServiceKeyHeaders returns headers which specify the called service
+// or nil if daemon serves the only service.
+func calledServiceKeyHeaders(services []*config.ServiceConf) *handler.ServiceKeyHeaders {
+	if len(services) == 1 {
+		return nil
+	}
+	headers := handler.GetServiceKeyHeaders()
+	return &headers
+}
+
 func (components *Components) EscrowPaymentHandler() handler.PaymentHandler {
 	if components.escrowPaymentHandler != nil {
 		return components.escrowPaymentHandler
 	}
 
+	services, err := config.GetServices()
+	if err != nil {
+		log.WithError(err).Panic("error reading services configuration")
+	}
 	components.escrowPaymentHandler = escrow.NewPaymentHandler(
 		components.PaymentChannelService(),
 		components.Blockchain(),
-		escrow.NewIncomeValidator(components.ServiceMetaData().GetPriceInCogs()),
+		components.IncomeValidator(),
+		components.MessageIncomeValidator(),
+		calledServiceKeyHeaders(services),
+		components.blockedSenders(),
+		components.channelValidator(services),
 	)
 
 	return components.escrowPaymentHandler
 }
 
+// channelValidator returns validator of the channels used to pay for the
+// called service or nil if no checks are needed. When daemon serves few
+// services channel should be opened to the group and payment address of the
+// called service. Channels of the services which set min_channel_deposit
+// should have enough funds deposited.
+func (components *Components) channelValidator(services []*config.ServiceConf) escrow.ChannelValidator {
+	validators := make(map[handler.ServiceKey]escrow.ChannelValidator)
+	for _, service := range services {
+		minDeposit, err := service.GetMinChannelDeposit()
+		if err != nil {
+			log.WithError(err).Panic("error reading min_channel_deposit")
+		}
+		if len(services) == 1 {
+			if minDeposit.Sign() <= 0 {
+				return nil
+			}
+			return escrow.NewMinChannelDepositValidator(minDeposit)
+		}
+		validator := escrow.NewChannelRecipientValidator(components.ServiceMetaDataOf(service))
+		if minDeposit.Sign() > 0 {
+			validator = escrow.NewCompositeChannelValidator(validator, escrow.NewMinChannelDepositValidator(minDeposit))
+		}
+		validators[serviceKey(service)] = validator
+	}
+	if len(validators) == 0 {
+		return nil
+	}
+	return escrow.NewServiceChannelValidator(handler.GetServiceKeyHeaders(), validators)
+}
+
+// blockedSenders returns denylist of the blocked_senders which is updated on
+// configuration reload. Previous list is kept if new one is incorrect.
+func (components *Components) blockedSenders() *escrow.SenderDenylist {
+	senders, err := config.GetBlockedSenders()
+	if err != nil {
+		log.WithError(err).Panic("error reading blocked_senders")
+	}
+	denylist := escrow.NewSenderDenylist(senders)
+	config.AddReloadListener(func() {
+		senders, err := config.GetBlockedSenders()
+		if err != nil {
+			log.WithError(err).Error("Cannot reload blocked_senders, previous list is kept")
+			return
+		}
+		denylist.Update(senders)
+		log.WithField("blockedSenders", denylist.Len()).Info("Blocked senders reloaded")
+	})
+	return denylist
+}
+
+func (components *Components) IncomeValidator() escrow.IncomeValidator {
+	if components.incomeValidator != nil {
+		return components.incomeValidator
+	}
+
+	components.incomeValidator = components.newIncomeValidator(false)
+	return components.incomeValidator
+}
+
+// ReplayIncomeValidator returns income validator to replay recorded
+// validation decisions. Unlike IncomeValidator it doesn't record decisions
+// and always enforces income validation.
+func (components *Components) ReplayIncomeValidator() escrow.IncomeValidator {
+	return components.newIncomeValidator(true)
+}
+
+func (components *Components) newIncomeValidator(replay bool) escrow.IncomeValidator {
+	validator := newPricingIncomeValidator(components.serviceIncomeValidator)
+	if replay {
+		return validator
+	}
+
+	validator = components.limitIncomeValidator(validator)
+
+	if components.Statsd() != nil {
+		validator = escrow.NewMetricsIncomeValidator(validator, components.Statsd())
+	}
+
+	if components.Tracer() != nil {
+		validator = escrow.NewTracingIncomeValidator(validator)
+	}
+
+	if path := config.GetString(config.ValidationRecordFileKey); path != "" {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			log.WithError(err).WithField("path", path).Panic("error opening income validation record file")
+		}
+		components.validationRecordFile = file
+		validator = escrow.NewRecordingIncomeValidator(validator, file, config.GetFloat64(config.ValidationRecordSampleRateKey))
+	}
+
+	if config.GetString(config.IncomeValidationModeKey) == "observe" {
+		log.Warn("Income validation is in observe mode: calls are not rejected")
+		validator = escrow.NewObservingIncomeValidator(validator)
+	}
+
+	return validator
+}
+
+// newPricingIncomeValidator returns validator which checks income of the
+// called service using validator returned by serviceValidator. min_income
+// and accepted_currency are applied to it.
+func newPricingIncomeValidator(serviceValidator func(*config.ServiceConf) escrow.IncomeValidator) escrow.IncomeValidator {
+	services, err := config.GetServices()
+	if err != nil {
+		log.WithError(err).Panic("error reading services configuration")
+	}
+
+	var validator escrow.IncomeValidator
+	if len(services) == 1 {
+		validator = serviceValidator(services[0])
+	} else {
+		validators := make(map[handler.ServiceKey]escrow.IncomeValidator)
+		for _, service := range services {
+			key := serviceKey(service)
+			validators[key] = serviceValidator(service)
+		}
+		validator = escrow.NewServiceIncomeValidator(handler.GetServiceKeyHeaders(), validators)
+	}
+
+	stages := map[string]escrow.IncomeValidator{config.IncomeValidationStagePrice: validator}
+	minIncome, err := config.GetCheckedBigInt(config.MinIncomeKey)
+	if err != nil {
+		log.WithError(err).Panic("error reading min_income")
+	}
+	if minIncome.Sign() > 0 {
+		stages[config.IncomeValidationStageMinIncome] = escrow.NewMinIncomeValidator(minIncome)
+	}
+
+	if len(stages) > 1 {
+		ordered := make([]escrow.IncomeValidator, 0, len(stages))
+		for _, stage := range config.GetIncomeValidationOrder() {
+			if stageValidator, ok := stages[stage]; ok {
+				ordered = append(ordered, stageValidator)
+			}
+		}
+		validator = escrow.NewCompositeIncomeValidator(ordered...)
+	}
+
+	return escrow.NewCurrencyIncomeValidator(validator, config.GetAcceptedCurrency())
+}
+
+// limitIncomeValidator applies subscriptions, per_sender_spend_cap and
+// income_validation_timeout to the validator. Timeout bounds the whole
+// chain including storage requests of subscriptions and spend cap.
+func (components *Components) limitIncomeValidator(validator escrow.IncomeValidator) escrow.IncomeValidator {
+	if subscriptionPrice().Sign() > 0 {
+		services, err := config.GetServices()
+		if err != nil {
+			log.WithError(err).Panic("error reading services configuration")
+		}
+		validator = escrow.NewSubscriptionIncomeValidator(validator, components.SubscriptionStorage(),
+			calledServiceKeyHeaders(services), serviceKey(services[0]))
+	}
+
+	spendCap, err := config.GetCheckedBigInt(config.PerSenderSpendCapKey)
+	if err != nil {
+		log.WithError(err).Panic("error reading per_sender_spend_cap")
+	}
+	if spendCap.Sign() > 0 {
+		validator = escrow.NewSpendCapIncomeValidator(validator, components.CounterStore(),
+			spendCap, config.GetDuration(config.PerSenderSpendCapPeriodKey))
+	}
+
+	if timeout := config.GetDuration(config.IncomeValidationTimeoutKey); timeout > 0 {
+		validator = escrow.NewTimeoutIncomeValidator(validator, timeout)
+	}
+
+	return validator
+}
+
+// PricingFileWatcher returns watcher of the pricing_file or nil if file is
+// not configured.
+func (components *Components) PricingFileWatcher() *escrow.PricingFileWatcher {
+	if components.pricingFileWatcher != nil {
+		return components.pricingFileWatcher
+	}
+
+	path := config.GetString(config.PricingFileKey)
+	if path == "" {
+		return nil
+	}
+
+	watcher, err := escrow.NewPricingFileWatcher(path)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Panic("error reading pricing file")
+	}
+
+	components.pricingFileWatcher = watcher
+	return components.pricingFileWatcher
+}
+
+func (components *Components) serviceIncomeValidator(service *config.ServiceConf) escrow.IncomeValidator {
+	validator := escrow.NewJobValueIncomeValidator(components.servicePriceProvider(service), escrow.GetJobValueHeader())
+	if header := escrow.GetInvoiceIDHeader(); header != "" {
+		validator = escrow.NewInvoiceIncomeValidator(validator, header, components.InvoiceStorage())
+	}
+	return validator
+}
+
+// MessageIncomeValidator returns validator of the client streaming calls
+// income or nil if streaming_income_validation is disabled. Subscriptions,
+// per_sender_spend_cap and income_validation_mode are applied to it in the
+// same way as to IncomeValidator.
+func (components *Components) MessageIncomeValidator() escrow.MessageIncomeValidator {
+	if components.messageIncomeValidator != nil {
+		return components.messageIncomeValidator
+	}
+
+	if !config.GetBool(config.StreamingIncomeValidationKey) {
+		return nil
+	}
+
+	validator := components.limitIncomeValidator(newPricingIncomeValidator(components.serviceMessageIncomeValidator))
+	if config.GetString(config.IncomeValidationModeKey) == "observe" {
+		validator = escrow.NewObservingIncomeValidator(validator)
+	}
+
+	components.messageIncomeValidator = escrow.NewMessageIncomeValidator(validator)
+	return components.messageIncomeValidator
+}
+
+func (components *Components) serviceMessageIncomeValidator(service *config.ServiceConf) escrow.IncomeValidator {
+	return escrow.NewMessagePriceIncomeValidator(components.servicePriceProvider(service))
+}
+
+// servicePriceProvider returns price provider of the service, the same
+// provider is returned for the same service, so income validators and
+// pricing endpoint share cached prices. Prices set in price_currency are
+// converted into cogs.
+func (components *Components) servicePriceProvider(service *config.ServiceConf) escrow.PriceProvider {
+	key := serviceKey(service)
+	if provider, ok := components.priceProviders[key]; ok {
+		return provider
+	}
+	if components.priceProviders == nil {
+		components.priceProviders = make(map[handler.ServiceKey]escrow.PriceProvider)
+	}
+	provider := components.newServicePriceProvider(service)
+	rate, err := config.GetPriceRate()
+	if err != nil {
+		log.WithError(err).Panic("error reading price_currency rate")
+	}
+	if rate != nil {
+		provider = escrow.NewCurrencyPriceProvider(provider, rate)
+	}
+	components.priceProviders[key] = provider
+	return provider
+}
+
+func (components *Components) newServicePriceProvider(service *config.ServiceConf) escrow.PriceProvider {
+	if watcher := components.PricingFileWatcher(); watcher != nil {
+		provider, err := watcher.PriceProvider(service.OrganizationID, service.ServiceID)
+		if err != nil {
+			log.WithError(err).Panic("error reading pricing file")
+		}
+		return provider
+	}
+
+	price, ok, err := service.GetPriceInCogs()
+	if err != nil {
+		log.WithError(err).Panic("error reading service price")
+	}
+
+	if !components.Blockchain().Enabled() {
+		if !ok {
+			price, err = localMetadataPrice(service, components.ServiceMetaData)
+			if err != nil {
+				log.WithError(err).Panic("error reading service price")
+			}
+		}
+		return escrow.NewFixedPriceProvider(price)
+	}
+
+	var provider escrow.PriceProvider = escrow.NewCachingPriceProvider(
+		blockchain.NewMetadataPriceProvider(service.OrganizationID, service.ServiceID, service.MetadataCID),
+		config.GetDuration(config.PriceCacheTtlKey),
+		config.GetDuration(config.MetadataCacheRefreshJitterKey),
+	)
+	if ok {
+		provider = escrow.NewFallbackPriceProvider(provider, escrow.NewFixedPriceProvider(price))
+	}
+	return provider
+}
+
+// localMetadataPrice returns price of the service from the local service
+// metadata file which is used when blockchain is disabled. This file
+// describes the service set by organization_id and service_id only, so
+// price of other services should be configured explicitly.
+func localMetadataPrice(service *config.ServiceConf, metadata func() *blockchain.ServiceMetadata) (price *big.Int, err error) {
+	if service.OrganizationID != config.GetString(config.OrganizationId) || service.ServiceID != config.GetString(config.ServiceId) {
+		return nil, fmt.Errorf("price_in_cogs or price should be set for service %v/%v as its metadata cannot be read when blockchain is disabled",
+			service.OrganizationID, service.ServiceID)
+	}
+	return metadata().GetPriceInCogs(), nil
+}
+
+// DescribeValidators returns description of the income validators which are
+// used to check calls.
+func (components *Components) DescribeValidators() []escrow.ValidatorInfo {
+	return []escrow.ValidatorInfo{escrow.DescribeValidator(components.IncomeValidator())}
+}
+
+// MaintenanceSchedule returns schedule of maintenance_windows or nil if there
+// are no windows. The same schedule is shared by the service calls, admin
+// HTTP endpoints and auto claim.
+func (components *Components) MaintenanceSchedule() *handler.MaintenanceSchedule {
+	if components.maintenanceScheduleRead {
+		return components.maintenanceSchedule
+	}
+
+	windows, err := config.GetMaintenanceWindows()
+	if err != nil {
+		log.WithError(err).Panic("error reading maintenance_windows")
+	}
+	if len(windows) > 0 {
+		log.WithField("windows", windows).Info("Service calls, admin writes and auto claim are paused during maintenance windows")
+	}
+	components.maintenanceSchedule = handler.NewMaintenanceSchedule(windows)
+	components.maintenanceScheduleRead = true
+	return components.maintenanceSchedule
+}
+
 func (components *Components) GrpcInterceptor() grpc.StreamServerInterceptor {
 	if components.grpcInterceptor != nil {
 		return components.grpcInterceptor
 	}
-	components.grpcInterceptor = grpc_middleware.ChainStreamServer(handler.GrpcRateLimitInterceptor(), components.GrpcPaymentValidationInterceptor())
+	var interceptors []grpc.StreamServerInterceptor
+	if components.Tracer() != nil {
+		interceptors = append(interceptors, handler.GrpcTracingInterceptor())
+	}
+	if components.Statsd() != nil {
+		interceptors = append(interceptors, handler.GrpcMetricsInterceptor(components.Statsd()))
+	}
+	interceptors = append(interceptors,
+		handler.NewGrpcRateLimitInterceptor(components.RateLimiter()),
+		handler.GrpcMetadataSizeInterceptor(config.GetInt(config.MaxMetadataBytesKey)),
+		handler.GrpcUnknownMetadataInterceptor(config.GetString(config.UnknownMetadataPolicyKey), knownMetadataHeaders()),
+		handler.GrpcMethodFilterInterceptor(config.GetEnabledMethods(), config.GetDisabledMethods()),
+		handler.GrpcMaintenanceInterceptor(components.MaintenanceSchedule()))
+	if pool := components.WorkerPool(); pool != nil {
+		interceptors = append(interceptors, handler.GrpcWorkerPoolInterceptor(pool))
+	}
+	interceptors = append(interceptors,
+		handler.DefaultHookRegistry.PreValidationInterceptor(),
+		components.GrpcPaymentValidationInterceptor(),
+		handler.DefaultHookRegistry.PostValidationInterceptor())
+	if methods := config.GetCacheableMethods(); len(methods) > 0 {
+		log.WithField("methods", methods).Info("Responses of cacheable methods are cached")
+		cache := handler.NewResponseCache(methods, config.GetDuration(config.ResponseCacheTtlKey),
+			config.GetInt(config.ResponseCacheMaxEntriesKey))
+		interceptors = append(interceptors, cache.Interceptor())
+	}
+	if config.GetBool(config.DebugLogBodiesKey) {
+		log.Warn("Request and response bodies are logged at debug level, it should not be used in production")
+		interceptors = append(interceptors, handler.GrpcBodyLoggingInterceptor(
+			config.GetInt(config.DebugLogBodyLimitKey),
+			[]string{escrow.PaymentChannelSignatureHeader, handler.OfflineAuthTokenHeader}))
+	}
+	components.grpcInterceptor = grpc_middleware.ChainStreamServer(interceptors...)
 	return components.grpcInterceptor
 }
 
+// knownMetadataHeaders returns names of the metadata headers which are used
+// by daemon including ones set in configuration.
+func knownMetadataHeaders() []string {
+	serviceKeyHeaders := handler.GetServiceKeyHeaders()
+	headers := []string{
+		handler.PaymentTypeHeader,
+		handler.OfflineAuthTokenHeader,
+		serviceKeyHeaders.OrganizationID,
+		serviceKeyHeaders.ServiceID,
+		escrow.PaymentChannelNonceHeader,
+		escrow.PaymentChannelAmountHeader,
+		escrow.PaymentChannelSignatureHeader,
+		escrow.PaymentCurrencyHeader,
+		escrow.GetInvoiceIDHeader(),
+		escrow.GetJobValueHeader(),
+		tracing.TraceparentHeader,
+		tracing.TracestateHeader,
+	}
+	return append(headers, escrow.GetPaymentChannelIDHeaders()...)
+}
+
+// RateLimiter returns limiter of the incoming calls rate. If
+// persist_rate_limits is set then limiter state is kept in the storage and
+// restored after restart. New rate_limit_per_minute and burst_size are
+// applied on configuration reload keeping tokens available.
+func (components *Components) RateLimiter() *ratelimit.ReloadableLimiter {
+	if components.rateLimiter != nil {
+		return components.rateLimiter
+	}
+
+	limiter := ratelimit.NewRateLimiter()
+	components.rateLimiter = ratelimit.NewReloadableLimiter(&limiter)
+	components.rateLimiter.SetIdleBurst(config.GetInt(config.IdleBurstSizeKey), config.GetDuration(config.IdleThresholdKey))
+	config.AddReloadListener(func() {
+		limiter := ratelimit.NewRateLimiter()
+		components.rateLimiter.Update(&limiter, time.Now())
+		components.rateLimiter.SetIdleBurst(config.GetInt(config.IdleBurstSizeKey), config.GetDuration(config.IdleThresholdKey))
+		log.WithField("limit", limiter.Limit()).WithField("burst", limiter.Burst()).Info("Rate limiter settings reloaded")
+	})
+
+	if config.GetBool(config.PersistRateLimitsKey) {
+		persister := ratelimit.NewLimiterPersister(components.rateLimiter, components.AtomicStorage(),
+			"/rate-limit/"+config.GetString(config.DaemonEndPoint))
+		if err := persister.Restore(); err != nil {
+			log.WithError(err).Warn("Cannot restore rate limiter state")
+		}
+		persister.Start(ratelimit.DefaultSaveInterval)
+		components.rateLimiterPersister = persister
+	}
+
+	return components.rateLimiter
+}
+
+// WorkerPool returns pool which processes calls or nil if
+// handler_worker_count is not set.
+func (components *Components) WorkerPool() *handler.WorkerPool {
+	if components.workerPool != nil {
+		return components.workerPool
+	}
+
+	workerCount := config.GetInt(config.HandlerWorkerCountKey)
+	if workerCount == 0 {
+		return nil
+	}
+
+	queueSize := config.GetInt(config.HandlerQueueSizeKey)
+	log.WithField("workerCount", workerCount).WithField("queueSize", queueSize).Info("Calls are processed by worker pool")
+	components.workerPool = handler.NewWorkerPool(workerCount, queueSize)
+	return components.workerPool
+}
+
 func (components *Components) GrpcPaymentValidationInterceptor() grpc.StreamServerInterceptor {
 	if !components.Blockchain().Enabled() {
-		log.Info("Blockchain is disabled: no payment validation")
+		if config.GetString(config.OfflineAuthModeKey) == config.OfflineAuthModeToken {
+			log.Info("Blockchain is disabled: calls are authorized by offline tokens")
+			return handler.GrpcPaymentValidationInterceptor(handler.NewOfflineTokenPaymentHandler(config.GetOfflineAuthTokens()))
+		}
+		log.Info("Blockchain is disabled: no payment validation, all calls are free")
 		return handler.NoOpInterceptor
 	} else {
 		log.Info("Blockchain is enabled: instantiate payment validation interceptor")
+		log.WithField("validators", components.DescribeValidators()).Info("Income validators configured")
 		return handler.GrpcPaymentValidationInterceptor(components.EscrowPaymentHandler())
 	}
 }
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/blockchain"
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+	"github.com/singnet/snet-daemon/handler"
+	"github.com/singnet/snet-daemon/tracing"
+)
+
+func testLocalMetadata() *blockchain.ServiceMetadata {
+	metadata := &blockchain.ServiceMetadata{}
+	metadata.Pricing.PriceInCogs = big.NewInt(17)
+	return metadata
+}
+
+func TestLocalMetadataPriceOfDefaultService(t *testing.T) {
+	defer config.WithDefaultConfig()()
+	config.Vip().Set(config.OrganizationId, "org")
+	config.Vip().Set(config.ServiceId, "first")
+
+	price, err := localMetadataPrice(&config.ServiceConf{OrganizationID: "org", ServiceID: "first"}, testLocalMetadata)
+
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(17), price)
+}
+
+func TestLocalMetadataPriceOfOtherService(t *testing.T) {
+	defer config.WithDefaultConfig()()
+	config.Vip().Set(config.OrganizationId, "org")
+	config.Vip().Set(config.ServiceId, "first")
+
+	_, err := localMetadataPrice(&config.ServiceConf{OrganizationID: "org", ServiceID: "second"}, testLocalMetadata)
+
+	assert.EqualError(t, err, "price_in_cogs or price should be set for service org/second as its metadata cannot be read when blockchain is disabled")
+}
+
+func TestKnownMetadataHeadersContainTraceContext(t *testing.T) {
+	defer config.WithDefaultConfig()()
+
+	headers := knownMetadataHeaders()
+
+	assert.Contains(t, headers, tracing.TraceparentHeader)
+	assert.Contains(t, headers, tracing.TracestateHeader)
+	assert.Contains(t, headers, handler.OrganizationIDHeader)
+	assert.Contains(t, headers, escrow.PaymentChannelIDHeader)
+}
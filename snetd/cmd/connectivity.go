@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/cobra"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/etcddb"
+)
+
+// connectivityTimeout is a time each dependency is probed for.
+const connectivityTimeout = 10 * time.Second
+
+// CheckCmd checks connectivity to the dependencies of the daemon
+var CheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check connectivity to the configured dependencies",
+	Long: "Use this command to check that Ethereum JSON-RPC endpoint, IPFS," +
+		" etcd cluster and passthrough endpoints configured in the" +
+		" configuration file are reachable before starting the daemon.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RunAndCleanup(cmd, args, newCheckCommand)
+	},
+}
+
+type checkCommand struct{}
+
+func newCheckCommand(cmd *cobra.Command, args []string, components *Components) (command Command, err error) {
+	return &checkCommand{}, nil
+}
+
+func (command *checkCommand) Run() error {
+	results := DiagnoseConnectivity(context.Background())
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := 0
+	for _, name := range names {
+		if err := results[name]; err != nil {
+			failed++
+			fmt.Printf("%v: FAILED: %v\n", name, err)
+		} else {
+			fmt.Printf("%v: OK\n", name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v dependencies are unreachable", failed, len(results))
+	}
+	return nil
+}
+
+// DiagnoseConnectivity probes dependencies which are enabled in the
+// configuration and returns result of each probe by dependency name, nil
+// result means that dependency is reachable.
+func DiagnoseConnectivity(ctx context.Context) map[string]error {
+	probes := connectivityProbes()
+
+	results := make(map[string]error, len(probes))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, probe := range probes {
+		wg.Add(1)
+		go func(name string, probe func(ctx context.Context) error) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, connectivityTimeout)
+			defer cancel()
+			err := probe(ctx)
+			mutex.Lock()
+			results[name] = err
+			mutex.Unlock()
+		}(name, probe)
+	}
+	wg.Wait()
+	return results
+}
+
+func connectivityProbes() map[string]func(ctx context.Context) error {
+	probes := make(map[string]func(ctx context.Context) error)
+
+	if config.GetBool(config.BlockchainEnabledKey) {
+		ethereumEndpoint := config.GetString(config.EthereumJsonRpcEndpointKey)
+		probes["ethereum"] = func(ctx context.Context) error {
+			return probeEthereum(ctx, ethereumEndpoint)
+		}
+		ipfsEndpoint := config.GetString(config.IpfsEndPoint)
+		probes["ipfs"] = func(ctx context.Context) error {
+			return probeHTTP(ctx, "POST", strings.TrimSuffix(ipfsEndpoint, "/")+"/api/v0/version")
+		}
+	}
+
+	if config.GetString(config.PaymentChannelStorageTypeKey) == "etcd" {
+		conf, err := etcddb.GetEtcdClientConf(config.Vip())
+		if err != nil {
+			probes["etcd"] = func(ctx context.Context) error {
+				return fmt.Errorf("cannot read %v: %v", config.PaymentChannelStorageClientKey, err)
+			}
+		} else {
+			for _, endpoint := range conf.Endpoints {
+				endpoint := endpoint
+				probes["etcd "+endpoint] = func(ctx context.Context) error {
+					return probeHTTP(ctx, "GET", strings.TrimSuffix(endpoint, "/")+"/health")
+				}
+			}
+		}
+	}
+
+	services, err := config.GetServices()
+	if err != nil {
+		probes["passthrough"] = func(ctx context.Context) error {
+			return err
+		}
+	}
+	for _, service := range services {
+		if !service.PassthroughEnabled || service.PassthroughEndpoint == "" {
+			continue
+		}
+		endpoint := service.PassthroughEndpoint
+		probes["passthrough "+endpoint] = func(ctx context.Context) error {
+			return probeTCP(ctx, endpoint)
+		}
+	}
+
+	return probes
+}
+
+func probeEthereum(ctx context.Context, endpoint string) error {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var currentBlock string
+	return client.CallContext(ctx, &currentBlock, "eth_blockNumber")
+}
+
+func probeHTTP(ctx context.Context, method, address string) error {
+	req, err := http.NewRequest(method, address, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status: %v", resp.Status)
+	}
+	return nil
+}
+
+// probeTCP checks that TCP connection can be opened to the endpoint which is
+// either URL or host:port pair.
+func probeTCP(ctx context.Context, endpoint string) error {
+	address := endpoint
+	if parsed, err := url.Parse(endpoint); err == nil && parsed.Host != "" {
+		address = parsed.Host
+		if parsed.Port() == "" {
+			port := "80"
+			if parsed.Scheme == "https" {
+				port = "443"
+			}
+			address = net.JoinHostPort(parsed.Hostname(), port)
+		}
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+func newEthereumServerMock() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+}
+
+func newStatusServerMock(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(status)
+	}))
+}
+
+// unreachableURL returns URL of the closed server.
+func unreachableURL() string {
+	server := newStatusServerMock(http.StatusOK)
+	server.Close()
+	return server.URL
+}
+
+// setConfig sets config values and returns function which restores
+// previous values.
+func setConfig(values map[string]interface{}) (restore func()) {
+	previous := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		previous[key] = config.Vip().Get(key)
+		config.Vip().Set(key, value)
+	}
+	return func() {
+		for key, value := range previous {
+			config.Vip().Set(key, value)
+		}
+	}
+}
+
+func TestDiagnoseConnectivity(t *testing.T) {
+	ethereum := newEthereumServerMock()
+	defer ethereum.Close()
+	etcdHealthy := newStatusServerMock(http.StatusOK)
+	defer etcdHealthy.Close()
+	etcdUnhealthy := newStatusServerMock(http.StatusServiceUnavailable)
+	defer etcdUnhealthy.Close()
+	passthrough, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer passthrough.Close()
+	ipfs := unreachableURL()
+
+	defer setConfig(map[string]interface{}{
+		config.BlockchainEnabledKey:         true,
+		config.EthereumJsonRpcEndpointKey:   ethereum.URL,
+		config.IpfsEndPoint:                 ipfs,
+		config.PaymentChannelStorageTypeKey: "etcd",
+		config.PaymentChannelStorageClientKey: map[string]interface{}{
+			"endpoints": []string{etcdHealthy.URL, etcdUnhealthy.URL},
+		},
+		config.PassthroughEnabledKey:  true,
+		config.PassthroughEndpointKey: "http://" + passthrough.Addr().String(),
+	})()
+
+	results := DiagnoseConnectivity(context.Background())
+
+	assert.Equal(t, 5, len(results))
+	assert.Nil(t, results["ethereum"])
+	assert.NotNil(t, results["ipfs"])
+	assert.Nil(t, results["etcd "+etcdHealthy.URL])
+	assert.EqualError(t, results["etcd "+etcdUnhealthy.URL], "unexpected HTTP status: 503 Service Unavailable")
+	assert.Nil(t, results["passthrough http://"+passthrough.Addr().String()])
+}
+
+func TestDiagnoseConnectivityBlockchainDisabled(t *testing.T) {
+	passthrough := unreachableURL()
+	defer setConfig(map[string]interface{}{
+		config.BlockchainEnabledKey:         false,
+		config.PaymentChannelStorageTypeKey: "memory",
+		config.PassthroughEnabledKey:        true,
+		config.PassthroughEndpointKey:       passthrough,
+	})()
+
+	results := DiagnoseConnectivity(context.Background())
+
+	assert.Equal(t, 1, len(results))
+	assert.NotNil(t, results["passthrough "+passthrough])
+}
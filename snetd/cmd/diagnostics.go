@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+	log "github.com/sirupsen/logrus"
+)
+
+// diagnosticsPath is a path of the HTTP endpoint which returns diagnostics
+// report.
+const diagnosticsPath = "/diagnostics"
+
+// diagnosticsReport describes effective runtime state of the daemon.
+type diagnosticsReport struct {
+	Version    BuildInfo                   `json:"version"`
+	Config     map[string]interface{}      `json:"config"`
+	Health     map[string]dependencyHealth `json:"health"`
+	Validators []escrow.ValidatorInfo      `json:"validators,omitempty"`
+}
+
+// dependencyHealth is a result of the dependency check.
+type dependencyHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// diagnosticsHandler serves diagnostics report to the clients which pass
+// token in "Authorization: Bearer <token>" header.
+type diagnosticsHandler struct {
+	token      string
+	checks     map[string]func() error
+	validators func() []escrow.ValidatorInfo
+}
+
+// newDiagnosticsHandler returns handler of the diagnostics endpoint or nil
+// if diagnostics_enabled is false.
+func newDiagnosticsHandler(components *Components) http.Handler {
+	if !config.GetBool(config.DiagnosticsEnabledKey) {
+		return nil
+	}
+
+	handler := &diagnosticsHandler{
+		token: config.GetString(config.DiagnosticsTokenKey),
+		checks: map[string]func() error{
+			"storage": func() error {
+				_, _, err := components.AtomicStorage().Get("health")
+				return err
+			},
+		},
+	}
+	if components.Blockchain().Enabled() {
+		handler.checks["blockchain"] = func() error {
+			_, err := components.Blockchain().CurrentBlock(context.Background())
+			return err
+		}
+		handler.validators = components.DescribeValidators
+	}
+	log.Warn("Diagnostics endpoint is enabled")
+	return handler
+}
+
+func (handler *diagnosticsHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if !bearerTokenMatches(req, handler.token) {
+		http.Error(resp, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(resp)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(handler.report()); err != nil {
+		log.WithError(err).Error("Cannot write diagnostics report")
+	}
+}
+
+// bearerTokenMatches returns true if request contains
+// "Authorization: Bearer <token>" header with the given token.
+func bearerTokenMatches(req *http.Request, token string) bool {
+	const prefix = "Bearer "
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, prefix)), []byte(token)) == 1
+}
+
+func (handler *diagnosticsHandler) report() *diagnosticsReport {
+	report := &diagnosticsReport{
+		Version: GetBuildInfo(),
+		Config:  config.GetMaskedSettings(),
+		Health:  make(map[string]dependencyHealth),
+	}
+	for name, check := range handler.checks {
+		if err := check(); err != nil {
+			report.Health[name] = dependencyHealth{Status: "error", Error: err.Error()}
+		} else {
+			report.Health[name] = dependencyHealth{Status: "ok"}
+		}
+	}
+	if handler.validators != nil {
+		report.Validators = handler.validators()
+	}
+	return report
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+func newTestDiagnosticsHandler() *diagnosticsHandler {
+	return &diagnosticsHandler{
+		token: "secret-token",
+		checks: map[string]func() error{
+			"storage":    func() error { return nil },
+			"blockchain": func() error { return errors.New("connection refused") },
+		},
+		validators: func() []escrow.ValidatorInfo {
+			return []escrow.ValidatorInfo{{Type: "price"}}
+		},
+	}
+}
+
+func requestDiagnostics(handler http.Handler, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", diagnosticsPath, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestDiagnosticsDisabledByDefault(t *testing.T) {
+	assert.Nil(t, newDiagnosticsHandler(&Components{}))
+}
+
+func TestDiagnosticsReport(t *testing.T) {
+	config.Vip().Set(config.PrivateKeyKey, "0123456789abcdef")
+	defer config.Vip().Set(config.PrivateKeyKey, "")
+
+	resp := requestDiagnostics(newTestDiagnosticsHandler(), "Bearer secret-token")
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	var report map[string]interface{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.Equal(t, "dev", report["version"].(map[string]interface{})["version"])
+	assert.Equal(t, "***", report["config"].(map[string]interface{})["private_key"])
+	assert.Equal(t, "grpc", report["config"].(map[string]interface{})["daemon_type"])
+	assert.Equal(t, map[string]interface{}{
+		"storage":    map[string]interface{}{"status": "ok"},
+		"blockchain": map[string]interface{}{"status": "error", "error": "connection refused"},
+	}, report["health"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "price"}}, report["validators"])
+}
+
+func TestDiagnosticsRejectsIncorrectToken(t *testing.T) {
+	resp := requestDiagnostics(newTestDiagnosticsHandler(), "Bearer incorrect-token")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestDiagnosticsRejectsMissingToken(t *testing.T) {
+	resp := requestDiagnostics(newTestDiagnosticsHandler(), "")
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
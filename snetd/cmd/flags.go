@@ -56,7 +56,8 @@ const (
 )
 
 var (
-	cfgFile = RootCmd.PersistentFlags().StringP("config", "c", "snetd.config.json", "config file")
+	cfgFile   = RootCmd.PersistentFlags().StringP("config", "c", "snetd.config.json", "config file")
+	cfgSigner = RootCmd.PersistentFlags().String("config-signer", "", "Ethereum address which should sign config file; signature is read from <config file>.sig")
 
 	autoSSLDomain      = ServeCmd.PersistentFlags().String("auto-ssl-domain", "", "enable SSL via LetsEncrypt for this domain (requires root)")
 	autoSSLCacheDir    = ServeCmd.PersistentFlags().String("auto-ssl-cache", ".certs", "auto-SSL certificate cache directory")
@@ -82,12 +83,14 @@ var (
 
 func init() {
 	serveCmdFlags := ServeCmd.PersistentFlags()
-	vip := config.Vip()
 
 	RootCmd.AddCommand(InitCmd)
 	RootCmd.AddCommand(ServeCmd)
 	RootCmd.AddCommand(ClaimCmd)
 	RootCmd.AddCommand(ListCmd)
+	RootCmd.AddCommand(ReplayCmd)
+	RootCmd.AddCommand(SchemaCmd)
+	RootCmd.AddCommand(CheckCmd)
 
 	ListCmd.AddCommand(ListChannelsCmd)
 	ListCmd.AddCommand(ListClaimsCmd)
@@ -99,17 +102,17 @@ func init() {
 		" timeout is specified as a sequence of decimal number with unit suffix;"+
 		" valid time units are \"ns\", \"us\", \"ms\", \"s\", \"m\", \"h\"")
 
-	vip.BindPFlag(config.AutoSSLDomainKey, serveCmdFlags.Lookup("auto-ssl-domain"))
-	vip.BindPFlag(config.AutoSSLCacheDirKey, serveCmdFlags.Lookup("auto-ssl-cache"))
-	vip.BindPFlag(config.DaemonTypeKey, serveCmdFlags.Lookup("type"))
-	vip.BindPFlag(config.BlockchainEnabledKey, serveCmdFlags.Lookup("blockchain"))
-
-	vip.BindPFlag(config.EthereumJsonRpcEndpointKey, serveCmdFlags.Lookup("ethereum-endpoint"))
-	vip.BindPFlag(config.HdwalletMnemonicKey, serveCmdFlags.Lookup("mnemonic"))
-	vip.BindPFlag(config.HdwalletIndexKey, serveCmdFlags.Lookup("wallet-index"))
-	vip.BindPFlag(config.PassthroughEnabledKey, serveCmdFlags.Lookup("passthrough"))
-	vip.BindPFlag(config.SSLCertPathKey, serveCmdFlags.Lookup("ssl-cert"))
-	vip.BindPFlag(config.SSLKeyPathKey, serveCmdFlags.Lookup("ssl-key"))
+	config.BindPFlag(config.AutoSSLDomainKey, serveCmdFlags.Lookup("auto-ssl-domain"))
+	config.BindPFlag(config.AutoSSLCacheDirKey, serveCmdFlags.Lookup("auto-ssl-cache"))
+	config.BindPFlag(config.DaemonTypeKey, serveCmdFlags.Lookup("type"))
+	config.BindPFlag(config.BlockchainEnabledKey, serveCmdFlags.Lookup("blockchain"))
+
+	config.BindPFlag(config.EthereumJsonRpcEndpointKey, serveCmdFlags.Lookup("ethereum-endpoint"))
+	config.BindPFlag(config.HdwalletMnemonicKey, serveCmdFlags.Lookup("mnemonic"))
+	config.BindPFlag(config.HdwalletIndexKey, serveCmdFlags.Lookup("wallet-index"))
+	config.BindPFlag(config.PassthroughEnabledKey, serveCmdFlags.Lookup("passthrough"))
+	config.BindPFlag(config.SSLCertPathKey, serveCmdFlags.Lookup("ssl-cert"))
+	config.BindPFlag(config.SSLKeyPathKey, serveCmdFlags.Lookup("ssl-key"))
 
 	cobra.OnInitialize(func() {
 
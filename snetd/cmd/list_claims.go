@@ -14,7 +14,9 @@ var ListClaimsCmd = &cobra.Command{
 	Short: "List payments which are not written to blockchain yet",
 	Long: "List payments which are in progress state and not written" +
 		" to the blockchain. 'snetd claim --payment-id' command can be" +
-		" used to retry writing to the blockchain.",
+		" used to retry writing to the blockchain. Payments which claims are" +
+		" marked as failed by claim_revert_policy are listed with (failed) mark" +
+		" and are not retried.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return RunAndCleanup(cmd, args, newListClaimsCommand)
 	},
@@ -38,7 +40,12 @@ func (command *listClaimsCommand) Run() (err error) {
 		return
 	}
 
-	if len(claims) == 0 {
+	failed, err := command.channelService.ListFailedClaims()
+	if err != nil {
+		return
+	}
+
+	if len(claims) == 0 && len(failed) == 0 {
 		fmt.Println("no claims in shared storage")
 	}
 
@@ -46,6 +53,9 @@ func (command *listClaimsCommand) Run() (err error) {
 		payment := claim.Payment()
 		fmt.Printf("%v: %v\n", payment.ID(), payment)
 	}
+	for _, payment := range failed {
+		fmt.Printf("%v (failed): %v\n", payment.ID(), payment)
+	}
 
 	return nil
 }
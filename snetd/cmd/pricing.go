@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+	log "github.com/sirupsen/logrus"
+)
+
+// pricingPath is a path of the HTTP endpoint which returns current prices of
+// the service methods.
+const pricingPath = "/pricing"
+
+// pricingReport contains effective prices of each service served by daemon.
+type pricingReport struct {
+	Services []servicePricing `json:"services"`
+}
+
+// servicePricing contains prices in cogs by full gRPC method name, price of
+// the methods which are not listed is set under "default" key. Error is set
+// when prices cannot be determined at the moment.
+type servicePricing struct {
+	OrganizationID string            `json:"organization_id"`
+	ServiceID      string            `json:"service_id"`
+	Prices         escrow.PriceTable `json:"prices,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+type pricedService struct {
+	conf     *config.ServiceConf
+	provider escrow.PriceProvider
+}
+
+// pricingHandler serves prices which are currently used to validate
+// payments, so pricing_file reload and metadata price updates are reflected.
+type pricingHandler struct {
+	services []pricedService
+}
+
+func newPricingHandler(components *Components) http.Handler {
+	services, err := config.GetServices()
+	if err != nil {
+		log.WithError(err).Panic("error reading services configuration")
+	}
+
+	handler := &pricingHandler{}
+	for _, service := range services {
+		handler.services = append(handler.services, pricedService{
+			conf:     service,
+			provider: components.servicePriceProvider(service),
+		})
+	}
+	return handler
+}
+
+func (handler *pricingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Access-Control-Allow-Origin", "*")
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(handler.report()); err != nil {
+		log.WithError(err).Error("Cannot write pricing report")
+	}
+}
+
+func (handler *pricingHandler) report() *pricingReport {
+	report := &pricingReport{Services: []servicePricing{}}
+	for _, service := range handler.services {
+		pricing := servicePricing{
+			OrganizationID: service.conf.OrganizationID,
+			ServiceID:      service.conf.ServiceID,
+		}
+		table, err := escrow.EffectivePriceTable(service.provider)
+		if err != nil {
+			pricing.Error = err.Error()
+		} else {
+			pricing.Prices = table
+		}
+		report.Services = append(report.Services, pricing)
+	}
+	return report
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+type failingPriceProvider struct{}
+
+func (provider *failingPriceProvider) GetPriceInCogs(method string) (*big.Int, error) {
+	return nil, errors.New("metadata is not available")
+}
+
+func requestPricing(handler *pricingHandler) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", pricingPath, nil))
+	return resp
+}
+
+func TestPricingReturnsCurrentTable(t *testing.T) {
+	provider := escrow.NewReloadablePriceProvider(escrow.PriceTable{
+		"default":                         big.NewInt(10),
+		"/example_service.Calculator/add": big.NewInt(20),
+	})
+	handler := &pricingHandler{services: []pricedService{
+		{conf: &config.ServiceConf{OrganizationID: "org", ServiceID: "calculator"}, provider: provider},
+	}}
+
+	resp := requestPricing(handler)
+
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+	assert.JSONEq(t, `{"services": [{"organization_id": "org", "service_id": "calculator", "prices": {"default": 10, "/example_service.Calculator/add": 20}}]}`, resp.Body.String())
+}
+
+func TestPricingReflectsReload(t *testing.T) {
+	provider := escrow.NewReloadablePriceProvider(escrow.PriceTable{"default": big.NewInt(10)})
+	handler := &pricingHandler{services: []pricedService{
+		{conf: &config.ServiceConf{OrganizationID: "org", ServiceID: "calculator"}, provider: provider},
+	}}
+	requestPricing(handler)
+
+	provider.Update(escrow.PriceTable{"default": big.NewInt(15)})
+	resp := requestPricing(handler)
+
+	assert.JSONEq(t, `{"services": [{"organization_id": "org", "service_id": "calculator", "prices": {"default": 15}}]}`, resp.Body.String())
+}
+
+func TestPricingOfFewServices(t *testing.T) {
+	handler := &pricingHandler{services: []pricedService{
+		{conf: &config.ServiceConf{OrganizationID: "org", ServiceID: "calculator"}, provider: escrow.NewFixedPriceProvider(big.NewInt(7))},
+		{conf: &config.ServiceConf{OrganizationID: "org", ServiceID: "translator"}, provider: &failingPriceProvider{}},
+	}}
+
+	resp := requestPricing(handler)
+
+	assert.JSONEq(t, `{"services": [
+		{"organization_id": "org", "service_id": "calculator", "prices": {"default": 7}},
+		{"organization_id": "org", "service_id": "translator", "error": "metadata is not available"}
+	]}`, resp.Body.String())
+}
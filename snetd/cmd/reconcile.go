@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+// channelReconcilerLoop periodically reconciles stored channels with
+// blockchain.
+type channelReconcilerLoop struct {
+	reconcile func(ctx context.Context) (updated int, err error)
+	interval  time.Duration
+
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// newChannelReconcilerLoop returns reconciler configured by
+// reconcile_interval and reconcile_concurrency or nil if reconciliation is
+// disabled or daemon runs in replica_mode, in the latter case channels are
+// reconciled by the primary daemon.
+func newChannelReconcilerLoop(components *Components) *channelReconcilerLoop {
+	interval := config.GetDuration(config.ReconcileIntervalKey)
+	if interval <= 0 || config.GetBool(config.ReplicaModeKey) {
+		return nil
+	}
+	if !components.Blockchain().Enabled() {
+		log.Warn("Channels are not reconciled as blockchain is disabled")
+		return nil
+	}
+
+	reconciler := escrow.NewChannelReconciler(
+		escrow.NewPaymentChannelStorage(components.AtomicStorage()),
+		escrow.NewBlockchainChannelReader(components.Blockchain(), config.Vip(), components.allServicesMetaData()...),
+		config.GetInt(config.ReconcileConcurrencyKey),
+	)
+	return &channelReconcilerLoop{reconcile: reconciler.Reconcile, interval: interval}
+}
+
+func (loop *channelReconcilerLoop) start() {
+	loop.stopChan = make(chan struct{})
+	loop.done = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-loop.stopChan
+		cancel()
+	}()
+	go func() {
+		defer close(loop.done)
+
+		ticker := time.NewTicker(loop.interval)
+		defer ticker.Stop()
+		for {
+			if updated, err := loop.reconcile(ctx); err != nil {
+				log.WithError(err).Warn("Cannot reconcile channels with blockchain")
+			} else if updated > 0 {
+				log.WithField("updated", updated).Info("Channels are reconciled with blockchain")
+			}
+			select {
+			case <-ticker.C:
+			case <-loop.stopChan:
+				return
+			}
+		}
+	}()
+	log.WithField("interval", loop.interval).Info("Channels are reconciled with blockchain periodically")
+}
+
+func (loop *channelReconcilerLoop) stop() {
+	close(loop.stopChan)
+	<-loop.done
+}
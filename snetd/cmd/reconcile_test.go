@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelReconcilerLoopReconcilesOnStart(t *testing.T) {
+	calls := 0
+	loop := &channelReconcilerLoop{
+		reconcile: func(ctx context.Context) (int, error) {
+			calls++
+			return 0, errors.New("storage error")
+		},
+		interval: time.Hour,
+	}
+
+	loop.start()
+	loop.stop()
+
+	assert.Equal(t, 1, calls)
+}
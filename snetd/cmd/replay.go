@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/singnet/snet-daemon/escrow"
+)
+
+// ReplayCmd replays recorded income validation decisions
+var ReplayCmd = &cobra.Command{
+	Use:   "replay <records file>",
+	Short: "Replay recorded income validation decisions against current configuration",
+	Long: "Replay command reads calls recorded using" +
+		" income_validation_record_file, runs them through income validator" +
+		" built from the current configuration and prints calls for which" +
+		" decision is changed. It is used to test pricing changes.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RunAndCleanup(cmd, args, newReplayCommand)
+	},
+}
+
+type replayCommand struct {
+	recordsFile string
+	validator   escrow.IncomeValidator
+}
+
+func newReplayCommand(cmd *cobra.Command, args []string, components *Components) (command Command, err error) {
+	command = &replayCommand{
+		recordsFile: args[0],
+		validator:   components.ReplayIncomeValidator(),
+	}
+
+	return
+}
+
+func (command *replayCommand) Run() (err error) {
+	file, err := os.Open(command.recordsFile)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	records, err := escrow.ReadValidationRecords(file)
+	if err != nil {
+		return
+	}
+
+	changed := 0
+	for _, result := range escrow.ReplayValidationRecords(records, command.validator) {
+		if result.Changed() {
+			changed++
+			fmt.Printf("%v, income: %v: %+v -> %+v\n", result.Record.Method, result.Record.Income, result.Record.Decision, result.Decision)
+		}
+	}
+	fmt.Printf("%v of %v decisions changed\n", changed, len(records))
+
+	return nil
+}
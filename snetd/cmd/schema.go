@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+// SchemaCmd prints JSON schema of the configuration file
+var SchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print JSON schema of the configuration file",
+	Long: "Use this command to get JSON schema of the configuration file for" +
+		" editor autocompletion or to validate configuration in CI.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := config.GenerateJSONSchema()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(schema))
+		return nil
+	},
+}
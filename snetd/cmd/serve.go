@@ -9,6 +9,7 @@ import (
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/gorilla/handlers"
@@ -16,6 +17,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/singnet/snet-daemon/blockchain"
 	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/connlimit"
 	"github.com/singnet/snet-daemon/escrow"
 	"github.com/singnet/snet-daemon/handler"
 	"github.com/singnet/snet-daemon/handler/httphandler"
@@ -40,46 +42,115 @@ var ServeCmd = &cobra.Command{
 		components := InitComponents(cmd)
 		defer components.Close()
 
-		etcdServer := components.EtcdServer()
-		if etcdServer == nil {
-			log.Info("Etcd server is disabled in the config file.")
-		}
+		var d *daemon
+		err = runWithStartupTimeout(config.GetDuration(config.StartupTimeoutKey), processStartTime, func() (err error) {
+			etcdServer := components.EtcdServer()
+			if etcdServer == nil {
+				log.Info("Etcd server is disabled in the config file.")
+			}
 
-		err = logger.InitLogger(config.SubWithDefault(config.Vip(), config.LogKey))
-		if err != nil {
-			log.WithError(err).Fatal("Unable to initialize logger")
-		}
-		config.LogConfig()
+			err = logger.InitLogger(config.SubWithDefault(config.Vip(), config.LogKey))
+			if err != nil {
+				return errors.Wrap(err, "unable to initialize logger")
+			}
+			logBuildInfo()
+			config.LogConfig()
 
-		var d daemon
-		d, err = newDaemon(components)
+			d, err = newDaemon(components)
+			if err != nil {
+				return errors.Wrap(err, "unable to initialize daemon")
+			}
+
+			d.start()
+			config.AddReloadListener(func() {
+				if err := d.rebind(config.GetString(config.DaemonEndPoint)); err != nil {
+					log.WithError(err).Error("Unable to listen new daemon_end_point, previous port is used")
+				}
+			})
+			return nil
+		})
 		if err != nil {
-			log.WithError(err).Fatal("Unable to initialize daemon")
+			log.WithError(err).Fatal("Unable to start daemon")
 		}
-
-		d.start()
 		defer d.stop()
 
+		if claimer := newAutoClaimer(components); claimer != nil {
+			claimer.start()
+			defer claimer.stop()
+		}
+		if deliverer := newWebhookDeliverer(components); deliverer != nil {
+			deliverer.start()
+			defer deliverer.stop()
+		}
+		if watcher := newChannelEventWatcher(components); watcher != nil {
+			watcher.start()
+			defer watcher.stop()
+		}
+		if reconciler := newChannelReconcilerLoop(components); reconciler != nil {
+			reconciler.start()
+			defer reconciler.stop()
+		}
+
 		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
-		<-sigChan
+		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+		if config.GetBool(config.ShutdownOnConfigRemovalKey) {
+			stopWatch := watchConfigRemoval(sigChan)
+			defer stopWatch()
+		}
+
+		for sig := <-sigChan; sig == syscall.SIGHUP; sig = <-sigChan {
+			if err := config.ReloadConfig(); err != nil {
+				log.WithError(err).Error("Unable to reload configuration")
+			} else {
+				log.Info("Configuration reloaded")
+			}
+		}
 
 		log.Debug("exiting")
 	},
 }
 
+// watchConfigRemoval sends SIGTERM to the sigChan when configuration file
+// is removed.
+func watchConfigRemoval(sigChan chan os.Signal) (stop func()) {
+	configFile := config.ConfigFileUsed()
+	if configFile == "" {
+		log.Warn("No configuration file is loaded, shutdown on configuration removal is disabled")
+		return func() {}
+	}
+
+	stop, err := config.WatchFileRemoval(configFile, func() {
+		log.WithField("configFile", configFile).Warn("Configuration file is removed, shutting down")
+		select {
+		case sigChan <- syscall.SIGTERM:
+		default:
+		}
+	})
+	if err != nil {
+		log.WithError(err).Fatal("Unable to watch configuration file")
+	}
+	return stop
+}
+
 type daemon struct {
 	autoSSLDomain string
 	acmeListener  net.Listener
 	grpcServer    *grpc.Server
 	blockProc     blockchain.Processor
-	lis           net.Listener
-	sslCert       *tls.Certificate
+	certWatcher   *config.CertificateWatcher
 	components    *Components
+	tlsConfig     *tls.Config
+	httpHandler   http.Handler
+	// lis accepts connections on port, both are changed when
+	// daemon_end_point is changed on configuration reload
+	lis      net.Listener
+	port     string
+	lisMutex sync.Mutex
 }
 
-func newDaemon(components *Components) (daemon, error) {
-	d := daemon{}
+func newDaemon(components *Components) (*daemon, error) {
+	d := &daemon{}
 
 	if err := config.Validate(); err != nil {
 		return d, err
@@ -94,10 +165,11 @@ func newDaemon(components *Components) (daemon, error) {
 	}
 	log.WithField("port", port).Info("Starting listening port")
 
-	d.lis, err = net.Listen("tcp", fmt.Sprintf("0.0.0.0:%+v", port))
+	d.lis, err = listenDaemonPort(port)
 	if err != nil {
 		return d, errors.Wrap(err, "error listening")
 	}
+	d.port = port
 
 	d.autoSSLDomain = config.GetString(config.AutoSSLDomainKey)
 	// In order to perform the LetsEncrypt (ACME) http-01 challenge-response, we need to bind
@@ -112,11 +184,10 @@ func newDaemon(components *Components) (daemon, error) {
 	d.blockProc = *components.Blockchain()
 
 	if sslKey := config.GetString(config.SSLKeyPathKey); sslKey != "" {
-		cert, err := tls.LoadX509KeyPair(config.GetString(config.SSLCertPathKey), sslKey)
+		d.certWatcher, err = config.NewCertificateWatcher(config.GetString(config.SSLCertPathKey), sslKey)
 		if err != nil {
 			return d, errors.Wrap(err, "unable to load specifiec SSL X509 keypair")
 		}
-		d.sslCert = &cert
 	}
 
 	return d, nil
@@ -135,7 +206,7 @@ func deriveDaemonPort(daemonEndpoint string) (string, error) {
 	length := len(splitString)
 	if length == 2 {
 		port = splitString[len(splitString)-1]
-		_, err = strconv.ParseInt(port, 0, 16)
+		_, err = strconv.ParseUint(port, 0, 16)
 		if err != nil {
 			log.WithField("daemonEndPoint", daemonEndpoint).Error(err)
 			err = fmt.Errorf("port number <%s> is not valid ,the daemon End point  %s", port, daemonEndpoint)
@@ -146,9 +217,17 @@ func deriveDaemonPort(daemonEndpoint string) (string, error) {
 	return port, err
 }
 
-func (d daemon) start() {
+// listenDaemonPort opens listener of the daemon port, number of connections
+// it accepts is limited by max_connections.
+func listenDaemonPort(port string) (net.Listener, error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%+v", port))
+	if err != nil {
+		return nil, err
+	}
+	return connlimit.NewListener(lis, config.GetInt(config.MaxConnectionsKey), config.GetDuration(config.ConnectionIdleTimeoutKey)), nil
+}
 
-	var tlsConfig *tls.Config
+func (d *daemon) start() {
 
 	if d.autoSSLDomain != "" {
 		log.Debug("enabling automatic SSL support")
@@ -164,53 +243,56 @@ func (d daemon) start() {
 		}
 		go acmeSrv.Serve(d.acmeListener)
 
-		tlsConfig = &tls.Config{
-			GetCertificate: func(c *tls.ClientHelloInfo) (*tls.Certificate, error) {
-				crt, err := certMgr.GetCertificate(c)
-				if err != nil {
-					log.WithError(err).Error("unable to fetch certificate")
-				}
-				return crt, err
-			},
+		d.tlsConfig = newTLSConfig()
+		d.tlsConfig.GetCertificate = func(c *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			crt, err := certMgr.GetCertificate(c)
+			if err != nil {
+				log.WithError(err).Error("unable to fetch certificate")
+			}
+			return crt, err
 		}
-	} else if d.sslCert != nil {
+	} else if d.certWatcher != nil {
 		log.Debug("enabling SSL support via X509 keypair")
-		tlsConfig = &tls.Config{
-			Certificates: []tls.Certificate{*d.sslCert},
-		}
+		d.tlsConfig = newTLSConfig()
+		d.tlsConfig.GetCertificate = d.certWatcher.GetCertificate
 	}
 
-	if tlsConfig != nil {
+	if d.tlsConfig != nil {
 		// See: https://gist.github.com/soheilhy/bb272c000f1987f17063
-		tlsConfig.NextProtos = []string{"http/1.1", http2.NextProtoTLS, "h2-14"}
-
-		// Wrap underlying listener with a TLS listener
-		d.lis = tls.NewListener(d.lis, tlsConfig)
+		d.tlsConfig.NextProtos = []string{"http/1.1", http2.NextProtoTLS, "h2-14"}
 	}
 
 	if config.GetString(config.DaemonTypeKey) == "grpc" {
-		d.grpcServer = grpc.NewServer(
-			grpc.UnknownServiceHandler(handler.NewGrpcHandler(d.components.ServiceMetaData())),
+		options := append(grpcServerLimitOptions(),
+			grpc.UnknownServiceHandler(handler.NewGrpcHandler(d.components.ServiceMetaDataOf)),
 			grpc.StreamInterceptor(d.components.GrpcInterceptor()),
 		)
+		d.grpcServer = grpc.NewServer(options...)
 		escrow.RegisterPaymentChannelStateServiceServer(d.grpcServer, d.components.PaymentChannelStateService())
 
-		mux := cmux.New(d.lis)
-		// Use "prefix" matching to support "application/grpc*" e.g. application/grpc+proto or +json
-		// Use SendSettings for compatibility with Java gRPC clients:
-		//   https://github.com/soheilhy/cmux#limitations
-		grpcL := mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
-		httpL := mux.Match(cmux.HTTP1Fast())
-
 		grpcWebServer := grpcweb.WrapServer(d.grpcServer, grpcweb.WithCorsForRegisteredEndpointsOnly(false))
+		diagnostics := newDiagnosticsHandler(d.components)
+		pricing := newPricingHandler(d.components)
+		subscriptions := newSubscriptionsHandler(d.components)
+		if subscriptions != nil {
+			subscriptions = handler.HTTPMaintenanceHandler(d.components.MaintenanceSchedule(), subscriptions)
+		}
 
-		httpHandler := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		d.httpHandler = http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 			if grpcWebServer.IsGrpcWebRequest(req) || grpcWebServer.IsAcceptableGrpcCorsRequest(req) {
 				grpcWebServer.ServeHTTP(resp, req)
 			} else {
 				if strings.Split(req.URL.Path, "/")[1] == "encoding" {
 					resp.Header().Set("Access-Control-Allow-Origin", "*")
 					fmt.Fprintln(resp, d.components.ServiceMetaData().GetWireEncoding())
+				} else if req.URL.Path == versionPath {
+					serveBuildInfo(resp, req)
+				} else if req.URL.Path == pricingPath {
+					pricing.ServeHTTP(resp, req)
+				} else if diagnostics != nil && req.URL.Path == diagnosticsPath {
+					diagnostics.ServeHTTP(resp, req)
+				} else if subscriptions != nil && req.URL.Path == subscriptionsPath {
+					subscriptions.ServeHTTP(resp, req)
 				} else {
 					http.NotFound(resp, req)
 				}
@@ -218,28 +300,106 @@ func (d daemon) start() {
 		})
 
 		log.Debug("starting daemon")
-
-		go d.grpcServer.Serve(grpcL)
-		go http.Serve(httpL, httpHandler)
-		go mux.Serve()
 	} else {
 		log.Debug("starting simple HTTP daemon")
 
-		go http.Serve(d.lis, handlers.CORS(corsOptions...)(httphandler.NewHTTPHandler(d.blockProc)))
+		d.httpHandler = handlers.CORS(corsOptions...)(handler.HTTPMaintenanceHandler(d.components.MaintenanceSchedule(), httphandler.NewHTTPHandler(d.blockProc)))
+	}
+
+	d.serve(d.lis)
+}
+
+// serve accepts connections of the listener in background. gRPC server is
+// used if it is started, otherwise all connections are HTTP ones.
+func (d *daemon) serve(lis net.Listener) {
+	if d.tlsConfig != nil {
+		// Wrap underlying listener with a TLS listener
+		lis = tls.NewListener(lis, d.tlsConfig)
+	}
+
+	if d.grpcServer == nil {
+		go http.Serve(lis, d.httpHandler)
+		return
+	}
+
+	mux := cmux.New(lis)
+	// Use "prefix" matching to support "application/grpc*" e.g. application/grpc+proto or +json
+	// Use SendSettings for compatibility with Java gRPC clients:
+	//   https://github.com/soheilhy/cmux#limitations
+	grpcL := mux.MatchWithWriters(cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
+	httpL := mux.Match(cmux.HTTP1Fast())
+
+	go d.grpcServer.Serve(grpcL)
+	go http.Serve(httpL, d.httpHandler)
+	go mux.Serve()
+}
+
+// rebind moves daemon to the port of the new daemon_end_point. Listener of
+// the previous port is closed so it doesn't accept new connections, but
+// connections which are already accepted are served, so calls in progress
+// are not interrupted.
+func (d *daemon) rebind(daemonEndpoint string) error {
+	port, err := deriveDaemonPort(daemonEndpoint)
+	if err != nil {
+		return err
+	}
+
+	d.lisMutex.Lock()
+	defer d.lisMutex.Unlock()
+
+	if port == d.port {
+		return nil
+	}
+	lis, err := listenDaemonPort(port)
+	if err != nil {
+		return errors.Wrap(err, "error listening")
 	}
+	d.serve(lis)
+
+	log.WithField("port", port).WithField("previousPort", d.port).Info("Daemon is listening new port, previous port is closed")
+	d.lis.Close()
+	d.lis, d.port = lis, port
+	return nil
 }
 
-func (d daemon) stop() {
+// maxConcurrentStreamsOption creates gRPC server option, it is replaced in
+// tests to check passed value.
+var maxConcurrentStreamsOption = grpc.MaxConcurrentStreams
+
+// grpcServerLimitOptions returns gRPC server options which limit resources
+// used by client connections.
+func grpcServerLimitOptions() (options []grpc.ServerOption) {
+	if streams := config.GetInt(config.GrpcMaxConcurrentStreamsKey); streams > 0 {
+		options = append(options, maxConcurrentStreamsOption(uint32(streams)))
+	}
+	return
+}
+
+func newTLSConfig() *tls.Config {
+	tlsConfig, err := config.NewTLSConfig()
+	if err != nil {
+		log.WithError(err).Panic("error reading SSL configuration")
+	}
+	return tlsConfig
+}
+
+func (d *daemon) stop() {
 
 	if d.grpcServer != nil {
 		d.grpcServer.Stop()
 	}
 
+	d.lisMutex.Lock()
 	d.lis.Close()
+	d.lisMutex.Unlock()
 
 	if d.acmeListener != nil {
 		d.acmeListener.Close()
 	}
 
+	if d.certWatcher != nil {
+		d.certWatcher.Close()
+	}
+
 	// TODO(aiden) add d.blockProc.StopLoop()
 }
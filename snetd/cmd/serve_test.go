@@ -1,8 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"github.com/magiconair/properties/assert"
+	"github.com/singnet/snet-daemon/codec"
+	"github.com/singnet/snet-daemon/config"
+	"google.golang.org/grpc"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestDeriveDaemonPort(t *testing.T) {
@@ -18,6 +29,13 @@ func TestDeriveDaemonPort(t *testing.T) {
 	assert.Equal(t, nil, err)
 	assert.Equal(t, "8111", port1)
 
+	port1, err = deriveDaemonPort("127.0.0.1:65535")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "65535", port1)
+
+	port1, err = deriveDaemonPort("127.0.0.1:65536")
+	assert.Equal(t, "port number <65536> is not valid ,the daemon End point  127.0.0.1:65536", err.Error())
+
 	port1, err = deriveDaemonPort("abcdefjl:wewee")
 	assert.Equal(t, "port number <wewee> is not valid ,the daemon End point  abcdefjl:wewee", err.Error())
 
@@ -38,3 +56,174 @@ func TestDeriveDaemonPort(t *testing.T) {
 	assert.Equal(t, port1, "8080")
 	assert.Equal(t, nil, err)
 }
+
+func TestWatchConfigRemoval(t *testing.T) {
+	defer config.WithDefaultConfig()()
+	dir, err := ioutil.TempDir("", "config")
+	assert.Equal(t, nil, err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "snetd.config.json")
+	assert.Equal(t, nil, ioutil.WriteFile(configFile, []byte(`{ "daemon_type": "grpc" }`), 0600))
+	assert.Equal(t, nil, config.LoadConfig(configFile))
+	sigChan := make(chan os.Signal, 1)
+
+	stop := watchConfigRemoval(sigChan)
+	defer stop()
+	os.Remove(configFile)
+
+	select {
+	case sig := <-sigChan:
+		assert.Equal(t, syscall.SIGTERM, sig)
+	case <-time.After(time.Second):
+		t.Error("shutdown is not triggered after configuration file removal")
+	}
+}
+
+func captureMaxConcurrentStreams() (captured *[]uint32, restore func()) {
+	captured = &[]uint32{}
+	previous := maxConcurrentStreamsOption
+	maxConcurrentStreamsOption = func(n uint32) grpc.ServerOption {
+		*captured = append(*captured, n)
+		return previous(n)
+	}
+	return captured, func() { maxConcurrentStreamsOption = previous }
+}
+
+func TestGrpcServerLimitOptionsMaxConcurrentStreams(t *testing.T) {
+	captured, restore := captureMaxConcurrentStreams()
+	defer restore()
+	defer config.WithDefaultConfig()()
+	config.Vip().Set(config.GrpcMaxConcurrentStreamsKey, 10)
+
+	options := grpcServerLimitOptions()
+
+	assert.Equal(t, 1, len(options))
+	assert.Equal(t, []uint32{10}, *captured)
+}
+
+func TestGrpcServerLimitOptionsDefault(t *testing.T) {
+	captured, restore := captureMaxConcurrentStreams()
+	defer restore()
+
+	options := grpcServerLimitOptions()
+
+	assert.Equal(t, 0, len(options))
+	assert.Equal(t, []uint32{}, *captured)
+}
+
+// blockingService replies to "fast" request immediately, other calls are
+// blocked until release is closed.
+type blockingService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (service *blockingService) handle(srv interface{}, stream grpc.ServerStream) error {
+	request := &codec.GrpcFrame{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	if string(request.Data) != "fast" {
+		service.started <- struct{}{}
+		<-service.release
+	}
+	return stream.SendMsg(&codec.GrpcFrame{Data: []byte("done " + string(request.Data))})
+}
+
+// openTestStream sends request to the service, close should be called to
+// release the connection.
+func openTestStream(t *testing.T, address string, request string) (stream grpc.ClientStream, close func()) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	close = func() {
+		cancel()
+		conn.Close()
+	}
+
+	stream, err = conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, "/service/method", grpc.CallContentSubtype("proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.SendMsg(&codec.GrpcFrame{Data: []byte(request)}); err != nil {
+		t.Fatal(err)
+	}
+	stream.CloseSend()
+	return stream, close
+}
+
+func receiveTestResponse(stream grpc.ClientStream) (response string, err error) {
+	frame := &codec.GrpcFrame{}
+	err = stream.RecvMsg(frame)
+	return string(frame.Data), err
+}
+
+func freePort(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return strconv.Itoa(lis.Addr().(*net.TCPAddr).Port)
+}
+
+func TestDaemonRebindKeepsOldStreams(t *testing.T) {
+	service := &blockingService{started: make(chan struct{}, 1), release: make(chan struct{})}
+	d := &daemon{grpcServer: grpc.NewServer(grpc.UnknownServiceHandler(service.handle))}
+	oldPort := freePort(t)
+	lis, err := listenDaemonPort(oldPort)
+	assert.Equal(t, nil, err)
+	d.lis, d.port = lis, oldPort
+	defer d.stop()
+	d.serve(d.lis)
+
+	oldStream, closeOld := openTestStream(t, "127.0.0.1:"+oldPort, "slow")
+	defer closeOld()
+	<-service.started
+
+	newPort := freePort(t)
+	assert.Equal(t, nil, d.rebind("127.0.0.1:"+newPort))
+	assert.Equal(t, newPort, d.port)
+
+	newStream, closeNew := openTestStream(t, "127.0.0.1:"+newPort, "fast")
+	defer closeNew()
+	newResponse, err := receiveTestResponse(newStream)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "done fast", newResponse)
+
+	_, err = net.DialTimeout("tcp", "127.0.0.1:"+oldPort, time.Second)
+	assert.Equal(t, true, err != nil, "old port doesn't accept connections")
+
+	close(service.release)
+	oldResponse, err := receiveTestResponse(oldStream)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "done slow", oldResponse)
+}
+
+func TestDaemonRebindSamePort(t *testing.T) {
+	port := freePort(t)
+	lis, err := listenDaemonPort(port)
+	assert.Equal(t, nil, err)
+	defer lis.Close()
+	d := &daemon{lis: lis, port: port}
+
+	err = d.rebind("http://127.0.0.1:" + port)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, lis, d.lis)
+}
+
+func TestDaemonRebindIncorrectEndpoint(t *testing.T) {
+	port := freePort(t)
+	lis, err := listenDaemonPort(port)
+	assert.Equal(t, nil, err)
+	defer lis.Close()
+	d := &daemon{lis: lis, port: port}
+
+	err = d.rebind("127.0.0.1:port")
+
+	assert.Equal(t, "port number <port> is not valid ,the daemon End point  127.0.0.1:port", err.Error())
+	assert.Equal(t, lis, d.lis)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// processStartTime is a time when daemon process is started, startup
+// timeout is counted from this time to include configuration loading.
+var processStartTime = time.Now()
+
+// runWithStartupTimeout runs initialize and returns its result. If
+// initialize doesn't finish before startup timeout counted from
+// startTime is expired then error is returned without waiting for it.
+// Panic of initialize is returned as error. Zero timeout disables the
+// check.
+func runWithStartupTimeout(timeout time.Duration, startTime time.Time, initialize func() error) error {
+	if timeout <= 0 {
+		return initialize()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if entry, ok := r.(*log.Entry); ok {
+					r = entry.Message
+				}
+				result <- fmt.Errorf("daemon initialization failed: %v", r)
+			}
+		}()
+		result <- initialize()
+	}()
+
+	timer := time.NewTimer(timeout - time.Since(startTime))
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("daemon is not started in startup_timeout %v, check that dependencies are reachable", timeout)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func slowDependency(delay time.Duration) func() error {
+	return func() error {
+		time.Sleep(delay)
+		return nil
+	}
+}
+
+func TestStartupTimeoutExceeded(t *testing.T) {
+	err := runWithStartupTimeout(50*time.Millisecond, time.Now(), slowDependency(time.Second))
+
+	assert.EqualError(t, err, "daemon is not started in startup_timeout 50ms, check that dependencies are reachable")
+}
+
+func TestStartupTimeoutIncludesTimeBeforeInitialization(t *testing.T) {
+	start := time.Now()
+
+	err := runWithStartupTimeout(time.Second, start.Add(-900*time.Millisecond), slowDependency(500*time.Millisecond))
+
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestStartupInTime(t *testing.T) {
+	err := runWithStartupTimeout(time.Second, time.Now(), slowDependency(10*time.Millisecond))
+
+	assert.Nil(t, err)
+}
+
+func TestStartupError(t *testing.T) {
+	err := runWithStartupTimeout(time.Second, time.Now(), func() error { return errors.New("cannot bind") })
+
+	assert.EqualError(t, err, "cannot bind")
+}
+
+func TestStartupPanic(t *testing.T) {
+	err := runWithStartupTimeout(time.Second, time.Now(), func() error { panic("etcd is not available") })
+
+	assert.EqualError(t, err, "daemon initialization failed: etcd is not available")
+}
+
+func TestStartupTimeoutDisabled(t *testing.T) {
+	err := runWithStartupTimeout(0, time.Now().Add(-time.Hour), slowDependency(10*time.Millisecond))
+
+	assert.Nil(t, err)
+}
+
+func TestStartupLogPanic(t *testing.T) {
+	err := runWithStartupTimeout(time.Second, time.Now(), func() error {
+		log.WithField("endpoint", "http://127.0.0.1:2379").Panic("etcd is not available")
+		return nil
+	})
+
+	assert.EqualError(t, err, "daemon initialization failed: etcd is not available")
+}
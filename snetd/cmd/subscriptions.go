@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/singnet/snet-daemon/config"
+	"github.com/singnet/snet-daemon/escrow"
+	"github.com/singnet/snet-daemon/handler"
+	log "github.com/sirupsen/logrus"
+)
+
+// subscriptionsPath is a path of the admin HTTP endpoint which creates
+// subscriptions paid by payments.
+const subscriptionsPath = "/subscriptions"
+
+// subscriptionRequest is a payment for the subscription signed by the
+// channel signer in the same way as payment for the call. Service can be
+// omitted if daemon serves the only service.
+type subscriptionRequest struct {
+	OrganizationID string        `json:"organization_id"`
+	ServiceID      string        `json:"service_id"`
+	ChannelID      *big.Int      `json:"channel_id"`
+	Nonce          *big.Int      `json:"nonce"`
+	Amount         *big.Int      `json:"amount"`
+	Signature      hexutil.Bytes `json:"signature"`
+}
+
+// subscriptionResponse describes subscription created by the payment.
+type subscriptionResponse struct {
+	OrganizationID string `json:"organization_id"`
+	ServiceID      string `json:"service_id"`
+	Sender         string `json:"sender"`
+	ExpiresAt      string `json:"expires_at"`
+}
+
+// subscriber creates subscription paid by the payment, it is implemented
+// by escrow.SubscriptionService.
+type subscriber interface {
+	Subscribe(ctx context.Context, service handler.ServiceKey, payment *escrow.Payment) (subscription *escrow.Subscription, err error)
+}
+
+// subscriptionsHandler creates subscriptions on behalf of the clients which
+// pass subscription_admin_token in "Authorization: Bearer <token>" header.
+type subscriptionsHandler struct {
+	token              string
+	service            subscriber
+	mpeContractAddress func() common.Address
+	// services are the services served by daemon
+	services []handler.ServiceKey
+}
+
+// newSubscriptionsHandler returns handler of the subscriptions endpoint or
+// nil if subscriptions are disabled. Subscriptions are paid via payment
+// channels, so they require blockchain to be enabled.
+func newSubscriptionsHandler(components *Components) http.Handler {
+	if !components.Blockchain().Enabled() {
+		return nil
+	}
+	service := components.SubscriptionService()
+	if service == nil {
+		return nil
+	}
+	services, err := config.GetServices()
+	if err != nil {
+		log.WithError(err).Panic("error reading services configuration")
+	}
+	keys := make([]handler.ServiceKey, 0, len(services))
+	for _, conf := range services {
+		keys = append(keys, serviceKey(conf))
+	}
+	return &subscriptionsHandler{
+		token:              config.GetString(config.SubscriptionAdminTokenKey),
+		service:            service,
+		mpeContractAddress: components.Blockchain().EscrowContractAddress,
+		services:           keys,
+	}
+}
+
+func (handler *subscriptionsHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if !bearerTokenMatches(req, handler.token) {
+		http.Error(resp, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if req.Method != "POST" {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request := &subscriptionRequest{}
+	if err := json.NewDecoder(req.Body).Decode(request); err != nil {
+		http.Error(resp, "incorrect subscription request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.ChannelID == nil || request.Nonce == nil || request.Amount == nil || len(request.Signature) == 0 {
+		http.Error(resp, "channel_id, nonce, amount and signature are required", http.StatusBadRequest)
+		return
+	}
+	service, err := subscribedService(handler.services, request)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := handler.service.Subscribe(req.Context(), service, &escrow.Payment{
+		MpeContractAddress: handler.mpeContractAddress(),
+		ChannelID:          request.ChannelID,
+		ChannelNonce:       request.Nonce,
+		Amount:             request.Amount,
+		Signature:          request.Signature,
+	})
+	if err != nil {
+		log.WithError(err).WithField("channelID", request.ChannelID).Warn("Subscription is not created")
+		http.Error(resp, err.Error(), subscriptionErrorStatus(err))
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(resp).Encode(&subscriptionResponse{
+		OrganizationID: subscription.Service.OrganizationID,
+		ServiceID:      subscription.Service.ServiceID,
+		Sender:         subscription.Sender.Hex(),
+		ExpiresAt:      subscription.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// subscribedService returns service of the subscription request, it should
+// be one of the services. Service is optional if there is the only service.
+func subscribedService(services []handler.ServiceKey, request *subscriptionRequest) (service handler.ServiceKey, err error) {
+	if request.OrganizationID == "" && request.ServiceID == "" {
+		if len(services) != 1 {
+			return service, errors.New("organization_id and service_id are required as daemon serves few services")
+		}
+		return services[0], nil
+	}
+
+	service = handler.ServiceKey{OrganizationID: request.OrganizationID, ServiceID: request.ServiceID}
+	for _, served := range services {
+		if served == service {
+			return service, nil
+		}
+	}
+	return service, fmt.Errorf("service \"%v\" is not served", service)
+}
+
+// subscriptionErrorStatus returns HTTP status of the payment error, errors
+// of the daemon infrastructure are distinguished from the incorrect
+// payments.
+func subscriptionErrorStatus(err error) int {
+	if escrow.IsInfrastructureError(err) {
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/singnet/snet-daemon/escrow"
+	"github.com/singnet/snet-daemon/handler"
+)
+
+type subscriberMock struct {
+	service handler.ServiceKey
+	payment *escrow.Payment
+	err     error
+}
+
+func (mock *subscriberMock) Subscribe(ctx context.Context, service handler.ServiceKey, payment *escrow.Payment) (*escrow.Subscription, error) {
+	mock.service = service
+	mock.payment = payment
+	if mock.err != nil {
+		return nil, mock.err
+	}
+	return &escrow.Subscription{
+		Service:   service,
+		Sender:    common.HexToAddress("0x0000000000000000000000000000000000000002"),
+		ExpiresAt: time.Date(2018, 11, 14, 12, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+var testMpeAddress = common.HexToAddress("0x0000000000000000000000000000000000000042")
+
+var testSubscribedService = handler.ServiceKey{OrganizationID: "org", ServiceID: "service-a"}
+
+func newTestSubscriptionsHandler(service subscriber, services ...handler.ServiceKey) *subscriptionsHandler {
+	if len(services) == 0 {
+		services = []handler.ServiceKey{testSubscribedService}
+	}
+	return &subscriptionsHandler{
+		token:              "admin-token",
+		service:            service,
+		mpeContractAddress: func() common.Address { return testMpeAddress },
+		services:           services,
+	}
+}
+
+func requestSubscription(handler http.Handler, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", subscriptionsPath, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+const testSubscriptionRequest = `{"channel_id": 42, "nonce": 3, "amount": 1000, "signature": "0x0102"}`
+
+func TestSubscriptionsCreatesSubscription(t *testing.T) {
+	service := &subscriberMock{}
+
+	resp := requestSubscription(newTestSubscriptionsHandler(service), "admin-token", testSubscriptionRequest)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"organization_id": "org", "service_id": "service-a", "sender": "0x0000000000000000000000000000000000000002", "expires_at": "2018-11-14T12:00:00Z"}`, resp.Body.String())
+	assert.Equal(t, testSubscribedService, service.service)
+	assert.Equal(t, &escrow.Payment{
+		MpeContractAddress: testMpeAddress,
+		ChannelID:          big.NewInt(42),
+		ChannelNonce:       big.NewInt(3),
+		Amount:             big.NewInt(1000),
+		Signature:          []byte{1, 2},
+	}, service.payment)
+}
+
+func TestSubscriptionsOfOneOfFewServices(t *testing.T) {
+	service := &subscriberMock{}
+	serviceB := handler.ServiceKey{OrganizationID: "org", ServiceID: "service-b"}
+	subscriptions := newTestSubscriptionsHandler(service, testSubscribedService, serviceB)
+
+	resp := requestSubscription(subscriptions, "admin-token",
+		`{"organization_id": "org", "service_id": "service-b", "channel_id": 42, "nonce": 3, "amount": 1000, "signature": "0x0102"}`)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, serviceB, service.service)
+}
+
+func TestSubscriptionsServiceIsRequired(t *testing.T) {
+	service := &subscriberMock{}
+	subscriptions := newTestSubscriptionsHandler(service, testSubscribedService, handler.ServiceKey{OrganizationID: "org", ServiceID: "service-b"})
+
+	resp := requestSubscription(subscriptions, "admin-token", testSubscriptionRequest)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "organization_id and service_id are required as daemon serves few services\n", resp.Body.String())
+	assert.Nil(t, service.payment)
+}
+
+func TestSubscriptionsUnknownService(t *testing.T) {
+	service := &subscriberMock{}
+
+	resp := requestSubscription(newTestSubscriptionsHandler(service), "admin-token",
+		`{"organization_id": "org", "service_id": "service-c", "channel_id": 42, "nonce": 3, "amount": 1000, "signature": "0x0102"}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "service \"org/service-c\" is not served\n", resp.Body.String())
+	assert.Nil(t, service.payment)
+}
+
+func TestSubscriptionsUnauthorized(t *testing.T) {
+	service := &subscriberMock{}
+
+	resp := requestSubscription(newTestSubscriptionsHandler(service), "wrong-token", testSubscriptionRequest)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Nil(t, service.payment)
+}
+
+func TestSubscriptionsIncompleteRequest(t *testing.T) {
+	resp := requestSubscription(newTestSubscriptionsHandler(&subscriberMock{}), "admin-token", `{"channel_id": 42}`)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "channel_id, nonce, amount and signature are required\n", resp.Body.String())
+}
+
+func TestSubscriptionsPaymentIsRejected(t *testing.T) {
+	service := &subscriberMock{err: escrow.NewPaymentError(escrow.Unauthenticated, "income 99 is less than subscription price 100")}
+
+	resp := requestSubscription(newTestSubscriptionsHandler(service), "admin-token", testSubscriptionRequest)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "income 99 is less than subscription price 100\n", resp.Body.String())
+}
+
+func TestSubscriptionsStorageError(t *testing.T) {
+	service := &subscriberMock{err: escrow.NewPaymentError(escrow.Internal, "cannot save subscription of the sender: storage is down")}
+
+	resp := requestSubscription(newTestSubscriptionsHandler(service), "admin-token", testSubscriptionRequest)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
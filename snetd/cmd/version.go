@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Build information, it is set at build time using linker flags, for
+// example:
+// go build -ldflags "-X github.com/singnet/snet-daemon/snetd/cmd.versionTag=v0.1.4"
+var (
+	versionTag = "dev"
+	gitCommit  = ""
+	buildTime  = ""
+)
+
+// versionPath is a path of the HTTP endpoint which returns build
+// information.
+const versionPath = "/version"
+
+// BuildInfo describes build of the daemon.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// GetBuildInfo returns version, git commit and build time injected at build
+// time.
+func GetBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   versionTag,
+		GitCommit: gitCommit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// logBuildInfo writes build information to the log, it helps to find out
+// which build produced the log.
+func logBuildInfo() {
+	info := GetBuildInfo()
+	log.WithFields(log.Fields{
+		"version":   info.Version,
+		"gitCommit": info.GitCommit,
+		"buildTime": info.BuildTime,
+		"goVersion": info.GoVersion,
+	}).Info("Daemon build")
+}
+
+// serveBuildInfo writes build information as JSON.
+func serveBuildInfo(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Access-Control-Allow-Origin", "*")
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(GetBuildInfo()); err != nil {
+		log.WithError(err).Error("Cannot write build information")
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setTestBuildInfo replaces build information as linker flags do and
+// returns function which restores previous values.
+func setTestBuildInfo() (restore func()) {
+	prevVersionTag, prevGitCommit, prevBuildTime := versionTag, gitCommit, buildTime
+	versionTag, gitCommit, buildTime = "v1.2.3", "0123456789abcdef", "2018-10-20T01:02:03Z"
+	return func() {
+		versionTag, gitCommit, buildTime = prevVersionTag, prevGitCommit, prevBuildTime
+	}
+}
+
+func TestGetBuildInfo(t *testing.T) {
+	defer setTestBuildInfo()()
+
+	info := GetBuildInfo()
+
+	assert.Equal(t, BuildInfo{
+		Version:   "v1.2.3",
+		GitCommit: "0123456789abcdef",
+		BuildTime: "2018-10-20T01:02:03Z",
+		GoVersion: runtime.Version(),
+	}, info)
+}
+
+func TestGetBuildInfoDefault(t *testing.T) {
+	info := GetBuildInfo()
+
+	assert.Equal(t, "dev", info.Version)
+	assert.Equal(t, "", info.GitCommit)
+}
+
+func TestServeBuildInfo(t *testing.T) {
+	defer setTestBuildInfo()()
+	resp := httptest.NewRecorder()
+
+	serveBuildInfo(resp, httptest.NewRequest("GET", versionPath, nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	var info map[string]interface{}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &info))
+	assert.Equal(t, map[string]interface{}{
+		"version":    "v1.2.3",
+		"git_commit": "0123456789abcdef",
+		"build_time": "2018-10-20T01:02:03Z",
+		"go_version": runtime.Version(),
+	}, info)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/singnet/snet-daemon/config"
+)
+
+const webhookDeliveryCheckInterval = 10 * time.Second
+
+// webhookDeliverer periodically retries webhook deliveries which are kept
+// in the delivery queue.
+type webhookDeliverer struct {
+	deliverDue func() error
+
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// newWebhookDeliverer returns deliverer of the pending webhook notifications
+// or nil if claim_webhook_urls is empty or daemon runs in replica_mode, in
+// the latter case notifications are delivered by the primary daemon.
+func newWebhookDeliverer(components *Components) *webhookDeliverer {
+	if len(config.GetStringSlice(config.ClaimWebhookURLsKey)) == 0 || config.GetBool(config.ReplicaModeKey) {
+		return nil
+	}
+	return &webhookDeliverer{deliverDue: components.WebhookDeliveryQueue().DeliverDue}
+}
+
+func (deliverer *webhookDeliverer) start() {
+	deliverer.stopChan = make(chan struct{})
+	deliverer.done = make(chan struct{})
+	go func() {
+		defer close(deliverer.done)
+
+		ticker := time.NewTicker(webhookDeliveryCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := deliverer.deliverDue(); err != nil {
+				log.WithError(err).Warn("Cannot deliver pending webhook notifications")
+			}
+			select {
+			case <-ticker.C:
+			case <-deliverer.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (deliverer *webhookDeliverer) stop() {
+	close(deliverer.stopChan)
+	<-deliverer.done
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDelivererDeliversOnStart(t *testing.T) {
+	calls := 0
+	deliverer := &webhookDeliverer{deliverDue: func() error {
+		calls++
+		return errors.New("storage error")
+	}}
+
+	deliverer.start()
+	deliverer.stop()
+
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"sync"
+)
+
+// InMemoryExporter keeps exported spans in memory, it is used in tests.
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+// NewInMemoryExporter returns new empty exporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// Export keeps span.
+func (exporter *InMemoryExporter) Export(span *Span) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	exporter.spans = append(exporter.spans, span)
+}
+
+// Close does nothing.
+func (exporter *InMemoryExporter) Close() {
+}
+
+// Spans returns spans exported so far in order of finishing.
+func (exporter *InMemoryExporter) Spans() []*Span {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+
+	return append([]*Span(nil), exporter.spans...)
+}
+
+// SpanNames returns names of the spans exported so far.
+func (exporter *InMemoryExporter) SpanNames() (names []string) {
+	for _, span := range exporter.Spans() {
+		names = append(names, span.Name)
+	}
+	return
+}
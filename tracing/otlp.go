@@ -0,0 +1,203 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	otlpQueueSize     = 2048
+	otlpBatchSize     = 512
+	otlpFlushInterval = 5 * time.Second
+	otlpStatusError   = 2
+)
+
+// OTLPExporter sends spans to the OpenTelemetry collector using OTLP/HTTP
+// protocol with JSON encoding. Spans are sent in batches from the background
+// goroutine, spans which don't fit into the queue are dropped.
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+	closeOnce   sync.Once
+	done        chan struct{}
+}
+
+// NewOTLPExporter returns exporter which posts spans to the endpoint, for
+// example "http://localhost:4318/v1/traces". serviceName is reported as
+// "service.name" resource attribute.
+func NewOTLPExporter(endpoint string, serviceName string) *OTLPExporter {
+	exporter := &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Span, otlpQueueSize),
+		done:        make(chan struct{}),
+	}
+	go exporter.run()
+	return exporter
+}
+
+// Export puts span into the queue of spans to send.
+func (exporter *OTLPExporter) Export(span *Span) {
+	select {
+	case exporter.queue <- span:
+	default:
+		log.WithField("span", span.Name).Debug("Tracing queue is full, span is dropped")
+	}
+}
+
+// Close sends spans which are in the queue and stops the exporter.
+func (exporter *OTLPExporter) Close() {
+	exporter.closeOnce.Do(func() {
+		close(exporter.queue)
+		<-exporter.done
+	})
+}
+
+func (exporter *OTLPExporter) run() {
+	defer close(exporter.done)
+
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span, ok := <-exporter.queue:
+			if !ok {
+				exporter.send(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				exporter.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			exporter.send(batch)
+			batch = nil
+		}
+	}
+}
+
+func (exporter *OTLPExporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := exporter.post(batch); err != nil {
+		log.WithError(err).WithField("endpoint", exporter.endpoint).WithField("spans", len(batch)).Warn("Cannot export spans")
+	}
+}
+
+func (exporter *OTLPExporter) post(batch []*Span) error {
+	body, err := json.Marshal(exporter.request(batch))
+	if err != nil {
+		return err
+	}
+	response, err := exporter.client.Post(exporter.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %v", response.StatusCode)
+	}
+	return nil
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func (exporter *OTLPExporter) request(batch []*Span) *otlpRequest {
+	scopeSpans := otlpScopeSpans{}
+	scopeSpans.Scope.Name = "github.com/singnet/snet-daemon"
+	for _, span := range batch {
+		scopeSpans.Spans = append(scopeSpans.Spans, toOtlpSpan(span))
+	}
+
+	resourceSpans := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scopeSpans}}
+	resourceSpans.Resource.Attributes = []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: exporter.serviceName}}}
+
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{resourceSpans}}
+}
+
+func toOtlpSpan(span *Span) otlpSpan {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	result := otlpSpan{
+		TraceID:           span.SpanContext.TraceID.String(),
+		SpanID:            span.SpanContext.SpanID.String(),
+		Name:              span.Name,
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+	}
+	if span.ParentSpanID != (SpanID{}) {
+		result.ParentSpanID = span.ParentSpanID.String()
+	}
+
+	keys := make([]string, 0, len(span.Attributes))
+	for key := range span.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		result.Attributes = append(result.Attributes, otlpKeyValue{Key: key, Value: otlpValue{StringValue: span.Attributes[key]}})
+	}
+
+	if span.Err != nil {
+		result.Status = otlpStatus{Code: otlpStatusError, Message: span.Err.Error()}
+	}
+	return result
+}
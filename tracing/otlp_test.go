@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type collectorMock struct {
+	mutex    sync.Mutex
+	requests []*otlpRequest
+}
+
+func (collector *collectorMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := &otlpRequest{}
+	json.NewDecoder(r.Body).Decode(request)
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.requests = append(collector.requests, request)
+}
+
+func TestOTLPExporterSendsSpansOnClose(t *testing.T) {
+	collector := &collectorMock{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	exporter := NewOTLPExporter(server.URL+"/v1/traces", "snetd")
+	tracer := NewTracer(exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.SetAttribute("key", "value")
+	child.SetError(errors.New("failed"))
+	child.End()
+	parent.End()
+	tracer.Close()
+
+	assert.Equal(t, 1, len(collector.requests))
+	resourceSpans := collector.requests[0].ResourceSpans[0]
+	assert.Equal(t, []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: "snetd"}}}, resourceSpans.Resource.Attributes)
+	spans := resourceSpans.ScopeSpans[0].Spans
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, parent.SpanContext.SpanID.String(), spans[0].ParentSpanID)
+	assert.Equal(t, []otlpKeyValue{{Key: "key", Value: otlpValue{StringValue: "value"}}}, spans[0].Attributes)
+	assert.Equal(t, otlpStatus{Code: otlpStatusError, Message: "failed"}, spans[0].Status)
+	assert.Equal(t, "parent", spans[1].Name)
+	assert.Equal(t, "", spans[1].ParentSpanID)
+	assert.Equal(t, parent.SpanContext.TraceID.String(), spans[1].TraceID)
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceparentHeader is a W3C Trace Context header which contains span
+// context of the caller.
+const TraceparentHeader = "traceparent"
+
+// TracestateHeader is a W3C Trace Context header which contains vendor
+// specific trace data of the caller. Daemon doesn't parse it, it is passed to
+// the service as is.
+const TracestateHeader = "tracestate"
+
+// Extract returns span context passed by the caller in the traceparent
+// metadata, ok is false if header is absent or incorrect.
+func Extract(md metadata.MD) (sc SpanContext, ok bool) {
+	values := md.Get(TraceparentHeader)
+	if len(values) == 0 {
+		return sc, false
+	}
+	sc, err := parseTraceparent(values[0])
+	if err != nil {
+		return sc, false
+	}
+	return sc, true
+}
+
+// Inject puts span context of the span into md as traceparent header. It
+// does nothing if span is nil.
+func Inject(span *Span, md metadata.MD) {
+	if span == nil {
+		return
+	}
+	md.Set(TraceparentHeader, formatTraceparent(span.SpanContext))
+}
+
+func formatTraceparent(sc SpanContext) string {
+	return fmt.Sprintf("00-%v-%v-01", sc.TraceID, sc.SpanID)
+}
+
+// parseTraceparent parses header of "version-traceid-spanid-flags" format.
+// Only version fields are checked as later versions are compatible with the
+// first one.
+func parseTraceparent(value string) (sc SpanContext, err error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("incorrect traceparent \"%v\"", value)
+	}
+	if err = decodeHex(parts[1], sc.TraceID[:]); err != nil {
+		return sc, err
+	}
+	if err = decodeHex(parts[2], sc.SpanID[:]); err != nil {
+		return sc, err
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("traceparent \"%v\" contains zero id", value)
+	}
+	return sc, nil
+}
+
+func decodeHex(value string, id []byte) error {
+	if len(value) != 2*len(id) {
+		return fmt.Errorf("incorrect id length %v, expected %v", len(value), 2*len(id))
+	}
+	_, err := hex.Decode(id, []byte(value))
+	return err
+}
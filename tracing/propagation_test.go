@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestExtract(t *testing.T) {
+	md := metadata.Pairs(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	sc, ok := Extract(md)
+
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+}
+
+func TestExtractMissingHeader(t *testing.T) {
+	_, ok := Extract(metadata.Pairs())
+
+	assert.False(t, ok)
+}
+
+func TestExtractIncorrectHeader(t *testing.T) {
+	for _, value := range []string{
+		"4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bz-01",
+	} {
+		_, ok := Extract(metadata.Pairs(TraceparentHeader, value))
+
+		assert.False(t, ok, value)
+	}
+}
+
+func TestInject(t *testing.T) {
+	_, span := NewTracer(NewInMemoryExporter()).Start(context.Background(), "span")
+	md := metadata.Pairs()
+
+	Inject(span, md)
+	sc, ok := Extract(md)
+
+	assert.True(t, ok)
+	assert.Equal(t, span.SpanContext, sc)
+}
+
+func TestInjectNilSpan(t *testing.T) {
+	md := metadata.Pairs()
+
+	Inject(nil, md)
+
+	assert.Equal(t, 0, len(md))
+}
@@ -0,0 +1,211 @@
+// Package tracing implements distributed tracing of the daemon calls. Data
+// model and wire formats are compatible with OpenTelemetry: span context is
+// propagated using W3C Trace Context "traceparent" header and spans are
+// exported using OTLP/HTTP protocol.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID is an identifier of the trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID is an identifier of the span.
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext identifies span and trace it belongs to.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid returns true if both trace and span ids are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Span is a single operation within a trace.
+type Span struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	Err          error
+
+	mutex  sync.Mutex
+	ended  bool
+	tracer *Tracer
+}
+
+// SetAttribute adds attribute to the span. All methods of the Span can be
+// called on nil span, so callers don't need to check whether tracing is
+// enabled.
+func (span *Span) SetAttribute(key, value string) {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	span.Attributes[key] = value
+}
+
+// SetError marks span as failed if err is not nil.
+func (span *Span) SetError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	span.Err = err
+}
+
+// End finishes the span and passes it to the exporter, next calls are
+// ignored.
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	if span.ended {
+		span.mutex.Unlock()
+		return
+	}
+	span.ended = true
+	span.EndTime = time.Now()
+	span.mutex.Unlock()
+
+	span.tracer.exporter.Export(span)
+}
+
+// SpanExporter sends finished spans to the tracing backend.
+type SpanExporter interface {
+	// Export is called for each finished span, it should not block.
+	Export(span *Span)
+	// Close sends spans which are not sent yet and releases resources.
+	Close()
+}
+
+// Tracer creates spans and passes finished spans to the exporter.
+type Tracer struct {
+	exporter SpanExporter
+}
+
+// NewTracer returns tracer which exports spans using exporter.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start starts new span. If ctx contains span or remote parent then new span
+// is its child, otherwise new trace is started. Returned context contains new
+// span. Nil tracer returns ctx as is and nil span.
+func (tracer *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]string),
+		tracer:     tracer,
+	}
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() {
+		span.SpanContext.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		rand.Read(span.SpanContext.TraceID[:])
+	}
+	rand.Read(span.SpanContext.SpanID[:])
+
+	return context.WithValue(ctx, spanKey, span), span
+}
+
+// Close closes exporter of the tracer.
+func (tracer *Tracer) Close() {
+	if tracer != nil {
+		tracer.exporter.Close()
+	}
+}
+
+var globalTracer struct {
+	mutex  sync.RWMutex
+	tracer *Tracer
+}
+
+// SetGlobalTracer sets tracer which is used by StartSpan, nil disables
+// tracing.
+func SetGlobalTracer(tracer *Tracer) {
+	globalTracer.mutex.Lock()
+	defer globalTracer.mutex.Unlock()
+
+	globalTracer.tracer = tracer
+}
+
+// GlobalTracer returns tracer set by SetGlobalTracer.
+func GlobalTracer() *Tracer {
+	globalTracer.mutex.RLock()
+	defer globalTracer.mutex.RUnlock()
+
+	return globalTracer.tracer
+}
+
+// StartSpan starts span using global tracer, see Tracer.Start. It returns
+// nil span when tracing is disabled.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return GlobalTracer().Start(ctx, name)
+}
+
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteParentKey
+)
+
+// ContextWithSpan returns context which contains span, nil span is ignored.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// SpanFromContext returns span kept in the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent returns context which contains span context
+// received from the caller, spans started from this context are its children.
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteParentKey, parent)
+}
+
+// SpanContextFromContext returns span context of the span kept in ctx or
+// remote parent if there is no span.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext
+	}
+	parent, _ := ctx.Value(remoteParentKey).(SpanContext)
+	return parent
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartRootSpan(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	ctx, span := tracer.Start(context.Background(), "root")
+	span.End()
+
+	assert.True(t, span.SpanContext.IsValid())
+	assert.Equal(t, SpanID{}, span.ParentSpanID)
+	assert.Equal(t, span, SpanFromContext(ctx))
+	assert.Equal(t, []string{"root"}, exporter.SpanNames())
+}
+
+func TestStartChildSpan(t *testing.T) {
+	tracer := NewTracer(NewInMemoryExporter())
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+
+	assert.Equal(t, parent.SpanContext.TraceID, child.SpanContext.TraceID)
+	assert.Equal(t, parent.SpanContext.SpanID, child.ParentSpanID)
+	assert.NotEqual(t, parent.SpanContext.SpanID, child.SpanContext.SpanID)
+}
+
+func TestStartSpanWithRemoteParent(t *testing.T) {
+	tracer := NewTracer(NewInMemoryExporter())
+	remote := SpanContext{TraceID: TraceID{1, 2, 3}, SpanID: SpanID{4, 5, 6}}
+
+	_, span := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "child")
+
+	assert.Equal(t, remote.TraceID, span.SpanContext.TraceID)
+	assert.Equal(t, remote.SpanID, span.ParentSpanID)
+}
+
+func TestSpanEndExportsOnce(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	_, span := NewTracer(exporter).Start(context.Background(), "span")
+
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("failed"))
+	span.End()
+	span.End()
+
+	assert.Equal(t, 1, len(exporter.Spans()))
+	assert.Equal(t, map[string]string{"key": "value"}, exporter.Spans()[0].Attributes)
+	assert.Equal(t, errors.New("failed"), exporter.Spans()[0].Err)
+}
+
+func TestNilTracerReturnsNilSpan(t *testing.T) {
+	var tracer *Tracer
+	ctx := context.Background()
+
+	spanCtx, span := tracer.Start(ctx, "span")
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("failed"))
+	span.End()
+
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, spanCtx)
+}
+
+func TestStartSpanUsesGlobalTracer(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	SetGlobalTracer(NewTracer(exporter))
+	defer SetGlobalTracer(nil)
+
+	_, span := StartSpan(context.Background(), "global")
+	span.End()
+
+	assert.Equal(t, []string{"global"}, exporter.SpanNames())
+}
@@ -0,0 +1,87 @@
+// Package endpoint implements strategies to choose the order in which
+// configured service endpoints are used.
+package endpoint
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+const (
+	// Priority strategy always uses endpoints in configured order, so the
+	// first available endpoint is used.
+	Priority = "priority"
+	// RoundRobin strategy starts each next selection from the next endpoint.
+	RoundRobin = "round_robin"
+	// Random strategy uses endpoints in random order.
+	Random = "random"
+)
+
+// Selector returns endpoints in order in which they should be tried.
+type Selector interface {
+	// Order returns all endpoints, preferred endpoint is the first one.
+	Order() []string
+}
+
+// NewSelector returns selector which implements the strategy. rnd is used
+// by Random strategy only, it allows getting reproducible order in tests.
+func NewSelector(strategy string, endpoints []string, rnd *rand.Rand) (selector Selector, err error) {
+	endpoints = append([]string(nil), endpoints...)
+	switch strategy {
+	case Priority:
+		return &prioritySelector{endpoints: endpoints}, nil
+	case RoundRobin:
+		return &roundRobinSelector{endpoints: endpoints}, nil
+	case Random:
+		return &randomSelector{endpoints: endpoints, rnd: rnd}, nil
+	default:
+		return nil, fmt.Errorf("unknown endpoint selection strategy \"%v\"", strategy)
+	}
+}
+
+type prioritySelector struct {
+	endpoints []string
+}
+
+func (selector *prioritySelector) Order() []string {
+	return append([]string(nil), selector.endpoints...)
+}
+
+type roundRobinSelector struct {
+	mutex     sync.Mutex
+	endpoints []string
+	next      int
+}
+
+func (selector *roundRobinSelector) Order() []string {
+	selector.mutex.Lock()
+	defer selector.mutex.Unlock()
+
+	count := len(selector.endpoints)
+	if count == 0 {
+		return nil
+	}
+	order := make([]string, 0, count)
+	order = append(order, selector.endpoints[selector.next:]...)
+	order = append(order, selector.endpoints[:selector.next]...)
+	selector.next = (selector.next + 1) % count
+	return order
+}
+
+type randomSelector struct {
+	mutex     sync.Mutex
+	endpoints []string
+	rnd       *rand.Rand
+}
+
+func (selector *randomSelector) Order() []string {
+	selector.mutex.Lock()
+	defer selector.mutex.Unlock()
+
+	order := make([]string, len(selector.endpoints))
+	for i, j := range selector.rnd.Perm(len(selector.endpoints)) {
+		order[i] = selector.endpoints[j]
+	}
+	return order
+}
@@ -0,0 +1,77 @@
+package endpoint
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEndpoints = []string{"http://a:2379", "http://b:2379", "http://c:2379"}
+
+func TestPrioritySelector(t *testing.T) {
+	selector, err := NewSelector(Priority, testEndpoints, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, testEndpoints, selector.Order())
+	assert.Equal(t, testEndpoints, selector.Order())
+}
+
+func TestRoundRobinSelector(t *testing.T) {
+	selector, err := NewSelector(RoundRobin, testEndpoints, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"http://a:2379", "http://b:2379", "http://c:2379"}, selector.Order())
+	assert.Equal(t, []string{"http://b:2379", "http://c:2379", "http://a:2379"}, selector.Order())
+	assert.Equal(t, []string{"http://c:2379", "http://a:2379", "http://b:2379"}, selector.Order())
+	assert.Equal(t, []string{"http://a:2379", "http://b:2379", "http://c:2379"}, selector.Order())
+}
+
+func TestRoundRobinSelectorNoEndpoints(t *testing.T) {
+	selector, err := NewSelector(RoundRobin, nil, nil)
+	assert.Nil(t, err)
+
+	assert.Nil(t, selector.Order())
+}
+
+func TestRandomSelectorIsReproducibleWithSeed(t *testing.T) {
+	first, err := NewSelector(Random, testEndpoints, rand.New(rand.NewSource(42)))
+	assert.Nil(t, err)
+	second, err := NewSelector(Random, testEndpoints, rand.New(rand.NewSource(42)))
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		order := first.Order()
+		assert.Equal(t, order, second.Order())
+		assert.ElementsMatch(t, testEndpoints, order)
+	}
+}
+
+func TestRandomSelectorOrder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	expected := make([]string, len(testEndpoints))
+	for i, j := range rand.New(rand.NewSource(1)).Perm(len(testEndpoints)) {
+		expected[i] = testEndpoints[j]
+	}
+	selector, err := NewSelector(Random, testEndpoints, rnd)
+	assert.Nil(t, err)
+
+	assert.Equal(t, expected, selector.Order())
+}
+
+func TestSelectorDoesNotShareEndpoints(t *testing.T) {
+	endpoints := append([]string(nil), testEndpoints...)
+	selector, _ := NewSelector(Priority, endpoints, nil)
+
+	endpoints[0] = "http://changed:2379"
+	order := selector.Order()
+	order[1] = "http://changed:2379"
+
+	assert.Equal(t, testEndpoints, selector.Order())
+}
+
+func TestUnknownStrategy(t *testing.T) {
+	_, err := NewSelector("fastest", testEndpoints, nil)
+
+	assert.Equal(t, "unknown endpoint selection strategy \"fastest\"", err.Error())
+}
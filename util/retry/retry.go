@@ -0,0 +1,96 @@
+// Package retry contains helpers to repeat operations which fail because of
+// temporary problems, for example unavailable network service.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Policy describes how operation is repeated.
+type Policy struct {
+	// MaxAttempts is a maximum number of operation calls including the first
+	// one, 0 means that operation is repeated until context is done.
+	MaxAttempts int
+	// InitialBackoff is a delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff limits the delay between attempts, 0 means no limit.
+	MaxBackoff time.Duration
+	// Multiplier is a factor the delay is multiplied by after each attempt,
+	// 2 is used when it is not set.
+	Multiplier float64
+	// Jitter randomizes delays to avoid retrying many operations at the
+	// same moment: each delay is chosen uniformly from [delay*(1-Jitter),
+	// delay*(1+Jitter)]. It should be between 0 and 1.
+	Jitter float64
+	// Retryable returns true if operation failed with error which can be
+	// fixed by repeating the operation. All errors are retryable when it is
+	// not set.
+	Retryable func(err error) bool
+}
+
+// random returns random number in [0, 1), it is replaced in tests.
+var random = rand.Float64
+
+// Do calls fn until it succeeds, returns error which is not retryable,
+// MaxAttempts is reached or ctx is done. The last error returned by fn is
+// returned in all cases.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) (err error) {
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || !policy.retryable(err) {
+			return err
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(policy.jitter(backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff = policy.next(backoff)
+	}
+}
+
+// Backoff returns delay before the next attempt after the given number of
+// failed attempts. It is used when attempts are scheduled by the caller
+// instead of Do, for example when they are persisted between restarts.
+func (policy Policy) Backoff(failedAttempts int) time.Duration {
+	backoff := policy.InitialBackoff
+	for i := 1; i < failedAttempts; i++ {
+		backoff = policy.next(backoff)
+	}
+	return policy.jitter(backoff)
+}
+
+func (policy Policy) retryable(err error) bool {
+	return policy.Retryable == nil || policy.Retryable(err)
+}
+
+// next returns delay before the next attempt.
+func (policy Policy) next(backoff time.Duration) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	backoff = time.Duration(float64(backoff) * multiplier)
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	return backoff
+}
+
+// jitter returns randomized delay.
+func (policy Policy) jitter(backoff time.Duration) time.Duration {
+	if policy.Jitter <= 0 {
+		return backoff
+	}
+	return time.Duration(float64(backoff) * (1 - policy.Jitter + 2*policy.Jitter*random()))
+}
@@ -0,0 +1,175 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTemporary = errors.New("temporary error")
+	errPermanent = errors.New("permanent error")
+)
+
+type operationMock struct {
+	errors []error
+	calls  int
+}
+
+func (operation *operationMock) call(ctx context.Context) error {
+	operation.calls++
+	if operation.calls > len(operation.errors) {
+		return nil
+	}
+	return operation.errors[operation.calls-1]
+}
+
+func isTemporary(err error) bool {
+	return err == errTemporary
+}
+
+var testPolicy = Policy{
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Retryable:      isTemporary,
+}
+
+func TestDoSucceedsAfterRetries(t *testing.T) {
+	operation := &operationMock{errors: []error{errTemporary, errTemporary}}
+
+	err := Do(context.Background(), testPolicy, operation.call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, operation.calls)
+}
+
+func TestDoFirstAttemptSucceeds(t *testing.T) {
+	operation := &operationMock{}
+
+	err := Do(context.Background(), testPolicy, operation.call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, operation.calls)
+}
+
+func TestDoNotRetryableError(t *testing.T) {
+	operation := &operationMock{errors: []error{errTemporary, errPermanent, errTemporary}}
+
+	err := Do(context.Background(), testPolicy, operation.call)
+
+	assert.Equal(t, errPermanent, err)
+	assert.Equal(t, 2, operation.calls)
+}
+
+func TestDoAllErrorsAreRetryableByDefault(t *testing.T) {
+	operation := &operationMock{errors: []error{errPermanent, errTemporary}}
+
+	err := Do(context.Background(), Policy{InitialBackoff: time.Millisecond}, operation.call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, operation.calls)
+}
+
+func TestDoMaxAttempts(t *testing.T) {
+	operation := &operationMock{errors: []error{errTemporary, errTemporary, errTemporary, errTemporary}}
+	policy := testPolicy
+	policy.MaxAttempts = 3
+
+	err := Do(context.Background(), policy, operation.call)
+
+	assert.Equal(t, errTemporary, err)
+	assert.Equal(t, 3, operation.calls)
+}
+
+func TestDoSingleAttempt(t *testing.T) {
+	operation := &operationMock{errors: []error{errTemporary}}
+	policy := testPolicy
+	policy.MaxAttempts = 1
+
+	err := Do(context.Background(), policy, operation.call)
+
+	assert.Equal(t, errTemporary, err)
+	assert.Equal(t, 1, operation.calls)
+}
+
+func TestDoContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	operation := func(ctx context.Context) error {
+		cancel()
+		return errTemporary
+	}
+	policy := testPolicy
+	policy.InitialBackoff = time.Hour
+
+	err := Do(ctx, policy, operation)
+
+	assert.Equal(t, errTemporary, err)
+}
+
+func TestDoContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	calls := 0
+	operation := func(ctx context.Context) error {
+		calls++
+		return errTemporary
+	}
+
+	start := time.Now()
+	err := Do(ctx, testPolicy, operation)
+
+	assert.Equal(t, errTemporary, err)
+	assert.True(t, calls > 1, "operation is not retried")
+	assert.True(t, time.Since(start) < time.Second, "retries are not stopped by deadline")
+}
+
+func TestNextBackoffGrowsExponentially(t *testing.T) {
+	policy := Policy{MaxBackoff: 10 * time.Second}
+
+	assert.Equal(t, 2*time.Second, policy.next(time.Second))
+	assert.Equal(t, 8*time.Second, policy.next(4*time.Second))
+	assert.Equal(t, 10*time.Second, policy.next(8*time.Second))
+}
+
+func TestNextBackoffMultiplier(t *testing.T) {
+	policy := Policy{Multiplier: 1.5}
+
+	assert.Equal(t, 3*time.Second, policy.next(2*time.Second))
+}
+
+func TestBackoffAfterFailedAttempts(t *testing.T) {
+	policy := Policy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 4*time.Second, policy.Backoff(3))
+	assert.Equal(t, 10*time.Second, policy.Backoff(5))
+}
+
+func TestJitterBounds(t *testing.T) {
+	randomOriginal := random
+	defer func() { random = randomOriginal }()
+	policy := Policy{Jitter: 0.25}
+
+	random = func() float64 { return 0 }
+	assert.Equal(t, 750*time.Millisecond, policy.jitter(time.Second))
+	random = func() float64 { return 0.5 }
+	assert.Equal(t, time.Second, policy.jitter(time.Second))
+	random = func() float64 { return 0.999999 }
+	assert.True(t, policy.jitter(time.Second) < 1250*time.Millisecond)
+}
+
+func TestJitterIsInRange(t *testing.T) {
+	policy := Policy{Jitter: 0.5}
+
+	for i := 0; i < 1000; i++ {
+		delay := policy.jitter(time.Second)
+		assert.True(t, delay >= 500*time.Millisecond && delay < 1500*time.Millisecond, "delay %v is out of range", delay)
+	}
+}
+
+func TestNoJitter(t *testing.T) {
+	assert.Equal(t, time.Second, Policy{}.jitter(time.Second))
+}